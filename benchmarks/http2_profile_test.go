@@ -0,0 +1,183 @@
+package benchmarks
+
+import (
+	"context"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/monstercameron/grpc-tunnel/examples/_shared/proto"
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// parseProfileOneWayDelay simulates a 40ms round trip, typical of a browser on another continent.
+const parseProfileOneWayDelay = 20 * time.Millisecond
+
+// parseProfileMaxMessageSize lifts the gRPC 4 MiB default so large payloads fit in one message.
+const parseProfileMaxMessageSize = 32 << 20
+
+// profileTodoService echoes CreateTodo without retaining todos, so large payloads do not accumulate.
+type profileTodoService struct {
+	proto.UnimplementedTodoServiceServer
+}
+
+func (parseS *profileTodoService) CreateTodo(parseCtx context.Context, parseReq *proto.CreateTodoRequest) (*proto.CreateTodoResponse, error) {
+	return &proto.CreateTodoResponse{Todo: &proto.Todo{Id: "todo-1", Text: parseReq.Text}}, nil
+}
+
+// profileDelayedChunk is one read waiting in a latency proxy delay line.
+type profileDelayedChunk struct {
+	getPayload   []byte
+	getDeliverAt time.Time
+}
+
+// startProfileLatencyProxy forwards TCP connections to parseTarget, delaying each direction by
+// parseOneWayDelay without limiting bandwidth, and returns the proxy address.
+func startProfileLatencyProxy(parseB *testing.B, parseTarget string, parseOneWayDelay time.Duration) string {
+	parseB.Helper()
+	parseListener, parseErr := net.Listen("tcp", "127.0.0.1:0")
+	if parseErr != nil {
+		parseB.Fatalf("Listen() error: %v", parseErr)
+	}
+	parseB.Cleanup(func() {
+		_ = parseListener.Close()
+	})
+	go func() {
+		for {
+			parseClientConn, parseErr := parseListener.Accept()
+			if parseErr != nil {
+				return
+			}
+			parseServerConn, parseErr := net.Dial("tcp", parseTarget)
+			if parseErr != nil {
+				_ = parseClientConn.Close()
+				continue
+			}
+			go handleProfileDelayLine(parseClientConn, parseServerConn, parseOneWayDelay)
+			go handleProfileDelayLine(parseServerConn, parseClientConn, parseOneWayDelay)
+		}
+	}()
+	return parseListener.Addr().String()
+}
+
+// handleProfileDelayLine copies parseSrc to parseDst, delivering each read parseDelay after it arrived.
+func handleProfileDelayLine(parseSrc net.Conn, parseDst net.Conn, parseDelay time.Duration) {
+	parseChunks := make(chan profileDelayedChunk, 4096)
+	go func() {
+		defer func() {
+			_ = parseDst.Close()
+		}()
+		for parseChunk := range parseChunks {
+			time.Sleep(time.Until(parseChunk.getDeliverAt))
+			if _, parseErr := parseDst.Write(parseChunk.getPayload); parseErr != nil {
+				return
+			}
+		}
+	}()
+	defer close(parseChunks)
+	for {
+		parseBuffer := make([]byte, 64<<10)
+		parseRead, parseErr := parseSrc.Read(parseBuffer)
+		if parseRead > 0 {
+			parseChunks <- profileDelayedChunk{getPayload: parseBuffer[:parseRead], getDeliverAt: time.Now().Add(parseDelay)}
+		}
+		if parseErr != nil {
+			return
+		}
+	}
+}
+
+// setupGRPCProfile creates a bridge and client using one HTTP/2 profile, connected through a latency proxy.
+func setupGRPCProfile(parseB *testing.B, parseProfile grpctunnel.HTTP2Profile) (proto.TodoServiceClient, func()) {
+	parseB.Helper()
+	storeBenchmarkSilentLogOnce.Do(func() {
+		log.SetOutput(io.Discard)
+	})
+
+	parseGrpcServer := grpc.NewServer(grpc.MaxRecvMsgSize(parseProfileMaxMessageSize), grpc.MaxSendMsgSize(parseProfileMaxMessageSize))
+	proto.RegisterTodoServiceServer(parseGrpcServer, &profileTodoService{})
+	parseHandler, parseErr := grpctunnel.BuildBridgeHandler(parseGrpcServer, grpctunnel.BridgeConfig{
+		ReadLimitBytes: parseProfileMaxMessageSize,
+		HTTP2Profile:   parseProfile,
+	})
+	if parseErr != nil {
+		parseB.Fatalf("BuildBridgeHandler() error: %v", parseErr)
+	}
+	parseListener, parseErr := net.Listen("tcp", "127.0.0.1:0")
+	if parseErr != nil {
+		parseB.Fatalf("Listen() error: %v", parseErr)
+	}
+	parseServer := &http.Server{Handler: parseHandler}
+	go func() {
+		_ = parseServer.Serve(parseListener)
+	}()
+
+	parseProxyAddr := startProfileLatencyProxy(parseB, parseListener.Addr().String(), parseProfileOneWayDelay)
+	parseConn, parseErr := grpctunnel.BuildTunnelConn(context.Background(), grpctunnel.TunnelConfig{
+		Target:       parseProxyAddr,
+		HTTP2Profile: parseProfile,
+		GRPCOptions: []grpc.DialOption{
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(parseProfileMaxMessageSize), grpc.MaxCallSendMsgSize(parseProfileMaxMessageSize)),
+		},
+	})
+	if parseErr != nil {
+		parseB.Fatalf("BuildTunnelConn() error: %v", parseErr)
+	}
+
+	parseCleanup := func() {
+		parseConn.Close()
+		_ = parseServer.Close()
+		parseGrpcServer.Stop()
+	}
+	return proto.NewTodoServiceClient(parseConn), parseCleanup
+}
+
+// BenchmarkGRPC_HTTP2Profile measures unary round trips of growing payloads over a 40ms RTT link
+// for each HTTP/2 profile. One warm-up call per run lets gRPC's dynamic windows settle, so the
+// default case shows steady-state rather than cold-start behavior.
+func BenchmarkGRPC_HTTP2Profile(parseB *testing.B) {
+	for _, parseProfile := range []struct {
+		getName    string
+		getProfile grpctunnel.HTTP2Profile
+	}{
+		{getName: "default"},
+		{getName: "low_latency", getProfile: grpctunnel.HTTP2ProfileLowLatency},
+		{getName: "high_throughput", getProfile: grpctunnel.HTTP2ProfileHighThroughput},
+		{getName: "constrained_memory", getProfile: grpctunnel.HTTP2ProfileConstrainedMemory},
+	} {
+		for _, parsePayloadSize := range []int{1 << 10, 1 << 20, 12 << 20} {
+			parseB.Run(parseProfile.getName+"/"+buildProfilePayloadName(parsePayloadSize), func(parseB *testing.B) {
+				parseClient, parseCleanup := setupGRPCProfile(parseB, parseProfile.getProfile)
+				defer parseCleanup()
+
+				parseRequest := &proto.CreateTodoRequest{Text: strings.Repeat("x", parsePayloadSize)}
+				if _, parseErr := parseClient.CreateTodo(context.Background(), parseRequest); parseErr != nil {
+					parseB.Fatal(parseErr)
+				}
+				parseB.SetBytes(int64(parsePayloadSize))
+				parseB.ResetTimer()
+				for parseI := 0; parseI < parseB.N; parseI++ {
+					if _, parseErr := parseClient.CreateTodo(context.Background(), parseRequest); parseErr != nil {
+						parseB.Fatal(parseErr)
+					}
+				}
+			})
+		}
+	}
+}
+
+// buildProfilePayloadName formats a payload size for sub-benchmark names.
+func buildProfilePayloadName(parsePayloadSize int) string {
+	if parsePayloadSize >= 1<<20 {
+		return strconv.Itoa(parsePayloadSize>>20) + "MiB"
+	}
+	return strconv.Itoa(parsePayloadSize>>10) + "KiB"
+}
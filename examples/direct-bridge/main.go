@@ -266,6 +266,19 @@ func isBridgeOriginAllowed(parseR *http.Request) bool {
 	}
 }
 
+// tunnelLogObserver logs tunnel connects and disconnects.
+type tunnelLogObserver struct {
+	grpctunnel.BaseTunnelObserver
+}
+
+func (tunnelLogObserver) OnTunnelConnected(parseEvent grpctunnel.TunnelConnectedEvent) {
+	log.Printf("Client connected: %s (tunnel %s)", parseEvent.Request.RemoteAddr, parseEvent.TunnelID)
+}
+
+func (tunnelLogObserver) OnTunnelDisconnected(parseEvent grpctunnel.TunnelDisconnectedEvent) {
+	log.Printf("Client disconnected: %s (tunnel %s, %s after %v, %d streams)", parseEvent.Request.RemoteAddr, parseEvent.TunnelID, parseEvent.Cause, parseEvent.Duration.Round(time.Millisecond), parseEvent.Streams)
+}
+
 func main() {
 	const parseBridgeAddress = "127.0.0.1:5000"
 
@@ -281,11 +294,6 @@ func main() {
 	log.Printf("Direct gRPC-over-WebSocket server listening on %s", parseBridgeAddress)
 	log.Fatal(grpctunnel.ListenAndServe(parseBridgeAddress, parseGrpcServer,
 		grpctunnel.WithOriginCheck(isBridgeOriginAllowed),
-		grpctunnel.WithConnectHook(func(parseR *http.Request) {
-			log.Printf("Client connected: %s", parseR.RemoteAddr)
-		}),
-		grpctunnel.WithDisconnectHook(func(parseR2 *http.Request) {
-			log.Printf("Client disconnected: %s", parseR2.RemoteAddr)
-		}),
+		grpctunnel.WithTunnelObserver(tunnelLogObserver{}),
 	))
 }
@@ -1,108 +1,529 @@
 package bridge
 
 import (
+	"container/list"
+	"errors"
 	"fmt"
+	"hash/fnv"
+	"math"
 	"net"
 	"net/http"
+	"net/netip"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/tunneladmin"
 )
 
 const parseHandlerAbuseWindowDuration = time.Minute
+const parseHandlerAbuseShardCount = 32
+const parseHandlerDefaultMaxTrackedClients = 1 << 16
+const parseHandlerDefaultClientStateTTL = 10 * time.Minute
+const parseHandlerDefaultBaseBan = 30 * time.Second
+const parseHandlerDefaultMaxBan = time.Hour
+
+const parseHandlerAbuseReasonDenied = "client_denied"
+const parseHandlerAbuseReasonBanned = "client_banned"
+const parseHandlerAbuseReasonUpgradeRate = "upgrade_rate_limited"
+const parseHandlerAbuseReasonClientConnections = "client_connection_limit"
+const parseHandlerAbuseReasonActiveConnections = "active_connection_limit"
+const parseHandlerAbuseReasonClientTableFull = "client_table_full"
 
-// storeHandlerAbuseRateWindow tracks one client's fixed-window upgrade attempt counts.
-type storeHandlerAbuseRateWindow struct {
-	getWindowStartedAt time.Time
-	getWindowCount     int
+// handlerTokenBucket stores one token bucket. The zero value starts full on first use.
+type handlerTokenBucket struct {
+	getTokens    float64
+	getUpdatedAt time.Time
 }
 
-// handlerAbuseGuard stores in-memory counters used to enforce websocket abuse controls.
-type handlerAbuseGuard struct {
-	setGuardLock sync.Mutex
-	setConfig    Config
+// refillHandlerTokenBucket adds tokens earned since the last update, capped at the burst size.
+func (parseBucket *handlerTokenBucket) refillHandlerTokenBucket(parseLimit RateLimit, parseNow time.Time) {
+	parseBurst := float64(getHandlerRateLimitBurst(parseLimit))
+	if parseBucket.getUpdatedAt.IsZero() {
+		parseBucket.getTokens = parseBurst
+		parseBucket.getUpdatedAt = parseNow
+		return
+	}
+	if parseElapsed := parseNow.Sub(parseBucket.getUpdatedAt); parseElapsed > 0 {
+		parseBucket.getTokens = math.Min(parseBurst, parseBucket.getTokens+parseElapsed.Seconds()*parseLimit.PerSecond)
+		parseBucket.getUpdatedAt = parseNow
+	}
+}
+
+// allowHandlerTokenBucket takes one token when available.
+func (parseBucket *handlerTokenBucket) allowHandlerTokenBucket(parseLimit RateLimit, parseNow time.Time) bool {
+	parseBucket.refillHandlerTokenBucket(parseLimit, parseNow)
+	if parseBucket.getTokens < 1 {
+		return false
+	}
+	parseBucket.getTokens--
+	return true
+}
+
+// reserveHandlerTokenBucket takes tokens even when that leaves the bucket in debt and returns how long
+// the caller must wait for the debt to be repaid.
+func (parseBucket *handlerTokenBucket) reserveHandlerTokenBucket(parseLimit RateLimit, parseNow time.Time, parseTokens float64) time.Duration {
+	parseBucket.refillHandlerTokenBucket(parseLimit, parseNow)
+	parseBucket.getTokens -= parseTokens
+	if parseBucket.getTokens >= 0 {
+		return 0
+	}
+	return time.Duration(-parseBucket.getTokens / parseLimit.PerSecond * float64(time.Second))
+}
+
+// getHandlerRateLimitBurst returns the bucket capacity, defaulting to one second of tokens.
+func getHandlerRateLimitBurst(parseLimit RateLimit) int {
+	if parseLimit.Burst > 0 {
+		return parseLimit.Burst
+	}
+	return max(1, int(math.Ceil(parseLimit.PerSecond)))
+}
+
+// getHandlerRateLimitError validates one token-bucket limit.
+func getHandlerRateLimitError(parseName string, parseLimit RateLimit) error {
+	if parseLimit.PerSecond < 0 || math.IsNaN(parseLimit.PerSecond) || math.IsInf(parseLimit.PerSecond, 0) {
+		return fmt.Errorf("bridge: %s.PerSecond must be a finite value >= 0", parseName)
+	}
+	if parseLimit.Burst < 0 {
+		return fmt.Errorf("bridge: %s.Burst must be >= 0", parseName)
+	}
+	if parseLimit.Burst > 0 && parseLimit.PerSecond == 0 {
+		return fmt.Errorf("bridge: %s.Burst requires PerSecond", parseName)
+	}
+	return nil
+}
+
+// getHandlerUpgradeRateLimit returns the per-client upgrade limit, mapping the legacy per-minute cap onto a bucket.
+func getHandlerUpgradeRateLimit(parseConfig Config) RateLimit {
+	if parseConfig.UpgradeRateLimit.PerSecond > 0 {
+		return parseConfig.UpgradeRateLimit
+	}
+	if parseConfig.MaxUpgradesPerClientPerMinute > 0 {
+		return RateLimit{
+			PerSecond: float64(parseConfig.MaxUpgradesPerClientPerMinute) / parseHandlerAbuseWindowDuration.Seconds(),
+			Burst:     parseConfig.MaxUpgradesPerClientPerMinute,
+		}
+	}
+	return RateLimit{}
+}
+
+// buildHandlerAbuseLimits returns the configured limits a TunnelAdmin may change while tunnels run.
+func buildHandlerAbuseLimits(parseConfig Config) tunneladmin.Limits {
+	return tunneladmin.Limits{
+		MaxActiveConnections:    parseConfig.MaxActiveConnections,
+		MaxConnectionsPerClient: parseConfig.MaxConnectionsPerClient,
+		UpgradeRateLimit:        tunneladmin.RateLimit(getHandlerUpgradeRateLimit(parseConfig)),
+		RPCRateLimit:            tunneladmin.RateLimit(parseConfig.RPCRateLimit),
+	}
+}
+
+// handlerRateLimiter guards one token bucket with its own lock, such as the RPC bucket of a tunnel.
+type handlerRateLimiter struct {
+	setLimiterLock sync.Mutex
+	getLimit       RateLimit
+	getBucket      handlerTokenBucket
+}
 
-	getActiveConnections       int
-	storeClientConnections     map[string]int
-	storeClientUpgradeAttempts map[string]storeHandlerAbuseRateWindow
+// buildHandlerRateLimiter returns a limiter, or nil when the limit is disabled.
+func buildHandlerRateLimiter(parseLimit RateLimit) *handlerRateLimiter {
+	if parseLimit.PerSecond <= 0 {
+		return nil
+	}
+	return &handlerRateLimiter{getLimit: parseLimit}
+}
+
+// allowHandlerRateLimiter takes one token. A nil limiter always allows.
+func (parseLimiter *handlerRateLimiter) allowHandlerRateLimiter(parseNow time.Time) bool {
+	if parseLimiter == nil {
+		return true
+	}
+	parseLimiter.setLimiterLock.Lock()
+	defer parseLimiter.setLimiterLock.Unlock()
+	return parseLimiter.getBucket.allowHandlerTokenBucket(parseLimiter.getLimit, parseNow)
+}
+
+// handlerAbuseRejection describes why abuse controls rejected an upgrade.
+type handlerAbuseRejection struct {
+	getReason     string
+	getStatusCode int
+	getRetryAfter time.Duration
+	getDetail     string
+}
+
+// Error returns the machine-readable reason followed by details.
+func (parseRejection *handlerAbuseRejection) Error() string {
+	return parseRejection.getReason + ": " + parseRejection.getDetail
+}
+
+// buildHandlerAbuseRejection creates a rejection answered with 429 Too Many Requests.
+func buildHandlerAbuseRejection(parseReason string, parseRetryAfter time.Duration, parseFormat string, parseArgs ...any) *handlerAbuseRejection {
+	return &handlerAbuseRejection{
+		getReason:     parseReason,
+		getStatusCode: http.StatusTooManyRequests,
+		getRetryAfter: parseRetryAfter,
+		getDetail:     fmt.Sprintf(parseFormat, parseArgs...),
+	}
+}
+
+// writeHandlerAbuseRejection answers a rejected upgrade with its status code, reason body, and Retry-After when known.
+func writeHandlerAbuseRejection(parseW http.ResponseWriter, parseErr error) {
+	var parseRejection *handlerAbuseRejection
+	if !errors.As(parseErr, &parseRejection) {
+		http.Error(parseW, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		return
+	}
+	if parseRejection.getRetryAfter > 0 {
+		parseSeconds := int64(math.Ceil(parseRejection.getRetryAfter.Seconds()))
+		parseW.Header().Set("Retry-After", strconv.FormatInt(parseSeconds, 10))
+	}
+	http.Error(parseW, parseRejection.getReason, parseRejection.getStatusCode)
+}
+
+// handlerAbuseClient stores the rate and penalty state of one client key.
+type handlerAbuseClient struct {
+	getClientKey     string
+	getUpgradeBucket handlerTokenBucket
+	getLastSeenAt    time.Time
+	getStrikes       int
+	getBans          int
+	getBannedUntil   time.Time
+}
+
+// handlerAbuseShard stores the per-client state for one slice of the client key space.
+// Client entries are kept in least-recently-seen order so the oldest can be evicted first.
+type handlerAbuseShard struct {
+	setShardLock     sync.Mutex
+	storeClients     map[string]*list.Element
+	storeClientOrder list.List
+}
+
+// handlerAbuseGuard enforces websocket abuse controls. Connection slots live in the LimitStore so
+// replicas can share them; rate and penalty state stays in memory, sharded by client key so concurrent
+// upgrades from different clients do not serialize, and bounded by MaxTrackedClients and ClientStateTTL
+// so address scans cannot grow it without limit.
+type handlerAbuseGuard struct {
+	setConfig          Config
+	getLimits          tunneladmin.Limits
+	getAdmin           *tunneladmin.Admin
+	getPenaltyBox      PenaltyBox
+	getAllowedClients  []netip.Prefix
+	getDeniedClients   []netip.Prefix
+	getMaxShardClients int
+	getClientStateTTL  time.Duration
+	getLimitStore      LimitStore
+	getLeaseTTL        time.Duration
+	getShards          [parseHandlerAbuseShardCount]handlerAbuseShard
 }
 
 // buildHandlerAbuseGuard creates an abuse guard for bridge runtime controls.
+// getHandlerConfigError has already validated the client CIDR lists.
 func buildHandlerAbuseGuard(parseConfig Config) *handlerAbuseGuard {
-	return &handlerAbuseGuard{
-		setConfig:                  parseConfig,
-		storeClientConnections:     map[string]int{},
-		storeClientUpgradeAttempts: map[string]storeHandlerAbuseRateWindow{},
+	parseLimits := buildHandlerAbuseLimits(parseConfig)
+	parseGuard := &handlerAbuseGuard{
+		setConfig:         parseConfig,
+		getLimits:         parseConfig.TunnelAdmin.InitLimits(parseLimits),
+		getAdmin:          parseConfig.TunnelAdmin,
+		getPenaltyBox:     getHandlerPenaltyBox(parseConfig.PenaltyBox),
+		getClientStateTTL: parseConfig.ClientStateTTL,
+		getLimitStore:     parseConfig.LimitStore,
+		getLeaseTTL:       parseConfig.LimitStoreLeaseTTL,
 	}
+	if parseGuard.getLimits != parseLimits {
+		logBridgeEvent(parseConfig.Logger, "WARN", "tunnel_admin_limits_ignored", nil, nil, "Configured abuse limits differ from the TunnelAdmin limits in effect; the TunnelAdmin limits apply")
+	}
+	if parseGuard.getLimitStore == nil {
+		// In-process leases end with the process, so they never need to expire.
+		parseGuard.getLimitStore = buildHandlerMemoryLimitStore()
+		parseGuard.getLeaseTTL = 0
+	} else if parseGuard.getLeaseTTL == 0 {
+		parseGuard.getLeaseTTL = parseHandlerDefaultLeaseTTL
+	}
+	parseGuard.getAllowedClients, _ = parseHandlerPrefixes("AllowedClientCIDRs", parseConfig.AllowedClientCIDRs)
+	parseGuard.getDeniedClients, _ = parseHandlerPrefixes("DeniedClientCIDRs", parseConfig.DeniedClientCIDRs)
+
+	parseMaxClients := parseConfig.MaxTrackedClients
+	if parseMaxClients == 0 {
+		parseMaxClients = parseHandlerDefaultMaxTrackedClients
+	}
+	parseGuard.getMaxShardClients = max(1, (parseMaxClients+parseHandlerAbuseShardCount-1)/parseHandlerAbuseShardCount)
+	if parseGuard.getClientStateTTL == 0 {
+		parseGuard.getClientStateTTL = parseHandlerDefaultClientStateTTL
+	}
+	// Keep banned clients tracked for the whole ban.
+	parseGuard.getClientStateTTL = max(parseGuard.getClientStateTTL, parseGuard.getPenaltyBox.MaxBan)
+
+	for parseIndex := range parseGuard.getShards {
+		parseGuard.getShards[parseIndex].storeClients = map[string]*list.Element{}
+	}
+	return parseGuard
 }
 
-// reserveHandlerConnection validates abuse controls and reserves one connection slot.
-func (parseGuard *handlerAbuseGuard) reserveHandlerConnection(parseRequest *http.Request, parseNow time.Time) error {
-	if parseGuard == nil {
-		return nil
+// getHandlerPenaltyBox applies penalty box defaults.
+func getHandlerPenaltyBox(parsePenaltyBox PenaltyBox) PenaltyBox {
+	if parsePenaltyBox.Strikes == 0 {
+		return PenaltyBox{}
+	}
+	if parsePenaltyBox.BaseBan == 0 {
+		parsePenaltyBox.BaseBan = parseHandlerDefaultBaseBan
+	}
+	if parsePenaltyBox.MaxBan == 0 {
+		parsePenaltyBox.MaxBan = max(parseHandlerDefaultMaxBan, parsePenaltyBox.BaseBan)
+	}
+	return parsePenaltyBox
+}
+
+// getHandlerPenaltyBoxError validates penalty box settings.
+func getHandlerPenaltyBoxError(parsePenaltyBox PenaltyBox) error {
+	if parsePenaltyBox.Strikes < 0 {
+		return fmt.Errorf("bridge: PenaltyBox.Strikes must be >= 0")
+	}
+	if parsePenaltyBox.BaseBan < 0 || parsePenaltyBox.MaxBan < 0 {
+		return fmt.Errorf("bridge: PenaltyBox ban durations must be >= 0")
+	}
+	if parsePenaltyBox.BaseBan > 0 && parsePenaltyBox.MaxBan > 0 && parsePenaltyBox.MaxBan < parsePenaltyBox.BaseBan {
+		return fmt.Errorf("bridge: PenaltyBox.MaxBan must be >= BaseBan")
 	}
+	return nil
+}
+
+// getHandlerAbuseLimits returns the limits in effect, which a TunnelAdmin may change while tunnels run.
+func (parseGuard *handlerAbuseGuard) getHandlerAbuseLimits() tunneladmin.Limits {
+	if parseGuard.getAdmin != nil {
+		return parseGuard.getAdmin.Limits()
+	}
+	return parseGuard.getLimits
+}
+
+// getHandlerSlotLimit returns the slot limit to reserve against, or zero to skip counting. With a
+// TunnelAdmin, disabled limits still count connections so that enabling them later sees the tunnels
+// already open.
+func (parseGuard *handlerAbuseGuard) getHandlerSlotLimit(parseLimit int) int {
+	if parseLimit == 0 && parseGuard.getAdmin != nil {
+		return math.MaxInt
+	}
+	return parseLimit
+}
 
-	parseClientKey := buildHandlerClientKey(parseRequest)
+// getHandlerAbuseShard returns the shard that owns a client key.
+func (parseGuard *handlerAbuseGuard) getHandlerAbuseShard(parseClientKey string) *handlerAbuseShard {
+	parseHash := fnv.New32a()
+	_, _ = parseHash.Write([]byte(parseClientKey))
+	return &parseGuard.getShards[parseHash.Sum32()%parseHandlerAbuseShardCount]
+}
+
+// getHandlerGuardClientKey resolves the client key with ClientKeyFunc, falling back to the client address.
+// IPv6 addresses are grouped by /64 when ShouldGroupIPv6Clients is set.
+func (parseGuard *handlerAbuseGuard) getHandlerGuardClientKey(parseRequest *http.Request) string {
+	parseClientKey := ""
+	if parseGuard.setConfig.ClientKeyFunc != nil && parseRequest != nil {
+		parseClientKey = parseGuard.setConfig.ClientKeyFunc(parseRequest)
+	}
+	if parseClientKey == "" && parseGuard.setConfig.ShouldGroupIPv6Clients && parseRequest != nil {
+		if parseAddr, isValidAddr := parseHandlerRemoteAddr(parseRequest.RemoteAddr); isValidAddr && parseAddr.Is6() {
+			parsePrefix, _ := parseAddr.WithZone("").Prefix(64)
+			parseClientKey = parsePrefix.String()
+		}
+	}
+	if parseClientKey == "" {
+		parseClientKey = buildHandlerClientKey(parseRequest)
+	}
 	if parseClientKey == "" {
 		parseClientKey = "unknown"
 	}
+	return parseClientKey
+}
 
-	parseGuard.setGuardLock.Lock()
-	defer parseGuard.setGuardLock.Unlock()
+// reserveHandlerConnection validates abuse controls and reserves connection slots for one tunnel.
+// Rejections are *handlerAbuseRejection values carrying a reason and, when known, a retry delay.
+// The returned lease must be passed to clearHandlerConnection when the tunnel ends.
+func (parseGuard *handlerAbuseGuard) reserveHandlerConnection(parseRequest *http.Request, parseNow time.Time) (*handlerConnectionLease, error) {
+	if parseGuard == nil {
+		return nil, nil
+	}
 
-	if parseGuard.setConfig.MaxUpgradesPerClientPerMinute > 0 {
-		parseWindow := parseGuard.storeClientUpgradeAttempts[parseClientKey]
-		if parseWindow.getWindowStartedAt.IsZero() || parseNow.Sub(parseWindow.getWindowStartedAt) >= parseHandlerAbuseWindowDuration {
-			parseWindow = storeHandlerAbuseRateWindow{
-				getWindowStartedAt: parseNow,
-				getWindowCount:     0,
+	isExemptClient := false
+	if parseRequest != nil {
+		if parseAddr, isValidAddr := parseHandlerRemoteAddr(parseRequest.RemoteAddr); isValidAddr {
+			if isHandlerPrefixMember(parseGuard.getDeniedClients, parseAddr) {
+				return nil, &handlerAbuseRejection{
+					getReason:     parseHandlerAbuseReasonDenied,
+					getStatusCode: http.StatusForbidden,
+					getDetail:     fmt.Sprintf("client %s matches DeniedClientCIDRs", parseAddr),
+				}
 			}
+			isExemptClient = isHandlerPrefixMember(parseGuard.getAllowedClients, parseAddr)
 		}
-		if parseWindow.getWindowCount >= parseGuard.setConfig.MaxUpgradesPerClientPerMinute {
-			return fmt.Errorf("upgrade rate exceeded for client %q", parseClientKey)
-		}
-		parseWindow.getWindowCount++
-		parseGuard.storeClientUpgradeAttempts[parseClientKey] = parseWindow
 	}
 
-	if parseGuard.setConfig.MaxActiveConnections > 0 && parseGuard.getActiveConnections >= parseGuard.setConfig.MaxActiveConnections {
-		return fmt.Errorf("active connection cap exceeded")
+	parseClientKey := parseGuard.getHandlerGuardClientKey(parseRequest)
+	parseLimits := parseGuard.getHandlerAbuseLimits()
+	parseUpgradeLimit := RateLimit(parseLimits.UpgradeRateLimit)
+	isTrackedClient := (parseUpgradeLimit.PerSecond > 0 || parseGuard.getPenaltyBox.Strikes > 0) && !isExemptClient
+	if isTrackedClient {
+		if parseErr := parseGuard.checkHandlerClientRate(parseClientKey, parseUpgradeLimit, parseNow); parseErr != nil {
+			return nil, parseErr
+		}
 	}
 
-	parseClientConnections := parseGuard.storeClientConnections[parseClientKey]
-	if parseGuard.setConfig.MaxConnectionsPerClient > 0 && parseClientConnections >= parseGuard.setConfig.MaxConnectionsPerClient {
-		return fmt.Errorf("per-client connection cap exceeded for client %q", parseClientKey)
+	parseLease := buildHandlerConnectionLease(parseGuard)
+	parseClientLimit := parseGuard.getHandlerSlotLimit(parseLimits.MaxConnectionsPerClient)
+	if !isExemptClient && parseClientLimit > 0 {
+		if !parseLease.reserveHandlerLeaseSlot(parseRequest, parseHandlerClientSlotPrefix+parseClientKey, parseClientLimit) {
+			parseRetryAfter := time.Duration(0)
+			if isTrackedClient {
+				parseRetryAfter = parseGuard.strikeHandlerAbuseClient(parseClientKey, parseNow)
+			}
+			return nil, buildHandlerAbuseRejection(parseHandlerAbuseReasonClientConnections, parseRetryAfter, "per-client connection cap exceeded for client %q", parseClientKey)
+		}
+	}
+	if parseActiveLimit := parseGuard.getHandlerSlotLimit(parseLimits.MaxActiveConnections); parseActiveLimit > 0 {
+		if !parseLease.reserveHandlerLeaseSlot(parseRequest, parseHandlerActiveSlotKey, parseActiveLimit) {
+			parseLease.clearHandlerLease()
+			return nil, buildHandlerAbuseRejection(parseHandlerAbuseReasonActiveConnections, 0, "active connection cap exceeded")
+		}
 	}
+	if isTrackedClient {
+		parseGuard.clearHandlerAbuseStrikes(parseClientKey)
+	}
+	parseLease.startHandlerLeaseRenewal()
+	return parseLease, nil
+}
 
-	parseGuard.getActiveConnections++
-	parseGuard.storeClientConnections[parseClientKey] = parseClientConnections + 1
+// checkHandlerClientRate applies the penalty box and upgrade rate limit to a client key.
+func (parseGuard *handlerAbuseGuard) checkHandlerClientRate(parseClientKey string, parseUpgradeLimit RateLimit, parseNow time.Time) error {
+	parseShard := parseGuard.getHandlerAbuseShard(parseClientKey)
+	parseShard.setShardLock.Lock()
+	defer parseShard.setShardLock.Unlock()
+
+	parseGuard.sweepHandlerAbuseShard(parseShard, parseNow)
+	parseClient, parseRetryAfter := parseGuard.touchHandlerAbuseClient(parseShard, parseClientKey, parseNow)
+	if parseClient == nil {
+		return buildHandlerAbuseRejection(parseHandlerAbuseReasonClientTableFull, parseRetryAfter, "client state table is full of banned clients")
+	}
+	if parseNow.Before(parseClient.getBannedUntil) {
+		return buildHandlerAbuseRejection(parseHandlerAbuseReasonBanned, parseClient.getBannedUntil.Sub(parseNow), "client %q is in the penalty box", parseClientKey)
+	}
+	if parseUpgradeLimit.PerSecond > 0 && !parseClient.getUpgradeBucket.allowHandlerTokenBucket(parseUpgradeLimit, parseNow) {
+		parseRetryAfter := time.Duration((1 - parseClient.getUpgradeBucket.getTokens) / parseUpgradeLimit.PerSecond * float64(time.Second))
+		parseRetryAfter = max(parseRetryAfter, parseGuard.strikeHandlerClientLocked(parseClient, parseNow))
+		return buildHandlerAbuseRejection(parseHandlerAbuseReasonUpgradeRate, parseRetryAfter, "upgrade rate exceeded for client %q", parseClientKey)
+	}
 	return nil
 }
 
-// clearHandlerConnection releases one reserved connection slot for abuse controls.
-func (parseGuard *handlerAbuseGuard) clearHandlerConnection(parseRequest *http.Request) {
-	if parseGuard == nil {
-		return
+// touchHandlerAbuseClient returns the state for a client key, creating it and evicting the least recently
+// seen unbanned client when the shard is full. Banned clients are never evicted, so rotating keys cannot
+// flush a ban; when only banned clients remain it returns nil and the wait until the first ban ends.
+// Callers hold the shard lock.
+func (parseGuard *handlerAbuseGuard) touchHandlerAbuseClient(parseShard *handlerAbuseShard, parseClientKey string, parseNow time.Time) (*handlerAbuseClient, time.Duration) {
+	if parseElement, isFoundClient := parseShard.storeClients[parseClientKey]; isFoundClient {
+		parseShard.storeClientOrder.MoveToFront(parseElement)
+		parseClient := parseElement.Value.(*handlerAbuseClient)
+		parseClient.getLastSeenAt = parseNow
+		return parseClient, 0
 	}
+	for len(parseShard.storeClients) >= parseGuard.getMaxShardClients {
+		parseVictim, parseBanWait := getHandlerAbuseEvictionVictim(parseShard, parseNow)
+		if parseVictim == nil {
+			return nil, parseBanWait
+		}
+		delete(parseShard.storeClients, parseVictim.Value.(*handlerAbuseClient).getClientKey)
+		parseShard.storeClientOrder.Remove(parseVictim)
+	}
+	parseClient := &handlerAbuseClient{getClientKey: parseClientKey, getLastSeenAt: parseNow}
+	parseShard.storeClients[parseClientKey] = parseShard.storeClientOrder.PushFront(parseClient)
+	return parseClient, 0
+}
 
-	parseClientKey := buildHandlerClientKey(parseRequest)
-	if parseClientKey == "" {
-		parseClientKey = "unknown"
+// getHandlerAbuseEvictionVictim returns the least recently seen client that is not banned. When every client
+// is banned it returns nil and the shortest remaining ban. Callers hold the shard lock.
+func getHandlerAbuseEvictionVictim(parseShard *handlerAbuseShard, parseNow time.Time) (*list.Element, time.Duration) {
+	parseBanWait := time.Duration(0)
+	for parseElement := parseShard.storeClientOrder.Back(); parseElement != nil; parseElement = parseElement.Prev() {
+		parseClient := parseElement.Value.(*handlerAbuseClient)
+		if !parseNow.Before(parseClient.getBannedUntil) {
+			return parseElement, 0
+		}
+		if parseWait := parseClient.getBannedUntil.Sub(parseNow); parseBanWait == 0 || parseWait < parseBanWait {
+			parseBanWait = parseWait
+		}
 	}
+	return nil, parseBanWait
+}
 
-	parseGuard.setGuardLock.Lock()
-	defer parseGuard.setGuardLock.Unlock()
+// strikeHandlerAbuseClient records one rejection for a tracked client key.
+func (parseGuard *handlerAbuseGuard) strikeHandlerAbuseClient(parseClientKey string, parseNow time.Time) time.Duration {
+	parseShard := parseGuard.getHandlerAbuseShard(parseClientKey)
+	parseShard.setShardLock.Lock()
+	defer parseShard.setShardLock.Unlock()
+	parseElement, isFoundClient := parseShard.storeClients[parseClientKey]
+	if !isFoundClient {
+		return 0
+	}
+	return parseGuard.strikeHandlerClientLocked(parseElement.Value.(*handlerAbuseClient), parseNow)
+}
 
-	if parseGuard.getActiveConnections > 0 {
-		parseGuard.getActiveConnections--
+// clearHandlerAbuseStrikes resets the consecutive rejection count after an accepted upgrade.
+func (parseGuard *handlerAbuseGuard) clearHandlerAbuseStrikes(parseClientKey string) {
+	parseShard := parseGuard.getHandlerAbuseShard(parseClientKey)
+	parseShard.setShardLock.Lock()
+	defer parseShard.setShardLock.Unlock()
+	if parseElement, isFoundClient := parseShard.storeClients[parseClientKey]; isFoundClient {
+		parseElement.Value.(*handlerAbuseClient).getStrikes = 0
 	}
+}
 
-	parseClientConnections := parseGuard.storeClientConnections[parseClientKey]
-	if parseClientConnections <= 1 {
-		delete(parseGuard.storeClientConnections, parseClientKey)
-		return
+// strikeHandlerClientLocked records one rejection and bans the client once PenaltyBox.Strikes is reached.
+// Each ban doubles the previous one up to MaxBan. It returns the new ban duration, or zero.
+// Callers hold the shard lock.
+func (parseGuard *handlerAbuseGuard) strikeHandlerClientLocked(parseClient *handlerAbuseClient, parseNow time.Time) time.Duration {
+	parsePenaltyBox := parseGuard.getPenaltyBox
+	if parsePenaltyBox.Strikes == 0 {
+		return 0
 	}
-	parseGuard.storeClientConnections[parseClientKey] = parseClientConnections - 1
+	parseClient.getStrikes++
+	if parseClient.getStrikes < parsePenaltyBox.Strikes {
+		return 0
+	}
+	parseClient.getStrikes = 0
+	parseClient.getBans++
+	parseBan := parsePenaltyBox.BaseBan
+	for parseIndex := 1; parseIndex < parseClient.getBans && parseBan < parsePenaltyBox.MaxBan; parseIndex++ {
+		parseBan *= 2
+	}
+	parseBan = min(parseBan, parsePenaltyBox.MaxBan)
+	parseClient.getBannedUntil = parseNow.Add(parseBan)
+	return parseBan
+}
+
+// sweepHandlerAbuseShard forgets clients idle for longer than the client state TTL. Callers hold the shard lock.
+func (parseGuard *handlerAbuseGuard) sweepHandlerAbuseShard(parseShard *handlerAbuseShard, parseNow time.Time) {
+	for parseOldest := parseShard.storeClientOrder.Back(); parseOldest != nil; parseOldest = parseShard.storeClientOrder.Back() {
+		parseClient := parseOldest.Value.(*handlerAbuseClient)
+		if parseNow.Sub(parseClient.getLastSeenAt) < parseGuard.getClientStateTTL {
+			return
+		}
+		delete(parseShard.storeClients, parseClient.getClientKey)
+		parseShard.storeClientOrder.Remove(parseOldest)
+	}
+}
+
+// getHandlerTrackedClients returns the number of client keys with rate or penalty state.
+func (parseGuard *handlerAbuseGuard) getHandlerTrackedClients() int {
+	parseTrackedClients := 0
+	for parseIndex := range parseGuard.getShards {
+		parseShard := &parseGuard.getShards[parseIndex]
+		parseShard.setShardLock.Lock()
+		parseTrackedClients += len(parseShard.storeClients)
+		parseShard.setShardLock.Unlock()
+	}
+	return parseTrackedClients
+}
+
+// clearHandlerConnection releases the connection slots held by a lease.
+func (parseGuard *handlerAbuseGuard) clearHandlerConnection(parseLease *handlerConnectionLease) {
+	parseLease.clearHandlerLease()
 }
 
 // buildHandlerClientKey derives a stable client key for abuse controls from request remote address.
@@ -0,0 +1,207 @@
+//lint:file-ignore SA1019 grpc.DialContext is retained in tests to match bridge integration coverage on grpc 1.x.
+
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/monstercameron/grpc-tunnel/examples/_shared/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// TestHandlerTokenBucket_BurstAndRefill verifies bursts drain the bucket and refills never exceed the burst.
+func TestHandlerTokenBucket_BurstAndRefill(parseT *testing.T) {
+	parseLimit := RateLimit{PerSecond: 2, Burst: 3}
+	parseBucket := &handlerTokenBucket{}
+	parseNow := time.Now()
+
+	for parseIndex := 0; parseIndex < 3; parseIndex++ {
+		if !parseBucket.allowHandlerTokenBucket(parseLimit, parseNow) {
+			parseT.Fatalf("allow(burst %d) = false, want true", parseIndex)
+		}
+	}
+	if parseBucket.allowHandlerTokenBucket(parseLimit, parseNow) {
+		parseT.Fatal("allow(after burst) = true, want false")
+	}
+	if !parseBucket.allowHandlerTokenBucket(parseLimit, parseNow.Add(500*time.Millisecond)) {
+		parseT.Fatal("allow(after refill) = false, want true")
+	}
+	parseIdle := parseNow.Add(time.Hour)
+	for parseIndex := 0; parseIndex < 3; parseIndex++ {
+		if !parseBucket.allowHandlerTokenBucket(parseLimit, parseIdle) {
+			parseT.Fatalf("allow(idle burst %d) = false, want true", parseIndex)
+		}
+	}
+	if parseBucket.allowHandlerTokenBucket(parseLimit, parseIdle) {
+		parseT.Fatal("allow(idle over burst) = true, want false")
+	}
+}
+
+// TestHandlerAbuseGuard_ClientKeyFunc verifies custom client keys share upgrade buckets across addresses.
+func TestHandlerAbuseGuard_ClientKeyFunc(parseT *testing.T) {
+	parseGuard := buildHandlerAbuseGuard(Config{
+		UpgradeRateLimit: RateLimit{PerSecond: 1, Burst: 1},
+		ClientKeyFunc: func(parseR *http.Request) string {
+			return parseR.Header.Get("X-Api-Key")
+		},
+	})
+	parseNow := time.Now()
+
+	parseFirstReq := httptest.NewRequest(http.MethodGet, "/", nil)
+	parseFirstReq.RemoteAddr = "203.0.113.10:50000"
+	parseFirstReq.Header.Set("X-Api-Key", "tenant-a")
+	parseSecondReq := httptest.NewRequest(http.MethodGet, "/", nil)
+	parseSecondReq.RemoteAddr = "198.51.100.20:50000"
+	parseSecondReq.Header.Set("X-Api-Key", "tenant-a")
+
+	if _, parseErr := parseGuard.reserveHandlerConnection(parseFirstReq, parseNow); parseErr != nil {
+		parseT.Fatalf("reserveHandlerConnection(first) error: %v", parseErr)
+	}
+	if _, parseErr := parseGuard.reserveHandlerConnection(parseSecondReq, parseNow); parseErr == nil {
+		parseT.Fatal("reserveHandlerConnection(same key) expected rate limit error, got nil")
+	}
+	if _, parseErr := parseGuard.reserveHandlerConnection(parseSecondReq, parseNow.Add(time.Second)); parseErr != nil {
+		parseT.Fatalf("reserveHandlerConnection(after refill) error: %v", parseErr)
+	}
+}
+
+// TestHandlerRPCRateLimit_ResourceExhausted verifies RPC starts over the per-tunnel limit never reach a backend.
+func TestHandlerRPCRateLimit_ResourceExhausted(parseT *testing.T) {
+	parseBackendAddress, _ := buildPoolTestBackend(parseT, "limited")
+	parseHandler := NewHandler(Config{
+		TargetAddress: parseBackendAddress,
+		RPCRateLimit:  RateLimit{PerSecond: 0.001, Burst: 2},
+	})
+	if parseHandler.initErr != nil {
+		parseT.Fatalf("NewHandler() error: %v", parseHandler.initErr)
+	}
+	parseTransport := &storeExposureTestTransport{getNext: parseHandler.proxy.Transport}
+	parseHandler.proxy.Transport = parseTransport
+	parseBridgeServer := httptest.NewServer(parseHandler)
+	defer parseBridgeServer.Close()
+
+	parseCtx, clearCtx := context.WithTimeout(context.Background(), 5*time.Second)
+	defer clearCtx()
+	parseClientConn, parseErr := grpc.DialContext(
+		parseCtx,
+		"ignored:1234",
+		DialOption("ws"+strings.TrimPrefix(parseBridgeServer.URL, "http")),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if parseErr != nil {
+		parseT.Fatalf("DialContext() error: %v", parseErr)
+	}
+	defer parseClientConn.Close()
+
+	parseClient := proto.NewTodoServiceClient(parseClientConn)
+	for parseIndex := 0; parseIndex < 2; parseIndex++ {
+		if _, parseErr = parseClient.CreateTodo(parseCtx, &proto.CreateTodoRequest{Text: "limited"}); parseErr != nil {
+			parseT.Fatalf("CreateTodo(%d) error: %v", parseIndex, parseErr)
+		}
+	}
+	_, parseErr = parseClient.CreateTodo(parseCtx, &proto.CreateTodoRequest{Text: "limited"})
+	if status.Code(parseErr) != codes.ResourceExhausted {
+		parseT.Fatalf("CreateTodo(over limit) error = %v, want %v", parseErr, codes.ResourceExhausted)
+	}
+	if parseCalls := parseTransport.getCalls.Load(); parseCalls != 2 {
+		parseT.Fatalf("backend requests = %d, want 2", parseCalls)
+	}
+}
+
+// TestHandlerAbuseGuard_PenaltyBoxAndClientCIDRs verifies bans, CIDR lists, and bounded client state.
+func TestHandlerAbuseGuard_PenaltyBoxAndClientCIDRs(parseT *testing.T) {
+	parseGuard := buildHandlerAbuseGuard(Config{
+		UpgradeRateLimit:   RateLimit{PerSecond: 1, Burst: 1},
+		PenaltyBox:         PenaltyBox{Strikes: 1, BaseBan: 10 * time.Second},
+		AllowedClientCIDRs: []string{"10.0.0.0/8"},
+		DeniedClientCIDRs:  []string{"192.0.2.0/24"},
+		MaxTrackedClients:  parseHandlerAbuseShardCount,
+	})
+	parseNow := time.Now()
+	parseBuildRequest := func(parseRemoteAddr string) *http.Request {
+		parseReq := httptest.NewRequest(http.MethodGet, "/", nil)
+		parseReq.RemoteAddr = parseRemoteAddr
+		return parseReq
+	}
+
+	var parseRejection *handlerAbuseRejection
+	_, parseErr := parseGuard.reserveHandlerConnection(parseBuildRequest("192.0.2.9:1"), parseNow)
+	if !errors.As(parseErr, &parseRejection) || parseRejection.getStatusCode != http.StatusForbidden {
+		parseT.Fatalf("reserveHandlerConnection(denied) error = %v, want 403 rejection", parseErr)
+	}
+	for parseIndex := 0; parseIndex < 3; parseIndex++ {
+		if _, parseErr = parseGuard.reserveHandlerConnection(parseBuildRequest("10.1.1.1:1"), parseNow); parseErr != nil {
+			parseT.Fatalf("reserveHandlerConnection(allowed %d) error: %v", parseIndex, parseErr)
+		}
+	}
+
+	parseOffender := parseBuildRequest("203.0.113.5:1")
+	if _, parseErr = parseGuard.reserveHandlerConnection(parseOffender, parseNow); parseErr != nil {
+		parseT.Fatalf("reserveHandlerConnection(first) error: %v", parseErr)
+	}
+	_, parseErr = parseGuard.reserveHandlerConnection(parseOffender, parseNow)
+	if !errors.As(parseErr, &parseRejection) || parseRejection.getRetryAfter != 10*time.Second {
+		parseT.Fatalf("reserveHandlerConnection(strike) error = %v, want 10s ban", parseErr)
+	}
+	_, parseErr = parseGuard.reserveHandlerConnection(parseOffender, parseNow.Add(2*time.Second))
+	if !errors.As(parseErr, &parseRejection) || parseRejection.getReason != parseHandlerAbuseReasonBanned {
+		parseT.Fatalf("reserveHandlerConnection(banned) error = %v, want %s", parseErr, parseHandlerAbuseReasonBanned)
+	}
+
+	for parseIndex := 0; parseIndex < 500; parseIndex++ {
+		parseReq := parseBuildRequest(fmt.Sprintf("172.16.%d.%d:1", parseIndex/256, parseIndex%256))
+		parseLease, _ := parseGuard.reserveHandlerConnection(parseReq, parseNow)
+		parseGuard.clearHandlerConnection(parseLease)
+	}
+	if parseTracked := parseGuard.getHandlerTrackedClients(); parseTracked > parseHandlerAbuseShardCount {
+		parseT.Fatalf("tracked clients = %d, want <= %d", parseTracked, parseHandlerAbuseShardCount)
+	}
+}
+
+// TestHandlerAbuseGuard_EvictionKeepsBannedClients verifies a full shard never evicts a banned client to make room.
+func TestHandlerAbuseGuard_EvictionKeepsBannedClients(parseT *testing.T) {
+	parseGuard := buildHandlerAbuseGuard(Config{
+		UpgradeRateLimit:  RateLimit{PerSecond: 1, Burst: 1},
+		PenaltyBox:        PenaltyBox{Strikes: 1, BaseBan: 10 * time.Second},
+		MaxTrackedClients: parseHandlerAbuseShardCount,
+	})
+	parseBannedKey := "203.0.113.7"
+	parseNewKey := ""
+	for parseIndex := 0; parseNewKey == ""; parseIndex++ {
+		parseKey := fmt.Sprintf("198.51.100.%d", parseIndex)
+		if parseGuard.getHandlerAbuseShard(parseKey) == parseGuard.getHandlerAbuseShard(parseBannedKey) {
+			parseNewKey = parseKey
+		}
+	}
+	parseNow := time.Now()
+	if parseErr := parseGuard.checkHandlerClientRate(parseBannedKey, parseGuard.setConfig.UpgradeRateLimit, parseNow); parseErr != nil {
+		parseT.Fatalf("checkHandlerClientRate(first) error: %v", parseErr)
+	}
+	var parseRejection *handlerAbuseRejection
+	if parseErr := parseGuard.checkHandlerClientRate(parseBannedKey, parseGuard.setConfig.UpgradeRateLimit, parseNow); !errors.As(parseErr, &parseRejection) || parseRejection.getRetryAfter != 10*time.Second {
+		parseT.Fatalf("checkHandlerClientRate(banning) = %v, want 10s ban", parseErr)
+	}
+
+	parseErr := parseGuard.checkHandlerClientRate(parseNewKey, parseGuard.setConfig.UpgradeRateLimit, parseNow.Add(time.Second))
+	if !errors.As(parseErr, &parseRejection) || parseRejection.getReason != parseHandlerAbuseReasonClientTableFull || parseRejection.getRetryAfter != 9*time.Second {
+		parseT.Fatalf("checkHandlerClientRate(new key, full shard) = %v, want %q with 9s retry", parseErr, parseHandlerAbuseReasonClientTableFull)
+	}
+	parseErr = parseGuard.checkHandlerClientRate(parseBannedKey, parseGuard.setConfig.UpgradeRateLimit, parseNow.Add(2*time.Second))
+	if !errors.As(parseErr, &parseRejection) || parseRejection.getReason != parseHandlerAbuseReasonBanned {
+		parseT.Fatalf("checkHandlerClientRate(banned key) = %v, want %q", parseErr, parseHandlerAbuseReasonBanned)
+	}
+
+	if parseErr := parseGuard.checkHandlerClientRate(parseNewKey, parseGuard.setConfig.UpgradeRateLimit, parseNow.Add(10*time.Second)); parseErr != nil {
+		parseT.Fatalf("checkHandlerClientRate(new key, ban expired) error: %v", parseErr)
+	}
+}
@@ -0,0 +1,21 @@
+package bridge
+
+import (
+	"errors"
+	"net/http"
+)
+
+// ErrUnauthenticated rejects a websocket upgrade with 401 Unauthorized when returned from Config.Authenticate.
+// Any Authenticate error that does not wrap ErrPermissionDenied is treated the same way.
+var ErrUnauthenticated = errors.New("bridge: unauthenticated")
+
+// ErrPermissionDenied rejects a websocket upgrade with 403 Forbidden when wrapped by a Config.Authenticate error.
+var ErrPermissionDenied = errors.New("bridge: permission denied")
+
+// getHandlerAuthStatusCode maps an Authenticate error to the HTTP status returned before upgrade.
+func getHandlerAuthStatusCode(parseErr error) int {
+	if errors.Is(parseErr, ErrPermissionDenied) {
+		return http.StatusForbidden
+	}
+	return http.StatusUnauthorized
+}
@@ -0,0 +1,189 @@
+//lint:file-ignore SA1019 grpc.DialContext is retained in tests to match bridge integration coverage on grpc 1.x.
+
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/monstercameron/grpc-tunnel/examples/_shared/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// storeAuthTestPrincipalKey stores the authenticated principal on tunnel contexts in tests.
+type storeAuthTestPrincipalKey struct{}
+
+// buildAuthTestAuthenticator accepts a bearer token of "good", forbids "blocked", and rejects everything else.
+func buildAuthTestAuthenticator(parseR *http.Request) (context.Context, error) {
+	switch parseR.Header.Get("Authorization") {
+	case "Bearer good":
+		return context.WithValue(parseR.Context(), storeAuthTestPrincipalKey{}, "alice"), nil
+	case "Bearer blocked":
+		return nil, fmt.Errorf("principal blocked: %w", ErrPermissionDenied)
+	default:
+		return nil, ErrUnauthenticated
+	}
+}
+
+// storeAuthTestTransport records the principal seen on proxied requests before delegating.
+type storeAuthTestTransport struct {
+	getNext      http.RoundTripper
+	getPrincipal atomic.Value
+}
+
+// RoundTrip records the request principal and forwards the request.
+func (parseTransport *storeAuthTestTransport) RoundTrip(parseRequest *http.Request) (*http.Response, error) {
+	if parseValue, isFoundValue := parseRequest.Context().Value(storeAuthTestPrincipalKey{}).(string); isFoundValue {
+		parseTransport.getPrincipal.Store(parseValue)
+	}
+	return parseTransport.getNext.RoundTrip(parseRequest)
+}
+
+// TestHandlerAuthenticate_RejectsUpgrade verifies failed authentication returns 401 or 403 before upgrade.
+func TestHandlerAuthenticate_RejectsUpgrade(parseT *testing.T) {
+	parseHandler := NewHandler(Config{TargetAddress: "localhost:50051", Authenticate: buildAuthTestAuthenticator})
+
+	parseTests := []struct {
+		parseAuthorization string
+		parseWantStatus    int
+	}{
+		{parseAuthorization: "", parseWantStatus: http.StatusUnauthorized},
+		{parseAuthorization: "Bearer blocked", parseWantStatus: http.StatusForbidden},
+	}
+	for _, parseTestCase := range parseTests {
+		parseRequest := httptest.NewRequest(http.MethodGet, "/", nil)
+		parseRequest.Header.Set("Authorization", parseTestCase.parseAuthorization)
+		parseRecorder := httptest.NewRecorder()
+		parseHandler.ServeHTTP(parseRecorder, parseRequest)
+		if parseRecorder.Code != parseTestCase.parseWantStatus {
+			parseT.Fatalf("ServeHTTP(%q) status = %d, want %d", parseTestCase.parseAuthorization, parseRecorder.Code, parseTestCase.parseWantStatus)
+		}
+	}
+}
+
+// TestHandlerAuthenticate_ContextReachesProxiedRequests verifies the Authenticate context is the base of proxied requests.
+func TestHandlerAuthenticate_ContextReachesProxiedRequests(parseT *testing.T) {
+	parseBackendAddress, _ := buildPoolTestBackend(parseT, "auth")
+	parseHandler := NewHandler(Config{TargetAddress: parseBackendAddress, Authenticate: buildAuthTestAuthenticator})
+	if parseHandler.initErr != nil {
+		parseT.Fatalf("NewHandler() error: %v", parseHandler.initErr)
+	}
+	parseTransport := &storeAuthTestTransport{getNext: parseHandler.proxy.Transport}
+	parseHandler.proxy.Transport = parseTransport
+
+	parseAuthorizedHandler := http.HandlerFunc(func(parseW http.ResponseWriter, parseR *http.Request) {
+		parseR.Header.Set("Authorization", "Bearer good")
+		parseHandler.ServeHTTP(parseW, parseR)
+	})
+	parseResp, parseErr := callTransportTestBridge(parseT, parseAuthorizedHandler)
+	if parseErr != nil {
+		parseT.Fatalf("CreateTodo() error: %v", parseErr)
+	}
+	if parseResp.Todo.Id != "auth" {
+		parseT.Fatalf("CreateTodo() id = %q, want %q", parseResp.Todo.Id, "auth")
+	}
+	if parseValue, _ := parseTransport.getPrincipal.Load().(string); parseValue != "alice" {
+		parseT.Fatalf("proxied request principal = %q, want %q", parseValue, "alice")
+	}
+}
+
+// TestHandlerAuthorize_DeniesBeforeBackend verifies Authorize errors map to gRPC codes without reaching a backend.
+func TestHandlerAuthorize_DeniesBeforeBackend(parseT *testing.T) {
+	parseBackendAddress, _ := buildPoolTestBackend(parseT, "authz")
+	var parseMethod atomic.Value
+	parseAuthorizeErr := error(ErrUnauthenticated)
+	parseHandler := NewHandler(Config{
+		TargetAddress: parseBackendAddress,
+		Authorize: func(parseCtx context.Context, parseUpgrade *http.Request, parseFullMethod string) error {
+			parseMethod.Store(parseFullMethod)
+			return parseAuthorizeErr
+		},
+	})
+	if parseHandler.initErr != nil {
+		parseT.Fatalf("NewHandler() error: %v", parseHandler.initErr)
+	}
+	parseTransport := &storeExposureTestTransport{getNext: parseHandler.proxy.Transport}
+	parseHandler.proxy.Transport = parseTransport
+
+	parseTests := []struct {
+		parseErr      error
+		parseWantCode codes.Code
+	}{
+		{parseErr: ErrUnauthenticated, parseWantCode: codes.Unauthenticated},
+		{parseErr: fmt.Errorf("role missing"), parseWantCode: codes.PermissionDenied},
+		{parseErr: status.Error(codes.ResourceExhausted, "quota"), parseWantCode: codes.ResourceExhausted},
+	}
+	for _, parseTestCase := range parseTests {
+		parseAuthorizeErr = parseTestCase.parseErr
+		if _, parseErr := callTransportTestBridge(parseT, parseHandler); status.Code(parseErr) != parseTestCase.parseWantCode {
+			parseT.Fatalf("CreateTodo() error = %v, want %v", parseErr, parseTestCase.parseWantCode)
+		}
+	}
+	if parseCalls := parseTransport.getCalls.Load(); parseCalls != 0 {
+		parseT.Fatalf("backend requests = %d, want 0", parseCalls)
+	}
+	if parseValue, _ := parseMethod.Load().(string); parseValue != "/TodoService/CreateTodo" {
+		parseT.Fatalf("Authorize() method = %q, want %q", parseValue, "/TodoService/CreateTodo")
+	}
+
+	parseAuthorizeErr = nil
+	if _, parseErr := callTransportTestBridge(parseT, parseHandler); parseErr != nil {
+		parseT.Fatalf("CreateTodo() allowed error: %v", parseErr)
+	}
+}
+
+// TestHandlerAuthExpiryGrace_ClosesTunnel verifies streams still running AuthExpiryGrace after the
+// Authenticate context ends are cut off.
+func TestHandlerAuthExpiryGrace_ClosesTunnel(parseT *testing.T) {
+	if parseErr := getHandlerConfigError(Config{AuthExpiryGrace: -time.Second}); parseErr == nil {
+		parseT.Fatal("getHandlerConfigError(negative AuthExpiryGrace) = nil, want error")
+	}
+
+	parseBackendListener, parseErr := net.Listen("tcp", "127.0.0.1:0")
+	if parseErr != nil {
+		parseT.Fatalf("Listen() error: %v", parseErr)
+	}
+	parseService := &buildDrainTestTodoService{
+		getStreamStarted: make(chan struct{}, 1),
+		getStreamRelease: make(chan struct{}),
+	}
+	parseBackendServer := grpc.NewServer()
+	proto.RegisterTodoServiceServer(parseBackendServer, parseService)
+	go func() {
+		_ = parseBackendServer.Serve(parseBackendListener)
+	}()
+	defer parseBackendServer.Stop()
+
+	parseHandler := NewHandler(Config{
+		TargetAddress: parseBackendListener.Addr().String(),
+		Authenticate: func(parseR *http.Request) (context.Context, error) {
+			parseAuthCtx, clearAuth := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			parseT.Cleanup(clearAuth)
+			return parseAuthCtx, nil
+		},
+		AuthExpiryGrace: 300 * time.Millisecond,
+	})
+	if parseHandler.initErr != nil {
+		parseT.Fatalf("NewHandler() error: %v", parseHandler.initErr)
+	}
+	parseBridgeServer := httptest.NewServer(parseHandler)
+	defer parseBridgeServer.Close()
+
+	parseCtx, clearCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer clearCtx()
+	parseClientConn, parseStream := startDrainTestStream(parseT, parseCtx, parseService, "ws"+strings.TrimPrefix(parseBridgeServer.URL, "http"))
+	defer parseClientConn.Close()
+
+	if _, parseErr := parseStream.Recv(); status.Code(parseErr) != codes.Unavailable {
+		parseT.Fatalf("Recv() after grace error = %v, want Unavailable", parseErr)
+	}
+}
@@ -0,0 +1,241 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/healthmonitor"
+	"google.golang.org/grpc"
+	grpc_health_v1 "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const parseDefaultHealthCheckTimeout = time.Second
+
+// LoadBalancingPolicy selects how proxied HTTP/2 requests are spread across Config.Targets.
+type LoadBalancingPolicy string
+
+const (
+	// LoadBalancingRoundRobin rotates requests across healthy targets. This is the default.
+	LoadBalancingRoundRobin LoadBalancingPolicy = "round_robin"
+	// LoadBalancingLeastOutstanding sends each request to the healthy target with the fewest in-flight requests.
+	LoadBalancingLeastOutstanding LoadBalancingPolicy = "least_outstanding"
+	// LoadBalancingStickyTunnel pins every request from one tunnel to the same healthy target.
+	// A pinned target that becomes unhealthy is replaced on the next request.
+	LoadBalancingStickyTunnel LoadBalancingPolicy = "sticky_tunnel"
+)
+
+// handlerBackendContextKey stores the selected backend on a proxied request context.
+type handlerBackendContextKey struct{}
+
+// handlerBackend stores one backend target with its live load and health state.
+type handlerBackend struct {
+	getTargetURL   *url.URL
+	getOutstanding atomic.Int64
+	isHealthy      atomic.Bool
+	getHealthConn  *grpc.ClientConn
+}
+
+// handlerBackendPool balances proxied requests across configured backend targets.
+type handlerBackendPool struct {
+	setPolicy         LoadBalancingPolicy
+	setLogger         Logger
+	storeBackends     []*handlerBackend
+	getNextIndex      atomic.Uint64
+	setStopOnce       sync.Once
+	getStopSignal     chan struct{}
+	getHealth         *healthmonitor.Reporter
+	getHealthServices []string
+}
+
+// buildHandlerBackendPool creates a backend pool with every target initially admitted.
+func buildHandlerBackendPool(parseConfig Config, parseTargetURLs []*url.URL) *handlerBackendPool {
+	parsePolicy := parseConfig.LoadBalancingPolicy
+	if parsePolicy == "" {
+		parsePolicy = LoadBalancingRoundRobin
+	}
+
+	parsePool := &handlerBackendPool{
+		setPolicy:         parsePolicy,
+		setLogger:         parseConfig.Logger,
+		storeBackends:     make([]*handlerBackend, 0, len(parseTargetURLs)),
+		getStopSignal:     make(chan struct{}),
+		getHealth:         parseConfig.HealthMonitor.NewReporter(),
+		getHealthServices: parseConfig.HealthServices,
+	}
+	for _, parseTargetURL := range parseTargetURLs {
+		parseBackend := &handlerBackend{getTargetURL: parseTargetURL}
+		parseBackend.isHealthy.Store(true)
+		parsePool.storeBackends = append(parsePool.storeBackends, parseBackend)
+	}
+	return parsePool
+}
+
+// pickHandlerBackend selects a healthy backend for one proxied request, or nil when none are healthy.
+func (parsePool *handlerBackendPool) pickHandlerBackend(parseTunnel *handlerTunnel) *handlerBackend {
+	if parsePool == nil || len(parsePool.storeBackends) == 0 {
+		return nil
+	}
+
+	if parsePool.setPolicy == LoadBalancingStickyTunnel && parseTunnel != nil {
+		parsePinnedBackend := parseTunnel.getPinnedBackend.Load()
+		if parsePinnedBackend != nil && parsePinnedBackend.isHealthy.Load() {
+			return parsePinnedBackend
+		}
+		parseBackend := parsePool.pickHandlerRoundRobinBackend()
+		if parseBackend != nil {
+			parseTunnel.getPinnedBackend.Store(parseBackend)
+		}
+		return parseBackend
+	}
+
+	if parsePool.setPolicy == LoadBalancingLeastOutstanding {
+		return parsePool.pickHandlerLeastOutstandingBackend()
+	}
+	return parsePool.pickHandlerRoundRobinBackend()
+}
+
+// pickHandlerRoundRobinBackend returns the next healthy backend in rotation.
+func (parsePool *handlerBackendPool) pickHandlerRoundRobinBackend() *handlerBackend {
+	parseBackendCount := uint64(len(parsePool.storeBackends))
+	parseStartIndex := parsePool.getNextIndex.Add(1) - 1
+	for parseOffset := uint64(0); parseOffset < parseBackendCount; parseOffset++ {
+		parseBackend := parsePool.storeBackends[(parseStartIndex+parseOffset)%parseBackendCount]
+		if parseBackend.isHealthy.Load() {
+			return parseBackend
+		}
+	}
+	return nil
+}
+
+// pickHandlerLeastOutstandingBackend returns the healthy backend with the fewest in-flight requests.
+// Ties rotate so equal backends share load.
+func (parsePool *handlerBackendPool) pickHandlerLeastOutstandingBackend() *handlerBackend {
+	parseBackendCount := uint64(len(parsePool.storeBackends))
+	parseStartIndex := parsePool.getNextIndex.Add(1) - 1
+	var parseSelected *handlerBackend
+	for parseOffset := uint64(0); parseOffset < parseBackendCount; parseOffset++ {
+		parseBackend := parsePool.storeBackends[(parseStartIndex+parseOffset)%parseBackendCount]
+		if !parseBackend.isHealthy.Load() {
+			continue
+		}
+		if parseSelected == nil || parseBackend.getOutstanding.Load() < parseSelected.getOutstanding.Load() {
+			parseSelected = parseBackend
+		}
+	}
+	return parseSelected
+}
+
+// startHandlerHealthChecks probes every backend with grpc.health.v1 on the configured interval.
+func (parsePool *handlerBackendPool) startHandlerHealthChecks(parseConfig Config, buildDialOptions func(*url.URL) []grpc.DialOption) error {
+	if parsePool == nil || parseConfig.HealthCheckInterval <= 0 {
+		return nil
+	}
+
+	for _, parseBackend := range parsePool.storeBackends {
+		parseHealthConn, parseErr := grpc.NewClient(getHandlerHealthTarget(parseBackend.getTargetURL), buildDialOptions(parseBackend.getTargetURL)...)
+		if parseErr != nil {
+			parsePool.stopHandlerHealthChecks()
+			return fmt.Errorf("bridge: health check client for target %q: %w", getBridgeTargetName(parseBackend.getTargetURL), parseErr)
+		}
+		parseBackend.getHealthConn = parseHealthConn
+	}
+
+	parseTimeout := parseConfig.HealthCheckTimeout
+	if parseTimeout <= 0 {
+		parseTimeout = parseDefaultHealthCheckTimeout
+	}
+	go func() {
+		parseTicker := time.NewTicker(parseConfig.HealthCheckInterval)
+		defer parseTicker.Stop()
+		for {
+			parsePool.checkHandlerBackends(parseConfig.HealthCheckService, parseTimeout)
+			select {
+			case <-parseTicker.C:
+			case <-parsePool.getStopSignal:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// checkHandlerBackends runs one health probe round concurrently across all backends and reports the
+// pool's readiness and HealthServices to the health monitor.
+func (parsePool *handlerBackendPool) checkHandlerBackends(parseService string, parseTimeout time.Duration) {
+	var parseWaitGroup sync.WaitGroup
+	var parseServingLock sync.Mutex
+	parseServing := make(map[string]bool, len(parsePool.getHealthServices))
+	for _, parseBackend := range parsePool.storeBackends {
+		if parseBackend.getHealthConn == nil {
+			continue
+		}
+		parseWaitGroup.Add(1)
+		go func(parseBackend *handlerBackend) {
+			defer parseWaitGroup.Done()
+			parsePool.storeHandlerBackendHealth(parseBackend, checkHandlerBackendHealth(parseBackend.getHealthConn, parseService, parseTimeout))
+			for _, parseHealthService := range parsePool.getHealthServices {
+				isServing := checkHandlerBackendHealth(parseBackend.getHealthConn, parseHealthService, parseTimeout) == nil
+				parseServingLock.Lock()
+				parseServing[parseHealthService] = parseServing[parseHealthService] || isServing
+				parseServingLock.Unlock()
+			}
+		}(parseBackend)
+	}
+	parseWaitGroup.Wait()
+
+	isAnyHealthy := false
+	for _, parseBackend := range parsePool.storeBackends {
+		isAnyHealthy = isAnyHealthy || parseBackend.isHealthy.Load()
+	}
+	parsePool.getHealth.SetServing("", isAnyHealthy)
+	for _, parseHealthService := range parsePool.getHealthServices {
+		parsePool.getHealth.SetServing(parseHealthService, parseServing[parseHealthService])
+	}
+}
+
+// checkHandlerBackendHealth runs one grpc.health.v1 Check call and returns nil when the backend is SERVING.
+func checkHandlerBackendHealth(parseConn *grpc.ClientConn, parseService string, parseTimeout time.Duration) error {
+	parseCtx, clearCtx := context.WithTimeout(context.Background(), parseTimeout)
+	defer clearCtx()
+
+	parseResponse, parseErr := grpc_health_v1.NewHealthClient(parseConn).Check(parseCtx, &grpc_health_v1.HealthCheckRequest{Service: parseService})
+	if parseErr != nil {
+		return parseErr
+	}
+	if parseResponse.GetStatus() != grpc_health_v1.HealthCheckResponse_SERVING {
+		return fmt.Errorf("health status %s", parseResponse.GetStatus())
+	}
+	return nil
+}
+
+// storeHandlerBackendHealth ejects or re-admits a backend and logs state transitions.
+func (parsePool *handlerBackendPool) storeHandlerBackendHealth(parseBackend *handlerBackend, parseErr error) {
+	isHealthy := parseErr == nil
+	if parseBackend.isHealthy.Swap(isHealthy) == isHealthy {
+		return
+	}
+	if isHealthy {
+		logBridgeEvent(parsePool.setLogger, "INFO", "backend_readmitted", nil, nil, fmt.Sprintf("Backend %q passed health check and was re-admitted", getBridgeTargetName(parseBackend.getTargetURL)))
+		return
+	}
+	logBridgeEvent(parsePool.setLogger, "WARN", "backend_ejected", nil, parseErr, fmt.Sprintf("Backend %q failed health check and was ejected", getBridgeTargetName(parseBackend.getTargetURL)))
+}
+
+// stopHandlerHealthChecks stops the probe loop and closes health check clients.
+func (parsePool *handlerBackendPool) stopHandlerHealthChecks() {
+	if parsePool == nil {
+		return
+	}
+	parsePool.setStopOnce.Do(func() {
+		close(parsePool.getStopSignal)
+		for _, parseBackend := range parsePool.storeBackends {
+			if parseBackend.getHealthConn != nil {
+				_ = parseBackend.getHealthConn.Close()
+			}
+		}
+	})
+}
@@ -0,0 +1,201 @@
+//lint:file-ignore SA1019 grpc.DialContext is retained in tests to match bridge integration coverage on grpc 1.x.
+
+package bridge
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/monstercameron/grpc-tunnel/examples/_shared/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	grpc_health_v1 "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// buildPoolTestTodoService reports which backend served each request.
+type buildPoolTestTodoService struct {
+	proto.UnimplementedTodoServiceServer
+	getBackendName string
+}
+
+// CreateTodo returns the serving backend name as the todo id.
+func (parseS buildPoolTestTodoService) CreateTodo(parseCtx context.Context, parseReq *proto.CreateTodoRequest) (*proto.CreateTodoResponse, error) {
+	return &proto.CreateTodoResponse{Todo: &proto.Todo{Id: parseS.getBackendName, Text: parseReq.Text}}, nil
+}
+
+// buildPoolTestBackend starts a named gRPC backend with a health service.
+func buildPoolTestBackend(parseT *testing.T, parseBackendName string) (string, *health.Server) {
+	parseT.Helper()
+
+	parseListener, parseErr := net.Listen("tcp", "127.0.0.1:0")
+	if parseErr != nil {
+		parseT.Fatalf("Listen() error: %v", parseErr)
+	}
+	parseHealthServer := health.NewServer()
+	parseServer := grpc.NewServer()
+	proto.RegisterTodoServiceServer(parseServer, buildPoolTestTodoService{getBackendName: parseBackendName})
+	grpc_health_v1.RegisterHealthServer(parseServer, parseHealthServer)
+	go func() {
+		_ = parseServer.Serve(parseListener)
+	}()
+	parseT.Cleanup(parseServer.Stop)
+	return parseListener.Addr().String(), parseHealthServer
+}
+
+// buildPoolTestURLs parses backend hosts into target URLs.
+func buildPoolTestURLs(parseHosts ...string) []*url.URL {
+	parseTargetURLs := make([]*url.URL, 0, len(parseHosts))
+	for _, parseHost := range parseHosts {
+		parseTargetURLs = append(parseTargetURLs, &url.URL{Scheme: "http", Host: parseHost})
+	}
+	return parseTargetURLs
+}
+
+// TestGetHandlerConfigError_LoadBalancingValidation verifies multi-target settings are validated.
+func TestGetHandlerConfigError_LoadBalancingValidation(parseT *testing.T) {
+	parseTests := []Config{
+		{TargetAddress: "localhost:1", Targets: []string{"localhost:2"}},
+		{Targets: []string{"localhost:1"}, LoadBalancingPolicy: "random"},
+		{Targets: []string{"localhost:1"}, HealthCheckInterval: -time.Second},
+		{Targets: []string{"localhost:1"}, HealthCheckTimeout: -time.Second},
+		{Targets: []string{"localhost:1"}, HealthServices: []string{"todo.TodoService"}},
+		{Targets: []string{"localhost:1"}, HealthCheckInterval: time.Second, HealthServices: []string{" "}},
+	}
+	for _, parseConfig := range parseTests {
+		if parseErr := getHandlerConfigError(parseConfig); parseErr == nil {
+			parseT.Fatalf("getHandlerConfigError(%#v) expected error, got nil", parseConfig)
+		}
+	}
+
+	parseH := NewHandler(Config{Targets: []string{"localhost:1", "ftp://localhost:2"}})
+	if parseH.initErr == nil {
+		parseT.Fatal("NewHandler() expected invalid target error, got nil")
+	}
+}
+
+// TestHandlerBackendPool_Policies verifies round-robin, least-outstanding, and sticky selection.
+func TestHandlerBackendPool_Policies(parseT *testing.T) {
+	parseRoundRobin := buildHandlerBackendPool(Config{}, buildPoolTestURLs("a:1", "b:1"))
+	parseFirst := parseRoundRobin.pickHandlerBackend(nil)
+	parseSecond := parseRoundRobin.pickHandlerBackend(nil)
+	if parseFirst == parseSecond {
+		parseT.Fatal("round robin picked the same backend twice in a row")
+	}
+
+	parseLeast := buildHandlerBackendPool(Config{LoadBalancingPolicy: LoadBalancingLeastOutstanding}, buildPoolTestURLs("a:1", "b:1"))
+	parseLeast.storeBackends[0].getOutstanding.Store(5)
+	for parseIndex := 0; parseIndex < 3; parseIndex++ {
+		if parseBackend := parseLeast.pickHandlerBackend(nil); parseBackend != parseLeast.storeBackends[1] {
+			parseT.Fatalf("least outstanding picked %q, want %q", parseBackend.getTargetURL.Host, "b:1")
+		}
+	}
+
+	parseSticky := buildHandlerBackendPool(Config{LoadBalancingPolicy: LoadBalancingStickyTunnel}, buildPoolTestURLs("a:1", "b:1"))
+	parseTunnel := &handlerTunnel{}
+	parsePinned := parseSticky.pickHandlerBackend(parseTunnel)
+	for parseIndex := 0; parseIndex < 3; parseIndex++ {
+		if parseSticky.pickHandlerBackend(parseTunnel) != parsePinned {
+			parseT.Fatal("sticky policy moved a healthy tunnel to another backend")
+		}
+	}
+	parsePinned.isHealthy.Store(false)
+	if parseRepinned := parseSticky.pickHandlerBackend(parseTunnel); parseRepinned == parsePinned || parseRepinned == nil {
+		parseT.Fatal("sticky policy did not re-pin away from an unhealthy backend")
+	}
+
+	for _, parseBackend := range parseRoundRobin.storeBackends {
+		parseBackend.isHealthy.Store(false)
+	}
+	if parseRoundRobin.pickHandlerBackend(nil) != nil {
+		parseT.Fatal("expected nil backend when every target is unhealthy")
+	}
+}
+
+// TestHandleBridgeMultiTargetHealthChecks verifies requests balance across targets and follow health transitions.
+func TestHandleBridgeMultiTargetHealthChecks(parseT *testing.T) {
+	parseAddressA, parseHealthA := buildPoolTestBackend(parseT, "a")
+	parseAddressB, parseHealthB := buildPoolTestBackend(parseT, "b")
+
+	parseHandler := NewHandler(Config{
+		Targets:             []string{parseAddressA, parseAddressB},
+		HealthCheckInterval: 20 * time.Millisecond,
+	})
+	if parseHandler.initErr != nil {
+		parseT.Fatalf("NewHandler() error: %v", parseHandler.initErr)
+	}
+	defer parseHandler.backendPool.stopHandlerHealthChecks()
+
+	parseBridgeServer := httptest.NewServer(parseHandler)
+	defer parseBridgeServer.Close()
+
+	parseCtx, clearCtx := context.WithTimeout(context.Background(), 5*time.Second)
+	defer clearCtx()
+	parseClientConn, parseErr := grpc.DialContext(
+		parseCtx,
+		"ignored:1234",
+		DialOption("ws"+strings.TrimPrefix(parseBridgeServer.URL, "http")),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if parseErr != nil {
+		parseT.Fatalf("DialContext() error: %v", parseErr)
+	}
+	defer parseClientConn.Close()
+	parseClient := proto.NewTodoServiceClient(parseClientConn)
+
+	collectBackends := func(parseCount int) map[string]int {
+		parseSeen := map[string]int{}
+		for parseIndex := 0; parseIndex < parseCount; parseIndex++ {
+			parseResp, parseErr := parseClient.CreateTodo(parseCtx, &proto.CreateTodoRequest{Text: "lb"})
+			if parseErr != nil {
+				parseT.Fatalf("CreateTodo() error: %v", parseErr)
+			}
+			parseSeen[parseResp.Todo.Id]++
+		}
+		return parseSeen
+	}
+
+	if parseSeen := collectBackends(4); parseSeen["a"] == 0 || parseSeen["b"] == 0 {
+		parseT.Fatalf("expected requests on both backends, got %v", parseSeen)
+	}
+
+	parseHealthB.SetServingStatus("", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	waitForPoolBackendHealth(parseT, parseHandler.backendPool.storeBackends[1], false)
+	if parseSeen := collectBackends(4); parseSeen["b"] != 0 {
+		parseT.Fatalf("expected ejected backend to receive no requests, got %v", parseSeen)
+	}
+
+	parseHealthB.SetServingStatus("", grpc_health_v1.HealthCheckResponse_SERVING)
+	waitForPoolBackendHealth(parseT, parseHandler.backendPool.storeBackends[1], true)
+	if parseSeen := collectBackends(4); parseSeen["b"] == 0 {
+		parseT.Fatalf("expected re-admitted backend to receive requests, got %v", parseSeen)
+	}
+
+	parseHealthA.SetServingStatus("", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	parseHealthB.SetServingStatus("", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	waitForPoolBackendHealth(parseT, parseHandler.backendPool.storeBackends[0], false)
+	waitForPoolBackendHealth(parseT, parseHandler.backendPool.storeBackends[1], false)
+	_, parseErr = parseClient.CreateTodo(parseCtx, &proto.CreateTodoRequest{Text: "lb"})
+	if status.Code(parseErr) != codes.Unavailable || status.Convert(parseErr).Message() != parseNoHealthyBackendMessage {
+		parseT.Fatalf("CreateTodo() with no healthy backend error = %v, want Unavailable %q", parseErr, parseNoHealthyBackendMessage)
+	}
+}
+
+// waitForPoolBackendHealth waits until a backend reaches the wanted health state.
+func waitForPoolBackendHealth(parseT *testing.T, parseBackend *handlerBackend, isWantHealthy bool) {
+	parseT.Helper()
+	parseDeadline := time.Now().Add(2 * time.Second)
+	for parseBackend.isHealthy.Load() != isWantHealthy {
+		if time.Now().After(parseDeadline) {
+			parseT.Fatalf("backend %q health = %v, want %v", parseBackend.getTargetURL.Host, !isWantHealthy, isWantHealthy)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,137 @@
+package bridge
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/net/http2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// handlerBackendTransport routes proxied requests to a plaintext h2c, TLS, or unix socket HTTP/2 transport by target.
+type handlerBackendTransport struct {
+	getPlaintextTransport http.RoundTripper
+	getTLSTransport       http.RoundTripper
+	storeUnixTransports   map[string]http.RoundTripper
+}
+
+// RoundTrip sends one proxied request using the transport that matches its target scheme.
+func (parseTransport *handlerBackendTransport) RoundTrip(parseRequest *http.Request) (*http.Response, error) {
+	if parseBackend, isFoundBackend := parseRequest.Context().Value(handlerBackendContextKey{}).(*handlerBackend); isFoundBackend &&
+		parseBackend.getTargetURL.Scheme == "unix" {
+		return parseTransport.storeUnixTransports[parseBackend.getTargetURL.Path].RoundTrip(parseRequest)
+	}
+	if parseRequest.URL != nil && parseRequest.URL.Scheme == "https" {
+		return parseTransport.getTLSTransport.RoundTrip(parseRequest)
+	}
+	return parseTransport.getPlaintextTransport.RoundTrip(parseRequest)
+}
+
+// buildHandlerBackendTransport creates the backend HTTP/2 transports used by the reverse proxy.
+// Each unix socket target gets its own transport because every socket shares the localhost authority.
+func buildHandlerBackendTransport(parseConfig Config, parseBackendDialer *net.Dialer, parseTargetURLs []*url.URL) http.RoundTripper {
+	parseUnixTransports := map[string]http.RoundTripper{}
+	for _, parseTargetURL := range parseTargetURLs {
+		if parseTargetURL.Scheme != "unix" {
+			continue
+		}
+		parseSocketPath := parseTargetURL.Path
+		parseUnixTransports[parseSocketPath] = buildHandlerBackendHTTP2Transport(parseConfig, &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(parseDialContext context.Context, parseNetwork string, parseAddr string, parseTLSConfig *tls.Config) (net.Conn, error) {
+				return parseBackendDialer.DialContext(parseDialContext, "unix", parseSocketPath)
+			},
+		})
+	}
+
+	return &handlerBackendTransport{
+		getPlaintextTransport: buildHandlerBackendHTTP2Transport(parseConfig, &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(parseDialContext context.Context, parseNetwork string, parseAddr string, parseTLSConfig *tls.Config) (net.Conn, error) {
+				return parseBackendDialer.DialContext(parseDialContext, parseNetwork, parseAddr)
+			},
+		}),
+		getTLSTransport: buildHandlerBackendHTTP2Transport(parseConfig, &http2.Transport{
+			TLSClientConfig: buildHandlerBackendTLSConfig(parseConfig),
+			DialTLSContext: func(parseDialContext context.Context, parseNetwork string, parseAddr string, parseTLSConfig *tls.Config) (net.Conn, error) {
+				return dialHandlerBackendTLS(parseDialContext, parseBackendDialer, parseNetwork, parseAddr, parseTLSConfig)
+			},
+		}),
+		storeUnixTransports: parseUnixTransports,
+	}
+}
+
+// buildHandlerBackendHTTP2Transport applies the HTTP/2 settings a client transport supports.
+// x/net/http2 transports size their own flow-control windows and follow the backend's stream limit.
+func buildHandlerBackendHTTP2Transport(parseConfig Config, parseTransport *http2.Transport) *http2.Transport {
+	parseSettings := buildHTTP2Settings(parseConfig.HTTP2Profile, parseConfig.HTTP2)
+	parseTransport.MaxReadFrameSize = parseSettings.MaxReadFrameSize
+	parseTransport.MaxHeaderListSize = parseSettings.MaxHeaderListSize
+	parseTransport.IdleConnTimeout = parseSettings.IdleTimeout
+	return parseTransport
+}
+
+// buildHandlerBackendTLSConfig clones the configured backend TLS settings and requires ALPN h2.
+func buildHandlerBackendTLSConfig(parseConfig Config) *tls.Config {
+	parseTLSConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if parseConfig.BackendTLSConfig != nil {
+		parseTLSConfig = parseConfig.BackendTLSConfig.Clone()
+	}
+	parseTLSConfig.NextProtos = []string{http2.NextProtoTLS}
+	return parseTLSConfig
+}
+
+// dialHandlerBackendTLS dials a backend with the bridge dialer and completes a TLS handshake that negotiates h2.
+func dialHandlerBackendTLS(parseDialContext context.Context, parseBackendDialer *net.Dialer, parseNetwork string, parseAddr string, parseTLSConfig *tls.Config) (net.Conn, error) {
+	parseTLSDialer := &tls.Dialer{
+		NetDialer: parseBackendDialer,
+		Config:    parseTLSConfig,
+	}
+	parseConn, parseErr := parseTLSDialer.DialContext(parseDialContext, parseNetwork, parseAddr)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
+	parseTLSConn, isTLSConn := parseConn.(*tls.Conn)
+	if !isTLSConn {
+		_ = parseConn.Close()
+		return nil, fmt.Errorf("bridge: backend %q did not return a TLS connection", parseAddr)
+	}
+	if parseProtocol := parseTLSConn.ConnectionState().NegotiatedProtocol; parseProtocol != http2.NextProtoTLS {
+		_ = parseConn.Close()
+		return nil, fmt.Errorf("bridge: backend %q negotiated ALPN protocol %q, want %q", parseAddr, parseProtocol, http2.NextProtoTLS)
+	}
+	return parseConn, nil
+}
+
+// buildHandlerHealthDialOptions builds dial options for backend health probes that match the proxy transport.
+func buildHandlerHealthDialOptions(parseConfig Config, parseBackendDialer *net.Dialer, parseTargetURL *url.URL) []grpc.DialOption {
+	parseCredentials := insecure.NewCredentials()
+	if parseTargetURL.Scheme == "https" {
+		parseCredentials = credentials.NewTLS(buildHandlerBackendTLSConfig(parseConfig))
+	}
+	return []grpc.DialOption{
+		grpc.WithTransportCredentials(parseCredentials),
+		grpc.WithContextDialer(func(parseCtx context.Context, parseAddr string) (net.Conn, error) {
+			if parseTargetURL.Scheme == "unix" {
+				return parseBackendDialer.DialContext(parseCtx, "unix", parseTargetURL.Path)
+			}
+			return parseBackendDialer.DialContext(parseCtx, "tcp", parseAddr)
+		}),
+	}
+}
+
+// getHandlerHealthTarget returns the grpc client target used to probe one backend.
+// Unix socket targets resolve to localhost and are dialed through the socket path by the context dialer.
+func getHandlerHealthTarget(parseTargetURL *url.URL) string {
+	if parseTargetURL.Scheme == "unix" {
+		return "passthrough:///localhost"
+	}
+	return parseTargetURL.Host
+}
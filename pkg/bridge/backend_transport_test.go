@@ -0,0 +1,314 @@
+//lint:file-ignore SA1019 grpc.DialContext is retained in tests to match bridge integration coverage on grpc 1.x.
+
+package bridge
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/monstercameron/grpc-tunnel/examples/_shared/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	grpc_health_v1 "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// storeTransportTestPKI holds a test CA plus server and client certificates it issued.
+type storeTransportTestPKI struct {
+	getRootPool   *x509.CertPool
+	getServerCert tls.Certificate
+	getClientCert tls.Certificate
+	getServerName string
+}
+
+// buildTransportTestCertificate issues a certificate signed by the parent, or self-signed when parent is nil.
+func buildTransportTestCertificate(parseT *testing.T, parseTemplate *x509.Certificate, parseParent *x509.Certificate, parseParentKey *ecdsa.PrivateKey) (tls.Certificate, *x509.Certificate, *ecdsa.PrivateKey) {
+	parseT.Helper()
+
+	parseKey, parseErr := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if parseErr != nil {
+		parseT.Fatalf("GenerateKey() error: %v", parseErr)
+	}
+	if parseParent == nil {
+		parseParent = parseTemplate
+		parseParentKey = parseKey
+	}
+	parseDER, parseErr := x509.CreateCertificate(rand.Reader, parseTemplate, parseParent, &parseKey.PublicKey, parseParentKey)
+	if parseErr != nil {
+		parseT.Fatalf("CreateCertificate() error: %v", parseErr)
+	}
+	parseCert, parseErr := x509.ParseCertificate(parseDER)
+	if parseErr != nil {
+		parseT.Fatalf("ParseCertificate() error: %v", parseErr)
+	}
+	return tls.Certificate{Certificate: [][]byte{parseDER}, PrivateKey: parseKey}, parseCert, parseKey
+}
+
+// buildTransportTestPKI creates a CA with a server certificate for backend.test and a client certificate.
+func buildTransportTestPKI(parseT *testing.T) storeTransportTestPKI {
+	parseT.Helper()
+
+	parseNotBefore := time.Now().Add(-time.Hour)
+	parseNotAfter := time.Now().Add(time.Hour)
+	_, parseCACert, parseCAKey := buildTransportTestCertificate(parseT, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "bridge test ca"},
+		NotBefore:             parseNotBefore,
+		NotAfter:              parseNotAfter,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil, nil)
+	parseServerCert, _, _ := buildTransportTestCertificate(parseT, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "backend.test"},
+		DNSNames:     []string{"backend.test"},
+		NotBefore:    parseNotBefore,
+		NotAfter:     parseNotAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, parseCACert, parseCAKey)
+	parseClientCert, _, _ := buildTransportTestCertificate(parseT, &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "bridge"},
+		NotBefore:    parseNotBefore,
+		NotAfter:     parseNotAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, parseCACert, parseCAKey)
+
+	parseRootPool := x509.NewCertPool()
+	parseRootPool.AddCert(parseCACert)
+	return storeTransportTestPKI{
+		getRootPool:   parseRootPool,
+		getServerCert: parseServerCert,
+		getClientCert: parseClientCert,
+		getServerName: "backend.test",
+	}
+}
+
+// buildTransportTestBackend starts a gRPC backend that requires client certificates from the test CA.
+func buildTransportTestBackend(parseT *testing.T, parsePKI storeTransportTestPKI) string {
+	parseT.Helper()
+
+	parseListener, parseErr := net.Listen("tcp", "127.0.0.1:0")
+	if parseErr != nil {
+		parseT.Fatalf("Listen() error: %v", parseErr)
+	}
+	parseServer := grpc.NewServer(grpc.Creds(credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{parsePKI.getServerCert},
+		ClientCAs:    parsePKI.getRootPool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	})))
+	proto.RegisterTodoServiceServer(parseServer, buildPoolTestTodoService{getBackendName: "tls"})
+	grpc_health_v1.RegisterHealthServer(parseServer, health.NewServer())
+	go func() {
+		_ = parseServer.Serve(parseListener)
+	}()
+	parseT.Cleanup(parseServer.Stop)
+	return parseListener.Addr().String()
+}
+
+// callTransportTestBridge sends one CreateTodo through a bridge handler and returns the result.
+func callTransportTestBridge(parseT *testing.T, parseHandler http.Handler) (*proto.CreateTodoResponse, error) {
+	parseT.Helper()
+
+	parseBridgeServer := httptest.NewServer(parseHandler)
+	defer parseBridgeServer.Close()
+
+	parseCtx, clearCtx := context.WithTimeout(context.Background(), 5*time.Second)
+	defer clearCtx()
+	parseClientConn, parseErr := grpc.DialContext(
+		parseCtx,
+		"ignored:1234",
+		DialOption("ws"+strings.TrimPrefix(parseBridgeServer.URL, "http")),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if parseErr != nil {
+		parseT.Fatalf("DialContext() error: %v", parseErr)
+	}
+	defer parseClientConn.Close()
+	return proto.NewTodoServiceClient(parseClientConn).CreateTodo(parseCtx, &proto.CreateTodoRequest{Text: "tls"})
+}
+
+// TestHandleBridgeBackendMutualTLS verifies the bridge negotiates mTLS with h2 and health-checks over TLS.
+func TestHandleBridgeBackendMutualTLS(parseT *testing.T) {
+	parsePKI := buildTransportTestPKI(parseT)
+	parseBackendAddress := buildTransportTestBackend(parseT, parsePKI)
+
+	parseHandler := NewHandler(Config{
+		TargetAddress: parseBackendAddress,
+		BackendTLSConfig: &tls.Config{
+			RootCAs:      parsePKI.getRootPool,
+			Certificates: []tls.Certificate{parsePKI.getClientCert},
+			ServerName:   parsePKI.getServerName,
+			MinVersion:   tls.VersionTLS12,
+		},
+		HealthCheckInterval: 20 * time.Millisecond,
+	})
+	if parseHandler.initErr != nil {
+		parseT.Fatalf("NewHandler() error: %v", parseHandler.initErr)
+	}
+	defer parseHandler.backendPool.stopHandlerHealthChecks()
+	if parseScheme := parseHandler.backendPool.storeBackends[0].getTargetURL.Scheme; parseScheme != "https" {
+		parseT.Fatalf("target scheme = %q, want https", parseScheme)
+	}
+
+	parseResp, parseErr := callTransportTestBridge(parseT, parseHandler)
+	if parseErr != nil {
+		parseT.Fatalf("CreateTodo() error: %v", parseErr)
+	}
+	if parseResp.Todo.Id != "tls" {
+		parseT.Fatalf("CreateTodo() id = %q, want %q", parseResp.Todo.Id, "tls")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if !parseHandler.backendPool.storeBackends[0].isHealthy.Load() {
+		parseT.Fatal("TLS backend was ejected by health checks")
+	}
+}
+
+// TestHandleBridgeBackendTLSRequiresClientCertificate verifies RPCs fail when the backend demands a missing client certificate.
+func TestHandleBridgeBackendTLSRequiresClientCertificate(parseT *testing.T) {
+	parsePKI := buildTransportTestPKI(parseT)
+	parseBackendAddress := buildTransportTestBackend(parseT, parsePKI)
+
+	parseHandler := NewHandler(Config{
+		TargetAddress: "https://" + parseBackendAddress,
+		BackendTLSConfig: &tls.Config{
+			RootCAs:    parsePKI.getRootPool,
+			ServerName: parsePKI.getServerName,
+			MinVersion: tls.VersionTLS12,
+		},
+	})
+	if parseHandler.initErr != nil {
+		parseT.Fatalf("NewHandler() error: %v", parseHandler.initErr)
+	}
+
+	parseReq := httptest.NewRequest(http.MethodPost, "https://"+parseBackendAddress+"/proto.TodoService/CreateTodo", nil)
+	parseReq.Header.Set("Content-Type", "application/grpc")
+	parseResp, parseErr := parseHandler.proxy.Transport.RoundTrip(parseReq)
+	if parseErr == nil {
+		_ = parseResp.Body.Close()
+		parseT.Fatal("RoundTrip() expected TLS error without client certificate, got nil")
+	}
+}
+
+// TestGetBridgeBackendTransportPolicyError_AllowsTLS verifies strict loopback policy accepts encrypted remote targets.
+func TestGetBridgeBackendTransportPolicyError_AllowsTLS(parseT *testing.T) {
+	parseHandler := NewHandler(Config{
+		TargetAddress:                "https://10.0.0.1:443",
+		ShouldRequireLoopbackBackend: true,
+	})
+	if parseHandler.initErr != nil {
+		parseT.Fatalf("NewHandler(https target) error: %v", parseHandler.initErr)
+	}
+
+	parseHandler = NewHandler(Config{
+		TargetAddress:                "10.0.0.1:443",
+		BackendTLSConfig:             &tls.Config{MinVersion: tls.VersionTLS12},
+		ShouldRequireLoopbackBackend: true,
+	})
+	if parseHandler.initErr != nil {
+		parseT.Fatalf("NewHandler(BackendTLSConfig) error: %v", parseHandler.initErr)
+	}
+
+	parseHandler = NewHandler(Config{
+		TargetAddress:                "http://10.0.0.1:443",
+		BackendTLSConfig:             &tls.Config{MinVersion: tls.VersionTLS12},
+		ShouldRequireLoopbackBackend: true,
+	})
+	if parseHandler.initErr == nil {
+		parseT.Fatal("NewHandler(explicit http target) expected policy error, got nil")
+	}
+}
+
+// buildUnixTestBackend starts a gRPC backend with a health service on a unix socket address.
+func buildUnixTestBackend(parseT *testing.T, parseSocketAddress string) {
+	parseT.Helper()
+
+	parseListener, parseErr := net.Listen("unix", parseSocketAddress)
+	if parseErr != nil {
+		parseT.Fatalf("Listen(unix) error: %v", parseErr)
+	}
+	parseServer := grpc.NewServer()
+	proto.RegisterTodoServiceServer(parseServer, buildPoolTestTodoService{getBackendName: "unix"})
+	grpc_health_v1.RegisterHealthServer(parseServer, health.NewServer())
+	go func() {
+		_ = parseServer.Serve(parseListener)
+	}()
+	parseT.Cleanup(parseServer.Stop)
+}
+
+// TestHandleBridgeUnixSocketBackend verifies the bridge proxies and health-checks through a unix socket file.
+func TestHandleBridgeUnixSocketBackend(parseT *testing.T) {
+	parseSocketDir, parseErr := os.MkdirTemp("", "bridge-unix")
+	if parseErr != nil {
+		parseT.Fatalf("MkdirTemp() error: %v", parseErr)
+	}
+	defer os.RemoveAll(parseSocketDir)
+	parseSocketPath := filepath.Join(parseSocketDir, "grpc.sock")
+	buildUnixTestBackend(parseT, parseSocketPath)
+
+	parseHandler := NewHandler(Config{
+		TargetAddress:                "unix://" + parseSocketPath,
+		ShouldRequireLoopbackBackend: true,
+		HealthCheckInterval:          20 * time.Millisecond,
+	})
+	if parseHandler.initErr != nil {
+		parseT.Fatalf("NewHandler() error: %v", parseHandler.initErr)
+	}
+	defer parseHandler.backendPool.stopHandlerHealthChecks()
+
+	parseResp, parseErr := callTransportTestBridge(parseT, parseHandler)
+	if parseErr != nil {
+		parseT.Fatalf("CreateTodo() error: %v", parseErr)
+	}
+	if parseResp.Todo.Id != "unix" {
+		parseT.Fatalf("CreateTodo() id = %q, want %q", parseResp.Todo.Id, "unix")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if !parseHandler.backendPool.storeBackends[0].isHealthy.Load() {
+		parseT.Fatal("unix socket backend was ejected by health checks")
+	}
+}
+
+// TestHandleBridgeAbstractUnixSocketBackend verifies the bridge proxies through a Linux abstract unix socket.
+func TestHandleBridgeAbstractUnixSocketBackend(parseT *testing.T) {
+	if runtime.GOOS != "linux" {
+		parseT.Skip("abstract unix sockets are only available on Linux")
+	}
+	parseSocketName := "@grpc-tunnel-bridge-" + strings.ReplaceAll(parseT.Name(), "/", "-") + "-" + time.Now().Format("150405.000000000")
+	buildUnixTestBackend(parseT, parseSocketName)
+
+	parseHandler := NewHandler(Config{TargetAddress: "unix:" + parseSocketName})
+	if parseHandler.initErr != nil {
+		parseT.Fatalf("NewHandler() error: %v", parseHandler.initErr)
+	}
+
+	parseResp, parseErr := callTransportTestBridge(parseT, parseHandler)
+	if parseErr != nil {
+		parseT.Fatalf("CreateTodo() error: %v", parseErr)
+	}
+	if parseResp.Todo.Id != "unix" {
+		parseT.Fatalf("CreateTodo() id = %q, want %q", parseResp.Todo.Id, "unix")
+	}
+}
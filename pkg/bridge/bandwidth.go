@@ -0,0 +1,279 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const parseHandlerBandwidthScopeGlobal = "global"
+const parseHandlerBandwidthScopeClient = "client"
+const parseHandlerBandwidthScopeMethod = "method"
+const parseHandlerBandwidthDirectionRead = "read"
+const parseHandlerBandwidthDirectionWrite = "write"
+
+// getHandlerBandwidthLimitsError validates bandwidth limits.
+func getHandlerBandwidthLimitsError(parseLimits BandwidthLimits) error {
+	if parseErr := getHandlerRateLimitError("BandwidthLimits.Global", parseLimits.Global); parseErr != nil {
+		return parseErr
+	}
+	if parseErr := getHandlerRateLimitError("BandwidthLimits.PerClient", parseLimits.PerClient); parseErr != nil {
+		return parseErr
+	}
+	for parseMethod, parseLimit := range parseLimits.Methods {
+		if !strings.HasPrefix(parseMethod, "/") {
+			return fmt.Errorf("bridge: BandwidthLimits.Methods key %q must start with /", parseMethod)
+		}
+		if parseErr := getHandlerRateLimitError("BandwidthLimits.Methods["+parseMethod+"]", parseLimit); parseErr != nil {
+			return parseErr
+		}
+	}
+	return nil
+}
+
+// handlerBandwidthLimiter paces bytes through one token bucket. The bucket may go into debt, so
+// messages larger than the burst still pass at the configured rate.
+type handlerBandwidthLimiter struct {
+	setLimiterLock sync.Mutex
+	getLimit       RateLimit
+	getScope       string
+	getBucket      handlerTokenBucket
+}
+
+// reserveHandlerBandwidth takes tokens for a number of bytes and returns how long the caller must wait.
+func (parseLimiter *handlerBandwidthLimiter) reserveHandlerBandwidth(parseBytes int, parseNow time.Time) time.Duration {
+	parseLimiter.setLimiterLock.Lock()
+	defer parseLimiter.setLimiterLock.Unlock()
+	return parseLimiter.getBucket.reserveHandlerTokenBucket(parseLimiter.getLimit, parseNow, float64(parseBytes))
+}
+
+// handlerBandwidthLimiters holds the read and write limiters of one scope. Nil entries are unlimited.
+type handlerBandwidthLimiters struct {
+	getRead  *handlerBandwidthLimiter
+	getWrite *handlerBandwidthLimiter
+}
+
+// buildHandlerBandwidthLimiters creates read and write limiters, or none when the limit is disabled.
+func buildHandlerBandwidthLimiters(parseLimit RateLimit, parseScope string) handlerBandwidthLimiters {
+	if parseLimit.PerSecond <= 0 {
+		return handlerBandwidthLimiters{}
+	}
+	return handlerBandwidthLimiters{
+		getRead:  &handlerBandwidthLimiter{getLimit: parseLimit, getScope: parseScope},
+		getWrite: &handlerBandwidthLimiter{getLimit: parseLimit, getScope: parseScope},
+	}
+}
+
+// waitHandlerBandwidth reserves bytes from every limiter and sleeps for the longest delay, recording it
+// as throttle time of the limiting scope. It reports false when done closed before the delay ended.
+func waitHandlerBandwidth(parseContext context.Context, parseObservability *handlerObservability, parseLimiters []*handlerBandwidthLimiter, parseDirection string, parseBytes int, parseDone <-chan struct{}) bool {
+	if parseBytes <= 0 {
+		return true
+	}
+	parseNow := time.Now()
+	parseWait := time.Duration(0)
+	parseScope := ""
+	for _, parseLimiter := range parseLimiters {
+		if parseLimiter == nil {
+			continue
+		}
+		if parseDelay := parseLimiter.reserveHandlerBandwidth(parseBytes, parseNow); parseDelay > parseWait {
+			parseWait = parseDelay
+			parseScope = parseLimiter.getScope
+		}
+	}
+	if parseWait <= 0 {
+		return true
+	}
+
+	parseObservability.storeHandlerBandwidthThrottle(parseContext, parseScope, parseDirection, parseWait)
+	parseTimer := time.NewTimer(parseWait)
+	defer parseTimer.Stop()
+	select {
+	case <-parseTimer.C:
+		return true
+	case <-parseDone:
+		return false
+	}
+}
+
+// handlerBandwidthClient holds the limiters shared by the live tunnels of one client key.
+type handlerBandwidthClient struct {
+	getLimiters handlerBandwidthLimiters
+	getTunnels  int
+}
+
+// handlerBandwidthGuard owns the global and per-client bandwidth limiters of a handler.
+type handlerBandwidthGuard struct {
+	getLimits        BandwidthLimits
+	getGlobal        handlerBandwidthLimiters
+	getObservability *handlerObservability
+	setClientLock    sync.Mutex
+	storeClients     map[string]*handlerBandwidthClient
+}
+
+// buildHandlerBandwidthGuard creates a bandwidth guard, or nil when no bandwidth limit is configured.
+func buildHandlerBandwidthGuard(parseLimits BandwidthLimits, parseObservability *handlerObservability) *handlerBandwidthGuard {
+	if parseLimits.Global.PerSecond <= 0 && parseLimits.PerClient.PerSecond <= 0 && len(parseLimits.Methods) == 0 {
+		return nil
+	}
+	return &handlerBandwidthGuard{
+		getLimits:        parseLimits,
+		getGlobal:        buildHandlerBandwidthLimiters(parseLimits.Global, parseHandlerBandwidthScopeGlobal),
+		getObservability: parseObservability,
+		storeClients:     map[string]*handlerBandwidthClient{},
+	}
+}
+
+// reserveHandlerClientBandwidth returns the limiters of a client key, creating them for its first tunnel.
+func (parseGuard *handlerBandwidthGuard) reserveHandlerClientBandwidth(parseClientKey string) handlerBandwidthLimiters {
+	if parseGuard.getLimits.PerClient.PerSecond <= 0 {
+		return handlerBandwidthLimiters{}
+	}
+	parseGuard.setClientLock.Lock()
+	defer parseGuard.setClientLock.Unlock()
+	parseClient, isFoundClient := parseGuard.storeClients[parseClientKey]
+	if !isFoundClient {
+		parseClient = &handlerBandwidthClient{getLimiters: buildHandlerBandwidthLimiters(parseGuard.getLimits.PerClient, parseHandlerBandwidthScopeClient)}
+		parseGuard.storeClients[parseClientKey] = parseClient
+	}
+	parseClient.getTunnels++
+	return parseClient.getLimiters
+}
+
+// clearHandlerClientBandwidth forgets the limiters of a client key once its last tunnel ends.
+func (parseGuard *handlerBandwidthGuard) clearHandlerClientBandwidth(parseClientKey string) {
+	if parseGuard.getLimits.PerClient.PerSecond <= 0 {
+		return
+	}
+	parseGuard.setClientLock.Lock()
+	defer parseGuard.setClientLock.Unlock()
+	parseClient, isFoundClient := parseGuard.storeClients[parseClientKey]
+	if !isFoundClient {
+		return
+	}
+	parseClient.getTunnels--
+	if parseClient.getTunnels <= 0 {
+		delete(parseGuard.storeClients, parseClientKey)
+	}
+}
+
+// handlerConnBandwidth paces the reads and writes of one tunnel connection through the global and
+// per-client limiters.
+type handlerConnBandwidth struct {
+	getGuard       *handlerBandwidthGuard
+	getContext     context.Context
+	getClientKey   string
+	getReaders     []*handlerBandwidthLimiter
+	getWriters     []*handlerBandwidthLimiter
+	getCloseSignal chan struct{}
+	clearCloseOnce sync.Once
+}
+
+// buildHandlerConnBandwidth creates the pacing state of one tunnel, or nil when neither a global nor a
+// per-client limit is configured.
+func (parseGuard *handlerBandwidthGuard) buildHandlerConnBandwidth(parseContext context.Context, parseClientKey string) *handlerConnBandwidth {
+	if parseGuard == nil || (parseGuard.getLimits.Global.PerSecond <= 0 && parseGuard.getLimits.PerClient.PerSecond <= 0) {
+		return nil
+	}
+	parseClientLimiters := parseGuard.reserveHandlerClientBandwidth(parseClientKey)
+	return &handlerConnBandwidth{
+		getGuard:       parseGuard,
+		getContext:     parseContext,
+		getClientKey:   parseClientKey,
+		getReaders:     []*handlerBandwidthLimiter{parseGuard.getGlobal.getRead, parseClientLimiters.getRead},
+		getWriters:     []*handlerBandwidthLimiter{parseGuard.getGlobal.getWrite, parseClientLimiters.getWrite},
+		getCloseSignal: make(chan struct{}),
+	}
+}
+
+// waitHandlerConnBandwidth paces bytes moved in one direction. It reports false when the connection closed while waiting.
+func (parseBandwidth *handlerConnBandwidth) waitHandlerConnBandwidth(parseDirection string, parseBytes int) bool {
+	if parseBandwidth == nil {
+		return true
+	}
+	parseLimiters := parseBandwidth.getWriters
+	if parseDirection == parseHandlerBandwidthDirectionRead {
+		parseLimiters = parseBandwidth.getReaders
+	}
+	return waitHandlerBandwidth(parseBandwidth.getContext, parseBandwidth.getGuard.getObservability, parseLimiters, parseDirection, parseBytes, parseBandwidth.getCloseSignal)
+}
+
+// clearHandlerConnBandwidth wakes blocked reads and writes and releases the per-client limiters. It is safe to call more than once.
+func (parseBandwidth *handlerConnBandwidth) clearHandlerConnBandwidth() {
+	if parseBandwidth == nil {
+		return
+	}
+	parseBandwidth.clearCloseOnce.Do(func() {
+		close(parseBandwidth.getCloseSignal)
+		parseBandwidth.getGuard.clearHandlerClientBandwidth(parseBandwidth.getClientKey)
+	})
+}
+
+// handlerBandwidthBody paces the request body of one RPC through its method limiter.
+type handlerBandwidthBody struct {
+	io.ReadCloser
+	getRequest *http.Request
+	getGuard   *handlerBandwidthGuard
+	getLimiter *handlerBandwidthLimiter
+}
+
+// Read reads request bytes and then waits until the method rate allows them.
+func (parseBody *handlerBandwidthBody) Read(parseP []byte) (int, error) {
+	parseN, parseErr := parseBody.ReadCloser.Read(parseP)
+	parseContext := parseBody.getRequest.Context()
+	if !waitHandlerBandwidth(parseContext, parseBody.getGuard.getObservability, []*handlerBandwidthLimiter{parseBody.getLimiter}, parseHandlerBandwidthDirectionRead, parseN, parseContext.Done()) {
+		return parseN, parseContext.Err()
+	}
+	return parseN, parseErr
+}
+
+// handlerBandwidthResponseWriter paces the response body of one RPC through its method limiter.
+type handlerBandwidthResponseWriter struct {
+	http.ResponseWriter
+	getRequest *http.Request
+	getGuard   *handlerBandwidthGuard
+	getLimiter *handlerBandwidthLimiter
+}
+
+// Write waits until the method rate allows the bytes and then writes them.
+func (parseWriter *handlerBandwidthResponseWriter) Write(parseP []byte) (int, error) {
+	parseContext := parseWriter.getRequest.Context()
+	if !waitHandlerBandwidth(parseContext, parseWriter.getGuard.getObservability, []*handlerBandwidthLimiter{parseWriter.getLimiter}, parseHandlerBandwidthDirectionWrite, len(parseP), parseContext.Done()) {
+		return 0, parseContext.Err()
+	}
+	return parseWriter.ResponseWriter.Write(parseP)
+}
+
+// Flush flushes buffered response data, which gRPC requires of its ResponseWriter.
+func (parseWriter *handlerBandwidthResponseWriter) Flush() {
+	if parseFlusher, isFlusher := parseWriter.ResponseWriter.(http.Flusher); isFlusher {
+		parseFlusher.Flush()
+	}
+}
+
+// Unwrap returns the wrapped ResponseWriter for http.ResponseController.
+func (parseWriter *handlerBandwidthResponseWriter) Unwrap() http.ResponseWriter {
+	return parseWriter.ResponseWriter
+}
+
+// applyHandlerMethodBandwidth paces the bodies of one RPC when its method has a bandwidth limit.
+func (parseGuard *handlerBandwidthGuard) applyHandlerMethodBandwidth(parseW http.ResponseWriter, parseRequest *http.Request) (http.ResponseWriter, *http.Request) {
+	if parseGuard == nil {
+		return parseW, parseRequest
+	}
+	parseLimit, isFoundMethod := parseGuard.getLimits.Methods[parseRequest.URL.Path]
+	if !isFoundMethod || parseLimit.PerSecond <= 0 {
+		return parseW, parseRequest
+	}
+	parseLimiters := buildHandlerBandwidthLimiters(parseLimit, parseHandlerBandwidthScopeMethod)
+	parseRequest = parseRequest.WithContext(parseRequest.Context())
+	if parseRequest.Body != nil {
+		parseRequest.Body = &handlerBandwidthBody{ReadCloser: parseRequest.Body, getRequest: parseRequest, getGuard: parseGuard, getLimiter: parseLimiters.getRead}
+	}
+	return &handlerBandwidthResponseWriter{ResponseWriter: parseW, getRequest: parseRequest, getGuard: parseGuard, getLimiter: parseLimiters.getWrite}, parseRequest
+}
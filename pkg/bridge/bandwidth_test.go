@@ -0,0 +1,85 @@
+package bridge
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/monstercameron/grpc-tunnel/examples/_shared/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// TestGetHandlerConfigError_BandwidthLimits verifies bandwidth limit validation.
+func TestGetHandlerConfigError_BandwidthLimits(parseT *testing.T) {
+	for _, parseLimits := range []BandwidthLimits{
+		{Global: RateLimit{PerSecond: -1}},
+		{PerClient: RateLimit{Burst: 10}},
+		{Methods: map[string]RateLimit{"TodoService/CreateTodo": {PerSecond: 1}}},
+	} {
+		if parseErr := getHandlerConfigError(Config{TargetAddress: "localhost:1", BandwidthLimits: parseLimits}); parseErr == nil {
+			parseT.Fatalf("getHandlerConfigError(%+v) = nil, want error", parseLimits)
+		}
+	}
+}
+
+// TestHandlerBandwidthLimits_DelayLargeMessages verifies connection and method limits delay large messages
+// and release per-client limiters when the tunnel ends.
+func TestHandlerBandwidthLimits_DelayLargeMessages(parseT *testing.T) {
+	parseBackendAddress, _ := buildPoolTestBackend(parseT, "throttled")
+	parseHandler := NewHandler(Config{
+		TargetAddress: parseBackendAddress,
+		BandwidthLimits: BandwidthLimits{
+			PerClient: RateLimit{PerSecond: 100_000, Burst: 10_000},
+			Methods:   map[string]RateLimit{proto.TodoService_CreateTodo_FullMethodName: {PerSecond: 50_000, Burst: 5_000}},
+		},
+	})
+	if parseHandler.initErr != nil {
+		parseT.Fatalf("NewHandler() error: %v", parseHandler.initErr)
+	}
+	parseBridgeServer := httptest.NewServer(parseHandler)
+	defer parseBridgeServer.Close()
+
+	parseCtx, clearCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer clearCtx()
+	parseClientConn, parseErr := grpc.DialContext(
+		parseCtx,
+		"ignored:1234",
+		DialOption("ws"+strings.TrimPrefix(parseBridgeServer.URL, "http")),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if parseErr != nil {
+		parseT.Fatalf("DialContext() error: %v", parseErr)
+	}
+
+	// The request and the echoed response each carry 15 KB: the method limiter delays each by about
+	// 200ms beyond its 5 KB burst.
+	parseStart := time.Now()
+	parseResp, parseErr := proto.NewTodoServiceClient(parseClientConn).CreateTodo(parseCtx, &proto.CreateTodoRequest{Text: strings.Repeat("x", 15_000)})
+	if parseErr != nil {
+		parseT.Fatalf("CreateTodo() error: %v", parseErr)
+	}
+	if len(parseResp.GetTodo().GetText()) != 15_000 {
+		parseT.Fatalf("CreateTodo() text length = %d, want 15000", len(parseResp.GetTodo().GetText()))
+	}
+	if parseElapsed := time.Since(parseStart); parseElapsed < 300*time.Millisecond {
+		parseT.Fatalf("CreateTodo() took %v, want >= 300ms of throttling", parseElapsed)
+	}
+
+	_ = parseClientConn.Close()
+	parseDeadline := time.Now().Add(2 * time.Second)
+	for {
+		parseHandler.bandwidthGuard.setClientLock.Lock()
+		parseClients := len(parseHandler.bandwidthGuard.storeClients)
+		parseHandler.bandwidthGuard.setClientLock.Unlock()
+		if parseClients == 0 {
+			break
+		}
+		if time.Now().After(parseDeadline) {
+			parseT.Fatalf("per-client bandwidth limiters = %d after tunnel close, want 0", parseClients)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -8,28 +8,71 @@ import (
 	"net"
 	"net/http"
 	"net/http/httputil"
+	"net/netip"
 	"net/url"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/healthmonitor"
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/membudget"
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/tunneladmin"
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/tunnelregistry"
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/tunnelstate"
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 const parseDefaultBackendDialTimeout = 10 * time.Second
 const parseDefaultWebSocketBufferSize = 4096
 const parseDefaultReadLimitBytes int64 = 16 << 20
 const parseReverseProxyBufferSize = 32 * 1024
+const parseRPCRateLimitedMessage = "tunnel RPC rate limit exceeded"
+const parseMemoryBudgetExhaustedMessage = "bridge memory budget exhausted"
+const parseNoHealthyBackendMessage = "no healthy backend target available"
 
 var cacheWebSocketWriteBufferPools sync.Map
 
 // Config holds configuration options for the gRPC-over-WebSocket bridge.
 type Config struct {
-	// TargetAddress is the address of the backend gRPC server (e.g., "localhost:50051")
+	// TargetAddress is the address of the backend gRPC server (e.g., "localhost:50051").
+	// Unix domain sockets use "unix:///path/to/sock", and Linux abstract sockets use "unix:@name".
 	TargetAddress string
 
+	// Targets lists several backend gRPC server addresses. Each tunnel's HTTP/2 requests are
+	// balanced across healthy targets using LoadBalancingPolicy. Set either TargetAddress or Targets.
+	Targets []string
+
+	// LoadBalancingPolicy selects how requests are spread across Targets.
+	// Default: LoadBalancingRoundRobin
+	LoadBalancingPolicy LoadBalancingPolicy
+
+	// HealthCheckInterval enables active grpc.health.v1 probes against every backend target.
+	// Failing targets are ejected and re-admitted once they report SERVING. Zero disables probing.
+	HealthCheckInterval time.Duration
+
+	// HealthCheckTimeout limits each backend health probe.
+	// Default: 1s
+	HealthCheckTimeout time.Duration
+
+	// HealthCheckService is the service name sent in backend health probes.
+	// Empty checks overall server health.
+	HealthCheckService string
+
+	// HealthServices lists further service names probed on every backend with each health check
+	// round. They are reported to HealthMonitor only and never eject backends. Requires HealthCheckInterval.
+	HealthServices []string
+
+	// HealthMonitor turns NOT_SERVING while the handler drains or while no backend passes health
+	// checks, and reports each of HealthServices as SERVING while any backend serves it. The handler
+	// answers /healthz and /readyz requests that are not websocket upgrades from it. One monitor may
+	// be shared by several handlers.
+	HealthMonitor *healthmonitor.Monitor
+
 	// CheckOrigin is called during the WebSocket upgrade to determine whether the origin is allowed.
 	// If nil, gorilla/websocket applies its default same-origin policy.
 	CheckOrigin func(r *http.Request) bool
@@ -56,38 +99,264 @@ type Config struct {
 	// IdleTimeout configures how long the bridge waits for client activity or pong frames.
 	IdleTimeout time.Duration
 
+	// UpgradeTimeout caps how long an upgrade request may take from reaching the handler through the
+	// handshake response, including Authenticate. Zero disables the limit.
+	UpgradeTimeout time.Duration
+
+	// PrefaceTimeout closes tunnels whose client has not sent the HTTP/2 connection preface this long
+	// after the upgrade. Zero disables the limit.
+	PrefaceTimeout time.Duration
+
+	// MinReadThroughput closes tunnels that deliver a websocket message slower than a minimum rate.
+	// Idle tunnels between messages are not affected. The zero value disables the limit.
+	// BandwidthLimits.Global and PerClient must be at least BytesPerSecond, because their pacing counts
+	// against it; tunnels sharing those buckets can still be paced below it and closed.
+	MinReadThroughput Throughput
+
+	// WriteTimeout bounds each websocket message write so clients that stop reading responses are
+	// disconnected instead of pinning handler goroutines and buffers. Zero disables the limit.
+	WriteTimeout time.Duration
+
+	// MaxConnectionAge sends GOAWAY to tunnels this old, with +/-10% random jitter so tunnels opened
+	// together do not reconnect together. Clients then redial, which lets load balancers rebalance
+	// long-lived tunnels and re-pins sticky tunnels to a fresh backend. Zero disables the limit.
+	MaxConnectionAge time.Duration
+
+	// MaxConnectionAgeGrace is how long in-flight RPCs may continue after the MaxConnectionAge GOAWAY
+	// before the tunnel is closed. Zero waits for them indefinitely.
+	MaxConnectionAgeGrace time.Duration
+
 	// BackendDialTimeout limits backend TCP dial time for proxied gRPC traffic.
 	// Default: 10s
 	BackendDialTimeout time.Duration
 
+	// BackendTLSConfig enables TLS for backend targets. Client certificates, RootCAs, and
+	// ServerName overrides are honored, and ALPN is always negotiated as h2.
+	// When set, targets without a scheme are dialed as https; explicit http:// targets stay plaintext.
+	// https:// targets with a nil BackendTLSConfig verify against the system roots.
+	BackendTLSConfig *tls.Config
+
 	// ShouldRequireLoopbackBackend rejects handler startup when plaintext backend transport
 	// targets a non-loopback host. Enable this for production boundaries where bridge-to-backend
-	// traffic must remain local to the host network namespace.
+	// traffic must remain local to the host network namespace. TLS and unix socket targets are always allowed.
 	ShouldRequireLoopbackBackend bool
 
+	// TrustedProxies lists proxy CIDR prefixes or IPs whose Forwarded (RFC 7239) and X-Forwarded-For
+	// headers are trusted. The resolved client address replaces RemoteAddr for abuse controls, logs,
+	// Authenticate, and the X-Forwarded-For sent to backends. Empty trusts no forwarding headers.
+	// Wrap the serving listener with grpctunnel.NewProxyProtocolListener to accept PROXY protocol.
+	TrustedProxies []string
+
 	// MaxActiveConnections limits total concurrent websocket tunnel connections.
 	// Zero disables this guard.
 	MaxActiveConnections int
 
 	// MaxConnectionsPerClient limits concurrent websocket tunnel connections per client key.
-	// Client key is derived from request remote address host. Zero disables this guard.
+	// The client key comes from ClientKeyFunc or the client address host. Zero disables this guard.
 	MaxConnectionsPerClient int
 
-	// MaxUpgradesPerClientPerMinute limits websocket upgrade attempts per client key over a 1-minute window.
+	// MaxUpgradesPerClientPerMinute limits websocket upgrade attempts per client key to N per minute with burst N.
 	// Zero disables this guard.
 	MaxUpgradesPerClientPerMinute int
 
+	// UpgradeRateLimit is a per-client token bucket for websocket upgrade attempts. It replaces
+	// MaxUpgradesPerClientPerMinute, which maps to a bucket refilling N per minute with burst N.
+	UpgradeRateLimit RateLimit
+
+	// RPCRateLimit is a per-tunnel token bucket for RPC starts. Calls over the limit receive
+	// ResourceExhausted without reaching a backend.
+	RPCRateLimit RateLimit
+	// BandwidthLimits caps tunnel throughput in bytes per second. Time spent waiting for bandwidth
+	// is reported as bridge_bandwidth_throttle_seconds_total.
+	BandwidthLimits BandwidthLimits
+	// MemoryBudget is a process-wide accountant, usually shared with other handlers. Under pressure
+	// it shrinks HTTP/2 window updates to clients, and once exhausted upgrades receive 503 and new
+	// streams receive ResourceExhausted.
+	MemoryBudget *membudget.Budget
+	// HTTP2Profile selects preset HTTP/2 settings for tunnels and backend connections. Clients using
+	// grpctunnel.TunnelConfig should select the same profile.
+	HTTP2Profile HTTP2Profile
+	// HTTP2 overrides individual HTTP2Profile settings.
+	HTTP2 HTTP2Settings
+
+	// ClientKeyFunc derives the abuse-control client key, for example from an API key header.
+	// It runs before Authenticate. Nil or an empty result keys on the resolved client IP.
+	ClientKeyFunc func(r *http.Request) string
+
+	// ShouldGroupIPv6Clients keys IPv6 clients by their /64 prefix, since one host often controls a whole /64.
+	ShouldGroupIPv6Clients bool
+
+	// AllowedClientCIDRs lists client CIDR prefixes or IPs exempt from per-client limits and the penalty box.
+	// They still count toward MaxActiveConnections.
+	AllowedClientCIDRs []string
+
+	// DeniedClientCIDRs lists client CIDR prefixes or IPs whose upgrades are rejected with 403. Deny wins over allow.
+	DeniedClientCIDRs []string
+
+	// PenaltyBox temporarily bans clients that keep exceeding per-client limits.
+	PenaltyBox PenaltyBox
+
+	// MaxTrackedClients caps the client keys holding rate or penalty state; the least recently seen
+	// client is evicted first. Zero uses 65536.
+	MaxTrackedClients int
+
+	// ClientStateTTL forgets rate and penalty state of clients idle this long, but never before a
+	// ban ends. Zero uses 10 minutes.
+	ClientStateTTL time.Duration
+	// LimitStore holds the MaxActiveConnections and MaxConnectionsPerClient slots. Share one store
+	// between handler replicas to enforce the limits across them. Nil keeps slots in process.
+	// Upgrade rate limits and the penalty box stay per replica.
+	LimitStore LimitStore
+	// LimitStoreLeaseTTL is how long a LimitStore slot outlives its last renewal, bounding how long a
+	// crashed replica's slots stay held. Live tunnels renew every third of it. Zero uses 30 seconds.
+	LimitStoreLeaseTTL time.Duration
+
 	// ShouldEnableCompression enables websocket per-message compression where supported.
 	ShouldEnableCompression bool
 
+	// Subprotocols lists websocket subprotocols the bridge selects from, in preference order,
+	// when the client offers any. Browsers fail handshakes that offer subprotocols none of which is selected.
+	Subprotocols []string
+
+	// ExposurePolicy restricts which gRPC methods tunnel clients may call. Denied calls receive
+	// PermissionDenied without reaching a backend. The zero value exposes every method.
+	ExposurePolicy ExposurePolicy
+
 	// Logger is used for logging. If nil, the default logger is used.
 	Logger Logger
 
+	// Authenticate verifies the upgrade request before the WebSocket handshake.
+	// A returned error rejects the upgrade with 401, or 403 when it wraps ErrPermissionDenied.
+	// The returned context becomes the base context for every request proxied on the tunnel.
+	// When that context is done, for example at a token-expiry deadline, the tunnel receives
+	// HTTP/2 GOAWAY and is closed once AuthExpiryGrace has passed; proxied requests keep its
+	// values but not its cancellation.
+	Authenticate func(r *http.Request) (context.Context, error)
+
+	// AuthExpiryGrace is how long in-flight requests may continue after the Authenticate context
+	// is done before the tunnel is closed. Zero uses a default of 30s.
+	AuthExpiryGrace time.Duration
+
+	// Authorize decides per HTTP/2 stream whether a tunneled RPC may proceed before a backend is picked.
+	// ctx carries the Authenticate context values, upgrade is the websocket upgrade request, and
+	// fullMethod is "/package.Service/Method". gRPC status errors are returned to the client as-is;
+	// other errors deny with PermissionDenied, or Unauthenticated when they wrap ErrUnauthenticated.
+	Authorize func(ctx context.Context, upgrade *http.Request, fullMethod string) error
+
 	// OnConnect is called when a WebSocket connection is established.
+	//
+	// Deprecated: Use TunnelObserver, whose events carry a tunnel ID.
 	OnConnect func(r *http.Request)
 
 	// OnDisconnect is called when a WebSocket connection ends.
+	//
+	// Deprecated: Use TunnelObserver, whose events also report duration, byte counts, and close cause.
 	OnDisconnect func(r *http.Request)
+
+	// TunnelObserver receives typed events for rejected upgrades, tunnels, and streams.
+	TunnelObserver TunnelObserver
+
+	// TunnelRegistry lists open tunnels with live stats and force-closes them. One registry may be
+	// shared by several handlers.
+	TunnelRegistry *tunnelregistry.Registry
+
+	// TunnelAdmin registers tunnels in its registry, receives their lifecycle events, and supplies
+	// MaxActiveConnections, MaxConnectionsPerClient, UpgradeRateLimit, and RPCRateLimit so they can
+	// change at runtime. The first handler built with an admin seeds its limits; a later handler whose
+	// configured limits differ logs tunnel_admin_limits_ignored. TunnelRegistry must be nil or the
+	// admin's registry.
+	TunnelAdmin *tunneladmin.Admin
+
+	// TunnelStates records tunnel state transitions for the grpctunnel tooling state snapshot. One
+	// recorder may be shared by several handlers.
+	TunnelStates *tunnelstate.Recorder
+}
+
+// LimitStore holds connection slots that may be shared by several handler replicas.
+// Implementations must be safe for concurrent use.
+type LimitStore interface {
+	// Reserve atomically takes a slot in key, held by lease until ttl elapses, when fewer than limit
+	// unexpired leases are held. It reports whether the slot was taken.
+	Reserve(ctx context.Context, key string, lease string, limit int, ttl time.Duration) (bool, error)
+	// Renew extends a lease by ttl from now.
+	Renew(ctx context.Context, key string, lease string, ttl time.Duration) error
+	// Release frees a lease. Releasing an unknown lease is not an error.
+	Release(ctx context.Context, key string, lease string) error
+}
+
+// PenaltyBox configures temporary bans for clients that repeatedly exceed per-client limits.
+type PenaltyBox struct {
+	// Strikes is the number of consecutive rejected upgrades that bans a client. Zero disables bans.
+	Strikes int
+	// BaseBan is the first ban duration; each further ban doubles it. Zero uses 30 seconds.
+	BaseBan time.Duration
+	// MaxBan caps ban durations. Zero uses 1 hour.
+	MaxBan time.Duration
+}
+
+// Throughput configures a minimum transfer rate.
+type Throughput struct {
+	// BytesPerSecond is the minimum rate. Zero disables the limit.
+	BytesPerSecond int64
+	// Window is how long a transfer may stay below the rate before the tunnel closes. Zero uses 10 seconds.
+	Window time.Duration
+}
+
+// HTTP2Profile names a preset of HTTP/2 settings tuned for one kind of traffic.
+type HTTP2Profile string
+
+const (
+	// HTTP2ProfileLowLatency sizes flow-control windows so messages up to a few MiB arrive without
+	// waiting a round trip for WINDOW_UPDATE, and keeps frames small so short calls interleave with
+	// bulk transfers on the same tunnel.
+	HTTP2ProfileLowLatency HTTP2Profile = "low_latency"
+	// HTTP2ProfileHighThroughput uses large windows and frames so long transfers over high-RTT links
+	// keep the pipe full, at the cost of more memory buffered per tunnel.
+	HTTP2ProfileHighThroughput HTTP2Profile = "high_throughput"
+	// HTTP2ProfileConstrainedMemory uses the smallest stream window HTTP/2 allows and few concurrent streams,
+	// bounding what one tunnel can make the bridge buffer, and closes idle tunnels after two minutes.
+	HTTP2ProfileConstrainedMemory HTTP2Profile = "constrained_memory"
+)
+
+// HTTP2Settings configures the HTTP/2 connection carried by each tunnel. Zero fields keep the
+// profile value, or the golang.org/x/net/http2 defaults when no profile is set.
+type HTTP2Settings struct {
+	// MaxConcurrentStreams caps concurrent RPCs per tunnel.
+	MaxConcurrentStreams uint32
+	// MaxReadFrameSize is the largest frame a client or backend may send, from 16 KiB to 16 MiB - 1.
+	MaxReadFrameSize uint32
+	// InitialStreamWindowSize is the flow-control window of each tunnel stream, at least 65535 bytes.
+	// It bounds request bytes buffered per RPC.
+	InitialStreamWindowSize int32
+	// InitialConnWindowSize is the flow-control window shared by every stream of a tunnel, at least
+	// 65535 bytes.
+	InitialConnWindowSize int32
+	// MaxHeaderListSize caps the size of header lists received from clients and backends.
+	MaxHeaderListSize uint32
+	// IdleTimeout sends GOAWAY on tunnels with no active streams for this long, and closes backend
+	// connections idle for this long.
+	IdleTimeout time.Duration
+}
+
+// BandwidthLimits caps tunnel throughput with byte token buckets. Every limit applies to reads
+// and writes separately, so a PerSecond of 1<<20 allows 1 MiB/s in each direction. Writes larger
+// than Burst still pass, delayed until the rate allows them.
+type BandwidthLimits struct {
+	// Global is shared by every tunnel of the handler.
+	Global RateLimit
+	// PerClient is shared by the tunnels of one client key, as derived for abuse controls.
+	PerClient RateLimit
+	// Methods caps the request and response bodies of each call to a full method name such as
+	// "/todo.v1.TodoService/StreamTodos".
+	Methods map[string]RateLimit
+}
+
+// RateLimit configures a token bucket that refills PerSecond tokens each second up to Burst.
+type RateLimit struct {
+	// PerSecond is the sustained event rate. Zero disables the limit.
+	PerSecond float64
+	// Burst is the bucket capacity. Zero uses PerSecond rounded up, and at least 1.
+	Burst int
 }
 
 // Logger interface for custom logging.
@@ -102,6 +371,19 @@ func (defaultLogger) Printf(format string, parseV ...interface{}) {
 	log.Printf(format, parseV...)
 }
 
+// getHandlerTunnelOverhead returns the fixed memory charged per tunnel: its websocket read and write buffers.
+func getHandlerTunnelOverhead(parseConfig Config) int64 {
+	parseReadBufferSize := parseConfig.ReadBufferSize
+	if parseReadBufferSize <= 0 {
+		parseReadBufferSize = parseDefaultWebSocketBufferSize
+	}
+	parseWriteBufferSize := parseConfig.WriteBufferSize
+	if parseWriteBufferSize <= 0 {
+		parseWriteBufferSize = parseDefaultWebSocketBufferSize
+	}
+	return int64(parseReadBufferSize + parseWriteBufferSize)
+}
+
 // buildWebSocketWriteBufferPool returns a shared pool for a websocket write-buffer size.
 func buildWebSocketWriteBufferPool(parseBufferSize int) *sync.Pool {
 	if parseBufferSize <= 0 {
@@ -180,6 +462,11 @@ type Handler struct {
 	http2Server     *http2.Server
 	serveH2CHandler http.Handler
 	abuseGuard      *handlerAbuseGuard
+	tunnelTracker   *handlerTunnelTracker
+	backendPool     *handlerBackendPool
+	observability   *handlerObservability
+	bandwidthGuard  *handlerBandwidthGuard
+	trustedProxies  []netip.Prefix
 	initErr         error
 }
 
@@ -207,74 +494,96 @@ func NewHandler(parseCfg Config) *Handler {
 	if parseCfg.Logger == nil {
 		parseCfg.Logger = defaultLogger{}
 	}
+	parseCfg = applyHandlerTunnelObservers(parseCfg)
 
 	parseH := &Handler{
-		config:      parseCfg,
-		logger:      parseCfg.Logger,
-		http2Server: &http2.Server{},
+		config:        parseCfg,
+		logger:        parseCfg.Logger,
+		http2Server:   &http2.Server{},
+		observability: buildHandlerObservability(),
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:    parseCfg.ReadBufferSize,
 			WriteBufferSize:   parseCfg.WriteBufferSize,
 			WriteBufferPool:   buildWebSocketWriteBufferPool(parseCfg.WriteBufferSize),
+			HandshakeTimeout:  parseCfg.UpgradeTimeout,
 			CheckOrigin:       parseCfg.CheckOrigin,
 			EnableCompression: parseCfg.ShouldEnableCompression,
+			Subprotocols:      parseCfg.Subprotocols,
 		},
-		abuseGuard: buildHandlerAbuseGuard(parseCfg),
+		abuseGuard:    buildHandlerAbuseGuard(parseCfg),
+		tunnelTracker: buildHandlerTunnelTracker(parseCfg.HealthMonitor.NewReporter()),
 	}
-
+	parseH.bandwidthGuard = buildHandlerBandwidthGuard(parseCfg.BandwidthLimits, parseH.observability)
 	if parseErr := getHandlerConfigError(parseCfg); parseErr != nil {
 		parseH.initErr = parseErr
 		logBridgeEvent(parseH.logger, "WARN", "bridge_config_invalid", nil, parseErr, "Bridge configuration warning")
 		return parseH
 	}
 
-	parseTargetURL, parseErr := parseBridgeTargetURL(parseCfg.TargetAddress)
+	// getHandlerConfigError has already validated TrustedProxies.
+	parseH.trustedProxies, _ = parseHandlerTrustedProxies(parseCfg.TrustedProxies)
+
+	parseTargetURLs, parseErr := parseBridgeTargetURLs(parseCfg)
 	if parseErr != nil {
 		parseH.initErr = parseErr
 		logBridgeEvent(parseH.logger, "WARN", "bridge_config_invalid", nil, parseErr, "Bridge configuration warning")
 		return parseH
 	}
-	if parseErr = getBridgeBackendTransportPolicyError(parseCfg, parseTargetURL); parseErr != nil {
-		parseH.initErr = parseErr
-		logBridgeEvent(parseH.logger, "ERROR", "backend_transport_policy_violation", nil, parseErr, "Bridge backend transport policy violation")
-		return parseH
-	}
-	if shouldWarnBridgePlaintextBackend(parseTargetURL.Hostname()) {
-		logBridgeEvent(
-			parseH.logger,
-			"WARN",
-			"backend_plaintext_non_loopback",
-			nil,
-			nil,
-			fmt.Sprintf(
-				"Bridge security warning: TargetAddress %q uses plaintext h2c backend transport to non-loopback host %q. Ensure this hop is on a trusted private network or terminate TLS before the bridge.",
-				parseCfg.TargetAddress,
-				parseTargetURL.Hostname(),
-			),
-		)
+	for _, parseTargetURL := range parseTargetURLs {
+		if parseErr = getBridgeBackendTransportPolicyError(parseCfg, parseTargetURL); parseErr != nil {
+			parseH.initErr = parseErr
+			logBridgeEvent(parseH.logger, "ERROR", "backend_transport_policy_violation", nil, parseErr, "Bridge backend transport policy violation")
+			return parseH
+		}
+		if parseTargetURL.Scheme == "http" && shouldWarnBridgePlaintextBackend(parseTargetURL.Hostname()) {
+			logBridgeEvent(
+				parseH.logger,
+				"WARN",
+				"backend_plaintext_non_loopback",
+				nil,
+				nil,
+				fmt.Sprintf(
+					"Bridge security warning: target %q uses plaintext h2c backend transport to non-loopback host %q. Ensure this hop is on a trusted private network or terminate TLS before the bridge.",
+					parseTargetURL.Host,
+					parseTargetURL.Hostname(),
+				),
+			)
+		}
 	}
 
 	parseBackendDialer := &net.Dialer{
 		Timeout: parseCfg.BackendDialTimeout,
 	}
 
+	parseH.backendPool = buildHandlerBackendPool(parseCfg, parseTargetURLs)
+	if parseErr = parseH.backendPool.startHandlerHealthChecks(parseCfg, func(parseTargetURL *url.URL) []grpc.DialOption {
+		return buildHandlerHealthDialOptions(parseCfg, parseBackendDialer, parseTargetURL)
+	}); parseErr != nil {
+		parseH.initErr = parseErr
+		logBridgeEvent(parseH.logger, "WARN", "bridge_config_invalid", nil, parseErr, "Bridge configuration warning")
+		return parseH
+	}
+
 	parseProxyBufferPool := &reverseProxyBufferPool{}
 
 	// Create the reverse proxy
 	parseH.proxy = &httputil.ReverseProxy{
 		Director: func(parseReq *http.Request) {
+			parseTargetURL := parseTargetURLs[0]
+			if parseBackend, isFoundBackend := parseReq.Context().Value(handlerBackendContextKey{}).(*handlerBackend); isFoundBackend {
+				parseTargetURL = parseBackend.getTargetURL
+			}
 			parseReq.URL.Scheme = parseTargetURL.Scheme
+			if parseTargetURL.Scheme == "unix" {
+				parseReq.URL.Scheme = "http"
+			}
 			parseReq.URL.Host = parseTargetURL.Host
 			parseReq.Host = parseTargetURL.Host
 		},
-		Transport: &http2.Transport{
-			AllowHTTP: true,
-			DialTLSContext: func(parseDialContext context.Context, parseNetwork string, parseAddr string, parseTLSConfig *tls.Config) (net.Conn, error) {
-				return parseBackendDialer.DialContext(parseDialContext, parseNetwork, parseAddr)
-			},
-		},
+		Transport: buildHandlerBackendTransport(parseCfg, parseBackendDialer, parseTargetURLs),
 		ErrorHandler: func(parseW http.ResponseWriter, parseR2 *http.Request, parseErr error) {
 			logBridgeEvent(parseH.logger, "ERROR", "backend_proxy_error", parseR2, parseErr, "Proxy error")
+			storeHandlerBackendDialFailed(parseH.config.TunnelStates, parseR2, parseErr)
 			http.Error(parseW, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
 		},
 		BufferPool: parseProxyBufferPool,
@@ -284,25 +593,91 @@ func NewHandler(parseCfg Config) *Handler {
 	return parseH
 }
 
+// serveHandlerHealthProbe answers /healthz and /readyz from the health monitor and reports whether it
+// handled the request. Websocket upgrades to those paths still open tunnels.
+func serveHandlerHealthProbe(parseW http.ResponseWriter, parseR *http.Request, parseMonitor *healthmonitor.Monitor) bool {
+	if parseMonitor == nil || websocket.IsWebSocketUpgrade(parseR) {
+		return false
+	}
+	switch parseR.URL.Path {
+	case "/healthz":
+		parseMonitor.ServeLiveness(parseW, parseR)
+	case "/readyz":
+		parseMonitor.ServeReadiness(parseW, parseR)
+	default:
+		return false
+	}
+	return true
+}
+
 // ServeHTTP implements http.Handler. This is called for each incoming HTTP request.
 func (parseH *Handler) ServeHTTP(parseW http.ResponseWriter, parseR *http.Request) {
+	parseUpgradeStart := time.Now()
+	parseR = resolveHandlerClientRequest(parseR, parseH.trustedProxies)
 	if parseH.initErr != nil {
 		logBridgeEvent(parseH.logger, "ERROR", "bridge_request_rejected", parseR, parseH.initErr, "Bridge request rejected due to configuration error")
+		storeHandlerUpgradeRejected(parseH.config.TunnelObserver, parseR, UpgradeRejectConfigInvalid, http.StatusInternalServerError, parseH.initErr)
 		http.Error(parseW, parseH.initErr.Error(), http.StatusInternalServerError)
 		return
 	}
+	if serveHandlerHealthProbe(parseW, parseR, parseH.config.HealthMonitor) {
+		return
+	}
+
+	if parseH.tunnelTracker != nil && parseH.tunnelTracker.isDraining.Load() {
+		logBridgeEvent(parseH.logger, "WARN", "ws_upgrade_rejected_draining", parseR, nil, "WebSocket upgrade rejected because the bridge is draining")
+		storeHandlerUpgradeRejected(parseH.config.TunnelObserver, parseR, UpgradeRejectDraining, http.StatusServiceUnavailable, nil)
+		http.Error(parseW, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+	if parseH.config.MemoryBudget.IsExhausted() {
+		logBridgeEvent(parseH.logger, "WARN", "ws_upgrade_rejected_memory_budget", parseR, nil, "WebSocket upgrade rejected because the memory budget is exhausted")
+		storeHandlerUpgradeRejected(parseH.config.TunnelObserver, parseR, UpgradeRejectMemoryBudget, http.StatusServiceUnavailable, nil)
+		http.Error(parseW, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
 
-	if parseErr := parseH.abuseGuard.reserveHandlerConnection(parseR, time.Now()); parseErr != nil {
+	parseLease, parseErr := parseH.abuseGuard.reserveHandlerConnection(parseR, time.Now())
+	if parseErr != nil {
+		parseH.observability.storeHandlerAbuseRejection(parseR.Context(), parseErr)
 		logBridgeEvent(parseH.logger, "WARN", "ws_upgrade_rejected_abuse_control", parseR, parseErr, "WebSocket upgrade rejected by abuse controls")
-		http.Error(parseW, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		parseReason, parseStatusCode := getHandlerAbuseRejectReason(parseErr)
+		storeHandlerUpgradeRejected(parseH.config.TunnelObserver, parseR, parseReason, parseStatusCode, parseErr)
+		writeHandlerAbuseRejection(parseW, parseErr)
+		return
+	}
+	defer parseH.abuseGuard.clearHandlerConnection(parseLease)
+
+	var parseBaseContext, parseExpiryContext context.Context
+	if parseH.config.Authenticate != nil {
+		parseAuthContext, parseErr := parseH.config.Authenticate(parseR)
+		if parseErr != nil {
+			parseStatusCode := getHandlerAuthStatusCode(parseErr)
+			logBridgeEvent(parseH.logger, "WARN", "ws_upgrade_rejected_auth", parseR, parseErr, "WebSocket upgrade rejected by authentication")
+			storeHandlerUpgradeRejected(parseH.config.TunnelObserver, parseR, UpgradeRejectAuthentication, parseStatusCode, parseErr)
+			http.Error(parseW, http.StatusText(parseStatusCode), parseStatusCode)
+			return
+		}
+		if parseAuthContext == nil {
+			parseAuthContext = parseR.Context()
+		}
+		parseExpiryContext = parseAuthContext
+		parseBaseContext = context.WithoutCancel(parseAuthContext)
+	}
+
+	if parseH.config.UpgradeTimeout > 0 && time.Since(parseUpgradeStart) > parseH.config.UpgradeTimeout {
+		parseH.observability.storeHandlerSlowClient(parseR.Context(), parseHandlerSlowClientUpgradeTimeout)
+		logBridgeEvent(parseH.logger, "WARN", "ws_upgrade_timeout", parseR, nil, "WebSocket upgrade rejected because it exceeded UpgradeTimeout")
+		storeHandlerUpgradeRejected(parseH.config.TunnelObserver, parseR, UpgradeRejectUpgradeTimeout, http.StatusRequestTimeout, nil)
+		http.Error(parseW, http.StatusText(http.StatusRequestTimeout), http.StatusRequestTimeout)
 		return
 	}
-	defer parseH.abuseGuard.clearHandlerConnection(parseR)
 
 	// Upgrade to WebSocket
 	parseWs, parseErr := parseH.upgrader.Upgrade(parseW, parseR, nil)
 	if parseErr != nil {
 		logBridgeEvent(parseH.logger, "WARN", "ws_upgrade_failed", parseR, parseErr, "WebSocket upgrade failed")
+		storeHandlerUpgradeRejected(parseH.config.TunnelObserver, parseR, UpgradeRejectHandshake, 0, parseErr)
 		return
 	}
 	logBridgeEvent(parseH.logger, "INFO", "ws_upgrade_succeeded", parseR, nil, "WebSocket upgrade succeeded")
@@ -328,36 +703,148 @@ func (parseH *Handler) ServeHTTP(parseW http.ResponseWriter, parseR *http.Reques
 	}()
 
 	// Wrap WebSocket as net.Conn
-	parseConn := NewWebSocketConn(parseWs)
+	parseClientKey := parseH.abuseGuard.getHandlerGuardClientKey(parseR)
+	parseBandwidth := parseH.bandwidthGuard.buildHandlerConnBandwidth(parseR.Context(), parseClientKey)
+	parseSlowClient := buildHandlerSlowClientGuard(parseH.config, parseR, parseH.observability)
+	parseMemory := parseH.config.MemoryBudget.NewTunnel(getHandlerTunnelOverhead(parseH.config))
+	parseWebSocketConn := newHandlerWebSocketConn(parseWs, parseBandwidth, parseSlowClient, parseMemory)
+	parseConn := buildHandlerClientAddrConn(parseWebSocketConn, parseR)
 	defer parseConn.Close()
 
-	// Serve HTTP/2 over the WebSocket connection
-	parseHTTP2Server := parseH.http2Server
-	if parseHTTP2Server == nil {
-		parseHTTP2Server = &http2.Server{}
+	parseTunnel := &handlerTunnel{
+		getID:          buildHandlerTunnelID(),
+		getConn:        parseConn,
+		getBaseContext: parseBaseContext,
+		getRPCLimiter:  buildHandlerRateLimiter(RateLimit(parseH.abuseGuard.getHandlerAbuseLimits().RPCRateLimit)),
 	}
+	storeDisconnected := storeHandlerTunnelConnected(parseH.config.TunnelObserver, parseTunnel, parseWebSocketConn, parseR)
+	defer storeDisconnected()
+	if parseH.tunnelTracker != nil {
+		parseH.tunnelTracker.storeHandlerTunnel(parseTunnel)
+		defer parseH.tunnelTracker.clearHandlerTunnel(parseTunnel)
+	}
+	parseRegistered := parseH.storeHandlerTunnelRegistry(parseTunnel, parseWebSocketConn, parseR, parseClientKey)
+	defer parseRegistered.Remove()
+	clearExpiry := parseH.startHandlerTunnelAuthExpiry(parseTunnel, parseExpiryContext, parseR)
+	defer clearExpiry()
+	clearMaxAge := parseH.startHandlerTunnelMaxAge(parseTunnel, parseR)
+	defer clearMaxAge()
+
+	// Serve HTTP/2 over the WebSocket connection
 	parseServeH2CHandler := parseH.serveH2CHandler
 	if parseServeH2CHandler == nil {
-		parseServeH2CHandler = h2c.NewHandler(parseH.proxy, parseHTTP2Server)
+		parseBaseHTTP2Server := parseH.http2Server
+		if parseBaseHTTP2Server == nil {
+			parseBaseHTTP2Server = &http2.Server{}
+		}
+		parseServeH2CHandler = h2c.NewHandler(parseH.proxy, parseBaseHTTP2Server)
 	}
+	parseHTTP2Server, parseShutdownServer := buildHandlerTunnelHTTP2Server(parseH.config)
+	parseTunnel.storeHandlerTunnelDrain(buildHandlerTunnelDrain(parseShutdownServer))
 	parseHTTP2Server.ServeConn(parseConn, &http2.ServeConnOpts{
-		Handler: parseServeH2CHandler,
+		Context:    parseTunnel.getBaseContext,
+		BaseConfig: parseShutdownServer,
+		Handler: http.HandlerFunc(func(parseStreamW http.ResponseWriter, parseStreamR *http.Request) {
+			parseTunnel.getActiveStreams.Add(1)
+			defer parseTunnel.getActiveStreams.Add(-1)
+			storeStreamClosed := startHandlerStream(parseH.config.TunnelObserver, parseTunnel, parseStreamR.URL.Path)
+			defer storeStreamClosed(parseStreamW.Header())
+
+			if !parseTunnel.getRPCLimiter.allowHandlerRateLimiter(time.Now()) {
+				parseH.observability.storeHandlerRPCDenied(parseStreamR.Context(), parseHandlerMetricReasonRateLimit)
+				logBridgeEvent(parseH.logger, "WARN", "rpc_rate_limited", parseStreamR, nil, "RPC rejected by tunnel rate limit")
+				writeHandlerRPCStatus(parseStreamW, status.New(codes.ResourceExhausted, parseRPCRateLimitedMessage))
+				return
+			}
+			if parseH.config.MemoryBudget.IsExhausted() {
+				parseH.observability.storeHandlerRPCDenied(parseStreamR.Context(), parseHandlerMetricReasonMemoryBudget)
+				logBridgeEvent(parseH.logger, "WARN", "rpc_rejected_memory_budget", parseStreamR, nil, "RPC rejected because the memory budget is exhausted")
+				writeHandlerRPCStatus(parseStreamW, status.New(codes.ResourceExhausted, parseMemoryBudgetExhaustedMessage))
+				return
+			}
+			if !parseH.config.ExposurePolicy.isExposed(parseStreamR.URL.Path) {
+				parseH.observability.storeHandlerRPCDenied(parseStreamR.Context(), parseHandlerMetricReasonExposure)
+				logBridgeEvent(parseH.logger, "WARN", "rpc_denied_exposure_policy", parseStreamR, nil, "RPC rejected by exposure policy")
+				writeHandlerRPCStatus(parseStreamW, status.New(codes.PermissionDenied, parseExposureDeniedMessage))
+				return
+			}
+			if parseH.config.Authorize != nil {
+				if parseErr := parseH.config.Authorize(parseStreamR.Context(), parseR, parseStreamR.URL.Path); parseErr != nil {
+					parseH.observability.storeHandlerRPCDenied(parseStreamR.Context(), parseHandlerMetricReasonAuthorization)
+					logBridgeEvent(parseH.logger, "WARN", "rpc_denied_authorization", parseStreamR, parseErr, "RPC rejected by authorization")
+					writeHandlerRPCStatus(parseStreamW, getHandlerAuthorizeStatus(parseErr))
+					return
+				}
+			}
+			parseBackend := parseH.backendPool.pickHandlerBackend(parseTunnel)
+			if parseBackend == nil {
+				logBridgeEvent(parseH.logger, "WARN", "backend_unavailable", parseStreamR, nil, "No healthy backend target available")
+				writeHandlerRPCStatus(parseStreamW, status.New(codes.Unavailable, parseNoHealthyBackendMessage))
+				return
+			}
+			parseBackend.getOutstanding.Add(1)
+			defer parseBackend.getOutstanding.Add(-1)
+			parseStreamContext := context.WithValue(parseStreamR.Context(), handlerBackendContextKey{}, parseBackend)
+			parseStreamR = parseStreamR.WithContext(context.WithValue(parseStreamContext, handlerTunnelIDContextKey{}, parseTunnel.getID))
+			parseStreamW, parseStreamR = parseH.bandwidthGuard.applyHandlerMethodBandwidth(parseStreamW, parseStreamR)
+			parseServeH2CHandler.ServeHTTP(parseStreamW, parseStreamR)
+		}),
 	})
 }
 
+// ActiveTunnels returns the number of live websocket tunnels served by the handler.
+func (parseH *Handler) ActiveTunnels() int {
+	if parseH.tunnelTracker == nil {
+		return 0
+	}
+	return len(parseH.tunnelTracker.getHandlerTunnels())
+}
+
+// Drain stops accepting new websocket upgrades and sends HTTP/2 GOAWAY to every live tunnel.
+// In-flight RPCs keep running; clients reconnect elsewhere for new RPCs. Drain does not block.
+func (parseH *Handler) Drain() {
+	if parseH.tunnelTracker == nil {
+		return
+	}
+	logBridgeEvent(parseH.logger, "INFO", "bridge_drain_started", nil, nil, "Bridge drain started")
+	parseH.tunnelTracker.drainHandlerTunnels()
+}
+
+// Shutdown drains the handler, closes idle tunnels, and waits for in-flight RPCs to finish.
+// When the context ends first, remaining tunnels are force-closed, and the number of them that still
+// had RPCs in flight is returned together with the context error.
+func (parseH *Handler) Shutdown(parseCtx context.Context) (int, error) {
+	if parseH.tunnelTracker == nil {
+		return 0, nil
+	}
+	parseH.Drain()
+	parseForceClosed, parseErr := parseH.tunnelTracker.shutdownHandlerTunnels(parseCtx)
+	parseH.backendPool.stopHandlerHealthChecks()
+	if parseForceClosed > 0 {
+		logBridgeEvent(parseH.logger, "WARN", "bridge_shutdown_forced", nil, parseErr, fmt.Sprintf("Bridge shutdown force-closed %d tunnels with RPCs in flight", parseForceClosed))
+	} else {
+		logBridgeEvent(parseH.logger, "INFO", "bridge_shutdown_completed", nil, nil, "Bridge shutdown completed")
+	}
+	return parseForceClosed, parseErr
+}
+
 // parseBridgeTargetURL validates the configured backend address and returns a proxy target URL.
 func parseBridgeTargetURL(parseTargetAddress string) (*url.URL, error) {
 	parseTargetAddress = strings.TrimSpace(parseTargetAddress)
 	if parseTargetAddress == "" {
 		return nil, fmt.Errorf("bridge: target address is required")
 	}
+	if strings.HasPrefix(parseTargetAddress, "unix:") {
+		return parseBridgeUnixTargetURL(parseTargetAddress)
+	}
+	parseTargetScheme := "http"
 	if strings.Contains(parseTargetAddress, "://") {
 		parseConfiguredTargetURL, parseErr := url.Parse(parseTargetAddress)
 		if parseErr != nil {
 			return nil, fmt.Errorf("bridge: invalid target address %q: %w", parseTargetAddress, parseErr)
 		}
-		if parseConfiguredTargetURL.Scheme != "http" {
-			return nil, fmt.Errorf("bridge: unsupported target scheme %q; use host:port or http://host:port", parseConfiguredTargetURL.Scheme)
+		if parseConfiguredTargetURL.Scheme != "http" && parseConfiguredTargetURL.Scheme != "https" {
+			return nil, fmt.Errorf("bridge: unsupported target scheme %q; use host:port, http://host:port, https://host:port, or unix:///path", parseConfiguredTargetURL.Scheme)
 		}
 		if parseConfiguredTargetURL.Host == "" {
 			return nil, fmt.Errorf("bridge: invalid target address %q", parseTargetAddress)
@@ -367,10 +854,11 @@ func parseBridgeTargetURL(parseTargetAddress string) (*url.URL, error) {
 			parseConfiguredTargetURL.Fragment != "" {
 			return nil, fmt.Errorf("bridge: target address %q must not include path, query, or fragment", parseTargetAddress)
 		}
+		parseTargetScheme = parseConfiguredTargetURL.Scheme
 		parseTargetAddress = parseConfiguredTargetURL.Host
 	}
 
-	parseTargetURL, parseErr := url.Parse("http://" + parseTargetAddress)
+	parseTargetURL, parseErr := url.Parse(parseTargetScheme + "://" + parseTargetAddress)
 	if parseErr != nil {
 		return nil, fmt.Errorf("bridge: invalid target address %q: %w", parseTargetAddress, parseErr)
 	}
@@ -385,6 +873,74 @@ func parseBridgeTargetURL(parseTargetAddress string) (*url.URL, error) {
 	return parseTargetURL, nil
 }
 
+// parseBridgeUnixTargetURL validates a unix:///path or unix:@abstract target and returns a proxy target URL.
+// The URL host is fixed to localhost so proxied requests carry the same :authority gRPC uses for unix targets.
+func parseBridgeUnixTargetURL(parseTargetAddress string) (*url.URL, error) {
+	parseConfiguredTargetURL, parseErr := url.Parse(parseTargetAddress)
+	if parseErr != nil {
+		return nil, fmt.Errorf("bridge: invalid target address %q: %w", parseTargetAddress, parseErr)
+	}
+	if parseConfiguredTargetURL.Host != "" || parseConfiguredTargetURL.User != nil {
+		return nil, fmt.Errorf("bridge: unix target %q must not include a host; use unix:///path or unix:@name", parseTargetAddress)
+	}
+	if parseConfiguredTargetURL.RawQuery != "" || parseConfiguredTargetURL.Fragment != "" {
+		return nil, fmt.Errorf("bridge: target address %q must not include query or fragment", parseTargetAddress)
+	}
+
+	parseSocketPath := parseConfiguredTargetURL.Path
+	if parseConfiguredTargetURL.Opaque != "" {
+		parseSocketPath = parseConfiguredTargetURL.Opaque
+		if !strings.HasPrefix(parseSocketPath, "@") {
+			return nil, fmt.Errorf("bridge: unix target %q must use an absolute path or @abstract name", parseTargetAddress)
+		}
+	}
+	if parseSocketPath == "" || parseSocketPath == "/" || parseSocketPath == "@" {
+		return nil, fmt.Errorf("bridge: unix target %q is missing a socket path", parseTargetAddress)
+	}
+	return &url.URL{Scheme: "unix", Host: "localhost", Path: parseSocketPath}, nil
+}
+
+// getBridgeTargetName returns the display name used for a backend target in logs and errors.
+func getBridgeTargetName(parseTargetURL *url.URL) string {
+	if parseTargetURL.Scheme == "unix" {
+		return "unix:" + parseTargetURL.Path
+	}
+	return parseTargetURL.Host
+}
+
+// parseBridgeTargetURLs validates TargetAddress or Targets and returns proxy target URLs in configured order.
+func parseBridgeTargetURLs(parseConfig Config) ([]*url.URL, error) {
+	if len(parseConfig.Targets) == 0 {
+		parseTargetURL, parseErr := parseBridgeConfiguredTargetURL(parseConfig, parseConfig.TargetAddress)
+		if parseErr != nil {
+			return nil, parseErr
+		}
+		return []*url.URL{parseTargetURL}, nil
+	}
+
+	parseTargetURLs := make([]*url.URL, 0, len(parseConfig.Targets))
+	for _, parseTargetAddress := range parseConfig.Targets {
+		parseTargetURL, parseErr := parseBridgeConfiguredTargetURL(parseConfig, parseTargetAddress)
+		if parseErr != nil {
+			return nil, parseErr
+		}
+		parseTargetURLs = append(parseTargetURLs, parseTargetURL)
+	}
+	return parseTargetURLs, nil
+}
+
+// parseBridgeConfiguredTargetURL parses one target and upgrades scheme-less targets to https when BackendTLSConfig is set.
+func parseBridgeConfiguredTargetURL(parseConfig Config, parseTargetAddress string) (*url.URL, error) {
+	parseTargetURL, parseErr := parseBridgeTargetURL(parseTargetAddress)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	if parseConfig.BackendTLSConfig != nil && parseTargetURL.Scheme == "http" && !strings.Contains(parseTargetAddress, "://") {
+		parseTargetURL.Scheme = "https"
+	}
+	return parseTargetURL, nil
+}
+
 // shouldWarnBridgePlaintextBackend reports whether plaintext backend transport should emit a warning.
 func shouldWarnBridgePlaintextBackend(parseHost string) bool {
 	parseHost = strings.TrimSpace(parseHost)
@@ -402,16 +958,17 @@ func shouldWarnBridgePlaintextBackend(parseHost string) bool {
 }
 
 // getBridgeBackendTransportPolicyError validates strict backend transport policy for non-loopback plaintext targets.
+// TLS and unix socket targets satisfy the policy regardless of host.
 func getBridgeBackendTransportPolicyError(parseConfig Config, parseTargetURL *url.URL) error {
-	if !parseConfig.ShouldRequireLoopbackBackend || parseTargetURL == nil {
+	if !parseConfig.ShouldRequireLoopbackBackend || parseTargetURL == nil || parseTargetURL.Scheme != "http" {
 		return nil
 	}
 	if !shouldWarnBridgePlaintextBackend(parseTargetURL.Hostname()) {
 		return nil
 	}
 	return fmt.Errorf(
-		"bridge: TargetAddress %q violates backend transport policy; non-loopback plaintext backend targets are not allowed when ShouldRequireLoopbackBackend is true",
-		parseConfig.TargetAddress,
+		"bridge: target %q violates backend transport policy; non-loopback plaintext backend targets are not allowed when ShouldRequireLoopbackBackend is true",
+		parseTargetURL.Host,
 	)
 }
 
@@ -453,6 +1010,85 @@ func getHandlerConfigError(parseConfig Config) error {
 	if parseConfig.MaxUpgradesPerClientPerMinute < 0 {
 		return fmt.Errorf("bridge: MaxUpgradesPerClientPerMinute must be >= 0")
 	}
+	if parseErr := getHandlerRateLimitError("UpgradeRateLimit", parseConfig.UpgradeRateLimit); parseErr != nil {
+		return parseErr
+	}
+	if parseErr := getHandlerRateLimitError("RPCRateLimit", parseConfig.RPCRateLimit); parseErr != nil {
+		return parseErr
+	}
+	if parseErr := getHandlerBandwidthLimitsError(parseConfig.BandwidthLimits); parseErr != nil {
+		return parseErr
+	}
+	if parseErr := getHandlerSlowClientError(parseConfig); parseErr != nil {
+		return parseErr
+	}
+	if parseConfig.MaxConnectionAge < 0 {
+		return fmt.Errorf("bridge: MaxConnectionAge must be >= 0")
+	}
+	if parseConfig.MaxConnectionAgeGrace < 0 {
+		return fmt.Errorf("bridge: MaxConnectionAgeGrace must be >= 0")
+	}
+	if parseConfig.AuthExpiryGrace < 0 {
+		return fmt.Errorf("bridge: AuthExpiryGrace must be >= 0")
+	}
+	if parseConfig.MaxConnectionAgeGrace > 0 && parseConfig.MaxConnectionAge == 0 {
+		return fmt.Errorf("bridge: MaxConnectionAgeGrace requires MaxConnectionAge")
+	}
+	if parseErr := getHTTP2SettingsError(parseConfig.HTTP2Profile, parseConfig.HTTP2); parseErr != nil {
+		return parseErr
+	}
+	if parseConfig.UpgradeRateLimit.PerSecond > 0 && parseConfig.MaxUpgradesPerClientPerMinute > 0 {
+		return fmt.Errorf("bridge: set either UpgradeRateLimit or MaxUpgradesPerClientPerMinute, not both")
+	}
+	if parseErr := getExposurePolicyError(parseConfig.ExposurePolicy); parseErr != nil {
+		return parseErr
+	}
+	if parseConfig.MaxTrackedClients < 0 {
+		return fmt.Errorf("bridge: MaxTrackedClients must be >= 0")
+	}
+	if parseConfig.ClientStateTTL < 0 {
+		return fmt.Errorf("bridge: ClientStateTTL must be >= 0")
+	}
+	if parseConfig.LimitStoreLeaseTTL < 0 {
+		return fmt.Errorf("bridge: LimitStoreLeaseTTL must be >= 0")
+	}
+	if parseErr := getHandlerPenaltyBoxError(parseConfig.PenaltyBox); parseErr != nil {
+		return parseErr
+	}
+	if _, parseErr := parseHandlerPrefixes("AllowedClientCIDRs", parseConfig.AllowedClientCIDRs); parseErr != nil {
+		return parseErr
+	}
+	if _, parseErr := parseHandlerPrefixes("DeniedClientCIDRs", parseConfig.DeniedClientCIDRs); parseErr != nil {
+		return parseErr
+	}
+	if _, parseErr := parseHandlerTrustedProxies(parseConfig.TrustedProxies); parseErr != nil {
+		return parseErr
+	}
+	if strings.TrimSpace(parseConfig.TargetAddress) != "" && len(parseConfig.Targets) > 0 {
+		return fmt.Errorf("bridge: set either TargetAddress or Targets, not both")
+	}
+	switch parseConfig.LoadBalancingPolicy {
+	case "", LoadBalancingRoundRobin, LoadBalancingLeastOutstanding, LoadBalancingStickyTunnel:
+	default:
+		return fmt.Errorf("bridge: unsupported LoadBalancingPolicy %q", parseConfig.LoadBalancingPolicy)
+	}
+	if parseConfig.HealthCheckInterval < 0 {
+		return fmt.Errorf("bridge: HealthCheckInterval must be >= 0")
+	}
+	if parseConfig.HealthCheckTimeout < 0 {
+		return fmt.Errorf("bridge: HealthCheckTimeout must be >= 0")
+	}
+	if len(parseConfig.HealthServices) > 0 && parseConfig.HealthCheckInterval <= 0 {
+		return fmt.Errorf("bridge: HealthServices requires HealthCheckInterval")
+	}
+	for _, parseService := range parseConfig.HealthServices {
+		if strings.TrimSpace(parseService) == "" {
+			return fmt.Errorf("bridge: HealthServices must not contain empty service names")
+		}
+	}
+	if parseConfig.TunnelAdmin != nil && parseConfig.TunnelRegistry != nil && parseConfig.TunnelRegistry != parseConfig.TunnelAdmin.Registry() {
+		return fmt.Errorf("bridge: TunnelRegistry must be nil or TunnelAdmin.Registry() when TunnelAdmin is set")
+	}
 	return nil
 }
 
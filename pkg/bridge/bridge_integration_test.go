@@ -159,8 +159,38 @@ func TestParseBridgeTargetURL(parseT *testing.T) {
 			parseHostValue: "127.0.0.1:50051",
 		},
 		{
-			parseName:   "https url target unsupported",
-			parseTarget: "https://127.0.0.1:50051",
+			parseName:      "https url target",
+			parseTarget:    "https://127.0.0.1:50051",
+			parseHostValue: "127.0.0.1:50051",
+		},
+		{
+			parseName:   "grpc url target unsupported",
+			parseTarget: "grpc://127.0.0.1:50051",
+			isWantError: true,
+		},
+		{
+			parseName:      "unix socket target",
+			parseTarget:    "unix:///tmp/grpc.sock",
+			parseHostValue: "localhost",
+		},
+		{
+			parseName:      "abstract unix socket target",
+			parseTarget:    "unix:@grpc-backend",
+			parseHostValue: "localhost",
+		},
+		{
+			parseName:   "unix target with host unsupported",
+			parseTarget: "unix://backend/tmp/grpc.sock",
+			isWantError: true,
+		},
+		{
+			parseName:   "relative unix target unsupported",
+			parseTarget: "unix:grpc.sock",
+			isWantError: true,
+		},
+		{
+			parseName:   "unix target missing path",
+			parseTarget: "unix://",
 			isWantError: true,
 		},
 		{
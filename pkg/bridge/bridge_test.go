@@ -168,6 +168,12 @@ func TestNewHandler_RejectsNegativeAbuseControlLimits(parseT *testing.T) {
 		{TargetAddress: "localhost:50051", MaxActiveConnections: -1},
 		{TargetAddress: "localhost:50051", MaxConnectionsPerClient: -1},
 		{TargetAddress: "localhost:50051", MaxUpgradesPerClientPerMinute: -1},
+		{TargetAddress: "localhost:50051", UpgradeRateLimit: RateLimit{PerSecond: -1}},
+		{TargetAddress: "localhost:50051", RPCRateLimit: RateLimit{Burst: 5}},
+		{TargetAddress: "localhost:50051", UpgradeRateLimit: RateLimit{PerSecond: 1}, MaxUpgradesPerClientPerMinute: 10},
+		{TargetAddress: "localhost:50051", MaxTrackedClients: -1},
+		{TargetAddress: "localhost:50051", PenaltyBox: PenaltyBox{Strikes: -1}},
+		{TargetAddress: "localhost:50051", DeniedClientCIDRs: []string{"not-an-ip"}},
 	}
 
 	for _, parseConfig := range parseTests {
@@ -198,6 +204,9 @@ func TestServeHTTP_RejectsUpgradeWhenRateLimitExceeded(parseT *testing.T) {
 	if parseWTwo.Code != http.StatusTooManyRequests {
 		parseT.Fatalf("second ServeHTTP() status = %d, want %d", parseWTwo.Code, http.StatusTooManyRequests)
 	}
+	if parseRetryAfter := parseWTwo.Header().Get("Retry-After"); parseRetryAfter != "60" {
+		parseT.Fatalf("second ServeHTTP() Retry-After = %q, want %q", parseRetryAfter, "60")
+	}
 }
 
 // TestNewHandler_NegativeBackendDialTimeoutGuard verifies invalid backend dial timeout config is rejected.
@@ -0,0 +1,170 @@
+package bridge
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// parseHandlerTrustedProxies parses TrustedProxies entries as CIDR prefixes or single IPs.
+func parseHandlerTrustedProxies(parseEntries []string) ([]netip.Prefix, error) {
+	return parseHandlerPrefixes("TrustedProxies", parseEntries)
+}
+
+// parseHandlerPrefixes parses a named list of CIDR prefixes or single IPs.
+func parseHandlerPrefixes(parseName string, parseEntries []string) ([]netip.Prefix, error) {
+	parsePrefixes := make([]netip.Prefix, 0, len(parseEntries))
+	for _, parseEntry := range parseEntries {
+		parseEntry = strings.TrimSpace(parseEntry)
+		if strings.Contains(parseEntry, "/") {
+			parsePrefix, parseErr := netip.ParsePrefix(parseEntry)
+			if parseErr != nil {
+				return nil, fmt.Errorf("bridge: invalid %s entry %q: %w", parseName, parseEntry, parseErr)
+			}
+			parsePrefixes = append(parsePrefixes, parsePrefix.Masked())
+			continue
+		}
+		parseAddr, parseErr := netip.ParseAddr(parseEntry)
+		if parseErr != nil {
+			return nil, fmt.Errorf("bridge: invalid %s entry %q: %w", parseName, parseEntry, parseErr)
+		}
+		parseAddr = parseAddr.Unmap()
+		parsePrefixes = append(parsePrefixes, netip.PrefixFrom(parseAddr, parseAddr.BitLen()))
+	}
+	return parsePrefixes, nil
+}
+
+// isHandlerPrefixMember reports whether an address belongs to one of the prefixes.
+func isHandlerPrefixMember(parsePrefixes []netip.Prefix, parseAddr netip.Addr) bool {
+	parseAddr = parseAddr.Unmap()
+	for _, parsePrefix := range parsePrefixes {
+		if parsePrefix.Contains(parseAddr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseHandlerRemoteAddr parses the IP of an http.Request RemoteAddr value.
+func parseHandlerRemoteAddr(parseRemoteAddress string) (netip.Addr, bool) {
+	parseHost := strings.TrimSpace(parseRemoteAddress)
+	if parseSplitHost, _, parseErr := net.SplitHostPort(parseHost); parseErr == nil {
+		parseHost = parseSplitHost
+	}
+	parseAddr, parseErr := netip.ParseAddr(parseHost)
+	if parseErr != nil {
+		return netip.Addr{}, false
+	}
+	return parseAddr.Unmap(), true
+}
+
+// resolveHandlerClientRequest returns a request whose RemoteAddr is the originating client when the
+// immediate peer is a trusted proxy. Forwarded (RFC 7239) takes precedence over X-Forwarded-For.
+// The forwarding chain is walked right to left and the first hop that is not a trusted proxy wins,
+// so clients cannot spoof their address by prepending entries.
+func resolveHandlerClientRequest(parseRequest *http.Request, parseTrustedProxies []netip.Prefix) *http.Request {
+	if parseRequest == nil || len(parseTrustedProxies) == 0 {
+		return parseRequest
+	}
+	parsePeerAddr, isValidPeer := parseHandlerRemoteAddr(parseRequest.RemoteAddr)
+	if !isValidPeer || !isHandlerPrefixMember(parseTrustedProxies, parsePeerAddr) {
+		return parseRequest
+	}
+
+	parseChain := parseHandlerForwardedChain(parseRequest.Header)
+	if len(parseChain) == 0 {
+		return parseRequest
+	}
+	parseClientAddress := ""
+	for parseIndex := len(parseChain) - 1; parseIndex >= 0; parseIndex-- {
+		parseHopAddr, isValidHop := parseHandlerRemoteAddr(parseChain[parseIndex])
+		if !isValidHop {
+			// Obfuscated or unknown hops end the walk at the nearest trusted proxy.
+			break
+		}
+		parseClientAddress = formatHandlerClientAddress(parseChain[parseIndex], parseHopAddr)
+		if !isHandlerPrefixMember(parseTrustedProxies, parseHopAddr) {
+			break
+		}
+	}
+	if parseClientAddress == "" {
+		return parseRequest
+	}
+
+	parseResolved := parseRequest.WithContext(parseRequest.Context())
+	parseResolved.RemoteAddr = parseClientAddress
+	return parseResolved
+}
+
+// formatHandlerClientAddress returns a forwarding hop as host:port, using port 0 when the hop carries none,
+// so downstream consumers that split RemoteAddr keep working.
+func formatHandlerClientAddress(parseHop string, parseAddr netip.Addr) string {
+	if parseAddrPort, parseErr := netip.ParseAddrPort(strings.TrimSpace(parseHop)); parseErr == nil {
+		return netip.AddrPortFrom(parseAddrPort.Addr().Unmap(), parseAddrPort.Port()).String()
+	}
+	return netip.AddrPortFrom(parseAddr, 0).String()
+}
+
+// parseHandlerForwardedChain returns forwarding hops from the Forwarded header or, when absent, X-Forwarded-For.
+func parseHandlerForwardedChain(parseHeader http.Header) []string {
+	var parseChain []string
+	for _, parseValue := range parseHeader.Values("Forwarded") {
+		for _, parseElement := range strings.Split(parseValue, ",") {
+			for _, parsePair := range strings.Split(parseElement, ";") {
+				parseKey, parseHop, isFoundPair := strings.Cut(strings.TrimSpace(parsePair), "=")
+				if !isFoundPair || !strings.EqualFold(parseKey, "for") {
+					continue
+				}
+				parseHop = strings.Trim(strings.TrimSpace(parseHop), "\"")
+				// RFC 7239 brackets IPv6 nodes; a bare bracketed address has no port to split.
+				if strings.HasPrefix(parseHop, "[") && strings.HasSuffix(parseHop, "]") {
+					parseHop = parseHop[1 : len(parseHop)-1]
+				}
+				parseChain = append(parseChain, parseHop)
+			}
+		}
+	}
+	if len(parseChain) > 0 {
+		return parseChain
+	}
+	for _, parseValue := range parseHeader.Values("X-Forwarded-For") {
+		for _, parseHop := range strings.Split(parseValue, ",") {
+			parseChain = append(parseChain, strings.TrimSpace(parseHop))
+		}
+	}
+	return parseChain
+}
+
+// handlerClientAddr is a resolved client address reported as a tunnel's remote address.
+type handlerClientAddr string
+
+// Network returns the network name of the resolved client address.
+func (handlerClientAddr) Network() string {
+	return "tcp"
+}
+
+// String returns the resolved client address.
+func (parseAddr handlerClientAddr) String() string {
+	return string(parseAddr)
+}
+
+// handlerClientAddrConn reports the resolved client address instead of the proxy peer address.
+type handlerClientAddrConn struct {
+	net.Conn
+	getRemoteAddr net.Addr
+}
+
+// RemoteAddr returns the resolved client address.
+func (parseConn *handlerClientAddrConn) RemoteAddr() net.Addr {
+	return parseConn.getRemoteAddr
+}
+
+// buildHandlerClientAddrConn makes a tunnel connection report the resolved client address when it differs from the peer.
+func buildHandlerClientAddrConn(parseConn net.Conn, parseRequest *http.Request) net.Conn {
+	if parseRequest == nil || parseConn.RemoteAddr() == nil || parseRequest.RemoteAddr == parseConn.RemoteAddr().String() {
+		return parseConn
+	}
+	return &handlerClientAddrConn{Conn: parseConn, getRemoteAddr: handlerClientAddr(parseRequest.RemoteAddr)}
+}
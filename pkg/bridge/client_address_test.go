@@ -0,0 +1,47 @@
+//lint:file-ignore SA1019 grpc.DialContext is retained in tests to match bridge integration coverage on grpc 1.x.
+
+package bridge
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+)
+
+// storeForwardedTestTransport records the X-Forwarded-For header sent to the backend.
+type storeForwardedTestTransport struct {
+	getNext      http.RoundTripper
+	getForwarded atomic.Value
+}
+
+// RoundTrip records the forwarded client address and forwards the request.
+func (parseTransport *storeForwardedTestTransport) RoundTrip(parseRequest *http.Request) (*http.Response, error) {
+	parseTransport.getForwarded.Store(parseRequest.Header.Get("X-Forwarded-For"))
+	return parseTransport.getNext.RoundTrip(parseRequest)
+}
+
+// TestHandlerTrustedProxies_ForwardsClientAddress verifies the resolved client address reaches backends and ignores spoofed prefixes.
+func TestHandlerTrustedProxies_ForwardsClientAddress(parseT *testing.T) {
+	parseBackendAddress, _ := buildPoolTestBackend(parseT, "proxied")
+	parseHandler := NewHandler(Config{TargetAddress: parseBackendAddress, TrustedProxies: []string{"127.0.0.0/8", "::1"}})
+	if parseHandler.initErr != nil {
+		parseT.Fatalf("NewHandler() error: %v", parseHandler.initErr)
+	}
+	parseTransport := &storeForwardedTestTransport{getNext: parseHandler.proxy.Transport}
+	parseHandler.proxy.Transport = parseTransport
+
+	parseForwardingHandler := http.HandlerFunc(func(parseW http.ResponseWriter, parseR *http.Request) {
+		parseR.Header.Set("X-Forwarded-For", "1.1.1.1, 198.51.100.4, 127.0.0.2")
+		parseHandler.ServeHTTP(parseW, parseR)
+	})
+	if _, parseErr := callTransportTestBridge(parseT, parseForwardingHandler); parseErr != nil {
+		parseT.Fatalf("CreateTodo() error: %v", parseErr)
+	}
+	if parseValue, _ := parseTransport.getForwarded.Load().(string); parseValue != "198.51.100.4" {
+		parseT.Fatalf("backend X-Forwarded-For = %q, want %q", parseValue, "198.51.100.4")
+	}
+
+	if parseErr := getHandlerConfigError(Config{TargetAddress: "localhost:1", TrustedProxies: []string{"not-an-ip"}}); parseErr == nil {
+		parseT.Fatal("expected error for invalid TrustedProxies entry")
+	}
+}
@@ -1,6 +1,7 @@
 package bridge
 
 import (
+	"errors"
 	"io"
 	"net"
 	"sync"
@@ -8,6 +9,7 @@ import (
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/membudget"
 )
 
 // webSocketConn adapts a gorilla/websocket.Conn to implement net.Conn.
@@ -52,6 +54,27 @@ type webSocketConn struct {
 	// writeMu serializes websocket writes because gorilla/websocket
 	// panics when multiple goroutines write concurrently.
 	writeMu sync.Mutex
+
+	// bandwidth paces reads and writes of handler tunnels. Nil leaves them unlimited.
+	bandwidth *handlerConnBandwidth
+
+	// slowClient enforces preface, read-throughput, and write deadlines of handler tunnels.
+	// Nil disables them.
+	slowClient *handlerSlowClientGuard
+
+	// memory charges handler tunnels to the memory budget and shapes their window updates.
+	// Nil leaves them unaccounted.
+	memory *membudget.Tunnel
+
+	// storeBytesRead and storeBytesWritten count tunneled bytes for TunnelObserver and TunnelRegistry.
+	storeBytesRead    atomic.Int64
+	storeBytesWritten atomic.Int64
+
+	// storeLastActivity is the Unix nanosecond time of the last read or write, or zero.
+	storeLastActivity atomic.Int64
+
+	// storeCloseCause records what ended the peer's side of the websocket.
+	storeCloseCause atomic.Pointer[TunnelCloseCause]
 }
 
 // NewWebSocketConn wraps a WebSocket connection as a net.Conn.
@@ -83,6 +106,15 @@ func NewWebSocketConn(parseWebsocketConnection *websocket.Conn) net.Conn {
 	return parseConnection
 }
 
+// newHandlerWebSocketConn adapts a handler-side websocket whose reads and writes are paced by bandwidth limits,
+// closed by slow-client limits, and charged to the memory budget.
+func newHandlerWebSocketConn(parseWebsocketConnection *websocket.Conn, parseBandwidth *handlerConnBandwidth, parseSlowClient *handlerSlowClientGuard, parseMemory *membudget.Tunnel) *webSocketConn {
+	parseConnection := &webSocketConn{websocket: parseWebsocketConnection, bandwidth: parseBandwidth, slowClient: parseSlowClient, memory: parseMemory}
+	parseSlowClient.startHandlerSlowClientGuard(parseConnection.Close)
+	parseMemory.Start(parseConnection.writeHandlerWindowCredit)
+	return parseConnection
+}
+
 // Read reads data from the WebSocket connection into p.
 // It implements the net.Conn Read method.
 //
@@ -121,26 +153,38 @@ func (parseC *webSocketConn) Read(parseDestinationBuffer []byte) (int, error) {
 			parseMessageType, parseFrameReader, parseErr := parseC.websocket.NextReader()
 			if parseErr != nil {
 				// WebSocket errors (connection closed, network errors, etc.)
+				parseC.storeHandlerReadError(parseErr)
 				return 0, parseErr
 			}
 
 			// gRPC sends data as binary, so we only accept binary WebSocket messages.
 			// Text messages indicate a protocol violation.
 			if parseMessageType != websocket.BinaryMessage {
+				parseC.storeHandlerReadError(net.ErrClosed)
 				return 0, net.ErrClosed
 			}
 			parseC.readStream = parseFrameReader
+			parseC.slowClient.storeHandlerMessageStart()
 		}
 
 		parseBytesRead, parseErr := parseC.readStream.Read(parseDestinationBuffer)
+		if parseBytesRead > 0 {
+			parseC.storeBytesRead.Add(int64(parseBytesRead))
+			parseC.storeLastActivity.Store(time.Now().UnixNano())
+		}
+		parseC.slowClient.storeHandlerReadBytes(parseBytesRead)
+		parseC.memory.ObserveRead(parseDestinationBuffer[:parseBytesRead])
 		if parseErr == io.EOF {
 			parseC.readStream = nil
-			if parseBytesRead > 0 {
-				return parseBytesRead, nil
+			parseC.slowClient.storeHandlerMessageEnd()
+			if parseBytesRead == 0 {
+				// Empty frame: continue to the next frame.
+				continue
 			}
-			// Empty frame: continue to the next frame.
-			continue
+			parseErr = nil
 		}
+		// Pacing after the read holds back the next one, so TCP backpressure throttles the peer.
+		parseC.bandwidth.waitHandlerConnBandwidth(parseHandlerBandwidthDirectionRead, parseBytesRead)
 		return parseBytesRead, parseErr
 	}
 }
@@ -171,16 +215,83 @@ func (parseC *webSocketConn) Write(parseSourceData []byte) (int, error) {
 		return 0, net.ErrClosed
 	}
 
+	if !parseC.bandwidth.waitHandlerConnBandwidth(parseHandlerBandwidthDirectionWrite, len(parseSourceData)) {
+		return 0, net.ErrClosed
+	}
+	if parseDeadline := parseC.slowClient.getHandlerWriteDeadline(); !parseDeadline.IsZero() {
+		if parseErr := parseC.websocket.SetWriteDeadline(parseDeadline); parseErr != nil {
+			return 0, parseErr
+		}
+	}
+	if parseErr := parseC.writeHandlerWindowFrame(); parseErr != nil {
+		return 0, parseErr
+	}
 	// Send the entire buffer as a single binary WebSocket message
-	parseErr := parseC.websocket.WriteMessage(websocket.BinaryMessage, parseSourceData)
+	parseErr := parseC.websocket.WriteMessage(websocket.BinaryMessage, parseC.memory.ShapeWrite(parseSourceData))
 	if parseErr != nil {
+		// A write that exceeds WriteTimeout leaves a slow consumer behind, so drop the tunnel.
+		if parseC.slowClient.isHandlerWriteTimeout(parseErr) {
+			_ = parseC.Close()
+		}
 		// WebSocket write errors (connection closed, network errors, etc.)
 		return 0, parseErr
 	}
 	// WebSocket writes are all-or-nothing, so we always write len(sourceData) bytes
+	parseC.storeBytesWritten.Add(int64(len(parseSourceData)))
+	parseC.storeLastActivity.Store(time.Now().UnixNano())
 	return len(parseSourceData), nil
 }
 
+// storeHandlerReadError records what ended the peer's side of the websocket, ignoring errors caused
+// by this side closing it.
+func (parseC *webSocketConn) storeHandlerReadError(parseErr error) {
+	if parseC.isClosed.Load() {
+		return
+	}
+	parseCause := TunnelCloseClient
+	var parseNetErr net.Error
+	if errors.As(parseErr, &parseNetErr) && parseNetErr.Timeout() {
+		parseCause = TunnelCloseIdleTimeout
+	}
+	parseC.storeCloseCause.CompareAndSwap(nil, &parseCause)
+}
+
+// getHandlerCloseCause reports why the websocket ended: a tripped slow-client limit, the peer's read
+// error, or otherwise this side closing it.
+func (parseC *webSocketConn) getHandlerCloseCause() TunnelCloseCause {
+	if parseCause := parseC.slowClient.getHandlerSlowClientCause(); parseCause != "" {
+		return parseCause
+	}
+	if parseCause := parseC.storeCloseCause.Load(); parseCause != nil {
+		return *parseCause
+	}
+	return TunnelCloseServer
+}
+
+// writeHandlerWindowCredit returns HTTP/2 window credit withheld by the memory budget once it recovers.
+func (parseC *webSocketConn) writeHandlerWindowCredit() error {
+	parseC.writeMu.Lock()
+	defer parseC.writeMu.Unlock()
+	if parseC.isClosed.Load() || parseC.websocket == nil {
+		return net.ErrClosed
+	}
+	if parseDeadline := parseC.slowClient.getHandlerWriteDeadline(); !parseDeadline.IsZero() {
+		if parseErr := parseC.websocket.SetWriteDeadline(parseDeadline); parseErr != nil {
+			return parseErr
+		}
+	}
+	return parseC.writeHandlerWindowFrame()
+}
+
+// writeHandlerWindowFrame writes any releasable window credit as its own message. Callers hold writeMu.
+func (parseC *webSocketConn) writeHandlerWindowFrame() error {
+	parseFrame := parseC.memory.TakeWindowUpdate()
+	if parseFrame == nil {
+		return nil
+	}
+	return parseC.websocket.WriteMessage(websocket.BinaryMessage, parseFrame)
+}
+
 // Close closes the WebSocket connection.
 // It implements the net.Conn Close method.
 //
@@ -193,6 +304,9 @@ func (parseC *webSocketConn) Close() error {
 	var parseCloseErr error
 	parseC.closeOnce.Do(func() {
 		parseC.isClosed.Store(true)
+		parseC.bandwidth.clearHandlerConnBandwidth()
+		parseC.slowClient.clearHandlerSlowClientGuard()
+		parseC.memory.Close()
 		if parseC.websocket == nil {
 			return
 		}
@@ -247,7 +361,7 @@ func (parseC *webSocketConn) SetDeadline(parseDeadline time.Time) error {
 	if parseErr := parseC.websocket.SetReadDeadline(parseDeadline); parseErr != nil {
 		return parseErr
 	}
-	return parseC.websocket.SetWriteDeadline(parseDeadline)
+	return parseC.setHandlerWriteDeadline(parseDeadline)
 }
 
 // SetReadDeadline sets the deadline for read operations.
@@ -285,5 +399,16 @@ func (parseC *webSocketConn) SetWriteDeadline(parseDeadline time.Time) error {
 	if parseC.websocket == nil {
 		return net.ErrClosed
 	}
+	return parseC.setHandlerWriteDeadline(parseDeadline)
+}
+
+// setHandlerWriteDeadline sets the write deadline without racing an in-flight write. gorilla/websocket
+// forbids setting it during a write, so that write gets the deadline on the socket instead, which
+// still unblocks it. Callers hold deadlineMu.
+func (parseC *webSocketConn) setHandlerWriteDeadline(parseDeadline time.Time) error {
+	if !parseC.writeMu.TryLock() {
+		return parseC.websocket.NetConn().SetWriteDeadline(parseDeadline)
+	}
+	defer parseC.writeMu.Unlock()
 	return parseC.websocket.SetWriteDeadline(parseDeadline)
 }
@@ -0,0 +1,283 @@
+package bridge
+
+import (
+	"context"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/healthmonitor"
+	"golang.org/x/net/http2"
+)
+
+const parseHandlerShutdownPollInterval = 10 * time.Millisecond
+
+// parseHandlerMaxAgeJitter spreads MaxConnectionAge by +/-10%, as gRPC servers do.
+const parseHandlerMaxAgeJitter = 0.1
+
+// parseHandlerDefaultAuthExpiryGrace bounds in-flight requests after the Authenticate context ends.
+const parseHandlerDefaultAuthExpiryGrace = 30 * time.Second
+
+const (
+	parseHandlerMaxAgeStageGoAway       = "goaway"
+	parseHandlerMaxAgeStageGraceExpired = "grace_expired"
+)
+
+// handlerTunnel stores one live websocket tunnel tracked for drain and shutdown.
+type handlerTunnel struct {
+	getID            string
+	getConn          net.Conn
+	getBaseContext   context.Context
+	getRPCLimiter    *handlerRateLimiter
+	getActiveStreams atomic.Int64
+	getStreams       atomic.Int64
+	storeCloseCause  atomic.Pointer[TunnelCloseCause]
+	getPinnedBackend atomic.Pointer[handlerBackend]
+	isClosing        atomic.Bool
+	setDrainLock     sync.Mutex
+	handleDrain      func()
+	isDrainRequested bool
+}
+
+// storeHandlerTunnelDrain installs the GOAWAY callback and runs it immediately when drain was already requested.
+func (parseTunnel *handlerTunnel) storeHandlerTunnelDrain(handleDrain func()) {
+	parseTunnel.setDrainLock.Lock()
+	parseTunnel.handleDrain = handleDrain
+	isDrainRequested := parseTunnel.isDrainRequested
+	parseTunnel.setDrainLock.Unlock()
+	if isDrainRequested && handleDrain != nil {
+		handleDrain()
+	}
+}
+
+// drainHandlerTunnel asks the tunnel to stop accepting new streams exactly once, recording parseCause
+// unless the tunnel already has a close cause.
+func (parseTunnel *handlerTunnel) drainHandlerTunnel(parseCause TunnelCloseCause) {
+	parseTunnel.storeHandlerCloseCause(parseCause)
+	parseTunnel.setDrainLock.Lock()
+	if parseTunnel.isDrainRequested {
+		parseTunnel.setDrainLock.Unlock()
+		return
+	}
+	parseTunnel.isDrainRequested = true
+	handleDrain := parseTunnel.handleDrain
+	parseTunnel.setDrainLock.Unlock()
+	if handleDrain != nil {
+		handleDrain()
+	}
+}
+
+// closeHandlerTunnel closes the tunnel connection and reports whether this call initiated the close.
+func (parseTunnel *handlerTunnel) closeHandlerTunnel() bool {
+	if !parseTunnel.isClosing.CompareAndSwap(false, true) {
+		return false
+	}
+	if parseTunnel.getConn != nil {
+		_ = parseTunnel.getConn.Close()
+	}
+	return true
+}
+
+// getHandlerTunnelMaxAge returns MaxConnectionAge with random jitter, so tunnels opened together rotate apart.
+func getHandlerTunnelMaxAge(parseMaxAge time.Duration) time.Duration {
+	parseJitter := (rand.Float64()*2 - 1) * parseHandlerMaxAgeJitter
+	return parseMaxAge + time.Duration(float64(parseMaxAge)*parseJitter)
+}
+
+// startHandlerTunnelMaxAge drains the tunnel at its jittered MaxConnectionAge and closes it once
+// MaxConnectionAgeGrace has passed. The returned callback stops both timers.
+func (parseH *Handler) startHandlerTunnelMaxAge(parseTunnel *handlerTunnel, parseRequest *http.Request) func() {
+	if parseH.config.MaxConnectionAge <= 0 {
+		return func() {}
+	}
+	var parseTimerLock sync.Mutex
+	var parseGraceTimer *time.Timer
+	isStopped := false
+	parseAgeTimer := time.AfterFunc(getHandlerTunnelMaxAge(parseH.config.MaxConnectionAge), func() {
+		logBridgeEvent(parseH.logger, "INFO", "tunnel_max_age", parseRequest, nil, "Tunnel reached MaxConnectionAge; draining tunnel")
+		parseH.observability.storeHandlerTunnelMaxAge(parseRequest.Context(), parseHandlerMaxAgeStageGoAway)
+		parseTunnel.drainHandlerTunnel(TunnelCloseMaxAge)
+		if parseH.config.MaxConnectionAgeGrace <= 0 {
+			return
+		}
+		parseTimerLock.Lock()
+		defer parseTimerLock.Unlock()
+		if isStopped {
+			return
+		}
+		parseGraceTimer = time.AfterFunc(parseH.config.MaxConnectionAgeGrace, func() {
+			// Tunnels without streams are already closing after GOAWAY; only count cut-off RPCs.
+			isStreamActive := parseTunnel.getActiveStreams.Load() > 0
+			if parseTunnel.closeHandlerTunnel() && isStreamActive {
+				logBridgeEvent(parseH.logger, "WARN", "tunnel_max_age_grace_expired", parseRequest, nil, "Tunnel closed with RPCs in flight after MaxConnectionAgeGrace")
+				parseH.observability.storeHandlerTunnelMaxAge(parseRequest.Context(), parseHandlerMaxAgeStageGraceExpired)
+			}
+		})
+	})
+	return func() {
+		parseTimerLock.Lock()
+		defer parseTimerLock.Unlock()
+		isStopped = true
+		parseAgeTimer.Stop()
+		if parseGraceTimer != nil {
+			parseGraceTimer.Stop()
+		}
+	}
+}
+
+// startHandlerTunnelAuthExpiry drains the tunnel when parseExpiryContext is done, so in-flight
+// requests can finish, and closes it once AuthExpiryGrace has passed so no stale session outlives
+// its credential. The returned callback stops both timers.
+func (parseH *Handler) startHandlerTunnelAuthExpiry(parseTunnel *handlerTunnel, parseExpiryContext context.Context, parseRequest *http.Request) func() {
+	if parseExpiryContext == nil {
+		return func() {}
+	}
+	parseGrace := parseH.config.AuthExpiryGrace
+	if parseGrace <= 0 {
+		parseGrace = parseHandlerDefaultAuthExpiryGrace
+	}
+	var parseTimerLock sync.Mutex
+	var parseGraceTimer *time.Timer
+	isStopped := false
+	clearExpiry := context.AfterFunc(parseExpiryContext, func() {
+		logBridgeEvent(parseH.logger, "INFO", "tunnel_auth_expired", parseRequest, context.Cause(parseExpiryContext), "Tunnel authentication expired; draining tunnel")
+		parseTunnel.drainHandlerTunnel(TunnelCloseAuthExpired)
+		parseTimerLock.Lock()
+		defer parseTimerLock.Unlock()
+		if isStopped {
+			return
+		}
+		parseGraceTimer = time.AfterFunc(parseGrace, func() {
+			isStreamActive := parseTunnel.getActiveStreams.Load() > 0
+			if parseTunnel.closeHandlerTunnel() && isStreamActive {
+				logBridgeEvent(parseH.logger, "WARN", "tunnel_auth_expired_grace_expired", parseRequest, nil, "Tunnel closed with requests in flight after AuthExpiryGrace")
+			}
+		})
+	})
+	return func() {
+		parseTimerLock.Lock()
+		defer parseTimerLock.Unlock()
+		isStopped = true
+		clearExpiry()
+		if parseGraceTimer != nil {
+			parseGraceTimer.Stop()
+		}
+	}
+}
+
+// handlerTunnelTracker stores active tunnels so the bridge handler can drain and shut down gracefully.
+type handlerTunnelTracker struct {
+	setTrackerLock sync.Mutex
+	storeTunnels   map[*handlerTunnel]struct{}
+	isDraining     atomic.Bool
+	getHealth      *healthmonitor.Reporter
+}
+
+// buildHandlerTunnelTracker creates an empty tunnel tracker that reports drain to parseHealth.
+func buildHandlerTunnelTracker(parseHealth *healthmonitor.Reporter) *handlerTunnelTracker {
+	return &handlerTunnelTracker{
+		storeTunnels: map[*handlerTunnel]struct{}{},
+		getHealth:    parseHealth,
+	}
+}
+
+// storeHandlerTunnel registers one live tunnel and drains it immediately when the tracker is draining.
+func (parseTracker *handlerTunnelTracker) storeHandlerTunnel(parseTunnel *handlerTunnel) {
+	parseTracker.setTrackerLock.Lock()
+	parseTracker.storeTunnels[parseTunnel] = struct{}{}
+	parseTracker.setTrackerLock.Unlock()
+	if parseTracker.isDraining.Load() {
+		parseTunnel.drainHandlerTunnel(TunnelCloseDrain)
+	}
+}
+
+// clearHandlerTunnel unregisters one tunnel after its connection has been released.
+func (parseTracker *handlerTunnelTracker) clearHandlerTunnel(parseTunnel *handlerTunnel) {
+	parseTracker.setTrackerLock.Lock()
+	delete(parseTracker.storeTunnels, parseTunnel)
+	parseTracker.setTrackerLock.Unlock()
+}
+
+// getHandlerTunnels returns a snapshot of live tunnels.
+func (parseTracker *handlerTunnelTracker) getHandlerTunnels() []*handlerTunnel {
+	parseTracker.setTrackerLock.Lock()
+	defer parseTracker.setTrackerLock.Unlock()
+	parseTunnels := make([]*handlerTunnel, 0, len(parseTracker.storeTunnels))
+	for parseTunnel := range parseTracker.storeTunnels {
+		parseTunnels = append(parseTunnels, parseTunnel)
+	}
+	return parseTunnels
+}
+
+// drainHandlerTunnels stops new upgrades and sends GOAWAY to every live tunnel.
+func (parseTracker *handlerTunnelTracker) drainHandlerTunnels() {
+	parseTracker.isDraining.Store(true)
+	parseTracker.getHealth.SetDraining(true)
+	for _, parseTunnel := range parseTracker.getHandlerTunnels() {
+		parseTunnel.drainHandlerTunnel(TunnelCloseDrain)
+	}
+}
+
+// shutdownHandlerTunnels drains tunnels, closes idle ones, and force-closes the rest when the context ends.
+// It returns the number of tunnels that were force-closed with streams still in flight.
+func (parseTracker *handlerTunnelTracker) shutdownHandlerTunnels(parseCtx context.Context) (int, error) {
+	parseTracker.drainHandlerTunnels()
+
+	parseTicker := time.NewTicker(parseHandlerShutdownPollInterval)
+	defer parseTicker.Stop()
+	for {
+		parseTunnels := parseTracker.getHandlerTunnels()
+		if len(parseTunnels) == 0 {
+			return 0, nil
+		}
+		for _, parseTunnel := range parseTunnels {
+			if parseTunnel.getActiveStreams.Load() == 0 {
+				parseTunnel.closeHandlerTunnel()
+			}
+		}
+
+		select {
+		case <-parseCtx.Done():
+			parseForceClosed := 0
+			for _, parseTunnel := range parseTracker.getHandlerTunnels() {
+				// Tunnels that went idle at the deadline are closed without being counted.
+				isStreamActive := parseTunnel.getActiveStreams.Load() > 0
+				if parseTunnel.closeHandlerTunnel() && isStreamActive {
+					parseForceClosed++
+				}
+			}
+			return parseForceClosed, parseCtx.Err()
+		case <-parseTicker.C:
+		}
+	}
+}
+
+// buildHandlerTunnelHTTP2Server creates a per-tunnel HTTP/2 server plus the base server used to trigger its GOAWAY.
+func buildHandlerTunnelHTTP2Server(parseConfig Config) (*http2.Server, *http.Server) {
+	parseSettings := buildHTTP2Settings(parseConfig.HTTP2Profile, parseConfig.HTTP2)
+	parseHTTP2Server := &http2.Server{
+		MaxConcurrentStreams:         parseSettings.MaxConcurrentStreams,
+		MaxReadFrameSize:             parseSettings.MaxReadFrameSize,
+		MaxUploadBufferPerStream:     parseSettings.InitialStreamWindowSize,
+		MaxUploadBufferPerConnection: parseSettings.InitialConnWindowSize,
+		IdleTimeout:                  parseSettings.IdleTimeout,
+	}
+	// ServeConn reads the header list limit from its base server.
+	parseShutdownServer := &http.Server{MaxHeaderBytes: int(parseSettings.MaxHeaderListSize)}
+	// ConfigureServer registers the HTTP/2 graceful shutdown hook on the base server,
+	// which lets one tunnel receive GOAWAY without touching any other tunnel.
+	_ = http2.ConfigureServer(parseShutdownServer, parseHTTP2Server)
+	return parseHTTP2Server, parseShutdownServer
+}
+
+// buildHandlerTunnelDrain returns a callback that sends HTTP/2 GOAWAY on one tunnel.
+func buildHandlerTunnelDrain(parseShutdownServer *http.Server) func() {
+	return func() {
+		// Shutdown returns immediately: the base server never listens and hijacked
+		// tunnel connections are not tracked by net/http.
+		_ = parseShutdownServer.Shutdown(context.Background())
+	}
+}
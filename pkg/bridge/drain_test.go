@@ -0,0 +1,169 @@
+//lint:file-ignore SA1019 grpc.DialContext is retained in tests to match bridge integration coverage on grpc 1.x.
+
+package bridge
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/monstercameron/grpc-tunnel/examples/_shared/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// buildDrainTestTodoService blocks streaming RPCs until released so drain behavior can be observed.
+type buildDrainTestTodoService struct {
+	proto.UnimplementedTodoServiceServer
+	getStreamStarted chan struct{}
+	getStreamRelease chan struct{}
+}
+
+// StreamTodos sends one todo, then blocks until the test releases the stream or the RPC ends.
+func (parseS *buildDrainTestTodoService) StreamTodos(parseReq *proto.StreamTodosRequest, parseStream proto.TodoService_StreamTodosServer) error {
+	if parseErr := parseStream.Send(&proto.StreamTodosResponse{Todo: &proto.Todo{Id: "1"}}); parseErr != nil {
+		return parseErr
+	}
+	parseS.getStreamStarted <- struct{}{}
+	select {
+	case <-parseS.getStreamRelease:
+		return parseStream.Send(&proto.StreamTodosResponse{Todo: &proto.Todo{Id: "2"}})
+	case <-parseStream.Context().Done():
+		return parseStream.Context().Err()
+	}
+}
+
+// buildDrainTestBridge starts a streaming backend plus a bridge handler in front of it.
+func buildDrainTestBridge(parseT *testing.T) (*Handler, *buildDrainTestTodoService, string, func()) {
+	parseT.Helper()
+
+	parseBackendListener, parseErr := net.Listen("tcp", "127.0.0.1:0")
+	if parseErr != nil {
+		parseT.Fatalf("Listen() error: %v", parseErr)
+	}
+	parseService := &buildDrainTestTodoService{
+		getStreamStarted: make(chan struct{}, 1),
+		getStreamRelease: make(chan struct{}),
+	}
+	parseBackendServer := grpc.NewServer()
+	proto.RegisterTodoServiceServer(parseBackendServer, parseService)
+	go func() {
+		_ = parseBackendServer.Serve(parseBackendListener)
+	}()
+
+	parseHandler := NewHandler(Config{TargetAddress: parseBackendListener.Addr().String()})
+	parseBridgeServer := httptest.NewServer(parseHandler)
+	return parseHandler, parseService, "ws" + strings.TrimPrefix(parseBridgeServer.URL, "http"), func() {
+		parseBridgeServer.Close()
+		parseBackendServer.Stop()
+	}
+}
+
+// startDrainTestStream opens a tunnel through the bridge and starts one blocking server stream.
+func startDrainTestStream(parseT *testing.T, parseCtx context.Context, parseService *buildDrainTestTodoService, parseBridgeURL string) (*grpc.ClientConn, proto.TodoService_StreamTodosClient) {
+	parseT.Helper()
+
+	parseClientConn, parseErr := grpc.DialContext(
+		parseCtx,
+		"ignored:1234",
+		DialOption(parseBridgeURL),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if parseErr != nil {
+		parseT.Fatalf("DialContext() error: %v", parseErr)
+	}
+	parseStream, parseErr := proto.NewTodoServiceClient(parseClientConn).StreamTodos(parseCtx, &proto.StreamTodosRequest{})
+	if parseErr != nil {
+		parseT.Fatalf("StreamTodos() error: %v", parseErr)
+	}
+	if _, parseErr = parseStream.Recv(); parseErr != nil {
+		parseT.Fatalf("Recv() error: %v", parseErr)
+	}
+	select {
+	case <-parseService.getStreamStarted:
+	case <-time.After(2 * time.Second):
+		parseT.Fatal("timed out waiting for stream to start")
+	}
+	return parseClientConn, parseStream
+}
+
+// TestHandlerDrain_RejectsNewUpgrades verifies a draining handler refuses upgrades with 503.
+func TestHandlerDrain_RejectsNewUpgrades(parseT *testing.T) {
+	parseHandler := NewHandler(Config{TargetAddress: "localhost:50051"})
+	parseHandler.Drain()
+
+	parseRecorder := httptest.NewRecorder()
+	parseHandler.ServeHTTP(parseRecorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	if parseRecorder.Code != http.StatusServiceUnavailable {
+		parseT.Fatalf("ServeHTTP() status = %d, want %d", parseRecorder.Code, http.StatusServiceUnavailable)
+	}
+}
+
+// TestHandlerShutdown_WaitsForInFlightStream verifies Shutdown lets a proxied stream finish before closing its tunnel.
+func TestHandlerShutdown_WaitsForInFlightStream(parseT *testing.T) {
+	parseHandler, parseService, parseBridgeURL, clearBridge := buildDrainTestBridge(parseT)
+	defer clearBridge()
+
+	parseCtx, clearCtx := context.WithTimeout(context.Background(), 5*time.Second)
+	defer clearCtx()
+	parseClientConn, parseStream := startDrainTestStream(parseT, parseCtx, parseService, parseBridgeURL)
+	defer parseClientConn.Close()
+
+	parseShutdownErr := make(chan error, 1)
+	go func() {
+		parseForceClosed, parseErr := parseHandler.Shutdown(parseCtx)
+		if parseErr == nil && parseForceClosed != 0 {
+			parseErr = errors.New("unexpected force-closed tunnels")
+		}
+		parseShutdownErr <- parseErr
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	if parseHandler.ActiveTunnels() != 1 {
+		parseT.Fatalf("ActiveTunnels() during drain = %d, want 1", parseHandler.ActiveTunnels())
+	}
+	close(parseService.getStreamRelease)
+
+	parseResp, parseErr := parseStream.Recv()
+	if parseErr != nil {
+		parseT.Fatalf("Recv() after drain error: %v", parseErr)
+	}
+	if parseResp.Todo.Id != "2" {
+		parseT.Fatalf("Recv() after drain id = %q, want %q", parseResp.Todo.Id, "2")
+	}
+
+	select {
+	case parseErr = <-parseShutdownErr:
+		if parseErr != nil {
+			parseT.Fatalf("Shutdown() error: %v", parseErr)
+		}
+	case <-time.After(3 * time.Second):
+		parseT.Fatal("Shutdown() did not return after stream completed")
+	}
+}
+
+// TestHandlerShutdown_ForceClosesAtDeadline verifies Shutdown reports tunnels force-closed when the deadline passes.
+func TestHandlerShutdown_ForceClosesAtDeadline(parseT *testing.T) {
+	parseHandler, parseService, parseBridgeURL, clearBridge := buildDrainTestBridge(parseT)
+	defer clearBridge()
+
+	parseCtx, clearCtx := context.WithTimeout(context.Background(), 5*time.Second)
+	defer clearCtx()
+	parseClientConn, _ := startDrainTestStream(parseT, parseCtx, parseService, parseBridgeURL)
+	defer parseClientConn.Close()
+
+	parseShutdownCtx, clearShutdown := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer clearShutdown()
+	parseForceClosed, parseErr := parseHandler.Shutdown(parseShutdownCtx)
+	if !errors.Is(parseErr, context.DeadlineExceeded) {
+		parseT.Fatalf("Shutdown() error = %v, want %v", parseErr, context.DeadlineExceeded)
+	}
+	if parseForceClosed != 1 {
+		parseT.Fatalf("Shutdown() force closed = %d, want 1", parseForceClosed)
+	}
+}
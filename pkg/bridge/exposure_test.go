@@ -0,0 +1,93 @@
+//lint:file-ignore SA1019 grpc.DialContext is retained in tests to match bridge integration coverage on grpc 1.x.
+
+package bridge
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// storeExposureTestTransport counts requests that reach the backend transport.
+type storeExposureTestTransport struct {
+	getNext  http.RoundTripper
+	getCalls atomic.Int64
+}
+
+// RoundTrip counts the request and forwards it.
+func (parseTransport *storeExposureTestTransport) RoundTrip(parseRequest *http.Request) (*http.Response, error) {
+	parseTransport.getCalls.Add(1)
+	return parseTransport.getNext.RoundTrip(parseRequest)
+}
+
+// TestExposurePolicy_IsExposed verifies glob matching and deny precedence.
+func TestExposurePolicy_IsExposed(parseT *testing.T) {
+	parsePolicy := ExposurePolicy{
+		Allow: []string{"/TodoService/*", "/grpc.health.v1.Health/Check"},
+		Deny:  []string{"/TodoService/Delete*"},
+	}
+	parseTests := []struct {
+		parseMethod string
+		isExposed   bool
+	}{
+		{parseMethod: "/TodoService/CreateTodo", isExposed: true},
+		{parseMethod: "/TodoService/DeleteTodo", isExposed: false},
+		{parseMethod: "/grpc.health.v1.Health/Check", isExposed: true},
+		{parseMethod: "/grpc.health.v1.Health/Watch", isExposed: false},
+		{parseMethod: "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo", isExposed: false},
+	}
+	for _, parseTestCase := range parseTests {
+		if isExposed := parsePolicy.isExposed(parseTestCase.parseMethod); isExposed != parseTestCase.isExposed {
+			parseT.Fatalf("isExposed(%q) = %v, want %v", parseTestCase.parseMethod, isExposed, parseTestCase.isExposed)
+		}
+	}
+	if !(ExposurePolicy{}).isExposed("/any.Service/Method") {
+		parseT.Fatal("zero ExposurePolicy should expose every method")
+	}
+}
+
+// TestGetExposurePolicyError verifies malformed patterns fail handler validation.
+func TestGetExposurePolicyError(parseT *testing.T) {
+	if parseErr := getExposurePolicyError(ExposurePolicy{Allow: []string{"TodoService/*"}}); parseErr == nil {
+		parseT.Fatal("expected error for pattern without leading slash")
+	}
+	if parseErr := getExposurePolicyError(ExposurePolicy{Deny: []string{"/TodoService/["}}); parseErr == nil {
+		parseT.Fatal("expected error for malformed pattern")
+	}
+	if parseHandler := NewHandler(Config{TargetAddress: "localhost:50051", ExposurePolicy: ExposurePolicy{Allow: []string{"bad"}}}); parseHandler.initErr == nil {
+		parseT.Fatal("NewHandler() should reject an invalid ExposurePolicy")
+	}
+}
+
+// TestHandlerExposurePolicy_DeniesBeforeBackend verifies denied RPCs get PermissionDenied without reaching a backend.
+func TestHandlerExposurePolicy_DeniesBeforeBackend(parseT *testing.T) {
+	parseBackendAddress, _ := buildPoolTestBackend(parseT, "exposed")
+	parseHandler := NewHandler(Config{
+		TargetAddress:  parseBackendAddress,
+		ExposurePolicy: ExposurePolicy{Deny: []string{"/TodoService/CreateTodo"}},
+	})
+	if parseHandler.initErr != nil {
+		parseT.Fatalf("NewHandler() error: %v", parseHandler.initErr)
+	}
+	parseTransport := &storeExposureTestTransport{getNext: parseHandler.proxy.Transport}
+	parseHandler.proxy.Transport = parseTransport
+
+	_, parseErr := callTransportTestBridge(parseT, parseHandler)
+	if status.Code(parseErr) != codes.PermissionDenied {
+		parseT.Fatalf("CreateTodo() error = %v, want %v", parseErr, codes.PermissionDenied)
+	}
+	if parseCalls := parseTransport.getCalls.Load(); parseCalls != 0 {
+		parseT.Fatalf("backend requests = %d, want 0", parseCalls)
+	}
+
+	parseHandler.config.ExposurePolicy = ExposurePolicy{Allow: []string{"/TodoService/*"}}
+	if _, parseErr = callTransportTestBridge(parseT, parseHandler); parseErr != nil {
+		parseT.Fatalf("CreateTodo() allowed error: %v", parseErr)
+	}
+	if parseCalls := parseTransport.getCalls.Load(); parseCalls != 1 {
+		parseT.Fatalf("backend requests = %d, want 1", parseCalls)
+	}
+}
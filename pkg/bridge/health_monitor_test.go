@@ -0,0 +1,69 @@
+package bridge
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/healthmonitor"
+	grpc_health_v1 "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// TestHandlerHealthMonitor verifies backend probes drive per-service and overall readiness, /readyz
+// on the handler agrees, and Drain turns the handler NOT_SERVING.
+func TestHandlerHealthMonitor(parseT *testing.T) {
+	parseBackendAddr, parseBackendHealth := buildPoolTestBackend(parseT, "probed")
+	parseBackendHealth.SetServingStatus("TodoService", grpc_health_v1.HealthCheckResponse_SERVING)
+	parseMonitor := healthmonitor.New()
+	parseHandler := NewHandler(Config{
+		TargetAddress:       parseBackendAddr,
+		HealthCheckInterval: 20 * time.Millisecond,
+		HealthServices:      []string{"TodoService"},
+		HealthMonitor:       parseMonitor,
+	})
+	if parseHandler.initErr != nil {
+		parseT.Fatalf("NewHandler() error: %v", parseHandler.initErr)
+	}
+	defer parseHandler.backendPool.stopHandlerHealthChecks()
+	parseServer := httptest.NewServer(parseHandler)
+	defer parseServer.Close()
+
+	waitStatus := func(parseService string, parseWant grpc_health_v1.HealthCheckResponse_ServingStatus) {
+		parseT.Helper()
+		for parseDeadline := time.Now().Add(5 * time.Second); parseMonitor.Status(parseService) != parseWant; time.Sleep(10 * time.Millisecond) {
+			if time.Now().After(parseDeadline) {
+				parseT.Fatalf("Status(%q) = %s, want %s", parseService, parseMonitor.Status(parseService), parseWant)
+			}
+		}
+	}
+	getReadyCode := func(parseQuery string) int {
+		parseT.Helper()
+		parseResponse, parseErr := http.Get(parseServer.URL + "/readyz" + parseQuery)
+		if parseErr != nil {
+			parseT.Fatalf("Get() error: %v", parseErr)
+		}
+		parseResponse.Body.Close()
+		return parseResponse.StatusCode
+	}
+
+	waitStatus("TodoService", grpc_health_v1.HealthCheckResponse_SERVING)
+	parseBackendHealth.SetServingStatus("TodoService", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	waitStatus("TodoService", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	if parseCode := getReadyCode("?service=TodoService"); parseCode != http.StatusServiceUnavailable {
+		parseT.Fatalf("GET /readyz?service=TodoService = %d, want 503", parseCode)
+	}
+	if parseCode := getReadyCode(""); parseCode != http.StatusOK {
+		parseT.Fatalf("GET /readyz = %d, want 200 while the backend passes overall checks", parseCode)
+	}
+
+	parseBackendHealth.SetServingStatus("", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	waitStatus("", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	parseBackendHealth.SetServingStatus("", grpc_health_v1.HealthCheckResponse_SERVING)
+	waitStatus("", grpc_health_v1.HealthCheckResponse_SERVING)
+
+	parseHandler.Drain()
+	if parseCode := getReadyCode(""); parseCode != http.StatusServiceUnavailable {
+		parseT.Fatalf("GET /readyz after Drain = %d, want 503", parseCode)
+	}
+}
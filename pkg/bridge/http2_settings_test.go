@@ -0,0 +1,86 @@
+package bridge
+
+import (
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"golang.org/x/net/http2"
+)
+
+// TestGetHandlerConfigError_HTTP2 verifies HTTP/2 profile and settings validation.
+func TestGetHandlerConfigError_HTTP2(parseT *testing.T) {
+	for _, parseConfig := range []Config{
+		{HTTP2Profile: "fastest"},
+		{HTTP2: HTTP2Settings{MaxReadFrameSize: 1024}},
+		{HTTP2: HTTP2Settings{InitialStreamWindowSize: 1024}},
+		{HTTP2: HTTP2Settings{InitialConnWindowSize: -1}},
+		{HTTP2: HTTP2Settings{IdleTimeout: -time.Second}},
+	} {
+		if parseErr := getHandlerConfigError(parseConfig); parseErr == nil {
+			parseT.Fatalf("getHandlerConfigError(%+v) = nil, want error", parseConfig)
+		}
+	}
+}
+
+// TestBuildHandlerBackendTransport_HTTP2Settings verifies backend transports receive the settings they support.
+func TestBuildHandlerBackendTransport_HTTP2Settings(parseT *testing.T) {
+	parseConfig := Config{HTTP2Profile: HTTP2ProfileConstrainedMemory, HTTP2: HTTP2Settings{IdleTimeout: time.Minute}}
+	parseTransport := buildHandlerBackendTransport(parseConfig, &net.Dialer{}, nil).(*handlerBackendTransport)
+	for _, parseRoundTripper := range []any{parseTransport.getPlaintextTransport, parseTransport.getTLSTransport} {
+		parseHTTP2Transport := parseRoundTripper.(*http2.Transport)
+		if parseHTTP2Transport.MaxReadFrameSize != 16<<10 || parseHTTP2Transport.MaxHeaderListSize != 16<<10 || parseHTTP2Transport.IdleConnTimeout != time.Minute {
+			parseT.Fatalf("transport settings = %d, %d, %v; want 16384, 16384, 1m", parseHTTP2Transport.MaxReadFrameSize, parseHTTP2Transport.MaxHeaderListSize, parseHTTP2Transport.IdleConnTimeout)
+		}
+	}
+}
+
+// TestHandlerHTTP2Settings verifies the tunnel's HTTP/2 server advertises the configured settings.
+func TestHandlerHTTP2Settings(parseT *testing.T) {
+	parseBackendAddress, _ := buildPoolTestBackend(parseT, "tuned")
+	parseHandler := NewHandler(Config{
+		TargetAddress: parseBackendAddress,
+		HTTP2Profile:  HTTP2ProfileLowLatency,
+		HTTP2:         HTTP2Settings{InitialStreamWindowSize: 1 << 20},
+	})
+	if parseHandler.initErr != nil {
+		parseT.Fatalf("NewHandler() error: %v", parseHandler.initErr)
+	}
+	parseServer := httptest.NewServer(parseHandler)
+	defer parseServer.Close()
+
+	parseWebsocket, _, parseErr := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(parseServer.URL, "http"), nil)
+	if parseErr != nil {
+		parseT.Fatalf("Dial() error: %v", parseErr)
+	}
+	parseConn := NewWebSocketConn(parseWebsocket)
+	defer parseConn.Close()
+	if _, parseErr := parseConn.Write([]byte(http2.ClientPreface)); parseErr != nil {
+		parseT.Fatalf("Write(preface) error: %v", parseErr)
+	}
+	parseFramer := http2.NewFramer(parseConn, parseConn)
+	if parseErr := parseFramer.WriteSettings(); parseErr != nil {
+		parseT.Fatalf("WriteSettings() error: %v", parseErr)
+	}
+	_ = parseConn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	parseFrame, parseErr := parseFramer.ReadFrame()
+	if parseErr != nil {
+		parseT.Fatalf("ReadFrame() error: %v", parseErr)
+	}
+	parseSettingsFrame, isSettingsFrame := parseFrame.(*http2.SettingsFrame)
+	if !isSettingsFrame {
+		parseT.Fatalf("first frame = %T, want *http2.SettingsFrame", parseFrame)
+	}
+	for parseID, parseWant := range map[http2.SettingID]uint32{
+		http2.SettingMaxConcurrentStreams: 250,
+		http2.SettingMaxFrameSize:         16 << 10,
+		http2.SettingInitialWindowSize:    1 << 20,
+	} {
+		if parseValue, isFound := parseSettingsFrame.Value(parseID); !isFound || parseValue != parseWant {
+			parseT.Fatalf("%v = %d (found=%v), want %d", parseID, parseValue, isFound, parseWant)
+		}
+	}
+}
@@ -0,0 +1,378 @@
+package bridge
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/tunneladmin"
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/tunneladmin/adminv1"
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/tunnelregistry"
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/tunnelstate"
+	"golang.org/x/net/http2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// buildHandlerTunnelID returns a random 128-bit tunnel ID in hex.
+func buildHandlerTunnelID() string {
+	var parseID [16]byte
+	_, _ = rand.Read(parseID[:])
+	return hex.EncodeToString(parseID[:])
+}
+
+// handlerTunnelIDContextKey stores the tunnel ID on a proxied request context.
+type handlerTunnelIDContextKey struct{}
+
+// storeHandlerBackendDialFailed records a backend_dial_failed transition for a proxied request the
+// reverse proxy could not send. Requests the client canceled are not backend failures.
+func storeHandlerBackendDialFailed(parseStates *tunnelstate.Recorder, parseRequest *http.Request, parseErr error) {
+	if parseStates == nil || errors.Is(parseErr, context.Canceled) {
+		return
+	}
+	parseTunnelID, _ := parseRequest.Context().Value(handlerTunnelIDContextKey{}).(string)
+	parseTransition := tunnelstate.Transition{
+		Time:       time.Now(),
+		Component:  "bridge.handler",
+		TunnelID:   parseTunnelID,
+		State:      tunnelstate.StateBackendDialFailed,
+		Target:     parseRequest.URL.Path,
+		RemoteAddr: parseRequest.RemoteAddr,
+		ErrorClass: codes.Unavailable.String(),
+	}
+	if parseErr != nil {
+		parseTransition.ErrorMessage = parseErr.Error()
+	}
+	parseStates.Record(parseTransition)
+}
+
+// storeHandlerUpgradeRejected reports one refused upgrade to the tunnel observer.
+func storeHandlerUpgradeRejected(parseObserver TunnelObserver, parseRequest *http.Request, parseReason UpgradeRejectReason, parseStatusCode int, parseErr error) {
+	if parseObserver == nil {
+		return
+	}
+	parseObserver.OnUpgradeRejected(UpgradeRejectedEvent{
+		Request:    parseRequest,
+		Reason:     parseReason,
+		StatusCode: parseStatusCode,
+		Err:        parseErr,
+		Time:       time.Now(),
+	})
+}
+
+// getHandlerAbuseRejectReason returns the observer reason and HTTP status of an abuse-control rejection.
+func getHandlerAbuseRejectReason(parseErr error) (UpgradeRejectReason, int) {
+	var parseRejection *handlerAbuseRejection
+	if !errors.As(parseErr, &parseRejection) {
+		return UpgradeRejectUpgradeRate, http.StatusTooManyRequests
+	}
+	return UpgradeRejectReason(parseRejection.getReason), parseRejection.getStatusCode
+}
+
+// storeHandlerCloseCause records why the tunnel is ending; only the first cause is kept.
+func (parseTunnel *handlerTunnel) storeHandlerCloseCause(parseCause TunnelCloseCause) {
+	parseTunnel.storeCloseCause.CompareAndSwap(nil, &parseCause)
+}
+
+// getHandlerCloseCause returns the recorded close cause, falling back to what ended the websocket.
+func (parseTunnel *handlerTunnel) getHandlerCloseCause(parseConn *webSocketConn) TunnelCloseCause {
+	if parseCause := parseTunnel.storeCloseCause.Load(); parseCause != nil {
+		return *parseCause
+	}
+	return parseConn.getHandlerCloseCause()
+}
+
+// startHandlerStream counts one stream on the tunnel and reports it opened. The returned callback
+// reports it closed with the gRPC status found in the response headers or trailers.
+func startHandlerStream(parseObserver TunnelObserver, parseTunnel *handlerTunnel, parseMethod string) func(http.Header) {
+	parseTunnel.getStreams.Add(1)
+	if parseObserver == nil {
+		return func(http.Header) {}
+	}
+	parseStart := time.Now()
+	parseObserver.OnStreamOpened(StreamOpenedEvent{
+		TunnelID: parseTunnel.getID,
+		Method:   parseMethod,
+		Time:     parseStart,
+	})
+	return func(parseHeader http.Header) {
+		parseNow := time.Now()
+		parseObserver.OnStreamClosed(StreamClosedEvent{
+			TunnelID: parseTunnel.getID,
+			Method:   parseMethod,
+			Code:     getHandlerStreamCode(parseHeader),
+			Duration: parseNow.Sub(parseStart),
+			Time:     parseNow,
+		})
+	}
+}
+
+// getHandlerStreamCode reads grpc-status from a finished response, which gRPC sets as a header on
+// trailers-only responses and as a trailer otherwise.
+func getHandlerStreamCode(parseHeader http.Header) codes.Code {
+	parseValue := parseHeader.Get("Grpc-Status")
+	if parseValue == "" {
+		parseValue = parseHeader.Get(http2.TrailerPrefix + "Grpc-Status")
+	}
+	parseCode, parseErr := strconv.ParseUint(parseValue, 10, 32)
+	if parseErr != nil {
+		return codes.Unknown
+	}
+	return codes.Code(parseCode)
+}
+
+// storeHandlerTunnelConnected reports a tunnel connected and returns the callback that reports it
+// disconnected once its connection has been released.
+func storeHandlerTunnelConnected(parseObserver TunnelObserver, parseTunnel *handlerTunnel, parseConn *webSocketConn, parseRequest *http.Request) func() {
+	if parseObserver == nil {
+		return func() {}
+	}
+	parseStart := time.Now()
+	parseObserver.OnTunnelConnected(TunnelConnectedEvent{
+		TunnelID: parseTunnel.getID,
+		Request:  parseRequest,
+		Time:     parseStart,
+	})
+	return func() {
+		parseNow := time.Now()
+		parseObserver.OnTunnelDisconnected(TunnelDisconnectedEvent{
+			TunnelID:     parseTunnel.getID,
+			Request:      parseRequest,
+			Cause:        parseTunnel.getHandlerCloseCause(parseConn),
+			Duration:     parseNow.Sub(parseStart),
+			BytesRead:    parseConn.storeBytesRead.Load(),
+			BytesWritten: parseConn.storeBytesWritten.Load(),
+			Streams:      parseTunnel.getStreams.Load(),
+			Time:         parseNow,
+		})
+	}
+}
+
+// storeHandlerTunnelRegistry registers the tunnel with the handler's TunnelRegistry, whose Close
+// force-closes it with the terminated cause. Without a registry it returns nil, which is safe to Remove.
+func (parseH *Handler) storeHandlerTunnelRegistry(parseTunnel *handlerTunnel, parseConn *webSocketConn, parseRequest *http.Request, parseClientKey string) *tunnelregistry.Tunnel {
+	if parseH.config.TunnelRegistry == nil {
+		return nil
+	}
+	return parseH.config.TunnelRegistry.Add(tunnelregistry.Info{
+		ID:         parseTunnel.getID,
+		Component:  "bridge.handler",
+		ClientKey:  parseClientKey,
+		Origin:     parseRequest.Header.Get("Origin"),
+		RemoteAddr: parseRequest.RemoteAddr,
+		StartedAt:  time.Now(),
+	}, func() tunnelregistry.Stats {
+		parseStats := tunnelregistry.Stats{
+			BytesIn:       parseConn.storeBytesRead.Load(),
+			BytesOut:      parseConn.storeBytesWritten.Load(),
+			ActiveStreams: parseTunnel.getActiveStreams.Load(),
+		}
+		if parseLastActivity := parseConn.storeLastActivity.Load(); parseLastActivity != 0 {
+			parseStats.LastActivity = time.Unix(0, parseLastActivity)
+		}
+		return parseStats
+	}, func() {
+		parseTunnel.storeHandlerCloseCause(TunnelCloseTerminated)
+		if parseTunnel.closeHandlerTunnel() {
+			logBridgeEvent(parseH.logger, "WARN", "tunnel_terminated", parseRequest, nil, "Tunnel force-closed through the tunnel registry")
+		}
+	})
+}
+
+// applyHandlerTunnelObservers registers tunnels with the TunnelAdmin's registry and delivers their
+// events to TunnelAdmin and TunnelStates alongside TunnelObserver. A different TunnelRegistry is
+// left for getHandlerConfigError to reject.
+func applyHandlerTunnelObservers(parseConfig Config) Config {
+	if parseConfig.TunnelAdmin != nil {
+		if parseConfig.TunnelRegistry == nil {
+			parseConfig.TunnelRegistry = parseConfig.TunnelAdmin.Registry()
+		}
+		parseConfig.TunnelObserver = addHandlerTunnelObserver(parseConfig.TunnelObserver, handlerTunnelAdminObserver{getAdmin: parseConfig.TunnelAdmin})
+	}
+	if parseConfig.TunnelStates != nil {
+		parseConfig.TunnelObserver = addHandlerTunnelObserver(parseConfig.TunnelObserver, handlerTunnelStateObserver{getStates: parseConfig.TunnelStates})
+	}
+	return parseConfig
+}
+
+// addHandlerTunnelObserver appends parseAdded to the observers parseObserver delivers to.
+func addHandlerTunnelObserver(parseObserver TunnelObserver, parseAdded TunnelObserver) TunnelObserver {
+	switch parseCurrent := parseObserver.(type) {
+	case nil:
+		return parseAdded
+	case handlerTunnelObservers:
+		return append(parseCurrent[:len(parseCurrent):len(parseCurrent)], parseAdded)
+	default:
+		return handlerTunnelObservers{parseCurrent, parseAdded}
+	}
+}
+
+// handlerTunnelObservers delivers each event to several observers in order.
+type handlerTunnelObservers []TunnelObserver
+
+func (parseObservers handlerTunnelObservers) OnUpgradeRejected(parseEvent UpgradeRejectedEvent) {
+	for _, parseObserver := range parseObservers {
+		parseObserver.OnUpgradeRejected(parseEvent)
+	}
+}
+
+func (parseObservers handlerTunnelObservers) OnTunnelConnected(parseEvent TunnelConnectedEvent) {
+	for _, parseObserver := range parseObservers {
+		parseObserver.OnTunnelConnected(parseEvent)
+	}
+}
+
+func (parseObservers handlerTunnelObservers) OnStreamOpened(parseEvent StreamOpenedEvent) {
+	for _, parseObserver := range parseObservers {
+		parseObserver.OnStreamOpened(parseEvent)
+	}
+}
+
+func (parseObservers handlerTunnelObservers) OnStreamClosed(parseEvent StreamClosedEvent) {
+	for _, parseObserver := range parseObservers {
+		parseObserver.OnStreamClosed(parseEvent)
+	}
+}
+
+func (parseObservers handlerTunnelObservers) OnTunnelDisconnected(parseEvent TunnelDisconnectedEvent) {
+	for _, parseObserver := range parseObservers {
+		parseObserver.OnTunnelDisconnected(parseEvent)
+	}
+}
+
+// handlerTunnelAdminObserver publishes lifecycle events to the TunnelAdmin event streams.
+type handlerTunnelAdminObserver struct {
+	getAdmin *tunneladmin.Admin
+}
+
+func (parseObserver handlerTunnelAdminObserver) OnUpgradeRejected(parseEvent UpgradeRejectedEvent) {
+	parseRejected := &adminv1.UpgradeRejected{Reason: string(parseEvent.Reason), StatusCode: int32(parseEvent.StatusCode)}
+	if parseEvent.Err != nil {
+		parseRejected.Error = parseEvent.Err.Error()
+	}
+	if parseEvent.Request != nil {
+		parseRejected.RemoteAddr = parseEvent.Request.RemoteAddr
+	}
+	parseObserver.getAdmin.Publish(&adminv1.TunnelEvent{
+		Time:  timestamppb.New(parseEvent.Time),
+		Event: &adminv1.TunnelEvent_UpgradeRejected{UpgradeRejected: parseRejected},
+	})
+}
+
+func (parseObserver handlerTunnelAdminObserver) OnTunnelConnected(parseEvent TunnelConnectedEvent) {
+	parseConnected := &adminv1.TunnelConnected{}
+	if parseEvent.Request != nil {
+		parseConnected.RemoteAddr = parseEvent.Request.RemoteAddr
+	}
+	parseObserver.getAdmin.Publish(&adminv1.TunnelEvent{
+		Time:     timestamppb.New(parseEvent.Time),
+		TunnelId: parseEvent.TunnelID,
+		Event:    &adminv1.TunnelEvent_TunnelConnected{TunnelConnected: parseConnected},
+	})
+}
+
+func (parseObserver handlerTunnelAdminObserver) OnStreamOpened(parseEvent StreamOpenedEvent) {
+	parseObserver.getAdmin.Publish(&adminv1.TunnelEvent{
+		Time:     timestamppb.New(parseEvent.Time),
+		TunnelId: parseEvent.TunnelID,
+		Event:    &adminv1.TunnelEvent_StreamOpened{StreamOpened: &adminv1.StreamOpened{Method: parseEvent.Method}},
+	})
+}
+
+func (parseObserver handlerTunnelAdminObserver) OnStreamClosed(parseEvent StreamClosedEvent) {
+	parseObserver.getAdmin.Publish(&adminv1.TunnelEvent{
+		Time:     timestamppb.New(parseEvent.Time),
+		TunnelId: parseEvent.TunnelID,
+		Event: &adminv1.TunnelEvent_StreamClosed{StreamClosed: &adminv1.StreamClosed{
+			Method:   parseEvent.Method,
+			Code:     int32(parseEvent.Code),
+			Duration: durationpb.New(parseEvent.Duration),
+		}},
+	})
+}
+
+func (parseObserver handlerTunnelAdminObserver) OnTunnelDisconnected(parseEvent TunnelDisconnectedEvent) {
+	parseObserver.getAdmin.Publish(&adminv1.TunnelEvent{
+		Time:     timestamppb.New(parseEvent.Time),
+		TunnelId: parseEvent.TunnelID,
+		Event: &adminv1.TunnelEvent_TunnelDisconnected{TunnelDisconnected: &adminv1.TunnelDisconnected{
+			Cause:        string(parseEvent.Cause),
+			Duration:     durationpb.New(parseEvent.Duration),
+			BytesRead:    parseEvent.BytesRead,
+			BytesWritten: parseEvent.BytesWritten,
+			Streams:      parseEvent.Streams,
+		}},
+	})
+}
+
+// handlerTunnelStateObserver records lifecycle events as tunnel state transitions.
+type handlerTunnelStateObserver struct {
+	getStates *tunnelstate.Recorder
+}
+
+func (parseObserver handlerTunnelStateObserver) OnUpgradeRejected(parseEvent UpgradeRejectedEvent) {
+	parseTransition := tunnelstate.Transition{
+		Time:       parseEvent.Time,
+		Component:  "bridge.handler",
+		State:      tunnelstate.StateUpgradeRejected,
+		ErrorClass: string(parseEvent.Reason),
+	}
+	if parseEvent.Err != nil {
+		parseTransition.ErrorMessage = parseEvent.Err.Error()
+	}
+	if parseEvent.Request != nil {
+		parseTransition.Target = parseEvent.Request.URL.Path
+		parseTransition.RemoteAddr = parseEvent.Request.RemoteAddr
+	}
+	parseObserver.getStates.Record(parseTransition)
+}
+
+func (parseObserver handlerTunnelStateObserver) OnTunnelConnected(parseEvent TunnelConnectedEvent) {
+	parseTransition := tunnelstate.Transition{
+		Time:      parseEvent.Time,
+		Component: "bridge.handler",
+		TunnelID:  parseEvent.TunnelID,
+		State:     tunnelstate.StateTunnelConnected,
+	}
+	if parseEvent.Request != nil {
+		parseTransition.Target = parseEvent.Request.URL.Path
+		parseTransition.RemoteAddr = parseEvent.Request.RemoteAddr
+	}
+	parseObserver.getStates.Record(parseTransition)
+}
+
+func (parseObserver handlerTunnelStateObserver) OnStreamOpened(StreamOpenedEvent) {}
+
+func (parseObserver handlerTunnelStateObserver) OnStreamClosed(parseEvent StreamClosedEvent) {
+	if parseEvent.Code == codes.OK {
+		return
+	}
+	parseObserver.getStates.Record(tunnelstate.Transition{
+		Time:       parseEvent.Time,
+		Component:  "bridge.handler",
+		TunnelID:   parseEvent.TunnelID,
+		State:      tunnelstate.StateStreamError,
+		Target:     parseEvent.Method,
+		ErrorClass: parseEvent.Code.String(),
+	})
+}
+
+func (parseObserver handlerTunnelStateObserver) OnTunnelDisconnected(parseEvent TunnelDisconnectedEvent) {
+	parseTransition := tunnelstate.Transition{
+		Time:       parseEvent.Time,
+		Component:  "bridge.handler",
+		TunnelID:   parseEvent.TunnelID,
+		State:      tunnelstate.StateTunnelDisconnected,
+		ErrorClass: string(parseEvent.Cause),
+	}
+	if parseEvent.Request != nil {
+		parseTransition.Target = parseEvent.Request.URL.Path
+		parseTransition.RemoteAddr = parseEvent.Request.RemoteAddr
+	}
+	parseObserver.getStates.Record(parseTransition)
+}
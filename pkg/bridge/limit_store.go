@@ -0,0 +1,190 @@
+package bridge
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"hash/fnv"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const parseHandlerActiveSlotKey = "active"
+const parseHandlerClientSlotPrefix = "client:"
+const parseHandlerDefaultLeaseTTL = 30 * time.Second
+const parseHandlerLimitStoreTimeout = 2 * time.Second
+
+// handlerMemoryLimitShard stores the leases of one slice of the slot key space.
+type handlerMemoryLimitShard struct {
+	setShardLock sync.Mutex
+	storeLeases  map[string]map[string]time.Time
+}
+
+// handlerMemoryLimitStore is the default in-process LimitStore.
+type handlerMemoryLimitStore struct {
+	getShards [parseHandlerAbuseShardCount]handlerMemoryLimitShard
+}
+
+// buildHandlerMemoryLimitStore creates an empty in-process LimitStore.
+func buildHandlerMemoryLimitStore() *handlerMemoryLimitStore {
+	parseStore := &handlerMemoryLimitStore{}
+	for parseIndex := range parseStore.getShards {
+		parseStore.getShards[parseIndex].storeLeases = map[string]map[string]time.Time{}
+	}
+	return parseStore
+}
+
+// getHandlerMemoryLimitShard returns the shard that owns a slot key.
+func (parseStore *handlerMemoryLimitStore) getHandlerMemoryLimitShard(parseKey string) *handlerMemoryLimitShard {
+	parseHash := fnv.New32a()
+	_, _ = parseHash.Write([]byte(parseKey))
+	return &parseStore.getShards[parseHash.Sum32()%parseHandlerAbuseShardCount]
+}
+
+// Reserve takes a slot in key when fewer than limit unexpired leases are held. A zero ttl never expires.
+func (parseStore *handlerMemoryLimitStore) Reserve(_ context.Context, parseKey string, parseLease string, parseLimit int, parseTTL time.Duration) (bool, error) {
+	parseShard := parseStore.getHandlerMemoryLimitShard(parseKey)
+	parseShard.setShardLock.Lock()
+	defer parseShard.setShardLock.Unlock()
+
+	parseNow := time.Now()
+	parseLeases := parseShard.storeLeases[parseKey]
+	for parseHeldLease, parseExpiresAt := range parseLeases {
+		if !parseExpiresAt.IsZero() && !parseNow.Before(parseExpiresAt) {
+			delete(parseLeases, parseHeldLease)
+		}
+	}
+	if len(parseLeases) >= parseLimit {
+		return false, nil
+	}
+	if parseLeases == nil {
+		parseLeases = map[string]time.Time{}
+		parseShard.storeLeases[parseKey] = parseLeases
+	}
+	parseLeases[parseLease] = getHandlerLeaseExpiry(parseNow, parseTTL)
+	return true, nil
+}
+
+// Renew extends a lease, re-adding it when it already expired.
+func (parseStore *handlerMemoryLimitStore) Renew(_ context.Context, parseKey string, parseLease string, parseTTL time.Duration) error {
+	parseShard := parseStore.getHandlerMemoryLimitShard(parseKey)
+	parseShard.setShardLock.Lock()
+	defer parseShard.setShardLock.Unlock()
+
+	parseLeases := parseShard.storeLeases[parseKey]
+	if parseLeases == nil {
+		parseLeases = map[string]time.Time{}
+		parseShard.storeLeases[parseKey] = parseLeases
+	}
+	parseLeases[parseLease] = getHandlerLeaseExpiry(time.Now(), parseTTL)
+	return nil
+}
+
+// Release frees a lease. Unknown leases are ignored.
+func (parseStore *handlerMemoryLimitStore) Release(_ context.Context, parseKey string, parseLease string) error {
+	parseShard := parseStore.getHandlerMemoryLimitShard(parseKey)
+	parseShard.setShardLock.Lock()
+	defer parseShard.setShardLock.Unlock()
+
+	parseLeases := parseShard.storeLeases[parseKey]
+	delete(parseLeases, parseLease)
+	if len(parseLeases) == 0 {
+		delete(parseShard.storeLeases, parseKey)
+	}
+	return nil
+}
+
+// getHandlerLeaseExpiry returns when a lease taken now expires, or the zero time for leases without a TTL.
+func getHandlerLeaseExpiry(parseNow time.Time, parseTTL time.Duration) time.Time {
+	if parseTTL <= 0 {
+		return time.Time{}
+	}
+	return parseNow.Add(parseTTL)
+}
+
+// handlerConnectionLease records the LimitStore slots held by one tunnel and keeps them renewed.
+type handlerConnectionLease struct {
+	getGuard    *handlerAbuseGuard
+	getLeaseID  string
+	storeKeys   []string
+	getStop     chan struct{}
+	setStopOnce sync.Once
+}
+
+// buildHandlerConnectionLease creates a lease with a random identifier.
+func buildHandlerConnectionLease(parseGuard *handlerAbuseGuard) *handlerConnectionLease {
+	parseID := make([]byte, 16)
+	_, _ = rand.Read(parseID)
+	return &handlerConnectionLease{
+		getGuard:   parseGuard,
+		getLeaseID: hex.EncodeToString(parseID),
+		getStop:    make(chan struct{}),
+	}
+}
+
+// reserveHandlerLeaseSlot takes one slot in a store key and reports whether the limit allowed it.
+// Store failures are logged and fail open so an unavailable store does not reject every client.
+func (parseLease *handlerConnectionLease) reserveHandlerLeaseSlot(parseRequest *http.Request, parseKey string, parseLimit int) bool {
+	parseContext := context.Background()
+	if parseRequest != nil {
+		parseContext = parseRequest.Context()
+	}
+	parseContext, clearContext := context.WithTimeout(parseContext, parseHandlerLimitStoreTimeout)
+	defer clearContext()
+
+	isReserved, parseErr := parseLease.getGuard.getLimitStore.Reserve(parseContext, parseKey, parseLease.getLeaseID, parseLimit, parseLease.getGuard.getLeaseTTL)
+	if parseErr != nil {
+		logBridgeEvent(parseLease.getGuard.setConfig.Logger, "WARN", "limit_store_unavailable", parseRequest, parseErr, "Limit store reserve failed; allowing upgrade")
+		return true
+	}
+	if isReserved {
+		parseLease.storeKeys = append(parseLease.storeKeys, parseKey)
+	}
+	return isReserved
+}
+
+// startHandlerLeaseRenewal renews expiring slots every third of the lease TTL until the lease is cleared.
+func (parseLease *handlerConnectionLease) startHandlerLeaseRenewal() {
+	parseTTL := parseLease.getGuard.getLeaseTTL
+	if parseTTL <= 0 || len(parseLease.storeKeys) == 0 {
+		return
+	}
+	go func() {
+		parseTicker := time.NewTicker(max(parseTTL/3, time.Millisecond))
+		defer parseTicker.Stop()
+		for {
+			select {
+			case <-parseLease.getStop:
+				return
+			case <-parseTicker.C:
+			}
+			for _, parseKey := range parseLease.storeKeys {
+				parseContext, clearContext := context.WithTimeout(context.Background(), parseHandlerLimitStoreTimeout)
+				parseErr := parseLease.getGuard.getLimitStore.Renew(parseContext, parseKey, parseLease.getLeaseID, parseTTL)
+				clearContext()
+				if parseErr != nil {
+					logBridgeEvent(parseLease.getGuard.setConfig.Logger, "WARN", "limit_store_renew_failed", nil, parseErr, "Limit store lease renewal failed")
+				}
+			}
+		}
+	}()
+}
+
+// clearHandlerLease stops renewal and releases every held slot. It is safe to call more than once.
+func (parseLease *handlerConnectionLease) clearHandlerLease() {
+	if parseLease == nil {
+		return
+	}
+	parseLease.setStopOnce.Do(func() {
+		close(parseLease.getStop)
+		for _, parseKey := range parseLease.storeKeys {
+			parseContext, clearContext := context.WithTimeout(context.Background(), parseHandlerLimitStoreTimeout)
+			parseErr := parseLease.getGuard.getLimitStore.Release(parseContext, parseKey, parseLease.getLeaseID)
+			clearContext()
+			if parseErr != nil {
+				logBridgeEvent(parseLease.getGuard.setConfig.Logger, "WARN", "limit_store_release_failed", nil, parseErr, "Limit store lease release failed; the slot frees when the lease expires")
+			}
+		}
+	})
+}
@@ -0,0 +1,87 @@
+//lint:file-ignore SA1019 grpc.DialContext is retained in tests to match bridge integration coverage on grpc 1.x.
+
+package bridge
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/monstercameron/grpc-tunnel/examples/_shared/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestGetHandlerConfigError_MaxConnectionAge verifies max-age validation.
+func TestGetHandlerConfigError_MaxConnectionAge(parseT *testing.T) {
+	for _, parseConfig := range []Config{
+		{MaxConnectionAge: -time.Second},
+		{MaxConnectionAge: time.Second, MaxConnectionAgeGrace: -time.Second},
+		{MaxConnectionAgeGrace: time.Second},
+	} {
+		if parseErr := getHandlerConfigError(parseConfig); parseErr == nil {
+			parseT.Fatalf("getHandlerConfigError(%+v) = nil, want error", parseConfig)
+		}
+	}
+	if parseAge := getHandlerTunnelMaxAge(time.Minute); parseAge < 54*time.Second || parseAge > 66*time.Second {
+		parseT.Fatalf("getHandlerTunnelMaxAge(1m) = %v, want within 10%%", parseAge)
+	}
+}
+
+// TestHandlerMaxConnectionAge verifies an aged tunnel receives GOAWAY, new RPCs move to a fresh
+// tunnel, and streams still running when the grace period ends are cut off.
+func TestHandlerMaxConnectionAge(parseT *testing.T) {
+	parseBackendListener, parseErr := net.Listen("tcp", "127.0.0.1:0")
+	if parseErr != nil {
+		parseT.Fatalf("Listen() error: %v", parseErr)
+	}
+	parseService := &buildDrainTestTodoService{
+		getStreamStarted: make(chan struct{}, 1),
+		getStreamRelease: make(chan struct{}),
+	}
+	parseBackendServer := grpc.NewServer()
+	proto.RegisterTodoServiceServer(parseBackendServer, parseService)
+	go func() {
+		_ = parseBackendServer.Serve(parseBackendListener)
+	}()
+	defer parseBackendServer.Stop()
+
+	var parseConnects atomic.Int64
+	parseHandler := NewHandler(Config{
+		TargetAddress:         parseBackendListener.Addr().String(),
+		MaxConnectionAge:      200 * time.Millisecond,
+		MaxConnectionAgeGrace: 400 * time.Millisecond,
+		OnConnect: func(*http.Request) {
+			parseConnects.Add(1)
+		},
+	})
+	if parseHandler.initErr != nil {
+		parseT.Fatalf("NewHandler() error: %v", parseHandler.initErr)
+	}
+	parseBridgeServer := httptest.NewServer(parseHandler)
+	defer parseBridgeServer.Close()
+
+	parseCtx, clearCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer clearCtx()
+	parseClientConn, parseStream := startDrainTestStream(parseT, parseCtx, parseService, "ws"+strings.TrimPrefix(parseBridgeServer.URL, "http"))
+	defer parseClientConn.Close()
+
+	time.Sleep(300 * time.Millisecond)
+	// The backend does not implement CreateTodo, so Unimplemented proves the call reached it.
+	if _, parseErr := proto.NewTodoServiceClient(parseClientConn).CreateTodo(parseCtx, &proto.CreateTodoRequest{}); status.Code(parseErr) != codes.Unimplemented {
+		parseT.Fatalf("CreateTodo() after max age error = %v, want Unimplemented from the backend", parseErr)
+	}
+	if parseConnects.Load() < 2 {
+		parseT.Fatalf("tunnel connects after max age = %d, want a fresh tunnel", parseConnects.Load())
+	}
+
+	if _, parseErr := parseStream.Recv(); status.Code(parseErr) != codes.Unavailable {
+		parseT.Fatalf("Recv() after grace error = %v, want Unavailable", parseErr)
+	}
+}
@@ -0,0 +1,87 @@
+package bridge
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/monstercameron/grpc-tunnel/examples/_shared/proto"
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/membudget"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// TestHandlerMemoryBudget verifies tunnels are charged to the budget, large uploads complete under
+// pressure, and an exhausted budget refuses new streams and upgrades.
+func TestHandlerMemoryBudget(parseT *testing.T) {
+	parseBudget, parseErr := membudget.New(membudget.Config{Name: "bridge-test", LimitBytes: 8 << 20, ThrottleRatio: 0.5})
+	if parseErr != nil {
+		parseT.Fatalf("membudget.New() error: %v", parseErr)
+	}
+	parseBackendAddress, _ := buildPoolTestBackend(parseT, "budgeted")
+	parseHandler := NewHandler(Config{TargetAddress: parseBackendAddress, MemoryBudget: parseBudget})
+	if parseHandler.initErr != nil {
+		parseT.Fatalf("NewHandler() error: %v", parseHandler.initErr)
+	}
+	parseServer := httptest.NewServer(parseHandler)
+	defer parseServer.Close()
+	parseWsURL := "ws" + strings.TrimPrefix(parseServer.URL, "http")
+
+	parseCtx, clearCtx := context.WithTimeout(context.Background(), 20*time.Second)
+	defer clearCtx()
+	parseConn, parseErr := grpc.DialContext(
+		parseCtx,
+		"ignored:1234",
+		DialOption(parseWsURL),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if parseErr != nil {
+		parseT.Fatalf("DialContext() error: %v", parseErr)
+	}
+	parseClient := proto.NewTodoServiceClient(parseConn)
+	if _, parseErr := parseClient.CreateTodo(parseCtx, &proto.CreateTodoRequest{Text: "warm up"}); parseErr != nil {
+		parseT.Fatalf("CreateTodo() error: %v", parseErr)
+	}
+	if parseUsed := parseBudget.Used(); parseUsed < getHandlerTunnelOverhead(Config{}) {
+		parseT.Fatalf("Used() with one tunnel = %d, want at least the tunnel overhead", parseUsed)
+	}
+
+	// Window updates shrink while usage is above ThrottleRatio, but withheld credit returns as the
+	// server consumes the upload, so it completes.
+	parseBudget.Reserve(5 << 20)
+	parseResp, parseErr := parseClient.CreateTodo(parseCtx, &proto.CreateTodoRequest{Text: strings.Repeat("x", 2<<20)})
+	if parseErr != nil {
+		parseT.Fatalf("CreateTodo(2 MiB) under pressure error: %v", parseErr)
+	}
+	if len(parseResp.GetTodo().GetText()) != 2<<20 {
+		parseT.Fatalf("CreateTodo(2 MiB) text length = %d", len(parseResp.GetTodo().GetText()))
+	}
+
+	parseBudget.Reserve(3 << 20)
+	if _, parseErr := parseClient.CreateTodo(parseCtx, &proto.CreateTodoRequest{Text: "refused"}); status.Code(parseErr) != codes.ResourceExhausted {
+		parseT.Fatalf("CreateTodo() with exhausted budget code = %v, want ResourceExhausted", status.Code(parseErr))
+	}
+	_, parseResponse, parseErr := websocket.DefaultDialer.Dial(parseWsURL, nil)
+	if parseErr == nil || parseResponse == nil || parseResponse.StatusCode != http.StatusServiceUnavailable {
+		parseT.Fatalf("Dial() with exhausted budget = %v, %v; want %d", parseResponse, parseErr, http.StatusServiceUnavailable)
+	}
+
+	parseBudget.Release(8 << 20)
+	if _, parseErr := parseClient.CreateTodo(parseCtx, &proto.CreateTodoRequest{Text: "recovered"}); parseErr != nil {
+		parseT.Fatalf("CreateTodo() after recovery error: %v", parseErr)
+	}
+	_ = parseConn.Close()
+	parseDeadline := time.Now().Add(2 * time.Second)
+	for parseBudget.Used() != 0 {
+		if time.Now().After(parseDeadline) {
+			parseT.Fatalf("Used() after tunnel close = %d, want 0", parseBudget.Used())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
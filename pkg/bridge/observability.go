@@ -0,0 +1,173 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const parseHandlerObservabilityScope = "github.com/monstercameron/grpc-tunnel/pkg/bridge"
+const parseHandlerRPCDeniedTotalMetric = "bridge_rpc_denied_total"
+const parseHandlerAbuseRejectionsTotalMetric = "bridge_abuse_rejections_total"
+const parseHandlerBandwidthThrottleMetric = "bridge_bandwidth_throttle_seconds_total"
+const parseHandlerUpgradeTimeoutsTotalMetric = "bridge_upgrade_timeouts_total"
+const parseHandlerPrefaceTimeoutsTotalMetric = "bridge_preface_timeouts_total"
+const parseHandlerSlowReadsTotalMetric = "bridge_slow_read_disconnects_total"
+const parseHandlerWriteTimeoutsTotalMetric = "bridge_write_timeout_disconnects_total"
+const parseHandlerTunnelMaxAgeTotalMetric = "bridge_tunnel_max_age_total"
+
+const parseHandlerMetricReasonExposure = "exposure_policy"
+const parseHandlerMetricReasonAuthorization = "authorization"
+const parseHandlerMetricReasonRateLimit = "rate_limit"
+const parseHandlerMetricReasonMemoryBudget = "memory_budget"
+
+// handlerObservability stores OTel metric handles for bridge handler runtime signals.
+type handlerObservability struct {
+	getHandlerRPCDeniedTotal       metric.Int64Counter
+	getHandlerAbuseRejectionsTotal metric.Int64Counter
+	getHandlerBandwidthThrottle    metric.Float64Counter
+	getHandlerSlowClientTotals     map[string]metric.Int64Counter
+	getHandlerTunnelMaxAgeTotal    metric.Int64Counter
+}
+
+// buildHandlerObservability creates a handler observability handle backed by the global OTel meter provider.
+func buildHandlerObservability() *handlerObservability {
+	parseMeter := otel.Meter(parseHandlerObservabilityScope)
+	parseRPCDeniedTotal, _ := parseMeter.Int64Counter(
+		parseHandlerRPCDeniedTotalMetric,
+		metric.WithDescription("Total tunneled RPCs rejected by the bridge before reaching a backend"),
+	)
+	parseAbuseRejectionsTotal, _ := parseMeter.Int64Counter(
+		parseHandlerAbuseRejectionsTotalMetric,
+		metric.WithDescription("Total websocket upgrades rejected by abuse controls"),
+	)
+	parseBandwidthThrottle, _ := parseMeter.Float64Counter(
+		parseHandlerBandwidthThrottleMetric,
+		metric.WithUnit("s"),
+		metric.WithDescription("Total time tunnel reads and writes waited for bandwidth limits"),
+	)
+	parseTunnelMaxAgeTotal, _ := parseMeter.Int64Counter(
+		parseHandlerTunnelMaxAgeTotalMetric,
+		metric.WithDescription("Total tunnels sent GOAWAY at MaxConnectionAge, and closed with RPCs in flight after MaxConnectionAgeGrace"),
+	)
+	parseSlowClientTotals := map[string]metric.Int64Counter{}
+	for _, parseSlowClientMetric := range []struct {
+		getLimit       string
+		getName        string
+		getDescription string
+	}{
+		{parseHandlerSlowClientUpgradeTimeout, parseHandlerUpgradeTimeoutsTotalMetric, "Total websocket upgrades rejected for exceeding UpgradeTimeout"},
+		{parseHandlerSlowClientPrefaceTimeout, parseHandlerPrefaceTimeoutsTotalMetric, "Total tunnels closed before the HTTP/2 preface arrived within PrefaceTimeout"},
+		{parseHandlerSlowClientReadTooSlow, parseHandlerSlowReadsTotalMetric, "Total tunnels closed for delivering messages below MinReadThroughput"},
+		{parseHandlerSlowClientWriteTimeout, parseHandlerWriteTimeoutsTotalMetric, "Total tunnels closed because a message write exceeded WriteTimeout"},
+	} {
+		parseCounter, _ := parseMeter.Int64Counter(parseSlowClientMetric.getName, metric.WithDescription(parseSlowClientMetric.getDescription))
+		parseSlowClientTotals[parseSlowClientMetric.getLimit] = parseCounter
+	}
+	return &handlerObservability{
+		getHandlerRPCDeniedTotal:       parseRPCDeniedTotal,
+		getHandlerAbuseRejectionsTotal: parseAbuseRejectionsTotal,
+		getHandlerBandwidthThrottle:    parseBandwidthThrottle,
+		getHandlerSlowClientTotals:     parseSlowClientTotals,
+		getHandlerTunnelMaxAgeTotal:    parseTunnelMaxAgeTotal,
+	}
+}
+
+// storeHandlerRPCDenied counts one tunneled RPC rejected by the bridge for a reason.
+// Method names are client-controlled, so they are not used as metric attributes.
+func (parseObservability *handlerObservability) storeHandlerRPCDenied(parseContext context.Context, parseReason string) {
+	if parseObservability == nil || parseObservability.getHandlerRPCDeniedTotal == nil {
+		return
+	}
+	if parseContext == nil {
+		parseContext = context.Background()
+	}
+	parseObservability.getHandlerRPCDeniedTotal.Add(
+		parseContext,
+		1,
+		metric.WithAttributes(
+			attribute.String("component", "bridge.handler"),
+			attribute.String("reason", parseReason),
+		),
+	)
+}
+
+// storeHandlerAbuseRejection counts one upgrade rejected by abuse controls, labeled with the rejection reason.
+func (parseObservability *handlerObservability) storeHandlerAbuseRejection(parseContext context.Context, parseErr error) {
+	if parseObservability == nil || parseObservability.getHandlerAbuseRejectionsTotal == nil {
+		return
+	}
+	if parseContext == nil {
+		parseContext = context.Background()
+	}
+	parseReason := "unknown"
+	var parseRejection *handlerAbuseRejection
+	if errors.As(parseErr, &parseRejection) {
+		parseReason = parseRejection.getReason
+	}
+	parseObservability.getHandlerAbuseRejectionsTotal.Add(
+		parseContext,
+		1,
+		metric.WithAttributes(
+			attribute.String("component", "bridge.handler"),
+			attribute.String("reason", parseReason),
+		),
+	)
+}
+
+// storeHandlerBandwidthThrottle adds time spent waiting for a bandwidth limit, labeled with the limiting
+// scope and direction, so throttling can be told apart from backend slowness.
+func (parseObservability *handlerObservability) storeHandlerBandwidthThrottle(parseContext context.Context, parseScope string, parseDirection string, parseDuration time.Duration) {
+	if parseObservability == nil || parseObservability.getHandlerBandwidthThrottle == nil {
+		return
+	}
+	if parseContext == nil {
+		parseContext = context.Background()
+	}
+	parseObservability.getHandlerBandwidthThrottle.Add(
+		parseContext,
+		parseDuration.Seconds(),
+		metric.WithAttributes(
+			attribute.String("component", "bridge.handler"),
+			attribute.String("scope", parseScope),
+			attribute.String("direction", parseDirection),
+		),
+	)
+}
+
+// storeHandlerSlowClient increments the counter for the slow-client limit that rejected or closed a tunnel.
+func (parseObservability *handlerObservability) storeHandlerSlowClient(parseContext context.Context, parseLimit string) {
+	if parseObservability == nil || parseObservability.getHandlerSlowClientTotals[parseLimit] == nil {
+		return
+	}
+	if parseContext == nil {
+		parseContext = context.Background()
+	}
+	parseObservability.getHandlerSlowClientTotals[parseLimit].Add(
+		parseContext,
+		1,
+		metric.WithAttributes(attribute.String("component", "bridge.handler")),
+	)
+}
+
+// storeHandlerTunnelMaxAge counts one MaxConnectionAge rotation stage: the GOAWAY, or a close when the grace expired.
+func (parseObservability *handlerObservability) storeHandlerTunnelMaxAge(parseContext context.Context, parseStage string) {
+	if parseObservability == nil || parseObservability.getHandlerTunnelMaxAgeTotal == nil {
+		return
+	}
+	if parseContext == nil {
+		parseContext = context.Background()
+	}
+	parseObservability.getHandlerTunnelMaxAgeTotal.Add(
+		parseContext,
+		1,
+		metric.WithAttributes(
+			attribute.String("component", "bridge.handler"),
+			attribute.String("stage", parseStage),
+		),
+	)
+}
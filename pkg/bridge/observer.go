@@ -0,0 +1,146 @@
+package bridge
+
+import (
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+// UpgradeRejectReason says why the bridge refused a websocket upgrade.
+type UpgradeRejectReason string
+
+const (
+	// UpgradeRejectDraining means the bridge was draining or shutting down.
+	UpgradeRejectDraining UpgradeRejectReason = "draining"
+	// UpgradeRejectMemoryBudget means MemoryBudget was exhausted.
+	UpgradeRejectMemoryBudget UpgradeRejectReason = "memory_budget"
+	// UpgradeRejectClientDenied means the client matched DeniedClientCIDRs.
+	UpgradeRejectClientDenied UpgradeRejectReason = "client_denied"
+	// UpgradeRejectClientBanned means the client was in the penalty box.
+	UpgradeRejectClientBanned UpgradeRejectReason = "client_banned"
+	// UpgradeRejectUpgradeRate means the client exceeded its upgrade rate limit.
+	UpgradeRejectUpgradeRate UpgradeRejectReason = "upgrade_rate_limited"
+	// UpgradeRejectClientConnections means the client already held MaxConnectionsPerClient tunnels.
+	UpgradeRejectClientConnections UpgradeRejectReason = "client_connection_limit"
+	// UpgradeRejectActiveConnections means the bridge already held MaxActiveConnections tunnels.
+	UpgradeRejectActiveConnections UpgradeRejectReason = "active_connection_limit"
+	// UpgradeRejectClientTableFull means the client was new and its abuse-control state shard held only banned clients.
+	UpgradeRejectClientTableFull UpgradeRejectReason = "client_table_full"
+	// UpgradeRejectAuthentication means Authenticate returned an error.
+	UpgradeRejectAuthentication UpgradeRejectReason = "authentication"
+	// UpgradeRejectUpgradeTimeout means the upgrade took longer than UpgradeTimeout.
+	UpgradeRejectUpgradeTimeout UpgradeRejectReason = "upgrade_timeout"
+	// UpgradeRejectConfigInvalid means the Handler was created from an invalid Config.
+	UpgradeRejectConfigInvalid UpgradeRejectReason = "config_invalid"
+	// UpgradeRejectHandshake means the websocket handshake itself failed, for example on CheckOrigin.
+	UpgradeRejectHandshake UpgradeRejectReason = "handshake_failed"
+)
+
+// TunnelCloseCause says why a connected tunnel ended. When several apply, the first one wins.
+type TunnelCloseCause string
+
+const (
+	// TunnelCloseClient means the client closed the websocket or the connection dropped.
+	TunnelCloseClient TunnelCloseCause = "client_closed"
+	// TunnelCloseServer means the bridge side closed the tunnel for another reason, such as an
+	// HTTP/2 protocol error.
+	TunnelCloseServer TunnelCloseCause = "server_closed"
+	// TunnelCloseIdleTimeout means no pong or frame arrived within IdleTimeout.
+	TunnelCloseIdleTimeout TunnelCloseCause = "idle_timeout"
+	// TunnelCloseDrain means Drain or Shutdown sent GOAWAY to the tunnel.
+	TunnelCloseDrain TunnelCloseCause = "drain"
+	// TunnelCloseMaxAge means the tunnel reached MaxConnectionAge.
+	TunnelCloseMaxAge TunnelCloseCause = "max_age"
+	// TunnelCloseAuthExpired means the Authenticate context ended.
+	TunnelCloseAuthExpired TunnelCloseCause = "auth_expired"
+	// TunnelClosePrefaceTimeout means the HTTP/2 preface did not arrive within PrefaceTimeout.
+	TunnelClosePrefaceTimeout TunnelCloseCause = "preface_timeout"
+	// TunnelCloseReadTooSlow means a message arrived slower than MinReadThroughput.
+	TunnelCloseReadTooSlow TunnelCloseCause = "read_too_slow"
+	// TunnelCloseWriteTimeout means a message write exceeded WriteTimeout.
+	TunnelCloseWriteTimeout TunnelCloseCause = "write_timeout"
+	// TunnelCloseTerminated means the tunnel was force-closed through TunnelRegistry.
+	TunnelCloseTerminated TunnelCloseCause = "terminated"
+)
+
+// UpgradeRejectedEvent reports a websocket upgrade the bridge refused.
+type UpgradeRejectedEvent struct {
+	// Request is the upgrade request, with RemoteAddr resolved through TrustedProxies.
+	Request *http.Request
+	Reason  UpgradeRejectReason
+	// StatusCode is the HTTP status sent to the client, or zero when the handshake failed.
+	StatusCode int
+	// Err is the Authenticate, abuse-control, or handshake error, when there was one.
+	Err  error
+	Time time.Time
+}
+
+// TunnelConnectedEvent reports a tunnel that completed its upgrade.
+type TunnelConnectedEvent struct {
+	// TunnelID identifies the tunnel in every later event for it.
+	TunnelID string
+	Request  *http.Request
+	Time     time.Time
+}
+
+// StreamOpenedEvent reports an HTTP/2 stream arriving on a tunnel, before rate limits,
+// ExposurePolicy, Authorize, and backend selection are checked.
+type StreamOpenedEvent struct {
+	TunnelID string
+	// Method is the full gRPC method, "/package.Service/Method".
+	Method string
+	Time   time.Time
+}
+
+// StreamClosedEvent reports the end of an HTTP/2 stream on a tunnel.
+type StreamClosedEvent struct {
+	TunnelID string
+	Method   string
+	// Code is the gRPC status sent to the client, or Unknown when the stream ended without one.
+	Code     codes.Code
+	Duration time.Duration
+	Time     time.Time
+}
+
+// TunnelDisconnectedEvent reports the end of a connected tunnel.
+type TunnelDisconnectedEvent struct {
+	TunnelID string
+	Request  *http.Request
+	Cause    TunnelCloseCause
+	Duration time.Duration
+	// BytesRead and BytesWritten count tunneled HTTP/2 bytes, excluding websocket framing.
+	BytesRead    int64
+	BytesWritten int64
+	// Streams counts every stream opened on the tunnel, including rejected ones.
+	Streams int64
+	Time    time.Time
+}
+
+// TunnelObserver receives typed tunnel lifecycle events. Methods are called synchronously on
+// tunnel goroutines, so implementations must be safe for concurrent use and return quickly.
+type TunnelObserver interface {
+	OnUpgradeRejected(event UpgradeRejectedEvent)
+	OnTunnelConnected(event TunnelConnectedEvent)
+	OnStreamOpened(event StreamOpenedEvent)
+	OnStreamClosed(event StreamClosedEvent)
+	OnTunnelDisconnected(event TunnelDisconnectedEvent)
+}
+
+// BaseTunnelObserver ignores every event. Embed it to implement only the TunnelObserver methods you need.
+type BaseTunnelObserver struct{}
+
+// OnUpgradeRejected ignores the event.
+func (BaseTunnelObserver) OnUpgradeRejected(UpgradeRejectedEvent) {}
+
+// OnTunnelConnected ignores the event.
+func (BaseTunnelObserver) OnTunnelConnected(TunnelConnectedEvent) {}
+
+// OnStreamOpened ignores the event.
+func (BaseTunnelObserver) OnStreamOpened(StreamOpenedEvent) {}
+
+// OnStreamClosed ignores the event.
+func (BaseTunnelObserver) OnStreamClosed(StreamClosedEvent) {}
+
+// OnTunnelDisconnected ignores the event.
+func (BaseTunnelObserver) OnTunnelDisconnected(TunnelDisconnectedEvent) {}
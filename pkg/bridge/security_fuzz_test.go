@@ -16,6 +16,8 @@ func FuzzParseBridgeTargetURL(parseF *testing.F) {
 	parseF.Add("[::1]:50051")
 	parseF.Add("api.example.com:443")
 	parseF.Add("user:pass@localhost:50051")
+	parseF.Add("unix:///tmp/grpc.sock")
+	parseF.Add("unix:@grpc")
 
 	parseF.Fuzz(func(parseT *testing.T, parseTargetAddress string) {
 		defer func() {
@@ -32,8 +34,8 @@ func FuzzParseBridgeTargetURL(parseF *testing.F) {
 		if parseTargetURL == nil {
 			parseT.Fatal("parseBridgeTargetURL returned nil URL without error")
 		}
-		if parseTargetURL.Scheme != "http" {
-			parseT.Fatalf("parseBridgeTargetURL scheme = %q, want http", parseTargetURL.Scheme)
+		if parseTargetURL.Scheme != "http" && parseTargetURL.Scheme != "https" && parseTargetURL.Scheme != "unix" {
+			parseT.Fatalf("parseBridgeTargetURL scheme = %q, want http, https, or unix", parseTargetURL.Scheme)
 		}
 		if parseTargetURL.Host == "" {
 			parseT.Fatal("parseBridgeTargetURL returned empty host without error")
@@ -0,0 +1,211 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+const parseHandlerSlowClientUpgradeTimeout = "upgrade_timeout"
+const parseHandlerSlowClientPrefaceTimeout = "preface_timeout"
+const parseHandlerSlowClientReadTooSlow = "read_too_slow"
+const parseHandlerSlowClientWriteTimeout = "write_timeout"
+
+// parseDefaultMinReadThroughputWindow is the measurement window used when Throughput.Window is zero.
+const parseDefaultMinReadThroughputWindow = 10 * time.Second
+
+// getHandlerSlowClientError validates the upgrade, preface, read-throughput, and write limits.
+func getHandlerSlowClientError(parseConfig Config) error {
+	if parseConfig.UpgradeTimeout < 0 || parseConfig.PrefaceTimeout < 0 || parseConfig.WriteTimeout < 0 {
+		return errors.New("bridge: UpgradeTimeout, PrefaceTimeout, and WriteTimeout must be >= 0")
+	}
+	if parseConfig.MinReadThroughput.BytesPerSecond < 0 || parseConfig.MinReadThroughput.Window < 0 {
+		return errors.New("bridge: MinReadThroughput.BytesPerSecond and Window must be >= 0")
+	}
+	// Bandwidth pacing waits while a message is in flight, so a slower limit would trip read_too_slow.
+	parseMinimum := float64(parseConfig.MinReadThroughput.BytesPerSecond)
+	if parseGlobal := parseConfig.BandwidthLimits.Global.PerSecond; parseMinimum > 0 && parseGlobal > 0 && parseGlobal < parseMinimum {
+		return errors.New("bridge: BandwidthLimits.Global.PerSecond must be >= MinReadThroughput.BytesPerSecond")
+	}
+	if parsePerClient := parseConfig.BandwidthLimits.PerClient.PerSecond; parseMinimum > 0 && parsePerClient > 0 && parsePerClient < parseMinimum {
+		return errors.New("bridge: BandwidthLimits.PerClient.PerSecond must be >= MinReadThroughput.BytesPerSecond")
+	}
+	return nil
+}
+
+// handlerSlowClientGuard enforces the preface deadline, minimum read throughput, and per-message write
+// deadline for one upgraded tunnel. A nil guard enforces nothing.
+type handlerSlowClientGuard struct {
+	getPrefaceTimeout    time.Duration
+	getWriteTimeout      time.Duration
+	getMinReadThroughput Throughput
+	getRequest           *http.Request
+	getLogger            Logger
+	getObservability     *handlerObservability
+	storeReadBytes       atomic.Int64
+	storeWindowBytes     atomic.Int64
+	storeMessageStart    atomic.Int64 // Unix nanoseconds when the in-flight message began, or zero.
+	getCloseSignal       chan struct{}
+	setCloseOnce         sync.Once
+	storeCloseCause      atomic.Pointer[TunnelCloseCause]
+}
+
+// buildHandlerSlowClientGuard returns a guard for one tunnel, or nil when no per-tunnel limit is configured.
+func buildHandlerSlowClientGuard(parseConfig Config, parseRequest *http.Request, parseObservability *handlerObservability) *handlerSlowClientGuard {
+	if parseConfig.PrefaceTimeout <= 0 && parseConfig.WriteTimeout <= 0 && parseConfig.MinReadThroughput.BytesPerSecond <= 0 {
+		return nil
+	}
+	parseThroughput := parseConfig.MinReadThroughput
+	if parseThroughput.Window <= 0 {
+		parseThroughput.Window = parseDefaultMinReadThroughputWindow
+	}
+	return &handlerSlowClientGuard{
+		getPrefaceTimeout:    parseConfig.PrefaceTimeout,
+		getWriteTimeout:      parseConfig.WriteTimeout,
+		getMinReadThroughput: parseThroughput,
+		getRequest:           parseRequest,
+		getLogger:            parseConfig.Logger,
+		getObservability:     parseObservability,
+		getCloseSignal:       make(chan struct{}),
+	}
+}
+
+// startHandlerSlowClientGuard starts the preface and read-throughput watchers, which call parseClose when a
+// limit is exceeded.
+func (parseGuard *handlerSlowClientGuard) startHandlerSlowClientGuard(parseClose func() error) {
+	if parseGuard == nil {
+		return
+	}
+	if parseGuard.getPrefaceTimeout > 0 {
+		go parseGuard.waitHandlerPreface(parseClose)
+	}
+	if parseGuard.getMinReadThroughput.BytesPerSecond > 0 {
+		go parseGuard.waitHandlerReadThroughput(parseClose)
+	}
+}
+
+// waitHandlerPreface closes the tunnel when the client has not sent the HTTP/2 preface within PrefaceTimeout.
+func (parseGuard *handlerSlowClientGuard) waitHandlerPreface(parseClose func() error) {
+	parseTimer := time.NewTimer(parseGuard.getPrefaceTimeout)
+	defer parseTimer.Stop()
+	select {
+	case <-parseGuard.getCloseSignal:
+		return
+	case <-parseTimer.C:
+	}
+	if parseGuard.storeReadBytes.Load() >= int64(len(http2.ClientPreface)) {
+		return
+	}
+	parseGuard.storeHandlerSlowClientCause(TunnelClosePrefaceTimeout)
+	parseGuard.getObservability.storeHandlerSlowClient(context.Background(), parseHandlerSlowClientPrefaceTimeout)
+	logBridgeEvent(parseGuard.getLogger, "WARN", "tunnel_preface_timeout", parseGuard.getRequest, nil, "Closing tunnel because the HTTP/2 preface did not arrive within PrefaceTimeout")
+	_ = parseClose()
+}
+
+// waitHandlerReadThroughput closes the tunnel when a message has been arriving for a full window at less
+// than MinReadThroughput. Idle tunnels with no message in flight are left to IdleTimeout.
+func (parseGuard *handlerSlowClientGuard) waitHandlerReadThroughput(parseClose func() error) {
+	parseWindow := parseGuard.getMinReadThroughput.Window
+	parseMinimum := int64(float64(parseGuard.getMinReadThroughput.BytesPerSecond) * parseWindow.Seconds())
+	parseTicker := time.NewTicker(parseWindow)
+	defer parseTicker.Stop()
+	for {
+		select {
+		case <-parseGuard.getCloseSignal:
+			return
+		case parseNow := <-parseTicker.C:
+			parseWindowBytes := parseGuard.storeWindowBytes.Swap(0)
+			parseMessageStart := parseGuard.storeMessageStart.Load()
+			if parseMessageStart == 0 || parseNow.Sub(time.Unix(0, parseMessageStart)) < parseWindow || parseWindowBytes >= parseMinimum {
+				continue
+			}
+			parseGuard.storeHandlerSlowClientCause(TunnelCloseReadTooSlow)
+			parseGuard.getObservability.storeHandlerSlowClient(context.Background(), parseHandlerSlowClientReadTooSlow)
+			logBridgeEvent(parseGuard.getLogger, "WARN", "tunnel_read_too_slow", parseGuard.getRequest, nil, "Closing tunnel because a message arrived slower than MinReadThroughput")
+			_ = parseClose()
+			return
+		}
+	}
+}
+
+// storeHandlerMessageStart marks the start of an inbound websocket message.
+func (parseGuard *handlerSlowClientGuard) storeHandlerMessageStart() {
+	if parseGuard == nil {
+		return
+	}
+	parseGuard.storeMessageStart.Store(time.Now().UnixNano())
+}
+
+// storeHandlerMessageEnd marks the current inbound websocket message as fully read.
+func (parseGuard *handlerSlowClientGuard) storeHandlerMessageEnd() {
+	if parseGuard == nil {
+		return
+	}
+	parseGuard.storeMessageStart.Store(0)
+}
+
+// storeHandlerReadBytes counts payload bytes read from the client.
+func (parseGuard *handlerSlowClientGuard) storeHandlerReadBytes(parseBytes int) {
+	if parseGuard == nil || parseBytes <= 0 {
+		return
+	}
+	parseGuard.storeReadBytes.Add(int64(parseBytes))
+	parseGuard.storeWindowBytes.Add(int64(parseBytes))
+}
+
+// getHandlerWriteDeadline returns the deadline for a message write starting now, or the zero time when
+// WriteTimeout is disabled.
+func (parseGuard *handlerSlowClientGuard) getHandlerWriteDeadline() time.Time {
+	if parseGuard == nil || parseGuard.getWriteTimeout <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(parseGuard.getWriteTimeout)
+}
+
+// isHandlerWriteTimeout reports whether a write error came from WriteTimeout, recording the disconnect
+// when it did.
+func (parseGuard *handlerSlowClientGuard) isHandlerWriteTimeout(parseErr error) bool {
+	if parseGuard == nil || parseGuard.getWriteTimeout <= 0 {
+		return false
+	}
+	var parseNetErr net.Error
+	if !errors.As(parseErr, &parseNetErr) || !parseNetErr.Timeout() {
+		return false
+	}
+	parseGuard.storeHandlerSlowClientCause(TunnelCloseWriteTimeout)
+	parseGuard.getObservability.storeHandlerSlowClient(context.Background(), parseHandlerSlowClientWriteTimeout)
+	logBridgeEvent(parseGuard.getLogger, "WARN", "tunnel_write_timeout", parseGuard.getRequest, parseErr, "Closing tunnel because a message write exceeded WriteTimeout")
+	return true
+}
+
+// storeHandlerSlowClientCause records the first limit that closed the tunnel.
+func (parseGuard *handlerSlowClientGuard) storeHandlerSlowClientCause(parseCause TunnelCloseCause) {
+	parseGuard.storeCloseCause.CompareAndSwap(nil, &parseCause)
+}
+
+// getHandlerSlowClientCause returns the limit that closed the tunnel, or "" when none did.
+func (parseGuard *handlerSlowClientGuard) getHandlerSlowClientCause() TunnelCloseCause {
+	if parseGuard == nil {
+		return ""
+	}
+	if parseCause := parseGuard.storeCloseCause.Load(); parseCause != nil {
+		return *parseCause
+	}
+	return ""
+}
+
+// clearHandlerSlowClientGuard stops the guard's watchers.
+func (parseGuard *handlerSlowClientGuard) clearHandlerSlowClientGuard() {
+	if parseGuard == nil {
+		return
+	}
+	parseGuard.setCloseOnce.Do(func() {
+		close(parseGuard.getCloseSignal)
+	})
+}
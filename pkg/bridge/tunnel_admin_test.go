@@ -0,0 +1,102 @@
+//lint:file-ignore SA1019 grpc.DialContext is retained in tests to match bridge integration coverage on grpc 1.x.
+
+package bridge
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/monstercameron/grpc-tunnel/examples/_shared/proto"
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/tunneladmin"
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/tunnelregistry"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// TestHandlerTunnelAdmin verifies tunnels are listed in the admin's registry, the configured
+// observer still receives events, and limits changed through the admin apply to later upgrades.
+func TestHandlerTunnelAdmin(parseT *testing.T) {
+	parseBackendAddr, _ := buildPoolTestBackend(parseT, "administered")
+	parseAdmin := tunneladmin.New(nil)
+	parseObserver := buildRecordingTunnelObserver()
+	parseHandler := NewHandler(Config{TargetAddress: parseBackendAddr, TunnelAdmin: parseAdmin, TunnelObserver: parseObserver})
+	if parseHandler.initErr != nil {
+		parseT.Fatalf("NewHandler() error: %v", parseHandler.initErr)
+	}
+	parseServer := httptest.NewServer(parseHandler)
+	defer parseServer.Close()
+
+	parseCtx, clearCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer clearCtx()
+	parseDial := func() (*grpc.ClientConn, error) {
+		return grpc.DialContext(
+			parseCtx,
+			"ignored:1234",
+			DialOptionWithConfig("ws"+strings.TrimPrefix(parseServer.URL, "http"), ClientConfig{
+				Headers: http.Header{"Origin": []string{parseServer.URL}},
+			}),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		)
+	}
+	parseConn, parseErr := parseDial()
+	if parseErr != nil {
+		parseT.Fatalf("DialContext() error: %v", parseErr)
+	}
+	defer parseConn.Close()
+	if _, parseErr := proto.NewTodoServiceClient(parseConn).CreateTodo(parseCtx, &proto.CreateTodoRequest{Text: "listed"}); parseErr != nil {
+		parseT.Fatalf("CreateTodo() error: %v", parseErr)
+	}
+	if parseTunnels := parseAdmin.Registry().List(tunnelregistry.Filter{Component: "bridge.handler"}); len(parseTunnels) != 1 {
+		parseT.Fatalf("List() = %+v, want one bridge tunnel", parseTunnels)
+	}
+
+	if parseErr := parseAdmin.UpdateLimits(tunneladmin.Limits{MaxActiveConnections: 1}); parseErr != nil {
+		parseT.Fatalf("UpdateLimits() error: %v", parseErr)
+	}
+	parseRejectedConn, parseErr := parseDial()
+	if parseErr != nil {
+		parseT.Fatalf("DialContext() error: %v", parseErr)
+	}
+	defer parseRejectedConn.Close()
+	parseRejectCtx, clearRejectCtx := context.WithTimeout(parseCtx, 500*time.Millisecond)
+	defer clearRejectCtx()
+	if _, parseErr := proto.NewTodoServiceClient(parseRejectedConn).CreateTodo(parseRejectCtx, &proto.CreateTodoRequest{Text: "over limit"}); parseErr == nil {
+		parseT.Fatal("CreateTodo() over the updated connection limit succeeded, want rejection")
+	}
+	parseObserver.setLock.Lock()
+	defer parseObserver.setLock.Unlock()
+	if len(parseObserver.storeRejected) == 0 || parseObserver.storeRejected[0].Reason != UpgradeRejectActiveConnections {
+		parseT.Fatalf("rejected = %+v, want an active_connection_limit rejection", parseObserver.storeRejected)
+	}
+}
+
+// TestHandlerTunnelAdmin_RegistryMismatch verifies a TunnelRegistry other than the admin's is rejected.
+func TestHandlerTunnelAdmin_RegistryMismatch(parseT *testing.T) {
+	parseHandler := NewHandler(Config{
+		TargetAddress:  "127.0.0.1:1",
+		TunnelAdmin:    tunneladmin.New(nil),
+		TunnelRegistry: tunnelregistry.New(),
+	})
+	if parseHandler.initErr == nil {
+		parseT.Fatal("NewHandler() expected TunnelRegistry mismatch error, got nil")
+	}
+}
+
+// TestHandlerTunnelAdmin_LimitsIgnored verifies a handler whose limits lose to the admin's logs a warning.
+func TestHandlerTunnelAdmin_LimitsIgnored(parseT *testing.T) {
+	parseAdmin := tunneladmin.New(nil)
+	parseLogger := &testLogger{}
+	NewHandler(Config{TargetAddress: "127.0.0.1:1", TunnelAdmin: parseAdmin, MaxActiveConnections: 1, Logger: parseLogger})
+	NewHandler(Config{TargetAddress: "127.0.0.1:1", TunnelAdmin: parseAdmin, MaxActiveConnections: 1, Logger: parseLogger})
+	if strings.Contains(strings.Join(parseLogger.messages, "\n"), "tunnel_admin_limits_ignored") {
+		parseT.Fatalf("log = %q, want no warning for matching limits", parseLogger.messages)
+	}
+	NewHandler(Config{TargetAddress: "127.0.0.1:1", TunnelAdmin: parseAdmin, MaxActiveConnections: 2, Logger: parseLogger})
+	if !strings.Contains(strings.Join(parseLogger.messages, "\n"), "tunnel_admin_limits_ignored") {
+		parseT.Fatalf("log = %q, want tunnel_admin_limits_ignored", parseLogger.messages)
+	}
+}
@@ -0,0 +1,83 @@
+//lint:file-ignore SA1019 grpc.DialContext is retained in tests to match bridge integration coverage on grpc 1.x.
+
+package bridge
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/monstercameron/grpc-tunnel/examples/_shared/proto"
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/tunnelregistry"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// TestHandlerTunnelRegistry verifies open tunnels are listed with live stats and that force-closing
+// one disconnects it with the terminated cause.
+func TestHandlerTunnelRegistry(parseT *testing.T) {
+	parseBackendAddr, _ := buildPoolTestBackend(parseT, "registered")
+	parseRegistry := tunnelregistry.New()
+	parseObserver := buildRecordingTunnelObserver()
+	parseHandler := NewHandler(Config{TargetAddress: parseBackendAddr, TunnelRegistry: parseRegistry, TunnelObserver: parseObserver})
+	if parseHandler.initErr != nil {
+		parseT.Fatalf("NewHandler() error: %v", parseHandler.initErr)
+	}
+	parseServer := httptest.NewServer(parseHandler)
+	defer parseServer.Close()
+
+	parseCtx, clearCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer clearCtx()
+	parseConn, parseErr := grpc.DialContext(
+		parseCtx,
+		"ignored:1234",
+		DialOptionWithConfig("ws"+strings.TrimPrefix(parseServer.URL, "http"), ClientConfig{
+			Headers: http.Header{"Origin": []string{parseServer.URL}},
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if parseErr != nil {
+		parseT.Fatalf("DialContext() error: %v", parseErr)
+	}
+	defer parseConn.Close()
+	parseClient := proto.NewTodoServiceClient(parseConn)
+	if _, parseErr := parseClient.CreateTodo(parseCtx, &proto.CreateTodoRequest{Text: "listed"}); parseErr != nil {
+		parseT.Fatalf("CreateTodo() error: %v", parseErr)
+	}
+
+	parseTunnels := parseRegistry.List(tunnelregistry.Filter{ClientKey: "127.0.0.1"})
+	if len(parseTunnels) != 1 {
+		parseT.Fatalf("List() = %+v, want one tunnel from 127.0.0.1", parseTunnels)
+	}
+	parseTunnel := parseTunnels[0]
+	if parseTunnel.Component != "bridge.handler" || parseTunnel.Origin != parseServer.URL || parseTunnel.BytesIn == 0 || parseTunnel.BytesOut == 0 {
+		parseT.Fatalf("tunnel = %+v, want origin and byte counts", parseTunnel)
+	}
+
+	if parseCount := parseRegistry.CloseMatching(tunnelregistry.Filter{Component: "bridge.handler"}); parseCount != 1 {
+		parseT.Fatalf("CloseMatching() = %d, want 1", parseCount)
+	}
+	if parseDisconnected := parseObserver.waitDisconnected(parseT); parseDisconnected.TunnelID != parseTunnel.ID || parseDisconnected.Cause != TunnelCloseTerminated {
+		parseT.Fatalf("disconnected = %+v, want tunnel %s terminated", parseDisconnected, parseTunnel.ID)
+	}
+	if parseRegistry.Len() != 0 {
+		parseT.Fatalf("Len() = %d, want the closed tunnel removed", parseRegistry.Len())
+	}
+
+	// The client redials a fresh tunnel on its next call.
+	for parseConn.GetState() == connectivity.Ready {
+		if !parseConn.WaitForStateChange(parseCtx, connectivity.Ready) {
+			parseT.Fatal("client never noticed the closed tunnel")
+		}
+	}
+	if _, parseErr := parseClient.CreateTodo(parseCtx, &proto.CreateTodoRequest{Text: "redialed"}, grpc.WaitForReady(true)); parseErr != nil {
+		parseT.Fatalf("CreateTodo() after CloseMatching error: %v", parseErr)
+	}
+	if parseRegistry.Len() != 1 {
+		parseT.Fatalf("Len() after redial = %d, want 1", parseRegistry.Len())
+	}
+}
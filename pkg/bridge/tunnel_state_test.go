@@ -0,0 +1,124 @@
+//lint:file-ignore SA1019 grpc.DialContext is retained in tests to match bridge integration coverage on grpc 1.x.
+
+package bridge
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/monstercameron/grpc-tunnel/examples/_shared/proto"
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/tunnelstate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// TestHandlerTunnelStates verifies handler tunnels and rejected upgrades are recorded under the
+// bridge.handler component alongside the configured observer.
+func TestHandlerTunnelStates(parseT *testing.T) {
+	parseBackendAddr, _ := buildPoolTestBackend(parseT, "recorded")
+	parseStates := tunnelstate.New(0)
+	parseObserver := buildRecordingTunnelObserver()
+	parseHandler := NewHandler(Config{TargetAddress: parseBackendAddr, TunnelStates: parseStates, TunnelObserver: parseObserver})
+	if parseHandler.initErr != nil {
+		parseT.Fatalf("NewHandler() error: %v", parseHandler.initErr)
+	}
+	parseServer := httptest.NewServer(parseHandler)
+	defer parseServer.Close()
+
+	parseCtx, clearCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer clearCtx()
+	parseConn, parseErr := grpc.DialContext(
+		parseCtx,
+		"ignored:1234",
+		DialOptionWithConfig("ws"+strings.TrimPrefix(parseServer.URL, "http"), ClientConfig{
+			Headers: http.Header{"Origin": []string{parseServer.URL}},
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if parseErr != nil {
+		parseT.Fatalf("DialContext() error: %v", parseErr)
+	}
+	defer parseConn.Close()
+	if _, parseErr := proto.NewTodoServiceClient(parseConn).CreateTodo(parseCtx, &proto.CreateTodoRequest{Text: "recorded"}); parseErr != nil {
+		parseT.Fatalf("CreateTodo() error: %v", parseErr)
+	}
+
+	parseRequest, _ := http.NewRequest(http.MethodGet, parseServer.URL, nil)
+	parseRequest.Header.Set("Connection", "Upgrade")
+	parseRequest.Header.Set("Upgrade", "websocket")
+	parseRequest.Header.Set("Origin", "https://evil.example")
+	parseRequest.Header.Set("Sec-WebSocket-Version", "13")
+	parseRequest.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	if parseResponse, parseErr := http.DefaultClient.Do(parseRequest); parseErr == nil {
+		parseResponse.Body.Close()
+	}
+
+	parseSnapshot := parseStates.Snapshot()
+	if len(parseSnapshot.Tunnels) != 1 || parseSnapshot.Tunnels[0].Component != "bridge.handler" {
+		parseT.Fatalf("Tunnels = %+v, want one bridge.handler tunnel", parseSnapshot.Tunnels)
+	}
+	if len(parseSnapshot.Counters) != 1 || parseSnapshot.Counters[0].TunnelsConnected != 1 || len(parseSnapshot.Counters[0].UpgradeRejections) != 1 {
+		parseT.Fatalf("Counters = %+v, want one connected tunnel and one rejection reason", parseSnapshot.Counters)
+	}
+	parseObserver.setLock.Lock()
+	defer parseObserver.setLock.Unlock()
+	if len(parseObserver.storeRejected) != 1 {
+		parseT.Fatalf("observer rejected = %+v, want the rejection delivered too", parseObserver.storeRejected)
+	}
+}
+
+// TestHandlerTunnelStates_BackendDialFailed verifies a stream whose backend cannot be reached is
+// recorded as backend_dial_failed on its tunnel.
+func TestHandlerTunnelStates_BackendDialFailed(parseT *testing.T) {
+	parseListener, parseErr := net.Listen("tcp", "127.0.0.1:0")
+	if parseErr != nil {
+		parseT.Fatalf("Listen() error: %v", parseErr)
+	}
+	parseBackendAddr := parseListener.Addr().String()
+	parseListener.Close()
+	parseStates := tunnelstate.New(0)
+	parseHandler := NewHandler(Config{TargetAddress: parseBackendAddr, TunnelStates: parseStates, Logger: &testLogger{}})
+	if parseHandler.initErr != nil {
+		parseT.Fatalf("NewHandler() error: %v", parseHandler.initErr)
+	}
+	parseServer := httptest.NewServer(parseHandler)
+	defer parseServer.Close()
+
+	parseCtx, clearCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer clearCtx()
+	parseConn, parseErr := grpc.DialContext(
+		parseCtx,
+		"ignored:1234",
+		DialOptionWithConfig("ws"+strings.TrimPrefix(parseServer.URL, "http"), ClientConfig{
+			Headers: http.Header{"Origin": []string{parseServer.URL}},
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if parseErr != nil {
+		parseT.Fatalf("DialContext() error: %v", parseErr)
+	}
+	defer parseConn.Close()
+	if _, parseErr := proto.NewTodoServiceClient(parseConn).CreateTodo(parseCtx, &proto.CreateTodoRequest{Text: "unreachable"}); parseErr == nil {
+		parseT.Fatal("CreateTodo() error = nil, want the backend dial failure")
+	}
+
+	parseSnapshot := parseStates.Snapshot()
+	if len(parseSnapshot.Counters) != 1 || parseSnapshot.Counters[0].BackendDialFailures != 1 {
+		parseT.Fatalf("Counters = %+v, want one backend dial failure", parseSnapshot.Counters)
+	}
+	for _, parseTransition := range parseSnapshot.Transitions {
+		if parseTransition.State != tunnelstate.StateBackendDialFailed {
+			continue
+		}
+		if parseTransition.TunnelID == "" || parseTransition.Target != proto.TodoService_CreateTodo_FullMethodName || parseTransition.ErrorMessage == "" {
+			parseT.Fatalf("backend_dial_failed transition = %+v, want tunnel ID, method, and error", parseTransition)
+		}
+		return
+	}
+	parseT.Fatalf("Transitions = %+v, want a backend_dial_failed transition", parseSnapshot.Transitions)
+}
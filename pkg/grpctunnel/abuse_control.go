@@ -3,108 +3,529 @@
 package grpctunnel
 
 import (
+	"container/list"
+	"errors"
 	"fmt"
+	"hash/fnv"
+	"math"
 	"net"
 	"net/http"
+	"net/netip"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/tunneladmin"
 )
 
 const parseBridgeAbuseWindowDuration = time.Minute
+const parseBridgeAbuseShardCount = 32
+const parseBridgeDefaultMaxTrackedClients = 1 << 16
+const parseBridgeDefaultClientStateTTL = 10 * time.Minute
+const parseBridgeDefaultBaseBan = 30 * time.Second
+const parseBridgeDefaultMaxBan = time.Hour
+
+const parseBridgeAbuseReasonDenied = "client_denied"
+const parseBridgeAbuseReasonBanned = "client_banned"
+const parseBridgeAbuseReasonUpgradeRate = "upgrade_rate_limited"
+const parseBridgeAbuseReasonClientConnections = "client_connection_limit"
+const parseBridgeAbuseReasonActiveConnections = "active_connection_limit"
+const parseBridgeAbuseReasonClientTableFull = "client_table_full"
 
-// storeBridgeAbuseRateWindow tracks one client's fixed-window upgrade attempt counts.
-type storeBridgeAbuseRateWindow struct {
-	getWindowStartedAt time.Time
-	getWindowCount     int
+// bridgeTokenBucket stores one token bucket. The zero value starts full on first use.
+type bridgeTokenBucket struct {
+	getTokens    float64
+	getUpdatedAt time.Time
 }
 
-// bridgeAbuseGuard stores in-memory counters used to enforce websocket abuse controls.
-type bridgeAbuseGuard struct {
-	setGuardLock sync.Mutex
-	setConfig    BridgeConfig
+// refillBridgeTokenBucket adds tokens earned since the last update, capped at the burst size.
+func (parseBucket *bridgeTokenBucket) refillBridgeTokenBucket(parseLimit RateLimit, parseNow time.Time) {
+	parseBurst := float64(getBridgeRateLimitBurst(parseLimit))
+	if parseBucket.getUpdatedAt.IsZero() {
+		parseBucket.getTokens = parseBurst
+		parseBucket.getUpdatedAt = parseNow
+		return
+	}
+	if parseElapsed := parseNow.Sub(parseBucket.getUpdatedAt); parseElapsed > 0 {
+		parseBucket.getTokens = math.Min(parseBurst, parseBucket.getTokens+parseElapsed.Seconds()*parseLimit.PerSecond)
+		parseBucket.getUpdatedAt = parseNow
+	}
+}
+
+// allowBridgeTokenBucket takes one token when available.
+func (parseBucket *bridgeTokenBucket) allowBridgeTokenBucket(parseLimit RateLimit, parseNow time.Time) bool {
+	parseBucket.refillBridgeTokenBucket(parseLimit, parseNow)
+	if parseBucket.getTokens < 1 {
+		return false
+	}
+	parseBucket.getTokens--
+	return true
+}
+
+// reserveBridgeTokenBucket takes tokens even when that leaves the bucket in debt and returns how long
+// the caller must wait for the debt to be repaid.
+func (parseBucket *bridgeTokenBucket) reserveBridgeTokenBucket(parseLimit RateLimit, parseNow time.Time, parseTokens float64) time.Duration {
+	parseBucket.refillBridgeTokenBucket(parseLimit, parseNow)
+	parseBucket.getTokens -= parseTokens
+	if parseBucket.getTokens >= 0 {
+		return 0
+	}
+	return time.Duration(-parseBucket.getTokens / parseLimit.PerSecond * float64(time.Second))
+}
+
+// getBridgeRateLimitBurst returns the bucket capacity, defaulting to one second of tokens.
+func getBridgeRateLimitBurst(parseLimit RateLimit) int {
+	if parseLimit.Burst > 0 {
+		return parseLimit.Burst
+	}
+	return max(1, int(math.Ceil(parseLimit.PerSecond)))
+}
+
+// getBridgeRateLimitError validates one token-bucket limit.
+func getBridgeRateLimitError(parseName string, parseLimit RateLimit) error {
+	if parseLimit.PerSecond < 0 || math.IsNaN(parseLimit.PerSecond) || math.IsInf(parseLimit.PerSecond, 0) {
+		return fmt.Errorf("grpctunnel: %s.PerSecond must be a finite value >= 0", parseName)
+	}
+	if parseLimit.Burst < 0 {
+		return fmt.Errorf("grpctunnel: %s.Burst must be >= 0", parseName)
+	}
+	if parseLimit.Burst > 0 && parseLimit.PerSecond == 0 {
+		return fmt.Errorf("grpctunnel: %s.Burst requires PerSecond", parseName)
+	}
+	return nil
+}
+
+// getBridgeUpgradeRateLimit returns the per-client upgrade limit, mapping the legacy per-minute cap onto a bucket.
+func getBridgeUpgradeRateLimit(parseConfig BridgeConfig) RateLimit {
+	if parseConfig.UpgradeRateLimit.PerSecond > 0 {
+		return parseConfig.UpgradeRateLimit
+	}
+	if parseConfig.MaxUpgradesPerClientPerMinute > 0 {
+		return RateLimit{
+			PerSecond: float64(parseConfig.MaxUpgradesPerClientPerMinute) / parseBridgeAbuseWindowDuration.Seconds(),
+			Burst:     parseConfig.MaxUpgradesPerClientPerMinute,
+		}
+	}
+	return RateLimit{}
+}
+
+// buildBridgeAbuseLimits returns the configured limits a TunnelAdmin may change while tunnels run.
+func buildBridgeAbuseLimits(parseConfig BridgeConfig) tunneladmin.Limits {
+	return tunneladmin.Limits{
+		MaxActiveConnections:    parseConfig.MaxActiveConnections,
+		MaxConnectionsPerClient: parseConfig.MaxConnectionsPerClient,
+		UpgradeRateLimit:        tunneladmin.RateLimit(getBridgeUpgradeRateLimit(parseConfig)),
+		RPCRateLimit:            tunneladmin.RateLimit(parseConfig.RPCRateLimit),
+	}
+}
+
+// bridgeRateLimiter guards one token bucket with its own lock, such as the RPC bucket of a tunnel.
+type bridgeRateLimiter struct {
+	setLimiterLock sync.Mutex
+	getLimit       RateLimit
+	getBucket      bridgeTokenBucket
+}
 
-	getActiveConnections       int
-	storeClientConnections     map[string]int
-	storeClientUpgradeAttempts map[string]storeBridgeAbuseRateWindow
+// buildBridgeRateLimiter returns a limiter, or nil when the limit is disabled.
+func buildBridgeRateLimiter(parseLimit RateLimit) *bridgeRateLimiter {
+	if parseLimit.PerSecond <= 0 {
+		return nil
+	}
+	return &bridgeRateLimiter{getLimit: parseLimit}
+}
+
+// allowBridgeRateLimiter takes one token. A nil limiter always allows.
+func (parseLimiter *bridgeRateLimiter) allowBridgeRateLimiter(parseNow time.Time) bool {
+	if parseLimiter == nil {
+		return true
+	}
+	parseLimiter.setLimiterLock.Lock()
+	defer parseLimiter.setLimiterLock.Unlock()
+	return parseLimiter.getBucket.allowBridgeTokenBucket(parseLimiter.getLimit, parseNow)
+}
+
+// bridgeAbuseRejection describes why abuse controls rejected an upgrade.
+type bridgeAbuseRejection struct {
+	getReason     string
+	getStatusCode int
+	getRetryAfter time.Duration
+	getDetail     string
+}
+
+// Error returns the machine-readable reason followed by details.
+func (parseRejection *bridgeAbuseRejection) Error() string {
+	return parseRejection.getReason + ": " + parseRejection.getDetail
+}
+
+// buildBridgeAbuseRejection creates a rejection answered with 429 Too Many Requests.
+func buildBridgeAbuseRejection(parseReason string, parseRetryAfter time.Duration, parseFormat string, parseArgs ...any) *bridgeAbuseRejection {
+	return &bridgeAbuseRejection{
+		getReason:     parseReason,
+		getStatusCode: http.StatusTooManyRequests,
+		getRetryAfter: parseRetryAfter,
+		getDetail:     fmt.Sprintf(parseFormat, parseArgs...),
+	}
+}
+
+// writeBridgeAbuseRejection answers a rejected upgrade with its status code, reason body, and Retry-After when known.
+func writeBridgeAbuseRejection(parseW http.ResponseWriter, parseErr error) {
+	var parseRejection *bridgeAbuseRejection
+	if !errors.As(parseErr, &parseRejection) {
+		http.Error(parseW, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		return
+	}
+	if parseRejection.getRetryAfter > 0 {
+		parseSeconds := int64(math.Ceil(parseRejection.getRetryAfter.Seconds()))
+		parseW.Header().Set("Retry-After", strconv.FormatInt(parseSeconds, 10))
+	}
+	http.Error(parseW, parseRejection.getReason, parseRejection.getStatusCode)
+}
+
+// bridgeAbuseClient stores the rate and penalty state of one client key.
+type bridgeAbuseClient struct {
+	getClientKey     string
+	getUpgradeBucket bridgeTokenBucket
+	getLastSeenAt    time.Time
+	getStrikes       int
+	getBans          int
+	getBannedUntil   time.Time
+}
+
+// bridgeAbuseShard stores the per-client state for one slice of the client key space.
+// Client entries are kept in least-recently-seen order so the oldest can be evicted first.
+type bridgeAbuseShard struct {
+	setShardLock     sync.Mutex
+	storeClients     map[string]*list.Element
+	storeClientOrder list.List
+}
+
+// bridgeAbuseGuard enforces websocket abuse controls. Connection slots live in the LimitStore so
+// replicas can share them; rate and penalty state stays in memory, sharded by client key so concurrent
+// upgrades from different clients do not serialize, and bounded by MaxTrackedClients and ClientStateTTL
+// so address scans cannot grow it without limit.
+type bridgeAbuseGuard struct {
+	setConfig          BridgeConfig
+	getLimits          tunneladmin.Limits
+	getAdmin           *tunneladmin.Admin
+	getPenaltyBox      PenaltyBox
+	getAllowedClients  []netip.Prefix
+	getDeniedClients   []netip.Prefix
+	getMaxShardClients int
+	getClientStateTTL  time.Duration
+	getLimitStore      LimitStore
+	getLeaseTTL        time.Duration
+	getShards          [parseBridgeAbuseShardCount]bridgeAbuseShard
 }
 
 // buildBridgeAbuseGuard creates an abuse guard for bridge runtime controls.
+// GetBridgeConfigError has already validated the client CIDR lists.
 func buildBridgeAbuseGuard(parseConfig BridgeConfig) *bridgeAbuseGuard {
-	return &bridgeAbuseGuard{
-		setConfig:                  parseConfig,
-		storeClientConnections:     map[string]int{},
-		storeClientUpgradeAttempts: map[string]storeBridgeAbuseRateWindow{},
+	parseLimits := buildBridgeAbuseLimits(parseConfig)
+	parseGuard := &bridgeAbuseGuard{
+		setConfig:         parseConfig,
+		getLimits:         parseConfig.TunnelAdmin.InitLimits(parseLimits),
+		getAdmin:          parseConfig.TunnelAdmin,
+		getPenaltyBox:     getBridgePenaltyBox(parseConfig.PenaltyBox),
+		getClientStateTTL: parseConfig.ClientStateTTL,
+		getLimitStore:     parseConfig.LimitStore,
+		getLeaseTTL:       parseConfig.LimitStoreLeaseTTL,
 	}
+	if parseGuard.getLimits != parseLimits {
+		logGrpctunnelEvent("grpctunnel.bridge", "WARN", "tunnel_admin_limits_ignored", nil, nil, "Configured abuse limits differ from the TunnelAdmin limits in effect; the TunnelAdmin limits apply")
+	}
+	if parseGuard.getLimitStore == nil {
+		// In-process leases end with the process, so they never need to expire.
+		parseGuard.getLimitStore = buildBridgeMemoryLimitStore()
+		parseGuard.getLeaseTTL = 0
+	} else if parseGuard.getLeaseTTL == 0 {
+		parseGuard.getLeaseTTL = parseBridgeDefaultLeaseTTL
+	}
+	parseGuard.getAllowedClients, _ = parseBridgePrefixes("AllowedClientCIDRs", parseConfig.AllowedClientCIDRs)
+	parseGuard.getDeniedClients, _ = parseBridgePrefixes("DeniedClientCIDRs", parseConfig.DeniedClientCIDRs)
+
+	parseMaxClients := parseConfig.MaxTrackedClients
+	if parseMaxClients == 0 {
+		parseMaxClients = parseBridgeDefaultMaxTrackedClients
+	}
+	parseGuard.getMaxShardClients = max(1, (parseMaxClients+parseBridgeAbuseShardCount-1)/parseBridgeAbuseShardCount)
+	if parseGuard.getClientStateTTL == 0 {
+		parseGuard.getClientStateTTL = parseBridgeDefaultClientStateTTL
+	}
+	// Keep banned clients tracked for the whole ban.
+	parseGuard.getClientStateTTL = max(parseGuard.getClientStateTTL, parseGuard.getPenaltyBox.MaxBan)
+
+	for parseIndex := range parseGuard.getShards {
+		parseGuard.getShards[parseIndex].storeClients = map[string]*list.Element{}
+	}
+	return parseGuard
 }
 
-// reserveBridgeConnection validates abuse controls and reserves one connection slot.
-func (parseGuard *bridgeAbuseGuard) reserveBridgeConnection(parseRequest *http.Request, parseNow time.Time) error {
-	if parseGuard == nil {
-		return nil
+// getBridgePenaltyBox applies penalty box defaults.
+func getBridgePenaltyBox(parsePenaltyBox PenaltyBox) PenaltyBox {
+	if parsePenaltyBox.Strikes == 0 {
+		return PenaltyBox{}
+	}
+	if parsePenaltyBox.BaseBan == 0 {
+		parsePenaltyBox.BaseBan = parseBridgeDefaultBaseBan
+	}
+	if parsePenaltyBox.MaxBan == 0 {
+		parsePenaltyBox.MaxBan = max(parseBridgeDefaultMaxBan, parsePenaltyBox.BaseBan)
+	}
+	return parsePenaltyBox
+}
+
+// getBridgePenaltyBoxError validates penalty box settings.
+func getBridgePenaltyBoxError(parsePenaltyBox PenaltyBox) error {
+	if parsePenaltyBox.Strikes < 0 {
+		return fmt.Errorf("grpctunnel: PenaltyBox.Strikes must be >= 0")
+	}
+	if parsePenaltyBox.BaseBan < 0 || parsePenaltyBox.MaxBan < 0 {
+		return fmt.Errorf("grpctunnel: PenaltyBox ban durations must be >= 0")
+	}
+	if parsePenaltyBox.BaseBan > 0 && parsePenaltyBox.MaxBan > 0 && parsePenaltyBox.MaxBan < parsePenaltyBox.BaseBan {
+		return fmt.Errorf("grpctunnel: PenaltyBox.MaxBan must be >= BaseBan")
 	}
+	return nil
+}
+
+// getBridgeAbuseLimits returns the limits in effect, which a TunnelAdmin may change while tunnels run.
+func (parseGuard *bridgeAbuseGuard) getBridgeAbuseLimits() tunneladmin.Limits {
+	if parseGuard.getAdmin != nil {
+		return parseGuard.getAdmin.Limits()
+	}
+	return parseGuard.getLimits
+}
+
+// getBridgeSlotLimit returns the slot limit to reserve against, or zero to skip counting. With a
+// TunnelAdmin, disabled limits still count connections so that enabling them later sees the tunnels
+// already open.
+func (parseGuard *bridgeAbuseGuard) getBridgeSlotLimit(parseLimit int) int {
+	if parseLimit == 0 && parseGuard.getAdmin != nil {
+		return math.MaxInt
+	}
+	return parseLimit
+}
 
-	parseClientKey := buildBridgeClientKey(parseRequest)
+// getBridgeAbuseShard returns the shard that owns a client key.
+func (parseGuard *bridgeAbuseGuard) getBridgeAbuseShard(parseClientKey string) *bridgeAbuseShard {
+	parseHash := fnv.New32a()
+	_, _ = parseHash.Write([]byte(parseClientKey))
+	return &parseGuard.getShards[parseHash.Sum32()%parseBridgeAbuseShardCount]
+}
+
+// getBridgeGuardClientKey resolves the client key with ClientKeyFunc, falling back to the client address.
+// IPv6 addresses are grouped by /64 when ShouldGroupIPv6Clients is set.
+func (parseGuard *bridgeAbuseGuard) getBridgeGuardClientKey(parseRequest *http.Request) string {
+	parseClientKey := ""
+	if parseGuard.setConfig.ClientKeyFunc != nil && parseRequest != nil {
+		parseClientKey = parseGuard.setConfig.ClientKeyFunc(parseRequest)
+	}
+	if parseClientKey == "" && parseGuard.setConfig.ShouldGroupIPv6Clients && parseRequest != nil {
+		if parseAddr, isValidAddr := parseBridgeRemoteAddr(parseRequest.RemoteAddr); isValidAddr && parseAddr.Is6() {
+			parsePrefix, _ := parseAddr.WithZone("").Prefix(64)
+			parseClientKey = parsePrefix.String()
+		}
+	}
+	if parseClientKey == "" {
+		parseClientKey = buildBridgeClientKey(parseRequest)
+	}
 	if parseClientKey == "" {
 		parseClientKey = "unknown"
 	}
+	return parseClientKey
+}
 
-	parseGuard.setGuardLock.Lock()
-	defer parseGuard.setGuardLock.Unlock()
+// reserveBridgeConnection validates abuse controls and reserves connection slots for one tunnel.
+// Rejections are *bridgeAbuseRejection values carrying a reason and, when known, a retry delay.
+// The returned lease must be passed to clearBridgeConnection when the tunnel ends.
+func (parseGuard *bridgeAbuseGuard) reserveBridgeConnection(parseRequest *http.Request, parseNow time.Time) (*bridgeConnectionLease, error) {
+	if parseGuard == nil {
+		return nil, nil
+	}
 
-	if parseGuard.setConfig.MaxUpgradesPerClientPerMinute > 0 {
-		parseWindow := parseGuard.storeClientUpgradeAttempts[parseClientKey]
-		if parseWindow.getWindowStartedAt.IsZero() || parseNow.Sub(parseWindow.getWindowStartedAt) >= parseBridgeAbuseWindowDuration {
-			parseWindow = storeBridgeAbuseRateWindow{
-				getWindowStartedAt: parseNow,
-				getWindowCount:     0,
+	isExemptClient := false
+	if parseRequest != nil {
+		if parseAddr, isValidAddr := parseBridgeRemoteAddr(parseRequest.RemoteAddr); isValidAddr {
+			if isBridgePrefixMember(parseGuard.getDeniedClients, parseAddr) {
+				return nil, &bridgeAbuseRejection{
+					getReason:     parseBridgeAbuseReasonDenied,
+					getStatusCode: http.StatusForbidden,
+					getDetail:     fmt.Sprintf("client %s matches DeniedClientCIDRs", parseAddr),
+				}
 			}
+			isExemptClient = isBridgePrefixMember(parseGuard.getAllowedClients, parseAddr)
 		}
-		if parseWindow.getWindowCount >= parseGuard.setConfig.MaxUpgradesPerClientPerMinute {
-			return fmt.Errorf("upgrade rate exceeded for client %q", parseClientKey)
-		}
-		parseWindow.getWindowCount++
-		parseGuard.storeClientUpgradeAttempts[parseClientKey] = parseWindow
 	}
 
-	if parseGuard.setConfig.MaxActiveConnections > 0 && parseGuard.getActiveConnections >= parseGuard.setConfig.MaxActiveConnections {
-		return fmt.Errorf("active connection cap exceeded")
+	parseClientKey := parseGuard.getBridgeGuardClientKey(parseRequest)
+	parseLimits := parseGuard.getBridgeAbuseLimits()
+	parseUpgradeLimit := RateLimit(parseLimits.UpgradeRateLimit)
+	isTrackedClient := (parseUpgradeLimit.PerSecond > 0 || parseGuard.getPenaltyBox.Strikes > 0) && !isExemptClient
+	if isTrackedClient {
+		if parseErr := parseGuard.checkBridgeClientRate(parseClientKey, parseUpgradeLimit, parseNow); parseErr != nil {
+			return nil, parseErr
+		}
 	}
 
-	parseClientConnections := parseGuard.storeClientConnections[parseClientKey]
-	if parseGuard.setConfig.MaxConnectionsPerClient > 0 && parseClientConnections >= parseGuard.setConfig.MaxConnectionsPerClient {
-		return fmt.Errorf("per-client connection cap exceeded for client %q", parseClientKey)
+	parseLease := buildBridgeConnectionLease(parseGuard)
+	parseClientLimit := parseGuard.getBridgeSlotLimit(parseLimits.MaxConnectionsPerClient)
+	if !isExemptClient && parseClientLimit > 0 {
+		if !parseLease.reserveBridgeLeaseSlot(parseRequest, parseBridgeClientSlotPrefix+parseClientKey, parseClientLimit) {
+			parseRetryAfter := time.Duration(0)
+			if isTrackedClient {
+				parseRetryAfter = parseGuard.strikeBridgeAbuseClient(parseClientKey, parseNow)
+			}
+			return nil, buildBridgeAbuseRejection(parseBridgeAbuseReasonClientConnections, parseRetryAfter, "per-client connection cap exceeded for client %q", parseClientKey)
+		}
+	}
+	if parseActiveLimit := parseGuard.getBridgeSlotLimit(parseLimits.MaxActiveConnections); parseActiveLimit > 0 {
+		if !parseLease.reserveBridgeLeaseSlot(parseRequest, parseBridgeActiveSlotKey, parseActiveLimit) {
+			parseLease.clearBridgeLease()
+			return nil, buildBridgeAbuseRejection(parseBridgeAbuseReasonActiveConnections, 0, "active connection cap exceeded")
+		}
 	}
+	if isTrackedClient {
+		parseGuard.clearBridgeAbuseStrikes(parseClientKey)
+	}
+	parseLease.startBridgeLeaseRenewal()
+	return parseLease, nil
+}
 
-	parseGuard.getActiveConnections++
-	parseGuard.storeClientConnections[parseClientKey] = parseClientConnections + 1
+// checkBridgeClientRate applies the penalty box and upgrade rate limit to a client key.
+func (parseGuard *bridgeAbuseGuard) checkBridgeClientRate(parseClientKey string, parseUpgradeLimit RateLimit, parseNow time.Time) error {
+	parseShard := parseGuard.getBridgeAbuseShard(parseClientKey)
+	parseShard.setShardLock.Lock()
+	defer parseShard.setShardLock.Unlock()
+
+	parseGuard.sweepBridgeAbuseShard(parseShard, parseNow)
+	parseClient, parseRetryAfter := parseGuard.touchBridgeAbuseClient(parseShard, parseClientKey, parseNow)
+	if parseClient == nil {
+		return buildBridgeAbuseRejection(parseBridgeAbuseReasonClientTableFull, parseRetryAfter, "client state table is full of banned clients")
+	}
+	if parseNow.Before(parseClient.getBannedUntil) {
+		return buildBridgeAbuseRejection(parseBridgeAbuseReasonBanned, parseClient.getBannedUntil.Sub(parseNow), "client %q is in the penalty box", parseClientKey)
+	}
+	if parseUpgradeLimit.PerSecond > 0 && !parseClient.getUpgradeBucket.allowBridgeTokenBucket(parseUpgradeLimit, parseNow) {
+		parseRetryAfter := time.Duration((1 - parseClient.getUpgradeBucket.getTokens) / parseUpgradeLimit.PerSecond * float64(time.Second))
+		parseRetryAfter = max(parseRetryAfter, parseGuard.strikeBridgeClientLocked(parseClient, parseNow))
+		return buildBridgeAbuseRejection(parseBridgeAbuseReasonUpgradeRate, parseRetryAfter, "upgrade rate exceeded for client %q", parseClientKey)
+	}
 	return nil
 }
 
-// clearBridgeConnection releases one reserved connection slot for abuse controls.
-func (parseGuard *bridgeAbuseGuard) clearBridgeConnection(parseRequest *http.Request) {
-	if parseGuard == nil {
-		return
+// touchBridgeAbuseClient returns the state for a client key, creating it and evicting the least recently
+// seen unbanned client when the shard is full. Banned clients are never evicted, so rotating keys cannot
+// flush a ban; when only banned clients remain it returns nil and the wait until the first ban ends.
+// Callers hold the shard lock.
+func (parseGuard *bridgeAbuseGuard) touchBridgeAbuseClient(parseShard *bridgeAbuseShard, parseClientKey string, parseNow time.Time) (*bridgeAbuseClient, time.Duration) {
+	if parseElement, isFoundClient := parseShard.storeClients[parseClientKey]; isFoundClient {
+		parseShard.storeClientOrder.MoveToFront(parseElement)
+		parseClient := parseElement.Value.(*bridgeAbuseClient)
+		parseClient.getLastSeenAt = parseNow
+		return parseClient, 0
 	}
+	for len(parseShard.storeClients) >= parseGuard.getMaxShardClients {
+		parseVictim, parseBanWait := getBridgeAbuseEvictionVictim(parseShard, parseNow)
+		if parseVictim == nil {
+			return nil, parseBanWait
+		}
+		delete(parseShard.storeClients, parseVictim.Value.(*bridgeAbuseClient).getClientKey)
+		parseShard.storeClientOrder.Remove(parseVictim)
+	}
+	parseClient := &bridgeAbuseClient{getClientKey: parseClientKey, getLastSeenAt: parseNow}
+	parseShard.storeClients[parseClientKey] = parseShard.storeClientOrder.PushFront(parseClient)
+	return parseClient, 0
+}
 
-	parseClientKey := buildBridgeClientKey(parseRequest)
-	if parseClientKey == "" {
-		parseClientKey = "unknown"
+// getBridgeAbuseEvictionVictim returns the least recently seen client that is not banned. When every client
+// is banned it returns nil and the shortest remaining ban. Callers hold the shard lock.
+func getBridgeAbuseEvictionVictim(parseShard *bridgeAbuseShard, parseNow time.Time) (*list.Element, time.Duration) {
+	parseBanWait := time.Duration(0)
+	for parseElement := parseShard.storeClientOrder.Back(); parseElement != nil; parseElement = parseElement.Prev() {
+		parseClient := parseElement.Value.(*bridgeAbuseClient)
+		if !parseNow.Before(parseClient.getBannedUntil) {
+			return parseElement, 0
+		}
+		if parseWait := parseClient.getBannedUntil.Sub(parseNow); parseBanWait == 0 || parseWait < parseBanWait {
+			parseBanWait = parseWait
+		}
 	}
+	return nil, parseBanWait
+}
 
-	parseGuard.setGuardLock.Lock()
-	defer parseGuard.setGuardLock.Unlock()
+// strikeBridgeAbuseClient records one rejection for a tracked client key.
+func (parseGuard *bridgeAbuseGuard) strikeBridgeAbuseClient(parseClientKey string, parseNow time.Time) time.Duration {
+	parseShard := parseGuard.getBridgeAbuseShard(parseClientKey)
+	parseShard.setShardLock.Lock()
+	defer parseShard.setShardLock.Unlock()
+	parseElement, isFoundClient := parseShard.storeClients[parseClientKey]
+	if !isFoundClient {
+		return 0
+	}
+	return parseGuard.strikeBridgeClientLocked(parseElement.Value.(*bridgeAbuseClient), parseNow)
+}
 
-	if parseGuard.getActiveConnections > 0 {
-		parseGuard.getActiveConnections--
+// clearBridgeAbuseStrikes resets the consecutive rejection count after an accepted upgrade.
+func (parseGuard *bridgeAbuseGuard) clearBridgeAbuseStrikes(parseClientKey string) {
+	parseShard := parseGuard.getBridgeAbuseShard(parseClientKey)
+	parseShard.setShardLock.Lock()
+	defer parseShard.setShardLock.Unlock()
+	if parseElement, isFoundClient := parseShard.storeClients[parseClientKey]; isFoundClient {
+		parseElement.Value.(*bridgeAbuseClient).getStrikes = 0
 	}
+}
 
-	parseClientConnections := parseGuard.storeClientConnections[parseClientKey]
-	if parseClientConnections <= 1 {
-		delete(parseGuard.storeClientConnections, parseClientKey)
-		return
+// strikeBridgeClientLocked records one rejection and bans the client once PenaltyBox.Strikes is reached.
+// Each ban doubles the previous one up to MaxBan. It returns the new ban duration, or zero.
+// Callers hold the shard lock.
+func (parseGuard *bridgeAbuseGuard) strikeBridgeClientLocked(parseClient *bridgeAbuseClient, parseNow time.Time) time.Duration {
+	parsePenaltyBox := parseGuard.getPenaltyBox
+	if parsePenaltyBox.Strikes == 0 {
+		return 0
 	}
-	parseGuard.storeClientConnections[parseClientKey] = parseClientConnections - 1
+	parseClient.getStrikes++
+	if parseClient.getStrikes < parsePenaltyBox.Strikes {
+		return 0
+	}
+	parseClient.getStrikes = 0
+	parseClient.getBans++
+	parseBan := parsePenaltyBox.BaseBan
+	for parseIndex := 1; parseIndex < parseClient.getBans && parseBan < parsePenaltyBox.MaxBan; parseIndex++ {
+		parseBan *= 2
+	}
+	parseBan = min(parseBan, parsePenaltyBox.MaxBan)
+	parseClient.getBannedUntil = parseNow.Add(parseBan)
+	return parseBan
+}
+
+// sweepBridgeAbuseShard forgets clients idle for longer than the client state TTL. Callers hold the shard lock.
+func (parseGuard *bridgeAbuseGuard) sweepBridgeAbuseShard(parseShard *bridgeAbuseShard, parseNow time.Time) {
+	for parseOldest := parseShard.storeClientOrder.Back(); parseOldest != nil; parseOldest = parseShard.storeClientOrder.Back() {
+		parseClient := parseOldest.Value.(*bridgeAbuseClient)
+		if parseNow.Sub(parseClient.getLastSeenAt) < parseGuard.getClientStateTTL {
+			return
+		}
+		delete(parseShard.storeClients, parseClient.getClientKey)
+		parseShard.storeClientOrder.Remove(parseOldest)
+	}
+}
+
+// getBridgeTrackedClients returns the number of client keys with rate or penalty state.
+func (parseGuard *bridgeAbuseGuard) getBridgeTrackedClients() int {
+	parseTrackedClients := 0
+	for parseIndex := range parseGuard.getShards {
+		parseShard := &parseGuard.getShards[parseIndex]
+		parseShard.setShardLock.Lock()
+		parseTrackedClients += len(parseShard.storeClients)
+		parseShard.setShardLock.Unlock()
+	}
+	return parseTrackedClients
+}
+
+// clearBridgeConnection releases the connection slots held by a lease.
+func (parseGuard *bridgeAbuseGuard) clearBridgeConnection(parseLease *bridgeConnectionLease) {
+	parseLease.clearBridgeLease()
 }
 
 // buildBridgeClientKey derives a stable client key for abuse controls from request remote address.
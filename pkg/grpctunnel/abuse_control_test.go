@@ -3,12 +3,20 @@
 package grpctunnel
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/monstercameron/grpc-tunnel/examples/_shared/proto"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 )
 
 // TestGetBridgeConfigError_AbuseControlValidation verifies abuse-control limits reject negative values.
@@ -17,6 +25,14 @@ func TestGetBridgeConfigError_AbuseControlValidation(parseT *testing.T) {
 		{MaxActiveConnections: -1},
 		{MaxConnectionsPerClient: -1},
 		{MaxUpgradesPerClientPerMinute: -1},
+		{UpgradeRateLimit: RateLimit{PerSecond: -1}},
+		{RPCRateLimit: RateLimit{Burst: 5}},
+		{UpgradeRateLimit: RateLimit{PerSecond: 1}, MaxUpgradesPerClientPerMinute: 10},
+		{MaxTrackedClients: -1},
+		{ClientStateTTL: -time.Second},
+		{PenaltyBox: PenaltyBox{Strikes: 1, BaseBan: time.Minute, MaxBan: time.Second}},
+		{AllowedClientCIDRs: []string{"10.0.0.0/33"}},
+		{DeniedClientCIDRs: []string{"not-an-ip"}},
 	}
 
 	for _, parseConfig := range parseTests {
@@ -39,24 +55,25 @@ func TestBridgeAbuseGuard_ConnectionCaps(parseT *testing.T) {
 	parseClientTwoReq := httptest.NewRequest(http.MethodGet, "/grpc", nil)
 	parseClientTwoReq.RemoteAddr = "203.0.113.11:50001"
 
-	if parseErr := parseGuard.reserveBridgeConnection(parseClientOneReq, parseNow); parseErr != nil {
+	parseClientOneLease, parseErr := parseGuard.reserveBridgeConnection(parseClientOneReq, parseNow)
+	if parseErr != nil {
 		parseT.Fatalf("reserveBridgeConnection(client1) error: %v", parseErr)
 	}
-	if parseErr := parseGuard.reserveBridgeConnection(parseClientOneReq, parseNow); parseErr == nil {
+	if _, parseErr := parseGuard.reserveBridgeConnection(parseClientOneReq, parseNow); parseErr == nil {
 		parseT.Fatal("reserveBridgeConnection(client1 second) expected per-client cap error, got nil")
 	}
-	if parseErr := parseGuard.reserveBridgeConnection(parseClientTwoReq, parseNow); parseErr != nil {
+	if _, parseErr := parseGuard.reserveBridgeConnection(parseClientTwoReq, parseNow); parseErr != nil {
 		parseT.Fatalf("reserveBridgeConnection(client2) error: %v", parseErr)
 	}
 
 	parseClientThreeReq := httptest.NewRequest(http.MethodGet, "/grpc", nil)
 	parseClientThreeReq.RemoteAddr = "203.0.113.12:50002"
-	if parseErr := parseGuard.reserveBridgeConnection(parseClientThreeReq, parseNow); parseErr == nil {
+	if _, parseErr := parseGuard.reserveBridgeConnection(parseClientThreeReq, parseNow); parseErr == nil {
 		parseT.Fatal("reserveBridgeConnection(client3) expected global cap error, got nil")
 	}
 
-	parseGuard.clearBridgeConnection(parseClientOneReq)
-	if parseErr := parseGuard.reserveBridgeConnection(parseClientThreeReq, parseNow); parseErr != nil {
+	parseGuard.clearBridgeConnection(parseClientOneLease)
+	if _, parseErr := parseGuard.reserveBridgeConnection(parseClientThreeReq, parseNow); parseErr != nil {
 		parseT.Fatalf("reserveBridgeConnection(client3 after clear) error: %v", parseErr)
 	}
 }
@@ -86,4 +103,267 @@ func TestBuildBridgeHandler_RejectsUpgradeWhenRateLimitExceeded(parseT *testing.
 	if parseWTwo.Code != http.StatusTooManyRequests {
 		parseT.Fatalf("second ServeHTTP() status = %d, want %d", parseWTwo.Code, http.StatusTooManyRequests)
 	}
+	if parseRetryAfter := parseWTwo.Header().Get("Retry-After"); parseRetryAfter != "60" {
+		parseT.Fatalf("second ServeHTTP() Retry-After = %q, want %q", parseRetryAfter, "60")
+	}
+	if parseBody := strings.TrimSpace(parseWTwo.Body.String()); parseBody != parseBridgeAbuseReasonUpgradeRate {
+		parseT.Fatalf("second ServeHTTP() body = %q, want %q", parseBody, parseBridgeAbuseReasonUpgradeRate)
+	}
+}
+
+// TestBridgeTokenBucket_BurstAndRefill verifies bursts drain the bucket and refills never exceed the burst.
+func TestBridgeTokenBucket_BurstAndRefill(parseT *testing.T) {
+	parseLimit := RateLimit{PerSecond: 2, Burst: 3}
+	parseBucket := &bridgeTokenBucket{}
+	parseNow := time.Now()
+
+	for parseIndex := 0; parseIndex < 3; parseIndex++ {
+		if !parseBucket.allowBridgeTokenBucket(parseLimit, parseNow) {
+			parseT.Fatalf("allow(burst %d) = false, want true", parseIndex)
+		}
+	}
+	if parseBucket.allowBridgeTokenBucket(parseLimit, parseNow) {
+		parseT.Fatal("allow(after burst) = true, want false")
+	}
+	if !parseBucket.allowBridgeTokenBucket(parseLimit, parseNow.Add(500*time.Millisecond)) {
+		parseT.Fatal("allow(after refill) = false, want true")
+	}
+
+	parseIdle := parseNow.Add(time.Hour)
+	for parseIndex := 0; parseIndex < 3; parseIndex++ {
+		if !parseBucket.allowBridgeTokenBucket(parseLimit, parseIdle) {
+			parseT.Fatalf("allow(idle burst %d) = false, want true", parseIndex)
+		}
+	}
+	if parseBucket.allowBridgeTokenBucket(parseLimit, parseIdle) {
+		parseT.Fatal("allow(idle over burst) = true, want false")
+	}
+	if parseBurst := getBridgeRateLimitBurst(RateLimit{PerSecond: 0.5}); parseBurst != 1 {
+		parseT.Fatalf("getBridgeRateLimitBurst(0.5/s) = %d, want 1", parseBurst)
+	}
+}
+
+// TestBridgeAbuseGuard_ClientKeyFunc verifies custom client keys share upgrade buckets across addresses.
+func TestBridgeAbuseGuard_ClientKeyFunc(parseT *testing.T) {
+	parseGuard := buildBridgeAbuseGuard(BridgeConfig{
+		UpgradeRateLimit: RateLimit{PerSecond: 1, Burst: 1},
+		ClientKeyFunc: func(parseR *http.Request) string {
+			return parseR.Header.Get("X-Api-Key")
+		},
+	})
+	parseNow := time.Now()
+
+	parseFirstReq := httptest.NewRequest(http.MethodGet, "/grpc", nil)
+	parseFirstReq.RemoteAddr = "203.0.113.10:50000"
+	parseFirstReq.Header.Set("X-Api-Key", "tenant-a")
+	parseSecondReq := httptest.NewRequest(http.MethodGet, "/grpc", nil)
+	parseSecondReq.RemoteAddr = "198.51.100.20:50000"
+	parseSecondReq.Header.Set("X-Api-Key", "tenant-a")
+	parseUnkeyedReq := httptest.NewRequest(http.MethodGet, "/grpc", nil)
+	parseUnkeyedReq.RemoteAddr = "203.0.113.10:50001"
+
+	if _, parseErr := parseGuard.reserveBridgeConnection(parseFirstReq, parseNow); parseErr != nil {
+		parseT.Fatalf("reserveBridgeConnection(first) error: %v", parseErr)
+	}
+	if _, parseErr := parseGuard.reserveBridgeConnection(parseSecondReq, parseNow); parseErr == nil {
+		parseT.Fatal("reserveBridgeConnection(same key) expected rate limit error, got nil")
+	}
+	if _, parseErr := parseGuard.reserveBridgeConnection(parseUnkeyedReq, parseNow); parseErr != nil {
+		parseT.Fatalf("reserveBridgeConnection(address fallback) error: %v", parseErr)
+	}
+	if _, parseErr := parseGuard.reserveBridgeConnection(parseSecondReq, parseNow.Add(time.Second)); parseErr != nil {
+		parseT.Fatalf("reserveBridgeConnection(after refill) error: %v", parseErr)
+	}
+}
+
+// TestBuildBridgeHandler_RPCRateLimit verifies RPC starts over the per-tunnel limit receive ResourceExhausted.
+func TestBuildBridgeHandler_RPCRateLimit(parseT *testing.T) {
+	parseGrpcServer := grpc.NewServer()
+	proto.RegisterTodoServiceServer(parseGrpcServer, &mockService{})
+	defer parseGrpcServer.Stop()
+
+	parseHandler, parseErr := BuildBridgeHandler(parseGrpcServer, BridgeConfig{
+		RPCRateLimit: RateLimit{PerSecond: 0.001, Burst: 2},
+	})
+	if parseErr != nil {
+		parseT.Fatalf("BuildBridgeHandler() error: %v", parseErr)
+	}
+	parseServer := httptest.NewServer(parseHandler)
+	defer parseServer.Close()
+
+	parseCtx, clearCtx := context.WithTimeout(context.Background(), 5*time.Second)
+	defer clearCtx()
+	parseConn, parseErr := BuildTunnelConn(parseCtx, TunnelConfig{
+		Target:      "ws" + strings.TrimPrefix(parseServer.URL, "http"),
+		GRPCOptions: []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())},
+	})
+	if parseErr != nil {
+		parseT.Fatalf("BuildTunnelConn() error: %v", parseErr)
+	}
+	defer parseConn.Close()
+
+	parseClient := proto.NewTodoServiceClient(parseConn)
+	for parseIndex := 0; parseIndex < 2; parseIndex++ {
+		if _, parseErr = parseClient.CreateTodo(parseCtx, &proto.CreateTodoRequest{Text: "limited"}); parseErr != nil {
+			parseT.Fatalf("CreateTodo(%d) error: %v", parseIndex, parseErr)
+		}
+	}
+	_, parseErr = parseClient.CreateTodo(parseCtx, &proto.CreateTodoRequest{Text: "limited"})
+	if status.Code(parseErr) != codes.ResourceExhausted {
+		parseT.Fatalf("CreateTodo(over limit) error = %v, want %v", parseErr, codes.ResourceExhausted)
+	}
+	if _, _, parseErr = NewListener(BridgeConfig{RPCRateLimit: RateLimit{PerSecond: 1}}); parseErr == nil {
+		parseT.Fatal("NewListener() should reject RPCRateLimit")
+	}
+}
+
+// getBridgeTestRejection returns a function extracting the reason and retry delay of an abuse-control rejection.
+func getBridgeTestRejection(parseT *testing.T) func(*bridgeConnectionLease, error) (string, time.Duration) {
+	return func(_ *bridgeConnectionLease, parseErr error) (string, time.Duration) {
+		parseT.Helper()
+		var parseRejection *bridgeAbuseRejection
+		if !errors.As(parseErr, &parseRejection) {
+			parseT.Fatalf("error = %v, want *bridgeAbuseRejection", parseErr)
+		}
+		return parseRejection.getReason, parseRejection.getRetryAfter
+	}
+}
+
+// TestBridgeAbuseGuard_BoundedClientState verifies LRU caps and idle TTLs bound per-client state.
+func TestBridgeAbuseGuard_BoundedClientState(parseT *testing.T) {
+	parseGuard := buildBridgeAbuseGuard(BridgeConfig{
+		UpgradeRateLimit:  RateLimit{PerSecond: 1},
+		MaxTrackedClients: parseBridgeAbuseShardCount,
+		ClientStateTTL:    time.Minute,
+	})
+	parseNow := time.Now()
+	for parseIndex := 0; parseIndex < 1000; parseIndex++ {
+		parseReq := httptest.NewRequest(http.MethodGet, "/grpc", nil)
+		parseReq.RemoteAddr = fmt.Sprintf("10.0.%d.%d:443", parseIndex/256, parseIndex%256)
+		parseLease, parseErr := parseGuard.reserveBridgeConnection(parseReq, parseNow)
+		if parseErr != nil {
+			parseT.Fatalf("reserveBridgeConnection(%s) error: %v", parseReq.RemoteAddr, parseErr)
+		}
+		parseGuard.clearBridgeConnection(parseLease)
+	}
+	if parseTracked := parseGuard.getBridgeTrackedClients(); parseTracked > parseBridgeAbuseShardCount {
+		parseT.Fatalf("tracked clients = %d, want <= %d", parseTracked, parseBridgeAbuseShardCount)
+	}
+
+	for parseIndex := range parseGuard.getShards {
+		parseGuard.sweepBridgeAbuseShard(&parseGuard.getShards[parseIndex], parseNow.Add(time.Minute))
+	}
+	if parseTracked := parseGuard.getBridgeTrackedClients(); parseTracked != 0 {
+		parseT.Fatalf("tracked clients after TTL = %d, want 0", parseTracked)
+	}
+}
+
+// TestBridgeAbuseGuard_PenaltyBox verifies repeat offenders get exponentially longer bans capped at MaxBan.
+func TestBridgeAbuseGuard_PenaltyBox(parseT *testing.T) {
+	parseGuard := buildBridgeAbuseGuard(BridgeConfig{
+		UpgradeRateLimit: RateLimit{PerSecond: 1, Burst: 1},
+		PenaltyBox:       PenaltyBox{Strikes: 2, BaseBan: 10 * time.Second, MaxBan: 25 * time.Second},
+	})
+	parseReq := httptest.NewRequest(http.MethodGet, "/grpc", nil)
+	parseReq.RemoteAddr = "203.0.113.7:4000"
+	parseNow := time.Now()
+
+	for _, parseWantBan := range []time.Duration{10 * time.Second, 20 * time.Second, 25 * time.Second} {
+		parseLease, parseErr := parseGuard.reserveBridgeConnection(parseReq, parseNow)
+		if parseErr != nil {
+			parseT.Fatalf("reserveBridgeConnection(allowed) error: %v", parseErr)
+		}
+		parseGuard.clearBridgeConnection(parseLease)
+
+		parseReason, parseRetryAfter := getBridgeTestRejection(parseT)(parseGuard.reserveBridgeConnection(parseReq, parseNow))
+		if parseReason != parseBridgeAbuseReasonUpgradeRate || parseRetryAfter != time.Second {
+			parseT.Fatalf("first strike = (%q, %v), want (%q, 1s)", parseReason, parseRetryAfter, parseBridgeAbuseReasonUpgradeRate)
+		}
+		parseReason, parseRetryAfter = getBridgeTestRejection(parseT)(parseGuard.reserveBridgeConnection(parseReq, parseNow))
+		if parseReason != parseBridgeAbuseReasonUpgradeRate || parseRetryAfter != parseWantBan {
+			parseT.Fatalf("banning strike = (%q, %v), want (%q, %v)", parseReason, parseRetryAfter, parseBridgeAbuseReasonUpgradeRate, parseWantBan)
+		}
+		parseReason, parseRetryAfter = getBridgeTestRejection(parseT)(parseGuard.reserveBridgeConnection(parseReq, parseNow.Add(parseWantBan/2)))
+		if parseReason != parseBridgeAbuseReasonBanned || parseRetryAfter != parseWantBan-parseWantBan/2 {
+			parseT.Fatalf("banned = (%q, %v), want (%q, %v)", parseReason, parseRetryAfter, parseBridgeAbuseReasonBanned, parseWantBan-parseWantBan/2)
+		}
+		parseNow = parseNow.Add(parseWantBan)
+	}
+}
+
+// TestBridgeAbuseGuard_ClientCIDRsAndIPv6Grouping verifies deny and allow lists and /64 client keys.
+func TestBridgeAbuseGuard_ClientCIDRsAndIPv6Grouping(parseT *testing.T) {
+	parseGuard := buildBridgeAbuseGuard(BridgeConfig{
+		UpgradeRateLimit:       RateLimit{PerSecond: 1, Burst: 1},
+		AllowedClientCIDRs:     []string{"10.0.0.0/8"},
+		DeniedClientCIDRs:      []string{"10.9.0.0/16", "192.0.2.1"},
+		ShouldGroupIPv6Clients: true,
+	})
+	parseNow := time.Now()
+	parseBuildRequest := func(parseRemoteAddr string) *http.Request {
+		parseReq := httptest.NewRequest(http.MethodGet, "/grpc", nil)
+		parseReq.RemoteAddr = parseRemoteAddr
+		return parseReq
+	}
+
+	for _, parseRemoteAddr := range []string{"192.0.2.1:1", "10.9.1.1:1"} {
+		_, parseErr := parseGuard.reserveBridgeConnection(parseBuildRequest(parseRemoteAddr), parseNow)
+		var parseRejection *bridgeAbuseRejection
+		if !errors.As(parseErr, &parseRejection) || parseRejection.getReason != parseBridgeAbuseReasonDenied || parseRejection.getStatusCode != http.StatusForbidden {
+			parseT.Fatalf("reserveBridgeConnection(%s) error = %v, want %s with 403", parseRemoteAddr, parseErr, parseBridgeAbuseReasonDenied)
+		}
+	}
+	for parseIndex := 0; parseIndex < 3; parseIndex++ {
+		if _, parseErr := parseGuard.reserveBridgeConnection(parseBuildRequest("10.1.2.3:1"), parseNow); parseErr != nil {
+			parseT.Fatalf("reserveBridgeConnection(allowed %d) error: %v", parseIndex, parseErr)
+		}
+	}
+
+	if _, parseErr := parseGuard.reserveBridgeConnection(parseBuildRequest("[2001:db8:1:2::1]:1"), parseNow); parseErr != nil {
+		parseT.Fatalf("reserveBridgeConnection(ipv6) error: %v", parseErr)
+	}
+	if _, parseErr := parseGuard.reserveBridgeConnection(parseBuildRequest("[2001:db8:1:2:ffff::9]:1"), parseNow); parseErr == nil {
+		parseT.Fatal("reserveBridgeConnection(same /64) expected rate limit error, got nil")
+	}
+	if _, parseErr := parseGuard.reserveBridgeConnection(parseBuildRequest("[2001:db8:1:3::1]:1"), parseNow); parseErr != nil {
+		parseT.Fatalf("reserveBridgeConnection(other /64) error: %v", parseErr)
+	}
+}
+
+// TestBridgeAbuseGuard_EvictionKeepsBannedClients verifies a full shard never evicts a banned client to make room.
+func TestBridgeAbuseGuard_EvictionKeepsBannedClients(parseT *testing.T) {
+	parseGuard := buildBridgeAbuseGuard(BridgeConfig{
+		UpgradeRateLimit:  RateLimit{PerSecond: 1, Burst: 1},
+		PenaltyBox:        PenaltyBox{Strikes: 1, BaseBan: 10 * time.Second},
+		MaxTrackedClients: parseBridgeAbuseShardCount,
+	})
+	parseBannedKey := "203.0.113.7"
+	parseNewKey := ""
+	for parseIndex := 0; parseNewKey == ""; parseIndex++ {
+		parseKey := fmt.Sprintf("198.51.100.%d", parseIndex)
+		if parseGuard.getBridgeAbuseShard(parseKey) == parseGuard.getBridgeAbuseShard(parseBannedKey) {
+			parseNewKey = parseKey
+		}
+	}
+	parseNow := time.Now()
+	if parseErr := parseGuard.checkBridgeClientRate(parseBannedKey, parseGuard.setConfig.UpgradeRateLimit, parseNow); parseErr != nil {
+		parseT.Fatalf("checkBridgeClientRate(first) error: %v", parseErr)
+	}
+	var parseRejection *bridgeAbuseRejection
+	if parseErr := parseGuard.checkBridgeClientRate(parseBannedKey, parseGuard.setConfig.UpgradeRateLimit, parseNow); !errors.As(parseErr, &parseRejection) || parseRejection.getRetryAfter != 10*time.Second {
+		parseT.Fatalf("checkBridgeClientRate(banning) = %v, want 10s ban", parseErr)
+	}
+
+	parseErr := parseGuard.checkBridgeClientRate(parseNewKey, parseGuard.setConfig.UpgradeRateLimit, parseNow.Add(time.Second))
+	if !errors.As(parseErr, &parseRejection) || parseRejection.getReason != parseBridgeAbuseReasonClientTableFull || parseRejection.getRetryAfter != 9*time.Second {
+		parseT.Fatalf("checkBridgeClientRate(new key, full shard) = %v, want %q with 9s retry", parseErr, parseBridgeAbuseReasonClientTableFull)
+	}
+	parseErr = parseGuard.checkBridgeClientRate(parseBannedKey, parseGuard.setConfig.UpgradeRateLimit, parseNow.Add(2*time.Second))
+	if !errors.As(parseErr, &parseRejection) || parseRejection.getReason != parseBridgeAbuseReasonBanned {
+		parseT.Fatalf("checkBridgeClientRate(banned key) = %v, want %q", parseErr, parseBridgeAbuseReasonBanned)
+	}
+
+	if parseErr := parseGuard.checkBridgeClientRate(parseNewKey, parseGuard.setConfig.UpgradeRateLimit, parseNow.Add(10*time.Second)); parseErr != nil {
+		parseT.Fatalf("checkBridgeClientRate(new key, ban expired) error: %v", parseErr)
+	}
 }
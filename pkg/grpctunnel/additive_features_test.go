@@ -879,6 +879,14 @@ func TestGetToolingListenAddressError_WildcardAndLoopback(parseT *testing.T) {
 			},
 			shouldHaveErr: true,
 		},
+		{
+			parseName: "wildcard with tunnel admin",
+			parseAddr: "[::]:9090",
+			parseConfig: ToolingConfig{
+				ShouldEnableTunnelAdmin: true,
+			},
+			shouldHaveErr: true,
+		},
 		{
 			parseName: "loopback with reflection",
 			parseAddr: "127.0.0.1:9090",
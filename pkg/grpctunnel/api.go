@@ -1,6 +1,7 @@
 package grpctunnel
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"math"
@@ -9,6 +10,11 @@ import (
 	"strings"
 	"time"
 
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/healthmonitor"
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/membudget"
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/tunneladmin"
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/tunnelregistry"
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/tunnelstate"
 	"google.golang.org/grpc"
 	grpcbackoff "google.golang.org/grpc/backoff"
 	"google.golang.org/grpc/credentials/insecure"
@@ -36,6 +42,11 @@ type TunnelConfig struct {
 	ShouldEnableCompression bool
 	// ReconnectConfig configures optional gRPC reconnect and backoff behavior.
 	ReconnectConfig *ReconnectConfig
+	// HTTP2Profile selects preset HTTP/2 settings for the client side of the tunnel. Use the same
+	// profile as the bridge, since each side's windows govern the data it receives.
+	HTTP2Profile HTTP2Profile
+	// HTTP2 overrides individual HTTP2Profile settings.
+	HTTP2 HTTP2Settings
 	// GRPCOptions passes through grpc.DialOption values.
 	GRPCOptions []grpc.DialOption
 }
@@ -59,21 +70,228 @@ type BridgeConfig struct {
 	PingInterval time.Duration
 	// IdleTimeout configures how long the bridge waits for client activity or pong frames.
 	IdleTimeout time.Duration
+	// UpgradeTimeout caps how long an upgrade request may take from reaching the handler through the
+	// handshake response, including Authenticate. Server and Serve also use it as the header read
+	// timeout. Zero disables the limit.
+	UpgradeTimeout time.Duration
+	// PrefaceTimeout closes tunnels whose client has not sent the HTTP/2 connection preface this long
+	// after the upgrade. Zero disables the limit.
+	PrefaceTimeout time.Duration
+	// MinReadThroughput closes tunnels that deliver a websocket message slower than a minimum rate.
+	// Idle tunnels between messages are not affected. The zero value disables the limit.
+	// BandwidthLimits.Global and PerClient must be at least BytesPerSecond, because their pacing counts
+	// against it; tunnels sharing those buckets can still be paced below it and closed.
+	MinReadThroughput Throughput
+	// WriteTimeout bounds each websocket message write so clients that stop reading responses are
+	// disconnected instead of pinning server goroutines and buffers. Zero disables the limit.
+	WriteTimeout time.Duration
+	// MaxConnectionAge sends GOAWAY to tunnels this old, with +/-10% random jitter so tunnels opened
+	// together do not reconnect together. Clients then redial, which lets load balancers rebalance
+	// long-lived tunnels. Not supported by NewListener; set keepalive.ServerParameters on the gRPC
+	// server for graceful rotation there. Zero disables the limit.
+	MaxConnectionAge time.Duration
+	// MaxConnectionAgeGrace is how long in-flight RPCs may continue after the MaxConnectionAge GOAWAY
+	// before the tunnel is closed. Zero waits for them indefinitely.
+	MaxConnectionAgeGrace time.Duration
 	// ShouldEnableCompression enables websocket per-message compression where supported.
 	ShouldEnableCompression bool
+	// Subprotocols lists websocket subprotocols the bridge selects from, in preference order,
+	// when the client offers any. Browsers fail handshakes that offer subprotocols none of which is selected.
+	Subprotocols []string
 	// MaxActiveConnections limits total concurrent websocket tunnel connections.
 	// Zero disables this guard.
 	MaxActiveConnections int
 	// MaxConnectionsPerClient limits concurrent websocket tunnel connections per client key.
-	// Client key is derived from request remote address host. Zero disables this guard.
+	// The client key comes from ClientKeyFunc or the client address host. Zero disables this guard.
 	MaxConnectionsPerClient int
-	// MaxUpgradesPerClientPerMinute limits websocket upgrade attempts per client key over a 1-minute window.
+	// MaxUpgradesPerClientPerMinute limits websocket upgrade attempts per client key to N per minute with burst N.
 	// Zero disables this guard.
 	MaxUpgradesPerClientPerMinute int
+	// TrustedProxies lists proxy CIDR prefixes or IPs whose Forwarded (RFC 7239) and X-Forwarded-For
+	// headers are trusted. The resolved client address replaces RemoteAddr for abuse controls, logs,
+	// traces, Authenticate, and the tunnel peer address. Empty trusts no forwarding headers.
+	TrustedProxies []string
+	// ShouldAcceptProxyProtocol makes Server.Serve, Serve, and ListenAndServe require a PROXY protocol
+	// v1 or v2 header on connections from TrustedProxies. Use NewProxyProtocolListener for other servers.
+	ShouldAcceptProxyProtocol bool
+	// UpgradeRateLimit is a per-client token bucket for websocket upgrade attempts. It replaces
+	// MaxUpgradesPerClientPerMinute, which maps to a bucket refilling N per minute with burst N.
+	UpgradeRateLimit RateLimit
+	// RPCRateLimit is a per-tunnel token bucket for RPC starts. Calls over the limit receive
+	// ResourceExhausted without reaching the gRPC server. Not supported by NewListener.
+	RPCRateLimit RateLimit
+	// BandwidthLimits caps tunnel throughput in bytes per second. Time spent waiting for bandwidth
+	// is reported as bridge_bandwidth_throttle_seconds_total.
+	BandwidthLimits BandwidthLimits
+	// MemoryBudget is a process-wide accountant, usually shared with other handlers. Under pressure
+	// it shrinks HTTP/2 window updates to clients, and once exhausted upgrades receive 503 and new
+	// streams receive ResourceExhausted. NewListener applies the first two but cannot refuse streams.
+	MemoryBudget *membudget.Budget
+	// HTTP2Profile selects preset HTTP/2 settings for each tunnel. NewListener tunnels use the gRPC
+	// server's own transport settings instead.
+	HTTP2Profile HTTP2Profile
+	// HTTP2 overrides individual HTTP2Profile settings.
+	HTTP2 HTTP2Settings
+	// ClientKeyFunc derives the abuse-control client key, for example from an API key header.
+	// It runs before Authenticate. Nil or an empty result keys on the resolved client IP.
+	ClientKeyFunc func(r *http.Request) string
+	// ShouldGroupIPv6Clients keys IPv6 clients by their /64 prefix, since one host often controls a whole /64.
+	ShouldGroupIPv6Clients bool
+	// AllowedClientCIDRs lists client CIDR prefixes or IPs exempt from per-client limits and the penalty box.
+	// They still count toward MaxActiveConnections.
+	AllowedClientCIDRs []string
+	// DeniedClientCIDRs lists client CIDR prefixes or IPs whose upgrades are rejected with 403. Deny wins over allow.
+	DeniedClientCIDRs []string
+	// PenaltyBox temporarily bans clients that keep exceeding per-client limits.
+	PenaltyBox PenaltyBox
+	// MaxTrackedClients caps the client keys holding rate or penalty state; the least recently seen
+	// client is evicted first. Zero uses 65536.
+	MaxTrackedClients int
+	// ClientStateTTL forgets rate and penalty state of clients idle this long, but never before a
+	// ban ends. Zero uses 10 minutes.
+	ClientStateTTL time.Duration
+	// LimitStore holds the MaxActiveConnections and MaxConnectionsPerClient slots. Share one store,
+	// such as limitstore.RedisStore, between replicas to enforce the limits across them. Nil keeps
+	// slots in process. Upgrade rate limits and the penalty box stay per replica.
+	LimitStore LimitStore
+	// LimitStoreLeaseTTL is how long a LimitStore slot outlives its last renewal, bounding how long a
+	// crashed replica's slots stay held. Live tunnels renew every third of it. Zero uses 30 seconds.
+	LimitStoreLeaseTTL time.Duration
+	// ExposurePolicy restricts which gRPC methods tunnel clients may call. Denied calls receive
+	// PermissionDenied without reaching the gRPC server. The zero value exposes every method.
+	ExposurePolicy ExposurePolicy
+	// Authenticate verifies the upgrade request before the websocket handshake.
+	// A returned error rejects the upgrade with 401, or 403 when it wraps ErrPermissionDenied.
+	// The returned context becomes the base context for every RPC served on the tunnel,
+	// so interceptors can read the verified identity from it. When that context is done, for
+	// example at a token-expiry deadline, the tunnel receives HTTP/2 GOAWAY and is closed once
+	// AuthExpiryGrace has passed; RPCs keep its values but not its cancellation. NewListener
+	// tunnels get no GOAWAY because grpc.Server owns their transport, and are only closed after
+	// the grace.
+	Authenticate func(r *http.Request) (context.Context, error)
+	// AuthExpiryGrace is how long in-flight RPCs may continue after the Authenticate context is
+	// done before the tunnel is closed. Zero uses a default of 30s.
+	AuthExpiryGrace time.Duration
+	// Authorize decides per HTTP/2 stream whether a tunneled RPC may proceed. ctx carries the
+	// Authenticate context values, upgrade is the websocket upgrade request, and fullMethod is
+	// "/package.Service/Method". gRPC status errors are returned to the client as-is; other errors
+	// deny with PermissionDenied, or Unauthenticated when they wrap ErrUnauthenticated.
+	Authorize func(ctx context.Context, upgrade *http.Request, fullMethod string) error
 	// OnConnect is called when a websocket client connects.
+	//
+	// Deprecated: Use TunnelObserver, whose events carry a tunnel ID.
 	OnConnect func(r *http.Request)
 	// OnDisconnect is called when a websocket client disconnects.
+	//
+	// Deprecated: Use TunnelObserver, whose events also report duration, byte counts, and close cause.
 	OnDisconnect func(r *http.Request)
+	// TunnelObserver receives typed events for rejected upgrades, tunnels, and streams.
+	TunnelObserver TunnelObserver
+	// TunnelRegistry lists open tunnels with live stats and force-closes them. One registry may be
+	// shared by several handlers.
+	TunnelRegistry *tunnelregistry.Registry
+	// TunnelAdmin registers tunnels in its registry, receives their lifecycle events, and supplies
+	// MaxActiveConnections, MaxConnectionsPerClient, UpgradeRateLimit, and RPCRateLimit so they can
+	// change at runtime. The first handler built with an admin seeds its limits; a later handler whose
+	// configured limits differ logs tunnel_admin_limits_ignored. TunnelRegistry must be nil or the
+	// admin's registry.
+	TunnelAdmin *tunneladmin.Admin
+	// TunnelStates records tunnel state transitions for the tooling handler's state snapshot. One
+	// recorder may be shared by several handlers.
+	TunnelStates *tunnelstate.Recorder
+	// HealthMonitor turns NOT_SERVING while the handler drains, and the handler answers /healthz and
+	// /readyz requests that are not websocket upgrades from it. One monitor may be shared by several
+	// handlers.
+	HealthMonitor *healthmonitor.Monitor
+}
+
+// LimitStore holds connection slots that may be shared by several bridge replicas.
+// Implementations must be safe for concurrent use.
+type LimitStore interface {
+	// Reserve atomically takes a slot in key, held by lease until ttl elapses, when fewer than limit
+	// unexpired leases are held. It reports whether the slot was taken.
+	Reserve(ctx context.Context, key string, lease string, limit int, ttl time.Duration) (bool, error)
+	// Renew extends a lease by ttl from now.
+	Renew(ctx context.Context, key string, lease string, ttl time.Duration) error
+	// Release frees a lease. Releasing an unknown lease is not an error.
+	Release(ctx context.Context, key string, lease string) error
+}
+
+// PenaltyBox configures temporary bans for clients that repeatedly exceed per-client limits.
+type PenaltyBox struct {
+	// Strikes is the number of consecutive rejected upgrades that bans a client. Zero disables bans.
+	Strikes int
+	// BaseBan is the first ban duration; each further ban doubles it. Zero uses 30 seconds.
+	BaseBan time.Duration
+	// MaxBan caps ban durations. Zero uses 1 hour.
+	MaxBan time.Duration
+}
+
+// Throughput configures a minimum transfer rate.
+type Throughput struct {
+	// BytesPerSecond is the minimum rate. Zero disables the limit.
+	BytesPerSecond int64
+	// Window is how long a transfer may stay below the rate before the tunnel closes. Zero uses 10 seconds.
+	Window time.Duration
+}
+
+// HTTP2Profile names a preset of HTTP/2 settings tuned for one kind of traffic.
+type HTTP2Profile string
+
+const (
+	// HTTP2ProfileLowLatency sizes flow-control windows so messages up to a few MiB arrive without
+	// waiting a round trip for WINDOW_UPDATE, and keeps frames small so short calls interleave with
+	// bulk transfers on the same tunnel.
+	HTTP2ProfileLowLatency HTTP2Profile = "low_latency"
+	// HTTP2ProfileHighThroughput uses large windows and frames so long transfers over high-RTT links
+	// keep the pipe full, at the cost of more memory buffered per tunnel.
+	HTTP2ProfileHighThroughput HTTP2Profile = "high_throughput"
+	// HTTP2ProfileConstrainedMemory uses the smallest stream window HTTP/2 allows and few concurrent streams,
+	// bounding what one tunnel can make the bridge buffer, and closes idle tunnels after two minutes.
+	HTTP2ProfileConstrainedMemory HTTP2Profile = "constrained_memory"
+)
+
+// HTTP2Settings configures the HTTP/2 connection carried by a tunnel. Zero fields keep the profile
+// value, or the golang.org/x/net/http2 and gRPC defaults when no profile is set.
+type HTTP2Settings struct {
+	// MaxConcurrentStreams caps concurrent RPCs per tunnel. Applied by bridges only.
+	MaxConcurrentStreams uint32
+	// MaxReadFrameSize is the largest frame the peer may send, from 16 KiB to 16 MiB - 1.
+	// Applied by bridges only.
+	MaxReadFrameSize uint32
+	// InitialStreamWindowSize is the flow-control window of each stream, at least 65535 bytes.
+	// For bridges it bounds request bytes buffered per RPC; for clients, response bytes. Setting it
+	// on a client disables gRPC's dynamic window sizing.
+	InitialStreamWindowSize int32
+	// InitialConnWindowSize is the flow-control window shared by every stream of a tunnel, at least
+	// 65535 bytes.
+	InitialConnWindowSize int32
+	// MaxHeaderListSize caps the size of received header lists.
+	MaxHeaderListSize uint32
+	// IdleTimeout closes tunnels with no active streams for this long. Bridges send GOAWAY; clients
+	// move the channel to idle and reconnect on the next RPC.
+	IdleTimeout time.Duration
+}
+
+// BandwidthLimits caps tunnel throughput with byte token buckets. Every limit applies to reads
+// and writes separately, so a PerSecond of 1<<20 allows 1 MiB/s in each direction. Writes larger
+// than Burst still pass, delayed until the rate allows them.
+type BandwidthLimits struct {
+	// Global is shared by every tunnel of the handler.
+	Global RateLimit
+	// PerClient is shared by the tunnels of one client key, as derived for abuse controls.
+	PerClient RateLimit
+	// Methods caps the request and response bodies of each call to a full method name such as
+	// "/todo.v1.TodoService/StreamTodos". Not supported by NewListener.
+	Methods map[string]RateLimit
+}
+
+// RateLimit configures a token bucket that refills PerSecond tokens each second up to Burst.
+type RateLimit struct {
+	// PerSecond is the sustained event rate. Zero disables the limit.
+	PerSecond float64
+	// Burst is the bucket capacity. Zero uses PerSecond rounded up, and at least 1.
+	Burst int
 }
 
 // ReconnectConfig configures optional gRPC reconnect backoff behavior.
@@ -96,10 +314,45 @@ type ToolingConfig struct {
 	ShouldEnableReflection bool
 	// ShouldEnableHealthService registers the standard gRPC health service when absent.
 	ShouldEnableHealthService bool
+	// HealthMonitor supplies the health service registered by ShouldEnableHealthService, so gRPC
+	// health clients see the drain and backend status the bridges report. Nil registers a health
+	// service that is always SERVING.
+	HealthMonitor *healthmonitor.Monitor
 	// ShouldEnablePprof exposes net/http/pprof handlers under DebugPathPrefix.
 	ShouldEnablePprof bool
 	// DebugPathPrefix configures the pprof route prefix. Empty uses /debug/pprof/.
 	DebugPathPrefix string
+	// ShouldEnableTunnelAdmin serves the grpctunnel.admin.v1.TunnelAdmin service for TunnelAdmin.
+	// The service is reachable through the tooling handler only, never through tunnels.
+	ShouldEnableTunnelAdmin bool
+	// TunnelAdmin is the admin the TunnelAdmin service operates on. Required with ShouldEnableTunnelAdmin.
+	TunnelAdmin *tunneladmin.Admin
+	// ShouldEnableChannelz serves the gRPC channelz service. Like TunnelAdmin, it is reachable
+	// through the tooling handler only, never through tunnels.
+	ShouldEnableChannelz bool
+	// ShouldEnableTunnelStates serves the TunnelStates snapshot as JSON at DebugPathPrefix + "tunnels".
+	ShouldEnableTunnelStates bool
+	// TunnelStates is the recorder the snapshot reads. Required with ShouldEnableTunnelStates.
+	TunnelStates *tunnelstate.Recorder
+	// Auth protects every tooling route: pprof, and the gRPC services served over h2c. An empty
+	// Auth allows every request. With Auth set, ListenAndServeTooling also accepts wildcard binds.
+	Auth ToolingAuth
+	// TLSConfig makes ListenAndServeTooling serve TLS, offering h2 for gRPC clients. Set ClientAuth
+	// to tls.VerifyClientCertIfGiven or tls.RequireAndVerifyClientCert, with ClientCAs, for
+	// Auth.ClientCertSANs.
+	TLSConfig *tls.Config
+}
+
+// ToolingAuth is the tooling handler's auth policy. A request is allowed when it passes any
+// configured method. Rejected HTTP requests get 401, and rejected gRPC calls get Unauthenticated.
+type ToolingAuth struct {
+	// BearerTokens are accepted from "Authorization: Bearer <token>" and compared in constant time.
+	BearerTokens []string
+	// BasicUsers maps HTTP basic auth user names to bcrypt password hashes.
+	BasicUsers map[string]string
+	// ClientCertSANs accepts verified TLS client certificates carrying one of these DNS, IP, email,
+	// or URI subject alternative names.
+	ClientCertSANs []string
 }
 
 // ApplyTunnelInsecureCredentials appends insecure transport credentials to the
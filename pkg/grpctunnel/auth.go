@@ -0,0 +1,50 @@
+//go:build !js && !wasm
+
+package grpctunnel
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	"google.golang.org/grpc/peer"
+)
+
+// ErrUnauthenticated rejects a websocket upgrade with 401 Unauthorized when returned from Authenticate.
+// Any Authenticate error that does not wrap ErrPermissionDenied is treated the same way.
+var ErrUnauthenticated = errors.New("grpctunnel: unauthenticated")
+
+// ErrPermissionDenied rejects a websocket upgrade with 403 Forbidden when wrapped by an Authenticate error.
+var ErrPermissionDenied = errors.New("grpctunnel: permission denied")
+
+// getBridgeAuthStatusCode maps an Authenticate error to the HTTP status returned before upgrade.
+func getBridgeAuthStatusCode(parseErr error) int {
+	if errors.Is(parseErr, ErrPermissionDenied) {
+		return http.StatusForbidden
+	}
+	return http.StatusUnauthorized
+}
+
+// tunnelPeerAddr carries the Authenticate context of a listener tunnel on the peer address seen by gRPC.
+type tunnelPeerAddr struct {
+	net.Addr
+	getContext context.Context
+}
+
+// AuthContextFromPeer returns the Authenticate context of the tunnel that carries an RPC served through NewListener.
+//
+// Handlers built with BuildBridgeHandler, Wrap, or NewServer already use the Authenticate context as the
+// base context for every RPC. grpc.Server.Serve creates its own per-connection contexts, so interceptors
+// for NewListener tunnels read the identity with this helper instead.
+func AuthContextFromPeer(parseCtx context.Context) (context.Context, bool) {
+	parsePeer, isFoundPeer := peer.FromContext(parseCtx)
+	if !isFoundPeer {
+		return nil, false
+	}
+	parseAddr, isTunnelAddr := parsePeer.Addr.(tunnelPeerAddr)
+	if !isTunnelAddr || parseAddr.getContext == nil {
+		return nil, false
+	}
+	return parseAddr.getContext, true
+}
@@ -0,0 +1,12 @@
+// Package jwt verifies JWT/OIDC bearer tokens on websocket tunnel upgrades.
+//
+// Authenticator.Authenticate plugs into grpctunnel.BridgeConfig.Authenticate,
+// grpctunnel.WithAuthenticator, and bridge.Config.Authenticate. It reads the token from the
+// Authorization header, an optional cookie, or an optional Sec-WebSocket-Protocol entry,
+// verifies RS256, ES256, or EdDSA signatures against a cached JWKS loaded from a file or URL,
+// and checks issuer, audience, and time claims with a configurable clock skew.
+//
+// Verified Claims are available to RPC handlers through ClaimsFromContext. When the token
+// expires, the bridge sends GOAWAY to the tunnel so clients reconnect with a fresh token, and
+// closes it once the bridge's AuthExpiryGrace has passed.
+package jwt
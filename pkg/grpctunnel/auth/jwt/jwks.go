@@ -0,0 +1,314 @@
+//go:build !js && !wasm
+
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const parseDefaultJWKSRefreshInterval = 5 * time.Minute
+const parseJWKSMinRefreshInterval = 10 * time.Second
+const parseJWKSFetchTimeout = 10 * time.Second
+const parseJWKSMaxBytes int64 = 1 << 20
+const parseMinRSAKeyBits = 2048
+
+// storeJWKSDocument is the JSON Web Key Set wire format.
+type storeJWKSDocument struct {
+	Keys []storeJWKSKey `json:"keys"`
+}
+
+// storeJWKSKey is one JSON Web Key in wire format.
+type storeJWKSKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// jwksKey stores one parsed verification key and the algorithm it is bound to.
+type jwksKey struct {
+	getKeyID     string
+	getAlgorithm string
+	getPublicKey crypto.PublicKey
+}
+
+// jwksCache loads, caches, and rotates the verification key set.
+type jwksCache struct {
+	getFilePath        string
+	getURL             string
+	getHTTPClient      *http.Client
+	getRefreshInterval time.Duration
+	setCacheLock       sync.Mutex
+	storeKeys          []jwksKey
+	storeLoadedAt      time.Time
+	storeAttemptedAt   time.Time
+	storeRefreshDone   chan struct{}
+}
+
+// buildJWKSCache validates the key source and performs the initial load.
+func buildJWKSCache(parseConfig Config) (*jwksCache, error) {
+	if (parseConfig.JWKSFile == "") == (parseConfig.JWKSURL == "") {
+		return nil, errors.New("jwt: exactly one of JWKSFile or JWKSURL is required")
+	}
+	if parseConfig.JWKSURL != "" {
+		if parseErr := getJWKSURLError(parseConfig.JWKSURL); parseErr != nil {
+			return nil, parseErr
+		}
+	}
+	if parseConfig.JWKSRefreshInterval < 0 {
+		return nil, errors.New("jwt: JWKSRefreshInterval must be >= 0")
+	}
+
+	parseRefreshInterval := parseConfig.JWKSRefreshInterval
+	if parseRefreshInterval == 0 {
+		parseRefreshInterval = parseDefaultJWKSRefreshInterval
+	}
+	parseHTTPClient := parseConfig.HTTPClient
+	if parseHTTPClient == nil {
+		parseHTTPClient = &http.Client{Timeout: parseJWKSFetchTimeout}
+	}
+
+	parseCache := &jwksCache{
+		getFilePath:        parseConfig.JWKSFile,
+		getURL:             parseConfig.JWKSURL,
+		getHTTPClient:      parseHTTPClient,
+		getRefreshInterval: parseRefreshInterval,
+	}
+	parseKeys, parseErr := parseCache.loadJWKSKeys(context.Background())
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	parseNow := time.Now()
+	parseCache.storeKeys = parseKeys
+	parseCache.storeLoadedAt = parseNow
+	parseCache.storeAttemptedAt = parseNow
+	return parseCache, nil
+}
+
+// getJWKSURLError accepts https URLs and plaintext http URLs that stay on a loopback host.
+func getJWKSURLError(parseRawURL string) error {
+	parseURL, parseErr := url.Parse(parseRawURL)
+	if parseErr != nil {
+		return fmt.Errorf("jwt: invalid JWKSURL: %w", parseErr)
+	}
+	switch parseURL.Scheme {
+	case "https":
+		return nil
+	case "http":
+		parseHost := parseURL.Hostname()
+		if parseHost == "localhost" {
+			return nil
+		}
+		if parseIP := net.ParseIP(parseHost); parseIP != nil && parseIP.IsLoopback() {
+			return nil
+		}
+		return fmt.Errorf("jwt: plaintext JWKSURL host %q must be loopback; use https", parseHost)
+	default:
+		return fmt.Errorf("jwt: JWKSURL scheme %q must be http or https", parseURL.Scheme)
+	}
+}
+
+// getJWKSKeys returns the cached keys, refreshing them when stale or when a key ID is unknown.
+// At most one refresh runs at a time and never under the cache lock; lookups for a known key ID
+// keep using the cached set while it runs. Refresh failures keep serving the last good key set.
+func (parseCache *jwksCache) getJWKSKeys(parseCtx context.Context, parseKeyID string) []jwksKey {
+	parseCache.setCacheLock.Lock()
+	parseNow := time.Now()
+	isStale := parseNow.Sub(parseCache.storeLoadedAt) >= parseCache.getRefreshInterval
+	isUnknownKey := parseKeyID != "" && !hasJWKSKeyID(parseCache.storeKeys, parseKeyID)
+	isRefreshAllowed := parseNow.Sub(parseCache.storeAttemptedAt) >= parseJWKSMinRefreshInterval
+	if (isStale || isUnknownKey) && isRefreshAllowed && parseCache.storeRefreshDone == nil {
+		parseCache.storeAttemptedAt = parseNow
+		parseCache.storeRefreshDone = make(chan struct{})
+		go parseCache.refreshJWKSKeys(parseCtx, parseCache.storeRefreshDone)
+	}
+	parseKeys := parseCache.storeKeys
+	parseRefreshDone := parseCache.storeRefreshDone
+	parseCache.setCacheLock.Unlock()
+
+	if !isUnknownKey || parseRefreshDone == nil {
+		return parseKeys
+	}
+	select {
+	case <-parseRefreshDone:
+	case <-parseCtx.Done():
+		return parseKeys
+	}
+	parseCache.setCacheLock.Lock()
+	defer parseCache.setCacheLock.Unlock()
+	return parseCache.storeKeys
+}
+
+// refreshJWKSKeys loads the key set outside the cache lock, swaps it in on success, and closes
+// parseRefreshDone. The load is detached from the caller's cancellation so waiters share it.
+func (parseCache *jwksCache) refreshJWKSKeys(parseCtx context.Context, parseRefreshDone chan struct{}) {
+	parseLoadCtx, clearLoadCtx := context.WithTimeout(context.WithoutCancel(parseCtx), parseJWKSFetchTimeout)
+	defer clearLoadCtx()
+	parseKeys, parseErr := parseCache.loadJWKSKeys(parseLoadCtx)
+
+	parseCache.setCacheLock.Lock()
+	if parseErr == nil {
+		parseCache.storeKeys = parseKeys
+		parseCache.storeLoadedAt = time.Now()
+	}
+	parseCache.storeRefreshDone = nil
+	parseCache.setCacheLock.Unlock()
+	close(parseRefreshDone)
+}
+
+// hasJWKSKeyID reports whether a key set contains a key ID.
+func hasJWKSKeyID(parseKeys []jwksKey, parseKeyID string) bool {
+	for _, parseKey := range parseKeys {
+		if parseKey.getKeyID == parseKeyID {
+			return true
+		}
+	}
+	return false
+}
+
+// loadJWKSKeys reads the key set document from its file or URL and parses the usable keys.
+func (parseCache *jwksCache) loadJWKSKeys(parseCtx context.Context) ([]jwksKey, error) {
+	var parseDocument []byte
+	var parseErr error
+	if parseCache.getFilePath != "" {
+		parseDocument, parseErr = os.ReadFile(parseCache.getFilePath)
+	} else {
+		parseDocument, parseErr = parseCache.fetchJWKSDocument(parseCtx)
+	}
+	if parseErr != nil {
+		return nil, fmt.Errorf("jwt: load JWKS: %w", parseErr)
+	}
+	return parseJWKSDocument(parseDocument)
+}
+
+// fetchJWKSDocument downloads the key set document from the configured URL.
+func (parseCache *jwksCache) fetchJWKSDocument(parseCtx context.Context) ([]byte, error) {
+	parseRequest, parseErr := http.NewRequestWithContext(parseCtx, http.MethodGet, parseCache.getURL, nil)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	parseRequest.Header.Set("Accept", "application/json")
+	parseResponse, parseErr := parseCache.getHTTPClient.Do(parseRequest)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	defer parseResponse.Body.Close()
+	if parseResponse.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", parseResponse.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(parseResponse.Body, parseJWKSMaxBytes))
+}
+
+// parseJWKSDocument decodes a key set and keeps signature keys for supported algorithms.
+func parseJWKSDocument(parseDocument []byte) ([]jwksKey, error) {
+	var parseSet storeJWKSDocument
+	if parseErr := json.Unmarshal(parseDocument, &parseSet); parseErr != nil {
+		return nil, fmt.Errorf("jwt: decode JWKS: %w", parseErr)
+	}
+
+	parseKeys := make([]jwksKey, 0, len(parseSet.Keys))
+	for _, parseRawKey := range parseSet.Keys {
+		if parseRawKey.Use != "" && parseRawKey.Use != "sig" {
+			continue
+		}
+		// Keys this package cannot verify with are skipped so a mixed key set still loads.
+		parseKey, parseErr := parseJWKSKey(parseRawKey)
+		if parseErr != nil {
+			continue
+		}
+		if parseRawKey.Alg != "" && parseRawKey.Alg != parseKey.getAlgorithm {
+			continue
+		}
+		parseKeys = append(parseKeys, parseKey)
+	}
+	if len(parseKeys) == 0 {
+		return nil, errors.New("jwt: JWKS contains no usable signature keys")
+	}
+	return parseKeys, nil
+}
+
+// parseJWKSKey converts one wire key into a public key bound to exactly one algorithm.
+func parseJWKSKey(parseRawKey storeJWKSKey) (jwksKey, error) {
+	parseKey := jwksKey{getKeyID: parseRawKey.Kid}
+	switch parseRawKey.Kty {
+	case "RSA":
+		parseModulus, parseErr := decodeJWKSInt(parseRawKey.N)
+		if parseErr != nil {
+			return jwksKey{}, fmt.Errorf("invalid n: %w", parseErr)
+		}
+		parseExponent, parseErr := decodeJWKSInt(parseRawKey.E)
+		if parseErr != nil || !parseExponent.IsInt64() || parseExponent.Int64() < 3 || parseExponent.Int64() > 1<<31-1 {
+			return jwksKey{}, errors.New("invalid e")
+		}
+		if parseModulus.BitLen() < parseMinRSAKeyBits {
+			return jwksKey{}, fmt.Errorf("RSA key must be at least %d bits", parseMinRSAKeyBits)
+		}
+		parseKey.getAlgorithm = AlgorithmRS256
+		parseKey.getPublicKey = &rsa.PublicKey{N: parseModulus, E: int(parseExponent.Int64())}
+	case "EC":
+		if parseRawKey.Crv != "P-256" {
+			return jwksKey{}, fmt.Errorf("unsupported curve %q", parseRawKey.Crv)
+		}
+		parseX, parseErr := decodeJWKSInt(parseRawKey.X)
+		if parseErr != nil {
+			return jwksKey{}, fmt.Errorf("invalid x: %w", parseErr)
+		}
+		parseY, parseErr := decodeJWKSInt(parseRawKey.Y)
+		if parseErr != nil {
+			return jwksKey{}, fmt.Errorf("invalid y: %w", parseErr)
+		}
+		if !elliptic.P256().IsOnCurve(parseX, parseY) {
+			return jwksKey{}, errors.New("point is not on P-256")
+		}
+		parseKey.getAlgorithm = AlgorithmES256
+		parseKey.getPublicKey = &ecdsa.PublicKey{Curve: elliptic.P256(), X: parseX, Y: parseY}
+	case "OKP":
+		if parseRawKey.Crv != "Ed25519" {
+			return jwksKey{}, fmt.Errorf("unsupported curve %q", parseRawKey.Crv)
+		}
+		parseX, parseErr := base64.RawURLEncoding.DecodeString(parseRawKey.X)
+		if parseErr != nil || len(parseX) != ed25519.PublicKeySize {
+			return jwksKey{}, errors.New("invalid x")
+		}
+		parseKey.getAlgorithm = AlgorithmEdDSA
+		parseKey.getPublicKey = ed25519.PublicKey(parseX)
+	default:
+		return jwksKey{}, fmt.Errorf("unsupported key type %q", parseRawKey.Kty)
+	}
+	return parseKey, nil
+}
+
+// decodeJWKSInt decodes an unsigned big-endian base64url integer.
+func decodeJWKSInt(parseValue string) (*big.Int, error) {
+	parseBytes, parseErr := base64.RawURLEncoding.DecodeString(strings.TrimRight(parseValue, "="))
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	if len(parseBytes) == 0 {
+		return nil, errors.New("empty value")
+	}
+	return new(big.Int).SetBytes(parseBytes), nil
+}
@@ -0,0 +1,400 @@
+//go:build !js && !wasm
+
+package jwt
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Supported JWS signature algorithms.
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+const parseBearerScheme = "bearer"
+const parseES256SignatureSize = 64
+
+// parseMaxNumericDate bounds NumericDate claims to the year 9999.
+const parseMaxNumericDate = 253402300799
+
+var (
+	// ErrMissingToken reports that the upgrade request carried no bearer token.
+	ErrMissingToken = errors.New("jwt: missing bearer token")
+	// ErrInvalidToken reports a malformed token, bad signature, or failed claim check.
+	ErrInvalidToken = errors.New("jwt: invalid token")
+	// ErrTokenExpired reports a token past its exp claim. It is also the cancellation
+	// cause of the tunnel context when a verified token expires mid-session.
+	ErrTokenExpired = errors.New("jwt: token expired")
+)
+
+// Config configures bearer-token verification for tunnel upgrades.
+type Config struct {
+	// JWKSFile reads the verification key set from a local JSON Web Key Set file.
+	// The file is re-read on refresh, so replacing it rotates keys.
+	JWKSFile string
+	// JWKSURL fetches the verification key set over HTTP. Plaintext http:// URLs must
+	// target a loopback host; use https:// for remote identity providers.
+	// Exactly one of JWKSFile or JWKSURL is required.
+	JWKSURL string
+	// JWKSRefreshInterval controls how long a loaded key set is cached. Tokens signed with an
+	// unknown key ID also trigger a rate-limited refresh. Zero uses 5 minutes.
+	JWKSRefreshInterval time.Duration
+	// HTTPClient fetches JWKSURL. Nil uses a client with a 10s timeout.
+	HTTPClient *http.Client
+	// Issuer is the required iss claim.
+	Issuer string
+	// Audiences lists accepted aud values; a token must carry at least one of them.
+	Audiences []string
+	// Algorithms restricts accepted signature algorithms. Empty accepts RS256, ES256, and EdDSA.
+	Algorithms []string
+	// ClockSkew tolerates clock drift when checking exp, nbf, and iat.
+	ClockSkew time.Duration
+	// CookieName reads the token from this cookie when the Authorization header is absent.
+	// Empty disables cookie tokens.
+	CookieName string
+	// SubprotocolPrefix reads the token from a Sec-WebSocket-Protocol entry of the form
+	// prefix+token when neither the header nor the cookie carries one. Empty disables
+	// subprotocol tokens. Browsers also need the bridge to select another offered
+	// subprotocol through its Subprotocols setting.
+	SubprotocolPrefix string
+}
+
+// Claims stores the verified registered claims of a token plus every decoded claim.
+type Claims struct {
+	Issuer    string
+	Subject   string
+	Audience  []string
+	ExpiresAt time.Time
+	NotBefore time.Time
+	IssuedAt  time.Time
+	ID        string
+	// Raw holds all claims as decoded JSON, with numbers as json.Number.
+	Raw map[string]any
+}
+
+// storeClaimsContextKey stores verified claims on tunnel contexts.
+type storeClaimsContextKey struct{}
+
+// storeTokenHeader is the JOSE header fields this package inspects.
+type storeTokenHeader struct {
+	Alg  string   `json:"alg"`
+	Kid  string   `json:"kid"`
+	Crit []string `json:"crit"`
+}
+
+// Authenticator verifies bearer tokens on websocket upgrade requests.
+type Authenticator struct {
+	getConfig     Config
+	getAlgorithms []string
+	getKeys       *jwksCache
+	getNow        func() time.Time
+}
+
+// NewAuthenticator validates the configuration and loads the initial key set.
+//
+// Example:
+//
+//	parseAuth, _ := jwt.NewAuthenticator(jwt.Config{
+//		JWKSURL:   "https://issuer.example.com/.well-known/jwks.json",
+//		Issuer:    "https://issuer.example.com/",
+//		Audiences: []string{"todo-api"},
+//		ClockSkew: 30 * time.Second,
+//	})
+//	handler, _ := grpctunnel.BuildBridgeHandler(grpcServer, grpctunnel.BridgeConfig{
+//		Authenticate: parseAuth.Authenticate,
+//	})
+func NewAuthenticator(parseConfig Config) (*Authenticator, error) {
+	if parseConfig.Issuer == "" {
+		return nil, errors.New("jwt: Issuer is required")
+	}
+	if len(parseConfig.Audiences) == 0 {
+		return nil, errors.New("jwt: at least one audience is required")
+	}
+	if parseConfig.ClockSkew < 0 {
+		return nil, errors.New("jwt: ClockSkew must be >= 0")
+	}
+	parseAlgorithms := parseConfig.Algorithms
+	if len(parseAlgorithms) == 0 {
+		parseAlgorithms = []string{AlgorithmRS256, AlgorithmES256, AlgorithmEdDSA}
+	}
+	for _, parseAlgorithm := range parseAlgorithms {
+		switch parseAlgorithm {
+		case AlgorithmRS256, AlgorithmES256, AlgorithmEdDSA:
+		default:
+			return nil, fmt.Errorf("jwt: unsupported algorithm %q", parseAlgorithm)
+		}
+	}
+
+	parseKeys, parseErr := buildJWKSCache(parseConfig)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	return &Authenticator{
+		getConfig:     parseConfig,
+		getAlgorithms: append([]string{}, parseAlgorithms...),
+		getKeys:       parseKeys,
+		getNow:        time.Now,
+	}, nil
+}
+
+// Authenticate verifies the bearer token on an upgrade request. It matches the Authenticate
+// hook of grpctunnel.BridgeConfig and bridge.Config.
+//
+// The returned context carries the verified Claims and ends with cause ErrTokenExpired when the
+// token expires, which makes the bridge send GOAWAY to the tunnel and close it after its
+// AuthExpiryGrace instead of keeping it alive.
+func (parseAuth *Authenticator) Authenticate(parseR *http.Request) (context.Context, error) {
+	parseToken := parseAuth.getRequestToken(parseR)
+	if parseToken == "" {
+		return nil, ErrMissingToken
+	}
+	parseClaims, parseErr := parseAuth.VerifyToken(parseR.Context(), parseToken)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
+	parseCtx := context.WithValue(parseR.Context(), storeClaimsContextKey{}, parseClaims)
+	parseCtx, clearExpiry := context.WithDeadlineCause(parseCtx, parseClaims.ExpiresAt.Add(parseAuth.getConfig.ClockSkew), ErrTokenExpired)
+	// The expiry timer is released with the upgrade request once the tunnel ends.
+	context.AfterFunc(parseR.Context(), clearExpiry)
+	return parseCtx, nil
+}
+
+// VerifyToken checks a compact JWS token's signature and claims and returns its claims.
+func (parseAuth *Authenticator) VerifyToken(parseCtx context.Context, parseToken string) (*Claims, error) {
+	parseParts := strings.Split(parseToken, ".")
+	if len(parseParts) != 3 {
+		return nil, fmt.Errorf("%w: malformed compact serialization", ErrInvalidToken)
+	}
+
+	var parseHeader storeTokenHeader
+	if parseErr := decodeTokenSegment(parseParts[0], &parseHeader); parseErr != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrInvalidToken, parseErr)
+	}
+	if len(parseHeader.Crit) > 0 {
+		return nil, fmt.Errorf("%w: unsupported critical header %q", ErrInvalidToken, parseHeader.Crit)
+	}
+	if !slices.Contains(parseAuth.getAlgorithms, parseHeader.Alg) {
+		return nil, fmt.Errorf("%w: algorithm %q is not accepted", ErrInvalidToken, parseHeader.Alg)
+	}
+	parseSignature, parseErr := base64.RawURLEncoding.DecodeString(parseParts[2])
+	if parseErr != nil {
+		return nil, fmt.Errorf("%w: signature: %v", ErrInvalidToken, parseErr)
+	}
+
+	parseSigningInput := []byte(parseParts[0] + "." + parseParts[1])
+	isVerified := false
+	for _, parseKey := range parseAuth.getKeys.getJWKSKeys(parseCtx, parseHeader.Kid) {
+		if parseKey.getAlgorithm != parseHeader.Alg {
+			continue
+		}
+		if parseHeader.Kid != "" && parseKey.getKeyID != parseHeader.Kid {
+			continue
+		}
+		if isTokenSignatureValid(parseKey, parseSigningInput, parseSignature) {
+			isVerified = true
+			break
+		}
+	}
+	if !isVerified {
+		return nil, fmt.Errorf("%w: signature verification failed", ErrInvalidToken)
+	}
+
+	parseClaims, parseErr := parseTokenClaims(parseParts[1])
+	if parseErr != nil {
+		return nil, fmt.Errorf("%w: claims: %v", ErrInvalidToken, parseErr)
+	}
+	if parseErr = parseAuth.getClaimsError(parseClaims); parseErr != nil {
+		return nil, parseErr
+	}
+	return parseClaims, nil
+}
+
+// ClaimsFromContext returns the claims stored by Authenticate on a tunnel or RPC context.
+func ClaimsFromContext(parseCtx context.Context) (*Claims, bool) {
+	parseClaims, isFoundClaims := parseCtx.Value(storeClaimsContextKey{}).(*Claims)
+	return parseClaims, isFoundClaims
+}
+
+// getRequestToken reads the token from the Authorization header, then the cookie, then a subprotocol entry.
+func (parseAuth *Authenticator) getRequestToken(parseR *http.Request) string {
+	if parseScheme, parseToken, isFound := strings.Cut(parseR.Header.Get("Authorization"), " "); isFound && strings.EqualFold(parseScheme, parseBearerScheme) {
+		return strings.TrimSpace(parseToken)
+	}
+	if parseAuth.getConfig.CookieName != "" {
+		if parseCookie, parseErr := parseR.Cookie(parseAuth.getConfig.CookieName); parseErr == nil {
+			return parseCookie.Value
+		}
+	}
+	if parseAuth.getConfig.SubprotocolPrefix != "" {
+		for _, parseSubprotocol := range websocket.Subprotocols(parseR) {
+			if parseToken, isFound := strings.CutPrefix(parseSubprotocol, parseAuth.getConfig.SubprotocolPrefix); isFound {
+				return parseToken
+			}
+		}
+	}
+	return ""
+}
+
+// getClaimsError checks issuer, audience, and time-based claims with the configured clock skew.
+func (parseAuth *Authenticator) getClaimsError(parseClaims *Claims) error {
+	parseNow := parseAuth.getNow()
+	parseSkew := parseAuth.getConfig.ClockSkew
+	if parseClaims.ExpiresAt.IsZero() {
+		return fmt.Errorf("%w: exp claim is required", ErrInvalidToken)
+	}
+	if !parseNow.Before(parseClaims.ExpiresAt.Add(parseSkew)) {
+		return ErrTokenExpired
+	}
+	if !parseClaims.NotBefore.IsZero() && parseNow.Add(parseSkew).Before(parseClaims.NotBefore) {
+		return fmt.Errorf("%w: token is not valid yet", ErrInvalidToken)
+	}
+	if !parseClaims.IssuedAt.IsZero() && parseNow.Add(parseSkew).Before(parseClaims.IssuedAt) {
+		return fmt.Errorf("%w: token issued in the future", ErrInvalidToken)
+	}
+	if parseClaims.Issuer != parseAuth.getConfig.Issuer {
+		return fmt.Errorf("%w: unexpected issuer %q", ErrInvalidToken, parseClaims.Issuer)
+	}
+	for _, parseAudience := range parseClaims.Audience {
+		if slices.Contains(parseAuth.getConfig.Audiences, parseAudience) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: no accepted audience", ErrInvalidToken)
+}
+
+// isTokenSignatureValid verifies one signature with a key already matched to the token algorithm.
+func isTokenSignatureValid(parseKey jwksKey, parseSigningInput []byte, parseSignature []byte) bool {
+	switch parsePublicKey := parseKey.getPublicKey.(type) {
+	case *rsa.PublicKey:
+		parseDigest := sha256.Sum256(parseSigningInput)
+		return rsa.VerifyPKCS1v15(parsePublicKey, crypto.SHA256, parseDigest[:], parseSignature) == nil
+	case *ecdsa.PublicKey:
+		if len(parseSignature) != parseES256SignatureSize {
+			return false
+		}
+		parseDigest := sha256.Sum256(parseSigningInput)
+		parseR := new(big.Int).SetBytes(parseSignature[:parseES256SignatureSize/2])
+		parseS := new(big.Int).SetBytes(parseSignature[parseES256SignatureSize/2:])
+		return ecdsa.Verify(parsePublicKey, parseDigest[:], parseR, parseS)
+	case ed25519.PublicKey:
+		return ed25519.Verify(parsePublicKey, parseSigningInput, parseSignature)
+	default:
+		return false
+	}
+}
+
+// decodeTokenSegment decodes one base64url JSON segment.
+func decodeTokenSegment(parseSegment string, parseTarget any) error {
+	parseBytes, parseErr := base64.RawURLEncoding.DecodeString(parseSegment)
+	if parseErr != nil {
+		return parseErr
+	}
+	return json.Unmarshal(parseBytes, parseTarget)
+}
+
+// parseTokenClaims decodes the claims segment and extracts the registered claims.
+func parseTokenClaims(parseSegment string) (*Claims, error) {
+	parseBytes, parseErr := base64.RawURLEncoding.DecodeString(parseSegment)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	parseDecoder := json.NewDecoder(bytes.NewReader(parseBytes))
+	parseDecoder.UseNumber()
+	var parseRaw map[string]any
+	if parseErr = parseDecoder.Decode(&parseRaw); parseErr != nil {
+		return nil, parseErr
+	}
+
+	parseClaims := &Claims{Raw: parseRaw}
+	var isStringClaim bool
+	if parseClaims.Issuer, isStringClaim = getStringClaim(parseRaw, "iss"); !isStringClaim {
+		return nil, errors.New("iss must be a string")
+	}
+	if parseClaims.Subject, isStringClaim = getStringClaim(parseRaw, "sub"); !isStringClaim {
+		return nil, errors.New("sub must be a string")
+	}
+	if parseClaims.ID, isStringClaim = getStringClaim(parseRaw, "jti"); !isStringClaim {
+		return nil, errors.New("jti must be a string")
+	}
+	switch parseAudience := parseRaw["aud"].(type) {
+	case nil:
+	case string:
+		parseClaims.Audience = []string{parseAudience}
+	case []any:
+		for _, parseEntry := range parseAudience {
+			parseValue, isString := parseEntry.(string)
+			if !isString {
+				return nil, errors.New("aud entries must be strings")
+			}
+			parseClaims.Audience = append(parseClaims.Audience, parseValue)
+		}
+	default:
+		return nil, errors.New("aud must be a string or array")
+	}
+	for _, parseTimeClaim := range []struct {
+		getName  string
+		setValue *time.Time
+	}{
+		{getName: "exp", setValue: &parseClaims.ExpiresAt},
+		{getName: "nbf", setValue: &parseClaims.NotBefore},
+		{getName: "iat", setValue: &parseClaims.IssuedAt},
+	} {
+		if parseErr = storeTimeClaim(parseRaw, parseTimeClaim.getName, parseTimeClaim.setValue); parseErr != nil {
+			return nil, parseErr
+		}
+	}
+	return parseClaims, nil
+}
+
+// getStringClaim returns an optional string claim and reports false when it has another type.
+func getStringClaim(parseRaw map[string]any, parseName string) (string, bool) {
+	parseValue, isFound := parseRaw[parseName]
+	if !isFound || parseValue == nil {
+		return "", true
+	}
+	parseString, isString := parseValue.(string)
+	return parseString, isString
+}
+
+// storeTimeClaim converts an optional NumericDate claim into a time.
+func storeTimeClaim(parseRaw map[string]any, parseName string, parseTarget *time.Time) error {
+	parseValue, isFound := parseRaw[parseName]
+	if !isFound || parseValue == nil {
+		return nil
+	}
+	parseNumber, isNumber := parseValue.(json.Number)
+	if !isNumber {
+		return fmt.Errorf("%s must be a number", parseName)
+	}
+	parseSeconds, parseErr := parseNumber.Float64()
+	if parseErr != nil {
+		return fmt.Errorf("%s: %w", parseName, parseErr)
+	}
+	if parseSeconds < 0 || parseSeconds > parseMaxNumericDate {
+		return fmt.Errorf("%s is out of range", parseName)
+	}
+	parseWhole, parseFraction := math.Modf(parseSeconds)
+	*parseTarget = time.Unix(int64(parseWhole), int64(parseFraction*float64(time.Second)))
+	return nil
+}
@@ -0,0 +1,384 @@
+//go:build !js && !wasm
+
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	grpc_health_v1 "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const parseTestIssuer = "https://issuer.test/"
+const parseTestAudience = "todo-api"
+
+// storeTestSigner signs test tokens with one key.
+type storeTestSigner struct {
+	getKeyID      string
+	getAlgorithm  string
+	getPrivateKey crypto.Signer
+	getJWK        map[string]string
+}
+
+// buildTestSigners creates one signer per supported algorithm.
+func buildTestSigners(parseT *testing.T) []storeTestSigner {
+	parseT.Helper()
+
+	parseRSAKey, parseErr := rsa.GenerateKey(rand.Reader, 2048)
+	if parseErr != nil {
+		parseT.Fatalf("rsa.GenerateKey() error: %v", parseErr)
+	}
+	parseECKey, parseErr := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if parseErr != nil {
+		parseT.Fatalf("ecdsa.GenerateKey() error: %v", parseErr)
+	}
+	parseEdPublic, parseEdKey, parseErr := ed25519.GenerateKey(rand.Reader)
+	if parseErr != nil {
+		parseT.Fatalf("ed25519.GenerateKey() error: %v", parseErr)
+	}
+
+	parseEncode := base64.RawURLEncoding.EncodeToString
+	return []storeTestSigner{
+		{
+			getKeyID: "rsa-1", getAlgorithm: AlgorithmRS256, getPrivateKey: parseRSAKey,
+			getJWK: map[string]string{"kty": "RSA", "kid": "rsa-1", "n": parseEncode(parseRSAKey.N.Bytes()), "e": parseEncode(big.NewInt(int64(parseRSAKey.E)).Bytes())},
+		},
+		{
+			getKeyID: "ec-1", getAlgorithm: AlgorithmES256, getPrivateKey: parseECKey,
+			getJWK: map[string]string{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": parseEncode(parseECKey.X.FillBytes(make([]byte, 32))), "y": parseEncode(parseECKey.Y.FillBytes(make([]byte, 32)))},
+		},
+		{
+			getKeyID: "ed-1", getAlgorithm: AlgorithmEdDSA, getPrivateKey: parseEdKey,
+			getJWK: map[string]string{"kty": "OKP", "kid": "ed-1", "crv": "Ed25519", "x": parseEncode(parseEdPublic)},
+		},
+	}
+}
+
+// buildTestJWKS encodes signer public keys as a JWKS document.
+func buildTestJWKS(parseT *testing.T, parseSigners ...storeTestSigner) []byte {
+	parseT.Helper()
+
+	parseKeys := make([]map[string]string, 0, len(parseSigners))
+	for _, parseSigner := range parseSigners {
+		parseKeys = append(parseKeys, parseSigner.getJWK)
+	}
+	parseDocument, parseErr := json.Marshal(map[string]any{"keys": parseKeys})
+	if parseErr != nil {
+		parseT.Fatalf("json.Marshal() error: %v", parseErr)
+	}
+	return parseDocument
+}
+
+// storeTestJWKSFile writes a JWKS document to a temporary file and returns its path.
+func storeTestJWKSFile(parseT *testing.T, parseDocument []byte) string {
+	parseT.Helper()
+
+	parsePath := filepath.Join(parseT.TempDir(), "jwks.json")
+	if parseErr := os.WriteFile(parsePath, parseDocument, 0o600); parseErr != nil {
+		parseT.Fatalf("os.WriteFile() error: %v", parseErr)
+	}
+	return parsePath
+}
+
+// buildTestToken signs a compact JWS with the given header algorithm and claims.
+func buildTestToken(parseT *testing.T, parseSigner storeTestSigner, parseAlgorithm string, parseClaims map[string]any) string {
+	parseT.Helper()
+
+	parseHeader, _ := json.Marshal(map[string]string{"alg": parseAlgorithm, "kid": parseSigner.getKeyID, "typ": "JWT"})
+	parsePayload, _ := json.Marshal(parseClaims)
+	parseSigningInput := base64.RawURLEncoding.EncodeToString(parseHeader) + "." + base64.RawURLEncoding.EncodeToString(parsePayload)
+
+	var parseSignature []byte
+	var parseErr error
+	switch parseKey := parseSigner.getPrivateKey.(type) {
+	case *rsa.PrivateKey:
+		parseDigest := sha256.Sum256([]byte(parseSigningInput))
+		parseSignature, parseErr = rsa.SignPKCS1v15(rand.Reader, parseKey, crypto.SHA256, parseDigest[:])
+	case *ecdsa.PrivateKey:
+		parseDigest := sha256.Sum256([]byte(parseSigningInput))
+		var parseR, parseS *big.Int
+		parseR, parseS, parseErr = ecdsa.Sign(rand.Reader, parseKey, parseDigest[:])
+		if parseErr == nil {
+			parseSignature = append(parseR.FillBytes(make([]byte, 32)), parseS.FillBytes(make([]byte, 32))...)
+		}
+	case ed25519.PrivateKey:
+		parseSignature = ed25519.Sign(parseKey, []byte(parseSigningInput))
+	}
+	if parseErr != nil {
+		parseT.Fatalf("sign token error: %v", parseErr)
+	}
+	return parseSigningInput + "." + base64.RawURLEncoding.EncodeToString(parseSignature)
+}
+
+// buildTestClaims returns valid claims expiring after the given duration.
+func buildTestClaims(parseExpiresIn time.Duration) map[string]any {
+	parseNow := time.Now()
+	return map[string]any{
+		"iss": parseTestIssuer,
+		"sub": "alice",
+		"aud": []string{"other", parseTestAudience},
+		"iat": parseNow.Unix(),
+		"exp": parseNow.Add(parseExpiresIn).Unix(),
+	}
+}
+
+// buildTestAuthenticator creates an authenticator over a JWKS file.
+func buildTestAuthenticator(parseT *testing.T, parseConfig Config, parseSigners ...storeTestSigner) *Authenticator {
+	parseT.Helper()
+
+	parseConfig.JWKSFile = storeTestJWKSFile(parseT, buildTestJWKS(parseT, parseSigners...))
+	parseConfig.Issuer = parseTestIssuer
+	parseConfig.Audiences = []string{parseTestAudience}
+	parseAuth, parseErr := NewAuthenticator(parseConfig)
+	if parseErr != nil {
+		parseT.Fatalf("NewAuthenticator() error: %v", parseErr)
+	}
+	return parseAuth
+}
+
+// TestAuthenticator_VerifiesSupportedAlgorithms verifies RS256, ES256, and EdDSA tokens and the claims context.
+func TestAuthenticator_VerifiesSupportedAlgorithms(parseT *testing.T) {
+	parseSigners := buildTestSigners(parseT)
+	parseAuth := buildTestAuthenticator(parseT, Config{}, parseSigners...)
+
+	for _, parseSigner := range parseSigners {
+		parseRequest := httptest.NewRequest(http.MethodGet, "/grpc", nil)
+		parseRequest.Header.Set("Authorization", "Bearer "+buildTestToken(parseT, parseSigner, parseSigner.getAlgorithm, buildTestClaims(time.Minute)))
+		parseCtx, parseErr := parseAuth.Authenticate(parseRequest)
+		if parseErr != nil {
+			parseT.Fatalf("Authenticate(%s) error: %v", parseSigner.getAlgorithm, parseErr)
+		}
+		parseClaims, isFoundClaims := ClaimsFromContext(parseCtx)
+		if !isFoundClaims || parseClaims.Subject != "alice" {
+			parseT.Fatalf("ClaimsFromContext(%s) = %+v, %v", parseSigner.getAlgorithm, parseClaims, isFoundClaims)
+		}
+		if parseDeadline, hasDeadline := parseCtx.Deadline(); !hasDeadline || !parseDeadline.Equal(parseClaims.ExpiresAt) {
+			parseT.Fatalf("context deadline = %v, %v; want %v", parseDeadline, hasDeadline, parseClaims.ExpiresAt)
+		}
+	}
+}
+
+// TestAuthenticator_RejectsInvalidTokens verifies claim, algorithm, and signature failures.
+func TestAuthenticator_RejectsInvalidTokens(parseT *testing.T) {
+	parseSigners := buildTestSigners(parseT)
+	parseRSA, parseEC := parseSigners[0], parseSigners[1]
+	parseAuth := buildTestAuthenticator(parseT, Config{ClockSkew: 5 * time.Second}, parseRSA, parseEC)
+
+	buildClaims := func(parseKey string, parseValue any) map[string]any {
+		parseClaims := buildTestClaims(time.Minute)
+		if parseValue == nil {
+			delete(parseClaims, parseKey)
+		} else {
+			parseClaims[parseKey] = parseValue
+		}
+		return parseClaims
+	}
+	parseValidToken := buildTestToken(parseT, parseRSA, AlgorithmRS256, buildTestClaims(time.Minute))
+	parseTests := []struct {
+		parseName  string
+		parseToken string
+		parseWant  error
+	}{
+		{parseName: "expired", parseToken: buildTestToken(parseT, parseRSA, AlgorithmRS256, buildClaims("exp", time.Now().Add(-time.Minute).Unix())), parseWant: ErrTokenExpired},
+		{parseName: "missing exp", parseToken: buildTestToken(parseT, parseRSA, AlgorithmRS256, buildClaims("exp", nil)), parseWant: ErrInvalidToken},
+		{parseName: "not yet valid", parseToken: buildTestToken(parseT, parseRSA, AlgorithmRS256, buildClaims("nbf", time.Now().Add(time.Minute).Unix())), parseWant: ErrInvalidToken},
+		{parseName: "wrong issuer", parseToken: buildTestToken(parseT, parseRSA, AlgorithmRS256, buildClaims("iss", "https://evil.test/")), parseWant: ErrInvalidToken},
+		{parseName: "wrong audience", parseToken: buildTestToken(parseT, parseRSA, AlgorithmRS256, buildClaims("aud", "other")), parseWant: ErrInvalidToken},
+		{parseName: "algorithm none", parseToken: base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." + strings.Split(parseValidToken, ".")[1] + ".", parseWant: ErrInvalidToken},
+		{parseName: "algorithm key mismatch", parseToken: buildTestToken(parseT, parseEC, AlgorithmRS256, buildTestClaims(time.Minute)), parseWant: ErrInvalidToken},
+		{parseName: "tampered payload", parseToken: strings.Replace(parseValidToken, ".", ".e30", 1), parseWant: ErrInvalidToken},
+		{parseName: "malformed", parseToken: "not-a-token", parseWant: ErrInvalidToken},
+	}
+	for _, parseTestCase := range parseTests {
+		if _, parseErr := parseAuth.VerifyToken(context.Background(), parseTestCase.parseToken); !errors.Is(parseErr, parseTestCase.parseWant) {
+			parseT.Fatalf("VerifyToken(%s) error = %v, want %v", parseTestCase.parseName, parseErr, parseTestCase.parseWant)
+		}
+	}
+
+	parseSkewed := buildTestToken(parseT, parseRSA, AlgorithmRS256, buildClaims("exp", time.Now().Add(-2*time.Second).Unix()))
+	if _, parseErr := parseAuth.VerifyToken(context.Background(), parseSkewed); parseErr != nil {
+		parseT.Fatalf("VerifyToken(within skew) error: %v", parseErr)
+	}
+}
+
+// TestAuthenticator_TokenSources verifies header, cookie, and subprotocol token lookup.
+func TestAuthenticator_TokenSources(parseT *testing.T) {
+	parseSigners := buildTestSigners(parseT)
+	parseAuth := buildTestAuthenticator(parseT, Config{CookieName: "session", SubprotocolPrefix: "bearer."}, parseSigners[2])
+	parseToken := buildTestToken(parseT, parseSigners[2], AlgorithmEdDSA, buildTestClaims(time.Minute))
+
+	parseCookieRequest := httptest.NewRequest(http.MethodGet, "/grpc", nil)
+	parseCookieRequest.AddCookie(&http.Cookie{Name: "session", Value: parseToken})
+	parseProtocolRequest := httptest.NewRequest(http.MethodGet, "/grpc", nil)
+	parseProtocolRequest.Header.Set("Sec-WebSocket-Protocol", "grpc-tunnel, bearer."+parseToken)
+	for _, parseRequest := range []*http.Request{parseCookieRequest, parseProtocolRequest} {
+		if _, parseErr := parseAuth.Authenticate(parseRequest); parseErr != nil {
+			parseT.Fatalf("Authenticate() error: %v", parseErr)
+		}
+	}
+
+	if _, parseErr := parseAuth.Authenticate(httptest.NewRequest(http.MethodGet, "/grpc", nil)); !errors.Is(parseErr, ErrMissingToken) {
+		parseT.Fatalf("Authenticate(no token) error = %v, want %v", parseErr, ErrMissingToken)
+	}
+}
+
+// TestAuthenticator_RotatesJWKSURL verifies an unknown key ID refreshes keys from the JWKS URL.
+func TestAuthenticator_RotatesJWKSURL(parseT *testing.T) {
+	parseSigners := buildTestSigners(parseT)
+	var parseDocument atomic.Value
+	parseDocument.Store(buildTestJWKS(parseT, parseSigners[0]))
+	parseServer := httptest.NewServer(http.HandlerFunc(func(parseW http.ResponseWriter, parseR *http.Request) {
+		_, _ = parseW.Write(parseDocument.Load().([]byte))
+	}))
+	defer parseServer.Close()
+
+	parseAuth, parseErr := NewAuthenticator(Config{JWKSURL: parseServer.URL, Issuer: parseTestIssuer, Audiences: []string{parseTestAudience}})
+	if parseErr != nil {
+		parseT.Fatalf("NewAuthenticator() error: %v", parseErr)
+	}
+	parseToken := buildTestToken(parseT, parseSigners[1], AlgorithmES256, buildTestClaims(time.Minute))
+	if _, parseErr = parseAuth.VerifyToken(context.Background(), parseToken); !errors.Is(parseErr, ErrInvalidToken) {
+		parseT.Fatalf("VerifyToken(before rotation) error = %v, want %v", parseErr, ErrInvalidToken)
+	}
+
+	parseDocument.Store(buildTestJWKS(parseT, parseSigners[1]))
+	parseAuth.getKeys.storeAttemptedAt = time.Time{}
+	if _, parseErr = parseAuth.VerifyToken(context.Background(), parseToken); parseErr != nil {
+		parseT.Fatalf("VerifyToken(after rotation) error: %v", parseErr)
+	}
+
+	if _, parseErr = NewAuthenticator(Config{JWKSURL: "http://issuer.example.com/jwks", Issuer: parseTestIssuer, Audiences: []string{parseTestAudience}}); parseErr == nil {
+		parseT.Fatal("NewAuthenticator(non-loopback http JWKSURL) error = nil")
+	}
+}
+
+// TestAuthenticator_RefreshesJWKSOutsideLock verifies a slow JWKS fetch does not block lookups of
+// cached keys, and that an unknown key ID waits for the shared fetch.
+func TestAuthenticator_RefreshesJWKSOutsideLock(parseT *testing.T) {
+	parseSigners := buildTestSigners(parseT)
+	var parseDocument atomic.Value
+	parseDocument.Store(buildTestJWKS(parseT, parseSigners[0]))
+	var isSlow atomic.Bool
+	var parseFetches atomic.Int64
+	parseRelease := make(chan struct{})
+	parseServer := httptest.NewServer(http.HandlerFunc(func(parseW http.ResponseWriter, parseR *http.Request) {
+		if isSlow.Load() {
+			parseFetches.Add(1)
+			<-parseRelease
+		}
+		_, _ = parseW.Write(parseDocument.Load().([]byte))
+	}))
+	defer parseServer.Close()
+
+	parseAuth, parseErr := NewAuthenticator(Config{JWKSURL: parseServer.URL, Issuer: parseTestIssuer, Audiences: []string{parseTestAudience}})
+	if parseErr != nil {
+		parseT.Fatalf("NewAuthenticator() error: %v", parseErr)
+	}
+	isSlow.Store(true)
+	parseDocument.Store(buildTestJWKS(parseT, parseSigners[0], parseSigners[1]))
+	parseAuth.getKeys.setCacheLock.Lock()
+	parseAuth.getKeys.storeLoadedAt = time.Time{}
+	parseAuth.getKeys.storeAttemptedAt = time.Time{}
+	parseAuth.getKeys.setCacheLock.Unlock()
+
+	parseRotatedToken := buildTestToken(parseT, parseSigners[1], AlgorithmES256, buildTestClaims(time.Minute))
+	parseRotatedErr := make(chan error, 1)
+	go func() {
+		_, parseVerifyErr := parseAuth.VerifyToken(context.Background(), parseRotatedToken)
+		parseRotatedErr <- parseVerifyErr
+	}()
+
+	parseCachedToken := buildTestToken(parseT, parseSigners[0], AlgorithmRS256, buildTestClaims(time.Minute))
+	parseCachedErr := make(chan error, 1)
+	go func() {
+		_, parseVerifyErr := parseAuth.VerifyToken(context.Background(), parseCachedToken)
+		parseCachedErr <- parseVerifyErr
+	}()
+	select {
+	case parseErr := <-parseCachedErr:
+		if parseErr != nil {
+			parseT.Fatalf("VerifyToken(cached key) error: %v", parseErr)
+		}
+	case <-time.After(2 * time.Second):
+		parseT.Fatal("VerifyToken(cached key) blocked on the JWKS fetch")
+	}
+
+	close(parseRelease)
+	select {
+	case parseErr := <-parseRotatedErr:
+		if parseErr != nil {
+			parseT.Fatalf("VerifyToken(rotated key) error: %v", parseErr)
+		}
+	case <-time.After(5 * time.Second):
+		parseT.Fatal("VerifyToken(rotated key) did not return after the JWKS fetch")
+	}
+	if parseCount := parseFetches.Load(); parseCount != 1 {
+		parseT.Fatalf("JWKS fetches = %d, want 1", parseCount)
+	}
+}
+
+// TestAuthenticator_ExpiryDrainsTunnel verifies token expiry ends a live bridge tunnel.
+func TestAuthenticator_ExpiryDrainsTunnel(parseT *testing.T) {
+	parseSigners := buildTestSigners(parseT)
+	parseAuth := buildTestAuthenticator(parseT, Config{}, parseSigners[2])
+
+	parseGrpcServer := grpc.NewServer()
+	grpc_health_v1.RegisterHealthServer(parseGrpcServer, health.NewServer())
+	defer parseGrpcServer.Stop()
+	parseDisconnected := make(chan struct{})
+	var clearDisconnectOnce sync.Once
+	parseHandler, parseErr := grpctunnel.BuildBridgeHandler(parseGrpcServer, grpctunnel.BridgeConfig{
+		Authenticate: parseAuth.Authenticate,
+		OnDisconnect: func(*http.Request) { clearDisconnectOnce.Do(func() { close(parseDisconnected) }) },
+	})
+	if parseErr != nil {
+		parseT.Fatalf("BuildBridgeHandler() error: %v", parseErr)
+	}
+	parseServer := httptest.NewServer(parseHandler)
+	defer parseServer.Close()
+
+	parseCtx, clearCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer clearCtx()
+	parseToken := buildTestToken(parseT, parseSigners[2], AlgorithmEdDSA, buildTestClaims(2*time.Second))
+	parseConn, parseErr := grpctunnel.BuildTunnelConn(parseCtx, grpctunnel.TunnelConfig{
+		Target:      "ws" + strings.TrimPrefix(parseServer.URL, "http"),
+		Headers:     http.Header{"Authorization": []string{"Bearer " + parseToken}},
+		GRPCOptions: []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())},
+	})
+	if parseErr != nil {
+		parseT.Fatalf("BuildTunnelConn() error: %v", parseErr)
+	}
+	defer parseConn.Close()
+	if _, parseErr = grpc_health_v1.NewHealthClient(parseConn).Check(parseCtx, &grpc_health_v1.HealthCheckRequest{}); parseErr != nil {
+		parseT.Fatalf("Check() error: %v", parseErr)
+	}
+
+	select {
+	case <-parseDisconnected:
+	case <-parseCtx.Done():
+		parseT.Fatal("tunnel stayed open after token expiry")
+	}
+}
@@ -0,0 +1,11 @@
+// Package rbac authorizes tunneled gRPC methods against identity-aware policy documents.
+//
+// Authorizer.Authorize plugs into grpctunnel.BridgeConfig.Authorize, grpctunnel.WithAuthorizer,
+// and bridge.Config.Authorize, which evaluate it for every HTTP/2 stream before the RPC reaches
+// a gRPC server or backend. Rules map principals or roles to glob patterns over full method
+// names. Identities come from the Authenticate context (ContextWithIdentity) or from the upgrade
+// request (HeaderIdentity).
+//
+// Policies load from JSON or YAML, reload when the policy file changes, and support
+// an audit mode that reports would-be denials without rejecting calls.
+package rbac
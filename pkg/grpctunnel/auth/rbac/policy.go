@@ -0,0 +1,168 @@
+//go:build !js && !wasm
+
+package rbac
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Policy modes.
+const (
+	// ModeEnforce denies calls that no rule allows.
+	ModeEnforce = "enforce"
+	// ModeAudit reports calls that no rule allows but lets them proceed.
+	ModeAudit = "audit"
+)
+
+// parseAnyPrincipal matches every authenticated principal in Rule.Principals.
+const parseAnyPrincipal = "*"
+
+// Rule allows a set of identities to call a set of methods.
+type Rule struct {
+	// Name identifies the rule in decisions and audit logs.
+	Name string `json:"name" yaml:"name"`
+	// Principals lists matching principals; "*" matches any authenticated principal.
+	Principals []string `json:"principals" yaml:"principals"`
+	// Roles lists matching roles; an identity matches when it holds any of them.
+	// A rule with no principals and no roles matches every caller, including anonymous ones.
+	Roles []string `json:"roles" yaml:"roles"`
+	// Methods lists path.Match patterns over full method names such as "/todo.v1.TodoService/*".
+	Methods []string `json:"methods" yaml:"methods"`
+}
+
+// Policy is a parsed authorization policy document. Calls are denied unless a rule allows them.
+//
+// Example document:
+//
+//	mode: enforce
+//	rules:
+//	  - name: viewers-read
+//	    roles: [viewer, editor]
+//	    methods:
+//	      - /todo.v1.TodoService/List*
+//	  - name: health
+//	    methods: ["/grpc.health.v1.Health/*"]
+type Policy struct {
+	// Mode is ModeEnforce or ModeAudit. Empty means ModeEnforce.
+	Mode string `json:"mode" yaml:"mode"`
+	// Rules lists allow rules in evaluation order.
+	Rules []Rule `json:"rules" yaml:"rules"`
+}
+
+// ParsePolicy decodes and validates a policy document. Documents starting with "{" are
+// decoded as JSON; anything else is decoded as a single YAML document. Unknown fields are
+// rejected in both.
+func ParsePolicy(parseDocument []byte) (*Policy, error) {
+	var parsePolicy Policy
+	if bytes.HasPrefix(bytes.TrimSpace(parseDocument), []byte("{")) {
+		parseDecoder := json.NewDecoder(bytes.NewReader(parseDocument))
+		parseDecoder.DisallowUnknownFields()
+		if parseErr := parseDecoder.Decode(&parsePolicy); parseErr != nil {
+			return nil, fmt.Errorf("rbac: decode policy: %w", parseErr)
+		}
+	} else if parseErr := decodeYAMLPolicy(parseDocument, &parsePolicy); parseErr != nil {
+		return nil, fmt.Errorf("rbac: decode policy: %w", parseErr)
+	}
+	if parseErr := getPolicyError(&parsePolicy); parseErr != nil {
+		return nil, parseErr
+	}
+	return &parsePolicy, nil
+}
+
+// decodeYAMLPolicy decodes exactly one YAML document into parsePolicy. An empty document is an
+// empty policy.
+func decodeYAMLPolicy(parseDocument []byte, parsePolicy *Policy) error {
+	parseDecoder := yaml.NewDecoder(bytes.NewReader(parseDocument))
+	parseDecoder.KnownFields(true)
+	if parseErr := parseDecoder.Decode(parsePolicy); parseErr != nil {
+		if errors.Is(parseErr, io.EOF) {
+			return nil
+		}
+		return parseErr
+	}
+	var parseExtra yaml.Node
+	if parseErr := parseDecoder.Decode(&parseExtra); !errors.Is(parseErr, io.EOF) {
+		return errors.New("policy must be a single YAML document")
+	}
+	return nil
+}
+
+// getPolicyError validates the mode and method patterns of a policy.
+func getPolicyError(parsePolicy *Policy) error {
+	if parsePolicy == nil {
+		return errors.New("rbac: policy is nil")
+	}
+	switch parsePolicy.Mode {
+	case "", ModeEnforce, ModeAudit:
+	default:
+		return fmt.Errorf("rbac: unknown policy mode %q", parsePolicy.Mode)
+	}
+	for parseIndex, parseRule := range parsePolicy.Rules {
+		if len(parseRule.Methods) == 0 {
+			return fmt.Errorf("rbac: rule %d (%q) has no methods", parseIndex, parseRule.Name)
+		}
+		for _, parsePattern := range parseRule.Methods {
+			if !strings.HasPrefix(parsePattern, "/") {
+				return fmt.Errorf("rbac: rule %q method pattern %q must start with /", parseRule.Name, parsePattern)
+			}
+			if _, parseErr := path.Match(parsePattern, ""); parseErr != nil {
+				return fmt.Errorf("rbac: rule %q method pattern %q is invalid: %w", parseRule.Name, parsePattern, parseErr)
+			}
+		}
+	}
+	return nil
+}
+
+// isAuditMode reports whether denials are only reported.
+func (parsePolicy *Policy) isAuditMode() bool {
+	return parsePolicy.Mode == ModeAudit
+}
+
+// Evaluate returns the name of the first rule allowing an identity to call a method.
+func (parsePolicy *Policy) Evaluate(parseIdentity Identity, parseFullMethod string) (string, bool) {
+	for _, parseRule := range parsePolicy.Rules {
+		if isRuleIdentityMatch(parseRule, parseIdentity) && isRuleMethodMatch(parseRule, parseFullMethod) {
+			return parseRule.Name, true
+		}
+	}
+	return "", false
+}
+
+// isRuleIdentityMatch reports whether a rule applies to an identity.
+func isRuleIdentityMatch(parseRule Rule, parseIdentity Identity) bool {
+	if len(parseRule.Principals) == 0 && len(parseRule.Roles) == 0 {
+		return true
+	}
+	if parseIdentity.Principal != "" {
+		for _, parsePrincipal := range parseRule.Principals {
+			if parsePrincipal == parseAnyPrincipal || parsePrincipal == parseIdentity.Principal {
+				return true
+			}
+		}
+	}
+	for _, parseRole := range parseIdentity.Roles {
+		if slices.Contains(parseRule.Roles, parseRole) {
+			return true
+		}
+	}
+	return false
+}
+
+// isRuleMethodMatch reports whether any validated rule pattern matches a full method name.
+func isRuleMethodMatch(parseRule Rule, parseFullMethod string) bool {
+	for _, parsePattern := range parseRule.Methods {
+		if isMatch, _ := path.Match(parsePattern, parseFullMethod); isMatch {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,258 @@
+//go:build !js && !wasm
+
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const parseDefaultReloadInterval = 5 * time.Second
+
+// Identity is the caller a policy is evaluated for.
+type Identity struct {
+	// Principal names the caller, such as a token subject. Empty means anonymous.
+	Principal string
+	// Roles lists the roles held by the caller.
+	Roles []string
+}
+
+// Decision records one authorization outcome.
+type Decision struct {
+	Identity   Identity
+	FullMethod string
+	// Rule names the allowing rule. It is empty for denials.
+	Rule    string
+	Allowed bool
+	// DryRun reports that a denial was not enforced because the policy is in audit mode.
+	DryRun bool
+	// Err holds the Identify error when the caller could not be identified.
+	Err error
+}
+
+// Config configures policy loading and identity resolution.
+type Config struct {
+	// PolicyFile loads the policy document from a JSON or YAML file. The file is checked for
+	// changes every ReloadInterval and reloaded in place; invalid edits keep the last good policy.
+	PolicyFile string
+	// Policy is a static policy. Exactly one of PolicyFile or Policy is required.
+	Policy *Policy
+	// ReloadInterval controls how often PolicyFile is checked for changes. Zero uses 5 seconds.
+	ReloadInterval time.Duration
+	// Identify resolves the caller from the tunnel context and websocket upgrade request.
+	// Nil uses the Identity stored by ContextWithIdentity, or an anonymous identity.
+	Identify func(ctx context.Context, upgrade *http.Request) (Identity, error)
+	// OnDecision observes every decision, including audit-mode denials reported with DryRun set.
+	// Nil discards decisions.
+	OnDecision func(Decision)
+	// OnReloadError receives PolicyFile reload failures; the previous policy stays active.
+	// Nil discards them.
+	OnReloadError func(error)
+}
+
+// storeIdentityContextKey stores caller identities on tunnel contexts.
+type storeIdentityContextKey struct{}
+
+// Authorizer evaluates tunneled RPCs against a hot-reloadable policy.
+type Authorizer struct {
+	getConfig         Config
+	getPolicy         atomic.Pointer[Policy]
+	getNextReloadNano atomic.Int64
+	setReloadLock     sync.Mutex
+	storeFileModTime  time.Time
+	storeFileSize     int64
+	getNow            func() time.Time
+}
+
+// NewAuthorizer validates the configuration and loads the initial policy.
+//
+// Example:
+//
+//	parseAuthz, _ := rbac.NewAuthorizer(rbac.Config{
+//		PolicyFile: "/etc/bridge/rbac.yaml",
+//		Identify: func(parseCtx context.Context, _ *http.Request) (rbac.Identity, error) {
+//			parseClaims, _ := jwt.ClaimsFromContext(parseCtx)
+//			return rbac.Identity{Principal: parseClaims.Subject}, nil
+//		},
+//	})
+//	handler, _ := grpctunnel.BuildBridgeHandler(grpcServer, grpctunnel.BridgeConfig{
+//		Authenticate: parseAuth.Authenticate,
+//		Authorize:    parseAuthz.Authorize,
+//	})
+func NewAuthorizer(parseConfig Config) (*Authorizer, error) {
+	if (parseConfig.PolicyFile == "") == (parseConfig.Policy == nil) {
+		return nil, errors.New("rbac: exactly one of PolicyFile or Policy is required")
+	}
+	if parseConfig.ReloadInterval < 0 {
+		return nil, errors.New("rbac: ReloadInterval must be >= 0")
+	}
+	if parseConfig.ReloadInterval == 0 {
+		parseConfig.ReloadInterval = parseDefaultReloadInterval
+	}
+
+	parseAuthorizer := &Authorizer{getConfig: parseConfig, getNow: time.Now}
+	if parseConfig.Policy != nil {
+		if parseErr := parseAuthorizer.SetPolicy(parseConfig.Policy); parseErr != nil {
+			return nil, parseErr
+		}
+		return parseAuthorizer, nil
+	}
+	if parseErr := parseAuthorizer.Reload(); parseErr != nil {
+		return nil, parseErr
+	}
+	return parseAuthorizer, nil
+}
+
+// Policy returns the active policy.
+func (parseAuthorizer *Authorizer) Policy() *Policy {
+	return parseAuthorizer.getPolicy.Load()
+}
+
+// SetPolicy validates and activates a policy for subsequent calls.
+func (parseAuthorizer *Authorizer) SetPolicy(parsePolicy *Policy) error {
+	if parseErr := getPolicyError(parsePolicy); parseErr != nil {
+		return parseErr
+	}
+	parseAuthorizer.getPolicy.Store(parsePolicy)
+	return nil
+}
+
+// Reload re-reads PolicyFile and activates it when valid. The previous policy stays active on error.
+func (parseAuthorizer *Authorizer) Reload() error {
+	if parseAuthorizer.getConfig.PolicyFile == "" {
+		return errors.New("rbac: Reload requires PolicyFile")
+	}
+	parseAuthorizer.setReloadLock.Lock()
+	defer parseAuthorizer.setReloadLock.Unlock()
+	return parseAuthorizer.loadPolicyFile(true)
+}
+
+// loadPolicyFile reads PolicyFile when forced or when its size or modification time changed.
+// Callers hold setReloadLock.
+func (parseAuthorizer *Authorizer) loadPolicyFile(shouldForce bool) error {
+	parseInfo, parseErr := os.Stat(parseAuthorizer.getConfig.PolicyFile)
+	if parseErr != nil {
+		return fmt.Errorf("rbac: load policy: %w", parseErr)
+	}
+	if !shouldForce && parseInfo.ModTime().Equal(parseAuthorizer.storeFileModTime) && parseInfo.Size() == parseAuthorizer.storeFileSize {
+		return nil
+	}
+	parseDocument, parseErr := os.ReadFile(parseAuthorizer.getConfig.PolicyFile)
+	if parseErr != nil {
+		return fmt.Errorf("rbac: load policy: %w", parseErr)
+	}
+	// Record the attempt first so an invalid edit is reported once rather than on every check.
+	parseAuthorizer.storeFileModTime = parseInfo.ModTime()
+	parseAuthorizer.storeFileSize = parseInfo.Size()
+	parsePolicy, parseErr := ParsePolicy(parseDocument)
+	if parseErr != nil {
+		return parseErr
+	}
+	parseAuthorizer.getPolicy.Store(parsePolicy)
+	return nil
+}
+
+// refreshPolicyFile reloads a changed PolicyFile at most once per ReloadInterval without blocking callers.
+func (parseAuthorizer *Authorizer) refreshPolicyFile() {
+	if parseAuthorizer.getConfig.PolicyFile == "" {
+		return
+	}
+	parseNow := parseAuthorizer.getNow().UnixNano()
+	parseNextReload := parseAuthorizer.getNextReloadNano.Load()
+	if parseNow < parseNextReload {
+		return
+	}
+	if !parseAuthorizer.getNextReloadNano.CompareAndSwap(parseNextReload, parseNow+int64(parseAuthorizer.getConfig.ReloadInterval)) {
+		return
+	}
+	if !parseAuthorizer.setReloadLock.TryLock() {
+		return
+	}
+	defer parseAuthorizer.setReloadLock.Unlock()
+	if parseErr := parseAuthorizer.loadPolicyFile(false); parseErr != nil && parseAuthorizer.getConfig.OnReloadError != nil {
+		parseAuthorizer.getConfig.OnReloadError(parseErr)
+	}
+}
+
+// Authorize decides whether the caller of a tunneled RPC may invoke fullMethod.
+// It matches the Authorize hook of grpctunnel.BridgeConfig and bridge.Config and returns
+// Unauthenticated when the caller cannot be identified and PermissionDenied when no rule allows the call.
+// In audit mode every call proceeds and denials are only reported.
+func (parseAuthorizer *Authorizer) Authorize(parseCtx context.Context, parseUpgrade *http.Request, parseFullMethod string) error {
+	parseAuthorizer.refreshPolicyFile()
+	parsePolicy := parseAuthorizer.getPolicy.Load()
+
+	parseDecision := Decision{FullMethod: parseFullMethod, DryRun: parsePolicy.isAuditMode()}
+	var parseErr error
+	parseDecision.Identity, parseDecision.Err = parseAuthorizer.getIdentity(parseCtx, parseUpgrade)
+	if parseDecision.Err != nil {
+		parseErr = status.Error(codes.Unauthenticated, "rbac: caller identity unavailable")
+	} else if parseDecision.Rule, parseDecision.Allowed = parsePolicy.Evaluate(parseDecision.Identity, parseFullMethod); !parseDecision.Allowed {
+		parseErr = status.Errorf(codes.PermissionDenied, "rbac: %s is not allowed", parseFullMethod)
+	}
+	if parseDecision.Allowed {
+		parseDecision.DryRun = false
+	}
+	parseAuthorizer.storeDecision(parseDecision)
+	if parseDecision.DryRun {
+		return nil
+	}
+	return parseErr
+}
+
+// getIdentity resolves the caller with the configured Identify hook or the context identity.
+func (parseAuthorizer *Authorizer) getIdentity(parseCtx context.Context, parseUpgrade *http.Request) (Identity, error) {
+	if parseAuthorizer.getConfig.Identify != nil {
+		return parseAuthorizer.getConfig.Identify(parseCtx, parseUpgrade)
+	}
+	parseIdentity, _ := IdentityFromContext(parseCtx)
+	return parseIdentity, nil
+}
+
+// storeDecision reports a decision to OnDecision.
+func (parseAuthorizer *Authorizer) storeDecision(parseDecision Decision) {
+	if parseAuthorizer.getConfig.OnDecision != nil {
+		parseAuthorizer.getConfig.OnDecision(parseDecision)
+	}
+}
+
+// ContextWithIdentity stores a caller identity for Authorizers without an Identify hook.
+// Authenticate hooks call it on the context they return.
+func ContextWithIdentity(parseCtx context.Context, parseIdentity Identity) context.Context {
+	return context.WithValue(parseCtx, storeIdentityContextKey{}, parseIdentity)
+}
+
+// IdentityFromContext returns the identity stored by ContextWithIdentity.
+func IdentityFromContext(parseCtx context.Context) (Identity, bool) {
+	parseIdentity, isFoundIdentity := parseCtx.Value(storeIdentityContextKey{}).(Identity)
+	return parseIdentity, isFoundIdentity
+}
+
+// HeaderIdentity returns an Identify hook reading the principal and comma-separated roles from
+// upgrade request headers. Use it only behind a proxy that sets and strips these headers.
+func HeaderIdentity(parsePrincipalHeader string, parseRolesHeader string) func(context.Context, *http.Request) (Identity, error) {
+	return func(_ context.Context, parseUpgrade *http.Request) (Identity, error) {
+		if parseUpgrade == nil {
+			return Identity{}, errors.New("rbac: upgrade request unavailable")
+		}
+		parseIdentity := Identity{Principal: strings.TrimSpace(parseUpgrade.Header.Get(parsePrincipalHeader))}
+		if parseRolesHeader != "" {
+			for _, parseRole := range strings.Split(parseUpgrade.Header.Get(parseRolesHeader), ",") {
+				if parseRole = strings.TrimSpace(parseRole); parseRole != "" {
+					parseIdentity.Roles = append(parseIdentity.Roles, parseRole)
+				}
+			}
+		}
+		return parseIdentity, nil
+	}
+}
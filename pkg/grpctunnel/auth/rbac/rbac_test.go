@@ -0,0 +1,210 @@
+//go:build !js && !wasm
+
+package rbac
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	grpc_health_v1 "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+const parseTestYAMLPolicy = `
+# Viewers may read; health is public.
+mode: enforce
+rules:
+  - name: viewers-read
+    roles: [viewer, "editor"]
+    methods:
+      - /todo.v1.TodoService/List*   # trailing comment
+  - name: admin
+    principals:
+    - alice
+    methods: ['/todo.v1.TodoService/*']
+  - name: health
+    methods: ["/grpc.health.v1.Health/Check"]
+`
+
+// TestParsePolicy_YAMLMatchesJSON verifies YAML decodes to the same policy as JSON.
+func TestParsePolicy_YAMLMatchesJSON(parseT *testing.T) {
+	parseYAMLPolicy, parseErr := ParsePolicy([]byte(parseTestYAMLPolicy))
+	if parseErr != nil {
+		parseT.Fatalf("ParsePolicy(yaml) error: %v", parseErr)
+	}
+	parseJSONPolicy, parseErr := ParsePolicy([]byte(`{
+		"mode": "enforce",
+		"rules": [
+			{"name": "viewers-read", "roles": ["viewer", "editor"], "methods": ["/todo.v1.TodoService/List*"]},
+			{"name": "admin", "principals": ["alice"], "methods": ["/todo.v1.TodoService/*"]},
+			{"name": "health", "methods": ["/grpc.health.v1.Health/Check"]}
+		]
+	}`))
+	if parseErr != nil {
+		parseT.Fatalf("ParsePolicy(json) error: %v", parseErr)
+	}
+	if !reflect.DeepEqual(parseYAMLPolicy, parseJSONPolicy) {
+		parseT.Fatalf("yaml policy = %+v, want %+v", parseYAMLPolicy, parseJSONPolicy)
+	}
+
+	parseInvalidDocuments := []string{
+		"mode: strict\nrules: []\n",
+		"rules:\n  - name: x\n    methods: [no-slash]\n",
+		"rules:\n  - name: x\n",
+		"unknown: true\n",
+		"rules:\n  - name: x\n      methods: [/a/b]\n",
+		"rules:\n  - name: x\n    method: [/a/b]\n",
+		"rules: []\n---\nrules: []\n",
+	}
+	for _, parseDocument := range parseInvalidDocuments {
+		if _, parseErr = ParsePolicy([]byte(parseDocument)); parseErr == nil {
+			parseT.Fatalf("ParsePolicy(%q) error = nil, want error", parseDocument)
+		}
+	}
+}
+
+// TestPolicy_Evaluate verifies principal, role, and anonymous rule matching.
+func TestPolicy_Evaluate(parseT *testing.T) {
+	parsePolicy, parseErr := ParsePolicy([]byte(parseTestYAMLPolicy))
+	if parseErr != nil {
+		parseT.Fatalf("ParsePolicy() error: %v", parseErr)
+	}
+	parseTests := []struct {
+		parseIdentity Identity
+		parseMethod   string
+		parseWantRule string
+	}{
+		{parseIdentity: Identity{Principal: "bob", Roles: []string{"viewer"}}, parseMethod: "/todo.v1.TodoService/ListTodos", parseWantRule: "viewers-read"},
+		{parseIdentity: Identity{Principal: "bob", Roles: []string{"viewer"}}, parseMethod: "/todo.v1.TodoService/DeleteTodo"},
+		{parseIdentity: Identity{Principal: "alice"}, parseMethod: "/todo.v1.TodoService/DeleteTodo", parseWantRule: "admin"},
+		{parseIdentity: Identity{}, parseMethod: "/grpc.health.v1.Health/Check", parseWantRule: "health"},
+		{parseIdentity: Identity{}, parseMethod: "/todo.v1.TodoService/ListTodos"},
+	}
+	for _, parseTestCase := range parseTests {
+		parseRule, isAllowed := parsePolicy.Evaluate(parseTestCase.parseIdentity, parseTestCase.parseMethod)
+		if parseRule != parseTestCase.parseWantRule || isAllowed != (parseTestCase.parseWantRule != "") {
+			parseT.Fatalf("Evaluate(%+v, %q) = (%q, %v), want rule %q", parseTestCase.parseIdentity, parseTestCase.parseMethod, parseRule, isAllowed, parseTestCase.parseWantRule)
+		}
+	}
+}
+
+// TestAuthorizer_AuditModeAndReload verifies audit mode reports denials, file edits hot-reload, and
+// invalid edits reach OnReloadError.
+func TestAuthorizer_AuditModeAndReload(parseT *testing.T) {
+	parsePolicyFile := filepath.Join(parseT.TempDir(), "rbac.yaml")
+	if parseErr := os.WriteFile(parsePolicyFile, []byte("mode: audit\nrules: []\n"), 0o600); parseErr != nil {
+		parseT.Fatalf("WriteFile() error: %v", parseErr)
+	}
+	var parseDecisions []Decision
+	var parseReloadErrors []error
+	parseAuthorizer, parseErr := NewAuthorizer(Config{
+		PolicyFile:    parsePolicyFile,
+		Identify:      HeaderIdentity("X-Principal", "X-Roles"),
+		OnDecision:    func(parseDecision Decision) { parseDecisions = append(parseDecisions, parseDecision) },
+		OnReloadError: func(parseErr error) { parseReloadErrors = append(parseReloadErrors, parseErr) },
+	})
+	if parseErr != nil {
+		parseT.Fatalf("NewAuthorizer() error: %v", parseErr)
+	}
+	parseNow := time.Now()
+	parseAuthorizer.getNow = func() time.Time { return parseNow }
+
+	parseUpgrade := httptest.NewRequest(http.MethodGet, "/grpc", nil)
+	parseUpgrade.Header.Set("X-Principal", "bob")
+	parseUpgrade.Header.Set("X-Roles", "viewer, editor")
+	if parseErr = parseAuthorizer.Authorize(context.Background(), parseUpgrade, "/todo.v1.TodoService/ListTodos"); parseErr != nil {
+		parseT.Fatalf("Authorize(audit) error: %v", parseErr)
+	}
+	if len(parseDecisions) != 1 || parseDecisions[0].Allowed || !parseDecisions[0].DryRun {
+		parseT.Fatalf("audit decisions = %+v, want one dry-run denial", parseDecisions)
+	}
+	if !reflect.DeepEqual(parseDecisions[0].Identity.Roles, []string{"viewer", "editor"}) {
+		parseT.Fatalf("decision roles = %v", parseDecisions[0].Identity.Roles)
+	}
+
+	parseEnforced := "rules:\n  - name: editors\n    roles: [editor]\n    methods: [/todo.v1.TodoService/Update*]\n"
+	if parseErr = os.WriteFile(parsePolicyFile, []byte(parseEnforced), 0o600); parseErr != nil {
+		parseT.Fatalf("WriteFile() error: %v", parseErr)
+	}
+	parseNow = parseNow.Add(parseDefaultReloadInterval)
+	parseErr = parseAuthorizer.Authorize(context.Background(), parseUpgrade, "/todo.v1.TodoService/ListTodos")
+	if status.Code(parseErr) != codes.PermissionDenied {
+		parseT.Fatalf("Authorize(enforced) error = %v, want %v", parseErr, codes.PermissionDenied)
+	}
+	if parseErr = parseAuthorizer.Authorize(context.Background(), parseUpgrade, "/todo.v1.TodoService/UpdateTodo"); parseErr != nil {
+		parseT.Fatalf("Authorize(allowed) error: %v", parseErr)
+	}
+
+	if parseErr = os.WriteFile(parsePolicyFile, []byte("mode: [broken\n"), 0o600); parseErr != nil {
+		parseT.Fatalf("WriteFile() error: %v", parseErr)
+	}
+	parseNow = parseNow.Add(parseDefaultReloadInterval)
+	if parseErr = parseAuthorizer.Authorize(context.Background(), parseUpgrade, "/todo.v1.TodoService/UpdateTodo"); parseErr != nil {
+		parseT.Fatalf("Authorize(after invalid edit) error: %v", parseErr)
+	}
+	if len(parseReloadErrors) != 1 {
+		parseT.Fatalf("reload errors = %v, want one for the invalid edit", parseReloadErrors)
+	}
+}
+
+// TestAuthorizer_BridgeHandler verifies the Authenticate identity drives per-RPC decisions on a tunnel.
+func TestAuthorizer_BridgeHandler(parseT *testing.T) {
+	parseAuthorizer, parseErr := NewAuthorizer(Config{Policy: &Policy{Rules: []Rule{
+		{Name: "health-check", Principals: []string{"*"}, Methods: []string{"/grpc.health.v1.Health/Check"}},
+	}}})
+	if parseErr != nil {
+		parseT.Fatalf("NewAuthorizer() error: %v", parseErr)
+	}
+
+	parseGrpcServer := grpc.NewServer()
+	grpc_health_v1.RegisterHealthServer(parseGrpcServer, health.NewServer())
+	defer parseGrpcServer.Stop()
+	parseHandler, parseErr := grpctunnel.BuildBridgeHandler(parseGrpcServer, grpctunnel.BridgeConfig{
+		Authenticate: func(parseR *http.Request) (context.Context, error) {
+			return ContextWithIdentity(parseR.Context(), Identity{Principal: parseR.URL.Query().Get("user")}), nil
+		},
+		Authorize: parseAuthorizer.Authorize,
+	})
+	if parseErr != nil {
+		parseT.Fatalf("BuildBridgeHandler() error: %v", parseErr)
+	}
+	parseServer := httptest.NewServer(parseHandler)
+	defer parseServer.Close()
+
+	parseCtx, clearCtx := context.WithTimeout(context.Background(), 5*time.Second)
+	defer clearCtx()
+	parseTests := []struct {
+		parseUser     string
+		parseWantCode codes.Code
+	}{
+		{parseUser: "alice", parseWantCode: codes.OK},
+		{parseUser: "", parseWantCode: codes.PermissionDenied},
+	}
+	for _, parseTestCase := range parseTests {
+		parseConn, parseErr := grpctunnel.BuildTunnelConn(parseCtx, grpctunnel.TunnelConfig{
+			Target:      "ws" + strings.TrimPrefix(parseServer.URL, "http") + "/?user=" + parseTestCase.parseUser,
+			GRPCOptions: []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())},
+		})
+		if parseErr != nil {
+			parseT.Fatalf("BuildTunnelConn() error: %v", parseErr)
+		}
+		parseClient := grpc_health_v1.NewHealthClient(parseConn)
+		_, parseErr = parseClient.Check(parseCtx, &grpc_health_v1.HealthCheckRequest{})
+		if status.Code(parseErr) != parseTestCase.parseWantCode {
+			parseT.Fatalf("Check(user=%q) error = %v, want %v", parseTestCase.parseUser, parseErr, parseTestCase.parseWantCode)
+		}
+		parseConn.Close()
+	}
+}
@@ -0,0 +1,10 @@
+// Package ticket issues and redeems short-lived upgrade tickets for browser tunnel clients.
+//
+// Browser WebSocket clients cannot set an Authorization header, and tokens must not travel in
+// query strings. A Manager instead serves an issue endpoint that exchanges an already
+// authenticated request (for example a same-origin session cookie) for an HMAC-signed,
+// audience-bound, single-use ticket with a short TTL. The client presents the ticket through
+// the websocket subprotocol list returned by BuildSubprotocols (see dialer.Config.GetTicket),
+// and Manager.Authenticate redeems it in the bridge Authenticate hook, rejecting replays through
+// a bounded nonce cache. RPC handlers read the redeemed Claims with ClaimsFromContext.
+package ticket
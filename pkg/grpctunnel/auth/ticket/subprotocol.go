@@ -0,0 +1,14 @@
+package ticket
+
+// Subprotocol is the websocket subprotocol clients offer alongside a ticket entry.
+// Bridges list it in their Subprotocols setting so browsers see a selected protocol.
+const Subprotocol = "grpctunnel-ticket"
+
+// parseSubprotocolEntryPrefix prefixes the subprotocol entry that carries the ticket itself.
+const parseSubprotocolEntryPrefix = Subprotocol + "."
+
+// BuildSubprotocols returns the websocket subprotocol entries that present a ticket:
+// the selectable Subprotocol marker followed by the ticket-carrying entry.
+func BuildSubprotocols(parseTicket string) []string {
+	return []string{Subprotocol, parseSubprotocolEntryPrefix + parseTicket}
+}
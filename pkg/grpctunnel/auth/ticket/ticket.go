@@ -0,0 +1,302 @@
+//go:build !js && !wasm
+
+package ticket
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const parseDefaultTicketTTL = 30 * time.Second
+const parseMaxTicketTTL = 5 * time.Minute
+const parseDefaultMaxReplayEntries = 10000
+const parseMinSecretBytes = 32
+const parseNonceBytes = 16
+
+var (
+	// ErrMissingTicket reports that the upgrade request offered no ticket subprotocol entry.
+	ErrMissingTicket = errors.New("ticket: missing upgrade ticket")
+	// ErrInvalidTicket reports a malformed, forged, expired, or wrong-audience ticket.
+	ErrInvalidTicket = errors.New("ticket: invalid upgrade ticket")
+	// ErrTicketReplayed reports a ticket that was already redeemed.
+	ErrTicketReplayed = errors.New("ticket: upgrade ticket already used")
+	// ErrReplayCacheFull reports that the bounded nonce cache cannot admit another live ticket.
+	ErrReplayCacheFull = errors.New("ticket: replay cache is full")
+)
+
+// Config configures upgrade ticket issuing and redemption.
+type Config struct {
+	// Secret is the HMAC-SHA256 signing key shared by issuers and bridges. It must be at least 32 bytes.
+	Secret []byte
+	// Audience binds tickets to one bridge deployment. Tickets for another audience are rejected.
+	Audience string
+	// TTL bounds how long an issued ticket can be redeemed. Zero uses 30s; the maximum is 5m.
+	TTL time.Duration
+	// MaxReplayEntries bounds the nonce cache that rejects replays. Once full of unexpired
+	// nonces, new tickets are rejected until entries expire. Zero uses 10000.
+	// Replay tracking is local to one Manager: each bridge replica sharing the Secret and Audience
+	// accepts a ticket once.
+	MaxReplayEntries int
+	// Identify resolves the caller of the issue endpoint, typically from a same-origin session
+	// cookie, and returns the ticket subject plus optional attributes. An error rejects with 401.
+	Identify func(r *http.Request) (string, map[string]string, error)
+}
+
+// Claims stores the verified contents of a redeemed ticket.
+type Claims struct {
+	Subject    string            `json:"sub"`
+	Audience   string            `json:"aud"`
+	IssuedAt   int64             `json:"iat"`
+	ExpiresAt  int64             `json:"exp"`
+	Nonce      string            `json:"nonce"`
+	Attributes map[string]string `json:"attrs,omitempty"`
+}
+
+// storeClaimsContextKey stores redeemed ticket claims on tunnel contexts.
+type storeClaimsContextKey struct{}
+
+// storeIssueResponse is the JSON body returned by the issue endpoint.
+type storeIssueResponse struct {
+	Ticket       string    `json:"ticket"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	Subprotocols []string  `json:"subprotocols"`
+}
+
+// Manager issues and redeems short-lived, single-use upgrade tickets.
+type Manager struct {
+	getConfig   Config
+	getTTL      time.Duration
+	getNonces   *nonceCache
+	getNow      func() time.Time
+	readEntropy func([]byte) (int, error)
+}
+
+// NewManager validates the configuration and creates a ticket manager.
+//
+// Example:
+//
+//	parseTickets, _ := ticket.NewManager(ticket.Config{
+//		Secret:   secret,
+//		Audience: "todo-bridge",
+//		Identify: sessionSubject,
+//	})
+//	mux.Handle("/grpc/ticket", parseTickets.IssueHandler())
+//	handler, _ := grpctunnel.BuildBridgeHandler(grpcServer, grpctunnel.BridgeConfig{
+//		Subprotocols: []string{ticket.Subprotocol},
+//		Authenticate: parseTickets.Authenticate,
+//	})
+func NewManager(parseConfig Config) (*Manager, error) {
+	if len(parseConfig.Secret) < parseMinSecretBytes {
+		return nil, fmt.Errorf("ticket: Secret must be at least %d bytes", parseMinSecretBytes)
+	}
+	if parseConfig.Audience == "" {
+		return nil, errors.New("ticket: Audience is required")
+	}
+	if parseConfig.TTL < 0 || parseConfig.TTL > parseMaxTicketTTL {
+		return nil, fmt.Errorf("ticket: TTL must be between 0 and %s", parseMaxTicketTTL)
+	}
+	if parseConfig.MaxReplayEntries < 0 {
+		return nil, errors.New("ticket: MaxReplayEntries must be >= 0")
+	}
+
+	parseTTL := parseConfig.TTL
+	if parseTTL == 0 {
+		parseTTL = parseDefaultTicketTTL
+	}
+	parseMaxEntries := parseConfig.MaxReplayEntries
+	if parseMaxEntries == 0 {
+		parseMaxEntries = parseDefaultMaxReplayEntries
+	}
+	parseConfig.Secret = append([]byte{}, parseConfig.Secret...)
+	return &Manager{
+		getConfig:   parseConfig,
+		getTTL:      parseTTL,
+		getNonces:   buildNonceCache(parseMaxEntries),
+		getNow:      time.Now,
+		readEntropy: rand.Read,
+	}, nil
+}
+
+// Issue signs a new single-use ticket for a subject.
+func (parseManager *Manager) Issue(parseSubject string, parseAttributes map[string]string) (string, *Claims, error) {
+	parseNonce := make([]byte, parseNonceBytes)
+	if _, parseErr := parseManager.readEntropy(parseNonce); parseErr != nil {
+		return "", nil, fmt.Errorf("ticket: generate nonce: %w", parseErr)
+	}
+	parseNow := parseManager.getNow()
+	parseClaims := &Claims{
+		Subject:    parseSubject,
+		Audience:   parseManager.getConfig.Audience,
+		IssuedAt:   parseNow.Unix(),
+		ExpiresAt:  parseNow.Add(parseManager.getTTL).Unix(),
+		Nonce:      base64.RawURLEncoding.EncodeToString(parseNonce),
+		Attributes: parseAttributes,
+	}
+	parsePayload, parseErr := json.Marshal(parseClaims)
+	if parseErr != nil {
+		return "", nil, fmt.Errorf("ticket: encode claims: %w", parseErr)
+	}
+	parseEncodedPayload := base64.RawURLEncoding.EncodeToString(parsePayload)
+	return parseEncodedPayload + "." + parseManager.buildTicketSignature(parseEncodedPayload), parseClaims, nil
+}
+
+// IssueHandler returns an endpoint that issues one ticket per POST to callers accepted by Config.Identify.
+// Responses are JSON with the ticket, its expiry, and the subprotocols that present it, and are never cached.
+func (parseManager *Manager) IssueHandler() http.Handler {
+	return http.HandlerFunc(func(parseW http.ResponseWriter, parseR *http.Request) {
+		if parseR.Method != http.MethodPost {
+			parseW.Header().Set("Allow", http.MethodPost)
+			http.Error(parseW, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		if parseManager.getConfig.Identify == nil {
+			http.Error(parseW, http.StatusText(http.StatusNotImplemented), http.StatusNotImplemented)
+			return
+		}
+		parseSubject, parseAttributes, parseErr := parseManager.getConfig.Identify(parseR)
+		if parseErr != nil {
+			http.Error(parseW, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		parseTicket, parseClaims, parseErr := parseManager.Issue(parseSubject, parseAttributes)
+		if parseErr != nil {
+			http.Error(parseW, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		parseW.Header().Set("Content-Type", "application/json")
+		parseW.Header().Set("Cache-Control", "no-store")
+		_ = json.NewEncoder(parseW).Encode(storeIssueResponse{
+			Ticket:       parseTicket,
+			ExpiresAt:    time.Unix(parseClaims.ExpiresAt, 0).UTC(),
+			Subprotocols: BuildSubprotocols(parseTicket),
+		})
+	})
+}
+
+// Authenticate redeems the ticket offered through the ticket subprotocol entry. It matches the
+// Authenticate hook of grpctunnel.BridgeConfig and bridge.Config, and stores the ticket Claims
+// on the returned context for RPC handlers.
+func (parseManager *Manager) Authenticate(parseR *http.Request) (context.Context, error) {
+	parseTicket := ""
+	for _, parseSubprotocol := range websocket.Subprotocols(parseR) {
+		if parseValue, isFound := strings.CutPrefix(parseSubprotocol, parseSubprotocolEntryPrefix); isFound {
+			parseTicket = parseValue
+			break
+		}
+	}
+	if parseTicket == "" {
+		return nil, ErrMissingTicket
+	}
+	parseClaims, parseErr := parseManager.Redeem(parseTicket)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	return context.WithValue(parseR.Context(), storeClaimsContextKey{}, parseClaims), nil
+}
+
+// Redeem verifies a ticket and consumes its nonce so it cannot be used again.
+func (parseManager *Manager) Redeem(parseTicket string) (*Claims, error) {
+	parseEncodedPayload, parseSignature, isFound := strings.Cut(parseTicket, ".")
+	if !isFound {
+		return nil, fmt.Errorf("%w: malformed ticket", ErrInvalidTicket)
+	}
+	if !hmac.Equal([]byte(parseSignature), []byte(parseManager.buildTicketSignature(parseEncodedPayload))) {
+		return nil, fmt.Errorf("%w: signature mismatch", ErrInvalidTicket)
+	}
+	parsePayload, parseErr := base64.RawURLEncoding.DecodeString(parseEncodedPayload)
+	if parseErr != nil {
+		return nil, fmt.Errorf("%w: payload: %v", ErrInvalidTicket, parseErr)
+	}
+	var parseClaims Claims
+	if parseErr = json.Unmarshal(parsePayload, &parseClaims); parseErr != nil {
+		return nil, fmt.Errorf("%w: payload: %v", ErrInvalidTicket, parseErr)
+	}
+
+	parseNow := parseManager.getNow()
+	parseExpiresAt := time.Unix(parseClaims.ExpiresAt, 0)
+	if !parseNow.Before(parseExpiresAt) {
+		return nil, fmt.Errorf("%w: expired", ErrInvalidTicket)
+	}
+	if parseClaims.Audience != parseManager.getConfig.Audience {
+		return nil, fmt.Errorf("%w: unexpected audience %q", ErrInvalidTicket, parseClaims.Audience)
+	}
+	if parseClaims.Nonce == "" {
+		return nil, fmt.Errorf("%w: missing nonce", ErrInvalidTicket)
+	}
+	if parseErr = parseManager.getNonces.storeNonce(parseClaims.Nonce, parseExpiresAt, parseNow); parseErr != nil {
+		return nil, parseErr
+	}
+	return &parseClaims, nil
+}
+
+// ClaimsFromContext returns the ticket claims stored by Authenticate on a tunnel or RPC context.
+func ClaimsFromContext(parseCtx context.Context) (*Claims, bool) {
+	parseClaims, isFoundClaims := parseCtx.Value(storeClaimsContextKey{}).(*Claims)
+	return parseClaims, isFoundClaims
+}
+
+// buildTicketSignature signs the encoded payload with HMAC-SHA256.
+func (parseManager *Manager) buildTicketSignature(parseEncodedPayload string) string {
+	parseMAC := hmac.New(sha256.New, parseManager.getConfig.Secret)
+	_, _ = parseMAC.Write([]byte(parseEncodedPayload))
+	return base64.RawURLEncoding.EncodeToString(parseMAC.Sum(nil))
+}
+
+// nonceEntry stores one redeemed nonce until its ticket expires.
+type nonceEntry struct {
+	getNonce     string
+	getExpiresAt time.Time
+}
+
+// nonceCache remembers redeemed nonces until expiry, bounded to a fixed number of entries.
+type nonceCache struct {
+	setCacheLock  sync.Mutex
+	getMaxEntries int
+	storeNonces   map[string]struct{}
+	storeByExpiry []nonceEntry
+}
+
+// buildNonceCache creates an empty nonce cache.
+func buildNonceCache(parseMaxEntries int) *nonceCache {
+	return &nonceCache{
+		getMaxEntries: parseMaxEntries,
+		storeNonces:   map[string]struct{}{},
+	}
+}
+
+// storeNonce records a nonce, rejecting replays and failing closed when the cache is full.
+func (parseCache *nonceCache) storeNonce(parseNonce string, parseExpiresAt time.Time, parseNow time.Time) error {
+	parseCache.setCacheLock.Lock()
+	defer parseCache.setCacheLock.Unlock()
+
+	// Tickets share one TTL, so insertion order is expiry order and pruning stops at the first live entry.
+	parseExpired := 0
+	for parseExpired < len(parseCache.storeByExpiry) && !parseNow.Before(parseCache.storeByExpiry[parseExpired].getExpiresAt) {
+		delete(parseCache.storeNonces, parseCache.storeByExpiry[parseExpired].getNonce)
+		parseExpired++
+	}
+	parseCache.storeByExpiry = parseCache.storeByExpiry[parseExpired:]
+
+	if _, isFound := parseCache.storeNonces[parseNonce]; isFound {
+		return ErrTicketReplayed
+	}
+	if len(parseCache.storeNonces) >= parseCache.getMaxEntries {
+		return ErrReplayCacheFull
+	}
+	parseCache.storeNonces[parseNonce] = struct{}{}
+	parseCache.storeByExpiry = append(parseCache.storeByExpiry, nonceEntry{getNonce: parseNonce, getExpiresAt: parseExpiresAt})
+	return nil
+}
@@ -0,0 +1,191 @@
+//go:build !js && !wasm
+
+package ticket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	grpc_health_v1 "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+var parseTestSecret = []byte("0123456789abcdef0123456789abcdef")
+
+// buildTestManager creates a ticket manager that identifies callers by a session cookie.
+func buildTestManager(parseT *testing.T, parseConfig Config) *Manager {
+	parseT.Helper()
+
+	parseConfig.Secret = parseTestSecret
+	if parseConfig.Audience == "" {
+		parseConfig.Audience = "todo-bridge"
+	}
+	parseConfig.Identify = func(parseR *http.Request) (string, map[string]string, error) {
+		parseCookie, parseErr := parseR.Cookie("session")
+		if parseErr != nil {
+			return "", nil, parseErr
+		}
+		return parseCookie.Value, map[string]string{"role": "viewer"}, nil
+	}
+	parseManager, parseErr := NewManager(parseConfig)
+	if parseErr != nil {
+		parseT.Fatalf("NewManager() error: %v", parseErr)
+	}
+	return parseManager
+}
+
+// TestManager_IssueHandler verifies the issue endpoint requires POST and an identified caller.
+func TestManager_IssueHandler(parseT *testing.T) {
+	parseManager := buildTestManager(parseT, Config{})
+	parseHandler := parseManager.IssueHandler()
+
+	parseRecorder := httptest.NewRecorder()
+	parseHandler.ServeHTTP(parseRecorder, httptest.NewRequest(http.MethodGet, "/ticket", nil))
+	if parseRecorder.Code != http.StatusMethodNotAllowed {
+		parseT.Fatalf("GET status = %d, want %d", parseRecorder.Code, http.StatusMethodNotAllowed)
+	}
+	parseRecorder = httptest.NewRecorder()
+	parseHandler.ServeHTTP(parseRecorder, httptest.NewRequest(http.MethodPost, "/ticket", nil))
+	if parseRecorder.Code != http.StatusUnauthorized {
+		parseT.Fatalf("anonymous POST status = %d, want %d", parseRecorder.Code, http.StatusUnauthorized)
+	}
+
+	parseRequest := httptest.NewRequest(http.MethodPost, "/ticket", nil)
+	parseRequest.AddCookie(&http.Cookie{Name: "session", Value: "alice"})
+	parseRecorder = httptest.NewRecorder()
+	parseHandler.ServeHTTP(parseRecorder, parseRequest)
+	if parseRecorder.Code != http.StatusOK {
+		parseT.Fatalf("POST status = %d, want %d", parseRecorder.Code, http.StatusOK)
+	}
+	if parseCache := parseRecorder.Header().Get("Cache-Control"); parseCache != "no-store" {
+		parseT.Fatalf("Cache-Control = %q, want no-store", parseCache)
+	}
+	var parseResponse storeIssueResponse
+	if parseErr := json.NewDecoder(parseRecorder.Body).Decode(&parseResponse); parseErr != nil {
+		parseT.Fatalf("decode response error: %v", parseErr)
+	}
+	parseClaims, parseErr := parseManager.Redeem(parseResponse.Ticket)
+	if parseErr != nil {
+		parseT.Fatalf("Redeem() error: %v", parseErr)
+	}
+	if parseClaims.Subject != "alice" || parseClaims.Attributes["role"] != "viewer" {
+		parseT.Fatalf("Redeem() claims = %+v", parseClaims)
+	}
+	if len(parseResponse.Subprotocols) != 2 || parseResponse.Subprotocols[0] != Subprotocol {
+		parseT.Fatalf("response subprotocols = %v", parseResponse.Subprotocols)
+	}
+}
+
+// TestManager_RedeemRejectsInvalidTickets verifies forgery, audience, expiry, and replay checks.
+func TestManager_RedeemRejectsInvalidTickets(parseT *testing.T) {
+	parseManager := buildTestManager(parseT, Config{TTL: time.Second})
+	parseOtherManager := buildTestManager(parseT, Config{Audience: "other-bridge"})
+
+	parseTicket, _, parseErr := parseManager.Issue("alice", nil)
+	if parseErr != nil {
+		parseT.Fatalf("Issue() error: %v", parseErr)
+	}
+	if _, parseErr = parseOtherManager.Redeem(parseTicket); !errors.Is(parseErr, ErrInvalidTicket) {
+		parseT.Fatalf("Redeem(other audience) error = %v, want %v", parseErr, ErrInvalidTicket)
+	}
+	parsePayload, _, _ := strings.Cut(parseTicket, ".")
+	if _, parseErr = parseManager.Redeem(parsePayload + ".forged"); !errors.Is(parseErr, ErrInvalidTicket) {
+		parseT.Fatalf("Redeem(forged) error = %v, want %v", parseErr, ErrInvalidTicket)
+	}
+	if _, parseErr = parseManager.Redeem(parseTicket); parseErr != nil {
+		parseT.Fatalf("Redeem() error: %v", parseErr)
+	}
+	if _, parseErr = parseManager.Redeem(parseTicket); !errors.Is(parseErr, ErrTicketReplayed) {
+		parseT.Fatalf("Redeem(replay) error = %v, want %v", parseErr, ErrTicketReplayed)
+	}
+
+	parseExpiredTicket, _, _ := parseManager.Issue("alice", nil)
+	parseManager.getNow = func() time.Time { return time.Now().Add(2 * time.Second) }
+	if _, parseErr = parseManager.Redeem(parseExpiredTicket); !errors.Is(parseErr, ErrInvalidTicket) {
+		parseT.Fatalf("Redeem(expired) error = %v, want %v", parseErr, ErrInvalidTicket)
+	}
+}
+
+// TestNonceCache_BoundedAndPruned verifies the replay cache fails closed when full and frees expired nonces.
+func TestNonceCache_BoundedAndPruned(parseT *testing.T) {
+	parseCache := buildNonceCache(2)
+	parseNow := time.Now()
+	parseExpiresAt := parseNow.Add(time.Second)
+	if parseErr := parseCache.storeNonce("a", parseExpiresAt, parseNow); parseErr != nil {
+		parseT.Fatalf("storeNonce(a) error: %v", parseErr)
+	}
+	if parseErr := parseCache.storeNonce("b", parseExpiresAt, parseNow); parseErr != nil {
+		parseT.Fatalf("storeNonce(b) error: %v", parseErr)
+	}
+	if parseErr := parseCache.storeNonce("c", parseExpiresAt, parseNow); !errors.Is(parseErr, ErrReplayCacheFull) {
+		parseT.Fatalf("storeNonce(full) error = %v, want %v", parseErr, ErrReplayCacheFull)
+	}
+	if parseErr := parseCache.storeNonce("c", parseExpiresAt.Add(time.Second), parseExpiresAt); parseErr != nil {
+		parseT.Fatalf("storeNonce(after expiry) error: %v", parseErr)
+	}
+	if len(parseCache.storeNonces) != 1 {
+		parseT.Fatalf("cache entries = %d, want 1", len(parseCache.storeNonces))
+	}
+}
+
+// TestManager_AuthenticateTunnel verifies a ticket presented through subprotocols reaches RPC handlers once.
+func TestManager_AuthenticateTunnel(parseT *testing.T) {
+	parseManager := buildTestManager(parseT, Config{})
+
+	var parseSubject atomic.Value
+	parseGrpcServer := grpc.NewServer(grpc.UnaryInterceptor(func(parseCtx context.Context, parseReq any, parseInfo *grpc.UnaryServerInfo, handleRPC grpc.UnaryHandler) (any, error) {
+		if parseClaims, isFoundClaims := ClaimsFromContext(parseCtx); isFoundClaims {
+			parseSubject.Store(parseClaims.Subject)
+		}
+		return handleRPC(parseCtx, parseReq)
+	}))
+	grpc_health_v1.RegisterHealthServer(parseGrpcServer, health.NewServer())
+	defer parseGrpcServer.Stop()
+	parseHandler, parseErr := grpctunnel.BuildBridgeHandler(parseGrpcServer, grpctunnel.BridgeConfig{
+		Subprotocols: []string{Subprotocol},
+		Authenticate: parseManager.Authenticate,
+	})
+	if parseErr != nil {
+		parseT.Fatalf("BuildBridgeHandler() error: %v", parseErr)
+	}
+	parseServer := httptest.NewServer(parseHandler)
+	defer parseServer.Close()
+
+	parseTicket, _, parseErr := parseManager.Issue("alice", nil)
+	if parseErr != nil {
+		parseT.Fatalf("Issue() error: %v", parseErr)
+	}
+	parseCtx, clearCtx := context.WithTimeout(context.Background(), 5*time.Second)
+	defer clearCtx()
+	parseConn, parseErr := grpctunnel.BuildTunnelConn(parseCtx, grpctunnel.TunnelConfig{
+		Target:       "ws" + strings.TrimPrefix(parseServer.URL, "http"),
+		Subprotocols: BuildSubprotocols(parseTicket),
+		GRPCOptions:  []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())},
+	})
+	if parseErr != nil {
+		parseT.Fatalf("BuildTunnelConn() error: %v", parseErr)
+	}
+	defer parseConn.Close()
+	if _, parseErr = grpc_health_v1.NewHealthClient(parseConn).Check(parseCtx, &grpc_health_v1.HealthCheckRequest{}); parseErr != nil {
+		parseT.Fatalf("Check() error: %v", parseErr)
+	}
+	if parseValue, _ := parseSubject.Load().(string); parseValue != "alice" {
+		parseT.Fatalf("RPC subject = %q, want %q", parseValue, "alice")
+	}
+
+	parseRequest := httptest.NewRequest(http.MethodGet, "/grpc", nil)
+	parseRequest.Header.Set("Sec-WebSocket-Protocol", strings.Join(BuildSubprotocols(parseTicket), ", "))
+	if _, parseErr = parseManager.Authenticate(parseRequest); !errors.Is(parseErr, ErrTicketReplayed) {
+		parseT.Fatalf("Authenticate(replay) error = %v, want %v", parseErr, ErrTicketReplayed)
+	}
+}
@@ -0,0 +1,246 @@
+//go:build !js && !wasm
+
+package grpctunnel
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/monstercameron/grpc-tunnel/examples/_shared/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// storeAuthTestPrincipalKey stores the authenticated principal on tunnel contexts in tests.
+type storeAuthTestPrincipalKey struct{}
+
+// buildAuthTestAuthenticator accepts a bearer token of "good", forbids "blocked", and rejects everything else.
+func buildAuthTestAuthenticator(parseR *http.Request) (context.Context, error) {
+	switch parseR.Header.Get("Authorization") {
+	case "Bearer good":
+		return context.WithValue(parseR.Context(), storeAuthTestPrincipalKey{}, "alice"), nil
+	case "Bearer blocked":
+		return nil, fmt.Errorf("principal blocked: %w", ErrPermissionDenied)
+	default:
+		return nil, ErrUnauthenticated
+	}
+}
+
+// buildAuthTestInterceptor records the principal read through the supplied lookup.
+func buildAuthTestInterceptor(parsePrincipal *atomic.Value, getContext func(context.Context) context.Context) grpc.UnaryServerInterceptor {
+	return func(parseCtx context.Context, parseReq any, parseInfo *grpc.UnaryServerInfo, handleRPC grpc.UnaryHandler) (any, error) {
+		if parseValue, isFoundValue := getContext(parseCtx).Value(storeAuthTestPrincipalKey{}).(string); isFoundValue {
+			parsePrincipal.Store(parseValue)
+		}
+		return handleRPC(parseCtx, parseReq)
+	}
+}
+
+// callAuthTestTunnel dials a tunnel with a bearer token and runs one CreateTodo.
+func callAuthTestTunnel(parseT *testing.T, parseWsURL string) {
+	parseT.Helper()
+
+	parseCtx, clearCtx := context.WithTimeout(context.Background(), 5*time.Second)
+	defer clearCtx()
+	parseConn, parseErr := BuildTunnelConn(parseCtx, TunnelConfig{
+		Target:      parseWsURL,
+		Headers:     http.Header{"Authorization": []string{"Bearer good"}},
+		GRPCOptions: []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())},
+	})
+	if parseErr != nil {
+		parseT.Fatalf("BuildTunnelConn() error: %v", parseErr)
+	}
+	defer parseConn.Close()
+	if _, parseErr = proto.NewTodoServiceClient(parseConn).CreateTodo(parseCtx, &proto.CreateTodoRequest{Text: "auth"}); parseErr != nil {
+		parseT.Fatalf("CreateTodo() error: %v", parseErr)
+	}
+}
+
+// TestBuildBridgeHandler_AuthenticateRejectsUpgrade verifies failed authentication returns 401 or 403 before upgrade.
+func TestBuildBridgeHandler_AuthenticateRejectsUpgrade(parseT *testing.T) {
+	parseHandler, parseErr := BuildBridgeHandler(grpc.NewServer(), BridgeConfig{Authenticate: buildAuthTestAuthenticator})
+	if parseErr != nil {
+		parseT.Fatalf("BuildBridgeHandler() error: %v", parseErr)
+	}
+
+	parseTests := []struct {
+		parseAuthorization string
+		parseWantStatus    int
+	}{
+		{parseAuthorization: "", parseWantStatus: http.StatusUnauthorized},
+		{parseAuthorization: "Bearer blocked", parseWantStatus: http.StatusForbidden},
+	}
+	for _, parseTestCase := range parseTests {
+		parseRequest := httptest.NewRequest(http.MethodGet, "/grpc", nil)
+		parseRequest.Header.Set("Authorization", parseTestCase.parseAuthorization)
+		parseRecorder := httptest.NewRecorder()
+		parseHandler.ServeHTTP(parseRecorder, parseRequest)
+		if parseRecorder.Code != parseTestCase.parseWantStatus {
+			parseT.Fatalf("ServeHTTP(%q) status = %d, want %d", parseTestCase.parseAuthorization, parseRecorder.Code, parseTestCase.parseWantStatus)
+		}
+	}
+
+	parseWrapped := Wrap(grpc.NewServer(), WithAuthenticator(buildAuthTestAuthenticator))
+	parseRecorder := httptest.NewRecorder()
+	parseWrapped.ServeHTTP(parseRecorder, httptest.NewRequest(http.MethodGet, "/grpc", nil))
+	if parseRecorder.Code != http.StatusUnauthorized {
+		parseT.Fatalf("Wrap() status = %d, want %d", parseRecorder.Code, http.StatusUnauthorized)
+	}
+}
+
+// TestBuildBridgeHandler_AuthenticateContextReachesRPC verifies the Authenticate context is the base of every tunnel RPC.
+func TestBuildBridgeHandler_AuthenticateContextReachesRPC(parseT *testing.T) {
+	var parsePrincipal atomic.Value
+	parseGrpcServer := grpc.NewServer(grpc.UnaryInterceptor(buildAuthTestInterceptor(&parsePrincipal, func(parseCtx context.Context) context.Context {
+		return parseCtx
+	})))
+	proto.RegisterTodoServiceServer(parseGrpcServer, &mockService{})
+	defer parseGrpcServer.Stop()
+
+	parseHandler, parseErr := BuildBridgeHandler(parseGrpcServer, BridgeConfig{Authenticate: buildAuthTestAuthenticator})
+	if parseErr != nil {
+		parseT.Fatalf("BuildBridgeHandler() error: %v", parseErr)
+	}
+	parseServer := httptest.NewServer(parseHandler)
+	defer parseServer.Close()
+
+	callAuthTestTunnel(parseT, "ws"+strings.TrimPrefix(parseServer.URL, "http"))
+	if parseValue, _ := parsePrincipal.Load().(string); parseValue != "alice" {
+		parseT.Fatalf("RPC principal = %q, want %q", parseValue, "alice")
+	}
+}
+
+// TestNewListener_AuthContextFromPeer verifies listener tunnels expose the Authenticate context through peer info.
+func TestNewListener_AuthContextFromPeer(parseT *testing.T) {
+	var parsePrincipal atomic.Value
+	parseInterceptor := buildAuthTestInterceptor(&parsePrincipal, func(parseCtx context.Context) context.Context {
+		parseAuthContext, isFoundAuth := AuthContextFromPeer(parseCtx)
+		if !isFoundAuth {
+			return parseCtx
+		}
+		return parseAuthContext
+	})
+	_, _, parseWsURL, clearServer := buildListenerTestServer(parseT, BridgeConfig{Authenticate: buildAuthTestAuthenticator}, grpc.UnaryInterceptor(parseInterceptor))
+	defer clearServer()
+
+	callAuthTestTunnel(parseT, parseWsURL)
+	if parseValue, _ := parsePrincipal.Load().(string); parseValue != "alice" {
+		parseT.Fatalf("RPC principal = %q, want %q", parseValue, "alice")
+	}
+	if _, isFoundAuth := AuthContextFromPeer(context.Background()); isFoundAuth {
+		parseT.Fatal("AuthContextFromPeer() found a context without peer info")
+	}
+}
+
+// TestNewListener_AuthExpiryGraceClosesTunnel verifies an expired listener tunnel keeps serving
+// in-flight RPCs until AuthExpiryGrace has passed and is closed then.
+func TestNewListener_AuthExpiryGraceClosesTunnel(parseT *testing.T) {
+	_, _, parseWsURL, clearServer := buildListenerTestServer(parseT, BridgeConfig{
+		Authenticate: func(parseR *http.Request) (context.Context, error) {
+			parseAuthCtx, clearAuth := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			parseT.Cleanup(clearAuth)
+			return parseAuthCtx, nil
+		},
+		AuthExpiryGrace: 300 * time.Millisecond,
+	})
+	defer clearServer()
+
+	parseCtx, clearCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer clearCtx()
+	parseConn, parseErr := DialContext(parseCtx, parseWsURL, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if parseErr != nil {
+		parseT.Fatalf("DialContext() error: %v", parseErr)
+	}
+	defer parseConn.Close()
+
+	parseStream, parseErr := proto.NewTodoServiceClient(parseConn).SyncTodos(parseCtx)
+	if parseErr != nil {
+		parseT.Fatalf("SyncTodos() error: %v", parseErr)
+	}
+	parseRequest := &proto.SyncRequest{Action: &proto.SyncRequest_Create{Create: &proto.CreateTodoRequest{Text: "sync"}}}
+	if parseErr := parseStream.Send(parseRequest); parseErr != nil {
+		parseT.Fatalf("Send() error: %v", parseErr)
+	}
+	if _, parseErr := parseStream.Recv(); parseErr != nil {
+		parseT.Fatalf("Recv() error: %v", parseErr)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	if parseErr := parseStream.Send(parseRequest); parseErr != nil {
+		parseT.Fatalf("Send() during grace error: %v", parseErr)
+	}
+	if _, parseErr := parseStream.Recv(); parseErr != nil {
+		parseT.Fatalf("Recv() during grace error: %v", parseErr)
+	}
+	if _, parseErr := parseStream.Recv(); status.Code(parseErr) != codes.Unavailable {
+		parseT.Fatalf("Recv() after grace error = %v, want Unavailable", parseErr)
+	}
+}
+
+// TestBuildBridgeHandler_AuthExpiryGraceClosesTunnel verifies RPCs still running AuthExpiryGrace
+// after the Authenticate context ends are cut off.
+func TestBuildBridgeHandler_AuthExpiryGraceClosesTunnel(parseT *testing.T) {
+	if parseErr := GetBridgeConfigError(BridgeConfig{AuthExpiryGrace: -time.Second}); parseErr == nil {
+		parseT.Fatal("GetBridgeConfigError(negative AuthExpiryGrace) = nil, want error")
+	}
+
+	parseGrpcServer := grpc.NewServer()
+	proto.RegisterTodoServiceServer(parseGrpcServer, &mockService{})
+	defer parseGrpcServer.Stop()
+
+	parseHandler, parseErr := BuildBridgeHandler(parseGrpcServer, BridgeConfig{
+		Authenticate: func(parseR *http.Request) (context.Context, error) {
+			parseAuthCtx, clearAuth := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			parseT.Cleanup(clearAuth)
+			return parseAuthCtx, nil
+		},
+		AuthExpiryGrace: 300 * time.Millisecond,
+	})
+	if parseErr != nil {
+		parseT.Fatalf("BuildBridgeHandler() error: %v", parseErr)
+	}
+	parseServer := httptest.NewServer(parseHandler)
+	defer parseServer.Close()
+
+	parseCtx, clearCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer clearCtx()
+	parseConn, parseErr := BuildTunnelConn(parseCtx, TunnelConfig{
+		Target:      "ws" + strings.TrimPrefix(parseServer.URL, "http"),
+		GRPCOptions: []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())},
+	})
+	if parseErr != nil {
+		parseT.Fatalf("BuildTunnelConn() error: %v", parseErr)
+	}
+	defer parseConn.Close()
+
+	parseStream, parseErr := proto.NewTodoServiceClient(parseConn).SyncTodos(parseCtx)
+	if parseErr != nil {
+		parseT.Fatalf("SyncTodos() error: %v", parseErr)
+	}
+	if parseErr := parseStream.Send(&proto.SyncRequest{Action: &proto.SyncRequest_Create{Create: &proto.CreateTodoRequest{Text: "sync"}}}); parseErr != nil {
+		parseT.Fatalf("Send() error: %v", parseErr)
+	}
+	if _, parseErr := parseStream.Recv(); parseErr != nil {
+		parseT.Fatalf("Recv() error: %v", parseErr)
+	}
+
+	// The stream survives the expiry GOAWAY and is closed only when the grace period ends.
+	time.Sleep(300 * time.Millisecond)
+	if parseErr := parseStream.Send(&proto.SyncRequest{Action: &proto.SyncRequest_Create{Create: &proto.CreateTodoRequest{Text: "sync"}}}); parseErr != nil {
+		parseT.Fatalf("Send() during grace error: %v", parseErr)
+	}
+	if _, parseErr := parseStream.Recv(); parseErr != nil {
+		parseT.Fatalf("Recv() during grace error: %v", parseErr)
+	}
+	if _, parseErr := parseStream.Recv(); status.Code(parseErr) != codes.Unavailable {
+		parseT.Fatalf("Recv() after grace error = %v, want Unavailable", parseErr)
+	}
+}
@@ -0,0 +1,49 @@
+//go:build !js && !wasm
+
+package grpctunnel
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const parseAuthorizeDeniedMessage = "tunnel authorization denied"
+
+// getBridgeAuthorizeStatus maps an Authorize error to the gRPC status returned to the client.
+// gRPC status errors pass through; ErrUnauthenticated maps to Unauthenticated and anything else to PermissionDenied.
+func getBridgeAuthorizeStatus(parseErr error) *status.Status {
+	if parseStatus, isStatusError := status.FromError(parseErr); isStatusError && parseStatus.Code() != codes.OK {
+		return parseStatus
+	}
+	if errors.Is(parseErr, ErrUnauthenticated) {
+		return status.New(codes.Unauthenticated, parseAuthorizeDeniedMessage)
+	}
+	return status.New(codes.PermissionDenied, parseAuthorizeDeniedMessage)
+}
+
+// writeBridgeRPCStatus answers a tunneled RPC with a trailers-only gRPC status.
+func writeBridgeRPCStatus(parseW http.ResponseWriter, parseStatus *status.Status) {
+	parseHeader := parseW.Header()
+	parseHeader.Set("Content-Type", "application/grpc")
+	parseHeader.Set("Grpc-Status", strconv.Itoa(int(parseStatus.Code())))
+	parseHeader.Set("Grpc-Message", encodeBridgeGrpcMessage(parseStatus.Message()))
+	parseW.WriteHeader(http.StatusOK)
+}
+
+// encodeBridgeGrpcMessage percent-encodes a grpc-message value as required by the gRPC HTTP/2 protocol.
+func encodeBridgeGrpcMessage(parseMessage string) string {
+	parseEncoded := make([]byte, 0, len(parseMessage))
+	for parseIndex := 0; parseIndex < len(parseMessage); parseIndex++ {
+		parseChar := parseMessage[parseIndex]
+		if parseChar >= ' ' && parseChar <= '~' && parseChar != '%' {
+			parseEncoded = append(parseEncoded, parseChar)
+			continue
+		}
+		parseEncoded = append(parseEncoded, '%', "0123456789ABCDEF"[parseChar>>4], "0123456789ABCDEF"[parseChar&0xF])
+	}
+	return string(parseEncoded)
+}
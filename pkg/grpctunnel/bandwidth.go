@@ -0,0 +1,281 @@
+//go:build !js && !wasm
+
+package grpctunnel
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const parseBridgeBandwidthScopeGlobal = "global"
+const parseBridgeBandwidthScopeClient = "client"
+const parseBridgeBandwidthScopeMethod = "method"
+const parseBridgeBandwidthDirectionRead = "read"
+const parseBridgeBandwidthDirectionWrite = "write"
+
+// getBridgeBandwidthLimitsError validates bandwidth limits.
+func getBridgeBandwidthLimitsError(parseLimits BandwidthLimits) error {
+	if parseErr := getBridgeRateLimitError("BandwidthLimits.Global", parseLimits.Global); parseErr != nil {
+		return parseErr
+	}
+	if parseErr := getBridgeRateLimitError("BandwidthLimits.PerClient", parseLimits.PerClient); parseErr != nil {
+		return parseErr
+	}
+	for parseMethod, parseLimit := range parseLimits.Methods {
+		if !strings.HasPrefix(parseMethod, "/") {
+			return fmt.Errorf("grpctunnel: BandwidthLimits.Methods key %q must start with /", parseMethod)
+		}
+		if parseErr := getBridgeRateLimitError("BandwidthLimits.Methods["+parseMethod+"]", parseLimit); parseErr != nil {
+			return parseErr
+		}
+	}
+	return nil
+}
+
+// bridgeBandwidthLimiter paces bytes through one token bucket. The bucket may go into debt, so
+// messages larger than the burst still pass at the configured rate.
+type bridgeBandwidthLimiter struct {
+	setLimiterLock sync.Mutex
+	getLimit       RateLimit
+	getScope       string
+	getBucket      bridgeTokenBucket
+}
+
+// reserveBridgeBandwidth takes tokens for a number of bytes and returns how long the caller must wait.
+func (parseLimiter *bridgeBandwidthLimiter) reserveBridgeBandwidth(parseBytes int, parseNow time.Time) time.Duration {
+	parseLimiter.setLimiterLock.Lock()
+	defer parseLimiter.setLimiterLock.Unlock()
+	return parseLimiter.getBucket.reserveBridgeTokenBucket(parseLimiter.getLimit, parseNow, float64(parseBytes))
+}
+
+// bridgeBandwidthLimiters holds the read and write limiters of one scope. Nil entries are unlimited.
+type bridgeBandwidthLimiters struct {
+	getRead  *bridgeBandwidthLimiter
+	getWrite *bridgeBandwidthLimiter
+}
+
+// buildBridgeBandwidthLimiters creates read and write limiters, or none when the limit is disabled.
+func buildBridgeBandwidthLimiters(parseLimit RateLimit, parseScope string) bridgeBandwidthLimiters {
+	if parseLimit.PerSecond <= 0 {
+		return bridgeBandwidthLimiters{}
+	}
+	return bridgeBandwidthLimiters{
+		getRead:  &bridgeBandwidthLimiter{getLimit: parseLimit, getScope: parseScope},
+		getWrite: &bridgeBandwidthLimiter{getLimit: parseLimit, getScope: parseScope},
+	}
+}
+
+// waitBridgeBandwidth reserves bytes from every limiter and sleeps for the longest delay, recording it
+// as throttle time of the limiting scope. It reports false when done closed before the delay ended.
+func waitBridgeBandwidth(parseContext context.Context, parseObservability *bridgeObservability, parseLimiters []*bridgeBandwidthLimiter, parseDirection string, parseBytes int, parseDone <-chan struct{}) bool {
+	if parseBytes <= 0 {
+		return true
+	}
+	parseNow := time.Now()
+	parseWait := time.Duration(0)
+	parseScope := ""
+	for _, parseLimiter := range parseLimiters {
+		if parseLimiter == nil {
+			continue
+		}
+		if parseDelay := parseLimiter.reserveBridgeBandwidth(parseBytes, parseNow); parseDelay > parseWait {
+			parseWait = parseDelay
+			parseScope = parseLimiter.getScope
+		}
+	}
+	if parseWait <= 0 {
+		return true
+	}
+
+	parseObservability.storeBridgeBandwidthThrottle(parseContext, parseScope, parseDirection, parseWait)
+	parseTimer := time.NewTimer(parseWait)
+	defer parseTimer.Stop()
+	select {
+	case <-parseTimer.C:
+		return true
+	case <-parseDone:
+		return false
+	}
+}
+
+// bridgeBandwidthClient holds the limiters shared by the live tunnels of one client key.
+type bridgeBandwidthClient struct {
+	getLimiters bridgeBandwidthLimiters
+	getTunnels  int
+}
+
+// bridgeBandwidthGuard owns the global and per-client bandwidth limiters of a handler.
+type bridgeBandwidthGuard struct {
+	getLimits        BandwidthLimits
+	getGlobal        bridgeBandwidthLimiters
+	getObservability *bridgeObservability
+	setClientLock    sync.Mutex
+	storeClients     map[string]*bridgeBandwidthClient
+}
+
+// buildBridgeBandwidthGuard creates a bandwidth guard, or nil when no bandwidth limit is configured.
+func buildBridgeBandwidthGuard(parseLimits BandwidthLimits, parseObservability *bridgeObservability) *bridgeBandwidthGuard {
+	if parseLimits.Global.PerSecond <= 0 && parseLimits.PerClient.PerSecond <= 0 && len(parseLimits.Methods) == 0 {
+		return nil
+	}
+	return &bridgeBandwidthGuard{
+		getLimits:        parseLimits,
+		getGlobal:        buildBridgeBandwidthLimiters(parseLimits.Global, parseBridgeBandwidthScopeGlobal),
+		getObservability: parseObservability,
+		storeClients:     map[string]*bridgeBandwidthClient{},
+	}
+}
+
+// reserveBridgeClientBandwidth returns the limiters of a client key, creating them for its first tunnel.
+func (parseGuard *bridgeBandwidthGuard) reserveBridgeClientBandwidth(parseClientKey string) bridgeBandwidthLimiters {
+	if parseGuard.getLimits.PerClient.PerSecond <= 0 {
+		return bridgeBandwidthLimiters{}
+	}
+	parseGuard.setClientLock.Lock()
+	defer parseGuard.setClientLock.Unlock()
+	parseClient, isFoundClient := parseGuard.storeClients[parseClientKey]
+	if !isFoundClient {
+		parseClient = &bridgeBandwidthClient{getLimiters: buildBridgeBandwidthLimiters(parseGuard.getLimits.PerClient, parseBridgeBandwidthScopeClient)}
+		parseGuard.storeClients[parseClientKey] = parseClient
+	}
+	parseClient.getTunnels++
+	return parseClient.getLimiters
+}
+
+// clearBridgeClientBandwidth forgets the limiters of a client key once its last tunnel ends.
+func (parseGuard *bridgeBandwidthGuard) clearBridgeClientBandwidth(parseClientKey string) {
+	if parseGuard.getLimits.PerClient.PerSecond <= 0 {
+		return
+	}
+	parseGuard.setClientLock.Lock()
+	defer parseGuard.setClientLock.Unlock()
+	parseClient, isFoundClient := parseGuard.storeClients[parseClientKey]
+	if !isFoundClient {
+		return
+	}
+	parseClient.getTunnels--
+	if parseClient.getTunnels <= 0 {
+		delete(parseGuard.storeClients, parseClientKey)
+	}
+}
+
+// bridgeConnBandwidth paces the reads and writes of one tunnel connection through the global and
+// per-client limiters.
+type bridgeConnBandwidth struct {
+	getGuard       *bridgeBandwidthGuard
+	getContext     context.Context
+	getClientKey   string
+	getReaders     []*bridgeBandwidthLimiter
+	getWriters     []*bridgeBandwidthLimiter
+	getCloseSignal chan struct{}
+	clearCloseOnce sync.Once
+}
+
+// buildBridgeConnBandwidth creates the pacing state of one tunnel, or nil when neither a global nor a
+// per-client limit is configured.
+func (parseGuard *bridgeBandwidthGuard) buildBridgeConnBandwidth(parseContext context.Context, parseClientKey string) *bridgeConnBandwidth {
+	if parseGuard == nil || (parseGuard.getLimits.Global.PerSecond <= 0 && parseGuard.getLimits.PerClient.PerSecond <= 0) {
+		return nil
+	}
+	parseClientLimiters := parseGuard.reserveBridgeClientBandwidth(parseClientKey)
+	return &bridgeConnBandwidth{
+		getGuard:       parseGuard,
+		getContext:     parseContext,
+		getClientKey:   parseClientKey,
+		getReaders:     []*bridgeBandwidthLimiter{parseGuard.getGlobal.getRead, parseClientLimiters.getRead},
+		getWriters:     []*bridgeBandwidthLimiter{parseGuard.getGlobal.getWrite, parseClientLimiters.getWrite},
+		getCloseSignal: make(chan struct{}),
+	}
+}
+
+// waitBridgeConnBandwidth paces bytes moved in one direction. It reports false when the connection closed while waiting.
+func (parseBandwidth *bridgeConnBandwidth) waitBridgeConnBandwidth(parseDirection string, parseBytes int) bool {
+	if parseBandwidth == nil {
+		return true
+	}
+	parseLimiters := parseBandwidth.getWriters
+	if parseDirection == parseBridgeBandwidthDirectionRead {
+		parseLimiters = parseBandwidth.getReaders
+	}
+	return waitBridgeBandwidth(parseBandwidth.getContext, parseBandwidth.getGuard.getObservability, parseLimiters, parseDirection, parseBytes, parseBandwidth.getCloseSignal)
+}
+
+// clearBridgeConnBandwidth wakes blocked reads and writes and releases the per-client limiters. It is safe to call more than once.
+func (parseBandwidth *bridgeConnBandwidth) clearBridgeConnBandwidth() {
+	if parseBandwidth == nil {
+		return
+	}
+	parseBandwidth.clearCloseOnce.Do(func() {
+		close(parseBandwidth.getCloseSignal)
+		parseBandwidth.getGuard.clearBridgeClientBandwidth(parseBandwidth.getClientKey)
+	})
+}
+
+// bridgeBandwidthBody paces the request body of one RPC through its method limiter.
+type bridgeBandwidthBody struct {
+	io.ReadCloser
+	getRequest *http.Request
+	getGuard   *bridgeBandwidthGuard
+	getLimiter *bridgeBandwidthLimiter
+}
+
+// Read reads request bytes and then waits until the method rate allows them.
+func (parseBody *bridgeBandwidthBody) Read(parseP []byte) (int, error) {
+	parseN, parseErr := parseBody.ReadCloser.Read(parseP)
+	parseContext := parseBody.getRequest.Context()
+	if !waitBridgeBandwidth(parseContext, parseBody.getGuard.getObservability, []*bridgeBandwidthLimiter{parseBody.getLimiter}, parseBridgeBandwidthDirectionRead, parseN, parseContext.Done()) {
+		return parseN, parseContext.Err()
+	}
+	return parseN, parseErr
+}
+
+// bridgeBandwidthResponseWriter paces the response body of one RPC through its method limiter.
+type bridgeBandwidthResponseWriter struct {
+	http.ResponseWriter
+	getRequest *http.Request
+	getGuard   *bridgeBandwidthGuard
+	getLimiter *bridgeBandwidthLimiter
+}
+
+// Write waits until the method rate allows the bytes and then writes them.
+func (parseWriter *bridgeBandwidthResponseWriter) Write(parseP []byte) (int, error) {
+	parseContext := parseWriter.getRequest.Context()
+	if !waitBridgeBandwidth(parseContext, parseWriter.getGuard.getObservability, []*bridgeBandwidthLimiter{parseWriter.getLimiter}, parseBridgeBandwidthDirectionWrite, len(parseP), parseContext.Done()) {
+		return 0, parseContext.Err()
+	}
+	return parseWriter.ResponseWriter.Write(parseP)
+}
+
+// Flush flushes buffered response data, which gRPC requires of its ResponseWriter.
+func (parseWriter *bridgeBandwidthResponseWriter) Flush() {
+	if parseFlusher, isFlusher := parseWriter.ResponseWriter.(http.Flusher); isFlusher {
+		parseFlusher.Flush()
+	}
+}
+
+// Unwrap returns the wrapped ResponseWriter for http.ResponseController.
+func (parseWriter *bridgeBandwidthResponseWriter) Unwrap() http.ResponseWriter {
+	return parseWriter.ResponseWriter
+}
+
+// applyBridgeMethodBandwidth paces the bodies of one RPC when its method has a bandwidth limit.
+func (parseGuard *bridgeBandwidthGuard) applyBridgeMethodBandwidth(parseW http.ResponseWriter, parseRequest *http.Request) (http.ResponseWriter, *http.Request) {
+	if parseGuard == nil {
+		return parseW, parseRequest
+	}
+	parseLimit, isFoundMethod := parseGuard.getLimits.Methods[parseRequest.URL.Path]
+	if !isFoundMethod || parseLimit.PerSecond <= 0 {
+		return parseW, parseRequest
+	}
+	parseLimiters := buildBridgeBandwidthLimiters(parseLimit, parseBridgeBandwidthScopeMethod)
+	parseRequest = parseRequest.WithContext(parseRequest.Context())
+	if parseRequest.Body != nil {
+		parseRequest.Body = &bridgeBandwidthBody{ReadCloser: parseRequest.Body, getRequest: parseRequest, getGuard: parseGuard, getLimiter: parseLimiters.getRead}
+	}
+	return &bridgeBandwidthResponseWriter{ResponseWriter: parseW, getRequest: parseRequest, getGuard: parseGuard, getLimiter: parseLimiters.getWrite}, parseRequest
+}
@@ -0,0 +1,131 @@
+//go:build !js && !wasm
+
+package grpctunnel
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/monstercameron/grpc-tunnel/examples/_shared/proto"
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// TestBridgeBandwidthLimiter_Debt verifies writes larger than the burst pass after a proportional delay.
+func TestBridgeBandwidthLimiter_Debt(parseT *testing.T) {
+	parseLimiter := &bridgeBandwidthLimiter{getLimit: RateLimit{PerSecond: 1000, Burst: 1000}}
+	parseNow := time.Now()
+
+	if parseWait := parseLimiter.reserveBridgeBandwidth(1000, parseNow); parseWait != 0 {
+		parseT.Fatalf("reserve(burst) wait = %v, want 0", parseWait)
+	}
+	if parseWait := parseLimiter.reserveBridgeBandwidth(2500, parseNow); parseWait != 2500*time.Millisecond {
+		parseT.Fatalf("reserve(over burst) wait = %v, want 2.5s", parseWait)
+	}
+	if parseWait := parseLimiter.reserveBridgeBandwidth(500, parseNow.Add(3*time.Second)); parseWait != 0 {
+		parseT.Fatalf("reserve(after repayment) wait = %v, want 0", parseWait)
+	}
+}
+
+// TestGetBridgeConfigError_BandwidthLimits verifies bandwidth limit validation and the NewListener restriction.
+func TestGetBridgeConfigError_BandwidthLimits(parseT *testing.T) {
+	for _, parseLimits := range []BandwidthLimits{
+		{Global: RateLimit{PerSecond: -1}},
+		{PerClient: RateLimit{Burst: 10}},
+		{Methods: map[string]RateLimit{"TodoService/CreateTodo": {PerSecond: 1}}},
+	} {
+		if parseErr := GetBridgeConfigError(BridgeConfig{BandwidthLimits: parseLimits}); parseErr == nil {
+			parseT.Fatalf("GetBridgeConfigError(%+v) = nil, want error", parseLimits)
+		}
+	}
+	if _, _, parseErr := NewListener(BridgeConfig{BandwidthLimits: BandwidthLimits{Methods: map[string]RateLimit{"/TodoService/CreateTodo": {PerSecond: 1}}}}); parseErr == nil {
+		parseT.Fatal("NewListener() should reject BandwidthLimits.Methods")
+	}
+}
+
+// TestBuildBridgeHandler_BandwidthLimits verifies connection and method limits delay large messages
+// and report throttle time by scope.
+func TestBuildBridgeHandler_BandwidthLimits(parseT *testing.T) {
+	parseReader := sdkmetric.NewManualReader()
+	parseMeterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(parseReader))
+	parseOriginalMeterProvider := otel.GetMeterProvider()
+	otel.SetMeterProvider(parseMeterProvider)
+	defer otel.SetMeterProvider(parseOriginalMeterProvider)
+	defer func() {
+		_ = parseMeterProvider.Shutdown(context.Background())
+	}()
+
+	parseGrpcServer := grpc.NewServer()
+	proto.RegisterTodoServiceServer(parseGrpcServer, &mockService{})
+	defer parseGrpcServer.Stop()
+
+	parseHandler, parseErr := BuildBridgeHandler(parseGrpcServer, BridgeConfig{
+		BandwidthLimits: BandwidthLimits{
+			PerClient: RateLimit{PerSecond: 100_000, Burst: 10_000},
+			Methods:   map[string]RateLimit{proto.TodoService_CreateTodo_FullMethodName: {PerSecond: 50_000, Burst: 5_000}},
+		},
+	})
+	if parseErr != nil {
+		parseT.Fatalf("BuildBridgeHandler() error: %v", parseErr)
+	}
+	parseServer := httptest.NewServer(parseHandler)
+	defer parseServer.Close()
+
+	parseCtx, clearCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer clearCtx()
+	parseConn, parseErr := BuildTunnelConn(parseCtx, TunnelConfig{
+		Target:      "ws" + strings.TrimPrefix(parseServer.URL, "http"),
+		GRPCOptions: []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())},
+	})
+	if parseErr != nil {
+		parseT.Fatalf("BuildTunnelConn() error: %v", parseErr)
+	}
+	defer parseConn.Close()
+
+	// The request and the echoed response each carry 15 KB: the method limiter delays each by about
+	// 200ms beyond its 5 KB burst.
+	parseStart := time.Now()
+	parseResp, parseErr := proto.NewTodoServiceClient(parseConn).CreateTodo(parseCtx, &proto.CreateTodoRequest{Text: strings.Repeat("x", 15_000)})
+	if parseErr != nil {
+		parseT.Fatalf("CreateTodo() error: %v", parseErr)
+	}
+	if len(parseResp.GetTodo().GetText()) != 15_000 {
+		parseT.Fatalf("CreateTodo() text length = %d, want 15000", len(parseResp.GetTodo().GetText()))
+	}
+	if parseElapsed := time.Since(parseStart); parseElapsed < 300*time.Millisecond {
+		parseT.Fatalf("CreateTodo() took %v, want >= 300ms of throttling", parseElapsed)
+	}
+
+	parseResourceMetrics := metricdata.ResourceMetrics{}
+	if parseCollectErr := parseReader.Collect(context.Background(), &parseResourceMetrics); parseCollectErr != nil {
+		parseT.Fatalf("Collect() error: %v", parseCollectErr)
+	}
+	parseThrottle := getBridgeThrottleSecondsByScope(parseResourceMetrics)
+	if parseThrottle[parseBridgeBandwidthScopeMethod] <= 0 || parseThrottle[parseBridgeBandwidthScopeClient] <= 0 {
+		parseT.Fatalf("%s by scope = %v, want method and client throttle time", parseBridgeBandwidthThrottleMetric, parseThrottle)
+	}
+}
+
+// getBridgeThrottleSecondsByScope sums bandwidth throttle seconds per scope attribute.
+func getBridgeThrottleSecondsByScope(parseResourceMetrics metricdata.ResourceMetrics) map[string]float64 {
+	parseTotals := map[string]float64{}
+	for _, parseScopeMetrics := range parseResourceMetrics.ScopeMetrics {
+		for _, parseMetric := range parseScopeMetrics.Metrics {
+			parseSum, isSum := parseMetric.Data.(metricdata.Sum[float64])
+			if parseMetric.Name != parseBridgeBandwidthThrottleMetric || !isSum {
+				continue
+			}
+			for _, parseDataPoint := range parseSum.DataPoints {
+				parseScope, _ := parseDataPoint.Attributes.Value("scope")
+				parseTotals[parseScope.AsString()] += parseDataPoint.Value
+			}
+		}
+	}
+	return parseTotals
+}
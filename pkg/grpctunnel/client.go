@@ -27,6 +27,8 @@ type clientOptions struct {
 	setTunnelSubprotocols   []string
 	setTunnelProxy          func(*http.Request) (*url.URL, error)
 	setTunnelReconnect      *ReconnectConfig
+	setTunnelHTTP2Profile   HTTP2Profile
+	setTunnelHTTP2          HTTP2Settings
 	setTunnelTimeout        time.Duration
 	isUseTLS                bool
 	shouldEnableCompression bool
@@ -101,6 +103,14 @@ func WithReconnectPolicy(parseConfig ReconnectConfig) ClientOption {
 	}
 }
 
+// WithDialHTTP2Settings applies an HTTP/2 profile, with nonzero settings overriding it, to the client side of the tunnel.
+func WithDialHTTP2Settings(parseProfile HTTP2Profile, parseSettings HTTP2Settings) ClientOption {
+	return func(parseO *clientOptions) {
+		parseO.setTunnelHTTP2Profile = parseProfile
+		parseO.setTunnelHTTP2 = parseSettings
+	}
+}
+
 // splitDialOptions separates grpctunnel client options from grpc dial options.
 func splitDialOptions(parseOpts []interface{}) ([]ClientOption, []grpc.DialOption, error) {
 	var parseTunnelOpts []ClientOption
@@ -217,7 +227,7 @@ func getTunnelConfigErrorWithoutTarget(parseConfig TunnelConfig) error {
 			return parseErr
 		}
 	}
-	return nil
+	return getHTTP2SettingsError(parseConfig.HTTP2Profile, parseConfig.HTTP2)
 }
 
 // buildTunnelTargetURL normalizes websocket target URL from TunnelConfig.
@@ -255,6 +265,7 @@ func BuildTunnelConn(parseCtx context.Context, parseConfig TunnelConfig) (*grpc.
 		}
 		parseDialOptions = append(parseDialOptions, parseReconnectOptions...)
 	}
+	parseDialOptions = append(parseDialOptions, buildTunnelHTTP2DialOptions(parseConfig)...)
 	parseDialOptions = append(parseDialOptions, parseConfig.GRPCOptions...)
 	parseDialOptions = append(parseDialOptions, grpc.WithContextDialer(buildTunnelDialer(TunnelConfig{
 		Target:                  parseTunnelURL,
@@ -332,6 +343,8 @@ func DialContext(parseCtx context.Context, parseTarget string, parseOpts ...inte
 		HandshakeTimeout:        parseTunnelOptions.setTunnelTimeout,
 		ShouldEnableCompression: parseTunnelOptions.shouldEnableCompression,
 		ReconnectConfig:         parseTunnelOptions.setTunnelReconnect,
+		HTTP2Profile:            parseTunnelOptions.setTunnelHTTP2Profile,
+		HTTP2:                   parseTunnelOptions.setTunnelHTTP2,
 		GRPCOptions:             parseGrpcOpts,
 	})
 }
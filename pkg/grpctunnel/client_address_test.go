@@ -0,0 +1,96 @@
+//go:build !js && !wasm
+
+package grpctunnel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/grpc"
+)
+
+// TestResolveBridgeClientRequest verifies forwarding headers are honored only from trusted proxies.
+func TestResolveBridgeClientRequest(parseT *testing.T) {
+	parseTrustedProxies, parseErr := parseBridgeTrustedProxies([]string{"10.0.0.0/8", "192.0.2.10"})
+	if parseErr != nil {
+		parseT.Fatalf("parseBridgeTrustedProxies() error: %v", parseErr)
+	}
+
+	parseTests := []struct {
+		parseName       string
+		parseRemoteAddr string
+		parseHeaders    map[string]string
+		parseWantAddr   string
+	}{
+		{parseName: "untrusted peer ignores headers", parseRemoteAddr: "203.0.113.9:4000", parseHeaders: map[string]string{"X-Forwarded-For": "198.51.100.1"}, parseWantAddr: "203.0.113.9:4000"},
+		{parseName: "x-forwarded-for", parseRemoteAddr: "10.1.2.3:4000", parseHeaders: map[string]string{"X-Forwarded-For": "198.51.100.1"}, parseWantAddr: "198.51.100.1:0"},
+		{parseName: "spoofed prefix skipped", parseRemoteAddr: "10.1.2.3:4000", parseHeaders: map[string]string{"X-Forwarded-For": "1.1.1.1, 198.51.100.1, 10.9.9.9"}, parseWantAddr: "198.51.100.1:0"},
+		{parseName: "forwarded precedence", parseRemoteAddr: "192.0.2.10:4000", parseHeaders: map[string]string{"Forwarded": `for="[2001:db8::7]:4711";proto=https, for=10.0.0.1`, "X-Forwarded-For": "198.51.100.1"}, parseWantAddr: "[2001:db8::7]:4711"},
+		{parseName: "obfuscated hop stops at proxy", parseRemoteAddr: "10.1.2.3:4000", parseHeaders: map[string]string{"Forwarded": "for=_hidden, for=10.0.0.5"}, parseWantAddr: "10.0.0.5:0"},
+		{parseName: "no headers", parseRemoteAddr: "10.1.2.3:4000", parseWantAddr: "10.1.2.3:4000"},
+	}
+	for _, parseTestCase := range parseTests {
+		parseRequest := httptest.NewRequest(http.MethodGet, "/grpc", nil)
+		parseRequest.RemoteAddr = parseTestCase.parseRemoteAddr
+		for parseKey, parseValue := range parseTestCase.parseHeaders {
+			parseRequest.Header.Set(parseKey, parseValue)
+		}
+		if parseAddr := resolveBridgeClientRequest(parseRequest, parseTrustedProxies).RemoteAddr; parseAddr != parseTestCase.parseWantAddr {
+			parseT.Fatalf("%s: RemoteAddr = %q, want %q", parseTestCase.parseName, parseAddr, parseTestCase.parseWantAddr)
+		}
+	}
+
+	if _, parseErr = parseBridgeTrustedProxies([]string{"10.0.0.0/33"}); parseErr == nil {
+		parseT.Fatal("expected error for invalid TrustedProxies entry")
+	}
+	if parseErr = GetBridgeConfigError(BridgeConfig{ShouldAcceptProxyProtocol: true}); parseErr == nil {
+		parseT.Fatal("expected error for ShouldAcceptProxyProtocol without TrustedProxies")
+	}
+}
+
+// TestBuildBridgeHandler_TrustedProxyClientKey verifies per-client limits key on the forwarded client address.
+func TestBuildBridgeHandler_TrustedProxyClientKey(parseT *testing.T) {
+	parseGrpcServer := grpc.NewServer()
+	defer parseGrpcServer.Stop()
+	parseHandler, parseErr := BuildBridgeHandler(parseGrpcServer, BridgeConfig{
+		TrustedProxies:          []string{"127.0.0.1", "::1"},
+		MaxConnectionsPerClient: 1,
+	})
+	if parseErr != nil {
+		parseT.Fatalf("BuildBridgeHandler() error: %v", parseErr)
+	}
+	parseServer := httptest.NewServer(parseHandler)
+	defer parseServer.Close()
+	parseWsURL := "ws" + strings.TrimPrefix(parseServer.URL, "http")
+
+	dialClient := func(parseClientIP string) (*websocket.Conn, int) {
+		parseConn, parseResponse, parseErr := websocket.DefaultDialer.Dial(parseWsURL, http.Header{"X-Forwarded-For": []string{parseClientIP}})
+		if parseErr != nil {
+			if parseResponse == nil {
+				parseT.Fatalf("Dial(%s) error: %v", parseClientIP, parseErr)
+			}
+			return nil, parseResponse.StatusCode
+		}
+		return parseConn, http.StatusSwitchingProtocols
+	}
+
+	parseFirst, parseStatus := dialClient("198.51.100.1")
+	if parseFirst == nil {
+		parseT.Fatalf("first client status = %d, want upgrade", parseStatus)
+	}
+	defer parseFirst.Close()
+	parseSecond, parseStatus := dialClient("198.51.100.2")
+	if parseSecond == nil {
+		parseT.Fatalf("second client status = %d, want upgrade", parseStatus)
+	}
+	defer parseSecond.Close()
+	if parseRepeat, parseStatus := dialClient("198.51.100.1"); parseRepeat != nil || parseStatus != http.StatusTooManyRequests {
+		if parseRepeat != nil {
+			parseRepeat.Close()
+		}
+		parseT.Fatalf("repeat client status = %d, want %d", parseStatus, http.StatusTooManyRequests)
+	}
+}
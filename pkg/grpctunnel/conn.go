@@ -3,6 +3,7 @@
 package grpctunnel
 
 import (
+	"errors"
 	"io"
 	"net"
 	"sync"
@@ -10,24 +11,41 @@ import (
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/membudget"
 )
 
 // webSocketConn adapts a WebSocket connection to net.Conn interface.
 // This is needed because gRPC expects a net.Conn but browsers only have WebSocket.
 type webSocketConn struct {
-	ws         *websocket.Conn
-	reader     io.Reader
-	readMu     sync.Mutex
-	closeOnce  sync.Once
-	isClosed   atomic.Bool
-	writeMu    sync.Mutex
-	deadlineMu sync.Mutex // Protects deadline operations
+	ws                *websocket.Conn
+	reader            io.Reader
+	readMu            sync.Mutex
+	closeOnce         sync.Once
+	isClosed          atomic.Bool
+	writeMu           sync.Mutex
+	deadlineMu        sync.Mutex // Protects deadline operations
+	bandwidth         *bridgeConnBandwidth
+	slowClient        *bridgeSlowClientGuard
+	memory            *membudget.Tunnel
+	storeBytesRead    atomic.Int64
+	storeBytesWritten atomic.Int64
+	storeLastActivity atomic.Int64 // Unix nanoseconds of the last read or write, or zero.
+	storeCloseCause   atomic.Pointer[TunnelCloseCause]
 }
 
 func newWebSocketConn(parseWs *websocket.Conn) net.Conn {
 	return &webSocketConn{ws: parseWs}
 }
 
+// newBridgeWebSocketConn adapts a bridge-side websocket whose reads and writes are paced by bandwidth limits,
+// closed by slow-client limits, and charged to the memory budget.
+func newBridgeWebSocketConn(parseWs *websocket.Conn, parseBandwidth *bridgeConnBandwidth, parseSlowClient *bridgeSlowClientGuard, parseMemory *membudget.Tunnel) *webSocketConn {
+	parseConn := &webSocketConn{ws: parseWs, bandwidth: parseBandwidth, slowClient: parseSlowClient, memory: parseMemory}
+	parseSlowClient.startBridgeSlowClientGuard(parseConn.Close)
+	parseMemory.Start(parseConn.writeBridgeWindowCredit)
+	return parseConn
+}
+
 // Read reads binary payload bytes from the underlying WebSocket stream.
 func (parseC *webSocketConn) Read(parseP []byte) (int, error) {
 	parseC.readMu.Lock()
@@ -44,23 +62,35 @@ func (parseC *webSocketConn) Read(parseP []byte) (int, error) {
 		if parseC.reader == nil {
 			parseMessageType, parseReader, parseErr := parseC.ws.NextReader()
 			if parseErr != nil {
+				parseC.storeBridgeReadError(parseErr)
 				return 0, parseErr
 			}
 			if parseMessageType != websocket.BinaryMessage {
+				parseC.storeBridgeReadError(io.EOF)
 				return 0, io.EOF
 			}
 			parseC.reader = parseReader
+			parseC.slowClient.storeBridgeMessageStart()
 		}
 
 		parseN, parseErr2 := parseC.reader.Read(parseP)
+		if parseN > 0 {
+			parseC.storeBytesRead.Add(int64(parseN))
+			parseC.storeLastActivity.Store(time.Now().UnixNano())
+		}
+		parseC.slowClient.storeBridgeReadBytes(parseN)
+		parseC.memory.ObserveRead(parseP[:parseN])
 		if parseErr2 == io.EOF {
 			parseC.reader = nil
-			if parseN > 0 {
-				return parseN, nil
+			parseC.slowClient.storeBridgeMessageEnd()
+			if parseN == 0 {
+				// Empty frame: continue draining subsequent frames until payload arrives.
+				continue
 			}
-			// Empty frame: continue draining subsequent frames until payload arrives.
-			continue
+			parseErr2 = nil
 		}
+		// Pacing after the read holds back the next one, so TCP backpressure throttles the peer.
+		parseC.bandwidth.waitBridgeConnBandwidth(parseBridgeBandwidthDirectionRead, parseN)
 		return parseN, parseErr2
 	}
 }
@@ -77,17 +107,86 @@ func (parseC *webSocketConn) Write(parseP []byte) (int, error) {
 		return 0, io.ErrClosedPipe
 	}
 
-	if parseErr := parseC.ws.WriteMessage(websocket.BinaryMessage, parseP); parseErr != nil {
+	if !parseC.bandwidth.waitBridgeConnBandwidth(parseBridgeBandwidthDirectionWrite, len(parseP)) {
+		return 0, io.ErrClosedPipe
+	}
+	if parseDeadline := parseC.slowClient.getBridgeWriteDeadline(); !parseDeadline.IsZero() {
+		if parseErr := parseC.ws.SetWriteDeadline(parseDeadline); parseErr != nil {
+			return 0, parseErr
+		}
+	}
+	if parseErr := parseC.writeBridgeWindowFrame(); parseErr != nil {
+		return 0, parseErr
+	}
+	if parseErr := parseC.ws.WriteMessage(websocket.BinaryMessage, parseC.memory.ShapeWrite(parseP)); parseErr != nil {
+		if parseC.slowClient.isBridgeWriteTimeout(parseErr) {
+			_ = parseC.Close()
+		}
 		return 0, parseErr
 	}
+	parseC.storeBytesWritten.Add(int64(len(parseP)))
+	parseC.storeLastActivity.Store(time.Now().UnixNano())
 	return len(parseP), nil
 }
 
+// storeBridgeReadError records what ended the peer's side of the websocket, ignoring errors caused
+// by this side closing it.
+func (parseC *webSocketConn) storeBridgeReadError(parseErr error) {
+	if parseC.isClosed.Load() {
+		return
+	}
+	parseCause := TunnelCloseClient
+	var parseNetErr net.Error
+	if errors.As(parseErr, &parseNetErr) && parseNetErr.Timeout() {
+		parseCause = TunnelCloseIdleTimeout
+	}
+	parseC.storeCloseCause.CompareAndSwap(nil, &parseCause)
+}
+
+// getBridgeCloseCause reports why the websocket ended: a tripped slow-client limit, the peer's read
+// error, or otherwise this side closing it.
+func (parseC *webSocketConn) getBridgeCloseCause() TunnelCloseCause {
+	if parseCause := parseC.slowClient.getBridgeSlowClientCause(); parseCause != "" {
+		return parseCause
+	}
+	if parseCause := parseC.storeCloseCause.Load(); parseCause != nil {
+		return *parseCause
+	}
+	return TunnelCloseServer
+}
+
+// writeBridgeWindowCredit returns HTTP/2 window credit withheld by the memory budget once it recovers.
+func (parseC *webSocketConn) writeBridgeWindowCredit() error {
+	parseC.writeMu.Lock()
+	defer parseC.writeMu.Unlock()
+	if parseC.isClosed.Load() || parseC.ws == nil {
+		return io.ErrClosedPipe
+	}
+	if parseDeadline := parseC.slowClient.getBridgeWriteDeadline(); !parseDeadline.IsZero() {
+		if parseErr := parseC.ws.SetWriteDeadline(parseDeadline); parseErr != nil {
+			return parseErr
+		}
+	}
+	return parseC.writeBridgeWindowFrame()
+}
+
+// writeBridgeWindowFrame writes any releasable window credit as its own message. Callers hold writeMu.
+func (parseC *webSocketConn) writeBridgeWindowFrame() error {
+	parseFrame := parseC.memory.TakeWindowUpdate()
+	if parseFrame == nil {
+		return nil
+	}
+	return parseC.ws.WriteMessage(websocket.BinaryMessage, parseFrame)
+}
+
 // Close closes the adapted connection and underlying WebSocket exactly once.
 func (parseC *webSocketConn) Close() error {
 	var parseErr error
 	parseC.closeOnce.Do(func() {
 		parseC.isClosed.Store(true)
+		parseC.bandwidth.clearBridgeConnBandwidth()
+		parseC.slowClient.clearBridgeSlowClientGuard()
+		parseC.memory.Close()
 		if parseC.ws == nil {
 			return
 		}
@@ -122,7 +221,7 @@ func (parseC *webSocketConn) SetDeadline(parseT time.Time) error {
 	if parseErr := parseC.ws.SetReadDeadline(parseT); parseErr != nil {
 		return parseErr
 	}
-	return parseC.ws.SetWriteDeadline(parseT)
+	return parseC.setBridgeWriteDeadline(parseT)
 }
 
 // SetReadDeadline sets the read deadline on the underlying WebSocket.
@@ -142,5 +241,16 @@ func (parseC *webSocketConn) SetWriteDeadline(parseT time.Time) error {
 	if parseC.ws == nil {
 		return net.ErrClosed
 	}
+	return parseC.setBridgeWriteDeadline(parseT)
+}
+
+// setBridgeWriteDeadline sets the write deadline without racing an in-flight write. gorilla/websocket
+// forbids setting it during a write, so that write gets the deadline on the socket instead, which
+// still unblocks it. Callers hold deadlineMu.
+func (parseC *webSocketConn) setBridgeWriteDeadline(parseT time.Time) error {
+	if !parseC.writeMu.TryLock() {
+		return parseC.ws.NetConn().SetWriteDeadline(parseT)
+	}
+	defer parseC.writeMu.Unlock()
 	return parseC.ws.SetWriteDeadline(parseT)
 }
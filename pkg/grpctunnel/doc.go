@@ -2,8 +2,10 @@
 //
 // Server-side entry points:
 //   - BuildBridgeHandler and HandleBridgeMux for typed composition
+//   - NewListener for serving tunnels with grpc.Server.Serve and the native HTTP/2 transport
 //   - Wrap for middleware-style integration
 //   - Serve and ListenAndServe for convenience startup
+//   - NewServer for startup with graceful Drain and Shutdown
 //
 // Client-side entry points:
 //   - BuildTunnelConn for typed connection setup
@@ -0,0 +1,283 @@
+//go:build !js && !wasm
+
+package grpctunnel
+
+import (
+	"context"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/healthmonitor"
+	"golang.org/x/net/http2"
+)
+
+const parseBridgeShutdownPollInterval = 10 * time.Millisecond
+
+// parseBridgeMaxAgeJitter spreads MaxConnectionAge by +/-10%, as gRPC servers do.
+const parseBridgeMaxAgeJitter = 0.1
+
+// parseBridgeDefaultAuthExpiryGrace bounds in-flight RPCs after the Authenticate context ends.
+const parseBridgeDefaultAuthExpiryGrace = 30 * time.Second
+
+const (
+	parseBridgeMaxAgeStageGoAway       = "goaway"
+	parseBridgeMaxAgeStageGraceExpired = "grace_expired"
+)
+
+// bridgeTunnel stores one live websocket tunnel tracked for drain and shutdown.
+type bridgeTunnel struct {
+	getID            string
+	getConn          net.Conn
+	getBaseContext   context.Context
+	getRPCLimiter    *bridgeRateLimiter
+	getActiveStreams atomic.Int64
+	getStreams       atomic.Int64
+	storeCloseCause  atomic.Pointer[TunnelCloseCause]
+	isClosing        atomic.Bool
+	setDrainLock     sync.Mutex
+	handleDrain      func()
+	isDrainRequested bool
+}
+
+// storeBridgeTunnelDrain installs the GOAWAY callback and runs it immediately when drain was already requested.
+func (parseTunnel *bridgeTunnel) storeBridgeTunnelDrain(handleDrain func()) {
+	parseTunnel.setDrainLock.Lock()
+	parseTunnel.handleDrain = handleDrain
+	isDrainRequested := parseTunnel.isDrainRequested
+	parseTunnel.setDrainLock.Unlock()
+	if isDrainRequested && handleDrain != nil {
+		handleDrain()
+	}
+}
+
+// drainBridgeTunnel asks the tunnel to stop accepting new streams exactly once, recording parseCause
+// unless the tunnel already has a close cause.
+func (parseTunnel *bridgeTunnel) drainBridgeTunnel(parseCause TunnelCloseCause) {
+	parseTunnel.storeBridgeCloseCause(parseCause)
+	parseTunnel.setDrainLock.Lock()
+	if parseTunnel.isDrainRequested {
+		parseTunnel.setDrainLock.Unlock()
+		return
+	}
+	parseTunnel.isDrainRequested = true
+	handleDrain := parseTunnel.handleDrain
+	parseTunnel.setDrainLock.Unlock()
+	if handleDrain != nil {
+		handleDrain()
+	}
+}
+
+// closeBridgeTunnel closes the tunnel connection and reports whether this call initiated the close.
+func (parseTunnel *bridgeTunnel) closeBridgeTunnel() bool {
+	if !parseTunnel.isClosing.CompareAndSwap(false, true) {
+		return false
+	}
+	if parseTunnel.getConn != nil {
+		_ = parseTunnel.getConn.Close()
+	}
+	return true
+}
+
+// getBridgeTunnelMaxAge returns MaxConnectionAge with random jitter, so tunnels opened together rotate apart.
+func getBridgeTunnelMaxAge(parseMaxAge time.Duration) time.Duration {
+	parseJitter := (rand.Float64()*2 - 1) * parseBridgeMaxAgeJitter
+	return parseMaxAge + time.Duration(float64(parseMaxAge)*parseJitter)
+}
+
+// startBridgeTunnelMaxAge drains the tunnel at its jittered MaxConnectionAge and closes it once
+// MaxConnectionAgeGrace has passed. The returned callback stops both timers.
+func startBridgeTunnelMaxAge(parseTunnel *bridgeTunnel, parseConfig BridgeConfig, parseRequest *http.Request, parseObservability *bridgeObservability) func() {
+	if parseConfig.MaxConnectionAge <= 0 {
+		return func() {}
+	}
+	var parseTimerLock sync.Mutex
+	var parseGraceTimer *time.Timer
+	isStopped := false
+	parseAgeTimer := time.AfterFunc(getBridgeTunnelMaxAge(parseConfig.MaxConnectionAge), func() {
+		logGrpctunnelEvent("grpctunnel.bridge", "INFO", "tunnel_max_age", parseRequest, nil, "Tunnel reached MaxConnectionAge; draining tunnel")
+		parseObservability.storeBridgeTunnelMaxAge(parseRequest.Context(), parseBridgeMaxAgeStageGoAway)
+		parseTunnel.drainBridgeTunnel(TunnelCloseMaxAge)
+		if parseConfig.MaxConnectionAgeGrace <= 0 {
+			return
+		}
+		parseTimerLock.Lock()
+		defer parseTimerLock.Unlock()
+		if isStopped {
+			return
+		}
+		parseGraceTimer = time.AfterFunc(parseConfig.MaxConnectionAgeGrace, func() {
+			// Tunnels without streams are already closing after GOAWAY; only count cut-off RPCs.
+			isStreamActive := parseTunnel.getActiveStreams.Load() > 0
+			if parseTunnel.closeBridgeTunnel() && isStreamActive {
+				logGrpctunnelEvent("grpctunnel.bridge", "WARN", "tunnel_max_age_grace_expired", parseRequest, nil, "Tunnel closed with RPCs in flight after MaxConnectionAgeGrace")
+				parseObservability.storeBridgeTunnelMaxAge(parseRequest.Context(), parseBridgeMaxAgeStageGraceExpired)
+			}
+		})
+	})
+	return func() {
+		parseTimerLock.Lock()
+		defer parseTimerLock.Unlock()
+		isStopped = true
+		parseAgeTimer.Stop()
+		if parseGraceTimer != nil {
+			parseGraceTimer.Stop()
+		}
+	}
+}
+
+// startBridgeTunnelAuthExpiry drains the tunnel when parseExpiryContext is done, so in-flight RPCs
+// can finish, and closes it once parseGrace has passed so no stale session outlives its credential.
+// The returned callback stops both timers.
+func startBridgeTunnelAuthExpiry(parseTunnel *bridgeTunnel, parseExpiryContext context.Context, parseGrace time.Duration, parseRequest *http.Request) func() {
+	if parseExpiryContext == nil {
+		return func() {}
+	}
+	if parseGrace <= 0 {
+		parseGrace = parseBridgeDefaultAuthExpiryGrace
+	}
+	var parseTimerLock sync.Mutex
+	var parseGraceTimer *time.Timer
+	isStopped := false
+	clearExpiry := context.AfterFunc(parseExpiryContext, func() {
+		logGrpctunnelEvent("grpctunnel.bridge", "INFO", "tunnel_auth_expired", parseRequest, context.Cause(parseExpiryContext), "Tunnel authentication expired; draining tunnel")
+		parseTunnel.drainBridgeTunnel(TunnelCloseAuthExpired)
+		parseTimerLock.Lock()
+		defer parseTimerLock.Unlock()
+		if isStopped {
+			return
+		}
+		parseGraceTimer = time.AfterFunc(parseGrace, func() {
+			isStreamActive := parseTunnel.getActiveStreams.Load() > 0
+			if parseTunnel.closeBridgeTunnel() && isStreamActive {
+				logGrpctunnelEvent("grpctunnel.bridge", "WARN", "tunnel_auth_expired_grace_expired", parseRequest, nil, "Tunnel closed with RPCs in flight after AuthExpiryGrace")
+			}
+		})
+	})
+	return func() {
+		parseTimerLock.Lock()
+		defer parseTimerLock.Unlock()
+		isStopped = true
+		clearExpiry()
+		if parseGraceTimer != nil {
+			parseGraceTimer.Stop()
+		}
+	}
+}
+
+// bridgeTunnelTracker stores active tunnels so bridge handlers can drain and shut down gracefully.
+type bridgeTunnelTracker struct {
+	setTrackerLock sync.Mutex
+	storeTunnels   map[*bridgeTunnel]struct{}
+	isDraining     atomic.Bool
+	getHealth      *healthmonitor.Reporter
+}
+
+// buildBridgeTunnelTracker creates an empty tunnel tracker that reports drain to parseHealth.
+func buildBridgeTunnelTracker(parseHealth *healthmonitor.Reporter) *bridgeTunnelTracker {
+	return &bridgeTunnelTracker{
+		storeTunnels: map[*bridgeTunnel]struct{}{},
+		getHealth:    parseHealth,
+	}
+}
+
+// storeBridgeTunnel registers one live tunnel and drains it immediately when the tracker is draining.
+func (parseTracker *bridgeTunnelTracker) storeBridgeTunnel(parseTunnel *bridgeTunnel) {
+	parseTracker.setTrackerLock.Lock()
+	parseTracker.storeTunnels[parseTunnel] = struct{}{}
+	parseTracker.setTrackerLock.Unlock()
+	if parseTracker.isDraining.Load() {
+		parseTunnel.drainBridgeTunnel(TunnelCloseDrain)
+	}
+}
+
+// clearBridgeTunnel unregisters one tunnel after its connection has been released.
+func (parseTracker *bridgeTunnelTracker) clearBridgeTunnel(parseTunnel *bridgeTunnel) {
+	parseTracker.setTrackerLock.Lock()
+	delete(parseTracker.storeTunnels, parseTunnel)
+	parseTracker.setTrackerLock.Unlock()
+}
+
+// getBridgeTunnels returns a snapshot of live tunnels.
+func (parseTracker *bridgeTunnelTracker) getBridgeTunnels() []*bridgeTunnel {
+	parseTracker.setTrackerLock.Lock()
+	defer parseTracker.setTrackerLock.Unlock()
+	parseTunnels := make([]*bridgeTunnel, 0, len(parseTracker.storeTunnels))
+	for parseTunnel := range parseTracker.storeTunnels {
+		parseTunnels = append(parseTunnels, parseTunnel)
+	}
+	return parseTunnels
+}
+
+// drainBridgeTunnels stops new upgrades and sends GOAWAY to every live tunnel.
+func (parseTracker *bridgeTunnelTracker) drainBridgeTunnels() {
+	parseTracker.isDraining.Store(true)
+	parseTracker.getHealth.SetDraining(true)
+	for _, parseTunnel := range parseTracker.getBridgeTunnels() {
+		parseTunnel.drainBridgeTunnel(TunnelCloseDrain)
+	}
+}
+
+// shutdownBridgeTunnels drains tunnels, closes idle ones, and force-closes the rest when the context ends.
+// It returns the number of tunnels that were force-closed with streams still in flight.
+func (parseTracker *bridgeTunnelTracker) shutdownBridgeTunnels(parseCtx context.Context) (int, error) {
+	parseTracker.drainBridgeTunnels()
+
+	parseTicker := time.NewTicker(parseBridgeShutdownPollInterval)
+	defer parseTicker.Stop()
+	for {
+		parseTunnels := parseTracker.getBridgeTunnels()
+		if len(parseTunnels) == 0 {
+			return 0, nil
+		}
+		for _, parseTunnel := range parseTunnels {
+			if parseTunnel.getActiveStreams.Load() == 0 {
+				parseTunnel.closeBridgeTunnel()
+			}
+		}
+
+		select {
+		case <-parseCtx.Done():
+			parseForceClosed := 0
+			for _, parseTunnel := range parseTracker.getBridgeTunnels() {
+				// Tunnels that went idle at the deadline are closed without being counted.
+				isStreamActive := parseTunnel.getActiveStreams.Load() > 0
+				if parseTunnel.closeBridgeTunnel() && isStreamActive {
+					parseForceClosed++
+				}
+			}
+			return parseForceClosed, parseCtx.Err()
+		case <-parseTicker.C:
+		}
+	}
+}
+
+// buildBridgeTunnelHTTP2Server creates a per-tunnel HTTP/2 server plus the base server used to trigger its GOAWAY.
+func buildBridgeTunnelHTTP2Server(parseConfig BridgeConfig) (*http2.Server, *http.Server) {
+	parseSettings := buildHTTP2Settings(parseConfig.HTTP2Profile, parseConfig.HTTP2)
+	parseHTTP2Server := &http2.Server{
+		MaxConcurrentStreams:         parseSettings.MaxConcurrentStreams,
+		MaxReadFrameSize:             parseSettings.MaxReadFrameSize,
+		MaxUploadBufferPerStream:     parseSettings.InitialStreamWindowSize,
+		MaxUploadBufferPerConnection: parseSettings.InitialConnWindowSize,
+		IdleTimeout:                  parseSettings.IdleTimeout,
+	}
+	// ServeConn reads the header list limit from its base server.
+	parseShutdownServer := &http.Server{MaxHeaderBytes: int(parseSettings.MaxHeaderListSize)}
+	// ConfigureServer registers the HTTP/2 graceful shutdown hook on the base server,
+	// which lets one tunnel receive GOAWAY without touching any other tunnel.
+	_ = http2.ConfigureServer(parseShutdownServer, parseHTTP2Server)
+	return parseHTTP2Server, parseShutdownServer
+}
+
+// buildBridgeTunnelDrain returns a callback that sends HTTP/2 GOAWAY on one tunnel.
+func buildBridgeTunnelDrain(parseShutdownServer *http.Server) func() {
+	return func() {
+		// Shutdown returns immediately: the base server never listens and hijacked
+		// tunnel connections are not tracked by net/http.
+		_ = parseShutdownServer.Shutdown(context.Background())
+	}
+}
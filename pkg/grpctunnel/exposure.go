@@ -0,0 +1,57 @@
+package grpctunnel
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+const parseExposureDeniedMessage = "method is not exposed by the tunnel bridge"
+
+// ExposurePolicy restricts which gRPC methods tunnel clients may call.
+//
+// Patterns use path.Match syntax over full method names of the form "/package.Service/Method",
+// so "*" never crosses the "/" between service and method. For example, "/todo.v1.TodoService/*"
+// matches every TodoService method and "/grpc.reflection.*/*" matches every reflection method.
+type ExposurePolicy struct {
+	// Allow lists exposed method patterns. Empty exposes every method not denied.
+	Allow []string
+	// Deny lists method patterns that are never exposed, even when they match Allow.
+	Deny []string
+}
+
+// getExposurePolicyError validates exposure patterns.
+func getExposurePolicyError(parsePolicy ExposurePolicy) error {
+	for _, parsePattern := range append(append([]string{}, parsePolicy.Allow...), parsePolicy.Deny...) {
+		if !strings.HasPrefix(parsePattern, "/") {
+			return fmt.Errorf("grpctunnel: ExposurePolicy pattern %q must start with /", parsePattern)
+		}
+		if _, parseErr := path.Match(parsePattern, ""); parseErr != nil {
+			return fmt.Errorf("grpctunnel: ExposurePolicy pattern %q is invalid: %w", parsePattern, parseErr)
+		}
+	}
+	return nil
+}
+
+// isEmpty reports whether the policy exposes every method.
+func (parsePolicy ExposurePolicy) isEmpty() bool {
+	return len(parsePolicy.Allow) == 0 && len(parsePolicy.Deny) == 0
+}
+
+// isExposed reports whether a full method name may be called through the tunnel.
+func (parsePolicy ExposurePolicy) isExposed(parseFullMethod string) bool {
+	if isExposureMatch(parsePolicy.Deny, parseFullMethod) {
+		return false
+	}
+	return len(parsePolicy.Allow) == 0 || isExposureMatch(parsePolicy.Allow, parseFullMethod)
+}
+
+// isExposureMatch reports whether any validated pattern matches a full method name.
+func isExposureMatch(parsePatterns []string, parseFullMethod string) bool {
+	for _, parsePattern := range parsePatterns {
+		if isMatch, _ := path.Match(parsePattern, parseFullMethod); isMatch {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+//go:build !js && !wasm
+
+package grpctunnel
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/monstercameron/grpc-tunnel/examples/_shared/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// TestExposurePolicy_IsExposed verifies glob matching and deny precedence.
+func TestExposurePolicy_IsExposed(parseT *testing.T) {
+	parsePolicy := ExposurePolicy{
+		Allow: []string{"/TodoService/*", "/grpc.health.v1.Health/Check"},
+		Deny:  []string{"/TodoService/Delete*"},
+	}
+	parseTests := []struct {
+		parseMethod string
+		isExposed   bool
+	}{
+		{parseMethod: "/TodoService/CreateTodo", isExposed: true},
+		{parseMethod: "/TodoService/DeleteTodo", isExposed: false},
+		{parseMethod: "/grpc.health.v1.Health/Check", isExposed: true},
+		{parseMethod: "/grpc.health.v1.Health/Watch", isExposed: false},
+		{parseMethod: "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo", isExposed: false},
+	}
+	for _, parseTestCase := range parseTests {
+		if isExposed := parsePolicy.isExposed(parseTestCase.parseMethod); isExposed != parseTestCase.isExposed {
+			parseT.Fatalf("isExposed(%q) = %v, want %v", parseTestCase.parseMethod, isExposed, parseTestCase.isExposed)
+		}
+	}
+}
+
+// TestGetBridgeConfigError_ExposurePolicy verifies malformed patterns and listener use are rejected.
+func TestGetBridgeConfigError_ExposurePolicy(parseT *testing.T) {
+	if parseErr := GetBridgeConfigError(BridgeConfig{ExposurePolicy: ExposurePolicy{Allow: []string{"TodoService/*"}}}); parseErr == nil {
+		parseT.Fatal("expected error for pattern without leading slash")
+	}
+	if parseErr := GetBridgeConfigError(BridgeConfig{ExposurePolicy: ExposurePolicy{Deny: []string{"/TodoService/["}}}); parseErr == nil {
+		parseT.Fatal("expected error for malformed pattern")
+	}
+	if _, _, parseErr := NewListener(BridgeConfig{ExposurePolicy: ExposurePolicy{Deny: []string{"/TodoService/*"}}}); parseErr == nil {
+		parseT.Fatal("NewListener() should reject ExposurePolicy")
+	}
+}
+
+// TestBuildBridgeHandler_ExposurePolicyDeniesBeforeServer verifies denied RPCs get PermissionDenied without reaching grpc.Server.
+func TestBuildBridgeHandler_ExposurePolicyDeniesBeforeServer(parseT *testing.T) {
+	var parseCalls atomic.Int64
+	parseGrpcServer := grpc.NewServer(grpc.UnaryInterceptor(func(parseCtx context.Context, parseReq any, parseInfo *grpc.UnaryServerInfo, handleRPC grpc.UnaryHandler) (any, error) {
+		parseCalls.Add(1)
+		return handleRPC(parseCtx, parseReq)
+	}))
+	proto.RegisterTodoServiceServer(parseGrpcServer, &mockService{})
+	defer parseGrpcServer.Stop()
+
+	parseHandler, parseErr := BuildBridgeHandler(parseGrpcServer, BridgeConfig{
+		ExposurePolicy: ExposurePolicy{Allow: []string{"/TodoService/*"}, Deny: []string{"/TodoService/DeleteTodo"}},
+	})
+	if parseErr != nil {
+		parseT.Fatalf("BuildBridgeHandler() error: %v", parseErr)
+	}
+	parseServer := httptest.NewServer(parseHandler)
+	defer parseServer.Close()
+
+	parseCtx, clearCtx := context.WithTimeout(context.Background(), 5*time.Second)
+	defer clearCtx()
+	parseConn, parseErr := BuildTunnelConn(parseCtx, TunnelConfig{
+		Target:      "ws" + strings.TrimPrefix(parseServer.URL, "http"),
+		GRPCOptions: []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())},
+	})
+	if parseErr != nil {
+		parseT.Fatalf("BuildTunnelConn() error: %v", parseErr)
+	}
+	defer parseConn.Close()
+
+	parseClient := proto.NewTodoServiceClient(parseConn)
+	_, parseErr = parseClient.DeleteTodo(parseCtx, &proto.DeleteTodoRequest{Id: "1"})
+	if status.Code(parseErr) != codes.PermissionDenied {
+		parseT.Fatalf("DeleteTodo() error = %v, want %v", parseErr, codes.PermissionDenied)
+	}
+	if parseCount := parseCalls.Load(); parseCount != 0 {
+		parseT.Fatalf("server RPCs = %d, want 0", parseCount)
+	}
+	if _, parseErr = parseClient.CreateTodo(parseCtx, &proto.CreateTodoRequest{Text: "exposed"}); parseErr != nil {
+		parseT.Fatalf("CreateTodo() error: %v", parseErr)
+	}
+	if parseCount := parseCalls.Load(); parseCount != 1 {
+		parseT.Fatalf("server RPCs = %d, want 1", parseCount)
+	}
+}
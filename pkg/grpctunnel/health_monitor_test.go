@@ -0,0 +1,83 @@
+//go:build !js && !wasm
+
+package grpctunnel
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/monstercameron/grpc-tunnel/examples/_shared/proto"
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/healthmonitor"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	grpc_health_v1 "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// TestServer_HealthMonitorDrain verifies /readyz on the bridge listener and the tooling gRPC health
+// service both turn NOT_SERVING on Drain while /healthz keeps succeeding.
+func TestServer_HealthMonitorDrain(parseT *testing.T) {
+	parseGrpcServer := grpc.NewServer()
+	proto.RegisterTodoServiceServer(parseGrpcServer, &mockService{})
+	parseMonitor := healthmonitor.New()
+	parseServer, parseErr := NewServer(parseGrpcServer, BridgeConfig{HealthMonitor: parseMonitor})
+	if parseErr != nil {
+		parseT.Fatalf("NewServer() error: %v", parseErr)
+	}
+	parseToolingHandler, parseHealthServer, parseErr := BuildToolingHandler(parseGrpcServer, ToolingConfig{
+		ShouldEnableHealthService: true,
+		HealthMonitor:             parseMonitor,
+	})
+	if parseErr != nil {
+		parseT.Fatalf("BuildToolingHandler() error: %v", parseErr)
+	}
+	if parseHealthServer != parseMonitor.HealthServer() {
+		parseT.Fatal("BuildToolingHandler() registered a health server other than the monitor's")
+	}
+	parseListener, parseErr := net.Listen("tcp", "127.0.0.1:0")
+	if parseErr != nil {
+		parseT.Fatalf("Listen() error: %v", parseErr)
+	}
+	go func() {
+		_ = parseServer.Serve(parseListener)
+	}()
+	defer func() {
+		_, _ = parseServer.Shutdown(context.Background())
+	}()
+	parseToolingServer := httptest.NewServer(parseToolingHandler)
+	defer parseToolingServer.Close()
+
+	parseCtx, clearCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer clearCtx()
+	parseConn, parseErr := grpc.NewClient(strings.TrimPrefix(parseToolingServer.URL, "http://"), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if parseErr != nil {
+		parseT.Fatalf("NewClient() error: %v", parseErr)
+	}
+	defer parseConn.Close()
+	parseHealthClient := grpc_health_v1.NewHealthClient(parseConn)
+	assertProbes := func(parseWantReadyCode int, parseWantStatus grpc_health_v1.HealthCheckResponse_ServingStatus) {
+		parseT.Helper()
+		for parsePath, parseWantCode := range map[string]int{"/healthz": http.StatusOK, "/readyz": parseWantReadyCode} {
+			parseResponse, parseErr := http.Get("http://" + parseListener.Addr().String() + parsePath)
+			if parseErr != nil {
+				parseT.Fatalf("Get(%s) error: %v", parsePath, parseErr)
+			}
+			parseResponse.Body.Close()
+			if parseResponse.StatusCode != parseWantCode {
+				parseT.Fatalf("GET %s = %d, want %d", parsePath, parseResponse.StatusCode, parseWantCode)
+			}
+		}
+		parseResponse, parseErr := parseHealthClient.Check(parseCtx, &grpc_health_v1.HealthCheckRequest{})
+		if parseErr != nil || parseResponse.GetStatus() != parseWantStatus {
+			parseT.Fatalf("Check() = %v, %v, want %s", parseResponse, parseErr, parseWantStatus)
+		}
+	}
+
+	assertProbes(http.StatusOK, grpc_health_v1.HealthCheckResponse_SERVING)
+	parseServer.Drain()
+	assertProbes(http.StatusServiceUnavailable, grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+}
@@ -0,0 +1,17 @@
+// Package healthmonitor keeps the grpc.health.v1 service and HTTP /healthz and /readyz probes in
+// step with the bridges that serve tunnels.
+//
+// A Monitor shared by several handlers turns NOT_SERVING while any of them drains, and tracks
+// per-service status from the periodic backend health probes of bridge.Handler. The handlers
+// answer /healthz and /readyz from it on their own listener, and the tooling handler registers its
+// health.Server, so both kinds of probe see the same status:
+//
+//	parseMonitor := healthmonitor.New()
+//	server, _ := grpctunnel.NewServer(grpcServer, grpctunnel.BridgeConfig{HealthMonitor: parseMonitor})
+//	toolingHandler, _, _ := grpctunnel.BuildToolingHandler(grpcServer, grpctunnel.ToolingConfig{
+//		ShouldEnableHealthService: true,
+//		HealthMonitor:             parseMonitor,
+//	})
+//
+//	curl http://127.0.0.1:8080/readyz
+package healthmonitor
@@ -0,0 +1,165 @@
+package healthmonitor
+
+import (
+	"net/http"
+	"sync"
+
+	"google.golang.org/grpc/health"
+	grpc_health_v1 "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Monitor derives gRPC health statuses from what its Reporters report and publishes them to a
+// health.Server. The overall status, service "", is SERVING while no Reporter is draining and no
+// Reporter reports "" as not serving. A named service is SERVING while no Reporter is draining and
+// every Reporter that reported it reports it serving. A nil Monitor reports nothing.
+type Monitor struct {
+	setMonitorLock sync.Mutex
+	getServer      *health.Server
+	storeReporters []*Reporter
+	storeStatuses  map[string]grpc_health_v1.HealthCheckResponse_ServingStatus
+}
+
+// Reporter is one handler's view of its own readiness. A nil Reporter reports nothing.
+type Reporter struct {
+	getMonitor    *Monitor
+	isDraining    bool
+	storeServices map[string]bool
+}
+
+// New creates a Monitor whose overall status starts SERVING.
+func New() *Monitor {
+	return &Monitor{
+		getServer:     health.NewServer(),
+		storeStatuses: map[string]grpc_health_v1.HealthCheckResponse_ServingStatus{"": grpc_health_v1.HealthCheckResponse_SERVING},
+	}
+}
+
+// HealthServer returns the grpc.health.v1 server the Monitor publishes to. Register it on a gRPC
+// server, or let the tooling handler register it.
+func (parseMonitor *Monitor) HealthServer() *health.Server {
+	if parseMonitor == nil {
+		return nil
+	}
+	return parseMonitor.getServer
+}
+
+// NewReporter adds a Reporter. Handlers call it once when they are built.
+func (parseMonitor *Monitor) NewReporter() *Reporter {
+	if parseMonitor == nil {
+		return nil
+	}
+	parseReporter := &Reporter{getMonitor: parseMonitor, storeServices: map[string]bool{}}
+	parseMonitor.setMonitorLock.Lock()
+	parseMonitor.storeReporters = append(parseMonitor.storeReporters, parseReporter)
+	parseMonitor.setMonitorLock.Unlock()
+	return parseReporter
+}
+
+// SetDraining reports whether the handler is draining. Every status is NOT_SERVING while any
+// Reporter drains.
+func (parseReporter *Reporter) SetDraining(isDraining bool) {
+	if parseReporter == nil {
+		return
+	}
+	parseMonitor := parseReporter.getMonitor
+	parseMonitor.setMonitorLock.Lock()
+	defer parseMonitor.setMonitorLock.Unlock()
+	parseReporter.isDraining = isDraining
+	parseMonitor.storeMonitorStatuses()
+}
+
+// SetServing reports whether the handler can serve a service; "" is the handler as a whole.
+func (parseReporter *Reporter) SetServing(parseService string, isServing bool) {
+	if parseReporter == nil {
+		return
+	}
+	parseMonitor := parseReporter.getMonitor
+	parseMonitor.setMonitorLock.Lock()
+	defer parseMonitor.setMonitorLock.Unlock()
+	parseReporter.storeServices[parseService] = isServing
+	parseMonitor.storeMonitorStatuses()
+}
+
+// storeMonitorStatuses recomputes every status and publishes the ones that changed. Callers hold
+// the lock.
+func (parseMonitor *Monitor) storeMonitorStatuses() {
+	isDraining := false
+	parseServing := map[string]bool{"": true}
+	for _, parseReporter := range parseMonitor.storeReporters {
+		isDraining = isDraining || parseReporter.isDraining
+		for parseService, isServing := range parseReporter.storeServices {
+			isOthersServing, isFound := parseServing[parseService]
+			parseServing[parseService] = isServing && (isOthersServing || !isFound)
+		}
+	}
+	for parseService, isServing := range parseServing {
+		parseStatus := grpc_health_v1.HealthCheckResponse_SERVING
+		if isDraining || !isServing {
+			parseStatus = grpc_health_v1.HealthCheckResponse_NOT_SERVING
+		}
+		if parseMonitor.storeStatuses[parseService] == parseStatus {
+			continue
+		}
+		parseMonitor.storeStatuses[parseService] = parseStatus
+		parseMonitor.getServer.SetServingStatus(parseService, parseStatus)
+	}
+}
+
+// Status returns the published status of a service, or SERVICE_UNKNOWN when nothing reported it.
+func (parseMonitor *Monitor) Status(parseService string) grpc_health_v1.HealthCheckResponse_ServingStatus {
+	if parseMonitor == nil {
+		return grpc_health_v1.HealthCheckResponse_SERVICE_UNKNOWN
+	}
+	parseMonitor.setMonitorLock.Lock()
+	defer parseMonitor.setMonitorLock.Unlock()
+	parseStatus, isFound := parseMonitor.storeStatuses[parseService]
+	if !isFound {
+		return grpc_health_v1.HealthCheckResponse_SERVICE_UNKNOWN
+	}
+	return parseStatus
+}
+
+// ServeLiveness answers a liveness probe. It succeeds while the process serves HTTP, including
+// while draining, so orchestrators do not restart a process that is shutting down gracefully.
+func (parseMonitor *Monitor) ServeLiveness(parseW http.ResponseWriter, parseR *http.Request) {
+	if !isProbeMethodAllowed(parseW, parseR) {
+		return
+	}
+	writeProbeStatus(parseW, http.StatusOK, "ok")
+}
+
+// ServeReadiness answers a readiness probe with the status of the service named by the "service"
+// query parameter, or the overall status: 200 when SERVING, 503 when NOT_SERVING, and 404 for
+// unknown services.
+func (parseMonitor *Monitor) ServeReadiness(parseW http.ResponseWriter, parseR *http.Request) {
+	if !isProbeMethodAllowed(parseW, parseR) {
+		return
+	}
+	parseStatus := parseMonitor.Status(parseR.URL.Query().Get("service"))
+	switch parseStatus {
+	case grpc_health_v1.HealthCheckResponse_SERVING:
+		writeProbeStatus(parseW, http.StatusOK, parseStatus.String())
+	case grpc_health_v1.HealthCheckResponse_SERVICE_UNKNOWN:
+		writeProbeStatus(parseW, http.StatusNotFound, parseStatus.String())
+	default:
+		writeProbeStatus(parseW, http.StatusServiceUnavailable, parseStatus.String())
+	}
+}
+
+// isProbeMethodAllowed rejects probe requests other than GET and HEAD.
+func isProbeMethodAllowed(parseW http.ResponseWriter, parseR *http.Request) bool {
+	if parseR.Method == http.MethodGet || parseR.Method == http.MethodHead {
+		return true
+	}
+	parseW.Header().Set("Allow", "GET, HEAD")
+	http.Error(parseW, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	return false
+}
+
+// writeProbeStatus writes a plain-text probe result.
+func writeProbeStatus(parseW http.ResponseWriter, parseStatusCode int, parseBody string) {
+	parseW.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	parseW.Header().Set("Cache-Control", "no-store")
+	parseW.WriteHeader(parseStatusCode)
+	_, _ = parseW.Write([]byte(parseBody + "\n"))
+}
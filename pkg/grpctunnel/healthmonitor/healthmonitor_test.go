@@ -0,0 +1,97 @@
+package healthmonitor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	grpc_health_v1 "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// TestMonitor_Statuses verifies drain and per-service reports combine across reporters and reach
+// the health server.
+func TestMonitor_Statuses(parseT *testing.T) {
+	parseMonitor := New()
+	parseFirst := parseMonitor.NewReporter()
+	parseSecond := parseMonitor.NewReporter()
+	assertStatus := func(parseService string, parseWant grpc_health_v1.HealthCheckResponse_ServingStatus) {
+		parseT.Helper()
+		if parseGot := parseMonitor.Status(parseService); parseGot != parseWant {
+			parseT.Fatalf("Status(%q) = %s, want %s", parseService, parseGot, parseWant)
+		}
+		parseResponse, parseErr := parseMonitor.HealthServer().Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: parseService})
+		if parseWant == grpc_health_v1.HealthCheckResponse_SERVICE_UNKNOWN {
+			if parseErr == nil {
+				parseT.Fatalf("Check(%q) = %s, want NotFound", parseService, parseResponse.GetStatus())
+			}
+			return
+		}
+		if parseErr != nil || parseResponse.GetStatus() != parseWant {
+			parseT.Fatalf("Check(%q) = %v, %v, want %s", parseService, parseResponse, parseErr, parseWant)
+		}
+	}
+
+	assertStatus("", grpc_health_v1.HealthCheckResponse_SERVING)
+	assertStatus("todo.TodoService", grpc_health_v1.HealthCheckResponse_SERVICE_UNKNOWN)
+
+	parseFirst.SetServing("todo.TodoService", true)
+	parseSecond.SetServing("todo.TodoService", false)
+	assertStatus("todo.TodoService", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	parseSecond.SetServing("todo.TodoService", true)
+	assertStatus("todo.TodoService", grpc_health_v1.HealthCheckResponse_SERVING)
+
+	parseFirst.SetServing("", false)
+	assertStatus("", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	assertStatus("todo.TodoService", grpc_health_v1.HealthCheckResponse_SERVING)
+	parseFirst.SetServing("", true)
+	assertStatus("", grpc_health_v1.HealthCheckResponse_SERVING)
+
+	parseSecond.SetDraining(true)
+	assertStatus("", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	assertStatus("todo.TodoService", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	parseSecond.SetDraining(false)
+	assertStatus("", grpc_health_v1.HealthCheckResponse_SERVING)
+
+	var parseNilMonitor *Monitor
+	parseNilMonitor.NewReporter().SetDraining(true)
+	if parseNilMonitor.HealthServer() != nil || parseNilMonitor.Status("") != grpc_health_v1.HealthCheckResponse_SERVICE_UNKNOWN {
+		parseT.Fatal("nil Monitor reports a status, want none")
+	}
+}
+
+// TestMonitor_Probes verifies readiness follows the published status while liveness survives drain.
+func TestMonitor_Probes(parseT *testing.T) {
+	parseMonitor := New()
+	parseReporter := parseMonitor.NewReporter()
+	parseReporter.SetServing("todo.TodoService", true)
+	getCode := func(serveProbe http.HandlerFunc, parseMethod string, parseTarget string) int {
+		parseResponse := httptest.NewRecorder()
+		serveProbe(parseResponse, httptest.NewRequest(parseMethod, parseTarget, nil))
+		return parseResponse.Code
+	}
+
+	for _, parseTestCase := range []struct {
+		parseTarget   string
+		parseWantCode int
+	}{
+		{parseTarget: "/readyz", parseWantCode: http.StatusOK},
+		{parseTarget: "/readyz?service=todo.TodoService", parseWantCode: http.StatusOK},
+		{parseTarget: "/readyz?service=missing", parseWantCode: http.StatusNotFound},
+	} {
+		if parseCode := getCode(parseMonitor.ServeReadiness, http.MethodGet, parseTestCase.parseTarget); parseCode != parseTestCase.parseWantCode {
+			parseT.Fatalf("GET %s = %d, want %d", parseTestCase.parseTarget, parseCode, parseTestCase.parseWantCode)
+		}
+	}
+
+	parseReporter.SetDraining(true)
+	if parseCode := getCode(parseMonitor.ServeReadiness, http.MethodGet, "/readyz"); parseCode != http.StatusServiceUnavailable {
+		parseT.Fatalf("GET /readyz while draining = %d, want 503", parseCode)
+	}
+	if parseCode := getCode(parseMonitor.ServeLiveness, http.MethodGet, "/healthz"); parseCode != http.StatusOK {
+		parseT.Fatalf("GET /healthz while draining = %d, want 200", parseCode)
+	}
+	if parseCode := getCode(parseMonitor.ServeLiveness, http.MethodPost, "/healthz"); parseCode != http.StatusMethodNotAllowed {
+		parseT.Fatalf("POST /healthz = %d, want 405", parseCode)
+	}
+}
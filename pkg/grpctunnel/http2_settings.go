@@ -0,0 +1,110 @@
+package grpctunnel
+
+import (
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+const (
+	parseMinHTTP2FrameSize  = 1 << 14
+	parseMaxHTTP2FrameSize  = 1<<24 - 1
+	parseMinHTTP2WindowSize = 65535
+)
+
+// getHTTP2ProfileSettings returns the preset settings of a profile, reporting whether it is known.
+func getHTTP2ProfileSettings(parseProfile HTTP2Profile) (HTTP2Settings, bool) {
+	switch parseProfile {
+	case "":
+		return HTTP2Settings{}, true
+	case HTTP2ProfileLowLatency:
+		return HTTP2Settings{
+			MaxConcurrentStreams:    250,
+			MaxReadFrameSize:        16 << 10,
+			InitialStreamWindowSize: 4 << 20,
+			InitialConnWindowSize:   16 << 20,
+		}, true
+	case HTTP2ProfileHighThroughput:
+		return HTTP2Settings{
+			MaxConcurrentStreams:    100,
+			MaxReadFrameSize:        1 << 20,
+			InitialStreamWindowSize: 16 << 20,
+			InitialConnWindowSize:   64 << 20,
+		}, true
+	case HTTP2ProfileConstrainedMemory:
+		return HTTP2Settings{
+			MaxConcurrentStreams:    16,
+			MaxReadFrameSize:        16 << 10,
+			InitialStreamWindowSize: parseMinHTTP2WindowSize,
+			InitialConnWindowSize:   256 << 10,
+			MaxHeaderListSize:       16 << 10,
+			IdleTimeout:             2 * time.Minute,
+		}, true
+	}
+	return HTTP2Settings{}, false
+}
+
+// buildHTTP2Settings merges explicit settings over the profile presets.
+func buildHTTP2Settings(parseProfile HTTP2Profile, parseOverrides HTTP2Settings) HTTP2Settings {
+	parseSettings, _ := getHTTP2ProfileSettings(parseProfile)
+	if parseOverrides.MaxConcurrentStreams > 0 {
+		parseSettings.MaxConcurrentStreams = parseOverrides.MaxConcurrentStreams
+	}
+	if parseOverrides.MaxReadFrameSize > 0 {
+		parseSettings.MaxReadFrameSize = parseOverrides.MaxReadFrameSize
+	}
+	if parseOverrides.InitialStreamWindowSize > 0 {
+		parseSettings.InitialStreamWindowSize = parseOverrides.InitialStreamWindowSize
+	}
+	if parseOverrides.InitialConnWindowSize > 0 {
+		parseSettings.InitialConnWindowSize = parseOverrides.InitialConnWindowSize
+	}
+	if parseOverrides.MaxHeaderListSize > 0 {
+		parseSettings.MaxHeaderListSize = parseOverrides.MaxHeaderListSize
+	}
+	if parseOverrides.IdleTimeout > 0 {
+		parseSettings.IdleTimeout = parseOverrides.IdleTimeout
+	}
+	return parseSettings
+}
+
+// getHTTP2SettingsError validates an HTTP/2 profile and its overrides.
+func getHTTP2SettingsError(parseProfile HTTP2Profile, parseSettings HTTP2Settings) error {
+	if _, isKnownProfile := getHTTP2ProfileSettings(parseProfile); !isKnownProfile {
+		return fmt.Errorf("grpctunnel: unknown HTTP2Profile %q", parseProfile)
+	}
+	if parseSettings.MaxReadFrameSize != 0 &&
+		(parseSettings.MaxReadFrameSize < parseMinHTTP2FrameSize || parseSettings.MaxReadFrameSize > parseMaxHTTP2FrameSize) {
+		return fmt.Errorf("grpctunnel: HTTP2 MaxReadFrameSize must be 0 or between %d and %d", parseMinHTTP2FrameSize, parseMaxHTTP2FrameSize)
+	}
+	if parseSettings.InitialStreamWindowSize != 0 && parseSettings.InitialStreamWindowSize < parseMinHTTP2WindowSize {
+		return fmt.Errorf("grpctunnel: HTTP2 InitialStreamWindowSize must be 0 or >= %d", parseMinHTTP2WindowSize)
+	}
+	if parseSettings.InitialConnWindowSize != 0 && parseSettings.InitialConnWindowSize < parseMinHTTP2WindowSize {
+		return fmt.Errorf("grpctunnel: HTTP2 InitialConnWindowSize must be 0 or >= %d", parseMinHTTP2WindowSize)
+	}
+	if parseSettings.IdleTimeout < 0 {
+		return fmt.Errorf("grpctunnel: HTTP2 IdleTimeout must be >= 0")
+	}
+	return nil
+}
+
+// buildTunnelHTTP2DialOptions maps the client-side HTTP/2 settings onto gRPC dial options.
+func buildTunnelHTTP2DialOptions(parseConfig TunnelConfig) []grpc.DialOption {
+	parseSettings := buildHTTP2Settings(parseConfig.HTTP2Profile, parseConfig.HTTP2)
+	var parseDialOptions []grpc.DialOption
+	if parseSettings.InitialStreamWindowSize > 0 {
+		parseDialOptions = append(parseDialOptions, grpc.WithInitialWindowSize(parseSettings.InitialStreamWindowSize))
+	}
+	if parseSettings.InitialConnWindowSize > 0 {
+		parseDialOptions = append(parseDialOptions, grpc.WithInitialConnWindowSize(parseSettings.InitialConnWindowSize))
+	}
+	if parseSettings.MaxHeaderListSize > 0 {
+		parseDialOptions = append(parseDialOptions, grpc.WithMaxHeaderListSize(parseSettings.MaxHeaderListSize))
+	}
+	if parseSettings.IdleTimeout > 0 {
+		parseDialOptions = append(parseDialOptions, grpc.WithIdleTimeout(parseSettings.IdleTimeout))
+	}
+	return parseDialOptions
+}
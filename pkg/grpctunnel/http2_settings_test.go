@@ -0,0 +1,100 @@
+//go:build !js && !wasm
+
+package grpctunnel
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"golang.org/x/net/http2"
+	"google.golang.org/grpc"
+)
+
+// TestGetHTTP2SettingsError verifies HTTP/2 profile and settings validation for bridges and clients.
+func TestGetHTTP2SettingsError(parseT *testing.T) {
+	for _, parseConfig := range []BridgeConfig{
+		{HTTP2Profile: "fastest"},
+		{HTTP2: HTTP2Settings{MaxReadFrameSize: 1024}},
+		{HTTP2: HTTP2Settings{MaxReadFrameSize: 1 << 24}},
+		{HTTP2: HTTP2Settings{InitialStreamWindowSize: 1024}},
+		{HTTP2: HTTP2Settings{InitialConnWindowSize: -1}},
+		{HTTP2: HTTP2Settings{IdleTimeout: -time.Second}},
+	} {
+		if parseErr := GetBridgeConfigError(parseConfig); parseErr == nil {
+			parseT.Fatalf("GetBridgeConfigError(%+v) = nil, want error", parseConfig)
+		}
+		if parseErr := GetTunnelConfigError(TunnelConfig{Target: "localhost:8080", HTTP2Profile: parseConfig.HTTP2Profile, HTTP2: parseConfig.HTTP2}); parseErr == nil {
+			parseT.Fatalf("GetTunnelConfigError(%+v) = nil, want error", parseConfig.HTTP2)
+		}
+	}
+	if parseErr := GetBridgeConfigError(BridgeConfig{HTTP2Profile: HTTP2ProfileHighThroughput, HTTP2: HTTP2Settings{MaxReadFrameSize: 1<<24 - 1}}); parseErr != nil {
+		parseT.Fatalf("GetBridgeConfigError(high throughput) error: %v", parseErr)
+	}
+}
+
+// TestBuildHTTP2Settings verifies nonzero settings override the profile and the rest keep its values.
+func TestBuildHTTP2Settings(parseT *testing.T) {
+	parseSettings := buildHTTP2Settings(HTTP2ProfileConstrainedMemory, HTTP2Settings{MaxConcurrentStreams: 4, IdleTimeout: time.Minute})
+	if parseSettings.MaxConcurrentStreams != 4 || parseSettings.IdleTimeout != time.Minute {
+		parseT.Fatalf("overrides = %+v, want MaxConcurrentStreams 4 and IdleTimeout 1m", parseSettings)
+	}
+	if parseSettings.InitialStreamWindowSize != parseMinHTTP2WindowSize || parseSettings.MaxHeaderListSize != 16<<10 {
+		parseT.Fatalf("profile values = %+v, want constrained memory windows and header limit", parseSettings)
+	}
+	if parseSettings := buildHTTP2Settings("", HTTP2Settings{}); parseSettings != (HTTP2Settings{}) {
+		parseT.Fatalf("no profile = %+v, want zero settings", parseSettings)
+	}
+	if parseDialOptions := buildTunnelHTTP2DialOptions(TunnelConfig{HTTP2Profile: HTTP2ProfileConstrainedMemory}); len(parseDialOptions) != 4 {
+		parseT.Fatalf("constrained memory dial options = %d, want 4", len(parseDialOptions))
+	}
+}
+
+// TestBuildBridgeHandler_HTTP2Settings verifies the tunnel's HTTP/2 server advertises the configured settings.
+func TestBuildBridgeHandler_HTTP2Settings(parseT *testing.T) {
+	parseGrpcServer := grpc.NewServer()
+	defer parseGrpcServer.Stop()
+	parseHandler, parseErr := BuildBridgeHandler(parseGrpcServer, BridgeConfig{
+		HTTP2Profile: HTTP2ProfileHighThroughput,
+		HTTP2:        HTTP2Settings{MaxConcurrentStreams: 7},
+	})
+	if parseErr != nil {
+		parseT.Fatalf("BuildBridgeHandler() error: %v", parseErr)
+	}
+	parseServer := httptest.NewServer(parseHandler)
+	defer parseServer.Close()
+
+	parseWebsocket, _, parseErr := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(parseServer.URL, "http"), nil)
+	if parseErr != nil {
+		parseT.Fatalf("Dial() error: %v", parseErr)
+	}
+	parseConn := newWebSocketConn(parseWebsocket)
+	defer parseConn.Close()
+	if _, parseErr := parseConn.Write([]byte(http2.ClientPreface)); parseErr != nil {
+		parseT.Fatalf("Write(preface) error: %v", parseErr)
+	}
+	parseFramer := http2.NewFramer(parseConn, parseConn)
+	if parseErr := parseFramer.WriteSettings(); parseErr != nil {
+		parseT.Fatalf("WriteSettings() error: %v", parseErr)
+	}
+	_ = parseConn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	parseFrame, parseErr := parseFramer.ReadFrame()
+	if parseErr != nil {
+		parseT.Fatalf("ReadFrame() error: %v", parseErr)
+	}
+	parseSettingsFrame, isSettingsFrame := parseFrame.(*http2.SettingsFrame)
+	if !isSettingsFrame {
+		parseT.Fatalf("first frame = %T, want *http2.SettingsFrame", parseFrame)
+	}
+	for parseID, parseWant := range map[http2.SettingID]uint32{
+		http2.SettingMaxConcurrentStreams: 7,
+		http2.SettingMaxFrameSize:         1 << 20,
+		http2.SettingInitialWindowSize:    16 << 20,
+	} {
+		if parseValue, isFound := parseSettingsFrame.Value(parseID); !isFound || parseValue != parseWant {
+			parseT.Fatalf("%v = %d (found=%v), want %d", parseID, parseValue, isFound, parseWant)
+		}
+	}
+}
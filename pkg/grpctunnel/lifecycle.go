@@ -0,0 +1,352 @@
+//go:build !js && !wasm
+
+package grpctunnel
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/tunneladmin"
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/tunneladmin/adminv1"
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/tunnelregistry"
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/tunnelstate"
+	"golang.org/x/net/http2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// buildBridgeTunnelID returns a random 128-bit tunnel ID in hex.
+func buildBridgeTunnelID() string {
+	var parseID [16]byte
+	_, _ = rand.Read(parseID[:])
+	return hex.EncodeToString(parseID[:])
+}
+
+// storeBridgeUpgradeRejected reports one refused upgrade to the tunnel observer.
+func storeBridgeUpgradeRejected(parseObserver TunnelObserver, parseRequest *http.Request, parseReason UpgradeRejectReason, parseStatusCode int, parseErr error) {
+	if parseObserver == nil {
+		return
+	}
+	parseObserver.OnUpgradeRejected(UpgradeRejectedEvent{
+		Request:    parseRequest,
+		Reason:     parseReason,
+		StatusCode: parseStatusCode,
+		Err:        parseErr,
+		Time:       time.Now(),
+	})
+}
+
+// getBridgeAbuseRejectReason returns the observer reason and HTTP status of an abuse-control rejection.
+func getBridgeAbuseRejectReason(parseErr error) (UpgradeRejectReason, int) {
+	var parseRejection *bridgeAbuseRejection
+	if !errors.As(parseErr, &parseRejection) {
+		return UpgradeRejectUpgradeRate, http.StatusTooManyRequests
+	}
+	return UpgradeRejectReason(parseRejection.getReason), parseRejection.getStatusCode
+}
+
+// storeBridgeCloseCause records why the tunnel is ending; only the first cause is kept.
+func (parseTunnel *bridgeTunnel) storeBridgeCloseCause(parseCause TunnelCloseCause) {
+	parseTunnel.storeCloseCause.CompareAndSwap(nil, &parseCause)
+}
+
+// getBridgeCloseCause returns the recorded close cause, falling back to what ended the websocket.
+func (parseTunnel *bridgeTunnel) getBridgeCloseCause(parseConn *webSocketConn) TunnelCloseCause {
+	if parseCause := parseTunnel.storeCloseCause.Load(); parseCause != nil {
+		return *parseCause
+	}
+	return parseConn.getBridgeCloseCause()
+}
+
+// startBridgeStream counts one stream on the tunnel and reports it opened. The returned callback
+// reports it closed with the gRPC status found in the response headers or trailers.
+func startBridgeStream(parseObserver TunnelObserver, parseTunnel *bridgeTunnel, parseMethod string) func(http.Header) {
+	parseTunnel.getStreams.Add(1)
+	if parseObserver == nil {
+		return func(http.Header) {}
+	}
+	parseStart := time.Now()
+	parseObserver.OnStreamOpened(StreamOpenedEvent{
+		TunnelID: parseTunnel.getID,
+		Method:   parseMethod,
+		Time:     parseStart,
+	})
+	return func(parseHeader http.Header) {
+		parseNow := time.Now()
+		parseObserver.OnStreamClosed(StreamClosedEvent{
+			TunnelID: parseTunnel.getID,
+			Method:   parseMethod,
+			Code:     getBridgeStreamCode(parseHeader),
+			Duration: parseNow.Sub(parseStart),
+			Time:     parseNow,
+		})
+	}
+}
+
+// getBridgeStreamCode reads grpc-status from a finished response, which gRPC sets as a header on
+// trailers-only responses and as a trailer otherwise.
+func getBridgeStreamCode(parseHeader http.Header) codes.Code {
+	parseValue := parseHeader.Get("Grpc-Status")
+	if parseValue == "" {
+		parseValue = parseHeader.Get(http2.TrailerPrefix + "Grpc-Status")
+	}
+	parseCode, parseErr := strconv.ParseUint(parseValue, 10, 32)
+	if parseErr != nil {
+		return codes.Unknown
+	}
+	return codes.Code(parseCode)
+}
+
+// storeBridgeTunnelConnected reports a tunnel connected and returns the callback that reports it
+// disconnected once its connection has been released.
+func storeBridgeTunnelConnected(parseObserver TunnelObserver, parseTunnel *bridgeTunnel, parseConn *webSocketConn, parseRequest *http.Request) func() {
+	if parseObserver == nil {
+		return func() {}
+	}
+	parseStart := time.Now()
+	parseObserver.OnTunnelConnected(TunnelConnectedEvent{
+		TunnelID: parseTunnel.getID,
+		Request:  parseRequest,
+		Time:     parseStart,
+	})
+	return func() {
+		parseNow := time.Now()
+		parseObserver.OnTunnelDisconnected(TunnelDisconnectedEvent{
+			TunnelID:     parseTunnel.getID,
+			Request:      parseRequest,
+			Cause:        parseTunnel.getBridgeCloseCause(parseConn),
+			Duration:     parseNow.Sub(parseStart),
+			BytesRead:    parseConn.storeBytesRead.Load(),
+			BytesWritten: parseConn.storeBytesWritten.Load(),
+			Streams:      parseTunnel.getStreams.Load(),
+			Time:         parseNow,
+		})
+	}
+}
+
+// storeBridgeTunnelRegistry registers the tunnel with parseRegistry, whose Close force-closes it
+// with the terminated cause. Nil registries return nil, which is safe to Remove.
+func storeBridgeTunnelRegistry(parseRegistry *tunnelregistry.Registry, parseTunnel *bridgeTunnel, parseConn *webSocketConn, parseRequest *http.Request, parseClientKey string) *tunnelregistry.Tunnel {
+	if parseRegistry == nil {
+		return nil
+	}
+	return parseRegistry.Add(tunnelregistry.Info{
+		ID:         parseTunnel.getID,
+		Component:  "grpctunnel.bridge",
+		ClientKey:  parseClientKey,
+		Origin:     parseRequest.Header.Get("Origin"),
+		RemoteAddr: parseRequest.RemoteAddr,
+		StartedAt:  time.Now(),
+	}, func() tunnelregistry.Stats {
+		parseStats := tunnelregistry.Stats{
+			BytesIn:       parseConn.storeBytesRead.Load(),
+			BytesOut:      parseConn.storeBytesWritten.Load(),
+			ActiveStreams: parseTunnel.getActiveStreams.Load(),
+		}
+		if parseLastActivity := parseConn.storeLastActivity.Load(); parseLastActivity != 0 {
+			parseStats.LastActivity = time.Unix(0, parseLastActivity)
+		}
+		return parseStats
+	}, func() {
+		parseTunnel.storeBridgeCloseCause(TunnelCloseTerminated)
+		if parseTunnel.closeBridgeTunnel() {
+			logGrpctunnelEvent("grpctunnel.bridge", "WARN", "tunnel_terminated", parseRequest, nil, "Tunnel force-closed through the tunnel registry")
+		}
+	})
+}
+
+// applyBridgeTunnelObservers registers tunnels with the TunnelAdmin's registry and delivers their
+// events to TunnelAdmin and TunnelStates alongside TunnelObserver. GetBridgeConfigError has already
+// checked the registry.
+func applyBridgeTunnelObservers(parseConfig BridgeConfig) BridgeConfig {
+	if parseConfig.TunnelAdmin != nil {
+		parseConfig.TunnelRegistry = parseConfig.TunnelAdmin.Registry()
+		parseConfig.TunnelObserver = addBridgeTunnelObserver(parseConfig.TunnelObserver, bridgeTunnelAdminObserver{getAdmin: parseConfig.TunnelAdmin})
+	}
+	if parseConfig.TunnelStates != nil {
+		parseConfig.TunnelObserver = addBridgeTunnelObserver(parseConfig.TunnelObserver, bridgeTunnelStateObserver{getStates: parseConfig.TunnelStates})
+	}
+	return parseConfig
+}
+
+// addBridgeTunnelObserver appends parseAdded to the observers parseObserver delivers to.
+func addBridgeTunnelObserver(parseObserver TunnelObserver, parseAdded TunnelObserver) TunnelObserver {
+	switch parseCurrent := parseObserver.(type) {
+	case nil:
+		return parseAdded
+	case bridgeTunnelObservers:
+		return append(parseCurrent[:len(parseCurrent):len(parseCurrent)], parseAdded)
+	default:
+		return bridgeTunnelObservers{parseCurrent, parseAdded}
+	}
+}
+
+// bridgeTunnelObservers delivers each event to several observers in order.
+type bridgeTunnelObservers []TunnelObserver
+
+func (parseObservers bridgeTunnelObservers) OnUpgradeRejected(parseEvent UpgradeRejectedEvent) {
+	for _, parseObserver := range parseObservers {
+		parseObserver.OnUpgradeRejected(parseEvent)
+	}
+}
+
+func (parseObservers bridgeTunnelObservers) OnTunnelConnected(parseEvent TunnelConnectedEvent) {
+	for _, parseObserver := range parseObservers {
+		parseObserver.OnTunnelConnected(parseEvent)
+	}
+}
+
+func (parseObservers bridgeTunnelObservers) OnStreamOpened(parseEvent StreamOpenedEvent) {
+	for _, parseObserver := range parseObservers {
+		parseObserver.OnStreamOpened(parseEvent)
+	}
+}
+
+func (parseObservers bridgeTunnelObservers) OnStreamClosed(parseEvent StreamClosedEvent) {
+	for _, parseObserver := range parseObservers {
+		parseObserver.OnStreamClosed(parseEvent)
+	}
+}
+
+func (parseObservers bridgeTunnelObservers) OnTunnelDisconnected(parseEvent TunnelDisconnectedEvent) {
+	for _, parseObserver := range parseObservers {
+		parseObserver.OnTunnelDisconnected(parseEvent)
+	}
+}
+
+// bridgeTunnelAdminObserver publishes lifecycle events to the TunnelAdmin event streams.
+type bridgeTunnelAdminObserver struct {
+	getAdmin *tunneladmin.Admin
+}
+
+func (parseObserver bridgeTunnelAdminObserver) OnUpgradeRejected(parseEvent UpgradeRejectedEvent) {
+	parseRejected := &adminv1.UpgradeRejected{Reason: string(parseEvent.Reason), StatusCode: int32(parseEvent.StatusCode)}
+	if parseEvent.Err != nil {
+		parseRejected.Error = parseEvent.Err.Error()
+	}
+	if parseEvent.Request != nil {
+		parseRejected.RemoteAddr = parseEvent.Request.RemoteAddr
+	}
+	parseObserver.getAdmin.Publish(&adminv1.TunnelEvent{
+		Time:  timestamppb.New(parseEvent.Time),
+		Event: &adminv1.TunnelEvent_UpgradeRejected{UpgradeRejected: parseRejected},
+	})
+}
+
+func (parseObserver bridgeTunnelAdminObserver) OnTunnelConnected(parseEvent TunnelConnectedEvent) {
+	parseConnected := &adminv1.TunnelConnected{}
+	if parseEvent.Request != nil {
+		parseConnected.RemoteAddr = parseEvent.Request.RemoteAddr
+	}
+	parseObserver.getAdmin.Publish(&adminv1.TunnelEvent{
+		Time:     timestamppb.New(parseEvent.Time),
+		TunnelId: parseEvent.TunnelID,
+		Event:    &adminv1.TunnelEvent_TunnelConnected{TunnelConnected: parseConnected},
+	})
+}
+
+func (parseObserver bridgeTunnelAdminObserver) OnStreamOpened(parseEvent StreamOpenedEvent) {
+	parseObserver.getAdmin.Publish(&adminv1.TunnelEvent{
+		Time:     timestamppb.New(parseEvent.Time),
+		TunnelId: parseEvent.TunnelID,
+		Event:    &adminv1.TunnelEvent_StreamOpened{StreamOpened: &adminv1.StreamOpened{Method: parseEvent.Method}},
+	})
+}
+
+func (parseObserver bridgeTunnelAdminObserver) OnStreamClosed(parseEvent StreamClosedEvent) {
+	parseObserver.getAdmin.Publish(&adminv1.TunnelEvent{
+		Time:     timestamppb.New(parseEvent.Time),
+		TunnelId: parseEvent.TunnelID,
+		Event: &adminv1.TunnelEvent_StreamClosed{StreamClosed: &adminv1.StreamClosed{
+			Method:   parseEvent.Method,
+			Code:     int32(parseEvent.Code),
+			Duration: durationpb.New(parseEvent.Duration),
+		}},
+	})
+}
+
+func (parseObserver bridgeTunnelAdminObserver) OnTunnelDisconnected(parseEvent TunnelDisconnectedEvent) {
+	parseObserver.getAdmin.Publish(&adminv1.TunnelEvent{
+		Time:     timestamppb.New(parseEvent.Time),
+		TunnelId: parseEvent.TunnelID,
+		Event: &adminv1.TunnelEvent_TunnelDisconnected{TunnelDisconnected: &adminv1.TunnelDisconnected{
+			Cause:        string(parseEvent.Cause),
+			Duration:     durationpb.New(parseEvent.Duration),
+			BytesRead:    parseEvent.BytesRead,
+			BytesWritten: parseEvent.BytesWritten,
+			Streams:      parseEvent.Streams,
+		}},
+	})
+}
+
+// bridgeTunnelStateObserver records lifecycle events as tunnel state transitions.
+type bridgeTunnelStateObserver struct {
+	getStates *tunnelstate.Recorder
+}
+
+func (parseObserver bridgeTunnelStateObserver) OnUpgradeRejected(parseEvent UpgradeRejectedEvent) {
+	parseTransition := tunnelstate.Transition{
+		Time:       parseEvent.Time,
+		Component:  "grpctunnel.bridge",
+		State:      tunnelstate.StateUpgradeRejected,
+		ErrorClass: string(parseEvent.Reason),
+	}
+	if parseEvent.Err != nil {
+		parseTransition.ErrorMessage = parseEvent.Err.Error()
+	}
+	if parseEvent.Request != nil {
+		parseTransition.Target = parseEvent.Request.URL.Path
+		parseTransition.RemoteAddr = parseEvent.Request.RemoteAddr
+	}
+	parseObserver.getStates.Record(parseTransition)
+}
+
+func (parseObserver bridgeTunnelStateObserver) OnTunnelConnected(parseEvent TunnelConnectedEvent) {
+	parseTransition := tunnelstate.Transition{
+		Time:      parseEvent.Time,
+		Component: "grpctunnel.bridge",
+		TunnelID:  parseEvent.TunnelID,
+		State:     tunnelstate.StateTunnelConnected,
+	}
+	if parseEvent.Request != nil {
+		parseTransition.Target = parseEvent.Request.URL.Path
+		parseTransition.RemoteAddr = parseEvent.Request.RemoteAddr
+	}
+	parseObserver.getStates.Record(parseTransition)
+}
+
+func (parseObserver bridgeTunnelStateObserver) OnStreamOpened(StreamOpenedEvent) {}
+
+func (parseObserver bridgeTunnelStateObserver) OnStreamClosed(parseEvent StreamClosedEvent) {
+	if parseEvent.Code == codes.OK {
+		return
+	}
+	parseObserver.getStates.Record(tunnelstate.Transition{
+		Time:       parseEvent.Time,
+		Component:  "grpctunnel.bridge",
+		TunnelID:   parseEvent.TunnelID,
+		State:      tunnelstate.StateStreamError,
+		Target:     parseEvent.Method,
+		ErrorClass: parseEvent.Code.String(),
+	})
+}
+
+func (parseObserver bridgeTunnelStateObserver) OnTunnelDisconnected(parseEvent TunnelDisconnectedEvent) {
+	parseTransition := tunnelstate.Transition{
+		Time:       parseEvent.Time,
+		Component:  "grpctunnel.bridge",
+		TunnelID:   parseEvent.TunnelID,
+		State:      tunnelstate.StateTunnelDisconnected,
+		ErrorClass: string(parseEvent.Cause),
+	}
+	if parseEvent.Request != nil {
+		parseTransition.Target = parseEvent.Request.URL.Path
+		parseTransition.RemoteAddr = parseEvent.Request.RemoteAddr
+	}
+	parseObserver.getStates.Record(parseTransition)
+}
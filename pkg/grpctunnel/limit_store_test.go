@@ -0,0 +1,89 @@
+//go:build !js && !wasm
+
+package grpctunnel
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestBridgeMemoryLimitStore_Leases verifies limits, expiry, renewal, and release of slot leases.
+func TestBridgeMemoryLimitStore_Leases(parseT *testing.T) {
+	parseStore := buildBridgeMemoryLimitStore()
+	parseCtx := context.Background()
+
+	if isReserved, _ := parseStore.Reserve(parseCtx, "active", "a", 1, 20*time.Millisecond); !isReserved {
+		parseT.Fatal("Reserve(a) = false, want true")
+	}
+	if isReserved, _ := parseStore.Reserve(parseCtx, "active", "b", 1, time.Minute); isReserved {
+		parseT.Fatal("Reserve(b) over limit = true, want false")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if isReserved, _ := parseStore.Reserve(parseCtx, "active", "b", 1, time.Minute); !isReserved {
+		parseT.Fatal("Reserve(b) after lease expiry = false, want true")
+	}
+	if parseErr := parseStore.Renew(parseCtx, "active", "b", time.Minute); parseErr != nil {
+		parseT.Fatalf("Renew(b) error: %v", parseErr)
+	}
+	if parseErr := parseStore.Release(parseCtx, "active", "b"); parseErr != nil {
+		parseT.Fatalf("Release(b) error: %v", parseErr)
+	}
+	if isReserved, _ := parseStore.Reserve(parseCtx, "active", "c", 1, 0); !isReserved {
+		parseT.Fatal("Reserve(c) after release = false, want true")
+	}
+	if parseErr := parseStore.Release(parseCtx, "active", "unknown"); parseErr != nil {
+		parseT.Fatalf("Release(unknown) error: %v", parseErr)
+	}
+}
+
+// TestBridgeAbuseGuard_SharedLimitStore verifies replicas sharing a store enforce one connection budget
+// and that leases of a crashed replica expire.
+func TestBridgeAbuseGuard_SharedLimitStore(parseT *testing.T) {
+	parseStore := buildBridgeMemoryLimitStore()
+	parseConfig := BridgeConfig{
+		MaxActiveConnections:    2,
+		MaxConnectionsPerClient: 1,
+		LimitStore:              parseStore,
+		LimitStoreLeaseTTL:      30 * time.Millisecond,
+	}
+	parseReplicaOne := buildBridgeAbuseGuard(parseConfig)
+	parseReplicaTwo := buildBridgeAbuseGuard(parseConfig)
+	parseNow := time.Now()
+	parseBuildRequest := func(parseRemoteAddr string) *http.Request {
+		parseReq := httptest.NewRequest(http.MethodGet, "/grpc", nil)
+		parseReq.RemoteAddr = parseRemoteAddr
+		return parseReq
+	}
+
+	parseLease, parseErr := parseReplicaOne.reserveBridgeConnection(parseBuildRequest("203.0.113.1:1"), parseNow)
+	if parseErr != nil {
+		parseT.Fatalf("replica one reserve error: %v", parseErr)
+	}
+	if _, parseErr = parseReplicaTwo.reserveBridgeConnection(parseBuildRequest("203.0.113.1:2"), parseNow); parseErr == nil {
+		parseT.Fatal("replica two reserve for same client expected per-client cap error, got nil")
+	}
+	parseCrashedLease, parseErr := parseReplicaTwo.reserveBridgeConnection(parseBuildRequest("203.0.113.2:1"), parseNow)
+	if parseErr != nil {
+		parseT.Fatalf("replica two reserve error: %v", parseErr)
+	}
+	if _, parseErr = parseReplicaTwo.reserveBridgeConnection(parseBuildRequest("203.0.113.3:1"), parseNow); parseErr == nil {
+		parseT.Fatal("reserve over shared active cap expected error, got nil")
+	}
+
+	// Renewal keeps live leases; stopping it without release simulates a crashed replica.
+	time.Sleep(60 * time.Millisecond)
+	parseCrashedLease.setStopOnce.Do(func() { close(parseCrashedLease.getStop) })
+	time.Sleep(60 * time.Millisecond)
+	if _, parseErr = parseReplicaOne.reserveBridgeConnection(parseBuildRequest("203.0.113.1:3"), parseNow); parseErr == nil {
+		parseT.Fatal("renewed lease expired: expected per-client cap error, got nil")
+	}
+	parseNewLease, parseErr := parseReplicaOne.reserveBridgeConnection(parseBuildRequest("203.0.113.3:1"), parseNow)
+	if parseErr != nil {
+		parseT.Fatalf("reserve after crashed lease expiry error: %v", parseErr)
+	}
+	parseReplicaOne.clearBridgeConnection(parseLease)
+	parseReplicaOne.clearBridgeConnection(parseNewLease)
+}
@@ -0,0 +1,19 @@
+// Package limitstore provides LimitStore implementations that share bridge connection limits
+// across replicas.
+//
+// RedisStore keeps each limit key as a sorted set of leases scored by expiry time, speaking the
+// Redis protocol (RESP2) directly so it works with Redis, Valkey, and compatible servers. Reserve
+// counts unexpired leases and adds its own inside an optimistic WATCH/MULTI/EXEC transaction, so
+// concurrent replicas never exceed the limit. Live tunnels renew their leases; leases of a crashed
+// replica expire after the lease TTL and free their slots.
+//
+// A RedisStore satisfies both grpctunnel.LimitStore and bridge.LimitStore:
+//
+//	parseStore, _ := limitstore.NewRedisStore(limitstore.RedisConfig{Address: "redis:6379"})
+//	defer parseStore.Close()
+//	handler, _ := grpctunnel.BuildBridgeHandler(grpcServer, grpctunnel.BridgeConfig{
+//		MaxActiveConnections:    10000,
+//		MaxConnectionsPerClient: 20,
+//		LimitStore:              parseStore,
+//	})
+package limitstore
@@ -0,0 +1,393 @@
+//go:build !js && !wasm
+
+package limitstore
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const parseDefaultKeyPrefix = "grpctunnel:limits:"
+const parseDefaultDialTimeout = 5 * time.Second
+const parseDefaultMaxIdleConns = 8
+const parseMaxReserveAttempts = 16
+const parseMaxReplyLength = 1 << 20
+
+// ErrClosed is returned by operations on a closed RedisStore.
+var ErrClosed = errors.New("limitstore: store closed")
+
+// RedisConfig configures a RedisStore.
+type RedisConfig struct {
+	// Address is the host:port of the Redis-protocol server.
+	Address string
+	// Username selects an ACL user. It is only sent when Password is set.
+	Username string
+	// Password authenticates connections with AUTH when set.
+	Password string
+	// Database selects a logical database with SELECT. Zero uses the default database.
+	Database int
+	// TLSConfig enables TLS when set.
+	TLSConfig *tls.Config
+	// KeyPrefix namespaces limit keys so several bridges can share one server. Empty uses "grpctunnel:limits:".
+	KeyPrefix string
+	// DialTimeout bounds connection setup. Zero uses 5 seconds.
+	DialTimeout time.Duration
+	// MaxIdleConns bounds pooled idle connections. Zero uses 8.
+	MaxIdleConns int
+}
+
+// RedisStore shares connection slots through a Redis-protocol server.
+// Lease expiry uses the local clock, so replica clocks must agree to well within the lease TTL.
+type RedisStore struct {
+	getConfig   RedisConfig
+	setPoolLock sync.Mutex
+	storeIdle   []*redisConn
+	isClosed    bool
+	getNow      func() time.Time
+}
+
+// redisError is an error reply sent by the server.
+type redisError string
+
+// Error returns the server error message.
+func (parseErr redisError) Error() string {
+	return "limitstore: redis: " + string(parseErr)
+}
+
+// redisConn is one buffered protocol connection.
+type redisConn struct {
+	getConn   net.Conn
+	getReader *bufio.Reader
+	getWriter *bufio.Writer
+}
+
+// NewRedisStore validates the configuration and checks that the server is reachable.
+func NewRedisStore(parseConfig RedisConfig) (*RedisStore, error) {
+	if strings.TrimSpace(parseConfig.Address) == "" {
+		return nil, errors.New("limitstore: Address is required")
+	}
+	if parseConfig.Database < 0 || parseConfig.DialTimeout < 0 || parseConfig.MaxIdleConns < 0 {
+		return nil, errors.New("limitstore: Database, DialTimeout, and MaxIdleConns must be >= 0")
+	}
+	if parseConfig.KeyPrefix == "" {
+		parseConfig.KeyPrefix = parseDefaultKeyPrefix
+	}
+	if parseConfig.DialTimeout == 0 {
+		parseConfig.DialTimeout = parseDefaultDialTimeout
+	}
+	if parseConfig.MaxIdleConns == 0 {
+		parseConfig.MaxIdleConns = parseDefaultMaxIdleConns
+	}
+
+	parseStore := &RedisStore{getConfig: parseConfig, getNow: time.Now}
+	parseCtx, clearCtx := context.WithTimeout(context.Background(), parseConfig.DialTimeout)
+	defer clearCtx()
+	parseConn, parseErr := parseStore.getRedisConn(parseCtx)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	_, parseErr = parseConn.doRedisCommand(parseCtx, "PING")
+	parseStore.storeRedisConn(parseConn, parseErr)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	return parseStore, nil
+}
+
+// Reserve atomically takes a slot in key when fewer than limit unexpired leases are held.
+// A zero ttl holds the slot until Release.
+func (parseStore *RedisStore) Reserve(parseCtx context.Context, parseKey string, parseLease string, parseLimit int, parseTTL time.Duration) (bool, error) {
+	parseConn, parseErr := parseStore.getRedisConn(parseCtx)
+	if parseErr != nil {
+		return false, parseErr
+	}
+	isReserved, parseErr := parseStore.reserveRedisSlot(parseCtx, parseConn, parseStore.getConfig.KeyPrefix+parseKey, parseLease, parseLimit, parseTTL)
+	parseStore.storeRedisConn(parseConn, parseErr)
+	return isReserved, parseErr
+}
+
+// reserveRedisSlot counts live leases and adds one inside a WATCH transaction, retrying when another
+// client changed the key in between.
+func (parseStore *RedisStore) reserveRedisSlot(parseCtx context.Context, parseConn *redisConn, parseKey string, parseLease string, parseLimit int, parseTTL time.Duration) (bool, error) {
+	for parseAttempt := 0; parseAttempt < parseMaxReserveAttempts; parseAttempt++ {
+		parseNow := parseStore.getNow()
+		if _, parseErr := parseConn.doRedisCommand(parseCtx, "WATCH", parseKey); parseErr != nil {
+			return false, parseErr
+		}
+		parseReply, parseErr := parseConn.doRedisCommand(parseCtx, "ZCOUNT", parseKey, "("+formatRedisMillis(parseNow), "+inf")
+		if parseErr != nil {
+			return false, parseErr
+		}
+		parseCount, isInteger := parseReply.(int64)
+		if !isInteger {
+			return false, fmt.Errorf("limitstore: unexpected ZCOUNT reply %T", parseReply)
+		}
+		if parseCount >= int64(parseLimit) {
+			_, parseErr = parseConn.doRedisCommand(parseCtx, "UNWATCH")
+			return false, parseErr
+		}
+
+		parseCommands := [][]string{
+			{"ZREMRANGEBYSCORE", parseKey, "-inf", formatRedisMillis(parseNow)},
+		}
+		parseCommands = append(parseCommands, buildRedisLeaseCommands(parseKey, parseLease, parseNow, parseTTL)...)
+		isCommitted, parseErr := parseConn.execRedisTransaction(parseCtx, parseCommands)
+		if parseErr != nil || isCommitted {
+			return isCommitted, parseErr
+		}
+	}
+	return false, fmt.Errorf("limitstore: reserve %q: too much contention", parseKey)
+}
+
+// Renew extends a lease by ttl, re-adding it when it already expired.
+func (parseStore *RedisStore) Renew(parseCtx context.Context, parseKey string, parseLease string, parseTTL time.Duration) error {
+	parseConn, parseErr := parseStore.getRedisConn(parseCtx)
+	if parseErr != nil {
+		return parseErr
+	}
+	parseKey = parseStore.getConfig.KeyPrefix + parseKey
+	isCommitted, parseErr := parseConn.execRedisTransaction(parseCtx, buildRedisLeaseCommands(parseKey, parseLease, parseStore.getNow(), parseTTL))
+	parseStore.storeRedisConn(parseConn, parseErr)
+	if parseErr == nil && !isCommitted {
+		return fmt.Errorf("limitstore: renew %q: transaction aborted", parseKey)
+	}
+	return parseErr
+}
+
+// Release frees a lease. Unknown leases are ignored.
+func (parseStore *RedisStore) Release(parseCtx context.Context, parseKey string, parseLease string) error {
+	parseConn, parseErr := parseStore.getRedisConn(parseCtx)
+	if parseErr != nil {
+		return parseErr
+	}
+	_, parseErr = parseConn.doRedisCommand(parseCtx, "ZREM", parseStore.getConfig.KeyPrefix+parseKey, parseLease)
+	parseStore.storeRedisConn(parseConn, parseErr)
+	return parseErr
+}
+
+// Close closes pooled connections. Later operations return ErrClosed.
+func (parseStore *RedisStore) Close() error {
+	parseStore.setPoolLock.Lock()
+	defer parseStore.setPoolLock.Unlock()
+	parseStore.isClosed = true
+	for _, parseConn := range parseStore.storeIdle {
+		_ = parseConn.getConn.Close()
+	}
+	parseStore.storeIdle = nil
+	return nil
+}
+
+// buildRedisLeaseCommands returns the commands that set a lease expiry and keep the key alive as long as it.
+func buildRedisLeaseCommands(parseKey string, parseLease string, parseNow time.Time, parseTTL time.Duration) [][]string {
+	if parseTTL <= 0 {
+		return [][]string{{"ZADD", parseKey, "+inf", parseLease}}
+	}
+	return [][]string{
+		{"ZADD", parseKey, formatRedisMillis(parseNow.Add(parseTTL)), parseLease},
+		{"PEXPIRE", parseKey, strconv.FormatInt(parseTTL.Milliseconds(), 10)},
+	}
+}
+
+// formatRedisMillis formats a time as a Unix millisecond score.
+func formatRedisMillis(parseTime time.Time) string {
+	return strconv.FormatInt(parseTime.UnixMilli(), 10)
+}
+
+// getRedisConn returns a pooled connection or dials, authenticates, and selects the database.
+func (parseStore *RedisStore) getRedisConn(parseCtx context.Context) (*redisConn, error) {
+	parseStore.setPoolLock.Lock()
+	if parseStore.isClosed {
+		parseStore.setPoolLock.Unlock()
+		return nil, ErrClosed
+	}
+	if parseCount := len(parseStore.storeIdle); parseCount > 0 {
+		parseConn := parseStore.storeIdle[parseCount-1]
+		parseStore.storeIdle = parseStore.storeIdle[:parseCount-1]
+		parseStore.setPoolLock.Unlock()
+		return parseConn, nil
+	}
+	parseStore.setPoolLock.Unlock()
+
+	parseDialer := &net.Dialer{Timeout: parseStore.getConfig.DialTimeout}
+	var parseNetConn net.Conn
+	var parseErr error
+	if parseStore.getConfig.TLSConfig != nil {
+		parseNetConn, parseErr = (&tls.Dialer{NetDialer: parseDialer, Config: parseStore.getConfig.TLSConfig}).DialContext(parseCtx, "tcp", parseStore.getConfig.Address)
+	} else {
+		parseNetConn, parseErr = parseDialer.DialContext(parseCtx, "tcp", parseStore.getConfig.Address)
+	}
+	if parseErr != nil {
+		return nil, fmt.Errorf("limitstore: dial %s: %w", parseStore.getConfig.Address, parseErr)
+	}
+	parseConn := &redisConn{
+		getConn:   parseNetConn,
+		getReader: bufio.NewReader(parseNetConn),
+		getWriter: bufio.NewWriter(parseNetConn),
+	}
+	if parseStore.getConfig.Password != "" {
+		parseArgs := []string{"AUTH", parseStore.getConfig.Password}
+		if parseStore.getConfig.Username != "" {
+			parseArgs = []string{"AUTH", parseStore.getConfig.Username, parseStore.getConfig.Password}
+		}
+		if _, parseErr = parseConn.doRedisCommand(parseCtx, parseArgs...); parseErr != nil {
+			_ = parseNetConn.Close()
+			return nil, parseErr
+		}
+	}
+	if parseStore.getConfig.Database > 0 {
+		if _, parseErr = parseConn.doRedisCommand(parseCtx, "SELECT", strconv.Itoa(parseStore.getConfig.Database)); parseErr != nil {
+			_ = parseNetConn.Close()
+			return nil, parseErr
+		}
+	}
+	return parseConn, nil
+}
+
+// storeRedisConn returns a connection to the pool, closing it after transport errors or when the pool is full.
+func (parseStore *RedisStore) storeRedisConn(parseConn *redisConn, parseErr error) {
+	var parseReplyErr redisError
+	if parseErr != nil && !errors.As(parseErr, &parseReplyErr) {
+		_ = parseConn.getConn.Close()
+		return
+	}
+	if parseErr != nil {
+		// A failed command may leave a WATCH or MULTI open; reset the connection state first. DISCARD
+		// fails without a MULTI and then leaves the WATCH in place, so UNWATCH follows it.
+		parseCtx, clearCtx := context.WithTimeout(context.Background(), parseStore.getConfig.DialTimeout)
+		_, parseResetErr := parseConn.doRedisCommand(parseCtx, "DISCARD")
+		if parseResetErr == nil || errors.As(parseResetErr, &parseReplyErr) {
+			_, parseResetErr = parseConn.doRedisCommand(parseCtx, "UNWATCH")
+		}
+		clearCtx()
+		if parseResetErr != nil {
+			_ = parseConn.getConn.Close()
+			return
+		}
+	}
+	parseStore.setPoolLock.Lock()
+	defer parseStore.setPoolLock.Unlock()
+	if parseStore.isClosed || len(parseStore.storeIdle) >= parseStore.getConfig.MaxIdleConns {
+		_ = parseConn.getConn.Close()
+		return
+	}
+	parseStore.storeIdle = append(parseStore.storeIdle, parseConn)
+}
+
+// execRedisTransaction runs commands in MULTI/EXEC. It reports false when a watched key changed.
+func (parseConn *redisConn) execRedisTransaction(parseCtx context.Context, parseCommands [][]string) (bool, error) {
+	if _, parseErr := parseConn.doRedisCommand(parseCtx, "MULTI"); parseErr != nil {
+		return false, parseErr
+	}
+	for _, parseCommand := range parseCommands {
+		if _, parseErr := parseConn.doRedisCommand(parseCtx, parseCommand...); parseErr != nil {
+			return false, parseErr
+		}
+	}
+	parseReply, parseErr := parseConn.doRedisCommand(parseCtx, "EXEC")
+	if parseErr != nil {
+		return false, parseErr
+	}
+	parseResults, isArray := parseReply.([]any)
+	if !isArray {
+		return false, nil
+	}
+	for _, parseResult := range parseResults {
+		if parseResultErr, isError := parseResult.(redisError); isError {
+			return false, parseResultErr
+		}
+	}
+	return true, nil
+}
+
+// doRedisCommand writes one command and reads its reply within the context deadline.
+func (parseConn *redisConn) doRedisCommand(parseCtx context.Context, parseArgs ...string) (any, error) {
+	parseDeadline, isDeadlineSet := parseCtx.Deadline()
+	if !isDeadlineSet {
+		parseDeadline = time.Time{}
+	}
+	if parseErr := parseConn.getConn.SetDeadline(parseDeadline); parseErr != nil {
+		return nil, parseErr
+	}
+
+	fmt.Fprintf(parseConn.getWriter, "*%d\r\n", len(parseArgs))
+	for _, parseArg := range parseArgs {
+		fmt.Fprintf(parseConn.getWriter, "$%d\r\n%s\r\n", len(parseArg), parseArg)
+	}
+	if parseErr := parseConn.getWriter.Flush(); parseErr != nil {
+		return nil, fmt.Errorf("limitstore: write %s: %w", parseArgs[0], parseErr)
+	}
+	parseReply, parseErr := readRedisReply(parseConn.getReader)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	if parseReplyErr, isError := parseReply.(redisError); isError {
+		return nil, parseReplyErr
+	}
+	return parseReply, nil
+}
+
+// readRedisReply reads one RESP2 reply. Nested error replies are returned as redisError values.
+func readRedisReply(parseReader *bufio.Reader) (any, error) {
+	parseLine, parseErr := parseReader.ReadString('\n')
+	if parseErr != nil {
+		return nil, fmt.Errorf("limitstore: read reply: %w", parseErr)
+	}
+	parseLine = strings.TrimSuffix(parseLine, "\r\n")
+	if parseLine == "" {
+		return nil, errors.New("limitstore: empty reply")
+	}
+
+	switch parseLine[0] {
+	case '+':
+		return parseLine[1:], nil
+	case '-':
+		return redisError(parseLine[1:]), nil
+	case ':':
+		parseValue, parseErr := strconv.ParseInt(parseLine[1:], 10, 64)
+		if parseErr != nil {
+			return nil, fmt.Errorf("limitstore: malformed integer reply: %w", parseErr)
+		}
+		return parseValue, nil
+	case '$':
+		parseLength, parseErr := strconv.Atoi(parseLine[1:])
+		if parseErr != nil || parseLength > parseMaxReplyLength {
+			return nil, errors.New("limitstore: malformed bulk reply")
+		}
+		if parseLength < 0 {
+			return nil, nil
+		}
+		parseBulk := make([]byte, parseLength+2)
+		if _, parseErr = io.ReadFull(parseReader, parseBulk); parseErr != nil {
+			return nil, fmt.Errorf("limitstore: read bulk reply: %w", parseErr)
+		}
+		return string(parseBulk[:parseLength]), nil
+	case '*':
+		parseCount, parseErr := strconv.Atoi(parseLine[1:])
+		if parseErr != nil || parseCount > parseMaxReplyLength {
+			return nil, errors.New("limitstore: malformed array reply")
+		}
+		if parseCount < 0 {
+			return nil, nil
+		}
+		parseItems := make([]any, 0, parseCount)
+		for parseIndex := 0; parseIndex < parseCount; parseIndex++ {
+			parseItem, parseErr := readRedisReply(parseReader)
+			if parseErr != nil {
+				return nil, parseErr
+			}
+			parseItems = append(parseItems, parseItem)
+		}
+		return parseItems, nil
+	default:
+		return nil, fmt.Errorf("limitstore: unknown reply type %q", parseLine[0])
+	}
+}
@@ -0,0 +1,451 @@
+//go:build !js && !wasm
+
+package limitstore
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/monstercameron/grpc-tunnel/pkg/bridge"
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel"
+	"google.golang.org/grpc"
+)
+
+var _ grpctunnel.LimitStore = (*RedisStore)(nil)
+var _ bridge.LimitStore = (*RedisStore)(nil)
+
+// fakeRedisServer is a minimal in-memory Redis-protocol server covering the commands RedisStore uses.
+type fakeRedisServer struct {
+	getListener    net.Listener
+	getPassword    string
+	setLock        sync.Mutex
+	storeSets      map[string]map[string]float64
+	storeVersions  map[string]uint64
+	storeStrings   map[string]bool
+	storeAborts    atomic.Int64
+	storeConnCount atomic.Int64
+	isAbortingExec atomic.Bool
+}
+
+// fakeRedisSession holds per-connection transaction state.
+type fakeRedisSession struct {
+	isAuthed     bool
+	storeWatched map[string]uint64
+	isInMulti    bool
+	storeQueued  [][]string
+}
+
+// buildFakeRedisServer starts a fake server that requires password when it is not empty.
+func buildFakeRedisServer(parseT *testing.T, parsePassword string) *fakeRedisServer {
+	parseT.Helper()
+	parseListener, parseErr := net.Listen("tcp", "127.0.0.1:0")
+	if parseErr != nil {
+		parseT.Fatalf("listen error: %v", parseErr)
+	}
+	parseServer := &fakeRedisServer{
+		getListener:   parseListener,
+		getPassword:   parsePassword,
+		storeSets:     map[string]map[string]float64{},
+		storeVersions: map[string]uint64{},
+		storeStrings:  map[string]bool{},
+	}
+	parseT.Cleanup(func() { _ = parseListener.Close() })
+	go func() {
+		for {
+			parseConn, parseErr := parseListener.Accept()
+			if parseErr != nil {
+				return
+			}
+			parseServer.storeConnCount.Add(1)
+			go parseServer.serveFakeRedisConn(parseConn)
+		}
+	}()
+	return parseServer
+}
+
+// getAddress returns the listening address.
+func (parseServer *fakeRedisServer) getAddress() string {
+	return parseServer.getListener.Addr().String()
+}
+
+// serveFakeRedisConn reads commands until the connection closes.
+func (parseServer *fakeRedisServer) serveFakeRedisConn(parseConn net.Conn) {
+	defer parseConn.Close()
+	parseReader := bufio.NewReader(parseConn)
+	parseSession := &fakeRedisSession{isAuthed: parseServer.getPassword == ""}
+	for {
+		parseArgs, parseErr := readFakeRedisCommand(parseReader)
+		if parseErr != nil {
+			return
+		}
+		if _, parseErr = io.WriteString(parseConn, parseServer.handleFakeRedisCommand(parseSession, parseArgs)); parseErr != nil {
+			return
+		}
+	}
+}
+
+// readFakeRedisCommand reads one array-of-bulk-strings command.
+func readFakeRedisCommand(parseReader *bufio.Reader) ([]string, error) {
+	parseReply, parseErr := readRedisReply(parseReader)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	parseItems, isArray := parseReply.([]any)
+	if !isArray || len(parseItems) == 0 {
+		return nil, errors.New("expected command array")
+	}
+	parseArgs := make([]string, 0, len(parseItems))
+	for _, parseItem := range parseItems {
+		parseArg, isString := parseItem.(string)
+		if !isString {
+			return nil, errors.New("expected bulk string argument")
+		}
+		parseArgs = append(parseArgs, parseArg)
+	}
+	return parseArgs, nil
+}
+
+// handleFakeRedisCommand applies connection and transaction commands and queues data commands inside MULTI.
+func (parseServer *fakeRedisServer) handleFakeRedisCommand(parseSession *fakeRedisSession, parseArgs []string) string {
+	parseName := strings.ToUpper(parseArgs[0])
+	if parseName == "AUTH" {
+		if parseArgs[len(parseArgs)-1] != parseServer.getPassword {
+			return "-WRONGPASS invalid password\r\n"
+		}
+		parseSession.isAuthed = true
+		return "+OK\r\n"
+	}
+	if !parseSession.isAuthed {
+		return "-NOAUTH Authentication required.\r\n"
+	}
+
+	parseServer.setLock.Lock()
+	defer parseServer.setLock.Unlock()
+	switch parseName {
+	case "PING":
+		return "+PONG\r\n"
+	case "SELECT":
+		return "+OK\r\n"
+	case "WATCH":
+		if parseSession.storeWatched == nil {
+			parseSession.storeWatched = map[string]uint64{}
+		}
+		for _, parseKey := range parseArgs[1:] {
+			parseSession.storeWatched[parseKey] = parseServer.storeVersions[parseKey]
+		}
+		return "+OK\r\n"
+	case "UNWATCH":
+		parseSession.storeWatched = nil
+		return "+OK\r\n"
+	case "MULTI":
+		parseSession.isInMulti = true
+		parseSession.storeQueued = nil
+		return "+OK\r\n"
+	case "DISCARD":
+		if !parseSession.isInMulti {
+			return "-ERR DISCARD without MULTI\r\n"
+		}
+		parseSession.isInMulti = false
+		parseSession.storeQueued = nil
+		parseSession.storeWatched = nil
+		return "+OK\r\n"
+	case "EXEC":
+		parseSession.isInMulti = false
+		parseQueued := parseSession.storeQueued
+		parseWatched := parseSession.storeWatched
+		parseSession.storeQueued = nil
+		parseSession.storeWatched = nil
+		if parseServer.isAbortingExec.Load() {
+			parseServer.storeAborts.Add(1)
+			return "*-1\r\n"
+		}
+		for parseKey, parseVersion := range parseWatched {
+			if parseServer.storeVersions[parseKey] != parseVersion {
+				parseServer.storeAborts.Add(1)
+				return "*-1\r\n"
+			}
+		}
+		parseReply := fmt.Sprintf("*%d\r\n", len(parseQueued))
+		for _, parseQueuedArgs := range parseQueued {
+			parseReply += parseServer.applyFakeRedisData(parseQueuedArgs)
+		}
+		return parseReply
+	}
+	if parseSession.isInMulti {
+		parseSession.storeQueued = append(parseSession.storeQueued, parseArgs)
+		return "+QUEUED\r\n"
+	}
+	return parseServer.applyFakeRedisData(parseArgs)
+}
+
+// applyFakeRedisData runs one sorted-set or expiry command. The caller holds the server lock.
+func (parseServer *fakeRedisServer) applyFakeRedisData(parseArgs []string) string {
+	parseKey := parseArgs[1]
+	if parseServer.storeStrings[parseKey] {
+		return "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"
+	}
+	parseSet := parseServer.storeSets[parseKey]
+	switch strings.ToUpper(parseArgs[0]) {
+	case "ZCOUNT":
+		parseMin, isMinExclusive := parseFakeRedisScore(parseArgs[2])
+		parseMax, _ := parseFakeRedisScore(parseArgs[3])
+		parseCount := 0
+		for _, parseScore := range parseSet {
+			if (parseScore > parseMin || (!isMinExclusive && parseScore == parseMin)) && parseScore <= parseMax {
+				parseCount++
+			}
+		}
+		return fmt.Sprintf(":%d\r\n", parseCount)
+	case "ZREMRANGEBYSCORE":
+		parseMin, _ := parseFakeRedisScore(parseArgs[2])
+		parseMax, _ := parseFakeRedisScore(parseArgs[3])
+		parseRemoved := 0
+		for parseMember, parseScore := range parseSet {
+			if parseScore >= parseMin && parseScore <= parseMax {
+				delete(parseSet, parseMember)
+				parseRemoved++
+			}
+		}
+		if parseRemoved > 0 {
+			parseServer.storeVersions[parseKey]++
+		}
+		return fmt.Sprintf(":%d\r\n", parseRemoved)
+	case "ZADD":
+		parseScore, _ := parseFakeRedisScore(parseArgs[2])
+		if parseSet == nil {
+			parseSet = map[string]float64{}
+			parseServer.storeSets[parseKey] = parseSet
+		}
+		_, isExisting := parseSet[parseArgs[3]]
+		parseSet[parseArgs[3]] = parseScore
+		parseServer.storeVersions[parseKey]++
+		if isExisting {
+			return ":0\r\n"
+		}
+		return ":1\r\n"
+	case "ZREM":
+		if _, isExisting := parseSet[parseArgs[2]]; !isExisting {
+			return ":0\r\n"
+		}
+		delete(parseSet, parseArgs[2])
+		parseServer.storeVersions[parseKey]++
+		return ":1\r\n"
+	case "PEXPIRE":
+		if parseSet == nil {
+			return ":0\r\n"
+		}
+		return ":1\r\n"
+	default:
+		return "-ERR unknown command '" + parseArgs[0] + "'\r\n"
+	}
+}
+
+// parseFakeRedisScore parses a score bound and reports whether it is exclusive.
+func parseFakeRedisScore(parseValue string) (float64, bool) {
+	isExclusive := strings.HasPrefix(parseValue, "(")
+	parseValue = strings.TrimPrefix(parseValue, "(")
+	switch parseValue {
+	case "+inf":
+		return math.Inf(1), isExclusive
+	case "-inf":
+		return math.Inf(-1), isExclusive
+	}
+	parseScore, _ := strconv.ParseFloat(parseValue, 64)
+	return parseScore, isExclusive
+}
+
+// getKeys returns the keys holding at least one lease.
+func (parseServer *fakeRedisServer) getKeys() []string {
+	parseServer.setLock.Lock()
+	defer parseServer.setLock.Unlock()
+	var parseKeys []string
+	for parseKey, parseSet := range parseServer.storeSets {
+		if len(parseSet) > 0 {
+			parseKeys = append(parseKeys, parseKey)
+		}
+	}
+	return parseKeys
+}
+
+// buildTestRedisStore creates a store against the fake server and closes it on cleanup.
+func buildTestRedisStore(parseT *testing.T, parseConfig RedisConfig) *RedisStore {
+	parseT.Helper()
+	parseStore, parseErr := NewRedisStore(parseConfig)
+	if parseErr != nil {
+		parseT.Fatalf("NewRedisStore error: %v", parseErr)
+	}
+	parseT.Cleanup(func() { _ = parseStore.Close() })
+	return parseStore
+}
+
+// TestNewRedisStore_Config verifies configuration validation, authentication, and Close.
+func TestNewRedisStore_Config(parseT *testing.T) {
+	parseServer := buildFakeRedisServer(parseT, "secret")
+
+	if _, parseErr := NewRedisStore(RedisConfig{}); parseErr == nil {
+		parseT.Fatal("NewRedisStore without Address expected error, got nil")
+	}
+	if _, parseErr := NewRedisStore(RedisConfig{Address: parseServer.getAddress(), MaxIdleConns: -1}); parseErr == nil {
+		parseT.Fatal("NewRedisStore with negative MaxIdleConns expected error, got nil")
+	}
+	if _, parseErr := NewRedisStore(RedisConfig{Address: parseServer.getAddress(), Password: "wrong"}); parseErr == nil || !strings.Contains(parseErr.Error(), "WRONGPASS") {
+		parseT.Fatalf("NewRedisStore with wrong password error = %v, want WRONGPASS", parseErr)
+	}
+
+	parseStore := buildTestRedisStore(parseT, RedisConfig{Address: parseServer.getAddress(), Password: "secret", Database: 2})
+	if parseErr := parseStore.Close(); parseErr != nil {
+		parseT.Fatalf("Close error: %v", parseErr)
+	}
+	if _, parseErr := parseStore.Reserve(context.Background(), "active", "a", 1, time.Minute); !errors.Is(parseErr, ErrClosed) {
+		parseT.Fatalf("Reserve after Close error = %v, want ErrClosed", parseErr)
+	}
+}
+
+// TestRedisStore_Leases verifies limits, key prefixing, expiry, renewal, release, and connection reuse.
+func TestRedisStore_Leases(parseT *testing.T) {
+	parseServer := buildFakeRedisServer(parseT, "")
+	parseStore := buildTestRedisStore(parseT, RedisConfig{Address: parseServer.getAddress(), KeyPrefix: "test:"})
+	parseCtx := context.Background()
+
+	if isReserved, parseErr := parseStore.Reserve(parseCtx, "active", "a", 1, 50*time.Millisecond); parseErr != nil || !isReserved {
+		parseT.Fatalf("Reserve(a) = %v, %v; want true, nil", isReserved, parseErr)
+	}
+	if parseKeys := parseServer.getKeys(); len(parseKeys) != 1 || parseKeys[0] != "test:active" {
+		parseT.Fatalf("server keys = %v, want [test:active]", parseKeys)
+	}
+	if isReserved, parseErr := parseStore.Reserve(parseCtx, "active", "b", 1, time.Minute); parseErr != nil || isReserved {
+		parseT.Fatalf("Reserve(b) over limit = %v, %v; want false, nil", isReserved, parseErr)
+	}
+	time.Sleep(70 * time.Millisecond)
+	if isReserved, parseErr := parseStore.Reserve(parseCtx, "active", "b", 1, 50*time.Millisecond); parseErr != nil || !isReserved {
+		parseT.Fatalf("Reserve(b) after lease expiry = %v, %v; want true, nil", isReserved, parseErr)
+	}
+	if parseErr := parseStore.Renew(parseCtx, "active", "b", time.Minute); parseErr != nil {
+		parseT.Fatalf("Renew(b) error: %v", parseErr)
+	}
+	time.Sleep(70 * time.Millisecond)
+	if isReserved, _ := parseStore.Reserve(parseCtx, "active", "c", 1, time.Minute); isReserved {
+		parseT.Fatal("Reserve(c) while renewed lease held = true, want false")
+	}
+	if parseErr := parseStore.Release(parseCtx, "active", "b"); parseErr != nil {
+		parseT.Fatalf("Release(b) error: %v", parseErr)
+	}
+	if isReserved, parseErr := parseStore.Reserve(parseCtx, "active", "c", 1, 0); parseErr != nil || !isReserved {
+		parseT.Fatalf("Reserve(c) after release = %v, %v; want true, nil", isReserved, parseErr)
+	}
+	if parseErr := parseStore.Release(parseCtx, "active", "unknown"); parseErr != nil {
+		parseT.Fatalf("Release(unknown) error: %v", parseErr)
+	}
+	if parseCount := parseServer.storeConnCount.Load(); parseCount != 1 {
+		parseT.Fatalf("server connections = %d, want 1 pooled connection", parseCount)
+	}
+}
+
+// TestRedisStore_ReplyErrorClearsWatch verifies a reply error between WATCH and MULTI does not leave
+// the pooled connection watching a key, and that Renew reports an aborted transaction.
+func TestRedisStore_ReplyErrorClearsWatch(parseT *testing.T) {
+	parseServer := buildFakeRedisServer(parseT, "")
+	parseServer.storeStrings["test:wrong"] = true
+	parseStore := buildTestRedisStore(parseT, RedisConfig{Address: parseServer.getAddress(), KeyPrefix: "test:"})
+	parseCtx := context.Background()
+
+	var parseReplyErr redisError
+	if _, parseErr := parseStore.Reserve(parseCtx, "wrong", "a", 1, time.Minute); !errors.As(parseErr, &parseReplyErr) {
+		parseT.Fatalf("Reserve(wrong) error = %v, want a WRONGTYPE reply error", parseErr)
+	}
+	parseServer.setLock.Lock()
+	parseServer.storeVersions["test:wrong"]++
+	parseServer.setLock.Unlock()
+	if parseErr := parseStore.Renew(parseCtx, "active", "a", time.Minute); parseErr != nil {
+		parseT.Fatalf("Renew(a) on the reused connection error: %v", parseErr)
+	}
+	if parseKeys := parseServer.getKeys(); len(parseKeys) != 1 || parseKeys[0] != "test:active" {
+		parseT.Fatalf("server keys = %v, want the renewed lease in [test:active]", parseKeys)
+	}
+	if parseCount := parseServer.storeConnCount.Load(); parseCount != 1 {
+		parseT.Fatalf("server connections = %d, want the connection pooled after the reply error", parseCount)
+	}
+
+	parseServer.isAbortingExec.Store(true)
+	if parseErr := parseStore.Renew(parseCtx, "active", "a", time.Minute); parseErr == nil {
+		parseT.Fatal("Renew(a) with an aborted EXEC error = nil, want an error")
+	}
+}
+
+// TestRedisStore_ConcurrentReplicas verifies separate stores racing on one key never exceed the limit.
+func TestRedisStore_ConcurrentReplicas(parseT *testing.T) {
+	parseServer := buildFakeRedisServer(parseT, "")
+	const parseLimit = 5
+	const parseReplicas = 20
+
+	parseStores := make([]*RedisStore, parseReplicas)
+	for parseIndex := range parseStores {
+		parseStores[parseIndex] = buildTestRedisStore(parseT, RedisConfig{Address: parseServer.getAddress()})
+	}
+	var parseReserved atomic.Int64
+	var parseWG sync.WaitGroup
+	for parseIndex, parseStore := range parseStores {
+		parseWG.Add(1)
+		go func() {
+			defer parseWG.Done()
+			isReserved, parseErr := parseStore.Reserve(context.Background(), "active", strconv.Itoa(parseIndex), parseLimit, time.Minute)
+			if parseErr != nil {
+				parseT.Errorf("Reserve(%d) error: %v", parseIndex, parseErr)
+			}
+			if isReserved {
+				parseReserved.Add(1)
+			}
+		}()
+	}
+	parseWG.Wait()
+	if parseCount := parseReserved.Load(); parseCount != parseLimit {
+		parseT.Fatalf("reserved slots = %d, want %d", parseCount, parseLimit)
+	}
+}
+
+// TestRedisStore_SharedBridgeLimit verifies two bridge replicas enforce one active-connection cap through the store.
+func TestRedisStore_SharedBridgeLimit(parseT *testing.T) {
+	parseServer := buildFakeRedisServer(parseT, "")
+	parseBuildReplica := func() *httptest.Server {
+		parseHandler, parseErr := grpctunnel.BuildBridgeHandler(grpc.NewServer(), grpctunnel.BridgeConfig{
+			MaxActiveConnections: 1,
+			LimitStore:           buildTestRedisStore(parseT, RedisConfig{Address: parseServer.getAddress()}),
+		})
+		if parseErr != nil {
+			parseT.Fatalf("BuildBridgeHandler error: %v", parseErr)
+		}
+		parseReplica := httptest.NewServer(parseHandler)
+		parseT.Cleanup(parseReplica.Close)
+		return parseReplica
+	}
+	parseReplicaOne := parseBuildReplica()
+	parseReplicaTwo := parseBuildReplica()
+
+	parseConn, _, parseErr := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(parseReplicaOne.URL, "http"), nil)
+	if parseErr != nil {
+		parseT.Fatalf("dial replica one error: %v", parseErr)
+	}
+	defer parseConn.Close()
+
+	_, parseResp, parseErr := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(parseReplicaTwo.URL, "http"), nil)
+	if parseErr == nil {
+		parseT.Fatal("dial replica two expected shared active cap rejection, got nil")
+	}
+	if parseResp == nil || parseResp.StatusCode != http.StatusTooManyRequests {
+		parseT.Fatalf("replica two response = %v, want 429", parseResp)
+	}
+}
@@ -0,0 +1,172 @@
+//go:build !js && !wasm
+
+package grpctunnel
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+)
+
+const parseTunnelListenerNetwork = "websocket"
+const parseTunnelListenerAddress = "grpctunnel"
+
+// tunnelListenerAddr reports a stable placeholder address for websocket tunnel listeners.
+type tunnelListenerAddr struct{}
+
+// Network returns the listener network name.
+func (tunnelListenerAddr) Network() string {
+	return parseTunnelListenerNetwork
+}
+
+// String returns the listener address label.
+func (tunnelListenerAddr) String() string {
+	return parseTunnelListenerAddress
+}
+
+// tunnelListener hands upgraded websocket tunnels to a net.Listener consumer such as grpc.Server.Serve.
+type tunnelListener struct {
+	storeAcceptQueue chan net.Conn
+	getCloseSignal   chan struct{}
+	clearCloseOnce   sync.Once
+}
+
+// tunnelListenerConn reports when the listener consumer releases an accepted tunnel connection.
+type tunnelListenerConn struct {
+	net.Conn
+	getBaseContext   context.Context
+	getReleaseSignal chan struct{}
+	clearReleaseOnce sync.Once
+}
+
+// RemoteAddr returns the tunnel peer address, tagged with the Authenticate context when one was supplied.
+func (parseConn *tunnelListenerConn) RemoteAddr() net.Addr {
+	parseAddr := parseConn.Conn.RemoteAddr()
+	if parseConn.getBaseContext == nil {
+		return parseAddr
+	}
+	return tunnelPeerAddr{Addr: parseAddr, getContext: parseConn.getBaseContext}
+}
+
+// Close closes the tunneled connection and releases the owning upgrade handler.
+func (parseConn *tunnelListenerConn) Close() error {
+	parseErr := parseConn.Conn.Close()
+	parseConn.clearReleaseOnce.Do(func() {
+		close(parseConn.getReleaseSignal)
+	})
+	return parseErr
+}
+
+// NewListener creates a websocket bridge handler paired with a net.Listener that yields tunneled connections.
+//
+// Serving the listener with grpc.Server.Serve runs the native gRPC HTTP/2 transport
+// over each tunnel, so server keepalive enforcement, stats.Handler connection events,
+// MaxConcurrentStreams, and peer information behave as they do for TCP listeners.
+// RPC interceptors read the BridgeConfig.Authenticate context with AuthContextFromPeer.
+//
+// The handler has no Drain or Shutdown, and the bridge never counts listener RPCs. Stop tunnels
+// gracefully with grpc.Server.GracefulStop, which sends GOAWAY on every tunnel and waits for
+// in-flight RPCs; Server.Shutdown and its force-closed count cover NewServer tunnels only.
+//
+// Example:
+//
+//	grpcServer := grpc.NewServer()
+//	proto.RegisterYourServiceServer(grpcServer, &yourImpl{})
+//
+//	handler, listener, _ := grpctunnel.NewListener(grpctunnel.BridgeConfig{})
+//	go grpcServer.Serve(listener)
+//	http.ListenAndServe(":8080", handler)
+func NewListener(parseConfig BridgeConfig) (http.Handler, net.Listener, error) {
+	if parseErr := GetBridgeConfigError(parseConfig); parseErr != nil {
+		return nil, nil, parseErr
+	}
+	if !parseConfig.ExposurePolicy.isEmpty() {
+		return nil, nil, fmt.Errorf("grpctunnel: ExposurePolicy is not supported by NewListener; grpc.Server owns the transport, so restrict methods with server interceptors")
+	}
+	if parseConfig.RPCRateLimit.PerSecond > 0 {
+		return nil, nil, fmt.Errorf("grpctunnel: RPCRateLimit is not supported by NewListener; grpc.Server owns the transport, so limit RPCs with server interceptors or tap handles")
+	}
+	if len(parseConfig.BandwidthLimits.Methods) > 0 {
+		return nil, nil, fmt.Errorf("grpctunnel: BandwidthLimits.Methods is not supported by NewListener; grpc.Server owns the transport, so only Global and PerClient limits apply")
+	}
+	if parseConfig.MaxConnectionAge > 0 || parseConfig.MaxConnectionAgeGrace > 0 {
+		return nil, nil, fmt.Errorf("grpctunnel: MaxConnectionAge is not supported by NewListener; grpc.Server owns the transport, so rotate tunnels with keepalive.ServerParameters MaxConnectionAge and MaxConnectionAgeGrace")
+	}
+	if parseConfig.Authorize != nil {
+		return nil, nil, fmt.Errorf("grpctunnel: Authorize is not supported by NewListener; grpc.Server owns the transport, so authorize with server interceptors and AuthContextFromPeer")
+	}
+
+	parseConfig = applyBridgeTunnelObservers(parseConfig)
+	parseListener := &tunnelListener{
+		storeAcceptQueue: make(chan net.Conn),
+		getCloseSignal:   make(chan struct{}),
+	}
+	parseTunnelServer := buildBridgeTunnelServer(parseConfig, parseListener.handleTunnelConn)
+	return http.HandlerFunc(func(parseW http.ResponseWriter, parseR *http.Request) {
+		if parseListener.isClosed() {
+			logGrpctunnelEvent("grpctunnel.bridge", "WARN", "ws_upgrade_rejected_listener_closed", parseR, net.ErrClosed, "WebSocket upgrade rejected because the tunnel listener is closed")
+			storeBridgeUpgradeRejected(parseConfig.TunnelObserver, parseR, UpgradeRejectListenerClosed, http.StatusServiceUnavailable, net.ErrClosed)
+			http.Error(parseW, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+		parseTunnelServer.ServeHTTP(parseW, parseR)
+	}), parseListener, nil
+}
+
+// handleTunnelConn queues one tunneled connection for Accept and waits until the consumer releases it.
+func (parseListener *tunnelListener) handleTunnelConn(parseRequest *http.Request, parseTunnel *bridgeTunnel) {
+	parseListenerConn := &tunnelListenerConn{
+		Conn:             parseTunnel.getConn,
+		getBaseContext:   parseTunnel.getBaseContext,
+		getReleaseSignal: make(chan struct{}),
+	}
+	// grpc.Server owns the HTTP/2 transport of listener tunnels, so they install no GOAWAY drain
+	// hook; an expired Authenticate context closes the tunnel once AuthExpiryGrace has passed.
+	select {
+	case parseListener.storeAcceptQueue <- parseListenerConn:
+	case <-parseListener.getCloseSignal:
+		logGrpctunnelEvent("grpctunnel.bridge", "WARN", "tunnel_listener_closed", parseRequest, net.ErrClosed, "Tunnel listener closed before connection was accepted")
+		return
+	case <-parseRequest.Context().Done():
+		return
+	case <-parseListenerConn.getReleaseSignal:
+		return
+	}
+
+	<-parseListenerConn.getReleaseSignal
+}
+
+// Accept waits for and returns the next upgraded websocket tunnel.
+func (parseListener *tunnelListener) Accept() (net.Conn, error) {
+	select {
+	case parseConn := <-parseListener.storeAcceptQueue:
+		return parseConn, nil
+	case <-parseListener.getCloseSignal:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close stops accepting tunnels. Connections already accepted remain owned by the consumer.
+func (parseListener *tunnelListener) Close() error {
+	parseListener.clearCloseOnce.Do(func() {
+		close(parseListener.getCloseSignal)
+	})
+	return nil
+}
+
+// Addr returns the placeholder listener address.
+func (parseListener *tunnelListener) Addr() net.Addr {
+	return tunnelListenerAddr{}
+}
+
+// isClosed reports whether the listener has been closed.
+func (parseListener *tunnelListener) isClosed() bool {
+	select {
+	case <-parseListener.getCloseSignal:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,195 @@
+//go:build !js && !wasm
+
+package grpctunnel
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/monstercameron/grpc-tunnel/examples/_shared/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/stats"
+)
+
+// storeListenerTestStatsHandler counts native transport connection events.
+type storeListenerTestStatsHandler struct {
+	getConnBegin atomic.Int32
+	getConnEnd   atomic.Int32
+}
+
+// TagRPC returns the RPC context unchanged.
+func (parseH *storeListenerTestStatsHandler) TagRPC(parseCtx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return parseCtx
+}
+
+// HandleRPC ignores RPC events.
+func (parseH *storeListenerTestStatsHandler) HandleRPC(context.Context, stats.RPCStats) {}
+
+// TagConn returns the connection context unchanged.
+func (parseH *storeListenerTestStatsHandler) TagConn(parseCtx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return parseCtx
+}
+
+// HandleConn counts connection begin and end events.
+func (parseH *storeListenerTestStatsHandler) HandleConn(_ context.Context, parseStats stats.ConnStats) {
+	switch parseStats.(type) {
+	case *stats.ConnBegin:
+		parseH.getConnBegin.Add(1)
+	case *stats.ConnEnd:
+		parseH.getConnEnd.Add(1)
+	}
+}
+
+// storeListenerTestPeerService records the peer address seen by a unary RPC.
+type storeListenerTestPeerService struct {
+	mockService
+	getPeerAddress atomic.Value
+}
+
+// CreateTodo records peer information before delegating to the mock service.
+func (parseS *storeListenerTestPeerService) CreateTodo(parseCtx context.Context, parseReq *proto.CreateTodoRequest) (*proto.CreateTodoResponse, error) {
+	if parsePeer, isFoundPeer := peer.FromContext(parseCtx); isFoundPeer && parsePeer.Addr != nil {
+		parseS.getPeerAddress.Store(parsePeer.Addr.String())
+	}
+	return parseS.mockService.CreateTodo(parseCtx, parseReq)
+}
+
+// buildListenerTestServer starts a grpc.Server on a tunnel listener behind an httptest server.
+func buildListenerTestServer(parseT *testing.T, parseConfig BridgeConfig, parseServerOptions ...grpc.ServerOption) (*grpc.Server, *storeListenerTestPeerService, string, func()) {
+	parseT.Helper()
+
+	parseHandler, parseListener, parseErr := NewListener(parseConfig)
+	if parseErr != nil {
+		parseT.Fatalf("NewListener() error: %v", parseErr)
+	}
+
+	parseGrpcServer := grpc.NewServer(parseServerOptions...)
+	parseService := &storeListenerTestPeerService{}
+	proto.RegisterTodoServiceServer(parseGrpcServer, parseService)
+	go func() {
+		_ = parseGrpcServer.Serve(parseListener)
+	}()
+
+	parseHTTPServer := httptest.NewServer(parseHandler)
+	return parseGrpcServer, parseService, "ws" + parseHTTPServer.URL[4:], func() {
+		parseGrpcServer.Stop()
+		parseHTTPServer.Close()
+	}
+}
+
+// TestNewListener_InvalidConfig verifies listener creation validates bridge config.
+func TestNewListener_InvalidConfig(parseT *testing.T) {
+	if _, _, parseErr := NewListener(BridgeConfig{ReadBufferSize: -1}); parseErr == nil {
+		parseT.Fatal("NewListener() expected validation error, got nil")
+	}
+}
+
+// TestNewListener_ServesNativeTransport verifies RPCs, peer info, and stats handlers work through grpc.Server.Serve.
+func TestNewListener_ServesNativeTransport(parseT *testing.T) {
+	parseStatsHandler := &storeListenerTestStatsHandler{}
+	_, parseService, parseWsURL, clearServer := buildListenerTestServer(parseT, BridgeConfig{}, grpc.StatsHandler(parseStatsHandler))
+	defer clearServer()
+
+	parseCtx, clearCtx := context.WithTimeout(context.Background(), 5*time.Second)
+	defer clearCtx()
+
+	parseConn, parseErr := DialContext(parseCtx, parseWsURL, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if parseErr != nil {
+		parseT.Fatalf("DialContext() error: %v", parseErr)
+	}
+
+	parseClient := proto.NewTodoServiceClient(parseConn)
+	parseResp, parseErr := parseClient.CreateTodo(parseCtx, &proto.CreateTodoRequest{Text: "listener"})
+	if parseErr != nil {
+		parseT.Fatalf("CreateTodo() error: %v", parseErr)
+	}
+	if parseResp.Todo.Text != "listener" {
+		parseT.Fatalf("CreateTodo() text = %q, want %q", parseResp.Todo.Text, "listener")
+	}
+
+	parsePeerAddress, _ := parseService.getPeerAddress.Load().(string)
+	if parsePeerAddress == "" {
+		parseT.Fatal("expected peer address in RPC context")
+	}
+	if parseStatsHandler.getConnBegin.Load() != 1 {
+		parseT.Fatalf("ConnBegin events = %d, want 1", parseStatsHandler.getConnBegin.Load())
+	}
+
+	_ = parseConn.Close()
+	parseDeadline := time.Now().Add(2 * time.Second)
+	for parseStatsHandler.getConnEnd.Load() == 0 && time.Now().Before(parseDeadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if parseStatsHandler.getConnEnd.Load() != 1 {
+		parseT.Fatalf("ConnEnd events = %d, want 1", parseStatsHandler.getConnEnd.Load())
+	}
+}
+
+// TestNewListener_EnforcesServerKeepalive verifies server keepalive parameters close idle tunnels.
+func TestNewListener_EnforcesServerKeepalive(parseT *testing.T) {
+	parseDisconnectSignal := make(chan struct{}, 1)
+	_, _, parseWsURL, clearServer := buildListenerTestServer(
+		parseT,
+		BridgeConfig{
+			OnDisconnect: func(*http.Request) {
+				select {
+				case parseDisconnectSignal <- struct{}{}:
+				default:
+				}
+			},
+		},
+		grpc.KeepaliveParams(keepalive.ServerParameters{MaxConnectionIdle: 200 * time.Millisecond}),
+	)
+	defer clearServer()
+
+	parseCtx, clearCtx := context.WithTimeout(context.Background(), 5*time.Second)
+	defer clearCtx()
+
+	parseConn, parseErr := DialContext(parseCtx, parseWsURL, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if parseErr != nil {
+		parseT.Fatalf("DialContext() error: %v", parseErr)
+	}
+	defer parseConn.Close()
+
+	if _, parseErr = proto.NewTodoServiceClient(parseConn).ListTodos(parseCtx, &proto.ListTodosRequest{}); parseErr != nil {
+		parseT.Fatalf("ListTodos() error: %v", parseErr)
+	}
+
+	select {
+	case <-parseDisconnectSignal:
+	case <-time.After(3 * time.Second):
+		parseT.Fatal("expected MaxConnectionIdle to close the idle tunnel")
+	}
+}
+
+// TestNewListener_CloseRejectsUpgrades verifies a closed listener stops accepting and rejects new upgrades.
+func TestNewListener_CloseRejectsUpgrades(parseT *testing.T) {
+	parseHandler, parseListener, parseErr := NewListener(BridgeConfig{})
+	if parseErr != nil {
+		parseT.Fatalf("NewListener() error: %v", parseErr)
+	}
+	if parseListener.Addr().Network() != "websocket" {
+		parseT.Fatalf("Addr().Network() = %q, want %q", parseListener.Addr().Network(), "websocket")
+	}
+
+	if parseErr = parseListener.Close(); parseErr != nil {
+		parseT.Fatalf("Close() error: %v", parseErr)
+	}
+	if _, parseErr = parseListener.Accept(); parseErr != net.ErrClosed {
+		parseT.Fatalf("Accept() error = %v, want %v", parseErr, net.ErrClosed)
+	}
+
+	parseRecorder := httptest.NewRecorder()
+	parseHandler.ServeHTTP(parseRecorder, httptest.NewRequest(http.MethodGet, "/grpc", nil))
+	if parseRecorder.Code != http.StatusServiceUnavailable {
+		parseT.Fatalf("ServeHTTP() status = %d, want %d", parseRecorder.Code, http.StatusServiceUnavailable)
+	}
+}
@@ -0,0 +1,159 @@
+//go:build !js && !wasm
+
+package grpctunnel
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/monstercameron/grpc-tunnel/examples/_shared/proto"
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// TestGetBridgeConfigError_MaxConnectionAge verifies max-age validation.
+func TestGetBridgeConfigError_MaxConnectionAge(parseT *testing.T) {
+	for _, parseConfig := range []BridgeConfig{
+		{MaxConnectionAge: -time.Second},
+		{MaxConnectionAge: time.Second, MaxConnectionAgeGrace: -time.Second},
+		{MaxConnectionAgeGrace: time.Second},
+	} {
+		if parseErr := GetBridgeConfigError(parseConfig); parseErr == nil {
+			parseT.Fatalf("GetBridgeConfigError(%+v) = nil, want error", parseConfig)
+		}
+	}
+	if _, _, parseErr := NewListener(BridgeConfig{MaxConnectionAge: time.Second, MaxConnectionAgeGrace: time.Second}); parseErr == nil {
+		parseT.Fatal("NewListener() should reject MaxConnectionAge")
+	}
+}
+
+// TestGetBridgeTunnelMaxAge verifies jitter stays within 10% of MaxConnectionAge and varies.
+func TestGetBridgeTunnelMaxAge(parseT *testing.T) {
+	parseAges := map[time.Duration]struct{}{}
+	for parseIndex := 0; parseIndex < 100; parseIndex++ {
+		parseAge := getBridgeTunnelMaxAge(time.Minute)
+		if parseAge < 54*time.Second || parseAge > 66*time.Second {
+			parseT.Fatalf("getBridgeTunnelMaxAge(1m) = %v, want within 10%%", parseAge)
+		}
+		parseAges[parseAge] = struct{}{}
+	}
+	if len(parseAges) < 2 {
+		parseT.Fatal("getBridgeTunnelMaxAge() returned the same age every time, want jitter")
+	}
+}
+
+// TestBuildBridgeHandler_MaxConnectionAge verifies an aged tunnel receives GOAWAY, new RPCs move to
+// a fresh tunnel, and RPCs still running when the grace period ends are cut off.
+func TestBuildBridgeHandler_MaxConnectionAge(parseT *testing.T) {
+	parseReader := sdkmetric.NewManualReader()
+	parseMeterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(parseReader))
+	parseOriginalMeterProvider := otel.GetMeterProvider()
+	otel.SetMeterProvider(parseMeterProvider)
+	defer otel.SetMeterProvider(parseOriginalMeterProvider)
+	defer func() {
+		_ = parseMeterProvider.Shutdown(context.Background())
+	}()
+
+	parseGrpcServer := grpc.NewServer()
+	proto.RegisterTodoServiceServer(parseGrpcServer, &mockService{})
+	defer parseGrpcServer.Stop()
+
+	var parseConnects atomic.Int64
+	parseHandler, parseErr := BuildBridgeHandler(parseGrpcServer, BridgeConfig{
+		MaxConnectionAge:      200 * time.Millisecond,
+		MaxConnectionAgeGrace: 400 * time.Millisecond,
+		OnConnect: func(*http.Request) {
+			parseConnects.Add(1)
+		},
+	})
+	if parseErr != nil {
+		parseT.Fatalf("BuildBridgeHandler() error: %v", parseErr)
+	}
+	parseServer := httptest.NewServer(parseHandler)
+	defer parseServer.Close()
+
+	parseCtx, clearCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer clearCtx()
+	parseConn, parseErr := BuildTunnelConn(parseCtx, TunnelConfig{
+		Target:      "ws" + strings.TrimPrefix(parseServer.URL, "http"),
+		GRPCOptions: []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())},
+	})
+	if parseErr != nil {
+		parseT.Fatalf("BuildTunnelConn() error: %v", parseErr)
+	}
+	defer parseConn.Close()
+	parseClient := proto.NewTodoServiceClient(parseConn)
+
+	// A bidirectional stream stays in flight across the GOAWAY until the grace period ends.
+	parseStream, parseErr := parseClient.SyncTodos(parseCtx)
+	if parseErr != nil {
+		parseT.Fatalf("SyncTodos() error: %v", parseErr)
+	}
+	if parseErr := parseStream.Send(&proto.SyncRequest{Action: &proto.SyncRequest_Create{Create: &proto.CreateTodoRequest{Text: "sync"}}}); parseErr != nil {
+		parseT.Fatalf("Send() error: %v", parseErr)
+	}
+	if _, parseErr := parseStream.Recv(); parseErr != nil {
+		parseT.Fatalf("Recv() error: %v", parseErr)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	if _, parseErr := parseClient.CreateTodo(parseCtx, &proto.CreateTodoRequest{Text: "after rotation"}); parseErr != nil {
+		parseT.Fatalf("CreateTodo() after max age error: %v", parseErr)
+	}
+	if parseConnects.Load() < 2 {
+		parseT.Fatalf("tunnel connects after max age = %d, want a fresh tunnel", parseConnects.Load())
+	}
+	if parseErr := parseStream.Send(&proto.SyncRequest{Action: &proto.SyncRequest_Create{Create: &proto.CreateTodoRequest{Text: "sync"}}}); parseErr != nil {
+		parseT.Fatalf("Send() during grace error: %v", parseErr)
+	}
+	if _, parseErr := parseStream.Recv(); parseErr != nil {
+		parseT.Fatalf("Recv() during grace error: %v", parseErr)
+	}
+
+	if _, parseErr := parseStream.Recv(); status.Code(parseErr) != codes.Unavailable {
+		parseT.Fatalf("Recv() after grace error = %v, want Unavailable", parseErr)
+	}
+
+	// The grace timer counts the cut-off after closing the tunnel, so the client can see it first.
+	for parseDeadline := time.Now().Add(2 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		parseResourceMetrics := metricdata.ResourceMetrics{}
+		if parseCollectErr := parseReader.Collect(context.Background(), &parseResourceMetrics); parseCollectErr != nil {
+			parseT.Fatalf("Collect() error: %v", parseCollectErr)
+		}
+		parseStages := getBridgeTunnelMaxAgeByStage(parseResourceMetrics)
+		if parseStages[parseBridgeMaxAgeStageGoAway] >= 1 && parseStages[parseBridgeMaxAgeStageGraceExpired] == 1 {
+			break
+		}
+		if time.Now().After(parseDeadline) {
+			parseT.Fatalf("%s by stage = %v, want at least one goaway and one grace_expired", parseBridgeTunnelMaxAgeTotalMetric, parseStages)
+		}
+	}
+}
+
+// getBridgeTunnelMaxAgeByStage sums the max-age counter by stage.
+func getBridgeTunnelMaxAgeByStage(parseResourceMetrics metricdata.ResourceMetrics) map[string]int64 {
+	parseTotals := map[string]int64{}
+	for _, parseScopeMetrics := range parseResourceMetrics.ScopeMetrics {
+		for _, parseMetric := range parseScopeMetrics.Metrics {
+			parseSum, isSum := parseMetric.Data.(metricdata.Sum[int64])
+			if parseMetric.Name != parseBridgeTunnelMaxAgeTotalMetric || !isSum {
+				continue
+			}
+			for _, parseDataPoint := range parseSum.DataPoints {
+				parseStage, _ := parseDataPoint.Attributes.Value("stage")
+				parseTotals[parseStage.AsString()] += parseDataPoint.Value
+			}
+		}
+	}
+	return parseTotals
+}
@@ -0,0 +1,18 @@
+// Package membudget provides a process-wide memory accountant for websocket tunnels.
+//
+// A Budget charges each tunnel a fixed overhead for its websocket buffers plus the HTTP/2 DATA
+// the client has sent that the server has not yet acknowledged with a connection-level
+// WINDOW_UPDATE, which approximates request bytes the bridge is holding. As usage crosses
+// ThrottleRatio of the limit, connection-level window updates sent to clients shrink in proportion
+// to the remaining headroom, and the withheld credit is returned once usage falls again. Above
+// RejectRatio, bridges refuse new upgrades with 503 and new streams with RESOURCE_EXHAUSTED.
+//
+// One Budget is meant to be shared by every handler in the process:
+//
+//	parseBudget, _ := membudget.New(membudget.Config{LimitBytes: 2 << 30})
+//	tunnelHandler, _ := grpctunnel.BuildBridgeHandler(grpcServer, grpctunnel.BridgeConfig{MemoryBudget: parseBudget})
+//	proxyHandler := bridge.NewHandler(bridge.Config{TargetAddress: "localhost:50051", MemoryBudget: parseBudget})
+//
+// Usage and the limit are exported as the bridge_memory_budget_used_bytes and
+// bridge_memory_budget_limit_bytes gauges, labeled with the budget Name.
+package membudget
@@ -0,0 +1,430 @@
+package membudget
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const parseMembudgetObservabilityScope = "github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/membudget"
+const parseMemoryBudgetUsedMetric = "bridge_memory_budget_used_bytes"
+const parseMemoryBudgetLimitMetric = "bridge_memory_budget_limit_bytes"
+
+const parseDefaultBudgetName = "default"
+const parseDefaultThrottleRatio = 0.75
+const parseDefaultRejectRatio = 0.9
+
+// parseWindowReleaseInterval is how often a tunnel with withheld credit checks whether it can return it.
+const parseWindowReleaseInterval = 100 * time.Millisecond
+
+const parseHTTP2PrefaceLength = 24
+const parseHTTP2FrameHeaderLength = 9
+const parseHTTP2FrameData = 0x0
+const parseHTTP2FrameWindowUpdate = 0x8
+const parseHTTP2MaxWindowIncrement = 1<<31 - 1
+
+// Config configures a Budget.
+type Config struct {
+	// Name labels the gauges with a budget attribute so several budgets can be told apart.
+	// Empty uses "default".
+	Name string
+	// LimitBytes is the process-wide budget. It must be > 0.
+	LimitBytes int64
+	// ThrottleRatio is the fraction of LimitBytes above which connection-level HTTP/2 window
+	// updates shrink. Zero uses 0.75.
+	ThrottleRatio float64
+	// RejectRatio is the fraction of LimitBytes above which new upgrades and streams are refused.
+	// Zero uses 0.9.
+	RejectRatio float64
+}
+
+// Budget accounts tunnel memory against a process-wide limit. A nil Budget accounts nothing and is
+// never exhausted.
+type Budget struct {
+	getAttributes metric.MeasurementOption
+	getLimit      int64
+	getThrottleAt int64
+	getRejectAt   int64
+	storeUsed     atomic.Int64
+}
+
+// New validates the configuration and creates a Budget reported by the usage gauges of the global
+// OpenTelemetry meter provider. Budgets are meant to live for the whole process.
+func New(parseConfig Config) (*Budget, error) {
+	if parseConfig.LimitBytes <= 0 {
+		return nil, errors.New("membudget: LimitBytes must be > 0")
+	}
+	if parseConfig.ThrottleRatio < 0 || parseConfig.ThrottleRatio > 1 || parseConfig.RejectRatio < 0 || parseConfig.RejectRatio > 1 {
+		return nil, errors.New("membudget: ThrottleRatio and RejectRatio must be between 0 and 1")
+	}
+	if parseConfig.ThrottleRatio == 0 {
+		parseConfig.ThrottleRatio = parseDefaultThrottleRatio
+	}
+	if parseConfig.RejectRatio == 0 {
+		parseConfig.RejectRatio = parseDefaultRejectRatio
+	}
+	if parseConfig.ThrottleRatio > parseConfig.RejectRatio {
+		return nil, errors.New("membudget: ThrottleRatio must not exceed RejectRatio")
+	}
+	parseBudget := &Budget{
+		getLimit:      parseConfig.LimitBytes,
+		getThrottleAt: int64(float64(parseConfig.LimitBytes) * parseConfig.ThrottleRatio),
+		getRejectAt:   int64(float64(parseConfig.LimitBytes) * parseConfig.RejectRatio),
+	}
+
+	if parseConfig.Name == "" {
+		parseConfig.Name = parseDefaultBudgetName
+	}
+	parseBudget.getAttributes = metric.WithAttributes(attribute.String("budget", parseConfig.Name))
+	storeBudgetGauges(parseBudget)
+	return parseBudget, nil
+}
+
+// budgetGauges reports every Budget through one pair of gauges, since instruments are registered
+// once per meter.
+var budgetGauges struct {
+	setOnce      sync.Once
+	setLock      sync.Mutex
+	storeBudgets []*Budget
+}
+
+// storeBudgetGauges adds a budget to the gauges, registering them with the global meter provider on
+// first use.
+func storeBudgetGauges(parseBudget *Budget) {
+	budgetGauges.setLock.Lock()
+	budgetGauges.storeBudgets = append(budgetGauges.storeBudgets, parseBudget)
+	budgetGauges.setLock.Unlock()
+
+	budgetGauges.setOnce.Do(func() {
+		parseMeter := otel.Meter(parseMembudgetObservabilityScope)
+		parseUsed, _ := parseMeter.Int64ObservableGauge(
+			parseMemoryBudgetUsedMetric,
+			metric.WithUnit("By"),
+			metric.WithDescription("Tunnel memory currently charged to the process-wide budget"),
+		)
+		parseLimit, _ := parseMeter.Int64ObservableGauge(
+			parseMemoryBudgetLimitMetric,
+			metric.WithUnit("By"),
+			metric.WithDescription("Process-wide tunnel memory budget"),
+		)
+		if parseUsed == nil || parseLimit == nil {
+			return
+		}
+		_, _ = parseMeter.RegisterCallback(func(_ context.Context, parseObserver metric.Observer) error {
+			budgetGauges.setLock.Lock()
+			defer budgetGauges.setLock.Unlock()
+			for _, parseBudget := range budgetGauges.storeBudgets {
+				parseObserver.ObserveInt64(parseUsed, parseBudget.Used(), parseBudget.getAttributes)
+				parseObserver.ObserveInt64(parseLimit, parseBudget.getLimit, parseBudget.getAttributes)
+			}
+			return nil
+		}, parseUsed, parseLimit)
+	})
+}
+
+// Limit returns the budget in bytes.
+func (parseBudget *Budget) Limit() int64 {
+	if parseBudget == nil {
+		return 0
+	}
+	return parseBudget.getLimit
+}
+
+// Used returns the bytes currently charged.
+func (parseBudget *Budget) Used() int64 {
+	if parseBudget == nil {
+		return 0
+	}
+	return parseBudget.storeUsed.Load()
+}
+
+// Reserve charges bytes held outside tunnel accounting, such as caches sized by traffic.
+func (parseBudget *Budget) Reserve(parseBytes int64) {
+	if parseBudget == nil || parseBytes <= 0 {
+		return
+	}
+	parseBudget.storeUsed.Add(parseBytes)
+}
+
+// Release returns bytes charged by Reserve.
+func (parseBudget *Budget) Release(parseBytes int64) {
+	if parseBudget == nil || parseBytes <= 0 {
+		return
+	}
+	parseBudget.storeUsed.Add(-parseBytes)
+}
+
+// IsExhausted reports whether usage has reached RejectRatio, at which point new upgrades and
+// streams are refused.
+func (parseBudget *Budget) IsExhausted() bool {
+	if parseBudget == nil {
+		return false
+	}
+	return parseBudget.storeUsed.Load() >= parseBudget.getRejectAt
+}
+
+// getWindowFraction returns the share of each window update to pass on: 1 below ThrottleRatio,
+// falling linearly to 0 at the limit.
+func (parseBudget *Budget) getWindowFraction() float64 {
+	parseUsed := parseBudget.storeUsed.Load()
+	if parseUsed <= parseBudget.getThrottleAt {
+		return 1
+	}
+	if parseUsed >= parseBudget.getLimit || parseBudget.getLimit <= parseBudget.getThrottleAt {
+		return 0
+	}
+	return float64(parseBudget.getLimit-parseUsed) / float64(parseBudget.getLimit-parseBudget.getThrottleAt)
+}
+
+// Tunnel accounts the HTTP/2 traffic of one websocket tunnel. Bridges call ObserveRead with bytes
+// read from the client and ShapeWrite with bytes written to it. A nil Tunnel passes traffic through.
+type Tunnel struct {
+	getBudget      *Budget
+	getOverhead    int64
+	setLock        sync.Mutex
+	storeCharged   int64
+	storeWithheld  int64
+	getReadFrames  http2FrameParser
+	getWriteFrames http2FrameParser
+	getCloseSignal chan struct{}
+	setCloseOnce   sync.Once
+}
+
+// http2FrameParser follows frame boundaries in one direction of an HTTP/2 byte stream that may be
+// split across arbitrary reads or writes.
+type http2FrameParser struct {
+	storeSkip        int
+	storeHeader      [parseHTTP2FrameHeaderLength]byte
+	storeHeaderLen   int
+	storePayloadLeft int
+	isWindowUpdate   bool
+	storeIncrement   [4]byte
+	storeIncrementAt int
+}
+
+// NewTunnel charges a tunnel's fixed overhead and returns its accountant. Nil budgets return nil.
+func (parseBudget *Budget) NewTunnel(parseOverhead int64) *Tunnel {
+	if parseBudget == nil {
+		return nil
+	}
+	if parseOverhead < 0 {
+		parseOverhead = 0
+	}
+	parseBudget.storeUsed.Add(parseOverhead)
+	return &Tunnel{
+		getBudget:      parseBudget,
+		getOverhead:    parseOverhead,
+		getReadFrames:  http2FrameParser{storeSkip: parseHTTP2PrefaceLength},
+		getCloseSignal: make(chan struct{}),
+	}
+}
+
+// Start returns withheld window credit through parseRelease while the tunnel is open. parseRelease
+// must serialize with the tunnel's writes and call TakeWindowUpdate.
+func (parseTunnel *Tunnel) Start(parseRelease func() error) {
+	if parseTunnel == nil {
+		return
+	}
+	go func() {
+		parseTicker := time.NewTicker(parseWindowReleaseInterval)
+		defer parseTicker.Stop()
+		for {
+			select {
+			case <-parseTunnel.getCloseSignal:
+				return
+			case <-parseTicker.C:
+				parseTunnel.setLock.Lock()
+				isWithheld := parseTunnel.storeWithheld > 0
+				parseTunnel.setLock.Unlock()
+				if isWithheld && parseRelease() != nil {
+					return
+				}
+			}
+		}
+	}()
+}
+
+// ObserveRead charges DATA frames read from the client.
+func (parseTunnel *Tunnel) ObserveRead(parseP []byte) {
+	if parseTunnel == nil {
+		return
+	}
+	parseTunnel.setLock.Lock()
+	defer parseTunnel.setLock.Unlock()
+	parseFrames := &parseTunnel.getReadFrames
+	for parseIndex := 0; parseIndex < len(parseP); {
+		parseIndex += parseFrames.skipHTTP2Payload(len(parseP) - parseIndex)
+		if parseIndex >= len(parseP) {
+			return
+		}
+		parseIndex++
+		if !parseFrames.storeHTTP2HeaderByte(parseP[parseIndex-1]) {
+			continue
+		}
+		if parseFrames.storeHeader[3] == parseHTTP2FrameData {
+			parseLength := int64(parseFrames.storePayloadLeft)
+			parseTunnel.storeCharged += parseLength
+			parseTunnel.getBudget.storeUsed.Add(parseLength)
+		}
+	}
+}
+
+// ShapeWrite credits connection-level WINDOW_UPDATE frames written to the client against charged
+// DATA and, while the budget is under pressure, shrinks their increments. It returns parseP, or a
+// rewritten copy when an increment changed.
+func (parseTunnel *Tunnel) ShapeWrite(parseP []byte) []byte {
+	if parseTunnel == nil {
+		return parseP
+	}
+	parseTunnel.setLock.Lock()
+	defer parseTunnel.setLock.Unlock()
+	parseFrames := &parseTunnel.getWriteFrames
+	parseOut := parseP
+	isCopied := false
+	for parseIndex := 0; parseIndex < len(parseP); {
+		if parseFrames.isWindowUpdate {
+			for parseFrames.storeIncrementAt < 4 && parseIndex < len(parseP) {
+				parseFrames.storeIncrement[parseFrames.storeIncrementAt] = parseP[parseIndex]
+				parseFrames.storeIncrementAt++
+				parseFrames.storePayloadLeft--
+				parseIndex++
+			}
+			if parseFrames.storeIncrementAt < 4 {
+				return parseOut
+			}
+			// An increment split across writes releases charged DATA but passes through unchanged,
+			// so none of it is withheld.
+			parseFrames.isWindowUpdate = false
+			parseTunnel.creditWindowUpdate(binary.BigEndian.Uint32(parseFrames.storeIncrement[:]) & parseHTTP2MaxWindowIncrement)
+			continue
+		}
+		parseIndex += parseFrames.skipHTTP2Payload(len(parseP) - parseIndex)
+		if parseIndex >= len(parseP) {
+			return parseOut
+		}
+		parseIndex++
+		if !parseFrames.storeHTTP2HeaderByte(parseP[parseIndex-1]) {
+			continue
+		}
+		parseStreamID := binary.BigEndian.Uint32(parseFrames.storeHeader[5:]) & parseHTTP2MaxWindowIncrement
+		if parseFrames.storeHeader[3] != parseHTTP2FrameWindowUpdate || parseStreamID != 0 || parseFrames.storePayloadLeft != 4 {
+			continue
+		}
+		if len(parseP)-parseIndex < 4 {
+			parseFrames.isWindowUpdate = true
+			parseFrames.storeIncrementAt = 0
+			continue
+		}
+		parseIncrement := binary.BigEndian.Uint32(parseP[parseIndex:]) & parseHTTP2MaxWindowIncrement
+		parseTunnel.creditWindowUpdate(parseIncrement)
+		parseShaped := parseTunnel.shapeWindowUpdate(parseIncrement)
+		if parseShaped != parseIncrement {
+			if !isCopied {
+				// Writers must not modify the caller's slice, so rewrite a copy.
+				parseOut = append([]byte(nil), parseP...)
+				isCopied = true
+			}
+			binary.BigEndian.PutUint32(parseOut[parseIndex:], parseShaped)
+		}
+		parseFrames.storePayloadLeft = 0
+		parseIndex += 4
+	}
+	return parseOut
+}
+
+// creditWindowUpdate releases charged DATA acknowledged by a connection-level increment.
+// Increments beyond the charged DATA grow the window rather than acknowledge data, so they
+// release nothing.
+func (parseTunnel *Tunnel) creditWindowUpdate(parseIncrement uint32) {
+	parseCredit := min(int64(parseIncrement), parseTunnel.storeCharged)
+	parseTunnel.storeCharged -= parseCredit
+	parseTunnel.getBudget.storeUsed.Add(-parseCredit)
+}
+
+// shapeWindowUpdate returns the increment to pass on under the current budget pressure and records
+// the difference as withheld credit for TakeWindowUpdate to hand back.
+func (parseTunnel *Tunnel) shapeWindowUpdate(parseIncrement uint32) uint32 {
+	parseFraction := parseTunnel.getBudget.getWindowFraction()
+	if parseFraction >= 1 || parseIncrement <= 1 {
+		return parseIncrement
+	}
+	// A zero increment is a protocol error, so at least one byte of credit always passes.
+	parseShaped := max(uint32(float64(parseIncrement)*parseFraction), 1)
+	parseTunnel.storeWithheld += int64(parseIncrement - parseShaped)
+	return parseShaped
+}
+
+// TakeWindowUpdate returns a connection-level WINDOW_UPDATE frame that hands back credit withheld
+// under pressure, in proportion to the headroom regained. It returns nil when nothing can be
+// released or the last write ended mid-frame. Callers write the frame before any further writes.
+func (parseTunnel *Tunnel) TakeWindowUpdate() []byte {
+	if parseTunnel == nil {
+		return nil
+	}
+	parseTunnel.setLock.Lock()
+	defer parseTunnel.setLock.Unlock()
+	parseFrames := &parseTunnel.getWriteFrames
+	if parseTunnel.storeWithheld <= 0 || parseFrames.storeHeaderLen != 0 || parseFrames.storePayloadLeft != 0 || parseFrames.isWindowUpdate {
+		return nil
+	}
+	parseRelease := min(int64(float64(parseTunnel.storeWithheld)*parseTunnel.getBudget.getWindowFraction()), parseHTTP2MaxWindowIncrement)
+	if parseRelease < 1 {
+		return nil
+	}
+	parseTunnel.storeWithheld -= parseRelease
+	parseFrame := make([]byte, parseHTTP2FrameHeaderLength+4)
+	parseFrame[2] = 4
+	parseFrame[3] = parseHTTP2FrameWindowUpdate
+	binary.BigEndian.PutUint32(parseFrame[parseHTTP2FrameHeaderLength:], uint32(parseRelease))
+	return parseFrame
+}
+
+// Close releases everything the tunnel still has charged. It is safe to call more than once.
+func (parseTunnel *Tunnel) Close() {
+	if parseTunnel == nil {
+		return
+	}
+	parseTunnel.setCloseOnce.Do(func() {
+		close(parseTunnel.getCloseSignal)
+		parseTunnel.setLock.Lock()
+		defer parseTunnel.setLock.Unlock()
+		parseTunnel.getBudget.storeUsed.Add(-(parseTunnel.getOverhead + parseTunnel.storeCharged))
+		parseTunnel.storeCharged = 0
+	})
+}
+
+// skipHTTP2Payload consumes up to parseAvailable bytes of preface or frame payload and returns how
+// many it consumed.
+func (parseFrames *http2FrameParser) skipHTTP2Payload(parseAvailable int) int {
+	if parseFrames.storeHeaderLen != 0 {
+		return 0
+	}
+	if parseFrames.storeSkip > 0 {
+		parseSkipped := min(parseFrames.storeSkip, parseAvailable)
+		parseFrames.storeSkip -= parseSkipped
+		return parseSkipped
+	}
+	parseSkipped := min(parseFrames.storePayloadLeft, parseAvailable)
+	parseFrames.storePayloadLeft -= parseSkipped
+	return parseSkipped
+}
+
+// storeHTTP2HeaderByte appends one frame header byte and reports whether the header is complete,
+// in which case storePayloadLeft holds the frame length.
+func (parseFrames *http2FrameParser) storeHTTP2HeaderByte(parseByte byte) bool {
+	parseFrames.storeHeader[parseFrames.storeHeaderLen] = parseByte
+	parseFrames.storeHeaderLen++
+	if parseFrames.storeHeaderLen < parseHTTP2FrameHeaderLength {
+		return false
+	}
+	parseFrames.storeHeaderLen = 0
+	parseFrames.storePayloadLeft = int(parseFrames.storeHeader[0])<<16 | int(parseFrames.storeHeader[1])<<8 | int(parseFrames.storeHeader[2])
+	return true
+}
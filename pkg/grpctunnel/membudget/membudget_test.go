@@ -0,0 +1,203 @@
+package membudget
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"golang.org/x/net/http2"
+)
+
+// buildMembudgetTestFrame encodes one HTTP/2 frame.
+func buildMembudgetTestFrame(parseType byte, parseStreamID uint32, parsePayload []byte) []byte {
+	parseFrame := []byte{byte(len(parsePayload) >> 16), byte(len(parsePayload) >> 8), byte(len(parsePayload)), parseType, 0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(parseFrame[5:], parseStreamID)
+	return append(parseFrame, parsePayload...)
+}
+
+// buildMembudgetTestWindowUpdate encodes a WINDOW_UPDATE frame.
+func buildMembudgetTestWindowUpdate(parseStreamID uint32, parseIncrement uint32) []byte {
+	parsePayload := make([]byte, 4)
+	binary.BigEndian.PutUint32(parsePayload, parseIncrement)
+	return buildMembudgetTestFrame(parseHTTP2FrameWindowUpdate, parseStreamID, parsePayload)
+}
+
+// TestNew_Config verifies configuration validation.
+func TestNew_Config(parseT *testing.T) {
+	for _, parseConfig := range []Config{
+		{},
+		{LimitBytes: 100, ThrottleRatio: 1.5},
+		{LimitBytes: 100, RejectRatio: -0.1},
+		{LimitBytes: 100, ThrottleRatio: 0.9, RejectRatio: 0.5},
+	} {
+		if _, parseErr := New(parseConfig); parseErr == nil {
+			parseT.Fatalf("New(%+v) = nil error, want error", parseConfig)
+		}
+	}
+	var parseBudget *Budget
+	if parseBudget.IsExhausted() || parseBudget.NewTunnel(10) != nil {
+		parseT.Fatal("nil Budget should never be exhausted or create tunnels")
+	}
+}
+
+// TestTunnel_ChargesDataAndCreditsWindowUpdates verifies DATA split across reads is charged and
+// connection-level window updates release it, while stream-level updates and window growth do not.
+func TestTunnel_ChargesDataAndCreditsWindowUpdates(parseT *testing.T) {
+	parseBudget, parseErr := New(Config{LimitBytes: 1 << 20})
+	if parseErr != nil {
+		parseT.Fatalf("New() error: %v", parseErr)
+	}
+	parseTunnel := parseBudget.NewTunnel(100)
+	if parseBudget.Used() != 100 {
+		parseT.Fatalf("Used() after NewTunnel = %d, want 100", parseBudget.Used())
+	}
+
+	parseInbound := append([]byte(http2.ClientPreface), buildMembudgetTestFrame(0x4, 0, nil)...)
+	parseInbound = append(parseInbound, buildMembudgetTestFrame(parseHTTP2FrameData, 1, make([]byte, 300))...)
+	parseInbound = append(parseInbound, buildMembudgetTestFrame(parseHTTP2FrameData, 3, make([]byte, 200))...)
+	for parseIndex := 0; parseIndex < len(parseInbound); parseIndex += 7 {
+		parseTunnel.ObserveRead(parseInbound[parseIndex:min(parseIndex+7, len(parseInbound))])
+	}
+	if parseBudget.Used() != 600 {
+		parseT.Fatalf("Used() after DATA = %d, want 600", parseBudget.Used())
+	}
+
+	parseOutbound := append(buildMembudgetTestWindowUpdate(1, 300), buildMembudgetTestWindowUpdate(0, 300)...)
+	if parseShaped := parseTunnel.ShapeWrite(parseOutbound); !bytes.Equal(parseShaped, parseOutbound) {
+		parseT.Fatal("ShapeWrite() changed window updates without budget pressure")
+	}
+	if parseBudget.Used() != 300 {
+		parseT.Fatalf("Used() after connection WINDOW_UPDATE = %d, want 300", parseBudget.Used())
+	}
+	parseTunnel.ShapeWrite(buildMembudgetTestWindowUpdate(0, 1<<20))
+	if parseBudget.Used() != 100 {
+		parseT.Fatalf("Used() after window growth = %d, want 100", parseBudget.Used())
+	}
+
+	parseTunnel.Close()
+	parseTunnel.Close()
+	if parseBudget.Used() != 0 {
+		parseT.Fatalf("Used() after Close = %d, want 0", parseBudget.Used())
+	}
+}
+
+// TestTunnel_ShrinksAndReturnsWindowCredit verifies increments shrink under pressure without
+// modifying the caller's buffer and that withheld credit returns as usage falls.
+func TestTunnel_ShrinksAndReturnsWindowCredit(parseT *testing.T) {
+	parseBudget, parseErr := New(Config{LimitBytes: 1000, ThrottleRatio: 0.5, RejectRatio: 0.8})
+	if parseErr != nil {
+		parseT.Fatalf("New() error: %v", parseErr)
+	}
+	parseTunnel := parseBudget.NewTunnel(0)
+	defer parseTunnel.Close()
+
+	parseBudget.Reserve(750)
+	if parseBudget.IsExhausted() {
+		parseT.Fatal("IsExhausted() below RejectRatio = true, want false")
+	}
+	parseOutbound := append(buildMembudgetTestFrame(0x6, 0, make([]byte, 8)), buildMembudgetTestWindowUpdate(0, 1000)...)
+	parseOriginal := append([]byte(nil), parseOutbound...)
+	parseShaped := parseTunnel.ShapeWrite(parseOutbound)
+	if !bytes.Equal(parseOutbound, parseOriginal) {
+		parseT.Fatal("ShapeWrite() modified the caller's buffer")
+	}
+	if parseIncrement := binary.BigEndian.Uint32(parseShaped[len(parseShaped)-4:]); parseIncrement != 500 {
+		parseT.Fatalf("shaped increment = %d, want 500 at half headroom", parseIncrement)
+	}
+
+	if parseFrame := parseTunnel.TakeWindowUpdate(); parseFrame == nil || binary.BigEndian.Uint32(parseFrame[9:]) != 250 {
+		parseT.Fatalf("TakeWindowUpdate() under pressure = %v, want increment 250", parseFrame)
+	}
+	parseBudget.Reserve(100)
+	if !parseBudget.IsExhausted() {
+		parseT.Fatal("IsExhausted() at RejectRatio = false, want true")
+	}
+	parseBudget.Release(850)
+	parseFrame := parseTunnel.TakeWindowUpdate()
+	if parseFrame == nil || parseFrame[3] != parseHTTP2FrameWindowUpdate || binary.BigEndian.Uint32(parseFrame[5:9]) != 0 || binary.BigEndian.Uint32(parseFrame[9:]) != 250 {
+		parseT.Fatalf("TakeWindowUpdate() after recovery = %v, want connection increment 250", parseFrame)
+	}
+	if parseTunnel.TakeWindowUpdate() != nil {
+		parseT.Fatal("TakeWindowUpdate() returned credit twice")
+	}
+
+	// Credit is only injected at a frame boundary.
+	parseBudget.Reserve(750)
+	parseTunnel.ShapeWrite(buildMembudgetTestWindowUpdate(0, 1000))
+	parseBudget.Release(750)
+	parseTunnel.ShapeWrite(buildMembudgetTestFrame(0x0, 1, make([]byte, 10))[:12])
+	if parseTunnel.TakeWindowUpdate() != nil {
+		parseT.Fatal("TakeWindowUpdate() mid-frame returned a frame")
+	}
+}
+
+// TestTunnel_SplitWindowUpdatePassesThrough verifies an increment split across writes releases
+// charged DATA without being withheld, so no extra credit is handed back later.
+func TestTunnel_SplitWindowUpdatePassesThrough(parseT *testing.T) {
+	parseBudget, parseErr := New(Config{LimitBytes: 1000, ThrottleRatio: 0.5, RejectRatio: 0.8})
+	if parseErr != nil {
+		parseT.Fatalf("New() error: %v", parseErr)
+	}
+	parseTunnel := parseBudget.NewTunnel(0)
+	defer parseTunnel.Close()
+
+	parseTunnel.ObserveRead(append([]byte(http2.ClientPreface), buildMembudgetTestFrame(parseHTTP2FrameData, 1, make([]byte, 100))...))
+	parseBudget.Reserve(650)
+	parseOutbound := buildMembudgetTestWindowUpdate(0, 1000)
+	parseFirst := parseTunnel.ShapeWrite(parseOutbound[:11])
+	parseSecond := parseTunnel.ShapeWrite(parseOutbound[11:])
+	if !bytes.Equal(append(parseFirst, parseSecond...), parseOutbound) {
+		parseT.Fatal("ShapeWrite() changed a split window update")
+	}
+	if parseBudget.Used() != 650 {
+		parseT.Fatalf("Used() after split WINDOW_UPDATE = %d, want 650", parseBudget.Used())
+	}
+	parseBudget.Release(650)
+	if parseFrame := parseTunnel.TakeWindowUpdate(); parseFrame != nil {
+		parseT.Fatalf("TakeWindowUpdate() after split increment = %v, want nil", parseFrame)
+	}
+}
+
+// TestNew_Gauges verifies usage and limit are exported as gauges.
+func TestNew_Gauges(parseT *testing.T) {
+	parseReader := sdkmetric.NewManualReader()
+	parseMeterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(parseReader))
+	parseOriginalMeterProvider := otel.GetMeterProvider()
+	otel.SetMeterProvider(parseMeterProvider)
+	defer otel.SetMeterProvider(parseOriginalMeterProvider)
+	defer func() {
+		_ = parseMeterProvider.Shutdown(context.Background())
+	}()
+
+	parseBudget, parseErr := New(Config{Name: "gauge-test", LimitBytes: 4096})
+	if parseErr != nil {
+		parseT.Fatalf("New() error: %v", parseErr)
+	}
+	parseBudget.NewTunnel(1024)
+
+	parseResourceMetrics := metricdata.ResourceMetrics{}
+	if parseCollectErr := parseReader.Collect(context.Background(), &parseResourceMetrics); parseCollectErr != nil {
+		parseT.Fatalf("Collect() error: %v", parseCollectErr)
+	}
+	parseValues := map[string]int64{}
+	for _, parseScopeMetrics := range parseResourceMetrics.ScopeMetrics {
+		for _, parseMetric := range parseScopeMetrics.Metrics {
+			parseGauge, isGauge := parseMetric.Data.(metricdata.Gauge[int64])
+			if !isGauge {
+				continue
+			}
+			for _, parseDataPoint := range parseGauge.DataPoints {
+				if parseName, _ := parseDataPoint.Attributes.Value("budget"); parseName.AsString() == "gauge-test" {
+					parseValues[parseMetric.Name] = parseDataPoint.Value
+				}
+			}
+		}
+	}
+	if parseValues[parseMemoryBudgetUsedMetric] != 1024 || parseValues[parseMemoryBudgetLimitMetric] != 4096 {
+		parseT.Fatalf("gauges = %v, want used 1024 and limit 4096", parseValues)
+	}
+}
@@ -4,6 +4,7 @@ package grpctunnel
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strings"
 	"time"
@@ -22,9 +23,21 @@ const parseBridgeConnectionsActiveMetric = "bridge_connections_active"
 const parseBridgeConnectionsTotalMetric = "bridge_connections_total"
 const parseBridgeUpgradeFailuresTotalMetric = "bridge_upgrade_failures_total"
 const parseBridgeUpgradeLatencyMetric = "bridge_request_latency_ms"
+const parseBridgeRPCDeniedTotalMetric = "bridge_rpc_denied_total"
+const parseBridgeAbuseRejectionsTotalMetric = "bridge_abuse_rejections_total"
+const parseBridgeBandwidthThrottleMetric = "bridge_bandwidth_throttle_seconds_total"
+const parseBridgeUpgradeTimeoutsTotalMetric = "bridge_upgrade_timeouts_total"
+const parseBridgePrefaceTimeoutsTotalMetric = "bridge_preface_timeouts_total"
+const parseBridgeSlowReadsTotalMetric = "bridge_slow_read_disconnects_total"
+const parseBridgeWriteTimeoutsTotalMetric = "bridge_write_timeout_disconnects_total"
+const parseBridgeTunnelMaxAgeTotalMetric = "bridge_tunnel_max_age_total"
 
 const parseBridgeMetricResultSuccess = "success"
 const parseBridgeMetricResultFailure = "failure"
+const parseBridgeMetricReasonExposure = "exposure_policy"
+const parseBridgeMetricReasonAuthorization = "authorization"
+const parseBridgeMetricReasonRateLimit = "rate_limit"
+const parseBridgeMetricReasonMemoryBudget = "memory_budget"
 
 // bridgeObservability stores OTel tracer and metrics handles for bridge runtime signals.
 type bridgeObservability struct {
@@ -33,6 +46,11 @@ type bridgeObservability struct {
 	getBridgeConnectionsTotal     metric.Int64Counter
 	getBridgeUpgradeFailuresTotal metric.Int64Counter
 	getBridgeUpgradeLatencyMS     metric.Float64Histogram
+	getBridgeRPCDeniedTotal       metric.Int64Counter
+	getBridgeAbuseRejectionsTotal metric.Int64Counter
+	getBridgeBandwidthThrottle    metric.Float64Counter
+	getBridgeSlowClientTotals     map[string]metric.Int64Counter
+	getBridgeTunnelMaxAgeTotal    metric.Int64Counter
 }
 
 // buildBridgeObservability creates a bridge observability handle backed by the global OTel providers.
@@ -57,6 +75,37 @@ func buildBridgeObservability() *bridgeObservability {
 		metric.WithUnit("ms"),
 		metric.WithDescription("Websocket upgrade request latency in milliseconds"),
 	)
+	parseRPCDeniedTotal, _ := parseMeter.Int64Counter(
+		parseBridgeRPCDeniedTotalMetric,
+		metric.WithDescription("Total tunneled RPCs rejected by the bridge before reaching the gRPC server"),
+	)
+	parseAbuseRejectionsTotal, _ := parseMeter.Int64Counter(
+		parseBridgeAbuseRejectionsTotalMetric,
+		metric.WithDescription("Total websocket upgrades rejected by abuse controls"),
+	)
+	parseBandwidthThrottle, _ := parseMeter.Float64Counter(
+		parseBridgeBandwidthThrottleMetric,
+		metric.WithUnit("s"),
+		metric.WithDescription("Total time tunnel reads and writes waited for bandwidth limits"),
+	)
+	parseTunnelMaxAgeTotal, _ := parseMeter.Int64Counter(
+		parseBridgeTunnelMaxAgeTotalMetric,
+		metric.WithDescription("Total tunnels sent GOAWAY at MaxConnectionAge, and closed with RPCs in flight after MaxConnectionAgeGrace"),
+	)
+	parseSlowClientTotals := map[string]metric.Int64Counter{}
+	for _, parseSlowClientMetric := range []struct {
+		getLimit       string
+		getName        string
+		getDescription string
+	}{
+		{parseBridgeSlowClientUpgradeTimeout, parseBridgeUpgradeTimeoutsTotalMetric, "Total websocket upgrades rejected for exceeding UpgradeTimeout"},
+		{parseBridgeSlowClientPrefaceTimeout, parseBridgePrefaceTimeoutsTotalMetric, "Total tunnels closed before the HTTP/2 preface arrived within PrefaceTimeout"},
+		{parseBridgeSlowClientReadTooSlow, parseBridgeSlowReadsTotalMetric, "Total tunnels closed for delivering messages below MinReadThroughput"},
+		{parseBridgeSlowClientWriteTimeout, parseBridgeWriteTimeoutsTotalMetric, "Total tunnels closed because a message write exceeded WriteTimeout"},
+	} {
+		parseCounter, _ := parseMeter.Int64Counter(parseSlowClientMetric.getName, metric.WithDescription(parseSlowClientMetric.getDescription))
+		parseSlowClientTotals[parseSlowClientMetric.getLimit] = parseCounter
+	}
 
 	return &bridgeObservability{
 		getBridgeTracer:               parseTracer,
@@ -64,6 +113,11 @@ func buildBridgeObservability() *bridgeObservability {
 		getBridgeConnectionsTotal:     parseConnectionsTotal,
 		getBridgeUpgradeFailuresTotal: parseUpgradeFailuresTotal,
 		getBridgeUpgradeLatencyMS:     parseUpgradeLatencyMS,
+		getBridgeRPCDeniedTotal:       parseRPCDeniedTotal,
+		getBridgeAbuseRejectionsTotal: parseAbuseRejectionsTotal,
+		getBridgeBandwidthThrottle:    parseBandwidthThrottle,
+		getBridgeSlowClientTotals:     parseSlowClientTotals,
+		getBridgeTunnelMaxAgeTotal:    parseTunnelMaxAgeTotal,
 	}
 }
 
@@ -153,13 +207,106 @@ func (parseObservability *bridgeObservability) storeBridgeConnectionDelta(parseC
 	}
 }
 
+// buildBridgeClientAttributes builds the resolved client address span attribute.
+// It is kept off metrics because client addresses are unbounded.
+func buildBridgeClientAttributes(parseRequest *http.Request) []attribute.KeyValue {
+	if parseRequest == nil {
+		return nil
+	}
+	parseClientAddr, isValidClient := parseBridgeRemoteAddr(parseRequest.RemoteAddr)
+	if !isValidClient {
+		return nil
+	}
+	return []attribute.KeyValue{attribute.String("client.address", parseClientAddr.String())}
+}
+
+// storeBridgeRPCDenied counts one tunneled RPC rejected by the bridge for a reason.
+// Method names are client-controlled, so they are not used as metric attributes.
+func (parseObservability *bridgeObservability) storeBridgeRPCDenied(parseContext context.Context, parseReason string) {
+	if parseObservability == nil || parseObservability.getBridgeRPCDeniedTotal == nil {
+		return
+	}
+	parseObservability.getBridgeRPCDeniedTotal.Add(
+		getBridgeMetricContext(parseContext),
+		1,
+		metric.WithAttributes(
+			attribute.String("component", "grpctunnel.bridge"),
+			attribute.String("reason", parseReason),
+		),
+	)
+}
+
+// storeBridgeAbuseRejection counts one upgrade rejected by abuse controls, labeled with the rejection reason.
+func (parseObservability *bridgeObservability) storeBridgeAbuseRejection(parseContext context.Context, parseErr error) {
+	if parseObservability == nil || parseObservability.getBridgeAbuseRejectionsTotal == nil {
+		return
+	}
+	parseReason := "unknown"
+	var parseRejection *bridgeAbuseRejection
+	if errors.As(parseErr, &parseRejection) {
+		parseReason = parseRejection.getReason
+	}
+	parseObservability.getBridgeAbuseRejectionsTotal.Add(
+		getBridgeMetricContext(parseContext),
+		1,
+		metric.WithAttributes(
+			attribute.String("component", "grpctunnel.bridge"),
+			attribute.String("reason", parseReason),
+		),
+	)
+}
+
+// storeBridgeBandwidthThrottle adds time spent waiting for a bandwidth limit, labeled with the limiting
+// scope and direction, so throttling can be told apart from backend slowness.
+func (parseObservability *bridgeObservability) storeBridgeBandwidthThrottle(parseContext context.Context, parseScope string, parseDirection string, parseDuration time.Duration) {
+	if parseObservability == nil || parseObservability.getBridgeBandwidthThrottle == nil {
+		return
+	}
+	parseObservability.getBridgeBandwidthThrottle.Add(
+		getBridgeMetricContext(parseContext),
+		parseDuration.Seconds(),
+		metric.WithAttributes(
+			attribute.String("component", "grpctunnel.bridge"),
+			attribute.String("scope", parseScope),
+			attribute.String("direction", parseDirection),
+		),
+	)
+}
+
+// storeBridgeSlowClient increments the counter for the slow-client limit that rejected or closed a tunnel.
+func (parseObservability *bridgeObservability) storeBridgeSlowClient(parseContext context.Context, parseLimit string) {
+	if parseObservability == nil || parseObservability.getBridgeSlowClientTotals[parseLimit] == nil {
+		return
+	}
+	parseObservability.getBridgeSlowClientTotals[parseLimit].Add(
+		getBridgeMetricContext(parseContext),
+		1,
+		metric.WithAttributes(attribute.String("component", "grpctunnel.bridge")),
+	)
+}
+
+// storeBridgeTunnelMaxAge counts one MaxConnectionAge rotation stage: the GOAWAY, or a close when the grace expired.
+func (parseObservability *bridgeObservability) storeBridgeTunnelMaxAge(parseContext context.Context, parseStage string) {
+	if parseObservability == nil || parseObservability.getBridgeTunnelMaxAgeTotal == nil {
+		return
+	}
+	parseObservability.getBridgeTunnelMaxAgeTotal.Add(
+		getBridgeMetricContext(parseContext),
+		1,
+		metric.WithAttributes(
+			attribute.String("component", "grpctunnel.bridge"),
+			attribute.String("stage", parseStage),
+		),
+	)
+}
+
 // startBridgeRequestSpan starts the server span used for one websocket upgrade request.
 func (parseObservability *bridgeObservability) startBridgeRequestSpan(parseContext context.Context, parseRequest *http.Request) (context.Context, trace.Span) {
 	parseContext = getBridgeMetricContext(parseContext)
 	if parseObservability == nil || parseObservability.getBridgeTracer == nil {
 		return parseContext, trace.SpanFromContext(parseContext)
 	}
-	parseAttributes := buildBridgeMetricAttributes(parseRequest, "")
+	parseAttributes := append(buildBridgeMetricAttributes(parseRequest, ""), buildBridgeClientAttributes(parseRequest)...)
 	return parseObservability.getBridgeTracer.Start(
 		parseContext,
 		parseBridgeRequestSpanName,
@@ -174,7 +321,7 @@ func (parseObservability *bridgeObservability) startBridgeSessionSpan(parseConte
 	if parseObservability == nil || parseObservability.getBridgeTracer == nil {
 		return parseContext, trace.SpanFromContext(parseContext)
 	}
-	parseAttributes := buildBridgeMetricAttributes(parseRequest, "")
+	parseAttributes := append(buildBridgeMetricAttributes(parseRequest, ""), buildBridgeClientAttributes(parseRequest)...)
 	return parseObservability.getBridgeTracer.Start(
 		parseContext,
 		parseBridgeSessionSpanName,
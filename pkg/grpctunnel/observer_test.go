@@ -0,0 +1,176 @@
+//go:build !js && !wasm
+
+package grpctunnel
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/monstercameron/grpc-tunnel/examples/_shared/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// recordingTunnelObserver stores every lifecycle event it receives.
+type recordingTunnelObserver struct {
+	setLock           sync.Mutex
+	storeRejected     []UpgradeRejectedEvent
+	storeConnected    []TunnelConnectedEvent
+	storeOpened       []StreamOpenedEvent
+	storeClosed       []StreamClosedEvent
+	storeDisconnected chan TunnelDisconnectedEvent
+}
+
+func buildRecordingTunnelObserver() *recordingTunnelObserver {
+	return &recordingTunnelObserver{storeDisconnected: make(chan TunnelDisconnectedEvent, 4)}
+}
+
+func (parseO *recordingTunnelObserver) OnUpgradeRejected(parseEvent UpgradeRejectedEvent) {
+	parseO.setLock.Lock()
+	defer parseO.setLock.Unlock()
+	parseO.storeRejected = append(parseO.storeRejected, parseEvent)
+}
+
+func (parseO *recordingTunnelObserver) OnTunnelConnected(parseEvent TunnelConnectedEvent) {
+	parseO.setLock.Lock()
+	defer parseO.setLock.Unlock()
+	parseO.storeConnected = append(parseO.storeConnected, parseEvent)
+}
+
+func (parseO *recordingTunnelObserver) OnStreamOpened(parseEvent StreamOpenedEvent) {
+	parseO.setLock.Lock()
+	defer parseO.setLock.Unlock()
+	parseO.storeOpened = append(parseO.storeOpened, parseEvent)
+}
+
+func (parseO *recordingTunnelObserver) OnStreamClosed(parseEvent StreamClosedEvent) {
+	parseO.setLock.Lock()
+	defer parseO.setLock.Unlock()
+	parseO.storeClosed = append(parseO.storeClosed, parseEvent)
+}
+
+func (parseO *recordingTunnelObserver) OnTunnelDisconnected(parseEvent TunnelDisconnectedEvent) {
+	parseO.storeDisconnected <- parseEvent
+}
+
+// waitDisconnected returns the next disconnect event or fails the test.
+func (parseO *recordingTunnelObserver) waitDisconnected(parseT *testing.T) TunnelDisconnectedEvent {
+	parseT.Helper()
+	select {
+	case parseEvent := <-parseO.storeDisconnected:
+		return parseEvent
+	case <-time.After(5 * time.Second):
+		parseT.Fatal("timed out waiting for OnTunnelDisconnected")
+		return TunnelDisconnectedEvent{}
+	}
+}
+
+// TestBuildBridgeHandler_TunnelObserver verifies one tunnel reports connect, per-stream status,
+// and a disconnect carrying its byte counts, stream count, and close cause.
+func TestBuildBridgeHandler_TunnelObserver(parseT *testing.T) {
+	parseGrpcServer := grpc.NewServer()
+	proto.RegisterTodoServiceServer(parseGrpcServer, &mockService{})
+	defer parseGrpcServer.Stop()
+
+	parseObserver := buildRecordingTunnelObserver()
+	parseHandler := Wrap(parseGrpcServer, WithTunnelObserver(parseObserver))
+	parseServer := httptest.NewServer(parseHandler)
+	defer parseServer.Close()
+
+	parseCtx, clearCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer clearCtx()
+	parseConn, parseErr := BuildTunnelConn(parseCtx, TunnelConfig{
+		Target:      "ws" + strings.TrimPrefix(parseServer.URL, "http"),
+		GRPCOptions: []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())},
+	})
+	if parseErr != nil {
+		parseT.Fatalf("BuildTunnelConn() error: %v", parseErr)
+	}
+	parseClient := proto.NewTodoServiceClient(parseConn)
+	if _, parseErr := parseClient.CreateTodo(parseCtx, &proto.CreateTodoRequest{Text: "observed"}); parseErr != nil {
+		parseT.Fatalf("CreateTodo() error: %v", parseErr)
+	}
+	if _, parseErr := parseClient.DeleteTodo(parseCtx, &proto.DeleteTodoRequest{Id: "missing"}); parseErr == nil {
+		parseT.Fatal("DeleteTodo() error = nil, want Unimplemented")
+	}
+	parseConn.Close()
+
+	parseDisconnected := parseObserver.waitDisconnected(parseT)
+	parseObserver.setLock.Lock()
+	defer parseObserver.setLock.Unlock()
+	if len(parseObserver.storeConnected) != 1 || parseObserver.storeConnected[0].TunnelID == "" {
+		parseT.Fatalf("connected events = %+v, want one with a tunnel ID", parseObserver.storeConnected)
+	}
+	parseTunnelID := parseObserver.storeConnected[0].TunnelID
+	if parseDisconnected.TunnelID != parseTunnelID || parseDisconnected.Cause != TunnelCloseClient || parseDisconnected.Streams != 2 {
+		parseT.Fatalf("disconnected = %+v, want tunnel %s closed by the client after 2 streams", parseDisconnected, parseTunnelID)
+	}
+	if parseDisconnected.BytesRead == 0 || parseDisconnected.BytesWritten == 0 || parseDisconnected.Duration <= 0 {
+		parseT.Fatalf("disconnected = %+v, want byte counts and a duration", parseDisconnected)
+	}
+	if len(parseObserver.storeOpened) != 2 || len(parseObserver.storeClosed) != 2 {
+		parseT.Fatalf("stream events = %d opened, %d closed, want 2 each", len(parseObserver.storeOpened), len(parseObserver.storeClosed))
+	}
+	parseCodes := map[string]codes.Code{}
+	for _, parseEvent := range parseObserver.storeClosed {
+		if parseEvent.TunnelID != parseTunnelID {
+			parseT.Fatalf("stream closed tunnel ID = %q, want %q", parseEvent.TunnelID, parseTunnelID)
+		}
+		parseCodes[parseEvent.Method] = parseEvent.Code
+	}
+	if parseCodes["/TodoService/CreateTodo"] != codes.OK || parseCodes["/TodoService/DeleteTodo"] != codes.Unimplemented {
+		parseT.Fatalf("stream codes = %v, want CreateTodo OK and DeleteTodo Unimplemented", parseCodes)
+	}
+}
+
+// TestBuildBridgeHandler_TunnelObserverRejectsAndCauses verifies rejected upgrades are reported with
+// their reason and a slow-client close is reported as the tunnel's cause.
+func TestBuildBridgeHandler_TunnelObserverRejectsAndCauses(parseT *testing.T) {
+	parseGrpcServer := grpc.NewServer()
+	defer parseGrpcServer.Stop()
+
+	parseObserver := buildRecordingTunnelObserver()
+	parseHandler, parseErr := BuildBridgeHandler(parseGrpcServer, BridgeConfig{
+		PrefaceTimeout: 100 * time.Millisecond,
+		TunnelObserver: parseObserver,
+		Authenticate: func(parseR *http.Request) (context.Context, error) {
+			if parseR.URL.Query().Get("token") != "ok" {
+				return nil, errors.New("bad token")
+			}
+			return parseR.Context(), nil
+		},
+	})
+	if parseErr != nil {
+		parseT.Fatalf("BuildBridgeHandler() error: %v", parseErr)
+	}
+	parseServer := httptest.NewServer(parseHandler)
+	defer parseServer.Close()
+	parseURL := "ws" + strings.TrimPrefix(parseServer.URL, "http")
+
+	if _, _, parseErr := websocket.DefaultDialer.Dial(parseURL, nil); parseErr == nil {
+		parseT.Fatal("Dial() without token error = nil, want rejection")
+	}
+	parseObserver.setLock.Lock()
+	parseRejected := append([]UpgradeRejectedEvent(nil), parseObserver.storeRejected...)
+	parseObserver.setLock.Unlock()
+	if len(parseRejected) != 1 || parseRejected[0].Reason != UpgradeRejectAuthentication || parseRejected[0].StatusCode != http.StatusUnauthorized || parseRejected[0].Err == nil {
+		parseT.Fatalf("rejected events = %+v, want one authentication rejection with 401", parseRejected)
+	}
+
+	parseWebsocket, _, parseErr := websocket.DefaultDialer.Dial(parseURL+"?token=ok", nil)
+	if parseErr != nil {
+		parseT.Fatalf("Dial() error: %v", parseErr)
+	}
+	defer parseWebsocket.Close()
+	if parseDisconnected := parseObserver.waitDisconnected(parseT); parseDisconnected.Cause != TunnelClosePrefaceTimeout {
+		parseT.Fatalf("disconnect cause = %q, want %q", parseDisconnected.Cause, TunnelClosePrefaceTimeout)
+	}
+}
@@ -0,0 +1,196 @@
+//go:build !js && !wasm
+
+package grpctunnel
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const parseProxyProtocolHeaderTimeout = 5 * time.Second
+const parseProxyProtocolV1MaxLength = 107
+
+var parseProxyProtocolV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// proxyProtocolListener reads PROXY protocol headers from connections accepted from trusted proxies.
+type proxyProtocolListener struct {
+	net.Listener
+	getTrustedProxies []netip.Prefix
+}
+
+// proxyProtocolConn replaces the peer address with the client address carried in a PROXY protocol header.
+// The header is read lazily so a slow proxy never blocks the accept loop.
+type proxyProtocolConn struct {
+	net.Conn
+	getTrustedProxies []netip.Prefix
+	setHeaderOnce     sync.Once
+	storeReader       *bufio.Reader
+	storeRemoteAddr   net.Addr
+	storeHeaderErr    error
+}
+
+// NewProxyProtocolListener wraps a listener so connections from trustedProxies must begin with a
+// PROXY protocol v1 or v2 header, whose source address becomes the connection's RemoteAddr.
+// Connections from other peers are served unchanged. trustedProxies holds CIDR prefixes or IPs.
+//
+// Server.Serve, Serve, and ListenAndServe apply it when ShouldAcceptProxyProtocol is set; use it
+// directly for handlers mounted on your own http.Server, such as bridge.Handler.
+func NewProxyProtocolListener(parseListener net.Listener, parseTrustedProxies []string) (net.Listener, error) {
+	if parseListener == nil {
+		return nil, errors.New("grpctunnel: listener is required")
+	}
+	parsePrefixes, parseErr := parseBridgeTrustedProxies(parseTrustedProxies)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	if len(parsePrefixes) == 0 {
+		return nil, errors.New("grpctunnel: PROXY protocol requires TrustedProxies")
+	}
+	return &proxyProtocolListener{Listener: parseListener, getTrustedProxies: parsePrefixes}, nil
+}
+
+// Accept wraps accepted connections from trusted proxies.
+func (parseListener *proxyProtocolListener) Accept() (net.Conn, error) {
+	parseConn, parseErr := parseListener.Listener.Accept()
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	parsePeerAddr, isValidPeer := parseBridgeRemoteAddr(parseConn.RemoteAddr().String())
+	if !isValidPeer || !isBridgePrefixMember(parseListener.getTrustedProxies, parsePeerAddr) {
+		return parseConn, nil
+	}
+	return &proxyProtocolConn{Conn: parseConn, getTrustedProxies: parseListener.getTrustedProxies}, nil
+}
+
+// readProxyProtocolHeader consumes the PROXY header once. Failures close the connection.
+func (parseConn *proxyProtocolConn) readProxyProtocolHeader() {
+	parseConn.setHeaderOnce.Do(func() {
+		parseConn.storeReader = bufio.NewReader(parseConn.Conn)
+		_ = parseConn.Conn.SetReadDeadline(time.Now().Add(parseProxyProtocolHeaderTimeout))
+		parseAddr, parseErr := parseProxyProtocolHeader(parseConn.storeReader)
+		_ = parseConn.Conn.SetReadDeadline(time.Time{})
+		if parseErr != nil {
+			parseConn.storeHeaderErr = parseErr
+			logGrpctunnelEvent("grpctunnel.bridge", "WARN", "proxy_protocol_header_invalid", nil, parseErr, fmt.Sprintf("Rejected connection from trusted proxy %s", parseConn.Conn.RemoteAddr()))
+			_ = parseConn.Conn.Close()
+			return
+		}
+		parseConn.storeRemoteAddr = parseAddr
+	})
+}
+
+// Read returns connection bytes after the PROXY header.
+func (parseConn *proxyProtocolConn) Read(parseBuffer []byte) (int, error) {
+	parseConn.readProxyProtocolHeader()
+	if parseConn.storeHeaderErr != nil {
+		return 0, parseConn.storeHeaderErr
+	}
+	return parseConn.storeReader.Read(parseBuffer)
+}
+
+// RemoteAddr returns the client address from the PROXY header, or the proxy address for LOCAL headers.
+func (parseConn *proxyProtocolConn) RemoteAddr() net.Addr {
+	parseConn.readProxyProtocolHeader()
+	if parseConn.storeRemoteAddr != nil {
+		return parseConn.storeRemoteAddr
+	}
+	return parseConn.Conn.RemoteAddr()
+}
+
+// parseProxyProtocolHeader reads a v1 or v2 header. A nil address means the header carried no client address.
+func parseProxyProtocolHeader(parseReader *bufio.Reader) (net.Addr, error) {
+	parsePrefix, parseErr := parseReader.Peek(len(parseProxyProtocolV2Signature))
+	if parseErr == nil && bytes.Equal(parsePrefix, parseProxyProtocolV2Signature) {
+		return parseProxyProtocolV2Header(parseReader)
+	}
+	if parsePrefix, parseErr = parseReader.Peek(6); parseErr != nil || string(parsePrefix) != "PROXY " {
+		return nil, errors.New("grpctunnel: missing PROXY protocol header")
+	}
+	return parseProxyProtocolV1Header(parseReader)
+}
+
+// parseProxyProtocolV1Header reads a text header such as "PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\n".
+func parseProxyProtocolV1Header(parseReader *bufio.Reader) (net.Addr, error) {
+	var parseLine []byte
+	for len(parseLine) < parseProxyProtocolV1MaxLength {
+		parseByte, parseErr := parseReader.ReadByte()
+		if parseErr != nil {
+			return nil, fmt.Errorf("grpctunnel: read PROXY v1 header: %w", parseErr)
+		}
+		parseLine = append(parseLine, parseByte)
+		if bytes.HasSuffix(parseLine, []byte("\r\n")) {
+			break
+		}
+	}
+	if !bytes.HasSuffix(parseLine, []byte("\r\n")) {
+		return nil, errors.New("grpctunnel: PROXY v1 header too long")
+	}
+
+	parseFields := strings.Fields(string(parseLine))
+	if len(parseFields) >= 2 && parseFields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(parseFields) != 6 || (parseFields[1] != "TCP4" && parseFields[1] != "TCP6") {
+		return nil, errors.New("grpctunnel: malformed PROXY v1 header")
+	}
+	parseAddr, parseErr := netip.ParseAddr(parseFields[2])
+	if parseErr != nil || parseAddr.Is4() != (parseFields[1] == "TCP4") {
+		return nil, errors.New("grpctunnel: malformed PROXY v1 source address")
+	}
+	parsePort, parseErr := strconv.ParseUint(parseFields[4], 10, 16)
+	if parseErr != nil {
+		return nil, errors.New("grpctunnel: malformed PROXY v1 source port")
+	}
+	return net.TCPAddrFromAddrPort(netip.AddrPortFrom(parseAddr, uint16(parsePort))), nil
+}
+
+// parseProxyProtocolV2Header reads a binary header and returns the TCP source address it carries.
+func parseProxyProtocolV2Header(parseReader *bufio.Reader) (net.Addr, error) {
+	parseFixed := make([]byte, 16)
+	if _, parseErr := io.ReadFull(parseReader, parseFixed); parseErr != nil {
+		return nil, fmt.Errorf("grpctunnel: read PROXY v2 header: %w", parseErr)
+	}
+	if parseFixed[12]>>4 != 2 {
+		return nil, errors.New("grpctunnel: unsupported PROXY protocol version")
+	}
+	parsePayload := make([]byte, binary.BigEndian.Uint16(parseFixed[14:16]))
+	if _, parseErr := io.ReadFull(parseReader, parsePayload); parseErr != nil {
+		return nil, fmt.Errorf("grpctunnel: read PROXY v2 addresses: %w", parseErr)
+	}
+
+	switch parseFixed[12] & 0x0F {
+	case 0x0:
+		// LOCAL: the proxy opened the connection itself, for example for health checks.
+		return nil, nil
+	case 0x1:
+	default:
+		return nil, errors.New("grpctunnel: unsupported PROXY v2 command")
+	}
+	switch parseFixed[13] {
+	case 0x11:
+		if len(parsePayload) < 12 {
+			return nil, errors.New("grpctunnel: short PROXY v2 IPv4 address block")
+		}
+		parseAddr := netip.AddrFrom4([4]byte(parsePayload[0:4]))
+		return net.TCPAddrFromAddrPort(netip.AddrPortFrom(parseAddr, binary.BigEndian.Uint16(parsePayload[8:10]))), nil
+	case 0x21:
+		if len(parsePayload) < 36 {
+			return nil, errors.New("grpctunnel: short PROXY v2 IPv6 address block")
+		}
+		parseAddr := netip.AddrFrom16([16]byte(parsePayload[0:16]))
+		return net.TCPAddrFromAddrPort(netip.AddrPortFrom(parseAddr, binary.BigEndian.Uint16(parsePayload[32:34]))), nil
+	default:
+		// Other families (UNSPEC, UDP, unix) carry no usable TCP client address.
+		return nil, nil
+	}
+}
@@ -0,0 +1,79 @@
+//go:build !js && !wasm
+
+package grpctunnel
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// buildProxyProtocolV2Header encodes a PROXY v2 TCP4 header for a source address.
+func buildProxyProtocolV2Header(parseSource [4]byte, parsePort uint16) []byte {
+	parseHeader := append([]byte{}, parseProxyProtocolV2Signature...)
+	parseHeader = append(parseHeader, 0x21, 0x11, 0, 12)
+	parseHeader = append(parseHeader, parseSource[:]...)
+	parseHeader = append(parseHeader, 192, 0, 2, 200)
+	parseHeader = binary.BigEndian.AppendUint16(parseHeader, parsePort)
+	return binary.BigEndian.AppendUint16(parseHeader, 443)
+}
+
+// TestNewProxyProtocolListener verifies v1 and v2 headers replace the peer address and missing headers are rejected.
+func TestNewProxyProtocolListener(parseT *testing.T) {
+	parseTCPListener, parseErr := net.Listen("tcp", "127.0.0.1:0")
+	if parseErr != nil {
+		parseT.Fatalf("Listen() error: %v", parseErr)
+	}
+	parseListener, parseErr := NewProxyProtocolListener(parseTCPListener, []string{"127.0.0.0/8"})
+	if parseErr != nil {
+		parseT.Fatalf("NewProxyProtocolListener() error: %v", parseErr)
+	}
+	defer parseListener.Close()
+
+	parseTests := []struct {
+		parseName     string
+		parsePrelude  []byte
+		parseWantAddr string
+		isWantErr     bool
+	}{
+		{parseName: "v1", parsePrelude: []byte("PROXY TCP4 198.51.100.7 192.0.2.200 56324 443\r\n"), parseWantAddr: "198.51.100.7:56324"},
+		{parseName: "v2", parsePrelude: buildProxyProtocolV2Header([4]byte{203, 0, 113, 5}, 40000), parseWantAddr: "203.0.113.5:40000"},
+		{parseName: "missing", parsePrelude: []byte("GET / HTTP/1.1\r\n"), isWantErr: true},
+	}
+	for _, parseTestCase := range parseTests {
+		parseClient, parseErr := net.Dial("tcp", parseTCPListener.Addr().String())
+		if parseErr != nil {
+			parseT.Fatalf("%s: Dial() error: %v", parseTestCase.parseName, parseErr)
+		}
+		if _, parseErr = parseClient.Write(append(parseTestCase.parsePrelude, "hello"...)); parseErr != nil {
+			parseT.Fatalf("%s: Write() error: %v", parseTestCase.parseName, parseErr)
+		}
+		parseConn, parseErr := parseListener.Accept()
+		if parseErr != nil {
+			parseT.Fatalf("%s: Accept() error: %v", parseTestCase.parseName, parseErr)
+		}
+		_ = parseConn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		parseBuffer := make([]byte, 5)
+		_, parseErr = io.ReadFull(parseConn, parseBuffer)
+		if parseTestCase.isWantErr {
+			if parseErr == nil {
+				parseT.Fatalf("%s: Read() error = nil, want error", parseTestCase.parseName)
+			}
+		} else {
+			if parseErr != nil || string(parseBuffer) != "hello" {
+				parseT.Fatalf("%s: Read() = %q, %v", parseTestCase.parseName, parseBuffer, parseErr)
+			}
+			if parseAddr := parseConn.RemoteAddr().String(); parseAddr != parseTestCase.parseWantAddr {
+				parseT.Fatalf("%s: RemoteAddr() = %q, want %q", parseTestCase.parseName, parseAddr, parseTestCase.parseWantAddr)
+			}
+		}
+		parseConn.Close()
+		parseClient.Close()
+	}
+
+	if _, parseErr = NewProxyProtocolListener(parseTCPListener, nil); parseErr == nil {
+		parseT.Fatal("NewProxyProtocolListener() without trusted proxies error = nil, want error")
+	}
+}
@@ -3,20 +3,31 @@
 package grpctunnel
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"net/http"
+	"net/netip"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/healthmonitor"
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/membudget"
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/tunneladmin"
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/tunnelregistry"
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/tunnelstate"
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/h2c"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 const parseDefaultWebSocketBufferSize = 4096
 const parseDefaultReadLimitBytes int64 = 16 << 20
+const parseRPCRateLimitedMessage = "tunnel RPC rate limit exceeded"
+const parseMemoryBudgetExhaustedMessage = "bridge memory budget exhausted"
 
 var cacheWebSocketWriteBufferPools sync.Map
 
@@ -31,12 +42,54 @@ type serverOptions struct {
 	shouldDisableReadLimit  bool
 	pingInterval            time.Duration
 	idleTimeout             time.Duration
+	upgradeTimeout          time.Duration
+	prefaceTimeout          time.Duration
+	writeTimeout            time.Duration
+	minReadThroughput       Throughput
+	maxConnectionAge        time.Duration
+	maxConnectionAgeGrace   time.Duration
+	authenticate            func(r *http.Request) (context.Context, error)
+	authExpiryGrace         time.Duration
+	exposurePolicy          ExposurePolicy
+	authorize               func(ctx context.Context, upgrade *http.Request, fullMethod string) error
 	onConnect               func(r *http.Request)
 	onDisconnect            func(r *http.Request)
+	tunnelObserver          TunnelObserver
+	tunnelRegistry          *tunnelregistry.Registry
+	tunnelAdmin             *tunneladmin.Admin
+	tunnelStates            *tunnelstate.Recorder
+	healthMonitor           *healthmonitor.Monitor
 	shouldEnableCompression bool
 	maxActiveConnections    int
 	maxConnectionsPerClient int
 	maxUpgradesPerClient    int
+	trustedProxies          []string
+	upgradeRateLimit        RateLimit
+	rpcRateLimit            RateLimit
+	bandwidthLimits         BandwidthLimits
+	memoryBudget            *membudget.Budget
+	http2Profile            HTTP2Profile
+	http2Settings           HTTP2Settings
+	clientKeyFunc           func(r *http.Request) string
+	shouldGroupIPv6Clients  bool
+	allowedClientCIDRs      []string
+	deniedClientCIDRs       []string
+	penaltyBox              PenaltyBox
+	limitStore              LimitStore
+	shouldAcceptProxyProto  bool
+}
+
+// getBridgeTunnelOverhead returns the fixed memory charged per tunnel: its websocket read and write buffers.
+func getBridgeTunnelOverhead(parseConfig BridgeConfig) int64 {
+	parseReadBufferSize := parseConfig.ReadBufferSize
+	if parseReadBufferSize <= 0 {
+		parseReadBufferSize = parseDefaultWebSocketBufferSize
+	}
+	parseWriteBufferSize := parseConfig.WriteBufferSize
+	if parseWriteBufferSize <= 0 {
+		parseWriteBufferSize = parseDefaultWebSocketBufferSize
+	}
+	return int64(parseReadBufferSize + parseWriteBufferSize)
 }
 
 // buildWebSocketWriteBufferPool returns a shared pool for a websocket write-buffer size.
@@ -112,6 +165,30 @@ func WithKeepalive(parsePingInterval time.Duration, parseIdleTimeout time.Durati
 	}
 }
 
+// WithSlowClientTimeouts bounds the upgrade request, the wait for the HTTP/2 preface, and each websocket message write.
+func WithSlowClientTimeouts(parseUpgradeTimeout time.Duration, parsePrefaceTimeout time.Duration, parseWriteTimeout time.Duration) ServerOption {
+	return func(parseO *serverOptions) {
+		parseO.upgradeTimeout = parseUpgradeTimeout
+		parseO.prefaceTimeout = parsePrefaceTimeout
+		parseO.writeTimeout = parseWriteTimeout
+	}
+}
+
+// WithMinReadThroughput closes tunnels that deliver websocket messages slower than a minimum rate.
+func WithMinReadThroughput(parseThroughput Throughput) ServerOption {
+	return func(parseO *serverOptions) {
+		parseO.minReadThroughput = parseThroughput
+	}
+}
+
+// WithMaxConnectionAge rotates tunnels older than a jittered age, giving in-flight RPCs a grace period.
+func WithMaxConnectionAge(parseAge time.Duration, parseGrace time.Duration) ServerOption {
+	return func(parseO *serverOptions) {
+		parseO.maxConnectionAge = parseAge
+		parseO.maxConnectionAgeGrace = parseGrace
+	}
+}
+
 // WithBridgeWebSocketCompression enables websocket per-message compression for bridge handlers.
 func WithBridgeWebSocketCompression() ServerOption {
 	return func(parseO *serverOptions) {
@@ -140,7 +217,123 @@ func WithMaxUpgradesPerClientPerMinute(parseMax int) ServerOption {
 	}
 }
 
+// WithAuthenticator sets a hook that verifies upgrade requests and supplies the base context for tunnel RPCs.
+func WithAuthenticator(parseFn func(r *http.Request) (context.Context, error)) ServerOption {
+	return func(parseO *serverOptions) {
+		parseO.authenticate = parseFn
+	}
+}
+
+// WithAuthExpiryGrace bounds how long in-flight RPCs may run after the Authenticate context ends.
+func WithAuthExpiryGrace(parseGrace time.Duration) ServerOption {
+	return func(parseO *serverOptions) {
+		parseO.authExpiryGrace = parseGrace
+	}
+}
+
+// WithAuthorizer sets a per-RPC authorization hook evaluated before each tunneled call.
+func WithAuthorizer(parseAuthorize func(ctx context.Context, upgrade *http.Request, fullMethod string) error) ServerOption {
+	return func(parseO *serverOptions) {
+		parseO.authorize = parseAuthorize
+	}
+}
+
+// WithUpgradeRateLimit sets a per-client token bucket for websocket upgrades.
+func WithUpgradeRateLimit(parseLimit RateLimit) ServerOption {
+	return func(parseO *serverOptions) {
+		parseO.upgradeRateLimit = parseLimit
+	}
+}
+
+// WithRPCRateLimit sets a per-tunnel token bucket for RPC starts.
+func WithRPCRateLimit(parseLimit RateLimit) ServerOption {
+	return func(parseO *serverOptions) {
+		parseO.rpcRateLimit = parseLimit
+	}
+}
+
+// WithBandwidthLimits caps tunnel throughput in bytes per second globally, per client, and per method.
+func WithBandwidthLimits(parseLimits BandwidthLimits) ServerOption {
+	return func(parseO *serverOptions) {
+		parseO.bandwidthLimits = parseLimits
+	}
+}
+
+// WithMemoryBudget charges tunnels to a process-wide memory budget.
+func WithMemoryBudget(parseBudget *membudget.Budget) ServerOption {
+	return func(parseO *serverOptions) {
+		parseO.memoryBudget = parseBudget
+	}
+}
+
+// WithHTTP2Settings applies an HTTP/2 profile, with nonzero settings overriding it, to each tunnel.
+func WithHTTP2Settings(parseProfile HTTP2Profile, parseSettings HTTP2Settings) ServerOption {
+	return func(parseO *serverOptions) {
+		parseO.http2Profile = parseProfile
+		parseO.http2Settings = parseSettings
+	}
+}
+
+// WithClientKeyFunc sets the client key used by abuse controls.
+func WithClientKeyFunc(parseClientKeyFunc func(r *http.Request) string) ServerOption {
+	return func(parseO *serverOptions) {
+		parseO.clientKeyFunc = parseClientKeyFunc
+	}
+}
+
+// WithIPv6ClientGrouping keys IPv6 clients by their /64 prefix in abuse controls.
+func WithIPv6ClientGrouping() ServerOption {
+	return func(parseO *serverOptions) {
+		parseO.shouldGroupIPv6Clients = true
+	}
+}
+
+// WithClientCIDRs sets client CIDR lists exempt from per-client limits (allowed) or rejected outright (denied).
+func WithClientCIDRs(parseAllowed []string, parseDenied []string) ServerOption {
+	return func(parseO *serverOptions) {
+		parseO.allowedClientCIDRs = parseAllowed
+		parseO.deniedClientCIDRs = parseDenied
+	}
+}
+
+// WithPenaltyBox temporarily bans clients that repeatedly exceed per-client limits.
+func WithPenaltyBox(parsePenaltyBox PenaltyBox) ServerOption {
+	return func(parseO *serverOptions) {
+		parseO.penaltyBox = parsePenaltyBox
+	}
+}
+
+// WithLimitStore keeps connection-limit slots in a store shared between bridge replicas.
+func WithLimitStore(parseStore LimitStore) ServerOption {
+	return func(parseO *serverOptions) {
+		parseO.limitStore = parseStore
+	}
+}
+
+// WithTrustedProxies resolves client addresses from Forwarded and X-Forwarded-For headers sent by these proxies.
+func WithTrustedProxies(parseTrustedProxies ...string) ServerOption {
+	return func(parseO *serverOptions) {
+		parseO.trustedProxies = parseTrustedProxies
+	}
+}
+
+// WithProxyProtocol makes Serve and ListenAndServe read PROXY protocol headers from trusted proxies.
+func WithProxyProtocol() ServerOption {
+	return func(parseO *serverOptions) {
+		parseO.shouldAcceptProxyProto = true
+	}
+}
+
+// WithExposurePolicy restricts which gRPC methods tunnel clients may call.
+func WithExposurePolicy(parsePolicy ExposurePolicy) ServerOption {
+	return func(parseO *serverOptions) {
+		parseO.exposurePolicy = parsePolicy
+	}
+}
+
 // WithConnectHook sets a callback for when clients connect.
+//
+// Deprecated: Use WithTunnelObserver.
 func WithConnectHook(parseFn func(r *http.Request)) ServerOption {
 	return func(parseO *serverOptions) {
 		parseO.onConnect = parseFn
@@ -148,12 +341,50 @@ func WithConnectHook(parseFn func(r *http.Request)) ServerOption {
 }
 
 // WithDisconnectHook sets a callback for when clients disconnect.
+//
+// Deprecated: Use WithTunnelObserver.
 func WithDisconnectHook(parseFn func(r *http.Request)) ServerOption {
 	return func(parseO *serverOptions) {
 		parseO.onDisconnect = parseFn
 	}
 }
 
+// WithTunnelObserver sets the receiver of typed upgrade, tunnel, and stream lifecycle events.
+func WithTunnelObserver(parseObserver TunnelObserver) ServerOption {
+	return func(parseO *serverOptions) {
+		parseO.tunnelObserver = parseObserver
+	}
+}
+
+// WithTunnelRegistry lists the server's tunnels in a registry that can also force-close them.
+func WithTunnelRegistry(parseRegistry *tunnelregistry.Registry) ServerOption {
+	return func(parseO *serverOptions) {
+		parseO.tunnelRegistry = parseRegistry
+	}
+}
+
+// WithTunnelAdmin lists the server's tunnels, reports their events, and takes its runtime-adjustable
+// limits from an admin served by the tooling handler.
+func WithTunnelAdmin(parseAdmin *tunneladmin.Admin) ServerOption {
+	return func(parseO *serverOptions) {
+		parseO.tunnelAdmin = parseAdmin
+	}
+}
+
+// WithTunnelStates records the server's tunnel state transitions for the tooling state snapshot.
+func WithTunnelStates(parseStates *tunnelstate.Recorder) ServerOption {
+	return func(parseO *serverOptions) {
+		parseO.tunnelStates = parseStates
+	}
+}
+
+// WithHealthMonitor reports the server's drain to a health monitor and answers /healthz and /readyz from it.
+func WithHealthMonitor(parseMonitor *healthmonitor.Monitor) ServerOption {
+	return func(parseO *serverOptions) {
+		parseO.healthMonitor = parseMonitor
+	}
+}
+
 // GetBridgeConfigError validates BridgeConfig for server handler creation.
 func GetBridgeConfigError(parseConfig BridgeConfig) error {
 	if parseConfig.ReadBufferSize < 0 {
@@ -180,6 +411,24 @@ func GetBridgeConfigError(parseConfig BridgeConfig) error {
 	if parseConfig.IdleTimeout > 0 && parseConfig.PingInterval >= parseConfig.IdleTimeout {
 		return fmt.Errorf("grpctunnel: PingInterval must be less than IdleTimeout")
 	}
+	if parseErr := getBridgeSlowClientError(parseConfig); parseErr != nil {
+		return parseErr
+	}
+	if parseConfig.MaxConnectionAge < 0 {
+		return fmt.Errorf("grpctunnel: MaxConnectionAge must be >= 0")
+	}
+	if parseConfig.MaxConnectionAgeGrace < 0 {
+		return fmt.Errorf("grpctunnel: MaxConnectionAgeGrace must be >= 0")
+	}
+	if parseConfig.AuthExpiryGrace < 0 {
+		return fmt.Errorf("grpctunnel: AuthExpiryGrace must be >= 0")
+	}
+	if parseConfig.MaxConnectionAgeGrace > 0 && parseConfig.MaxConnectionAge == 0 {
+		return fmt.Errorf("grpctunnel: MaxConnectionAgeGrace requires MaxConnectionAge")
+	}
+	if parseErr := getHTTP2SettingsError(parseConfig.HTTP2Profile, parseConfig.HTTP2); parseErr != nil {
+		return parseErr
+	}
 	if parseConfig.MaxActiveConnections < 0 {
 		return fmt.Errorf("grpctunnel: MaxActiveConnections must be >= 0")
 	}
@@ -189,6 +438,48 @@ func GetBridgeConfigError(parseConfig BridgeConfig) error {
 	if parseConfig.MaxUpgradesPerClientPerMinute < 0 {
 		return fmt.Errorf("grpctunnel: MaxUpgradesPerClientPerMinute must be >= 0")
 	}
+	if parseErr := getExposurePolicyError(parseConfig.ExposurePolicy); parseErr != nil {
+		return parseErr
+	}
+	if parseErr := getBridgeRateLimitError("UpgradeRateLimit", parseConfig.UpgradeRateLimit); parseErr != nil {
+		return parseErr
+	}
+	if parseErr := getBridgeRateLimitError("RPCRateLimit", parseConfig.RPCRateLimit); parseErr != nil {
+		return parseErr
+	}
+	if parseErr := getBridgeBandwidthLimitsError(parseConfig.BandwidthLimits); parseErr != nil {
+		return parseErr
+	}
+	if parseConfig.UpgradeRateLimit.PerSecond > 0 && parseConfig.MaxUpgradesPerClientPerMinute > 0 {
+		return fmt.Errorf("grpctunnel: set either UpgradeRateLimit or MaxUpgradesPerClientPerMinute, not both")
+	}
+	if parseConfig.MaxTrackedClients < 0 {
+		return fmt.Errorf("grpctunnel: MaxTrackedClients must be >= 0")
+	}
+	if parseConfig.ClientStateTTL < 0 {
+		return fmt.Errorf("grpctunnel: ClientStateTTL must be >= 0")
+	}
+	if parseConfig.LimitStoreLeaseTTL < 0 {
+		return fmt.Errorf("grpctunnel: LimitStoreLeaseTTL must be >= 0")
+	}
+	if parseErr := getBridgePenaltyBoxError(parseConfig.PenaltyBox); parseErr != nil {
+		return parseErr
+	}
+	if _, parseErr := parseBridgePrefixes("AllowedClientCIDRs", parseConfig.AllowedClientCIDRs); parseErr != nil {
+		return parseErr
+	}
+	if _, parseErr := parseBridgePrefixes("DeniedClientCIDRs", parseConfig.DeniedClientCIDRs); parseErr != nil {
+		return parseErr
+	}
+	if _, parseErr := parseBridgeTrustedProxies(parseConfig.TrustedProxies); parseErr != nil {
+		return parseErr
+	}
+	if parseConfig.ShouldAcceptProxyProtocol && len(parseConfig.TrustedProxies) == 0 {
+		return fmt.Errorf("grpctunnel: ShouldAcceptProxyProtocol requires TrustedProxies")
+	}
+	if parseConfig.TunnelAdmin != nil && parseConfig.TunnelRegistry != nil && parseConfig.TunnelRegistry != parseConfig.TunnelAdmin.Registry() {
+		return fmt.Errorf("grpctunnel: TunnelRegistry must be nil or TunnelAdmin.Registry() when TunnelAdmin is set")
+	}
 	return nil
 }
 
@@ -264,15 +555,20 @@ func buildBridgePingWriteTimeout(parseConfig BridgeConfig) time.Duration {
 	return parseWriteTimeout
 }
 
-// BuildBridgeHandler creates a typed websocket handler for a gRPC server.
-func BuildBridgeHandler(parseGrpcServer *grpc.Server, parseConfig BridgeConfig) (http.Handler, error) {
-	if parseGrpcServer == nil {
-		return nil, fmt.Errorf("grpctunnel: grpc server is required")
-	}
-	if parseErr := GetBridgeConfigError(parseConfig); parseErr != nil {
-		return nil, parseErr
-	}
+// bridgeTunnelServer stores the shared websocket upgrade pipeline used by bridge handlers and listeners.
+type bridgeTunnelServer struct {
+	setConfig         BridgeConfig
+	setUpgrader       websocket.Upgrader
+	getObservability  *bridgeObservability
+	getAbuseGuard     *bridgeAbuseGuard
+	getBandwidthGuard *bridgeBandwidthGuard
+	getTunnelTracker  *bridgeTunnelTracker
+	getTrustedProxies []netip.Prefix
+	handleTunnelConn  func(*http.Request, *bridgeTunnel)
+}
 
+// buildBridgeTunnelServer creates the shared upgrade pipeline that hands tunneled connections to a serve callback.
+func buildBridgeTunnelServer(parseConfig BridgeConfig, handleTunnelConn func(*http.Request, *bridgeTunnel)) *bridgeTunnelServer {
 	parseReadBufferSize := parseConfig.ReadBufferSize
 	if parseReadBufferSize == 0 {
 		parseReadBufferSize = parseDefaultWebSocketBufferSize
@@ -283,75 +579,241 @@ func BuildBridgeHandler(parseGrpcServer *grpc.Server, parseConfig BridgeConfig)
 		parseWriteBufferSize = parseDefaultWebSocketBufferSize
 	}
 
-	parseUpgrader := websocket.Upgrader{
-		ReadBufferSize:    parseReadBufferSize,
-		WriteBufferSize:   parseWriteBufferSize,
-		WriteBufferPool:   buildWebSocketWriteBufferPool(parseWriteBufferSize),
-		CheckOrigin:       parseConfig.CheckOrigin,
-		EnableCompression: parseConfig.ShouldEnableCompression,
-	}
-	parseHTTP2Server := &http2.Server{}
-	parseServeH2CHandler := h2c.NewHandler(parseGrpcServer, parseHTTP2Server)
+	// GetBridgeConfigError has already validated TrustedProxies.
+	parseTrustedProxies, _ := parseBridgeTrustedProxies(parseConfig.TrustedProxies)
+
 	parseObservability := buildBridgeObservability()
-	parseAbuseGuard := buildBridgeAbuseGuard(parseConfig)
-
-	return http.HandlerFunc(func(parseW http.ResponseWriter, parseR2 *http.Request) {
-		parseUpgradeStart := time.Now()
-		parseRequestContext, parseRequestSpan := parseObservability.startBridgeRequestSpan(parseR2.Context(), parseR2)
-		defer parseRequestSpan.End()
-		parseR2 = parseR2.WithContext(parseRequestContext)
-		if parseErr := parseAbuseGuard.reserveBridgeConnection(parseR2, time.Now()); parseErr != nil {
-			parseObservability.storeBridgeUpgradeFailure(parseRequestContext, time.Since(parseUpgradeStart), parseR2)
-			logGrpctunnelEvent("grpctunnel.bridge", "WARN", "ws_upgrade_rejected_abuse_control", parseR2, parseErr, "WebSocket upgrade rejected by abuse controls")
-			http.Error(parseW, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
-			return
-		}
-		defer parseAbuseGuard.clearBridgeConnection(parseR2)
+	return &bridgeTunnelServer{
+		setConfig: parseConfig,
+		setUpgrader: websocket.Upgrader{
+			ReadBufferSize:    parseReadBufferSize,
+			WriteBufferSize:   parseWriteBufferSize,
+			WriteBufferPool:   buildWebSocketWriteBufferPool(parseWriteBufferSize),
+			HandshakeTimeout:  parseConfig.UpgradeTimeout,
+			CheckOrigin:       parseConfig.CheckOrigin,
+			EnableCompression: parseConfig.ShouldEnableCompression,
+			Subprotocols:      parseConfig.Subprotocols,
+		},
+		getObservability:  parseObservability,
+		getAbuseGuard:     buildBridgeAbuseGuard(parseConfig),
+		getBandwidthGuard: buildBridgeBandwidthGuard(parseConfig.BandwidthLimits, parseObservability),
+		getTunnelTracker:  buildBridgeTunnelTracker(parseConfig.HealthMonitor.NewReporter()),
+		getTrustedProxies: parseTrustedProxies,
+		handleTunnelConn:  handleTunnelConn,
+	}
+}
+
+// serveBridgeHealthProbe answers /healthz and /readyz from the health monitor and reports whether it
+// handled the request. Websocket upgrades to those paths still open tunnels.
+func serveBridgeHealthProbe(parseW http.ResponseWriter, parseR *http.Request, parseMonitor *healthmonitor.Monitor) bool {
+	if parseMonitor == nil || websocket.IsWebSocketUpgrade(parseR) {
+		return false
+	}
+	switch parseR.URL.Path {
+	case "/healthz":
+		parseMonitor.ServeLiveness(parseW, parseR)
+	case "/readyz":
+		parseMonitor.ServeReadiness(parseW, parseR)
+	default:
+		return false
+	}
+	return true
+}
 
-		// Upgrade to WebSocket
-		parseWs, parseErr := parseUpgrader.Upgrade(parseW, parseR2, nil)
+// ServeHTTP upgrades one websocket tunnel and blocks until the tunneled connection is released.
+func (parseServer *bridgeTunnelServer) ServeHTTP(parseW http.ResponseWriter, parseR2 *http.Request) {
+	parseConfig := parseServer.setConfig
+	parseObservability := parseServer.getObservability
+	parseAbuseGuard := parseServer.getAbuseGuard
+
+	if serveBridgeHealthProbe(parseW, parseR2, parseConfig.HealthMonitor) {
+		return
+	}
+
+	parseUpgradeStart := time.Now()
+	parseR2 = resolveBridgeClientRequest(parseR2, parseServer.getTrustedProxies)
+	parseRequestContext, parseRequestSpan := parseObservability.startBridgeRequestSpan(parseR2.Context(), parseR2)
+	defer parseRequestSpan.End()
+	parseR2 = parseR2.WithContext(parseRequestContext)
+	if parseServer.getTunnelTracker.isDraining.Load() {
+		parseObservability.storeBridgeUpgradeFailure(parseRequestContext, time.Since(parseUpgradeStart), parseR2)
+		logGrpctunnelEvent("grpctunnel.bridge", "WARN", "ws_upgrade_rejected_draining", parseR2, nil, "WebSocket upgrade rejected because the bridge is draining")
+		storeBridgeUpgradeRejected(parseConfig.TunnelObserver, parseR2, UpgradeRejectDraining, http.StatusServiceUnavailable, nil)
+		http.Error(parseW, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+	if parseConfig.MemoryBudget.IsExhausted() {
+		parseObservability.storeBridgeUpgradeFailure(parseRequestContext, time.Since(parseUpgradeStart), parseR2)
+		logGrpctunnelEvent("grpctunnel.bridge", "WARN", "ws_upgrade_rejected_memory_budget", parseR2, nil, "WebSocket upgrade rejected because the memory budget is exhausted")
+		storeBridgeUpgradeRejected(parseConfig.TunnelObserver, parseR2, UpgradeRejectMemoryBudget, http.StatusServiceUnavailable, nil)
+		http.Error(parseW, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+	parseLease, parseErr := parseAbuseGuard.reserveBridgeConnection(parseR2, time.Now())
+	if parseErr != nil {
+		parseObservability.storeBridgeUpgradeFailure(parseRequestContext, time.Since(parseUpgradeStart), parseR2)
+		parseObservability.storeBridgeAbuseRejection(parseRequestContext, parseErr)
+		logGrpctunnelEvent("grpctunnel.bridge", "WARN", "ws_upgrade_rejected_abuse_control", parseR2, parseErr, "WebSocket upgrade rejected by abuse controls")
+		parseReason, parseStatusCode := getBridgeAbuseRejectReason(parseErr)
+		storeBridgeUpgradeRejected(parseConfig.TunnelObserver, parseR2, parseReason, parseStatusCode, parseErr)
+		writeBridgeAbuseRejection(parseW, parseErr)
+		return
+	}
+	defer parseAbuseGuard.clearBridgeConnection(parseLease)
+
+	var parseBaseContext, parseExpiryContext context.Context
+	if parseConfig.Authenticate != nil {
+		parseAuthContext, parseErr := parseConfig.Authenticate(parseR2)
 		if parseErr != nil {
 			parseObservability.storeBridgeUpgradeFailure(parseRequestContext, time.Since(parseUpgradeStart), parseR2)
-			logGrpctunnelEvent("grpctunnel.bridge", "WARN", "ws_upgrade_failed", parseR2, parseErr, "WebSocket upgrade failed")
+			parseStatusCode := getBridgeAuthStatusCode(parseErr)
+			logGrpctunnelEvent("grpctunnel.bridge", "WARN", "ws_upgrade_rejected_auth", parseR2, parseErr, "WebSocket upgrade rejected by authentication")
+			storeBridgeUpgradeRejected(parseConfig.TunnelObserver, parseR2, UpgradeRejectAuthentication, parseStatusCode, parseErr)
+			http.Error(parseW, http.StatusText(parseStatusCode), parseStatusCode)
 			return
 		}
-		parseObservability.storeBridgeUpgradeSuccess(parseRequestContext, time.Since(parseUpgradeStart), parseR2)
-		parseObservability.storeBridgeConnectionDelta(parseRequestContext, parseR2, 1)
-		defer parseObservability.storeBridgeConnectionDelta(parseRequestContext, parseR2, -1)
-		parseSessionContext, parseSessionSpan := parseObservability.startBridgeSessionSpan(parseRequestContext, parseR2)
-		defer parseSessionSpan.End()
-		parseR2 = parseR2.WithContext(parseSessionContext)
-		logGrpctunnelEvent("grpctunnel.bridge", "INFO", "ws_upgrade_succeeded", parseR2, nil, "WebSocket upgrade succeeded")
-		defer parseWs.Close()
-
-		parseStopKeepalive, parseErr := applyBridgeConnectionSettings(parseWs, parseConfig)
-		if parseErr != nil {
-			logGrpctunnelEvent("grpctunnel.bridge", "WARN", "ws_connection_setup_failed", parseR2, parseErr, "WebSocket connection setup failed")
-			return
+		if parseAuthContext == nil {
+			parseAuthContext = parseR2.Context()
 		}
-		defer parseStopKeepalive()
+		parseExpiryContext = parseAuthContext
+		parseBaseContext = context.WithoutCancel(parseAuthContext)
+	}
+
+	if parseConfig.UpgradeTimeout > 0 && time.Since(parseUpgradeStart) > parseConfig.UpgradeTimeout {
+		parseObservability.storeBridgeUpgradeFailure(parseRequestContext, time.Since(parseUpgradeStart), parseR2)
+		parseObservability.storeBridgeSlowClient(parseRequestContext, parseBridgeSlowClientUpgradeTimeout)
+		logGrpctunnelEvent("grpctunnel.bridge", "WARN", "ws_upgrade_timeout", parseR2, nil, "WebSocket upgrade rejected because it exceeded UpgradeTimeout")
+		storeBridgeUpgradeRejected(parseConfig.TunnelObserver, parseR2, UpgradeRejectUpgradeTimeout, http.StatusRequestTimeout, nil)
+		http.Error(parseW, http.StatusText(http.StatusRequestTimeout), http.StatusRequestTimeout)
+		return
+	}
+
+	// Upgrade to WebSocket
+	parseWs, parseErr := parseServer.setUpgrader.Upgrade(parseW, parseR2, nil)
+	if parseErr != nil {
+		parseObservability.storeBridgeUpgradeFailure(parseRequestContext, time.Since(parseUpgradeStart), parseR2)
+		logGrpctunnelEvent("grpctunnel.bridge", "WARN", "ws_upgrade_failed", parseR2, parseErr, "WebSocket upgrade failed")
+		storeBridgeUpgradeRejected(parseConfig.TunnelObserver, parseR2, UpgradeRejectHandshake, 0, parseErr)
+		return
+	}
+	parseObservability.storeBridgeUpgradeSuccess(parseRequestContext, time.Since(parseUpgradeStart), parseR2)
+	parseObservability.storeBridgeConnectionDelta(parseRequestContext, parseR2, 1)
+	defer parseObservability.storeBridgeConnectionDelta(parseRequestContext, parseR2, -1)
+	parseSessionContext, parseSessionSpan := parseObservability.startBridgeSessionSpan(parseRequestContext, parseR2)
+	defer parseSessionSpan.End()
+	parseR2 = parseR2.WithContext(parseSessionContext)
+	logGrpctunnelEvent("grpctunnel.bridge", "INFO", "ws_upgrade_succeeded", parseR2, nil, "WebSocket upgrade succeeded")
+	defer parseWs.Close()
+
+	parseStopKeepalive, parseErr := applyBridgeConnectionSettings(parseWs, parseConfig)
+	if parseErr != nil {
+		logGrpctunnelEvent("grpctunnel.bridge", "WARN", "ws_connection_setup_failed", parseR2, parseErr, "WebSocket connection setup failed")
+		return
+	}
+	defer parseStopKeepalive()
 
-		// Lifecycle hooks
-		if parseConfig.OnConnect != nil {
-			parseConfig.OnConnect(parseR2)
+	// Lifecycle hooks
+	if parseConfig.OnConnect != nil {
+		parseConfig.OnConnect(parseR2)
+	}
+	logGrpctunnelEvent("grpctunnel.bridge", "INFO", "tunnel_connect", parseR2, nil, "Tunnel connected")
+	defer func() {
+		logGrpctunnelEvent("grpctunnel.bridge", "INFO", "tunnel_disconnect", parseR2, nil, "Tunnel disconnected")
+		if parseConfig.OnDisconnect != nil {
+			parseConfig.OnDisconnect(parseR2)
 		}
-		logGrpctunnelEvent("grpctunnel.bridge", "INFO", "tunnel_connect", parseR2, nil, "Tunnel connected")
-		defer func() {
-			logGrpctunnelEvent("grpctunnel.bridge", "INFO", "tunnel_disconnect", parseR2, nil, "Tunnel disconnected")
-			if parseConfig.OnDisconnect != nil {
-				parseConfig.OnDisconnect(parseR2)
-			}
-		}()
+	}()
+
+	// Wrap WebSocket as net.Conn
+	parseClientKey := parseAbuseGuard.getBridgeGuardClientKey(parseR2)
+	parseBandwidth := parseServer.getBandwidthGuard.buildBridgeConnBandwidth(parseSessionContext, parseClientKey)
+	parseSlowClient := buildBridgeSlowClientGuard(parseConfig, parseR2, parseObservability)
+	parseMemory := parseConfig.MemoryBudget.NewTunnel(getBridgeTunnelOverhead(parseConfig))
+	parseWebSocketConn := newBridgeWebSocketConn(parseWs, parseBandwidth, parseSlowClient, parseMemory)
+	parseConn := buildBridgeClientAddrConn(parseWebSocketConn, parseR2)
+	defer parseConn.Close()
+
+	parseTunnel := &bridgeTunnel{
+		getID:          buildBridgeTunnelID(),
+		getConn:        parseConn,
+		getBaseContext: parseBaseContext,
+		getRPCLimiter:  buildBridgeRateLimiter(RateLimit(parseAbuseGuard.getBridgeAbuseLimits().RPCRateLimit)),
+	}
+	storeDisconnected := storeBridgeTunnelConnected(parseConfig.TunnelObserver, parseTunnel, parseWebSocketConn, parseR2)
+	defer storeDisconnected()
+	parseServer.getTunnelTracker.storeBridgeTunnel(parseTunnel)
+	defer parseServer.getTunnelTracker.clearBridgeTunnel(parseTunnel)
+	parseRegistered := storeBridgeTunnelRegistry(parseConfig.TunnelRegistry, parseTunnel, parseWebSocketConn, parseR2, parseClientKey)
+	defer parseRegistered.Remove()
+	clearExpiry := startBridgeTunnelAuthExpiry(parseTunnel, parseExpiryContext, parseConfig.AuthExpiryGrace, parseR2)
+	defer clearExpiry()
+	clearMaxAge := startBridgeTunnelMaxAge(parseTunnel, parseConfig, parseR2, parseObservability)
+	defer clearMaxAge()
+
+	parseServer.handleTunnelConn(parseR2, parseTunnel)
+}
+
+// BuildBridgeHandler creates a typed websocket handler for a gRPC server.
+func BuildBridgeHandler(parseGrpcServer *grpc.Server, parseConfig BridgeConfig) (http.Handler, error) {
+	if parseGrpcServer == nil {
+		return nil, fmt.Errorf("grpctunnel: grpc server is required")
+	}
+	if parseErr := GetBridgeConfigError(parseConfig); parseErr != nil {
+		return nil, parseErr
+	}
+
+	return buildBridgeGRPCTunnelServer(parseGrpcServer, applyBridgeTunnelObservers(parseConfig)), nil
+}
 
-		// Wrap WebSocket as net.Conn
-		parseConn := newWebSocketConn(parseWs)
-		defer parseConn.Close()
+// buildBridgeGRPCTunnelServer creates the tunnel pipeline that serves gRPC over HTTP/2 on each websocket.
+func buildBridgeGRPCTunnelServer(parseGrpcServer *grpc.Server, parseConfig BridgeConfig) *bridgeTunnelServer {
+	parseServeH2CHandler := h2c.NewHandler(parseGrpcServer, &http2.Server{})
+	parseTunnelServer := buildBridgeTunnelServer(parseConfig, nil)
+	parseTunnelServer.handleTunnelConn = func(parseRequest *http.Request, parseTunnel *bridgeTunnel) {
+		parseHTTP2Server, parseShutdownServer := buildBridgeTunnelHTTP2Server(parseConfig)
+		parseTunnel.storeBridgeTunnelDrain(buildBridgeTunnelDrain(parseShutdownServer))
 
 		// Serve gRPC over HTTP/2 on the WebSocket connection
-		parseHTTP2Server.ServeConn(parseConn, &http2.ServeConnOpts{
-			Handler: parseServeH2CHandler,
+		parseHTTP2Server.ServeConn(parseTunnel.getConn, &http2.ServeConnOpts{
+			Context:    parseTunnel.getBaseContext,
+			BaseConfig: parseShutdownServer,
+			Handler: http.HandlerFunc(func(parseW http.ResponseWriter, parseStreamRequest *http.Request) {
+				parseTunnel.getActiveStreams.Add(1)
+				defer parseTunnel.getActiveStreams.Add(-1)
+				storeStreamClosed := startBridgeStream(parseConfig.TunnelObserver, parseTunnel, parseStreamRequest.URL.Path)
+				defer storeStreamClosed(parseW.Header())
+				if !parseTunnel.getRPCLimiter.allowBridgeRateLimiter(time.Now()) {
+					parseTunnelServer.getObservability.storeBridgeRPCDenied(parseStreamRequest.Context(), parseBridgeMetricReasonRateLimit)
+					logGrpctunnelEvent("grpctunnel.bridge", "WARN", "rpc_rate_limited", parseStreamRequest, nil, "RPC rejected by tunnel rate limit")
+					writeBridgeRPCStatus(parseW, status.New(codes.ResourceExhausted, parseRPCRateLimitedMessage))
+					return
+				}
+				if parseConfig.MemoryBudget.IsExhausted() {
+					parseTunnelServer.getObservability.storeBridgeRPCDenied(parseStreamRequest.Context(), parseBridgeMetricReasonMemoryBudget)
+					logGrpctunnelEvent("grpctunnel.bridge", "WARN", "rpc_rejected_memory_budget", parseStreamRequest, nil, "RPC rejected because the memory budget is exhausted")
+					writeBridgeRPCStatus(parseW, status.New(codes.ResourceExhausted, parseMemoryBudgetExhaustedMessage))
+					return
+				}
+				if !parseConfig.ExposurePolicy.isExposed(parseStreamRequest.URL.Path) {
+					parseTunnelServer.getObservability.storeBridgeRPCDenied(parseStreamRequest.Context(), parseBridgeMetricReasonExposure)
+					logGrpctunnelEvent("grpctunnel.bridge", "WARN", "rpc_denied_exposure_policy", parseStreamRequest, nil, "RPC rejected by exposure policy")
+					writeBridgeRPCStatus(parseW, status.New(codes.PermissionDenied, parseExposureDeniedMessage))
+					return
+				}
+				if parseConfig.Authorize != nil {
+					if parseErr := parseConfig.Authorize(parseStreamRequest.Context(), parseRequest, parseStreamRequest.URL.Path); parseErr != nil {
+						parseTunnelServer.getObservability.storeBridgeRPCDenied(parseStreamRequest.Context(), parseBridgeMetricReasonAuthorization)
+						logGrpctunnelEvent("grpctunnel.bridge", "WARN", "rpc_denied_authorization", parseStreamRequest, parseErr, "RPC rejected by authorization")
+						writeBridgeRPCStatus(parseW, getBridgeAuthorizeStatus(parseErr))
+						return
+					}
+				}
+				parseW, parseStreamRequest = parseTunnelServer.getBandwidthGuard.applyBridgeMethodBandwidth(parseW, parseStreamRequest)
+				parseServeH2CHandler.ServeHTTP(parseW, parseStreamRequest)
+			}),
 		})
-	}), nil
+	}
+	return parseTunnelServer
 }
 
 // HandleBridgeMux registers a typed bridge handler on a mux path.
@@ -384,17 +846,9 @@ func buildServerOptions(parseOpts ...ServerOption) *serverOptions {
 	return parseOptions
 }
 
-// Wrap creates an http.Handler that serves a gRPC server over WebSocket.
-// This is the middleware-style API for integrating WebSocket transport.
-//
-// Example:
-//
-//	grpcServer := grpc.NewServer()
-//	proto.RegisterYourServiceServer(grpcServer, &yourImpl{})
-//	http.ListenAndServe(":8080", grpctunnel.Wrap(grpcServer))
-func Wrap(parseGrpcServer *grpc.Server, parseOpts ...ServerOption) http.Handler {
-	parseOptions := buildServerOptions(parseOpts...)
-	parseHandler, parseErr := BuildBridgeHandler(parseGrpcServer, BridgeConfig{
+// buildServerOptionsBridgeConfig converts functional server options into a bridge configuration.
+func buildServerOptionsBridgeConfig(parseOptions *serverOptions) BridgeConfig {
+	return BridgeConfig{
 		CheckOrigin:                   parseOptions.checkOrigin,
 		ReadBufferSize:                parseOptions.readBufferSize,
 		WriteBufferSize:               parseOptions.writeBufferSize,
@@ -402,13 +856,55 @@ func Wrap(parseGrpcServer *grpc.Server, parseOpts ...ServerOption) http.Handler
 		ShouldDisableReadLimit:        parseOptions.shouldDisableReadLimit,
 		PingInterval:                  parseOptions.pingInterval,
 		IdleTimeout:                   parseOptions.idleTimeout,
+		UpgradeTimeout:                parseOptions.upgradeTimeout,
+		PrefaceTimeout:                parseOptions.prefaceTimeout,
+		MinReadThroughput:             parseOptions.minReadThroughput,
+		WriteTimeout:                  parseOptions.writeTimeout,
+		MaxConnectionAge:              parseOptions.maxConnectionAge,
+		MaxConnectionAgeGrace:         parseOptions.maxConnectionAgeGrace,
 		ShouldEnableCompression:       parseOptions.shouldEnableCompression,
 		MaxActiveConnections:          parseOptions.maxActiveConnections,
 		MaxConnectionsPerClient:       parseOptions.maxConnectionsPerClient,
 		MaxUpgradesPerClientPerMinute: parseOptions.maxUpgradesPerClient,
+		UpgradeRateLimit:              parseOptions.upgradeRateLimit,
+		RPCRateLimit:                  parseOptions.rpcRateLimit,
+		BandwidthLimits:               parseOptions.bandwidthLimits,
+		MemoryBudget:                  parseOptions.memoryBudget,
+		HTTP2Profile:                  parseOptions.http2Profile,
+		HTTP2:                         parseOptions.http2Settings,
+		ClientKeyFunc:                 parseOptions.clientKeyFunc,
+		ShouldGroupIPv6Clients:        parseOptions.shouldGroupIPv6Clients,
+		AllowedClientCIDRs:            parseOptions.allowedClientCIDRs,
+		DeniedClientCIDRs:             parseOptions.deniedClientCIDRs,
+		PenaltyBox:                    parseOptions.penaltyBox,
+		LimitStore:                    parseOptions.limitStore,
+		ExposurePolicy:                parseOptions.exposurePolicy,
+		Authorize:                     parseOptions.authorize,
+		Authenticate:                  parseOptions.authenticate,
+		AuthExpiryGrace:               parseOptions.authExpiryGrace,
+		TrustedProxies:                parseOptions.trustedProxies,
+		ShouldAcceptProxyProtocol:     parseOptions.shouldAcceptProxyProto,
 		OnConnect:                     parseOptions.onConnect,
 		OnDisconnect:                  parseOptions.onDisconnect,
-	})
+		TunnelObserver:                parseOptions.tunnelObserver,
+		TunnelRegistry:                parseOptions.tunnelRegistry,
+		TunnelAdmin:                   parseOptions.tunnelAdmin,
+		TunnelStates:                  parseOptions.tunnelStates,
+		HealthMonitor:                 parseOptions.healthMonitor,
+	}
+}
+
+// Wrap creates an http.Handler that serves a gRPC server over WebSocket.
+// This is the middleware-style API for integrating WebSocket transport.
+//
+// Example:
+//
+//	grpcServer := grpc.NewServer()
+//	proto.RegisterYourServiceServer(grpcServer, &yourImpl{})
+//	http.ListenAndServe(":8080", grpctunnel.Wrap(grpcServer))
+func Wrap(parseGrpcServer *grpc.Server, parseOpts ...ServerOption) http.Handler {
+	parseOptions := buildServerOptions(parseOpts...)
+	parseHandler, parseErr := BuildBridgeHandler(parseGrpcServer, buildServerOptionsBridgeConfig(parseOptions))
 	if parseErr != nil {
 		return http.HandlerFunc(func(parseW http.ResponseWriter, parseR *http.Request) {
 			logGrpctunnelEvent("grpctunnel.bridge", "ERROR", "bridge_handler_init_failed", parseR, parseErr, "Bridge handler initialization failed")
@@ -429,11 +925,20 @@ func Wrap(parseGrpcServer *grpc.Server, parseOpts ...ServerOption) http.Handler
 //	lis, _ := net.Listen("tcp", ":8080")
 //	grpctunnel.Serve(lis, grpcServer)
 func Serve(parseListener net.Listener, parseGrpcServer *grpc.Server, parseOpts ...ServerOption) error {
+	parseOptions := buildServerOptions(parseOpts...)
+	if parseOptions.shouldAcceptProxyProto {
+		parseProxyListener, parseErr := NewProxyProtocolListener(parseListener, parseOptions.trustedProxies)
+		if parseErr != nil {
+			return parseErr
+		}
+		parseListener = parseProxyListener
+	}
 	parseServer := &http.Server{
-		Handler:      Wrap(parseGrpcServer, parseOpts...),
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		Handler:           Wrap(parseGrpcServer, parseOpts...),
+		ReadHeaderTimeout: parseOptions.upgradeTimeout,
+		ReadTimeout:       15 * time.Second,
+		WriteTimeout:      15 * time.Second,
+		IdleTimeout:       60 * time.Second,
 	}
 	return parseServer.Serve(parseListener)
 }
@@ -447,12 +952,12 @@ func Serve(parseListener net.Listener, parseGrpcServer *grpc.Server, parseOpts .
 //	proto.RegisterYourServiceServer(grpcServer, &yourImpl{})
 //	grpctunnel.ListenAndServe(":8080", grpcServer)
 func ListenAndServe(parseAddr string, parseGrpcServer *grpc.Server, parseOpts ...ServerOption) error {
-	parseServer := &http.Server{
-		Addr:         parseAddr,
-		Handler:      Wrap(parseGrpcServer, parseOpts...),
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+	if parseAddr == "" {
+		parseAddr = ":http"
+	}
+	parseListener, parseErr := net.Listen("tcp", parseAddr)
+	if parseErr != nil {
+		return parseErr
 	}
-	return parseServer.ListenAndServe()
+	return Serve(parseListener, parseGrpcServer, parseOpts...)
 }
@@ -0,0 +1,119 @@
+//go:build !js && !wasm
+
+package grpctunnel
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// Server serves a gRPC server over websocket tunnels with graceful drain and shutdown support.
+//
+// Example:
+//
+//	grpcServer := grpc.NewServer()
+//	proto.RegisterYourServiceServer(grpcServer, &yourImpl{})
+//
+//	server, _ := grpctunnel.NewServer(grpcServer, grpctunnel.BridgeConfig{})
+//	go server.ListenAndServe(":8080")
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+//	defer cancel()
+//	forceClosed, err := server.Shutdown(ctx)
+type Server struct {
+	getGrpcServer   *grpc.Server
+	getHTTPServer   *http.Server
+	getTunnelServer *bridgeTunnelServer
+}
+
+// NewServer creates a tunnel server that owns the HTTP server and the wrapped gRPC server lifecycle.
+func NewServer(parseGrpcServer *grpc.Server, parseConfig BridgeConfig) (*Server, error) {
+	if parseGrpcServer == nil {
+		return nil, fmt.Errorf("grpctunnel: grpc server is required")
+	}
+	if parseErr := GetBridgeConfigError(parseConfig); parseErr != nil {
+		return nil, parseErr
+	}
+
+	parseTunnelServer := buildBridgeGRPCTunnelServer(parseGrpcServer, applyBridgeTunnelObservers(parseConfig))
+	return &Server{
+		getGrpcServer: parseGrpcServer,
+		getHTTPServer: &http.Server{
+			Handler:           parseTunnelServer,
+			ReadHeaderTimeout: parseConfig.UpgradeTimeout,
+			ReadTimeout:       15 * time.Second,
+			WriteTimeout:      15 * time.Second,
+			IdleTimeout:       60 * time.Second,
+		},
+		getTunnelServer: parseTunnelServer,
+	}, nil
+}
+
+// Handler returns the websocket bridge handler for mounting on a custom mux.
+// Tunnels served through it are still tracked by Drain and Shutdown.
+func (parseServer *Server) Handler() http.Handler {
+	return parseServer.getTunnelServer
+}
+
+// Serve accepts connections on the listener and serves gRPC over WebSocket.
+// When ShouldAcceptProxyProtocol is set, connections from TrustedProxies must start with a PROXY protocol header.
+// It returns http.ErrServerClosed after Shutdown.
+func (parseServer *Server) Serve(parseListener net.Listener) error {
+	if parseServer.getTunnelServer.setConfig.ShouldAcceptProxyProtocol {
+		parseProxyListener, parseErr := NewProxyProtocolListener(parseListener, parseServer.getTunnelServer.setConfig.TrustedProxies)
+		if parseErr != nil {
+			return parseErr
+		}
+		parseListener = parseProxyListener
+	}
+	return parseServer.getHTTPServer.Serve(parseListener)
+}
+
+// ListenAndServe listens on the TCP network address and serves gRPC over WebSocket.
+// It returns http.ErrServerClosed after Shutdown.
+func (parseServer *Server) ListenAndServe(parseAddr string) error {
+	parseListener, parseErr := net.Listen("tcp", parseAddr)
+	if parseErr != nil {
+		return parseErr
+	}
+	return parseServer.Serve(parseListener)
+}
+
+// ActiveTunnels returns the number of live websocket tunnels.
+func (parseServer *Server) ActiveTunnels() int {
+	return len(parseServer.getTunnelServer.getTunnelTracker.getBridgeTunnels())
+}
+
+// Drain stops accepting new websocket upgrades and sends HTTP/2 GOAWAY to every live tunnel.
+// In-flight RPCs keep running; clients reconnect elsewhere for new RPCs. Drain does not block.
+func (parseServer *Server) Drain() {
+	logGrpctunnelEvent("grpctunnel.bridge", "INFO", "bridge_drain_started", nil, nil, "Bridge drain started")
+	parseServer.getHTTPServer.SetKeepAlivesEnabled(false)
+	parseServer.getTunnelServer.getTunnelTracker.drainBridgeTunnels()
+}
+
+// Shutdown drains the server, closes idle tunnels, and waits for in-flight RPCs to finish.
+// When the context ends first, remaining tunnels are force-closed, and the number of them that still
+// had RPCs in flight is returned together with the context error. The wrapped gRPC server is stopped before Shutdown returns.
+// Tunnels served by NewListener are not tracked here; stop them with grpc.Server.GracefulStop.
+func (parseServer *Server) Shutdown(parseCtx context.Context) (int, error) {
+	parseServer.Drain()
+	parseHTTPErr := parseServer.getHTTPServer.Shutdown(parseCtx)
+
+	parseForceClosed, parseErr := parseServer.getTunnelServer.getTunnelTracker.shutdownBridgeTunnels(parseCtx)
+	parseServer.getGrpcServer.Stop()
+	if parseForceClosed > 0 {
+		logGrpctunnelEvent("grpctunnel.bridge", "WARN", "bridge_shutdown_forced", nil, parseErr, fmt.Sprintf("Bridge shutdown force-closed %d tunnels with RPCs in flight", parseForceClosed))
+	} else {
+		logGrpctunnelEvent("grpctunnel.bridge", "INFO", "bridge_shutdown_completed", nil, nil, "Bridge shutdown completed")
+	}
+	if parseErr != nil {
+		return parseForceClosed, parseErr
+	}
+	return parseForceClosed, parseHTTPErr
+}
@@ -0,0 +1,253 @@
+//go:build !js && !wasm
+
+package grpctunnel
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/monstercameron/grpc-tunnel/examples/_shared/proto"
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/tunneladmin"
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/tunnelregistry"
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/tunnelstate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// storeShutdownTestService blocks streaming RPCs until released so drain behavior can be observed.
+type storeShutdownTestService struct {
+	mockService
+	getStreamStarted chan struct{}
+	getStreamRelease chan struct{}
+}
+
+// StreamTodos sends one todo, then blocks until the test releases the stream or the RPC ends.
+func (parseS *storeShutdownTestService) StreamTodos(parseReq *proto.StreamTodosRequest, parseStream proto.TodoService_StreamTodosServer) error {
+	if parseErr := parseStream.Send(&proto.StreamTodosResponse{Todo: &proto.Todo{Id: "1"}}); parseErr != nil {
+		return parseErr
+	}
+	parseS.getStreamStarted <- struct{}{}
+	select {
+	case <-parseS.getStreamRelease:
+		return parseStream.Send(&proto.StreamTodosResponse{Todo: &proto.Todo{Id: "2"}})
+	case <-parseStream.Context().Done():
+		return parseStream.Context().Err()
+	}
+}
+
+// buildShutdownTestServer starts a tunnel Server on a loopback listener.
+func buildShutdownTestServer(parseT *testing.T) (*Server, *storeShutdownTestService, string) {
+	parseT.Helper()
+
+	parseGrpcServer := grpc.NewServer()
+	parseService := &storeShutdownTestService{
+		getStreamStarted: make(chan struct{}, 1),
+		getStreamRelease: make(chan struct{}),
+	}
+	proto.RegisterTodoServiceServer(parseGrpcServer, parseService)
+
+	parseServer, parseErr := NewServer(parseGrpcServer, BridgeConfig{})
+	if parseErr != nil {
+		parseT.Fatalf("NewServer() error: %v", parseErr)
+	}
+	parseListener, parseErr := net.Listen("tcp", "127.0.0.1:0")
+	if parseErr != nil {
+		parseT.Fatalf("Listen() error: %v", parseErr)
+	}
+	go func() {
+		_ = parseServer.Serve(parseListener)
+	}()
+	return parseServer, parseService, "ws://" + parseListener.Addr().String()
+}
+
+// startShutdownTestStream opens a tunnel and starts one blocking server stream.
+func startShutdownTestStream(parseT *testing.T, parseCtx context.Context, parseService *storeShutdownTestService, parseWsURL string) (*grpc.ClientConn, proto.TodoService_StreamTodosClient) {
+	parseT.Helper()
+
+	parseConn, parseErr := DialContext(parseCtx, parseWsURL, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if parseErr != nil {
+		parseT.Fatalf("DialContext() error: %v", parseErr)
+	}
+	parseStream, parseErr := proto.NewTodoServiceClient(parseConn).StreamTodos(parseCtx, &proto.StreamTodosRequest{})
+	if parseErr != nil {
+		parseT.Fatalf("StreamTodos() error: %v", parseErr)
+	}
+	if _, parseErr = parseStream.Recv(); parseErr != nil {
+		parseT.Fatalf("Recv() error: %v", parseErr)
+	}
+	select {
+	case <-parseService.getStreamStarted:
+	case <-time.After(2 * time.Second):
+		parseT.Fatal("timed out waiting for stream to start")
+	}
+	return parseConn, parseStream
+}
+
+// TestNewServer_Validation verifies NewServer rejects nil servers and invalid config.
+func TestNewServer_Validation(parseT *testing.T) {
+	if _, parseErr := NewServer(nil, BridgeConfig{}); parseErr == nil {
+		parseT.Fatal("NewServer(nil) expected error, got nil")
+	}
+	if _, parseErr := NewServer(grpc.NewServer(), BridgeConfig{ReadBufferSize: -1}); parseErr == nil {
+		parseT.Fatal("NewServer(invalid config) expected error, got nil")
+	}
+}
+
+// TestNewServer_TunnelObservers verifies NewServer tunnels reach the TunnelAdmin registry and
+// the TunnelStates recorder.
+func TestNewServer_TunnelObservers(parseT *testing.T) {
+	parseGrpcServer := grpc.NewServer()
+	proto.RegisterTodoServiceServer(parseGrpcServer, &mockService{})
+	defer parseGrpcServer.Stop()
+
+	parseAdmin := tunneladmin.New(nil)
+	parseStates := tunnelstate.New(0)
+	parseServer, parseErr := NewServer(parseGrpcServer, BridgeConfig{TunnelAdmin: parseAdmin, TunnelStates: parseStates})
+	if parseErr != nil {
+		parseT.Fatalf("NewServer() error: %v", parseErr)
+	}
+	parseHTTPServer := httptest.NewServer(parseServer.Handler())
+	defer parseHTTPServer.Close()
+
+	parseCtx, clearCtx := context.WithTimeout(context.Background(), 5*time.Second)
+	defer clearCtx()
+	parseConn, parseErr := BuildTunnelConn(parseCtx, TunnelConfig{
+		Target:      "ws" + strings.TrimPrefix(parseHTTPServer.URL, "http"),
+		GRPCOptions: []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())},
+	})
+	if parseErr != nil {
+		parseT.Fatalf("BuildTunnelConn() error: %v", parseErr)
+	}
+	defer parseConn.Close()
+	if _, parseErr := proto.NewTodoServiceClient(parseConn).CreateTodo(parseCtx, &proto.CreateTodoRequest{Text: "observed"}); parseErr != nil {
+		parseT.Fatalf("CreateTodo() error: %v", parseErr)
+	}
+
+	if parseTunnels := parseAdmin.Registry().List(tunnelregistry.Filter{}); len(parseTunnels) != 1 {
+		parseT.Fatalf("TunnelAdmin registry tunnels = %d, want 1", len(parseTunnels))
+	}
+	if parseSnapshot := parseStates.Snapshot(); len(parseSnapshot.Tunnels) != 1 {
+		parseT.Fatalf("TunnelStates tunnels = %+v, want one open tunnel", parseSnapshot.Tunnels)
+	}
+}
+
+// TestServer_DrainRejectsNewUpgrades verifies a draining server refuses upgrades with 503.
+func TestServer_DrainRejectsNewUpgrades(parseT *testing.T) {
+	parseGrpcServer := grpc.NewServer()
+	defer parseGrpcServer.Stop()
+
+	parseServer, parseErr := NewServer(parseGrpcServer, BridgeConfig{})
+	if parseErr != nil {
+		parseT.Fatalf("NewServer() error: %v", parseErr)
+	}
+	parseServer.Drain()
+
+	parseRecorder := httptest.NewRecorder()
+	parseServer.Handler().ServeHTTP(parseRecorder, httptest.NewRequest(http.MethodGet, "/grpc", nil))
+	if parseRecorder.Code != http.StatusServiceUnavailable {
+		parseT.Fatalf("ServeHTTP() status = %d, want %d", parseRecorder.Code, http.StatusServiceUnavailable)
+	}
+}
+
+// TestServer_ShutdownWaitsForInFlightStream verifies Shutdown lets a streaming RPC finish before closing its tunnel.
+func TestServer_ShutdownWaitsForInFlightStream(parseT *testing.T) {
+	parseServer, parseService, parseWsURL := buildShutdownTestServer(parseT)
+
+	parseCtx, clearCtx := context.WithTimeout(context.Background(), 5*time.Second)
+	defer clearCtx()
+	parseConn, parseStream := startShutdownTestStream(parseT, parseCtx, parseService, parseWsURL)
+	defer parseConn.Close()
+
+	type storeShutdownResult struct {
+		getForceClosed int
+		getErr         error
+	}
+	parseShutdownResult := make(chan storeShutdownResult, 1)
+	go func() {
+		parseForceClosed, parseErr := parseServer.Shutdown(parseCtx)
+		parseShutdownResult <- storeShutdownResult{getForceClosed: parseForceClosed, getErr: parseErr}
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	if parseServer.ActiveTunnels() != 1 {
+		parseT.Fatalf("ActiveTunnels() during drain = %d, want 1", parseServer.ActiveTunnels())
+	}
+	close(parseService.getStreamRelease)
+
+	parseResp, parseErr := parseStream.Recv()
+	if parseErr != nil {
+		parseT.Fatalf("Recv() after drain error: %v", parseErr)
+	}
+	if parseResp.Todo.Id != "2" {
+		parseT.Fatalf("Recv() after drain id = %q, want %q", parseResp.Todo.Id, "2")
+	}
+
+	select {
+	case parseResult := <-parseShutdownResult:
+		if parseResult.getErr != nil {
+			parseT.Fatalf("Shutdown() error: %v", parseResult.getErr)
+		}
+		if parseResult.getForceClosed != 0 {
+			parseT.Fatalf("Shutdown() force closed = %d, want 0", parseResult.getForceClosed)
+		}
+	case <-time.After(3 * time.Second):
+		parseT.Fatal("Shutdown() did not return after stream completed")
+	}
+	if parseServer.ActiveTunnels() != 0 {
+		parseT.Fatalf("ActiveTunnels() after shutdown = %d, want 0", parseServer.ActiveTunnels())
+	}
+}
+
+// TestServer_ShutdownForceClosesAtDeadline verifies Shutdown reports tunnels force-closed when the deadline passes.
+func TestServer_ShutdownForceClosesAtDeadline(parseT *testing.T) {
+	parseServer, parseService, parseWsURL := buildShutdownTestServer(parseT)
+
+	parseCtx, clearCtx := context.WithTimeout(context.Background(), 5*time.Second)
+	defer clearCtx()
+	parseConn, _ := startShutdownTestStream(parseT, parseCtx, parseService, parseWsURL)
+	defer parseConn.Close()
+
+	parseShutdownCtx, clearShutdown := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer clearShutdown()
+	parseForceClosed, parseErr := parseServer.Shutdown(parseShutdownCtx)
+	if !errors.Is(parseErr, context.DeadlineExceeded) {
+		parseT.Fatalf("Shutdown() error = %v, want %v", parseErr, context.DeadlineExceeded)
+	}
+	if parseForceClosed != 1 {
+		parseT.Fatalf("Shutdown() force closed = %d, want 1", parseForceClosed)
+	}
+}
+
+// TestServer_ShutdownClosesIdleTunnels verifies idle tunnels are closed without waiting for the deadline.
+func TestServer_ShutdownClosesIdleTunnels(parseT *testing.T) {
+	parseServer, _, parseWsURL := buildShutdownTestServer(parseT)
+
+	parseCtx, clearCtx := context.WithTimeout(context.Background(), 5*time.Second)
+	defer clearCtx()
+	parseConn, parseErr := DialContext(parseCtx, parseWsURL, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if parseErr != nil {
+		parseT.Fatalf("DialContext() error: %v", parseErr)
+	}
+	defer parseConn.Close()
+	if _, parseErr = proto.NewTodoServiceClient(parseConn).ListTodos(parseCtx, &proto.ListTodosRequest{}); parseErr != nil {
+		parseT.Fatalf("ListTodos() error: %v", parseErr)
+	}
+
+	parseStart := time.Now()
+	parseForceClosed, parseErr := parseServer.Shutdown(parseCtx)
+	if parseErr != nil {
+		parseT.Fatalf("Shutdown() error: %v", parseErr)
+	}
+	if parseForceClosed != 0 {
+		parseT.Fatalf("Shutdown() force closed = %d, want 0", parseForceClosed)
+	}
+	if time.Since(parseStart) > 2*time.Second {
+		parseT.Fatalf("Shutdown() took %v, want idle tunnels closed promptly", time.Since(parseStart))
+	}
+}
@@ -0,0 +1,211 @@
+//go:build !js && !wasm
+
+package grpctunnel
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+const parseBridgeSlowClientUpgradeTimeout = "upgrade_timeout"
+const parseBridgeSlowClientPrefaceTimeout = "preface_timeout"
+const parseBridgeSlowClientReadTooSlow = "read_too_slow"
+const parseBridgeSlowClientWriteTimeout = "write_timeout"
+
+// parseDefaultMinReadThroughputWindow is the measurement window used when Throughput.Window is zero.
+const parseDefaultMinReadThroughputWindow = 10 * time.Second
+
+// getBridgeSlowClientError validates the upgrade, preface, read-throughput, and write limits.
+func getBridgeSlowClientError(parseConfig BridgeConfig) error {
+	if parseConfig.UpgradeTimeout < 0 || parseConfig.PrefaceTimeout < 0 || parseConfig.WriteTimeout < 0 {
+		return errors.New("grpctunnel: UpgradeTimeout, PrefaceTimeout, and WriteTimeout must be >= 0")
+	}
+	if parseConfig.MinReadThroughput.BytesPerSecond < 0 || parseConfig.MinReadThroughput.Window < 0 {
+		return errors.New("grpctunnel: MinReadThroughput.BytesPerSecond and Window must be >= 0")
+	}
+	// Bandwidth pacing waits while a message is in flight, so a slower limit would trip read_too_slow.
+	parseMinimum := float64(parseConfig.MinReadThroughput.BytesPerSecond)
+	if parseGlobal := parseConfig.BandwidthLimits.Global.PerSecond; parseMinimum > 0 && parseGlobal > 0 && parseGlobal < parseMinimum {
+		return errors.New("grpctunnel: BandwidthLimits.Global.PerSecond must be >= MinReadThroughput.BytesPerSecond")
+	}
+	if parsePerClient := parseConfig.BandwidthLimits.PerClient.PerSecond; parseMinimum > 0 && parsePerClient > 0 && parsePerClient < parseMinimum {
+		return errors.New("grpctunnel: BandwidthLimits.PerClient.PerSecond must be >= MinReadThroughput.BytesPerSecond")
+	}
+	return nil
+}
+
+// bridgeSlowClientGuard enforces the preface deadline, minimum read throughput, and per-message write
+// deadline for one upgraded tunnel. A nil guard enforces nothing.
+type bridgeSlowClientGuard struct {
+	getPrefaceTimeout    time.Duration
+	getWriteTimeout      time.Duration
+	getMinReadThroughput Throughput
+	getRequest           *http.Request
+	getObservability     *bridgeObservability
+	storeReadBytes       atomic.Int64
+	storeWindowBytes     atomic.Int64
+	storeMessageStart    atomic.Int64 // Unix nanoseconds when the in-flight message began, or zero.
+	getCloseSignal       chan struct{}
+	setCloseOnce         sync.Once
+	storeCloseCause      atomic.Pointer[TunnelCloseCause]
+}
+
+// buildBridgeSlowClientGuard returns a guard for one tunnel, or nil when no per-tunnel limit is configured.
+func buildBridgeSlowClientGuard(parseConfig BridgeConfig, parseRequest *http.Request, parseObservability *bridgeObservability) *bridgeSlowClientGuard {
+	if parseConfig.PrefaceTimeout <= 0 && parseConfig.WriteTimeout <= 0 && parseConfig.MinReadThroughput.BytesPerSecond <= 0 {
+		return nil
+	}
+	parseThroughput := parseConfig.MinReadThroughput
+	if parseThroughput.Window <= 0 {
+		parseThroughput.Window = parseDefaultMinReadThroughputWindow
+	}
+	return &bridgeSlowClientGuard{
+		getPrefaceTimeout:    parseConfig.PrefaceTimeout,
+		getWriteTimeout:      parseConfig.WriteTimeout,
+		getMinReadThroughput: parseThroughput,
+		getRequest:           parseRequest,
+		getObservability:     parseObservability,
+		getCloseSignal:       make(chan struct{}),
+	}
+}
+
+// startBridgeSlowClientGuard starts the preface and read-throughput watchers, which call parseClose when a
+// limit is exceeded.
+func (parseGuard *bridgeSlowClientGuard) startBridgeSlowClientGuard(parseClose func() error) {
+	if parseGuard == nil {
+		return
+	}
+	if parseGuard.getPrefaceTimeout > 0 {
+		go parseGuard.waitBridgePreface(parseClose)
+	}
+	if parseGuard.getMinReadThroughput.BytesPerSecond > 0 {
+		go parseGuard.waitBridgeReadThroughput(parseClose)
+	}
+}
+
+// waitBridgePreface closes the tunnel when the client has not sent the HTTP/2 preface within PrefaceTimeout.
+func (parseGuard *bridgeSlowClientGuard) waitBridgePreface(parseClose func() error) {
+	parseTimer := time.NewTimer(parseGuard.getPrefaceTimeout)
+	defer parseTimer.Stop()
+	select {
+	case <-parseGuard.getCloseSignal:
+		return
+	case <-parseTimer.C:
+	}
+	if parseGuard.storeReadBytes.Load() >= int64(len(http2.ClientPreface)) {
+		return
+	}
+	parseGuard.storeBridgeSlowClientCause(TunnelClosePrefaceTimeout)
+	parseGuard.getObservability.storeBridgeSlowClient(context.Background(), parseBridgeSlowClientPrefaceTimeout)
+	logGrpctunnelEvent("grpctunnel.bridge", "WARN", "tunnel_preface_timeout", parseGuard.getRequest, nil, "Closing tunnel because the HTTP/2 preface did not arrive within PrefaceTimeout")
+	_ = parseClose()
+}
+
+// waitBridgeReadThroughput closes the tunnel when a message has been arriving for a full window at less
+// than MinReadThroughput. Idle tunnels with no message in flight are left to IdleTimeout.
+func (parseGuard *bridgeSlowClientGuard) waitBridgeReadThroughput(parseClose func() error) {
+	parseWindow := parseGuard.getMinReadThroughput.Window
+	parseMinimum := int64(float64(parseGuard.getMinReadThroughput.BytesPerSecond) * parseWindow.Seconds())
+	parseTicker := time.NewTicker(parseWindow)
+	defer parseTicker.Stop()
+	for {
+		select {
+		case <-parseGuard.getCloseSignal:
+			return
+		case parseNow := <-parseTicker.C:
+			parseWindowBytes := parseGuard.storeWindowBytes.Swap(0)
+			parseMessageStart := parseGuard.storeMessageStart.Load()
+			if parseMessageStart == 0 || parseNow.Sub(time.Unix(0, parseMessageStart)) < parseWindow || parseWindowBytes >= parseMinimum {
+				continue
+			}
+			parseGuard.storeBridgeSlowClientCause(TunnelCloseReadTooSlow)
+			parseGuard.getObservability.storeBridgeSlowClient(context.Background(), parseBridgeSlowClientReadTooSlow)
+			logGrpctunnelEvent("grpctunnel.bridge", "WARN", "tunnel_read_too_slow", parseGuard.getRequest, nil, "Closing tunnel because a message arrived slower than MinReadThroughput")
+			_ = parseClose()
+			return
+		}
+	}
+}
+
+// storeBridgeMessageStart marks the start of an inbound websocket message.
+func (parseGuard *bridgeSlowClientGuard) storeBridgeMessageStart() {
+	if parseGuard == nil {
+		return
+	}
+	parseGuard.storeMessageStart.Store(time.Now().UnixNano())
+}
+
+// storeBridgeMessageEnd marks the current inbound websocket message as fully read.
+func (parseGuard *bridgeSlowClientGuard) storeBridgeMessageEnd() {
+	if parseGuard == nil {
+		return
+	}
+	parseGuard.storeMessageStart.Store(0)
+}
+
+// storeBridgeReadBytes counts payload bytes read from the client.
+func (parseGuard *bridgeSlowClientGuard) storeBridgeReadBytes(parseBytes int) {
+	if parseGuard == nil || parseBytes <= 0 {
+		return
+	}
+	parseGuard.storeReadBytes.Add(int64(parseBytes))
+	parseGuard.storeWindowBytes.Add(int64(parseBytes))
+}
+
+// getBridgeWriteDeadline returns the deadline for a message write starting now, or the zero time when
+// WriteTimeout is disabled.
+func (parseGuard *bridgeSlowClientGuard) getBridgeWriteDeadline() time.Time {
+	if parseGuard == nil || parseGuard.getWriteTimeout <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(parseGuard.getWriteTimeout)
+}
+
+// isBridgeWriteTimeout reports whether a write error came from WriteTimeout, recording the disconnect
+// when it did.
+func (parseGuard *bridgeSlowClientGuard) isBridgeWriteTimeout(parseErr error) bool {
+	if parseGuard == nil || parseGuard.getWriteTimeout <= 0 {
+		return false
+	}
+	var parseNetErr net.Error
+	if !errors.As(parseErr, &parseNetErr) || !parseNetErr.Timeout() {
+		return false
+	}
+	parseGuard.storeBridgeSlowClientCause(TunnelCloseWriteTimeout)
+	parseGuard.getObservability.storeBridgeSlowClient(context.Background(), parseBridgeSlowClientWriteTimeout)
+	logGrpctunnelEvent("grpctunnel.bridge", "WARN", "tunnel_write_timeout", parseGuard.getRequest, parseErr, "Closing tunnel because a message write exceeded WriteTimeout")
+	return true
+}
+
+// storeBridgeSlowClientCause records the first limit that closed the tunnel.
+func (parseGuard *bridgeSlowClientGuard) storeBridgeSlowClientCause(parseCause TunnelCloseCause) {
+	parseGuard.storeCloseCause.CompareAndSwap(nil, &parseCause)
+}
+
+// getBridgeSlowClientCause returns the limit that closed the tunnel, or "" when none did.
+func (parseGuard *bridgeSlowClientGuard) getBridgeSlowClientCause() TunnelCloseCause {
+	if parseGuard == nil {
+		return ""
+	}
+	if parseCause := parseGuard.storeCloseCause.Load(); parseCause != nil {
+		return *parseCause
+	}
+	return ""
+}
+
+// clearBridgeSlowClientGuard stops the guard's watchers.
+func (parseGuard *bridgeSlowClientGuard) clearBridgeSlowClientGuard() {
+	if parseGuard == nil {
+		return
+	}
+	parseGuard.setCloseOnce.Do(func() {
+		close(parseGuard.getCloseSignal)
+	})
+}
@@ -0,0 +1,170 @@
+//go:build !js && !wasm
+
+package grpctunnel
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"golang.org/x/net/http2"
+	"google.golang.org/grpc"
+)
+
+// TestGetBridgeConfigError_SlowClient verifies slow-client limit validation.
+func TestGetBridgeConfigError_SlowClient(parseT *testing.T) {
+	for _, parseConfig := range []BridgeConfig{
+		{UpgradeTimeout: -time.Second},
+		{PrefaceTimeout: -time.Second},
+		{WriteTimeout: -time.Second},
+		{MinReadThroughput: Throughput{BytesPerSecond: -1}},
+		{MinReadThroughput: Throughput{BytesPerSecond: 1, Window: -time.Second}},
+		{MinReadThroughput: Throughput{BytesPerSecond: 1000}, BandwidthLimits: BandwidthLimits{Global: RateLimit{PerSecond: 999}}},
+		{MinReadThroughput: Throughput{BytesPerSecond: 1000}, BandwidthLimits: BandwidthLimits{PerClient: RateLimit{PerSecond: 500}}},
+	} {
+		if parseErr := GetBridgeConfigError(parseConfig); parseErr == nil {
+			parseT.Fatalf("GetBridgeConfigError(%+v) = nil, want error", parseConfig)
+		}
+	}
+	parseConfig := BridgeConfig{
+		MinReadThroughput: Throughput{BytesPerSecond: 1000},
+		BandwidthLimits:   BandwidthLimits{Global: RateLimit{PerSecond: 1000}, PerClient: RateLimit{PerSecond: 4000}},
+	}
+	if parseErr := GetBridgeConfigError(parseConfig); parseErr != nil {
+		parseT.Fatalf("GetBridgeConfigError(bandwidth at MinReadThroughput) error: %v", parseErr)
+	}
+}
+
+// TestBuildBridgeHandler_SlowClientLimits verifies the upgrade, preface, and read-throughput limits close
+// slow clients and count each limit separately.
+func TestBuildBridgeHandler_SlowClientLimits(parseT *testing.T) {
+	parseReader := sdkmetric.NewManualReader()
+	parseMeterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(parseReader))
+	parseOriginalMeterProvider := otel.GetMeterProvider()
+	otel.SetMeterProvider(parseMeterProvider)
+	defer otel.SetMeterProvider(parseOriginalMeterProvider)
+	defer func() {
+		_ = parseMeterProvider.Shutdown(context.Background())
+	}()
+
+	parseGrpcServer := grpc.NewServer()
+	defer parseGrpcServer.Stop()
+
+	parseHandler, parseErr := BuildBridgeHandler(parseGrpcServer, BridgeConfig{
+		UpgradeTimeout:    time.Second,
+		PrefaceTimeout:    100 * time.Millisecond,
+		MinReadThroughput: Throughput{BytesPerSecond: 1000, Window: 200 * time.Millisecond},
+		Authenticate: func(parseR *http.Request) (context.Context, error) {
+			if parseR.Header.Get("X-Slow-Auth") != "" {
+				time.Sleep(1100 * time.Millisecond)
+			}
+			return parseR.Context(), nil
+		},
+	})
+	if parseErr != nil {
+		parseT.Fatalf("BuildBridgeHandler() error: %v", parseErr)
+	}
+	parseServer := httptest.NewServer(parseHandler)
+	defer parseServer.Close()
+	parseWsURL := "ws" + strings.TrimPrefix(parseServer.URL, "http")
+
+	_, parseResponse, parseErr := websocket.DefaultDialer.Dial(parseWsURL, http.Header{"X-Slow-Auth": []string{"1"}})
+	if parseErr == nil || parseResponse == nil || parseResponse.StatusCode != http.StatusRequestTimeout {
+		parseT.Fatalf("slow upgrade Dial() = %v, %v; want %d", parseResponse, parseErr, http.StatusRequestTimeout)
+	}
+
+	// A client that never sends the preface is closed after PrefaceTimeout.
+	parseSilent, _, parseErr := websocket.DefaultDialer.Dial(parseWsURL, nil)
+	if parseErr != nil {
+		parseT.Fatalf("Dial() error: %v", parseErr)
+	}
+	defer parseSilent.Close()
+	waitSlowClientTestClosed(parseT, parseSilent)
+
+	// A client that sends the preface and then trickles the rest of one message is closed for missing
+	// MinReadThroughput.
+	parseTrickle, _, parseErr := websocket.DefaultDialer.Dial(parseWsURL, nil)
+	if parseErr != nil {
+		parseT.Fatalf("Dial() error: %v", parseErr)
+	}
+	defer parseTrickle.Close()
+	parseSettingsLength := 600
+	parsePayloadLength := len(http2.ClientPreface) + 9 + parseSettingsLength
+	// A masked binary frame with a zero mask key carries the payload unchanged.
+	parseFrame := []byte{0x82, 0x80 | 126, byte(parsePayloadLength >> 8), byte(parsePayloadLength), 0, 0, 0, 0}
+	parseFrame = append(parseFrame, http2.ClientPreface...)
+	parseFrame = append(parseFrame, 0, byte(parseSettingsLength>>8), byte(parseSettingsLength), byte(http2.FrameSettings), 0, 0, 0, 0, 0)
+	parseRawConn := parseTrickle.UnderlyingConn()
+	if _, parseErr := parseRawConn.Write(parseFrame); parseErr != nil {
+		parseT.Fatalf("Write(frame header) error: %v", parseErr)
+	}
+	parseTrickleDone := make(chan struct{})
+	go func() {
+		defer close(parseTrickleDone)
+		for parseSent := 0; parseSent < parseSettingsLength; parseSent += 6 {
+			if _, parseErr := parseRawConn.Write(make([]byte, 6)); parseErr != nil {
+				return
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}()
+	waitSlowClientTestClosed(parseT, parseTrickle)
+	_ = parseTrickle.Close()
+	<-parseTrickleDone
+
+	parseResourceMetrics := metricdata.ResourceMetrics{}
+	if parseCollectErr := parseReader.Collect(context.Background(), &parseResourceMetrics); parseCollectErr != nil {
+		parseT.Fatalf("Collect() error: %v", parseCollectErr)
+	}
+	for _, parseMetricName := range []string{parseBridgeUpgradeTimeoutsTotalMetric, parseBridgePrefaceTimeoutsTotalMetric, parseBridgeSlowReadsTotalMetric} {
+		if parseValue, isFound := getBridgeInt64SumMetricValue(parseResourceMetrics, parseMetricName); !isFound || parseValue != 1 {
+			parseT.Fatalf("%s = %d (found=%v), want 1", parseMetricName, parseValue, isFound)
+		}
+	}
+}
+
+// waitSlowClientTestClosed reads until the bridge closes the websocket, failing if it stays open.
+func waitSlowClientTestClosed(parseT *testing.T, parseConn *websocket.Conn) {
+	parseT.Helper()
+	_ = parseConn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	for {
+		_, _, parseErr := parseConn.ReadMessage()
+		if parseErr == nil {
+			continue
+		}
+		if parseNetErr, isNetErr := parseErr.(net.Error); isNetErr && parseNetErr.Timeout() {
+			parseT.Fatalf("ReadMessage() error = %v, want bridge to close the tunnel", parseErr)
+		}
+		return
+	}
+}
+
+// TestWebSocketConn_WriteTimeout verifies a peer that stops reading is disconnected after WriteTimeout.
+func TestWebSocketConn_WriteTimeout(parseT *testing.T) {
+	parseRelease := make(chan struct{})
+	parseConn, clearConn := getGrpctunnelTestConn(parseT, func(*websocket.Conn) {
+		<-parseRelease
+	})
+	defer clearConn()
+	defer close(parseRelease)
+	parseConn.slowClient = buildBridgeSlowClientGuard(BridgeConfig{WriteTimeout: 50 * time.Millisecond}, nil, nil)
+
+	parsePayload := make([]byte, 1<<20)
+	for parseIndex := 0; parseIndex < 256; parseIndex++ {
+		if _, parseErr := parseConn.Write(parsePayload); parseErr != nil {
+			if !parseConn.isClosed.Load() {
+				parseT.Fatalf("Write() error = %v, want conn closed after write timeout", parseErr)
+			}
+			return
+		}
+	}
+	parseT.Fatal("Write() never timed out against a peer that stopped reading")
+}
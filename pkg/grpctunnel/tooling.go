@@ -10,9 +10,12 @@ import (
 	"strings"
 	"time"
 
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/tunneladmin"
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/tunneladmin/adminv1"
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/h2c"
 	"google.golang.org/grpc"
+	channelzservice "google.golang.org/grpc/channelz/service"
 	"google.golang.org/grpc/health"
 	grpc_health_v1 "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
@@ -28,10 +31,20 @@ func GetToolingConfigError(parseConfig ToolingConfig) error {
 			return fmt.Errorf("grpctunnel: DebugPathPrefix must end with /")
 		}
 	}
-	return nil
+	if parseConfig.ShouldEnableTunnelAdmin && parseConfig.TunnelAdmin == nil {
+		return fmt.Errorf("grpctunnel: TunnelAdmin is required when ShouldEnableTunnelAdmin is set")
+	}
+	if parseConfig.ShouldEnableTunnelStates && parseConfig.TunnelStates == nil {
+		return fmt.Errorf("grpctunnel: TunnelStates is required when ShouldEnableTunnelStates is set")
+	}
+	return getToolingAuthError(parseConfig.Auth)
 }
 
 // BuildToolingHandler builds an optional direct gRPC tooling handler for grpcurl, grpcui, and pprof.
+// With ShouldEnableTunnelAdmin or ShouldEnableChannelz, it also serves the TunnelAdmin and channelz
+// services from a separate internal gRPC server, so they never become reachable through tunnels
+// serving parseGrpcServer.
+// Auth applies to every route; client certificates are only seen when the handler is served over TLS.
 func BuildToolingHandler(parseGrpcServer *grpc.Server, parseConfig ToolingConfig) (http.Handler, *health.Server, error) {
 	if parseGrpcServer == nil {
 		return nil, nil, fmt.Errorf("grpctunnel: grpc server is required")
@@ -46,8 +59,14 @@ func BuildToolingHandler(parseGrpcServer *grpc.Server, parseConfig ToolingConfig
 	if parseConfig.ShouldEnablePprof {
 		registerToolingPprofHandlers(parseMux, parseConfig)
 	}
-	parseMux.Handle("/", h2c.NewHandler(parseGrpcServer, &http2.Server{}))
-	return parseMux, parseHealthServer, nil
+	if parseConfig.ShouldEnableTunnelStates {
+		parseMux.Handle(getToolingDebugPathPrefix(parseConfig)+"tunnels", parseConfig.TunnelStates)
+	}
+	parseMux.Handle("/", buildToolingInternalHandler(parseGrpcServer, parseConfig))
+	// h2c wraps the mux, not just the gRPC route, so prior-knowledge HTTP/2 clients such as grpc-go
+	// and grpcurl -plaintext can open connections; their "PRI *" preface never matches a mux route.
+	// Auth sits inside h2c so that it checks each request rather than the connection preface.
+	return h2c.NewHandler(buildToolingAuthHandler(parseMux, parseConfig.Auth), &http2.Server{}), parseHealthServer, nil
 }
 
 // warnToolingExposure logs security-sensitive tooling exposure warnings.
@@ -59,13 +78,53 @@ func warnToolingExposure(parseConfig ToolingConfig) {
 	if parseConfig.ShouldEnablePprof {
 		logGrpctunnelEvent("grpctunnel.tooling", "WARN", "tooling_pprof_enabled", nil, nil, "pprof is enabled on tooling handler")
 	}
+	if parseConfig.ShouldEnableChannelz {
+		logGrpctunnelEvent("grpctunnel.tooling", "WARN", "tooling_channelz_enabled", nil, nil, "gRPC channelz is enabled on tooling handler")
+	}
+	if parseConfig.ShouldEnableTunnelStates {
+		logGrpctunnelEvent("grpctunnel.tooling", "WARN", "tooling_tunnel_states_enabled", nil, nil, "Tunnel state snapshot is enabled on tooling handler")
+	}
+	if parseConfig.ShouldEnableTunnelAdmin {
+		logGrpctunnelEvent("grpctunnel.tooling", "WARN", "tooling_tunnel_admin_enabled", nil, nil, "TunnelAdmin service is enabled on tooling handler and can close tunnels and change limits")
+	}
+}
+
+// buildToolingInternalHandler routes TunnelAdmin and channelz calls to an internal gRPC server and
+// every other call to parseGrpcServer.
+func buildToolingInternalHandler(parseGrpcServer *grpc.Server, parseConfig ToolingConfig) http.Handler {
+	if !parseConfig.ShouldEnableTunnelAdmin && !parseConfig.ShouldEnableChannelz {
+		return parseGrpcServer
+	}
+	parseInternalServer := grpc.NewServer()
+	var parsePathPrefixes []string
+	if parseConfig.ShouldEnableTunnelAdmin {
+		tunneladmin.Register(parseInternalServer, parseConfig.TunnelAdmin)
+		parsePathPrefixes = append(parsePathPrefixes, "/"+adminv1.TunnelAdmin_ServiceDesc.ServiceName+"/")
+	}
+	if parseConfig.ShouldEnableChannelz {
+		channelzservice.RegisterChannelzServiceToServer(parseInternalServer)
+		parsePathPrefixes = append(parsePathPrefixes, "/grpc.channelz.v1.Channelz/")
+	}
+	return http.HandlerFunc(func(parseW http.ResponseWriter, parseR *http.Request) {
+		for _, parsePathPrefix := range parsePathPrefixes {
+			if strings.HasPrefix(parseR.URL.Path, parsePathPrefix) {
+				parseInternalServer.ServeHTTP(parseW, parseR)
+				return
+			}
+		}
+		parseGrpcServer.ServeHTTP(parseW, parseR)
+	})
 }
 
 // ListenAndServeTooling starts an additive direct gRPC tooling server on a separate address.
+// With TLSConfig it serves TLS, and gRPC clients connect over h2.
 func ListenAndServeTooling(parseAddr string, parseGrpcServer *grpc.Server, parseConfig ToolingConfig) error {
 	if parseErr := getToolingListenAddressError(parseAddr, parseConfig); parseErr != nil {
 		return parseErr
 	}
+	if parseErr := getToolingTLSConfigError(parseConfig); parseErr != nil {
+		return parseErr
+	}
 	if shouldWarnToolingNonLoopbackBind(parseAddr, parseConfig) {
 		logGrpctunnelEvent(
 			"grpctunnel.tooling",
@@ -74,7 +133,7 @@ func ListenAndServeTooling(parseAddr string, parseGrpcServer *grpc.Server, parse
 			nil,
 			nil,
 			fmt.Sprintf(
-				"Tooling server with reflection/pprof is bound to non-loopback address %q; restrict access with trusted network boundaries and auth controls",
+				"Tooling server with introspection or tunnel admin features is bound to non-loopback address %q; restrict access with trusted network boundaries and auth controls",
 				parseAddr,
 			),
 		)
@@ -92,7 +151,14 @@ func ListenAndServeTooling(parseAddr string, parseGrpcServer *grpc.Server, parse
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
-	return parseServer.ListenAndServe()
+	if parseConfig.TLSConfig == nil {
+		return parseServer.ListenAndServe()
+	}
+	parseServer.TLSConfig = parseConfig.TLSConfig.Clone()
+	if len(parseServer.TLSConfig.NextProtos) == 0 {
+		parseServer.TLSConfig.NextProtos = []string{"h2", "http/1.1"}
+	}
+	return parseServer.ListenAndServeTLS("", "")
 }
 
 // ensureToolingServices registers optional reflection and health services when absent.
@@ -103,8 +169,11 @@ func ensureToolingServices(parseGrpcServer *grpc.Server, parseConfig ToolingConf
 	}
 
 	if parseConfig.ShouldEnableHealthService && !hasToolingService(parseServiceInfo, grpc_health_v1.Health_ServiceDesc.ServiceName) {
-		parseHealthServer := health.NewServer()
-		parseHealthServer.SetServingStatus("", grpc_health_v1.HealthCheckResponse_SERVING)
+		parseHealthServer := parseConfig.HealthMonitor.HealthServer()
+		if parseHealthServer == nil {
+			parseHealthServer = health.NewServer()
+			parseHealthServer.SetServingStatus("", grpc_health_v1.HealthCheckResponse_SERVING)
+		}
 		grpc_health_v1.RegisterHealthServer(parseGrpcServer, parseHealthServer)
 		return parseHealthServer
 	}
@@ -118,12 +187,17 @@ func hasToolingService(parseServices map[string]grpc.ServiceInfo, parseServiceNa
 	return hasService
 }
 
+// getToolingDebugPathPrefix returns the configured debug route prefix.
+func getToolingDebugPathPrefix(parseConfig ToolingConfig) string {
+	if parseConfig.DebugPathPrefix == "" {
+		return "/debug/pprof/"
+	}
+	return parseConfig.DebugPathPrefix
+}
+
 // registerToolingPprofHandlers mounts pprof handlers under the configured path prefix.
 func registerToolingPprofHandlers(parseMux *http.ServeMux, parseConfig ToolingConfig) {
-	parseDebugPathPrefix := parseConfig.DebugPathPrefix
-	if parseDebugPathPrefix == "" {
-		parseDebugPathPrefix = "/debug/pprof/"
-	}
+	parseDebugPathPrefix := getToolingDebugPathPrefix(parseConfig)
 
 	parseDebugPathBase := strings.TrimSuffix(parseDebugPathPrefix, "/")
 	parseMux.Handle(parseDebugPathPrefix, http.HandlerFunc(pprof.Index))
@@ -133,16 +207,29 @@ func registerToolingPprofHandlers(parseMux *http.ServeMux, parseConfig ToolingCo
 	parseMux.Handle(parseDebugPathBase+"/trace", http.HandlerFunc(pprof.Trace))
 }
 
-// getToolingListenAddressError blocks wildcard tooling binds when reflection/pprof are enabled.
+// isToolingSensitive reports whether the tooling handler exposes introspection or tunnel admin.
+func isToolingSensitive(parseConfig ToolingConfig) bool {
+	return parseConfig.ShouldEnableReflection || parseConfig.ShouldEnablePprof || parseConfig.ShouldEnableTunnelAdmin ||
+		parseConfig.ShouldEnableChannelz || parseConfig.ShouldEnableTunnelStates
+}
+
+// getToolingListenAddressError blocks wildcard tooling binds when introspection or tunnel admin features
+// are enabled without Auth, and non-loopback binds that would receive Auth credentials without TLS.
 func getToolingListenAddressError(parseAddr string, parseConfig ToolingConfig) error {
-	if !parseConfig.ShouldEnableReflection && !parseConfig.ShouldEnablePprof {
+	if isToolingAuthEnabled(parseConfig.Auth) {
+		if parseConfig.TLSConfig == nil && !isToolingLoopbackBind(parseAddr) {
+			return fmt.Errorf("grpctunnel: tooling listen address %q with Auth requires TLSConfig; credentials would otherwise be sent in plaintext", parseAddr)
+		}
+		return nil
+	}
+	if !isToolingSensitive(parseConfig) {
 		return nil
 	}
 	if !shouldWarnToolingWildcardBind(parseAddr) {
 		return nil
 	}
 	return fmt.Errorf(
-		"grpctunnel: refusing tooling listen address %q with reflection/pprof enabled; bind to loopback (127.0.0.1 or ::1) or disable introspection features",
+		"grpctunnel: refusing tooling listen address %q with introspection or tunnel admin features enabled; bind to loopback (127.0.0.1 or ::1), configure Auth, or disable introspection features",
 		parseAddr,
 	)
 }
@@ -162,14 +249,24 @@ func shouldWarnToolingWildcardBind(parseAddr string) bool {
 	return parseHost == "" || parseHost == "0.0.0.0" || parseHost == "::" || parseHost == "[::]"
 }
 
-// shouldWarnToolingNonLoopbackBind reports whether a tooling listen address is non-loopback.
+// shouldWarnToolingNonLoopbackBind reports whether a tooling listen address without Auth is non-loopback.
 func shouldWarnToolingNonLoopbackBind(parseAddr string, parseConfig ToolingConfig) bool {
-	if !parseConfig.ShouldEnableReflection && !parseConfig.ShouldEnablePprof {
+	if !isToolingSensitive(parseConfig) || isToolingAuthEnabled(parseConfig.Auth) {
 		return false
 	}
 	if shouldWarnToolingWildcardBind(parseAddr) {
 		return false
 	}
+	return isToolingNonLoopbackHost(parseAddr)
+}
+
+// isToolingLoopbackBind reports whether a listen address only accepts loopback connections.
+func isToolingLoopbackBind(parseAddr string) bool {
+	return !shouldWarnToolingWildcardBind(parseAddr) && !isToolingNonLoopbackHost(parseAddr)
+}
+
+// isToolingNonLoopbackHost reports whether a listen address names a host other than loopback.
+func isToolingNonLoopbackHost(parseAddr string) bool {
 
 	parseHost, _, parseErr := net.SplitHostPort(strings.TrimSpace(parseAddr))
 	if parseErr != nil {
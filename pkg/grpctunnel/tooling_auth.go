@@ -0,0 +1,182 @@
+//go:build !js && !wasm
+
+package grpctunnel
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const parseToolingAuthRealm = "grpctunnel tooling"
+const parseToolingAuthDeniedMessage = "tooling authentication required"
+
+// toolingAuthPolicy checks tooling requests against a validated ToolingAuth.
+type toolingAuthPolicy struct {
+	getTokenDigests [][sha256.Size]byte
+	getBasicUsers   map[string][]byte
+	// getDecoyHash is compared for unknown basic auth users so that they take as long to reject as
+	// wrong passwords.
+	getDecoyHash []byte
+	getCertSANs  map[string]struct{}
+	getChallenge string
+}
+
+// isToolingAuthEnabled reports whether parseAuth configures any method.
+func isToolingAuthEnabled(parseAuth ToolingAuth) bool {
+	return len(parseAuth.BearerTokens) > 0 || len(parseAuth.BasicUsers) > 0 || len(parseAuth.ClientCertSANs) > 0
+}
+
+// getToolingAuthError validates a tooling auth policy.
+func getToolingAuthError(parseAuth ToolingAuth) error {
+	for _, parseToken := range parseAuth.BearerTokens {
+		if parseToken == "" {
+			return fmt.Errorf("grpctunnel: Auth.BearerTokens must not contain empty tokens")
+		}
+	}
+	for parseUser, parseHash := range parseAuth.BasicUsers {
+		if parseUser == "" || strings.Contains(parseUser, ":") {
+			return fmt.Errorf("grpctunnel: Auth.BasicUsers has invalid user name %q", parseUser)
+		}
+		if _, parseErr := bcrypt.Cost([]byte(parseHash)); parseErr != nil {
+			return fmt.Errorf("grpctunnel: Auth.BasicUsers[%q] must be a bcrypt hash: %w", parseUser, parseErr)
+		}
+	}
+	for _, parseSAN := range parseAuth.ClientCertSANs {
+		if strings.TrimSpace(parseSAN) == "" {
+			return fmt.Errorf("grpctunnel: Auth.ClientCertSANs must not contain empty names")
+		}
+	}
+	return nil
+}
+
+// getToolingTLSConfigError checks that a TLS config verifies the client certificates Auth matches.
+func getToolingTLSConfigError(parseConfig ToolingConfig) error {
+	if len(parseConfig.Auth.ClientCertSANs) == 0 {
+		return nil
+	}
+	if parseConfig.TLSConfig == nil {
+		return fmt.Errorf("grpctunnel: Auth.ClientCertSANs requires TLSConfig")
+	}
+	if parseConfig.TLSConfig.ClientAuth != tls.VerifyClientCertIfGiven && parseConfig.TLSConfig.ClientAuth != tls.RequireAndVerifyClientCert {
+		return fmt.Errorf("grpctunnel: Auth.ClientCertSANs requires TLSConfig.ClientAuth to verify client certificates")
+	}
+	return nil
+}
+
+// buildToolingAuthPolicy prepares a validated ToolingAuth for request checks.
+func buildToolingAuthPolicy(parseAuth ToolingAuth) *toolingAuthPolicy {
+	parsePolicy := &toolingAuthPolicy{
+		getBasicUsers: make(map[string][]byte, len(parseAuth.BasicUsers)),
+		getCertSANs:   make(map[string]struct{}, len(parseAuth.ClientCertSANs)),
+		getChallenge:  "Bearer realm=\"" + parseToolingAuthRealm + "\"",
+	}
+	for _, parseToken := range parseAuth.BearerTokens {
+		parsePolicy.getTokenDigests = append(parsePolicy.getTokenDigests, sha256.Sum256([]byte(parseToken)))
+	}
+	for parseUser, parseHash := range parseAuth.BasicUsers {
+		parsePolicy.getBasicUsers[parseUser] = []byte(parseHash)
+		parsePolicy.getDecoyHash = []byte(parseHash)
+	}
+	if len(parseAuth.BasicUsers) > 0 {
+		parsePolicy.getChallenge = "Basic realm=\"" + parseToolingAuthRealm + "\", charset=\"UTF-8\""
+	}
+	for _, parseSAN := range parseAuth.ClientCertSANs {
+		parsePolicy.getCertSANs[strings.ToLower(strings.TrimSpace(parseSAN))] = struct{}{}
+	}
+	return parsePolicy
+}
+
+// buildToolingAuthHandler rejects requests that fail parseAuth before they reach parseNext.
+func buildToolingAuthHandler(parseNext http.Handler, parseAuth ToolingAuth) http.Handler {
+	if !isToolingAuthEnabled(parseAuth) {
+		return parseNext
+	}
+	parsePolicy := buildToolingAuthPolicy(parseAuth)
+	return http.HandlerFunc(func(parseW http.ResponseWriter, parseR *http.Request) {
+		if parsePolicy.isToolingRequestAllowed(parseR) {
+			parseNext.ServeHTTP(parseW, parseR)
+			return
+		}
+		logGrpctunnelEvent("grpctunnel.tooling", "WARN", "tooling_auth_rejected", parseR, nil, "Tooling request rejected by auth policy")
+		if parseR.ProtoMajor == 2 && strings.HasPrefix(parseR.Header.Get("Content-Type"), "application/grpc") {
+			writeBridgeRPCStatus(parseW, status.New(codes.Unauthenticated, parseToolingAuthDeniedMessage))
+			return
+		}
+		parseW.Header().Set("WWW-Authenticate", parsePolicy.getChallenge)
+		http.Error(parseW, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	})
+}
+
+// isToolingRequestAllowed reports whether a request passes any configured method.
+func (parsePolicy *toolingAuthPolicy) isToolingRequestAllowed(parseR *http.Request) bool {
+	if parsePolicy.isToolingCertAllowed(parseR) {
+		return true
+	}
+	parseAuthorization := parseR.Header.Get("Authorization")
+	if parseAuthorization == "" {
+		return false
+	}
+	if parseScheme, parseToken, isFound := strings.Cut(parseAuthorization, " "); isFound && strings.EqualFold(parseScheme, "Bearer") {
+		return parsePolicy.isToolingTokenAllowed(strings.TrimSpace(parseToken))
+	}
+	if parseUser, parsePassword, isBasic := parseR.BasicAuth(); isBasic {
+		return parsePolicy.isToolingBasicAllowed(parseUser, parsePassword)
+	}
+	return false
+}
+
+// isToolingTokenAllowed compares a bearer token against every configured token in constant time.
+// Comparing digests keeps the comparison independent of token lengths.
+func (parsePolicy *toolingAuthPolicy) isToolingTokenAllowed(parseToken string) bool {
+	parseDigest := sha256.Sum256([]byte(parseToken))
+	isAllowed := 0
+	for _, parseTokenDigest := range parsePolicy.getTokenDigests {
+		isAllowed |= subtle.ConstantTimeCompare(parseDigest[:], parseTokenDigest[:])
+	}
+	return isAllowed == 1
+}
+
+// isToolingBasicAllowed checks HTTP basic credentials against the bcrypt hashes.
+func (parsePolicy *toolingAuthPolicy) isToolingBasicAllowed(parseUser string, parsePassword string) bool {
+	if parsePolicy.getDecoyHash == nil {
+		return false
+	}
+	parseHash, isKnownUser := parsePolicy.getBasicUsers[parseUser]
+	if !isKnownUser {
+		_ = bcrypt.CompareHashAndPassword(parsePolicy.getDecoyHash, []byte(parsePassword))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword(parseHash, []byte(parsePassword)) == nil
+}
+
+// isToolingCertAllowed reports whether the request carries a verified client certificate with a
+// required subject alternative name.
+func (parsePolicy *toolingAuthPolicy) isToolingCertAllowed(parseR *http.Request) bool {
+	if len(parsePolicy.getCertSANs) == 0 || parseR.TLS == nil || len(parseR.TLS.VerifiedChains) == 0 || len(parseR.TLS.VerifiedChains[0]) == 0 {
+		return false
+	}
+	parseLeaf := parseR.TLS.VerifiedChains[0][0]
+	parseNames := make([]string, 0, len(parseLeaf.DNSNames)+len(parseLeaf.IPAddresses)+len(parseLeaf.EmailAddresses)+len(parseLeaf.URIs))
+	parseNames = append(parseNames, parseLeaf.DNSNames...)
+	parseNames = append(parseNames, parseLeaf.EmailAddresses...)
+	for _, parseIP := range parseLeaf.IPAddresses {
+		parseNames = append(parseNames, parseIP.String())
+	}
+	for _, parseURI := range parseLeaf.URIs {
+		parseNames = append(parseNames, parseURI.String())
+	}
+	for _, parseName := range parseNames {
+		if _, isRequired := parsePolicy.getCertSANs[strings.ToLower(parseName)]; isRequired {
+			return true
+		}
+	}
+	return false
+}
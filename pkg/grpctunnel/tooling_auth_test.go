@@ -0,0 +1,243 @@
+//go:build !js && !wasm
+
+package grpctunnel
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc"
+	channelzpb "google.golang.org/grpc/channelz/grpc_channelz_v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	grpc_health_v1 "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// buildToolingAuthTestHandler builds a tooling handler with pprof, health, and channelz behind parseAuth.
+func buildToolingAuthTestHandler(parseT *testing.T, parseAuth ToolingAuth) http.Handler {
+	parseT.Helper()
+	parseGrpcServer := grpc.NewServer()
+	parseT.Cleanup(parseGrpcServer.Stop)
+	parseHandler, _, parseErr := BuildToolingHandler(parseGrpcServer, ToolingConfig{
+		ShouldEnablePprof:         true,
+		ShouldEnableHealthService: true,
+		ShouldEnableChannelz:      true,
+		Auth:                      parseAuth,
+	})
+	if parseErr != nil {
+		parseT.Fatalf("BuildToolingHandler() error: %v", parseErr)
+	}
+	return parseHandler
+}
+
+func TestBuildToolingHandler_AuthBearerAndBasic(parseT *testing.T) {
+	parseHash, parseErr := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	if parseErr != nil {
+		parseT.Fatalf("GenerateFromPassword() error: %v", parseErr)
+	}
+	parseHandler := buildToolingAuthTestHandler(parseT, ToolingAuth{
+		BearerTokens: []string{"token-a", "token-b"},
+		BasicUsers:   map[string]string{"ops": string(parseHash)},
+	})
+
+	parseTests := []struct {
+		parseName       string
+		applyCredential func(*http.Request)
+		parseWantCode   int
+	}{
+		{parseName: "none", applyCredential: func(*http.Request) {}, parseWantCode: http.StatusUnauthorized},
+		{parseName: "bearer", applyCredential: func(parseR *http.Request) { parseR.Header.Set("Authorization", "Bearer token-b") }, parseWantCode: http.StatusOK},
+		{parseName: "wrong bearer", applyCredential: func(parseR *http.Request) { parseR.Header.Set("Authorization", "Bearer token-c") }, parseWantCode: http.StatusUnauthorized},
+		{parseName: "basic", applyCredential: func(parseR *http.Request) { parseR.SetBasicAuth("ops", "s3cret") }, parseWantCode: http.StatusOK},
+		{parseName: "wrong password", applyCredential: func(parseR *http.Request) { parseR.SetBasicAuth("ops", "guess") }, parseWantCode: http.StatusUnauthorized},
+		{parseName: "unknown user", applyCredential: func(parseR *http.Request) { parseR.SetBasicAuth("root", "s3cret") }, parseWantCode: http.StatusUnauthorized},
+	}
+	for _, parseTestCase := range parseTests {
+		parseT.Run(parseTestCase.parseName, func(parseT2 *testing.T) {
+			parseRequest := httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil)
+			parseTestCase.applyCredential(parseRequest)
+			parseRecorder := httptest.NewRecorder()
+			parseHandler.ServeHTTP(parseRecorder, parseRequest)
+			if parseRecorder.Code != parseTestCase.parseWantCode {
+				parseT2.Fatalf("status = %d, want %d", parseRecorder.Code, parseTestCase.parseWantCode)
+			}
+			if parseRecorder.Code == http.StatusUnauthorized && !strings.HasPrefix(parseRecorder.Header().Get("WWW-Authenticate"), "Basic ") {
+				parseT2.Fatalf("WWW-Authenticate = %q, want a Basic challenge", parseRecorder.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestBuildToolingHandler_AuthGRPCOverH2C(parseT *testing.T) {
+	parseServer := httptest.NewServer(buildToolingAuthTestHandler(parseT, ToolingAuth{BearerTokens: []string{"token-a"}}))
+	defer parseServer.Close()
+
+	parseConn, parseErr := grpc.NewClient(strings.TrimPrefix(parseServer.URL, "http://"), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if parseErr != nil {
+		parseT.Fatalf("NewClient() error: %v", parseErr)
+	}
+	defer parseConn.Close()
+	parseClient := grpc_health_v1.NewHealthClient(parseConn)
+	parseCtx, clearCtx := context.WithTimeout(context.Background(), 5*time.Second)
+	defer clearCtx()
+
+	if _, parseErr := parseClient.Check(parseCtx, &grpc_health_v1.HealthCheckRequest{}); status.Code(parseErr) != codes.Unauthenticated {
+		parseT.Fatalf("Check() without token error = %v, want Unauthenticated", parseErr)
+	}
+	parseAuthCtx := metadata.AppendToOutgoingContext(parseCtx, "authorization", "Bearer token-a")
+	if parseResponse, parseErr := parseClient.Check(parseAuthCtx, &grpc_health_v1.HealthCheckRequest{}); parseErr != nil || parseResponse.GetStatus() != grpc_health_v1.HealthCheckResponse_SERVING {
+		parseT.Fatalf("Check() with token = %v, %v, want SERVING", parseResponse, parseErr)
+	}
+
+	// channelz is served by the internal tooling server, which sits behind the same Auth.
+	parseChannelzClient := channelzpb.NewChannelzClient(parseConn)
+	if _, parseErr := parseChannelzClient.GetServers(parseCtx, &channelzpb.GetServersRequest{}); status.Code(parseErr) != codes.Unauthenticated {
+		parseT.Fatalf("GetServers() without token error = %v, want Unauthenticated", parseErr)
+	}
+	if _, parseErr := parseChannelzClient.GetServers(parseAuthCtx, &channelzpb.GetServersRequest{}); parseErr != nil {
+		parseT.Fatalf("GetServers() with token error: %v", parseErr)
+	}
+}
+
+func TestBuildToolingHandler_AuthClientCertSANs(parseT *testing.T) {
+	parseNotBefore := time.Now().Add(-time.Hour)
+	parseNotAfter := time.Now().Add(time.Hour)
+	parseCACert, parseCAKey := buildToolingTestCertificate(parseT, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "tooling test ca"},
+		NotBefore:             parseNotBefore,
+		NotAfter:              parseNotAfter,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil, nil)
+	buildClientCert := func(parseSerial int64, parseURI string) tls.Certificate {
+		parseURL, parseErr := url.Parse(parseURI)
+		if parseErr != nil {
+			parseT.Fatalf("url.Parse() error: %v", parseErr)
+		}
+		parseCert, parseKey := buildToolingTestCertificate(parseT, &x509.Certificate{
+			SerialNumber: big.NewInt(parseSerial),
+			Subject:      pkix.Name{CommonName: parseURI},
+			URIs:         []*url.URL{parseURL},
+			NotBefore:    parseNotBefore,
+			NotAfter:     parseNotAfter,
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}, parseCACert, parseCAKey)
+		return tls.Certificate{Certificate: [][]byte{parseCert.Raw}, PrivateKey: parseKey}
+	}
+	parseCAPool := x509.NewCertPool()
+	parseCAPool.AddCert(parseCACert)
+
+	parseServer := httptest.NewUnstartedServer(buildToolingAuthTestHandler(parseT, ToolingAuth{ClientCertSANs: []string{"spiffe://example.test/ops"}}))
+	parseServer.TLS = &tls.Config{ClientAuth: tls.VerifyClientCertIfGiven, ClientCAs: parseCAPool}
+	parseServer.StartTLS()
+	defer parseServer.Close()
+
+	for _, parseTestCase := range []struct {
+		parseName     string
+		parseCerts    []tls.Certificate
+		parseWantCode int
+	}{
+		{parseName: "no certificate", parseWantCode: http.StatusUnauthorized},
+		{parseName: "required SAN", parseCerts: []tls.Certificate{buildClientCert(2, "spiffe://example.test/ops")}, parseWantCode: http.StatusOK},
+		{parseName: "other SAN", parseCerts: []tls.Certificate{buildClientCert(3, "spiffe://example.test/guest")}, parseWantCode: http.StatusUnauthorized},
+	} {
+		parseT.Run(parseTestCase.parseName, func(parseT2 *testing.T) {
+			parseClient := parseServer.Client()
+			parseTransport := parseClient.Transport.(*http.Transport).Clone()
+			parseTransport.TLSClientConfig.Certificates = parseTestCase.parseCerts
+			parseClient.Transport = parseTransport
+			parseResponse, parseErr := parseClient.Get(parseServer.URL + "/debug/pprof/")
+			if parseErr != nil {
+				parseT2.Fatalf("Get() error: %v", parseErr)
+			}
+			parseResponse.Body.Close()
+			if parseResponse.StatusCode != parseTestCase.parseWantCode {
+				parseT2.Fatalf("status = %d, want %d", parseResponse.StatusCode, parseTestCase.parseWantCode)
+			}
+		})
+	}
+}
+
+// buildToolingTestCertificate issues a certificate signed by the parent, or self-signed when parent is nil.
+func buildToolingTestCertificate(parseT *testing.T, parseTemplate *x509.Certificate, parseParent *x509.Certificate, parseParentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	parseT.Helper()
+	parseKey, parseErr := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if parseErr != nil {
+		parseT.Fatalf("GenerateKey() error: %v", parseErr)
+	}
+	if parseParent == nil {
+		parseParent = parseTemplate
+		parseParentKey = parseKey
+	}
+	parseDER, parseErr := x509.CreateCertificate(rand.Reader, parseTemplate, parseParent, &parseKey.PublicKey, parseParentKey)
+	if parseErr != nil {
+		parseT.Fatalf("CreateCertificate() error: %v", parseErr)
+	}
+	parseCert, parseErr := x509.ParseCertificate(parseDER)
+	if parseErr != nil {
+		parseT.Fatalf("ParseCertificate() error: %v", parseErr)
+	}
+	return parseCert, parseKey
+}
+
+func TestGetToolingConfigError_Auth(parseT *testing.T) {
+	for _, parseAuth := range []ToolingAuth{
+		{BearerTokens: []string{""}},
+		{BasicUsers: map[string]string{"ops": "plaintext"}},
+		{BasicUsers: map[string]string{"a:b": "$2a$04$abcdefghijklmnopqrstuuabcdefghijklmnopqrstuvwxyz01234"}},
+		{ClientCertSANs: []string{" "}},
+	} {
+		if parseErr := GetToolingConfigError(ToolingConfig{Auth: parseAuth}); parseErr == nil {
+			parseT.Fatalf("GetToolingConfigError(%+v) expected error, got nil", parseAuth)
+		}
+	}
+}
+
+func TestListenAndServeTooling_AuthBindChecks(parseT *testing.T) {
+	parseConfig := ToolingConfig{ShouldEnablePprof: true, Auth: ToolingAuth{BearerTokens: []string{"token-a"}}}
+	for _, parseAddr := range []string{":9090", "10.0.0.5:9090"} {
+		if parseErr := getToolingListenAddressError(parseAddr, parseConfig); parseErr == nil || !strings.Contains(parseErr.Error(), "TLSConfig") {
+			parseT.Fatalf("getToolingListenAddressError(%q) with Auth and no TLSConfig error = %v, want TLSConfig error", parseAddr, parseErr)
+		}
+	}
+	if parseErr := getToolingListenAddressError("127.0.0.1:9090", parseConfig); parseErr != nil {
+		parseT.Fatalf("getToolingListenAddressError(loopback) with Auth error: %v, want allowed", parseErr)
+	}
+	parseTLSConfig := parseConfig
+	parseTLSConfig.TLSConfig = &tls.Config{}
+	if parseErr := getToolingListenAddressError(":9090", parseTLSConfig); parseErr != nil {
+		parseT.Fatalf("getToolingListenAddressError() with Auth and TLSConfig error: %v, want wildcard bind allowed", parseErr)
+	}
+	if shouldWarnToolingNonLoopbackBind("10.0.0.5:9090", parseConfig) {
+		parseT.Fatal("shouldWarnToolingNonLoopbackBind() with Auth = true, want false")
+	}
+
+	parseConfig.Auth = ToolingAuth{ClientCertSANs: []string{"ops.example.test"}}
+	parseErr := ListenAndServeTooling("127.0.0.1:0", grpc.NewServer(), parseConfig)
+	if parseErr == nil || !strings.Contains(parseErr.Error(), "TLSConfig") {
+		parseT.Fatalf("ListenAndServeTooling() without TLSConfig error = %v, want TLSConfig error", parseErr)
+	}
+	parseConfig.TLSConfig = &tls.Config{}
+	parseErr = ListenAndServeTooling("127.0.0.1:0", grpc.NewServer(), parseConfig)
+	if parseErr == nil || !strings.Contains(parseErr.Error(), "ClientAuth") {
+		parseT.Fatalf("ListenAndServeTooling() without client verification error = %v, want ClientAuth error", parseErr)
+	}
+}
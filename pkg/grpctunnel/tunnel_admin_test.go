@@ -0,0 +1,186 @@
+//go:build !js && !wasm
+
+package grpctunnel
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/monstercameron/grpc-tunnel/examples/_shared/proto"
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/tunneladmin"
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/tunneladmin/adminv1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// TestBuildToolingHandler_TunnelAdmin verifies the TunnelAdmin service lists, streams, and closes
+// tunnels and changes limits for later upgrades, reached by a stock gRPC client over h2c.
+func TestBuildToolingHandler_TunnelAdmin(parseT *testing.T) {
+	parseGrpcServer := grpc.NewServer()
+	proto.RegisterTodoServiceServer(parseGrpcServer, &mockService{})
+	defer parseGrpcServer.Stop()
+
+	parseAdmin := tunneladmin.New(nil)
+	parseTunnelServer := httptest.NewServer(Wrap(parseGrpcServer, WithTunnelAdmin(parseAdmin)))
+	defer parseTunnelServer.Close()
+	parseToolingHandler, _, parseErr := BuildToolingHandler(grpc.NewServer(), ToolingConfig{
+		ShouldEnableTunnelAdmin: true,
+		TunnelAdmin:             parseAdmin,
+	})
+	if parseErr != nil {
+		parseT.Fatalf("BuildToolingHandler() error: %v", parseErr)
+	}
+	parseToolingServer := httptest.NewServer(parseToolingHandler)
+	defer parseToolingServer.Close()
+
+	parseCtx, clearCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer clearCtx()
+	parseAdminConn, parseErr := grpc.NewClient(
+		strings.TrimPrefix(parseToolingServer.URL, "http://"),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if parseErr != nil {
+		parseT.Fatalf("NewClient() error: %v", parseErr)
+	}
+	defer parseAdminConn.Close()
+	parseAdminClient := adminv1.NewTunnelAdminClient(parseAdminConn)
+
+	parseEvents, parseErr := parseAdminClient.StreamTunnelEvents(parseCtx, &adminv1.StreamTunnelEventsRequest{})
+	if parseErr != nil {
+		parseT.Fatalf("StreamTunnelEvents() error: %v", parseErr)
+	}
+	if _, parseErr := parseEvents.Header(); parseErr != nil {
+		parseT.Fatalf("StreamTunnelEvents() header error: %v", parseErr)
+	}
+
+	parseDialTunnel := func() (*grpc.ClientConn, error) {
+		return BuildTunnelConn(parseCtx, TunnelConfig{
+			Target:      "ws" + strings.TrimPrefix(parseTunnelServer.URL, "http"),
+			Headers:     http.Header{"Origin": []string{parseTunnelServer.URL}},
+			GRPCOptions: []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())},
+		})
+	}
+	parseConn, parseErr := parseDialTunnel()
+	if parseErr != nil {
+		parseT.Fatalf("BuildTunnelConn() error: %v", parseErr)
+	}
+	defer parseConn.Close()
+	if _, parseErr := proto.NewTodoServiceClient(parseConn).CreateTodo(parseCtx, &proto.CreateTodoRequest{Text: "listed"}); parseErr != nil {
+		parseT.Fatalf("CreateTodo() error: %v", parseErr)
+	}
+
+	parseList, parseErr := parseAdminClient.ListTunnels(parseCtx, &adminv1.ListTunnelsRequest{Component: "grpctunnel.bridge"})
+	if parseErr != nil {
+		parseT.Fatalf("ListTunnels() error: %v", parseErr)
+	}
+	if len(parseList.GetTunnels()) != 1 || parseList.GetTunnels()[0].GetBytesIn() == 0 {
+		parseT.Fatalf("ListTunnels() = %v, want one tunnel with traffic", parseList.GetTunnels())
+	}
+	parseTunnelID := parseList.GetTunnels()[0].GetId()
+	if parseTunnel, parseErr := parseAdminClient.GetTunnel(parseCtx, &adminv1.GetTunnelRequest{Id: parseTunnelID}); parseErr != nil || parseTunnel.GetOrigin() != parseTunnelServer.URL {
+		parseT.Fatalf("GetTunnel() = %v, %v, want origin %s", parseTunnel, parseErr, parseTunnelServer.URL)
+	}
+	if _, parseErr := parseAdminClient.GetTunnel(parseCtx, &adminv1.GetTunnelRequest{Id: "missing"}); status.Code(parseErr) != codes.NotFound {
+		parseT.Fatalf("GetTunnel(missing) error = %v, want NotFound", parseErr)
+	}
+
+	parseLimits, parseErr := parseAdminClient.GetLimits(parseCtx, &adminv1.GetLimitsRequest{})
+	if parseErr != nil {
+		parseT.Fatalf("GetLimits() error: %v", parseErr)
+	}
+	parseLimits.MaxActiveConnections = 1
+	if _, parseErr := parseAdminClient.UpdateLimits(parseCtx, &adminv1.UpdateLimitsRequest{Limits: parseLimits}); parseErr != nil {
+		parseT.Fatalf("UpdateLimits() error: %v", parseErr)
+	}
+	if _, parseErr := parseAdminClient.UpdateLimits(parseCtx, &adminv1.UpdateLimitsRequest{Limits: &adminv1.Limits{MaxActiveConnections: -1}}); status.Code(parseErr) != codes.InvalidArgument {
+		parseT.Fatalf("UpdateLimits(-1) error = %v, want InvalidArgument", parseErr)
+	}
+	parseRejectedConn, parseErr := parseDialTunnel()
+	if parseErr != nil {
+		parseT.Fatalf("BuildTunnelConn() error: %v", parseErr)
+	}
+	defer parseRejectedConn.Close()
+	parseRejectCtx, clearRejectCtx := context.WithTimeout(parseCtx, 500*time.Millisecond)
+	defer clearRejectCtx()
+	if _, parseErr := proto.NewTodoServiceClient(parseRejectedConn).CreateTodo(parseRejectCtx, &proto.CreateTodoRequest{Text: "over limit"}); parseErr == nil {
+		parseT.Fatal("CreateTodo() over the updated connection limit succeeded, want rejection")
+	}
+
+	if _, parseErr := parseAdminClient.CloseTunnel(parseCtx, &adminv1.CloseTunnelRequest{Id: parseTunnelID}); parseErr != nil {
+		parseT.Fatalf("CloseTunnel() error: %v", parseErr)
+	}
+	if _, parseErr := parseAdminClient.CloseTunnel(parseCtx, &adminv1.CloseTunnelRequest{Id: parseTunnelID}); status.Code(parseErr) != codes.NotFound {
+		parseT.Fatalf("second CloseTunnel() error = %v, want NotFound", parseErr)
+	}
+
+	var isConnected, isOpened, isRejected bool
+	for {
+		parseEvent, parseErr := parseEvents.Recv()
+		if parseErr != nil {
+			parseT.Fatalf("Recv() error: %v", parseErr)
+		}
+		switch {
+		case parseEvent.GetTunnelConnected() != nil:
+			isConnected = isConnected || parseEvent.GetTunnelId() == parseTunnelID
+		case parseEvent.GetStreamOpened().GetMethod() == "/TodoService/CreateTodo":
+			isOpened = true
+		case parseEvent.GetUpgradeRejected().GetReason() == string(UpgradeRejectActiveConnections):
+			isRejected = true
+		case parseEvent.GetTunnelDisconnected() != nil && parseEvent.GetTunnelId() == parseTunnelID:
+			if parseCause := parseEvent.GetTunnelDisconnected().GetCause(); parseCause != string(TunnelCloseTerminated) {
+				parseT.Fatalf("disconnect cause = %q, want %q", parseCause, TunnelCloseTerminated)
+			}
+			if !isConnected || !isOpened || !isRejected {
+				parseT.Fatalf("events before disconnect: connected=%v opened=%v rejected=%v, want all", isConnected, isOpened, isRejected)
+			}
+			return
+		}
+	}
+}
+
+// TestGetToolingConfigError_TunnelAdminRequired verifies the flag needs an Admin to serve.
+func TestGetToolingConfigError_TunnelAdminRequired(parseT *testing.T) {
+	if parseErr := GetToolingConfigError(ToolingConfig{ShouldEnableTunnelAdmin: true}); parseErr == nil {
+		parseT.Fatal("GetToolingConfigError() expected missing TunnelAdmin error, got nil")
+	}
+	if parseErr := GetToolingConfigError(ToolingConfig{ShouldEnableTunnelAdmin: true, TunnelAdmin: tunneladmin.New(nil)}); parseErr != nil {
+		parseT.Fatalf("GetToolingConfigError() error: %v", parseErr)
+	}
+}
+
+// TestNewServer_TunnelAdminLimitsIgnored verifies a handler whose limits lose to the admin's logs a warning.
+func TestNewServer_TunnelAdminLimitsIgnored(parseT *testing.T) {
+	parseGrpcServer := grpc.NewServer()
+	defer parseGrpcServer.Stop()
+	var parseLogBuffer bytes.Buffer
+	parseOriginalWriter := log.Writer()
+	log.SetOutput(&parseLogBuffer)
+	defer log.SetOutput(parseOriginalWriter)
+
+	parseAdmin := tunneladmin.New(nil)
+	for _, parseMaxActive := range []int{1, 1} {
+		if _, parseErr := NewServer(parseGrpcServer, BridgeConfig{TunnelAdmin: parseAdmin, MaxActiveConnections: parseMaxActive}); parseErr != nil {
+			parseT.Fatalf("NewServer() error: %v", parseErr)
+		}
+	}
+	if strings.Contains(parseLogBuffer.String(), "tunnel_admin_limits_ignored") {
+		parseT.Fatalf("log = %q, want no warning for matching limits", parseLogBuffer.String())
+	}
+	if _, parseErr := NewServer(parseGrpcServer, BridgeConfig{TunnelAdmin: parseAdmin, MaxActiveConnections: 2}); parseErr != nil {
+		parseT.Fatalf("NewServer() error: %v", parseErr)
+	}
+	if !strings.Contains(parseLogBuffer.String(), `event="tunnel_admin_limits_ignored"`) {
+		parseT.Fatalf("log = %q, want tunnel_admin_limits_ignored", parseLogBuffer.String())
+	}
+	if parseLimits := parseAdmin.Limits(); parseLimits.MaxActiveConnections != 1 {
+		parseT.Fatalf("admin limits = %+v, want the first handler's limits", parseLimits)
+	}
+}
@@ -0,0 +1,82 @@
+//go:build !js && !wasm
+
+package grpctunnel
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/monstercameron/grpc-tunnel/examples/_shared/proto"
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/tunnelregistry"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// TestBuildBridgeHandler_TunnelRegistry verifies open tunnels are listed with live stats and that
+// force-closing one disconnects it with the terminated cause.
+func TestBuildBridgeHandler_TunnelRegistry(parseT *testing.T) {
+	parseGrpcServer := grpc.NewServer()
+	proto.RegisterTodoServiceServer(parseGrpcServer, &mockService{})
+	defer parseGrpcServer.Stop()
+
+	parseRegistry := tunnelregistry.New()
+	parseObserver := buildRecordingTunnelObserver()
+	parseHandler := Wrap(parseGrpcServer, WithTunnelRegistry(parseRegistry), WithTunnelObserver(parseObserver))
+	parseServer := httptest.NewServer(parseHandler)
+	defer parseServer.Close()
+
+	parseCtx, clearCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer clearCtx()
+	parseConn, parseErr := BuildTunnelConn(parseCtx, TunnelConfig{
+		Target:      "ws" + strings.TrimPrefix(parseServer.URL, "http"),
+		Headers:     http.Header{"Origin": []string{parseServer.URL}},
+		GRPCOptions: []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())},
+	})
+	if parseErr != nil {
+		parseT.Fatalf("BuildTunnelConn() error: %v", parseErr)
+	}
+	defer parseConn.Close()
+	if _, parseErr := proto.NewTodoServiceClient(parseConn).CreateTodo(parseCtx, &proto.CreateTodoRequest{Text: "listed"}); parseErr != nil {
+		parseT.Fatalf("CreateTodo() error: %v", parseErr)
+	}
+
+	parseTunnels := parseRegistry.List(tunnelregistry.Filter{ClientKey: "127.0.0.1"})
+	if len(parseTunnels) != 1 {
+		parseT.Fatalf("List() = %+v, want one tunnel from 127.0.0.1", parseTunnels)
+	}
+	parseTunnel := parseTunnels[0]
+	if parseTunnel.Component != "grpctunnel.bridge" || parseTunnel.Origin != parseServer.URL || parseTunnel.BytesIn == 0 || parseTunnel.BytesOut == 0 {
+		parseT.Fatalf("tunnel = %+v, want origin and byte counts", parseTunnel)
+	}
+	if parseTunnel.LastActivity.Before(parseTunnel.StartedAt) {
+		parseT.Fatalf("LastActivity = %v, want after StartedAt %v", parseTunnel.LastActivity, parseTunnel.StartedAt)
+	}
+
+	if !parseRegistry.Close(parseTunnel.ID) {
+		parseT.Fatalf("Close(%s) = false, want true", parseTunnel.ID)
+	}
+	if parseDisconnected := parseObserver.waitDisconnected(parseT); parseDisconnected.TunnelID != parseTunnel.ID || parseDisconnected.Cause != TunnelCloseTerminated {
+		parseT.Fatalf("disconnected = %+v, want tunnel %s terminated", parseDisconnected, parseTunnel.ID)
+	}
+	if _, isFound := parseRegistry.Get(parseTunnel.ID); isFound {
+		parseT.Fatal("Get() found the closed tunnel, want it removed")
+	}
+
+	// The client redials a fresh tunnel on its next call.
+	for parseConn.GetState() == connectivity.Ready {
+		if !parseConn.WaitForStateChange(parseCtx, connectivity.Ready) {
+			parseT.Fatal("client never noticed the closed tunnel")
+		}
+	}
+	if _, parseErr := proto.NewTodoServiceClient(parseConn).CreateTodo(parseCtx, &proto.CreateTodoRequest{Text: "redialed"}, grpc.WaitForReady(true)); parseErr != nil {
+		parseT.Fatalf("CreateTodo() after Close error: %v", parseErr)
+	}
+	if parseTunnels := parseRegistry.List(tunnelregistry.Filter{}); len(parseTunnels) != 1 || parseTunnels[0].ID == parseTunnel.ID {
+		parseT.Fatalf("List() after redial = %+v, want one new tunnel", parseTunnels)
+	}
+}
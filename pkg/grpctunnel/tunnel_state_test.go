@@ -0,0 +1,119 @@
+//go:build !js && !wasm
+
+package grpctunnel
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/monstercameron/grpc-tunnel/examples/_shared/proto"
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/tunnelstate"
+	"google.golang.org/grpc"
+	channelzpb "google.golang.org/grpc/channelz/grpc_channelz_v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// TestBuildToolingHandler_TunnelStates verifies the snapshot endpoint reports an open tunnel's
+// position after a failed stream and that channelz is served by the tooling handler only.
+func TestBuildToolingHandler_TunnelStates(parseT *testing.T) {
+	parseGrpcServer := grpc.NewServer()
+	proto.RegisterTodoServiceServer(parseGrpcServer, &mockService{})
+	defer parseGrpcServer.Stop()
+
+	parseStates := tunnelstate.New(0)
+	parseTunnelServer := httptest.NewServer(Wrap(parseGrpcServer, WithTunnelStates(parseStates)))
+	defer parseTunnelServer.Close()
+	parseToolingHandler, _, parseErr := BuildToolingHandler(parseGrpcServer, ToolingConfig{
+		ShouldEnableChannelz:     true,
+		ShouldEnableTunnelStates: true,
+		TunnelStates:             parseStates,
+	})
+	if parseErr != nil {
+		parseT.Fatalf("BuildToolingHandler() error: %v", parseErr)
+	}
+	if _, isRegistered := parseGrpcServer.GetServiceInfo()["grpc.channelz.v1.Channelz"]; isRegistered {
+		parseT.Fatal("channelz service registered on the tunneled gRPC server")
+	}
+	parseToolingServer := httptest.NewServer(parseToolingHandler)
+	defer parseToolingServer.Close()
+
+	parseCtx, clearCtx := context.WithTimeout(context.Background(), 10*time.Second)
+	defer clearCtx()
+	parseConn, parseErr := BuildTunnelConn(parseCtx, TunnelConfig{
+		Target:      "ws" + strings.TrimPrefix(parseTunnelServer.URL, "http"),
+		Headers:     http.Header{"Origin": []string{parseTunnelServer.URL}},
+		GRPCOptions: []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())},
+	})
+	if parseErr != nil {
+		parseT.Fatalf("BuildTunnelConn() error: %v", parseErr)
+	}
+	defer parseConn.Close()
+	parseToolingConn, parseErr := grpc.NewClient(
+		strings.TrimPrefix(parseToolingServer.URL, "http://"),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if parseErr != nil {
+		parseT.Fatalf("NewClient() error: %v", parseErr)
+	}
+	defer parseToolingConn.Close()
+	if _, parseErr := channelzpb.NewChannelzClient(parseToolingConn).GetServers(parseCtx, &channelzpb.GetServersRequest{}); parseErr != nil {
+		parseT.Fatalf("channelz GetServers() through tooling error: %v", parseErr)
+	}
+
+	parseErr = parseConn.Invoke(parseCtx, "/TodoService/Missing", &proto.CreateTodoRequest{}, &proto.CreateTodoResponse{})
+	if status.Code(parseErr) != codes.Unimplemented {
+		parseT.Fatalf("Invoke() error = %v, want Unimplemented", parseErr)
+	}
+
+	var parseSnapshot tunnelstate.Snapshot
+	for parseDeadline := time.Now().Add(5 * time.Second); ; {
+		parseResponse, parseErr := http.Get(parseToolingServer.URL + "/debug/pprof/tunnels")
+		if parseErr != nil {
+			parseT.Fatalf("Get() error: %v", parseErr)
+		}
+		parseErr = json.NewDecoder(parseResponse.Body).Decode(&parseSnapshot)
+		parseResponse.Body.Close()
+		if parseErr != nil {
+			parseT.Fatalf("Decode() error: %v", parseErr)
+		}
+		if len(parseSnapshot.Tunnels) == 1 && parseSnapshot.Tunnels[0].State == tunnelstate.StateStreamError {
+			break
+		}
+		if time.Now().After(parseDeadline) {
+			parseT.Fatalf("Tunnels = %+v, want one tunnel in stream_error", parseSnapshot.Tunnels)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if parseTunnel := parseSnapshot.Tunnels[0]; parseTunnel.Component != "grpctunnel.bridge" || parseTunnel.LastErrorClass != codes.Unimplemented.String() {
+		parseT.Fatalf("tunnel = %+v, want grpctunnel.bridge with last error Unimplemented", parseTunnel)
+	}
+	parseLast := parseSnapshot.Transitions[len(parseSnapshot.Transitions)-1]
+	if parseLast.State != tunnelstate.StateStreamError || parseLast.Target != "/TodoService/Missing" {
+		parseT.Fatalf("last transition = %+v, want a stream error on /TodoService/Missing", parseLast)
+	}
+	if len(parseSnapshot.Counters) != 1 || parseSnapshot.Counters[0].TunnelsConnected != 1 || parseSnapshot.Counters[0].StreamErrors != 1 {
+		parseT.Fatalf("Counters = %+v, want one connected tunnel and one stream error", parseSnapshot.Counters)
+	}
+
+	parseErr = parseConn.Invoke(parseCtx, "/grpc.channelz.v1.Channelz/GetServers", &channelzpb.GetServersRequest{}, &channelzpb.GetServersResponse{})
+	if status.Code(parseErr) != codes.Unimplemented {
+		parseT.Fatalf("channelz through tunnel error = %v, want Unimplemented", parseErr)
+	}
+}
+
+// TestGetToolingConfigError_TunnelStatesRequired verifies the flag needs a Recorder to serve.
+func TestGetToolingConfigError_TunnelStatesRequired(parseT *testing.T) {
+	if parseErr := GetToolingConfigError(ToolingConfig{ShouldEnableTunnelStates: true}); parseErr == nil {
+		parseT.Fatal("GetToolingConfigError() expected missing TunnelStates error, got nil")
+	}
+	if parseErr := GetToolingConfigError(ToolingConfig{ShouldEnableTunnelStates: true, TunnelStates: tunnelstate.New(0)}); parseErr != nil {
+		parseT.Fatalf("GetToolingConfigError() error: %v", parseErr)
+	}
+}
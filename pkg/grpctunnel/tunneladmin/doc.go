@@ -0,0 +1,20 @@
+// Package tunneladmin serves the grpctunnel.admin.v1.TunnelAdmin gRPC service for operators.
+//
+// An Admin joins a tunnelregistry.Registry of open tunnels, a live feed of tunnel lifecycle events,
+// and the abuse-control limits that handlers read on every upgrade. Share one Admin between the
+// handlers and the tooling handler, which serves the service next to reflection, health, and pprof:
+//
+//	parseAdmin := tunneladmin.New(nil)
+//	tunnelHandler, _ := grpctunnel.BuildBridgeHandler(grpcServer, grpctunnel.BridgeConfig{TunnelAdmin: parseAdmin, MaxActiveConnections: 1000})
+//	toolingHandler, _, _ := grpctunnel.BuildToolingHandler(grpcServer, grpctunnel.ToolingConfig{
+//		ShouldEnableTunnelAdmin: true,
+//		TunnelAdmin:             parseAdmin,
+//	})
+//
+// Any gRPC client can then call the service, for example:
+//
+//	grpcurl -plaintext -d '{"client_key": "203.0.113.7"}' 127.0.0.1:9090 grpctunnel.admin.v1.TunnelAdmin/ListTunnels
+//
+// The service definition is in adminv1/tunnel_admin.proto. Register can also mount the service on
+// another operator-only server; never register it on a server that browsers reach through tunnels.
+package tunneladmin
@@ -0,0 +1,155 @@
+package tunneladmin
+
+import (
+	"context"
+	"math"
+
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/tunneladmin/adminv1"
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/tunnelregistry"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Register registers the grpctunnel.admin.v1.TunnelAdmin service for parseAdmin. Register it on a
+// server reachable by operators only, such as the grpctunnel tooling handler, never on a server
+// that browsers reach through tunnels.
+func Register(parseRegistrar grpc.ServiceRegistrar, parseAdmin *Admin) {
+	adminv1.RegisterTunnelAdminServer(parseRegistrar, &adminServer{getAdmin: parseAdmin})
+}
+
+// adminServer implements TunnelAdmin on top of an Admin.
+type adminServer struct {
+	adminv1.UnimplementedTunnelAdminServer
+	getAdmin *Admin
+}
+
+// ListTunnels returns the open tunnels matching the request, oldest first.
+func (parseServer *adminServer) ListTunnels(_ context.Context, parseRequest *adminv1.ListTunnelsRequest) (*adminv1.ListTunnelsResponse, error) {
+	parseIdleFor := parseRequest.GetIdleFor().AsDuration()
+	if parseIdleFor < 0 {
+		return nil, status.Error(codes.InvalidArgument, "idle_for must be >= 0")
+	}
+	parseSnapshots := parseServer.getAdmin.Registry().List(tunnelregistry.Filter{
+		Component: parseRequest.GetComponent(),
+		ClientKey: parseRequest.GetClientKey(),
+		Origin:    parseRequest.GetOrigin(),
+		IdleFor:   parseIdleFor,
+	})
+	parseResponse := &adminv1.ListTunnelsResponse{Tunnels: make([]*adminv1.Tunnel, 0, len(parseSnapshots))}
+	for _, parseSnapshot := range parseSnapshots {
+		parseResponse.Tunnels = append(parseResponse.Tunnels, buildTunnelMessage(parseSnapshot))
+	}
+	return parseResponse, nil
+}
+
+// GetTunnel returns one open tunnel.
+func (parseServer *adminServer) GetTunnel(_ context.Context, parseRequest *adminv1.GetTunnelRequest) (*adminv1.Tunnel, error) {
+	parseSnapshot, isFound := parseServer.getAdmin.Registry().Get(parseRequest.GetId())
+	if !isFound {
+		return nil, status.Errorf(codes.NotFound, "tunnel %q is not open", parseRequest.GetId())
+	}
+	return buildTunnelMessage(parseSnapshot), nil
+}
+
+// CloseTunnel force-closes one open tunnel.
+func (parseServer *adminServer) CloseTunnel(_ context.Context, parseRequest *adminv1.CloseTunnelRequest) (*adminv1.CloseTunnelResponse, error) {
+	if !parseServer.getAdmin.Registry().Close(parseRequest.GetId()) {
+		return nil, status.Errorf(codes.NotFound, "tunnel %q is not open", parseRequest.GetId())
+	}
+	return &adminv1.CloseTunnelResponse{}, nil
+}
+
+// StreamTunnelEvents streams lifecycle events until the client cancels or falls too far behind.
+// Response headers are sent once the stream is subscribed, so clients may wait for them before
+// relying on receiving later events.
+func (parseServer *adminServer) StreamTunnelEvents(parseRequest *adminv1.StreamTunnelEventsRequest, parseStream grpc.ServerStreamingServer[adminv1.TunnelEvent]) error {
+	if parseServer.getAdmin == nil {
+		return status.Error(codes.FailedPrecondition, "tunnel admin is not configured")
+	}
+	parseSubscriber := parseServer.getAdmin.subscribe(parseRequest.GetTunnelId())
+	defer parseServer.getAdmin.unsubscribe(parseSubscriber)
+	if parseErr := parseStream.SendHeader(metadata.MD{}); parseErr != nil {
+		return parseErr
+	}
+	for {
+		select {
+		case <-parseStream.Context().Done():
+			return status.FromContextError(parseStream.Context().Err()).Err()
+		case <-parseSubscriber.getOverflow:
+			return status.Errorf(codes.ResourceExhausted, "event stream fell more than %d events behind", parseSubscriberBuffer)
+		case parseEvent := <-parseSubscriber.storeEvents:
+			if parseErr := parseStream.Send(parseEvent); parseErr != nil {
+				return parseErr
+			}
+		}
+	}
+}
+
+// GetLimits returns the limits in effect.
+func (parseServer *adminServer) GetLimits(context.Context, *adminv1.GetLimitsRequest) (*adminv1.Limits, error) {
+	return buildLimitsMessage(parseServer.getAdmin.Limits()), nil
+}
+
+// UpdateLimits validates and replaces the limits.
+func (parseServer *adminServer) UpdateLimits(_ context.Context, parseRequest *adminv1.UpdateLimitsRequest) (*adminv1.Limits, error) {
+	if parseServer.getAdmin == nil {
+		return nil, status.Error(codes.FailedPrecondition, "tunnel admin is not configured")
+	}
+	if parseRequest.GetLimits() == nil {
+		return nil, status.Error(codes.InvalidArgument, "limits are required")
+	}
+	parseLimits := parseRequest.GetLimits()
+	if parseErr := parseServer.getAdmin.UpdateLimits(Limits{
+		MaxActiveConnections:    int(parseLimits.GetMaxActiveConnections()),
+		MaxConnectionsPerClient: int(parseLimits.GetMaxConnectionsPerClient()),
+		UpgradeRateLimit:        buildRateLimit(parseLimits.GetUpgradeRateLimit()),
+		RPCRateLimit:            buildRateLimit(parseLimits.GetRpcRateLimit()),
+	}); parseErr != nil {
+		return nil, status.Error(codes.InvalidArgument, parseErr.Error())
+	}
+	return buildLimitsMessage(parseServer.getAdmin.Limits()), nil
+}
+
+// buildTunnelMessage converts a registry snapshot.
+func buildTunnelMessage(parseSnapshot tunnelregistry.Snapshot) *adminv1.Tunnel {
+	return &adminv1.Tunnel{
+		Id:            parseSnapshot.ID,
+		Component:     parseSnapshot.Component,
+		ClientKey:     parseSnapshot.ClientKey,
+		Origin:        parseSnapshot.Origin,
+		RemoteAddr:    parseSnapshot.RemoteAddr,
+		StartedAt:     timestamppb.New(parseSnapshot.StartedAt),
+		BytesIn:       parseSnapshot.BytesIn,
+		BytesOut:      parseSnapshot.BytesOut,
+		ActiveStreams: parseSnapshot.ActiveStreams,
+		LastActivity:  timestamppb.New(parseSnapshot.LastActivity),
+	}
+}
+
+// buildLimitsMessage converts limits, saturating counts that do not fit the wire type.
+func buildLimitsMessage(parseLimits Limits) *adminv1.Limits {
+	return &adminv1.Limits{
+		MaxActiveConnections:    getSaturatedInt32(parseLimits.MaxActiveConnections),
+		MaxConnectionsPerClient: getSaturatedInt32(parseLimits.MaxConnectionsPerClient),
+		UpgradeRateLimit:        buildRateLimitMessage(parseLimits.UpgradeRateLimit),
+		RpcRateLimit:            buildRateLimitMessage(parseLimits.RPCRateLimit),
+	}
+}
+
+// buildRateLimitMessage converts one token-bucket limit.
+func buildRateLimitMessage(parseLimit RateLimit) *adminv1.RateLimit {
+	return &adminv1.RateLimit{PerSecond: parseLimit.PerSecond, Burst: getSaturatedInt32(parseLimit.Burst)}
+}
+
+// buildRateLimit converts one token-bucket limit; a missing message disables it.
+func buildRateLimit(parseLimit *adminv1.RateLimit) RateLimit {
+	return RateLimit{PerSecond: parseLimit.GetPerSecond(), Burst: int(parseLimit.GetBurst())}
+}
+
+// getSaturatedInt32 clamps parseValue to the int32 range.
+func getSaturatedInt32(parseValue int) int32 {
+	return int32(max(math.MinInt32, min(parseValue, math.MaxInt32)))
+}
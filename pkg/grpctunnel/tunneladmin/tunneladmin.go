@@ -0,0 +1,170 @@
+package tunneladmin
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/tunneladmin/adminv1"
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/tunnelregistry"
+)
+
+// parseSubscriberBuffer is how many events a StreamTunnelEvents stream may fall behind before it ends.
+const parseSubscriberBuffer = 256
+
+// RateLimit is a token bucket. Zero PerSecond disables it; zero Burst uses PerSecond rounded up,
+// and at least 1.
+type RateLimit struct {
+	PerSecond float64
+	Burst     int
+}
+
+// Limits are the abuse-control limits an Admin can change while tunnels run. Zero disables a limit.
+type Limits struct {
+	MaxActiveConnections    int
+	MaxConnectionsPerClient int
+	// UpgradeRateLimit is the per-client token bucket for websocket upgrades.
+	UpgradeRateLimit RateLimit
+	// RPCRateLimit is the per-tunnel token bucket for RPC starts. Changes apply to tunnels opened
+	// afterwards.
+	RPCRateLimit RateLimit
+}
+
+// Admin is the shared state behind the TunnelAdmin service: the registry of open tunnels, the live
+// event feed, and the limits handlers enforce. A nil Admin tracks nothing and enforces no limits.
+type Admin struct {
+	getRegistry        *tunnelregistry.Registry
+	storeLimits        atomic.Pointer[Limits]
+	setSubscribersLock sync.Mutex
+	storeSubscribers   map[*subscriber]struct{}
+}
+
+// subscriber is one StreamTunnelEvents stream.
+type subscriber struct {
+	getTunnelID     string
+	storeEvents     chan *adminv1.TunnelEvent
+	getOverflow     chan struct{}
+	setOverflowOnce sync.Once
+}
+
+// New creates an Admin listing tunnels in parseRegistry. A nil registry creates one.
+func New(parseRegistry *tunnelregistry.Registry) *Admin {
+	if parseRegistry == nil {
+		parseRegistry = tunnelregistry.New()
+	}
+	return &Admin{
+		getRegistry:      parseRegistry,
+		storeSubscribers: map[*subscriber]struct{}{},
+	}
+}
+
+// Registry returns the registry handlers register their tunnels with.
+func (parseAdmin *Admin) Registry() *tunnelregistry.Registry {
+	if parseAdmin == nil {
+		return nil
+	}
+	return parseAdmin.getRegistry
+}
+
+// InitLimits sets the limits unless they are already set and returns the limits in effect.
+// Handlers call it with their configured limits when built, so the first handler's configuration
+// wins until UpdateLimits replaces it; handlers log a warning when the result is not what they passed.
+func (parseAdmin *Admin) InitLimits(parseLimits Limits) Limits {
+	if parseAdmin == nil {
+		return parseLimits
+	}
+	parseAdmin.storeLimits.CompareAndSwap(nil, &parseLimits)
+	return *parseAdmin.storeLimits.Load()
+}
+
+// Limits returns the limits in effect.
+func (parseAdmin *Admin) Limits() Limits {
+	if parseAdmin == nil {
+		return Limits{}
+	}
+	if parseLimits := parseAdmin.storeLimits.Load(); parseLimits != nil {
+		return *parseLimits
+	}
+	return Limits{}
+}
+
+// UpdateLimits validates and replaces the limits. They apply to every later upgrade.
+func (parseAdmin *Admin) UpdateLimits(parseLimits Limits) error {
+	if parseErr := getLimitsError(parseLimits); parseErr != nil {
+		return parseErr
+	}
+	if parseAdmin == nil {
+		return nil
+	}
+	parseAdmin.storeLimits.Store(&parseLimits)
+	return nil
+}
+
+// getLimitsError validates limits the way handlers validate their configuration.
+func getLimitsError(parseLimits Limits) error {
+	if parseLimits.MaxActiveConnections < 0 {
+		return fmt.Errorf("tunneladmin: MaxActiveConnections must be >= 0")
+	}
+	if parseLimits.MaxConnectionsPerClient < 0 {
+		return fmt.Errorf("tunneladmin: MaxConnectionsPerClient must be >= 0")
+	}
+	if parseErr := getRateLimitError("UpgradeRateLimit", parseLimits.UpgradeRateLimit); parseErr != nil {
+		return parseErr
+	}
+	return getRateLimitError("RPCRateLimit", parseLimits.RPCRateLimit)
+}
+
+// getRateLimitError validates one token-bucket limit.
+func getRateLimitError(parseName string, parseLimit RateLimit) error {
+	if parseLimit.PerSecond < 0 || math.IsNaN(parseLimit.PerSecond) || math.IsInf(parseLimit.PerSecond, 0) {
+		return fmt.Errorf("tunneladmin: %s.PerSecond must be a finite value >= 0", parseName)
+	}
+	if parseLimit.Burst < 0 {
+		return fmt.Errorf("tunneladmin: %s.Burst must be >= 0", parseName)
+	}
+	if parseLimit.Burst > 0 && parseLimit.PerSecond == 0 {
+		return fmt.Errorf("tunneladmin: %s.Burst requires PerSecond", parseName)
+	}
+	return nil
+}
+
+// Publish delivers one lifecycle event to every StreamTunnelEvents stream. Handlers call it; it
+// never blocks, and streams that fall too far behind are ended instead.
+func (parseAdmin *Admin) Publish(parseEvent *adminv1.TunnelEvent) {
+	if parseAdmin == nil {
+		return
+	}
+	parseAdmin.setSubscribersLock.Lock()
+	defer parseAdmin.setSubscribersLock.Unlock()
+	for parseSubscriber := range parseAdmin.storeSubscribers {
+		if parseSubscriber.getTunnelID != "" && parseSubscriber.getTunnelID != parseEvent.GetTunnelId() {
+			continue
+		}
+		select {
+		case parseSubscriber.storeEvents <- parseEvent:
+		default:
+			parseSubscriber.setOverflowOnce.Do(func() { close(parseSubscriber.getOverflow) })
+		}
+	}
+}
+
+// subscribe starts delivering events, of one tunnel when parseTunnelID is set, until unsubscribe.
+func (parseAdmin *Admin) subscribe(parseTunnelID string) *subscriber {
+	parseSubscriber := &subscriber{
+		getTunnelID: parseTunnelID,
+		storeEvents: make(chan *adminv1.TunnelEvent, parseSubscriberBuffer),
+		getOverflow: make(chan struct{}),
+	}
+	parseAdmin.setSubscribersLock.Lock()
+	parseAdmin.storeSubscribers[parseSubscriber] = struct{}{}
+	parseAdmin.setSubscribersLock.Unlock()
+	return parseSubscriber
+}
+
+// unsubscribe stops delivering events to parseSubscriber.
+func (parseAdmin *Admin) unsubscribe(parseSubscriber *subscriber) {
+	parseAdmin.setSubscribersLock.Lock()
+	delete(parseAdmin.storeSubscribers, parseSubscriber)
+	parseAdmin.setSubscribersLock.Unlock()
+}
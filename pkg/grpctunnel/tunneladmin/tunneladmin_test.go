@@ -0,0 +1,82 @@
+package tunneladmin
+
+import (
+	"math"
+	"testing"
+
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/tunneladmin/adminv1"
+)
+
+// TestAdmin_Limits verifies the first InitLimits wins and UpdateLimits validates before replacing.
+func TestAdmin_Limits(parseT *testing.T) {
+	parseAdmin := New(nil)
+	if parseAdmin.Registry() == nil {
+		parseT.Fatal("Registry() = nil, want a created registry")
+	}
+	parseFirst := Limits{MaxActiveConnections: 10, UpgradeRateLimit: RateLimit{PerSecond: 2, Burst: 4}}
+	if parseGot := parseAdmin.InitLimits(parseFirst); parseGot != parseFirst {
+		parseT.Fatalf("InitLimits() = %+v, want %+v", parseGot, parseFirst)
+	}
+	if parseGot := parseAdmin.InitLimits(Limits{MaxActiveConnections: 99}); parseGot != parseFirst {
+		parseT.Fatalf("second InitLimits() = %+v, want the first limits %+v", parseGot, parseFirst)
+	}
+
+	for _, parseInvalid := range []Limits{
+		{MaxActiveConnections: -1},
+		{MaxConnectionsPerClient: -1},
+		{UpgradeRateLimit: RateLimit{PerSecond: math.NaN()}},
+		{RPCRateLimit: RateLimit{PerSecond: -1}},
+		{RPCRateLimit: RateLimit{Burst: 3}},
+	} {
+		if parseErr := parseAdmin.UpdateLimits(parseInvalid); parseErr == nil {
+			parseT.Fatalf("UpdateLimits(%+v) expected error, got nil", parseInvalid)
+		}
+	}
+	if parseGot := parseAdmin.Limits(); parseGot != parseFirst {
+		parseT.Fatalf("Limits() after rejected updates = %+v, want %+v", parseGot, parseFirst)
+	}
+
+	parseUpdated := Limits{MaxConnectionsPerClient: 2, RPCRateLimit: RateLimit{PerSecond: 50}}
+	if parseErr := parseAdmin.UpdateLimits(parseUpdated); parseErr != nil {
+		parseT.Fatalf("UpdateLimits() error: %v", parseErr)
+	}
+	if parseGot := parseAdmin.Limits(); parseGot != parseUpdated {
+		parseT.Fatalf("Limits() = %+v, want %+v", parseGot, parseUpdated)
+	}
+
+	var parseNilAdmin *Admin
+	if parseGot := parseNilAdmin.InitLimits(parseFirst); parseGot != parseFirst {
+		parseT.Fatalf("nil InitLimits() = %+v, want the given limits", parseGot)
+	}
+	parseNilAdmin.Publish(&adminv1.TunnelEvent{})
+}
+
+// TestAdmin_PublishFiltersAndEndsSlowStreams verifies tunnel filtering and that a full stream is
+// ended instead of blocking Publish.
+func TestAdmin_PublishFiltersAndEndsSlowStreams(parseT *testing.T) {
+	parseAdmin := New(nil)
+	parseAll := parseAdmin.subscribe("")
+	defer parseAdmin.unsubscribe(parseAll)
+	parseOne := parseAdmin.subscribe("t-1")
+	defer parseAdmin.unsubscribe(parseOne)
+
+	parseAdmin.Publish(&adminv1.TunnelEvent{TunnelId: "t-1"})
+	parseAdmin.Publish(&adminv1.TunnelEvent{TunnelId: "t-2"})
+	if len(parseAll.storeEvents) != 2 || len(parseOne.storeEvents) != 1 {
+		parseT.Fatalf("queued events = %d and %d, want 2 and 1", len(parseAll.storeEvents), len(parseOne.storeEvents))
+	}
+
+	for parseIndex := 0; parseIndex < parseSubscriberBuffer; parseIndex++ {
+		parseAdmin.Publish(&adminv1.TunnelEvent{TunnelId: "t-2"})
+	}
+	select {
+	case <-parseAll.getOverflow:
+	default:
+		parseT.Fatal("full stream was not ended")
+	}
+	select {
+	case <-parseOne.getOverflow:
+		parseT.Fatal("filtered stream was ended by events of another tunnel")
+	default:
+	}
+}
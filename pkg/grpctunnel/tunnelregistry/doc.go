@@ -0,0 +1,19 @@
+// Package tunnelregistry provides a live view of open websocket tunnels.
+//
+// A Registry lists the tunnels served by every handler that shares it, with each tunnel's client
+// key, origin, start time, byte counts, active HTTP/2 streams, and last activity read live at list
+// time. Operators can filter tunnels and force-close them from Go code, for example to disconnect a
+// misbehaving client without restarting the process:
+//
+//	parseRegistry := tunnelregistry.New()
+//	tunnelHandler, _ := grpctunnel.BuildBridgeHandler(grpcServer, grpctunnel.BridgeConfig{TunnelRegistry: parseRegistry})
+//	proxyHandler := bridge.NewHandler(bridge.Config{TargetAddress: "localhost:50051", TunnelRegistry: parseRegistry})
+//
+//	for _, parseTunnel := range parseRegistry.List(tunnelregistry.Filter{ClientKey: "203.0.113.7"}) {
+//		log.Printf("%s open since %v, %d bytes in", parseTunnel.ID, parseTunnel.StartedAt, parseTunnel.BytesIn)
+//	}
+//	parseRegistry.CloseMatching(tunnelregistry.Filter{ClientKey: "203.0.113.7"})
+//
+// Force-closed tunnels drop their websocket immediately, failing in-flight RPCs, and are reported
+// to TunnelObserver with the "terminated" close cause.
+package tunnelregistry
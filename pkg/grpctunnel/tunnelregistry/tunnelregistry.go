@@ -0,0 +1,212 @@
+package tunnelregistry
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Info is what a tunnel reports about itself when it registers.
+type Info struct {
+	// ID is the tunnel ID also carried by TunnelObserver events.
+	ID string
+	// Component names the handler serving the tunnel: "grpctunnel.bridge" or "bridge.handler".
+	Component string
+	// ClientKey is the abuse-control client key, such as the resolved client IP.
+	ClientKey string
+	// Origin is the Origin header of the upgrade request.
+	Origin     string
+	RemoteAddr string
+	StartedAt  time.Time
+}
+
+// Stats are a tunnel's live counters, read each time the registry is listed.
+type Stats struct {
+	// BytesIn and BytesOut count tunneled HTTP/2 bytes read from and written to the client.
+	BytesIn       int64
+	BytesOut      int64
+	ActiveStreams int64
+	// LastActivity is when the tunnel last read or wrote bytes, or StartedAt when it has not.
+	LastActivity time.Time
+}
+
+// Snapshot is one tunnel as seen when the registry was read.
+type Snapshot struct {
+	Info
+	Stats
+}
+
+// Filter selects tunnels. Empty fields match every tunnel.
+type Filter struct {
+	ID        string
+	Component string
+	ClientKey string
+	Origin    string
+	// IdleFor matches tunnels with no activity for at least this long.
+	IdleFor time.Duration
+}
+
+// Match reports whether parseSnapshot satisfies every set field of the filter.
+func (parseFilter Filter) Match(parseSnapshot Snapshot) bool {
+	if parseFilter.ID != "" && parseSnapshot.ID != parseFilter.ID {
+		return false
+	}
+	if parseFilter.Component != "" && parseSnapshot.Component != parseFilter.Component {
+		return false
+	}
+	if parseFilter.ClientKey != "" && parseSnapshot.ClientKey != parseFilter.ClientKey {
+		return false
+	}
+	if parseFilter.Origin != "" && parseSnapshot.Origin != parseFilter.Origin {
+		return false
+	}
+	if parseFilter.IdleFor > 0 && time.Since(parseSnapshot.LastActivity) < parseFilter.IdleFor {
+		return false
+	}
+	return true
+}
+
+// Registry tracks the open tunnels of every handler sharing it. A nil Registry tracks nothing.
+type Registry struct {
+	setLock      sync.Mutex
+	storeTunnels map[string]*Tunnel
+}
+
+// New creates an empty Registry.
+func New() *Registry {
+	return &Registry{storeTunnels: map[string]*Tunnel{}}
+}
+
+// Tunnel is one registered tunnel. Its methods are safe on a nil Tunnel.
+type Tunnel struct {
+	getRegistry *Registry
+	getInfo     Info
+	getStats    func() Stats
+	handleClose func()
+}
+
+// Add registers a tunnel whose counters are read through parseStats and which parseClose
+// force-closes. Handlers call it; nil registries return nil.
+func (parseRegistry *Registry) Add(parseInfo Info, parseStats func() Stats, parseClose func()) *Tunnel {
+	if parseRegistry == nil {
+		return nil
+	}
+	parseTunnel := &Tunnel{
+		getRegistry: parseRegistry,
+		getInfo:     parseInfo,
+		getStats:    parseStats,
+		handleClose: parseClose,
+	}
+	parseRegistry.setLock.Lock()
+	parseRegistry.storeTunnels[parseInfo.ID] = parseTunnel
+	parseRegistry.setLock.Unlock()
+	return parseTunnel
+}
+
+// Remove unregisters the tunnel once its connection has been released.
+func (parseTunnel *Tunnel) Remove() {
+	if parseTunnel == nil {
+		return
+	}
+	parseRegistry := parseTunnel.getRegistry
+	parseRegistry.setLock.Lock()
+	if parseRegistry.storeTunnels[parseTunnel.getInfo.ID] == parseTunnel {
+		delete(parseRegistry.storeTunnels, parseTunnel.getInfo.ID)
+	}
+	parseRegistry.setLock.Unlock()
+}
+
+// getSnapshot reads the tunnel's live counters.
+func (parseTunnel *Tunnel) getSnapshot() Snapshot {
+	parseSnapshot := Snapshot{Info: parseTunnel.getInfo}
+	if parseTunnel.getStats != nil {
+		parseSnapshot.Stats = parseTunnel.getStats()
+	}
+	if parseSnapshot.LastActivity.IsZero() {
+		parseSnapshot.LastActivity = parseSnapshot.StartedAt
+	}
+	return parseSnapshot
+}
+
+// getTunnels returns the registered tunnels without holding the lock while their counters are read.
+func (parseRegistry *Registry) getTunnels() []*Tunnel {
+	if parseRegistry == nil {
+		return nil
+	}
+	parseRegistry.setLock.Lock()
+	defer parseRegistry.setLock.Unlock()
+	parseTunnels := make([]*Tunnel, 0, len(parseRegistry.storeTunnels))
+	for _, parseTunnel := range parseRegistry.storeTunnels {
+		parseTunnels = append(parseTunnels, parseTunnel)
+	}
+	return parseTunnels
+}
+
+// Len returns the number of open tunnels.
+func (parseRegistry *Registry) Len() int {
+	if parseRegistry == nil {
+		return 0
+	}
+	parseRegistry.setLock.Lock()
+	defer parseRegistry.setLock.Unlock()
+	return len(parseRegistry.storeTunnels)
+}
+
+// List returns the open tunnels matching parseFilter, oldest first.
+func (parseRegistry *Registry) List(parseFilter Filter) []Snapshot {
+	parseSnapshots := []Snapshot{}
+	for _, parseTunnel := range parseRegistry.getTunnels() {
+		if parseSnapshot := parseTunnel.getSnapshot(); parseFilter.Match(parseSnapshot) {
+			parseSnapshots = append(parseSnapshots, parseSnapshot)
+		}
+	}
+	sort.Slice(parseSnapshots, func(parseI int, parseJ int) bool {
+		if parseSnapshots[parseI].StartedAt.Equal(parseSnapshots[parseJ].StartedAt) {
+			return parseSnapshots[parseI].ID < parseSnapshots[parseJ].ID
+		}
+		return parseSnapshots[parseI].StartedAt.Before(parseSnapshots[parseJ].StartedAt)
+	})
+	return parseSnapshots
+}
+
+// Get returns one open tunnel by ID.
+func (parseRegistry *Registry) Get(parseID string) (Snapshot, bool) {
+	if parseRegistry == nil {
+		return Snapshot{}, false
+	}
+	parseRegistry.setLock.Lock()
+	parseTunnel, isFound := parseRegistry.storeTunnels[parseID]
+	parseRegistry.setLock.Unlock()
+	if !isFound {
+		return Snapshot{}, false
+	}
+	return parseTunnel.getSnapshot(), true
+}
+
+// Close force-closes one open tunnel by ID and reports whether it was found.
+func (parseRegistry *Registry) Close(parseID string) bool {
+	if parseRegistry == nil {
+		return false
+	}
+	parseRegistry.setLock.Lock()
+	parseTunnel, isFound := parseRegistry.storeTunnels[parseID]
+	parseRegistry.setLock.Unlock()
+	if !isFound {
+		return false
+	}
+	parseTunnel.handleClose()
+	return true
+}
+
+// CloseMatching force-closes every open tunnel matching parseFilter and returns how many it closed.
+// The zero Filter closes every tunnel.
+func (parseRegistry *Registry) CloseMatching(parseFilter Filter) int {
+	parseClosed := 0
+	for _, parseTunnel := range parseRegistry.getTunnels() {
+		if parseFilter.Match(parseTunnel.getSnapshot()) {
+			parseTunnel.handleClose()
+			parseClosed++
+		}
+	}
+	return parseClosed
+}
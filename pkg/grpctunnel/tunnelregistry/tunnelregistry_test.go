@@ -0,0 +1,75 @@
+package tunnelregistry
+
+import (
+	"testing"
+	"time"
+)
+
+// TestRegistry_ListFiltersAndReadsLiveStats verifies listing order, filters, and live counters.
+func TestRegistry_ListFiltersAndReadsLiveStats(parseT *testing.T) {
+	parseRegistry := New()
+	parseStart := time.Now().Add(-time.Minute)
+	var parseBytesIn int64
+	parseRegistry.Add(Info{ID: "b", ClientKey: "10.0.0.2", Origin: "https://app.example", StartedAt: parseStart.Add(time.Second)}, func() Stats {
+		return Stats{BytesIn: parseBytesIn, LastActivity: time.Now()}
+	}, func() {})
+	parseRegistry.Add(Info{ID: "a", ClientKey: "10.0.0.1", StartedAt: parseStart}, nil, func() {})
+
+	parseAll := parseRegistry.List(Filter{})
+	if len(parseAll) != 2 || parseAll[0].ID != "a" || parseAll[1].ID != "b" {
+		parseT.Fatalf("List() = %+v, want a then b", parseAll)
+	}
+	if !parseAll[0].LastActivity.Equal(parseStart) {
+		parseT.Fatalf("LastActivity without activity = %v, want StartedAt %v", parseAll[0].LastActivity, parseStart)
+	}
+
+	parseBytesIn = 42
+	if parseSnapshot, isFound := parseRegistry.Get("b"); !isFound || parseSnapshot.BytesIn != 42 {
+		parseT.Fatalf("Get(b) = %+v, %v, want live BytesIn 42", parseSnapshot, isFound)
+	}
+	for _, parseCase := range []struct {
+		getFilter Filter
+		getWant   int
+	}{
+		{getFilter: Filter{ClientKey: "10.0.0.1"}, getWant: 1},
+		{getFilter: Filter{Origin: "https://app.example"}, getWant: 1},
+		{getFilter: Filter{IdleFor: 30 * time.Second}, getWant: 1},
+		{getFilter: Filter{ID: "missing"}, getWant: 0},
+	} {
+		if parseGot := parseRegistry.List(parseCase.getFilter); len(parseGot) != parseCase.getWant {
+			parseT.Fatalf("List(%+v) = %d tunnels, want %d", parseCase.getFilter, len(parseGot), parseCase.getWant)
+		}
+	}
+}
+
+// TestRegistry_CloseAndRemove verifies force-closing by ID and filter, and unregistering.
+func TestRegistry_CloseAndRemove(parseT *testing.T) {
+	parseRegistry := New()
+	parseClosed := map[string]int{}
+	parseTunnels := map[string]*Tunnel{}
+	for _, parseID := range []string{"a", "b", "c"} {
+		parseTunnels[parseID] = parseRegistry.Add(Info{ID: parseID, ClientKey: "shared"}, nil, func() {
+			parseClosed[parseID]++
+		})
+	}
+	parseTunnels["c"].Remove()
+	if parseRegistry.Len() != 2 {
+		parseT.Fatalf("Len() after Remove = %d, want 2", parseRegistry.Len())
+	}
+	if !parseRegistry.Close("a") || parseRegistry.Close("c") {
+		parseT.Fatal("Close() should find a and not the removed c")
+	}
+	if parseCount := parseRegistry.CloseMatching(Filter{ClientKey: "shared"}); parseCount != 2 {
+		parseT.Fatalf("CloseMatching() = %d, want 2", parseCount)
+	}
+	if parseClosed["a"] != 2 || parseClosed["b"] != 1 || parseClosed["c"] != 0 {
+		parseT.Fatalf("close calls = %v, want a twice and b once", parseClosed)
+	}
+
+	var parseNil *Registry
+	if parseNil.Add(Info{ID: "x"}, nil, func() {}) != nil || parseNil.Len() != 0 || len(parseNil.List(Filter{})) != 0 || parseNil.Close("x") {
+		parseT.Fatal("nil Registry should track nothing")
+	}
+	var parseNilTunnel *Tunnel
+	parseNilTunnel.Remove()
+}
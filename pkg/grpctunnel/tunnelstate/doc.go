@@ -0,0 +1,17 @@
+// Package tunnelstate records the server tunnel state transitions described in
+// docs/core/TUNNEL_STATE_DIAGNOSTICS.md for incident response.
+//
+// A Recorder shared by several handlers keeps each open tunnel's position in the state machine,
+// a bounded history of recent transitions with their error classes, and per-handler counters of
+// rejected upgrades by abuse-guard reason and of ended tunnels by close cause. The tooling handler
+// serves its Snapshot as JSON under DebugPathPrefix:
+//
+//	parseStates := tunnelstate.New(0)
+//	tunnelHandler, _ := grpctunnel.BuildBridgeHandler(grpcServer, grpctunnel.BridgeConfig{TunnelStates: parseStates})
+//	toolingHandler, _, _ := grpctunnel.BuildToolingHandler(grpcServer, grpctunnel.ToolingConfig{
+//		ShouldEnableTunnelStates: true,
+//		TunnelStates:             parseStates,
+//	})
+//
+//	curl http://127.0.0.1:9090/debug/pprof/tunnels
+package tunnelstate
@@ -0,0 +1,221 @@
+package tunnelstate
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// parseDefaultMaxTransitions is how many recent transitions a Recorder keeps by default.
+const parseDefaultMaxTransitions = 256
+
+// State is a position in the server tunnel state machine of docs/core/TUNNEL_STATE_DIAGNOSTICS.md.
+// Upgrades are not recorded until they end, so upgrade_received and upgrade_accepted never appear:
+// an upgrade is either upgrade_rejected or tunnel_connected.
+type State string
+
+const (
+	// StateUpgradeRejected means the bridge refused a websocket upgrade; no tunnel exists.
+	StateUpgradeRejected State = "upgrade_rejected"
+	// StateTunnelConnected means the upgrade succeeded and the tunnel serves HTTP/2.
+	StateTunnelConnected State = "tunnel_connected"
+	// StateStreamError means a stream on the tunnel ended with a gRPC status other than OK.
+	StateStreamError State = "stream_error"
+	// StateBackendDialFailed means bridge.Handler could not reach its backend for a stream. The
+	// stream's own stream_error follows it.
+	StateBackendDialFailed State = "backend_dial_failed"
+	// StateTunnelDisconnected means the tunnel ended.
+	StateTunnelDisconnected State = "tunnel_disconnected"
+)
+
+// Transition is one state change reported by a handler.
+type Transition struct {
+	Time      time.Time `json:"timestamp"`
+	Component string    `json:"component"`
+	// TunnelID is empty for rejected upgrades.
+	TunnelID string `json:"tunnel_id,omitempty"`
+	State    State  `json:"state"`
+	// Target is the gRPC method of stream errors, or the request path of upgrades.
+	Target     string `json:"target,omitempty"`
+	RemoteAddr string `json:"remote_addr,omitempty"`
+	// ErrorClass groups failures: a rejection reason, a gRPC code name, or a close cause.
+	ErrorClass   string `json:"error_class,omitempty"`
+	ErrorMessage string `json:"error_message,omitempty"`
+}
+
+// Tunnel is the state machine position of one open tunnel.
+type Tunnel struct {
+	ID          string    `json:"id"`
+	Component   string    `json:"component"`
+	RemoteAddr  string    `json:"remote_addr,omitempty"`
+	State       State     `json:"state"`
+	ConnectedAt time.Time `json:"connected_at"`
+	// Since is when the tunnel entered State.
+	Since          time.Time `json:"since"`
+	StreamErrors   int64     `json:"stream_errors"`
+	LastErrorClass string    `json:"last_error_class,omitempty"`
+}
+
+// Counters are cumulative totals for one component.
+type Counters struct {
+	Component           string `json:"component"`
+	TunnelsConnected    int64  `json:"tunnels_connected"`
+	TunnelsDisconnected int64  `json:"tunnels_disconnected"`
+	StreamErrors        int64  `json:"stream_errors"`
+	BackendDialFailures int64  `json:"backend_dial_failures"`
+	// UpgradeRejections counts refused upgrades by reason, including every abuse-guard rejection
+	// such as "client_banned" or "upgrade_rate_limited".
+	UpgradeRejections map[string]int64 `json:"upgrade_rejections"`
+	// DisconnectCauses counts ended tunnels by close cause.
+	DisconnectCauses map[string]int64 `json:"disconnect_causes"`
+}
+
+// Snapshot is the whole recorded picture at one time.
+type Snapshot struct {
+	Time time.Time `json:"timestamp"`
+	// Tunnels are the open tunnels, oldest first.
+	Tunnels []Tunnel `json:"tunnels"`
+	// Transitions are the most recent transitions, oldest first.
+	Transitions []Transition `json:"transitions"`
+	// Counters are sorted by component.
+	Counters []Counters `json:"counters"`
+}
+
+// Recorder tracks tunnel state transitions reported by every handler that shares it. A nil
+// Recorder records nothing.
+type Recorder struct {
+	setRecorderLock     sync.Mutex
+	getMaxTransitions   int
+	storeTunnels        map[string]*Tunnel
+	storeTransitions    []Transition
+	getTransitionsStart int
+	storeCounters       map[string]*Counters
+}
+
+// New creates a Recorder keeping the last parseMaxTransitions transitions. Zero keeps 256.
+func New(parseMaxTransitions int) *Recorder {
+	if parseMaxTransitions <= 0 {
+		parseMaxTransitions = parseDefaultMaxTransitions
+	}
+	return &Recorder{
+		getMaxTransitions: parseMaxTransitions,
+		storeTunnels:      map[string]*Tunnel{},
+		storeCounters:     map[string]*Counters{},
+	}
+}
+
+// Record applies one transition. Handlers call it; it never blocks on I/O.
+func (parseRecorder *Recorder) Record(parseTransition Transition) {
+	if parseRecorder == nil {
+		return
+	}
+	parseRecorder.setRecorderLock.Lock()
+	defer parseRecorder.setRecorderLock.Unlock()
+
+	if len(parseRecorder.storeTransitions) < parseRecorder.getMaxTransitions {
+		parseRecorder.storeTransitions = append(parseRecorder.storeTransitions, parseTransition)
+	} else {
+		parseRecorder.storeTransitions[parseRecorder.getTransitionsStart] = parseTransition
+		parseRecorder.getTransitionsStart = (parseRecorder.getTransitionsStart + 1) % parseRecorder.getMaxTransitions
+	}
+
+	parseCounters := parseRecorder.getCounters(parseTransition.Component)
+	switch parseTransition.State {
+	case StateUpgradeRejected:
+		parseCounters.UpgradeRejections[parseTransition.ErrorClass]++
+	case StateTunnelConnected:
+		parseCounters.TunnelsConnected++
+		parseRecorder.storeTunnels[parseTransition.TunnelID] = &Tunnel{
+			ID:          parseTransition.TunnelID,
+			Component:   parseTransition.Component,
+			RemoteAddr:  parseTransition.RemoteAddr,
+			State:       StateTunnelConnected,
+			ConnectedAt: parseTransition.Time,
+			Since:       parseTransition.Time,
+		}
+	case StateStreamError:
+		parseCounters.StreamErrors++
+		if parseTunnel, isOpen := parseRecorder.storeTunnels[parseTransition.TunnelID]; isOpen {
+			parseTunnel.State = StateStreamError
+			parseTunnel.Since = parseTransition.Time
+			parseTunnel.StreamErrors++
+			parseTunnel.LastErrorClass = parseTransition.ErrorClass
+		}
+	case StateBackendDialFailed:
+		parseCounters.BackendDialFailures++
+		if parseTunnel, isOpen := parseRecorder.storeTunnels[parseTransition.TunnelID]; isOpen {
+			parseTunnel.State = StateBackendDialFailed
+			parseTunnel.Since = parseTransition.Time
+			parseTunnel.LastErrorClass = parseTransition.ErrorClass
+		}
+	case StateTunnelDisconnected:
+		parseCounters.TunnelsDisconnected++
+		parseCounters.DisconnectCauses[parseTransition.ErrorClass]++
+		delete(parseRecorder.storeTunnels, parseTransition.TunnelID)
+	}
+}
+
+// getCounters returns the counters of a component, creating them. Callers hold the lock.
+func (parseRecorder *Recorder) getCounters(parseComponent string) *Counters {
+	parseCounters, isFound := parseRecorder.storeCounters[parseComponent]
+	if !isFound {
+		parseCounters = &Counters{
+			Component:         parseComponent,
+			UpgradeRejections: map[string]int64{},
+			DisconnectCauses:  map[string]int64{},
+		}
+		parseRecorder.storeCounters[parseComponent] = parseCounters
+	}
+	return parseCounters
+}
+
+// Snapshot returns a copy of the recorded state.
+func (parseRecorder *Recorder) Snapshot() Snapshot {
+	parseSnapshot := Snapshot{Time: time.Now(), Tunnels: []Tunnel{}, Transitions: []Transition{}, Counters: []Counters{}}
+	if parseRecorder == nil {
+		return parseSnapshot
+	}
+	parseRecorder.setRecorderLock.Lock()
+	defer parseRecorder.setRecorderLock.Unlock()
+
+	for _, parseTunnel := range parseRecorder.storeTunnels {
+		parseSnapshot.Tunnels = append(parseSnapshot.Tunnels, *parseTunnel)
+	}
+	sort.Slice(parseSnapshot.Tunnels, func(parseI, parseJ int) bool {
+		return parseSnapshot.Tunnels[parseI].ConnectedAt.Before(parseSnapshot.Tunnels[parseJ].ConnectedAt)
+	})
+	parseSnapshot.Transitions = append(parseSnapshot.Transitions, parseRecorder.storeTransitions[parseRecorder.getTransitionsStart:]...)
+	parseSnapshot.Transitions = append(parseSnapshot.Transitions, parseRecorder.storeTransitions[:parseRecorder.getTransitionsStart]...)
+	for _, parseCounters := range parseRecorder.storeCounters {
+		parseCopy := *parseCounters
+		parseCopy.UpgradeRejections = make(map[string]int64, len(parseCounters.UpgradeRejections))
+		for parseReason, parseCount := range parseCounters.UpgradeRejections {
+			parseCopy.UpgradeRejections[parseReason] = parseCount
+		}
+		parseCopy.DisconnectCauses = make(map[string]int64, len(parseCounters.DisconnectCauses))
+		for parseCause, parseCount := range parseCounters.DisconnectCauses {
+			parseCopy.DisconnectCauses[parseCause] = parseCount
+		}
+		parseSnapshot.Counters = append(parseSnapshot.Counters, parseCopy)
+	}
+	sort.Slice(parseSnapshot.Counters, func(parseI, parseJ int) bool {
+		return parseSnapshot.Counters[parseI].Component < parseSnapshot.Counters[parseJ].Component
+	})
+	return parseSnapshot
+}
+
+// ServeHTTP writes the Snapshot as indented JSON.
+func (parseRecorder *Recorder) ServeHTTP(parseW http.ResponseWriter, parseR *http.Request) {
+	if parseR.Method != http.MethodGet && parseR.Method != http.MethodHead {
+		parseW.Header().Set("Allow", "GET, HEAD")
+		http.Error(parseW, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	parseW.Header().Set("Content-Type", "application/json")
+	parseW.Header().Set("Cache-Control", "no-store")
+	parseEncoder := json.NewEncoder(parseW)
+	parseEncoder.SetIndent("", "  ")
+	_ = parseEncoder.Encode(parseRecorder.Snapshot())
+}
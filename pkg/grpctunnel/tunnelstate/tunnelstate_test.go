@@ -0,0 +1,109 @@
+package tunnelstate
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestRecorder_Record verifies tunnel positions, counters, and that the transition history keeps
+// only the most recent transitions in order.
+func TestRecorder_Record(parseT *testing.T) {
+	parseRecorder := New(3)
+	parseStart := time.Unix(1700000000, 0)
+	for parseIndex, parseTransition := range []Transition{
+		{Component: "a", State: StateUpgradeRejected, ErrorClass: "client_banned"},
+		{Component: "a", TunnelID: "t-1", State: StateTunnelConnected},
+		{Component: "a", TunnelID: "t-2", State: StateTunnelConnected},
+		{Component: "a", TunnelID: "t-1", State: StateStreamError, Target: "/svc/Method", ErrorClass: "Unavailable"},
+		{Component: "b", TunnelID: "t-2", State: StateTunnelDisconnected, ErrorClass: "idle_timeout"},
+	} {
+		parseTransition.Time = parseStart.Add(time.Duration(parseIndex) * time.Second)
+		parseRecorder.Record(parseTransition)
+	}
+
+	parseSnapshot := parseRecorder.Snapshot()
+	if len(parseSnapshot.Tunnels) != 1 {
+		parseT.Fatalf("Tunnels = %+v, want t-1 open", parseSnapshot.Tunnels)
+	}
+	parseTunnel := parseSnapshot.Tunnels[0]
+	if parseTunnel.ID != "t-1" || parseTunnel.State != StateStreamError || parseTunnel.StreamErrors != 1 || parseTunnel.LastErrorClass != "Unavailable" {
+		parseT.Fatalf("tunnel = %+v, want t-1 in stream_error with one Unavailable error", parseTunnel)
+	}
+	if !parseTunnel.ConnectedAt.Equal(parseStart.Add(time.Second)) || !parseTunnel.Since.Equal(parseStart.Add(3*time.Second)) {
+		parseT.Fatalf("tunnel times = %v, %v, want connected at 1s and in state since 3s", parseTunnel.ConnectedAt, parseTunnel.Since)
+	}
+
+	if len(parseSnapshot.Transitions) != 3 {
+		parseT.Fatalf("len(Transitions) = %d, want 3", len(parseSnapshot.Transitions))
+	}
+	for parseIndex, parseWantState := range []State{StateTunnelConnected, StateStreamError, StateTunnelDisconnected} {
+		if parseSnapshot.Transitions[parseIndex].State != parseWantState {
+			parseT.Fatalf("Transitions[%d].State = %q, want %q", parseIndex, parseSnapshot.Transitions[parseIndex].State, parseWantState)
+		}
+	}
+
+	if len(parseSnapshot.Counters) != 2 || parseSnapshot.Counters[0].Component != "a" || parseSnapshot.Counters[1].Component != "b" {
+		parseT.Fatalf("Counters = %+v, want components a and b", parseSnapshot.Counters)
+	}
+	parseA := parseSnapshot.Counters[0]
+	if parseA.TunnelsConnected != 2 || parseA.StreamErrors != 1 || parseA.UpgradeRejections["client_banned"] != 1 {
+		parseT.Fatalf("counters a = %+v, want 2 connected, 1 stream error, 1 client_banned", parseA)
+	}
+	if parseSnapshot.Counters[1].DisconnectCauses["idle_timeout"] != 1 {
+		parseT.Fatalf("counters b = %+v, want one idle_timeout disconnect", parseSnapshot.Counters[1])
+	}
+
+	parseA.UpgradeRejections["client_banned"] = 99
+	if parseRecorder.Snapshot().Counters[0].UpgradeRejections["client_banned"] != 1 {
+		parseT.Fatal("Snapshot() shares counter maps with the recorder")
+	}
+
+	var parseNilRecorder *Recorder
+	parseNilRecorder.Record(Transition{State: StateTunnelConnected})
+	if parseNil := parseNilRecorder.Snapshot(); parseNil.Tunnels == nil || len(parseNil.Tunnels) != 0 {
+		parseT.Fatalf("nil Snapshot() = %+v, want empty lists", parseNil)
+	}
+}
+
+// TestRecorder_BackendDialFailed verifies a backend dial failure moves its tunnel and is counted.
+func TestRecorder_BackendDialFailed(parseT *testing.T) {
+	parseRecorder := New(0)
+	parseRecorder.Record(Transition{Component: "bridge.handler", TunnelID: "t-1", State: StateTunnelConnected})
+	parseRecorder.Record(Transition{Component: "bridge.handler", TunnelID: "t-1", State: StateBackendDialFailed, ErrorClass: "Unavailable"})
+
+	parseSnapshot := parseRecorder.Snapshot()
+	if len(parseSnapshot.Tunnels) != 1 || parseSnapshot.Tunnels[0].State != StateBackendDialFailed || parseSnapshot.Tunnels[0].LastErrorClass != "Unavailable" {
+		parseT.Fatalf("Tunnels = %+v, want t-1 in backend_dial_failed", parseSnapshot.Tunnels)
+	}
+	if parseSnapshot.Counters[0].BackendDialFailures != 1 || parseSnapshot.Counters[0].StreamErrors != 0 {
+		parseT.Fatalf("Counters = %+v, want one backend dial failure and no stream errors", parseSnapshot.Counters)
+	}
+}
+
+// TestRecorder_ServeHTTP verifies the snapshot is served as JSON to GET and refused to other methods.
+func TestRecorder_ServeHTTP(parseT *testing.T) {
+	parseRecorder := New(0)
+	parseRecorder.Record(Transition{Time: time.Now(), Component: "a", TunnelID: "t-1", State: StateTunnelConnected})
+
+	parseResponse := httptest.NewRecorder()
+	parseRecorder.ServeHTTP(parseResponse, httptest.NewRequest(http.MethodGet, "/debug/pprof/tunnels", nil))
+	if parseResponse.Code != http.StatusOK || parseResponse.Header().Get("Content-Type") != "application/json" {
+		parseT.Fatalf("GET = %d %q, want 200 application/json", parseResponse.Code, parseResponse.Header().Get("Content-Type"))
+	}
+	var parseSnapshot Snapshot
+	if parseErr := json.Unmarshal(parseResponse.Body.Bytes(), &parseSnapshot); parseErr != nil {
+		parseT.Fatalf("Unmarshal() error: %v", parseErr)
+	}
+	if len(parseSnapshot.Tunnels) != 1 || parseSnapshot.Tunnels[0].ID != "t-1" {
+		parseT.Fatalf("Tunnels = %+v, want t-1", parseSnapshot.Tunnels)
+	}
+
+	parseResponse = httptest.NewRecorder()
+	parseRecorder.ServeHTTP(parseResponse, httptest.NewRequest(http.MethodPost, "/debug/pprof/tunnels", nil))
+	if parseResponse.Code != http.StatusMethodNotAllowed {
+		parseT.Fatalf("POST = %d, want 405", parseResponse.Code)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"net"
 	"syscall/js"
 
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/auth/ticket"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -30,6 +31,10 @@ const (
 type Config struct {
 	// Subprotocols configures optional websocket subprotocol negotiation.
 	Subprotocols []string
+	// GetTicket fetches a fresh single-use upgrade ticket before every dial, for example from a
+	// ticket.Manager issue endpoint. The ticket is presented through the ticket subprotocol
+	// entries because browsers cannot set an Authorization header on websocket handshakes.
+	GetTicket func(ctx context.Context) (string, error)
 }
 
 // newBrowserWebSocketDialer creates a custom gRPC dialer that establishes a WebSocket
@@ -65,9 +70,19 @@ func newBrowserWebSocketDialerWithConfig(parseWebSocketURL string, parseConfig C
 			return nil, status.Errorf(codes.Unavailable, "WASM: WebSocket not available in this environment")
 		}
 
+		// Tickets are single-use, so every dial (including reconnects) fetches a new one.
+		parseTicket := ""
+		if parseConfig.GetTicket != nil {
+			var parseErr error
+			parseTicket, parseErr = parseConfig.GetTicket(parseDialContext)
+			if parseErr != nil {
+				return nil, status.Errorf(codes.Unauthenticated, "WASM: failed to obtain upgrade ticket: %v", parseErr)
+			}
+		}
+
 		// Create a new browser WebSocket instance with the provided URL.
 		// This initiates the WebSocket handshake in the background.
-		parseBrowserWebSocket := buildBrowserWebSocket(parseBrowserWebSocketConstructor, parseWebSocketURL, parseConfig)
+		parseBrowserWebSocket := buildBrowserWebSocket(parseBrowserWebSocketConstructor, parseWebSocketURL, buildBrowserWebSocketProtocols(parseConfig, parseTicket))
 
 		// Configure the WebSocket to use ArrayBuffer for binary data.
 		// gRPC requires binary communication, so we must set binaryType to 'arraybuffer'.
@@ -134,14 +149,23 @@ func newBrowserWebSocketDialerWithConfig(parseWebSocketURL string, parseConfig C
 	}
 }
 
+// buildBrowserWebSocketProtocols returns configured subprotocols followed by ticket entries when a ticket is present.
+func buildBrowserWebSocketProtocols(parseConfig Config, parseTicket string) []string {
+	parseSubprotocols := append([]string{}, parseConfig.Subprotocols...)
+	if parseTicket != "" {
+		parseSubprotocols = append(parseSubprotocols, ticket.BuildSubprotocols(parseTicket)...)
+	}
+	return parseSubprotocols
+}
+
 // buildBrowserWebSocket constructs the browser WebSocket with optional subprotocols.
-func buildBrowserWebSocket(parseConstructor js.Value, parseWebSocketURL string, parseConfig Config) js.Value {
-	if len(parseConfig.Subprotocols) == 0 {
+func buildBrowserWebSocket(parseConstructor js.Value, parseWebSocketURL string, parseSubprotocols []string) js.Value {
+	if len(parseSubprotocols) == 0 {
 		return parseConstructor.New(parseWebSocketURL)
 	}
 
-	parseProtocols := make([]interface{}, 0, len(parseConfig.Subprotocols))
-	for _, parseSubprotocol := range parseConfig.Subprotocols {
+	parseProtocols := make([]interface{}, 0, len(parseSubprotocols))
+	for _, parseSubprotocol := range parseSubprotocols {
 		parseProtocols = append(parseProtocols, parseSubprotocol)
 	}
 	return parseConstructor.New(parseWebSocketURL, js.ValueOf(parseProtocols))
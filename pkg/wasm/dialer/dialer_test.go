@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/monstercameron/grpc-tunnel/pkg/grpctunnel/auth/ticket"
 	"google.golang.org/grpc"
 )
 
@@ -44,6 +45,24 @@ func TestNew_URLFormats(parseT *testing.T) {
 	}
 }
 
+// TestBuildBrowserWebSocketProtocols_AppendsTicket verifies ticket entries follow configured subprotocols.
+func TestBuildBrowserWebSocketProtocols_AppendsTicket(parseT *testing.T) {
+	parseConfig := Config{Subprotocols: []string{"proto.v1"}}
+	if parseProtocols := buildBrowserWebSocketProtocols(parseConfig, ""); len(parseProtocols) != 1 || parseProtocols[0] != "proto.v1" {
+		parseT.Fatalf("buildBrowserWebSocketProtocols(no ticket) = %v", parseProtocols)
+	}
+	parseProtocols := buildBrowserWebSocketProtocols(parseConfig, "abc.def")
+	parseWant := []string{"proto.v1", ticket.Subprotocol, ticket.Subprotocol + ".abc.def"}
+	if len(parseProtocols) != len(parseWant) {
+		parseT.Fatalf("buildBrowserWebSocketProtocols() = %v, want %v", parseProtocols, parseWant)
+	}
+	for parseIndex := range parseWant {
+		if parseProtocols[parseIndex] != parseWant[parseIndex] {
+			parseT.Fatalf("buildBrowserWebSocketProtocols() = %v, want %v", parseProtocols, parseWant)
+		}
+	}
+}
+
 func TestNewWithConfig_ReturnType(parseT *testing.T) {
 	parseDialOption := NewWithConfig("ws://localhost:8080", Config{
 		Subprotocols: []string{"proto.v1"},